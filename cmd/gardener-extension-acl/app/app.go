@@ -17,20 +17,45 @@ package app
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
+	"github.com/gardener/gardener/extensions/pkg/controller/heartbeat"
 	"github.com/gardener/gardener/extensions/pkg/util"
+	gardenerhealthz "github.com/gardener/gardener/pkg/healthz"
+	"github.com/go-logr/logr"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	istionetworkv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	istionetworkv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
 	componentbaseconfig "k8s.io/component-base/config"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/stackitcloud/gardener-extension-acl/pkg/accessreview"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/aggregation"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/globallist"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/healthcheck"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/operatorconfig"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/deniedconnections"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/local"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/migration"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/multiseed"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/tracing"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/webhook"
 )
 
@@ -47,24 +72,86 @@ func NewControllerManagerCommand(ctx context.Context) *cobra.Command {
 			if err := options.optionAggregator.Complete(); err != nil {
 				return fmt.Errorf("error completing options: %s", err)
 			}
+			logf.SetLogger(options.logger(cmd.Flags()))
 			cmd.SilenceUsage = true
 			return options.run(ctx)
 		},
 	}
 
 	options.optionAggregator.AddFlags(cmd.Flags())
+	zapFlags := flag.NewFlagSet("zap", flag.ContinueOnError)
+	options.zapOptions.BindFlags(zapFlags)
+	cmd.Flags().AddGoFlagSet(zapFlags)
+	cmd.Flags().BoolVar(&options.local, "local", false, "Run against a kind cluster with istio instead of a seed, "+
+		"i.e. install the CRDs of gardener and disable the leader election. The objects of a shoot are printed by the fake-shoot command.")
+	cmd.AddCommand(NewRenderCommand(), NewCheckIPCommand(), NewFakeShootCommand())
 
 	return cmd
 }
 
+// Permissions required by the leader election, the heartbeat, the event
+// recorder and the gardener extension library, which aren't derived from the controllers.
+//
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=create;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=dnsrecords,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;create;update;patch
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles;clusterrolebindings;roles;rolebindings,verbs=get;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=create;update;patch;delete
+
+// logger returns the logger configured by the zap flags (e.g. --zap-log-level)
+// if any of them is set, otherwise the one configured by --log-level and
+// --log-format.
+func (o *Options) logger(fs *pflag.FlagSet) logr.Logger {
+	zapFlagsSet := false
+	fs.Visit(func(f *pflag.Flag) {
+		zapFlagsSet = zapFlagsSet || strings.HasPrefix(f.Name, "zap-")
+	})
+	if !zapFlagsSet {
+		return o.managerOptions.Completed().Logger
+	}
+	return zap.New(zap.UseFlagOptions(o.zapOptions))
+}
+
 func (o *Options) run(ctx context.Context) error {
+	// keep serving the admission requests for a while after the signal, see
+	// helper.DelayedShutdown
+	ctx, shutdownCheck := helper.DelayedShutdown(ctx, o.extensionOptions.ShutdownDelay, clock.RealClock{})
+
+	o.extensionOptions.Completed().ApplyTracingConfig(&tracing.DefaultOptions)
+	shutdownTracing, err := tracing.Setup(ctx, tracing.DefaultOptions)
+	if err != nil {
+		return fmt.Errorf("could not set up tracing: %w", err)
+	}
+	defer func() {
+		// the context is canceled already, the pending spans are flushed
+		// within a grace period of their own
+		flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logf.Log.Error(err, "Could not flush the pending spans")
+		}
+	}()
+
 	// TODO: Make these flags configurable via command line parameters or component config file.
-	util.ApplyClientConnectionConfigurationToRESTConfig(&componentbaseconfig.ClientConnectionConfiguration{
+	clientConnectionConfig := &componentbaseconfig.ClientConnectionConfiguration{
 		QPS:   100.0,
 		Burst: 130,
-	}, o.restOptions.Completed().Config)
+	}
+	util.ApplyClientConnectionConfigurationToRESTConfig(clientConnectionConfig, o.restOptions.Completed().Config)
 
 	mgrOpts := o.managerOptions.Completed().Options()
+	mgrOpts.Logger = logf.Log
+	o.extensionOptions.Completed().ApplyLeaderElectionConfig(&mgrOpts)
+
+	if o.local {
+		// there is a single instance of the extension, and no gardenlet
+		// installing the CRDs of the seed
+		mgrOpts.LeaderElection = false
+		if err := installLocalCRDs(ctx, o.restOptions.Completed().Config); err != nil {
+			return fmt.Errorf("could not install CRDs: %w", err)
+		}
+	}
 
 	// TODO why??
 	mgrOpts.Client = client.Options{
@@ -92,25 +179,123 @@ func (o *Options) run(ctx context.Context) error {
 		return fmt.Errorf("could not update manager scheme: %s", err)
 	}
 
+	if err := controller.AddIndexes(ctx, mgr.GetFieldIndexer()); err != nil {
+		return fmt.Errorf("could not add field indexes to manager: %s", err)
+	}
+
+	// the extension is started without istio as well, the reconciliations of
+	// the shoots are retried until istio is installed
+	if istioInstalled, err := helper.IsIstioInstalled(mgr.GetRESTMapper()); err != nil {
+		mgr.GetLogger().Error(err, "Could not check whether istio is installed in the seed")
+	} else if !istioInstalled {
+		mgr.GetLogger().Info("Istio is not installed in the seed, ACL extensions are marked with the IncompatibleSeed condition until it is installed")
+	}
+
 	ctrlConfig := o.extensionOptions.Completed()
 	ctrlConfig.ApplyHealthCheckConfig(&healthcheck.DefaultAddOptions.HealthCheckConfig)
 	ctrlConfig.Apply(&controller.DefaultAddOptions.ExtensionConfig)
-	webhook.DefaultAddOptions.AllowedCIDRs = ctrlConfig.AdditionalAllowedCIDRs
+	webhook.DefaultAddOptions.AllowedCIDRs = append(
+		slices.Clone(controller.DefaultAddOptions.ExtensionConfig.AdditionalAllowedCIDRs),
+		controller.DefaultAddOptions.ExtensionConfig.GardenEgressCIDRs...,
+	)
+	webhook.DefaultAddOptions.AutoAllowInfrastructureEgressCIDRs = controller.DefaultAddOptions.ExtensionConfig.AutoAllowInfrastructureEgressCIDRs
+	webhook.DefaultAddOptions.GlobalAllowlistConfigMap = controller.DefaultAddOptions.ExtensionConfig.GlobalAllowlistConfigMap
+	webhook.DefaultAddOptions.GlobalDenylistConfigMap = controller.DefaultAddOptions.ExtensionConfig.GlobalDenylistConfigMap
+	webhook.DefaultAddOptions.ClientIPPreservation = controller.DefaultAddOptions.ExtensionConfig.ClientIPPreservation
+	webhook.DefaultAddOptions.PurposePolicies = controller.DefaultAddOptions.ExtensionConfig.PurposePolicies
+	webhook.DefaultAddOptions.IgnoredNamespaces = controller.DefaultAddOptions.ExtensionConfig.IgnoredNamespaces
+	ctrlConfig.ApplyWebhookHandlerConfig(&webhook.DefaultAddOptions)
+	globallist.DefaultAddOptions.AllowlistConfigMap = controller.DefaultAddOptions.ExtensionConfig.GlobalAllowlistConfigMap
+	globallist.DefaultAddOptions.DenylistConfigMap = controller.DefaultAddOptions.ExtensionConfig.GlobalDenylistConfigMap
+	operatorconfig.DefaultAddOptions.ExtensionConfig = controller.DefaultAddOptions.ExtensionConfig
+	aggregation.DefaultAddOptions.ManagedResource = !controller.DefaultAddOptions.ExtensionConfig.CreateAggregatedEnvoyFiltersDirectly
+	ctrlConfig.ApplyMultiSeedConfig(&multiseed.DefaultAddOptions)
+	ctrlConfig.ApplyDeniedConnectionsConfig(&deniedconnections.DefaultAddOptions)
+	ctrlConfig.ApplyAccessReviewConfig(&accessreview.DefaultAddOptions)
+	ctrlConfig.ApplyMigrationConfig(&migration.DefaultAddOptions)
+	multiseed.DefaultAddOptions.ConfigureRESTConfig = func(config *rest.Config) {
+		util.ApplyClientConnectionConfigurationToRESTConfig(clientConnectionConfig, config)
+	}
 
 	o.controllerOptions.Completed().Apply(&controller.DefaultAddOptions.ControllerOptions)
-	o.healthOptions.Completed().Apply(&healthcheck.DefaultAddOptions.Controller)
+	o.controllerOptions.Completed().ApplyHealthCheck(&healthcheck.DefaultAddOptions.Controller)
 	o.reconcileOptions.Completed().Apply(&controller.DefaultAddOptions.IgnoreOperationAnnotation)
 
 	if err := o.controllerSwitches.Completed().AddToManager(ctx, mgr); err != nil {
 		return fmt.Errorf("could not add controllers to manager: %s", err)
 	}
 
-	if err := o.webhookOptions.Completed().AddToManager(ctx, mgr); err != nil {
+	// gardenlet detects a dead extension by the heartbeat Lease in the
+	// namespace of the extension, which doesn't exist when running locally
+	o.heartbeatOptions.Completed().Apply(&heartbeat.DefaultAddOptions)
+	if heartbeat.DefaultAddOptions.Namespace != "" {
+		if err := heartbeat.AddToManager(ctx, mgr); err != nil {
+			return fmt.Errorf("could not add heartbeat controller to manager: %s", err)
+		}
+	}
+
+	// every additional seed gets the same controllers, but no webhooks
+	addToSeedManager := func(ctx context.Context, seedMgr manager.Manager) error {
+		if err := controller.AddIndexes(ctx, seedMgr.GetFieldIndexer()); err != nil {
+			return fmt.Errorf("could not add field indexes to manager: %w", err)
+		}
+		return o.controllerSwitches.Completed().AddToManager(ctx, seedMgr)
+	}
+	if err := multiseed.AddToManager(mgr, multiseed.DefaultAddOptions, addToSeedManager); err != nil {
+		return fmt.Errorf("could not add seed managers to manager: %s", err)
+	}
+
+	scraper, err := deniedconnections.AddToManager(mgr, deniedconnections.DefaultAddOptions)
+	if err != nil {
+		return fmt.Errorf("could not add denied connections scraper to manager: %s", err)
+	}
+	if scraper != nil {
+		accessreview.DefaultAddOptions.Hits = scraper
+	}
+	if err := accessreview.AddToManager(mgr, accessreview.DefaultAddOptions); err != nil {
+		return fmt.Errorf("could not add access review reporter to manager: %s", err)
+	}
+	if err := migration.AddToManager(mgr, migration.DefaultAddOptions); err != nil {
+		return fmt.Errorf("could not add migration to manager: %s", err)
+	}
+
+	webhookConfig := o.webhookOptions.Completed()
+	ctrlConfig.ApplyWebhookConfig(webhookConfig)
+	if err := webhookConfig.AddToManager(ctx, mgr); err != nil {
 		return fmt.Errorf("could not add controllers to manager: %s", err)
 	}
+
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		return fmt.Errorf("could not add healthcheck: %w", err)
+	}
+	if err := mgr.AddReadyzCheck("informer-sync", gardenerhealthz.NewCacheSyncHealthz(mgr.GetCache())); err != nil {
+		return fmt.Errorf("could not add readycheck for informers: %w", err)
+	}
+	if err := mgr.AddReadyzCheck("webhook-server", mgr.GetWebhookServer().StartedChecker()); err != nil {
+		return fmt.Errorf("could not add readycheck of webhook to manager: %w", err)
+	}
+	if err := mgr.AddReadyzCheck("istio-discovery", helper.IstioDiscoveryChecker(mgr.GetRESTMapper())); err != nil {
+		return fmt.Errorf("could not add readycheck for istio discovery: %w", err)
+	}
+	if err := mgr.AddReadyzCheck("shutdown", shutdownCheck); err != nil {
+		return fmt.Errorf("could not add readycheck for shutdown: %w", err)
+	}
+
 	if err := mgr.Start(ctx); err != nil {
 		return fmt.Errorf("error running manager: %s", err)
 	}
 
 	return nil
 }
+
+func installLocalCRDs(ctx context.Context, config *rest.Config) error {
+	scheme := runtime.NewScheme()
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		return err
+	}
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+	return local.InstallCRDs(ctx, c, time.Minute)
+}
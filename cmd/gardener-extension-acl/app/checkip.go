@@ -0,0 +1,116 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/checkip"
+)
+
+// NewCheckIPCommand creates a new command that reports whether a source IP
+// would be allowed by the ACL of a shoot.
+func NewCheckIPCommand() *cobra.Command {
+	opts := checkip.Options{}
+	var (
+		providerConfigPath string
+		kubeconfig         string
+		namespace          string
+		output             string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "check-ip <ip>",
+		Short: "Report whether a source IP would be allowed by the ACL of a shoot",
+		Long: "Report whether a connection from the given source IP to the API server of a shoot would be allowed " +
+			"or denied by the ACL, and which CIDR matched. The providerConfig is either read from a file, or from the " +
+			"ACL extension in the given shoot namespace of the seed, together with the always allowed CIDRs recorded " +
+			"in its status.",
+		Args:          cobra.ExactArgs(1),
+		SilenceErrors: true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (providerConfigPath == "") == (namespace == "") {
+				return errors.New("exactly one of --provider-config and --namespace is required")
+			}
+			if output != "text" && output != "json" {
+				return fmt.Errorf("invalid output %q, either 'text' or 'json'", output)
+			}
+			cmd.SilenceUsage = true
+			opts.IP = args[0]
+
+			if providerConfigPath != "" {
+				var err error
+				if opts.ProviderConfig, err = os.ReadFile(providerConfigPath); err != nil {
+					return fmt.Errorf("could not read providerConfig: %w", err)
+				}
+			} else {
+				c, err := newSeedClient(kubeconfig)
+				if err != nil {
+					return err
+				}
+				providerConfig, alwaysAllowedCIDRs, err := checkip.FromExtension(cmd.Context(), c, namespace)
+				if err != nil {
+					return fmt.Errorf("could not read the ACL extension of namespace %s: %w", namespace, err)
+				}
+				opts.ProviderConfig = providerConfig
+				opts.AlwaysAllowedCIDRs = append(opts.AlwaysAllowedCIDRs, alwaysAllowedCIDRs...)
+			}
+
+			decision, err := checkip.Check(opts)
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				return json.NewEncoder(cmd.OutOrStdout()).Encode(decision)
+			}
+			result := "denied"
+			if decision.Allowed {
+				result = "allowed"
+			}
+			if decision.RateLimited {
+				result += " (rate-limited)"
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", result, decision.Reason)
+			return err
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVarP(&providerConfigPath, "provider-config", "f", "", "Path of the providerConfig of the ACL extension in YAML or JSON.")
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "Path of the kubeconfig of the seed (defaults to $KUBECONFIG), used with --namespace.")
+	fs.StringVarP(&namespace, "namespace", "n", "", "Shoot namespace (technical ID) in the seed to read the ACL extension from.")
+	fs.StringSliceVar(
+		&opts.AlwaysAllowedCIDRs,
+		"always-allowed-cidrs",
+		nil,
+		"Additional always allowed CIDRs, e.g. the seed and shoot networks when using --provider-config.",
+	)
+	fs.StringSliceVar(&opts.DeniedCIDRs, "denied-cidrs", nil, "CIDRs of the global denylist.")
+	fs.StringVarP(&output, "output", "o", "text", "Output format, either 'text' or 'json'.")
+
+	return cmd
+}
+
+func newSeedClient(kubeconfig string) (client.Client, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("could not load kubeconfig: %w", err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := extensionsv1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	return client.New(config, client.Options{Scheme: scheme})
+}
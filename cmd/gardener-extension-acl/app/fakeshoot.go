@@ -0,0 +1,67 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/local"
+)
+
+// NewFakeShootCommand creates a new command that prints the objects of a fake
+// shoot for running the extension with --local against a kind cluster.
+func NewFakeShootCommand() *cobra.Command {
+	opts := local.DefaultOptions()
+	var providerConfigPath string
+
+	cmd := &cobra.Command{
+		Use:   "fake-shoot",
+		Short: "Print the objects of a fake shoot for running the extension with --local",
+		Long: "Print the objects gardenlet would create in the seed for a workerless shoot with the ACL extension, " +
+			"i.e. its namespace, the Cluster, the istio Gateway of its API server, the EnvoyFilter of its internal " +
+			"flow and the Extension, e.g. to apply them to a kind cluster with istio.",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if providerConfigPath != "" {
+				var err error
+				if opts.ProviderConfig, err = os.ReadFile(providerConfigPath); err != nil {
+					return fmt.Errorf("could not read providerConfig: %w", err)
+				}
+			}
+			cmd.SilenceUsage = true
+
+			objects, err := local.FakeShoot(opts)
+			if err != nil {
+				return err
+			}
+			manifests, err := local.Manifests(objects)
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(manifests)
+			return err
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVar(&opts.ShootName, "name", opts.ShootName, "Name of the shoot.")
+	fs.StringVar(&opts.ProjectName, "project", opts.ProjectName, "Name of the project of the shoot.")
+	fs.StringVar(&opts.APIServerHost, "apiserver-host", opts.APIServerHost, "Host (SNI) of the shoot's API server.")
+	fs.StringVarP(
+		&providerConfigPath,
+		"provider-config",
+		"f",
+		"",
+		"Path of the providerConfig of the ACL extension in YAML or JSON (defaults to an ALLOW rule for --allowed-cidrs).",
+	)
+	fs.StringSliceVar(&opts.AllowedCIDRs, "allowed-cidrs", opts.AllowedCIDRs, "CIDRs allowed by the default rule.")
+	fs.StringVar(&opts.IstioNamespace, "istio-namespace", opts.IstioNamespace, "Namespace of the istio ingress gateway.")
+	fs.StringToStringVar(&opts.IstioLabels, "istio-labels", opts.IstioLabels, "Labels of the istio ingress gateway.")
+	fs.StringVar(&opts.SeedNodesCIDR, "seed-nodes-cidr", opts.SeedNodesCIDR, "Node network of the kind cluster, i.e. its docker network.")
+	fs.StringVar(&opts.SeedPodsCIDR, "seed-pods-cidr", opts.SeedPodsCIDR, "Pod network of the kind cluster.")
+
+	return cmd
+}
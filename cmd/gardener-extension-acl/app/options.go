@@ -19,9 +19,12 @@ import (
 	"os"
 
 	extensionscmdcontroller "github.com/gardener/gardener/extensions/pkg/controller/cmd"
+	heartbeatcmd "github.com/gardener/gardener/extensions/pkg/controller/heartbeat/cmd"
 	extensionscmdwebhook "github.com/gardener/gardener/extensions/pkg/webhook/cmd"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	extensioncmd "github.com/stackitcloud/gardener-extension-acl/pkg/cmd"
+	controllerconfig "github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
 )
 
 // ExtensionName is the name of the extension.
@@ -33,12 +36,18 @@ type Options struct {
 	extensionOptions   *extensioncmd.ExtensionOptions
 	restOptions        *extensionscmdcontroller.RESTOptions
 	managerOptions     *extensionscmdcontroller.ManagerOptions
-	controllerOptions  *extensionscmdcontroller.ControllerOptions
-	healthOptions      *extensionscmdcontroller.ControllerOptions
+	controllerOptions  *extensioncmd.ControllerOptions
 	controllerSwitches *extensionscmdcontroller.SwitchOptions
 	webhookOptions     *extensioncmd.AddToManagerOptions
 	reconcileOptions   *extensionscmdcontroller.ReconcilerOptions
-	optionAggregator   extensionscmdcontroller.OptionAggregator
+	heartbeatOptions   *heartbeatcmd.Options
+	// zapOptions are bound to the standard zap flags of controller-runtime,
+	// see logger.
+	zapOptions       *zap.Options
+	optionAggregator extensionscmdcontroller.OptionAggregator
+	// local runs the extension against a kind cluster with istio instead of
+	// a seed, see --local.
+	local bool
 }
 
 // NewOptions creates a new Options instance.
@@ -46,8 +55,10 @@ func NewOptions() *Options {
 	options := &Options{
 		generalOptions: &extensionscmdcontroller.GeneralOptions{},
 		extensionOptions: &extensioncmd.ExtensionOptions{
-			AdditionalAllowedCIDRs: nil,
-			ChartPath:              "charts",
+			AdditionalAllowedCIDRs:             nil,
+			AutoAllowInfrastructureEgressCIDRs: true,
+			ChartPath:                          "charts",
+			EnforcementBackend:                 controllerconfig.EnforcementBackendEnvoyFilter,
 		},
 		restOptions: &extensionscmdcontroller.RESTOptions{},
 		managerOptions: &extensionscmdcontroller.ManagerOptions{
@@ -56,14 +67,7 @@ func NewOptions() *Options {
 			LeaderElectionID:        extensionscmdcontroller.LeaderElectionNameID(ExtensionName),
 			LeaderElectionNamespace: os.Getenv("LEADER_ELECTION_NAMESPACE"),
 		},
-		controllerOptions: &extensionscmdcontroller.ControllerOptions{
-			// This is a default value.
-			MaxConcurrentReconciles: 5,
-		},
-		healthOptions: &extensionscmdcontroller.ControllerOptions{
-			// This is a default value.
-			MaxConcurrentReconciles: 5,
-		},
+		controllerOptions:  extensioncmd.NewControllerOptions(),
 		controllerSwitches: extensioncmd.ControllerSwitches(),
 		webhookOptions: extensioncmd.NewAddToManagerOptions(
 			ExtensionName,
@@ -73,6 +77,12 @@ func NewOptions() *Options {
 			extensioncmd.WebhookSwitchOptions(),
 		),
 		reconcileOptions: &extensionscmdcontroller.ReconcilerOptions{},
+		heartbeatOptions: &heartbeatcmd.Options{
+			ExtensionName:        ExtensionName,
+			Namespace:            os.Getenv("LEADER_ELECTION_NAMESPACE"),
+			RenewIntervalSeconds: 30,
+		},
+		zapOptions: &zap.Options{},
 	}
 
 	options.optionAggregator = extensionscmdcontroller.NewOptionAggregator(
@@ -81,10 +91,10 @@ func NewOptions() *Options {
 		options.managerOptions,
 		options.controllerOptions,
 		options.extensionOptions,
-		extensionscmdcontroller.PrefixOption("healthcheck-", options.healthOptions),
 		options.controllerSwitches,
 		options.webhookOptions,
 		options.reconcileOptions,
+		extensionscmdcontroller.PrefixOption("heartbeat-", options.heartbeatOptions),
 	)
 
 	return options
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	controllerconfig "github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/render"
+)
+
+// NewRenderCommand creates a new command that prints the EnvoyFilters (or
+// AuthorizationPolicies) the extension would create for a providerConfig.
+func NewRenderCommand() *cobra.Command {
+	opts := render.Options{}
+	var providerConfigPath string
+
+	cmd := &cobra.Command{
+		Use:   "render",
+		Short: "Print the EnvoyFilters the extension would create for a providerConfig",
+		Long: "Print the EnvoyFilters (or AuthorizationPolicies) the extension would create in the istio namespaces " +
+			"for the given providerConfig and shoot, e.g. to review rule changes. The always allowed CIDRs of the seed " +
+			"aren't looked up, pass them with --always-allowed-cidrs. The patches of the webhook for the internal flow " +
+			"aren't printed.",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var err error
+			if providerConfigPath == "-" {
+				opts.ProviderConfig, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				opts.ProviderConfig, err = os.ReadFile(providerConfigPath)
+			}
+			if err != nil {
+				return fmt.Errorf("could not read providerConfig: %w", err)
+			}
+			cmd.SilenceUsage = true
+
+			manifests, err := render.Render(opts)
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(manifests)
+			return err
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVarP(&providerConfigPath, "provider-config", "f", "", "Path of the providerConfig of the ACL extension in YAML or JSON ('-' reads from stdin).")
+	fs.StringVar(&opts.TechnicalID, "technical-id", "", "Technical ID of the shoot, e.g. 'shoot--foo--bar'.")
+	fs.StringSliceVar(&opts.Hosts, "apiserver-host", nil, "Hosts (SNI) or IPs of the shoot's API server, as in its advertised addresses.")
+	fs.StringSliceVar(&opts.InternalHosts, "internal-apiserver-host", nil, "Hosts (SNI) of the internal address of the shoot's API server, required to render the internalRule.")
+	fs.StringSliceVar(&opts.IstioNamespaces, "istio-namespace", []string{"istio-ingress"}, "Namespaces of the istio ingress gateways serving the shoot.")
+	fs.StringToStringVar(
+		&opts.IstioLabels,
+		"istio-labels",
+		map[string]string{"app": "istio-ingressgateway", "istio": "ingressgateway"},
+		"Labels of the istio ingress gateways serving the shoot.",
+	)
+	fs.StringSliceVar(
+		&opts.AlwaysAllowedCIDRs,
+		"always-allowed-cidrs",
+		nil,
+		"CIDRs allowed for every shoot, i.e. the seed networks, the additional allowed CIDRs and the global allowlist.",
+	)
+	fs.StringSliceVar(&opts.ShootCIDRs, "shoot-cidrs", nil, "Always allowed CIDRs of the shoot, i.e. its node network and egress CIDRs.")
+	fs.StringVar(&opts.SeedIngressDomain, "seed-ingress-domain", "", "Ingress domain of the seed, the shoot's ingress is only rendered if it is set.")
+	fs.StringVar(&opts.ShootDomain, "shoot-domain", "", "Domain of the shoot, its ingress domain is only rendered with protectIngress if it is set.")
+	fs.StringToStringVar(
+		&opts.IngressIstioLabels,
+		"ingress-istio-labels",
+		nil,
+		"Labels of the istio ingress gateway of the seed ingress domain (defaults to --istio-labels).",
+	)
+	fs.StringVar(
+		&opts.EnforcementBackend,
+		"enforcement-backend",
+		controllerconfig.EnforcementBackendEnvoyFilter,
+		fmt.Sprintf(
+			"Resources used to enforce the ACL in the istio namespaces, either '%s' or '%s'.",
+			controllerconfig.EnforcementBackendEnvoyFilter, controllerconfig.EnforcementBackendAuthorizationPolicy,
+		),
+	)
+	fs.StringVar(&opts.IstioVersion, "istio-version", "", "Istio release of the ingress gateways, e.g. '1.21' (defaults to the latest supported release).")
+	fs.BoolVar(&opts.LogDeniedConnections, "log-denied-connections", false, "Render the denied connection logs, unless configured otherwise in the providerConfig.")
+
+	for _, flag := range []string{"provider-config", "technical-id", "apiserver-host"} {
+		if err := cmd.MarkFlagRequired(flag); err != nil {
+			panic(err)
+		}
+	}
+
+	return cmd
+}
@@ -29,9 +29,10 @@ import (
 func main() {
 	utils.DeduplicateWarnings()
 
-	logf.SetLogger(logger.MustNewZapLogger(logger.InfoLevel, logger.FormatJSON))
-
 	if err := app.NewControllerManagerCommand(signals.SetupSignalHandler()).Execute(); err != nil {
+		// the logger is configured by the flags, it is only set here if they
+		// couldn't be parsed
+		logf.SetLogger(logger.MustNewZapLogger(logger.InfoLevel, logger.FormatJSON))
 		logf.Log.Error(err, "Error executing the main controller command")
 		os.Exit(1)
 	}
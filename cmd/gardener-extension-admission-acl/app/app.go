@@ -88,6 +88,9 @@ func NewControllerManagerCommand(ctx context.Context) *cobra.Command {
 				return fmt.Errorf("error completing options: %w", err)
 			}
 			validator.DefaultAddOptions.MaxAllowedCIDRs = admissionOptions.Completed().MaxAllowedCIDRs
+			validator.DefaultAddOptions.InfrastructureEgressCIDRsAutoAllowed = admissionOptions.Completed().InfrastructureEgressCIDRsAutoAllowed
+			validator.DefaultAddOptions.ClientIPUserExtraKey = admissionOptions.Completed().ClientIPUserExtraKey
+			validator.DefaultAddOptions.ForbidShadowModePurposes = admissionOptions.Completed().ForbidShadowModePurposes
 
 			util.ApplyClientConnectionConfigurationToRESTConfig(&componentbaseconfig.ClientConnectionConfiguration{
 				QPS:   100.0,
@@ -13,4 +13,5 @@ import (
 	_ "k8s.io/code-generator"
 
 	_ "sigs.k8s.io/controller-runtime/tools/setup-envtest"
+	_ "sigs.k8s.io/controller-tools/cmd/controller-gen"
 )
@@ -0,0 +1,25 @@
+#!/usr/bin/env bash
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+# Generates the deepcopy, conversion and defaulting functions of the
+# component configuration API in pkg/apis.
+
+REPO_ROOT="$(cd "$(dirname "$0")/.." && pwd)"
+CODE_GEN_DIR="$(go list -m -f '{{.Dir}}' k8s.io/code-generator)"
+
+source "${CODE_GEN_DIR}/kube_codegen.sh"
+
+# the code generators expect the packages in a GOPATH layout
+VGOPATH_DIR="$(mktemp -d)"
+trap 'rm -rf "${VGOPATH_DIR}"' EXIT
+(cd "${REPO_ROOT}" && "${VGOPATH}" -o "${VGOPATH_DIR}")
+
+kube::codegen::gen_helpers \
+  --input-pkg-root github.com/stackitcloud/gardener-extension-acl/pkg/apis \
+  --output-base "${VGOPATH_DIR}/src" \
+  --extra-peer-dir github.com/gardener/gardener/extensions/pkg/apis/config/v1alpha1 \
+  --extra-peer-dir k8s.io/component-base/config/v1alpha1 \
+  --boilerplate "${REPO_ROOT}/hack/boilerplate.go.txt"
@@ -0,0 +1,207 @@
+// Package accessreview periodically writes an access review report for every
+// shoot with the ACL extension, listing all allowed CIDRs together with their
+// source, owner and age. Security officers can use the reports for the
+// regular re-certification of the ACLs.
+package accessreview
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap in the shoot namespace
+	// containing the report.
+	ConfigMapName = "acl-access-review"
+	// ReportDataKey is the key of the report in the ConfigMap.
+	ReportDataKey = "report.json"
+
+	// OwnerOperator owns the entries configured by the operator of the
+	// landscape.
+	OwnerOperator = "operator"
+	// OwnerGardener owns the entries added automatically for the shoot, e.g.
+	// its node networks.
+	OwnerGardener = "gardener"
+)
+
+// DefaultAddOptions are the default AddOptions for AddToManager.
+var DefaultAddOptions = AddOptions{}
+
+// AddOptions are options to apply when adding the reporter to the manager.
+type AddOptions struct {
+	// Interval is the interval of the reports, the reporter is disabled if it
+	// is zero.
+	Interval time.Duration
+	// Hits tracks the connections allowed by the ACL, the last hit of the
+	// shoots is unknown if it is nil.
+	Hits HitTracker
+}
+
+// HitTracker returns the last time a connection to a shoot was allowed.
+type HitTracker interface {
+	LastHit(shoot string) (time.Time, bool)
+}
+
+// Report is the access review of a single shoot.
+type Report struct {
+	// Shoot is the technical ID of the shoot.
+	Shoot string `json:"shoot"`
+	// GeneratedAt is the time the report was generated.
+	GeneratedAt metav1.Time `json:"generatedAt"`
+	// LastHit is the last time a connection to the shoot was allowed, if
+	// known. Envoy only counts the connections per shoot, not per entry.
+	LastHit *metav1.Time `json:"lastHit,omitempty"`
+	// Entries are the allowed CIDRs of the shoot.
+	Entries []Entry `json:"entries"`
+}
+
+// Entry is a single allowed CIDR of a shoot.
+type Entry struct {
+	// CIDR is the allowed CIDR.
+	CIDR string `json:"cidr"`
+	// Source is where the CIDR comes from, e.g. "rule" or "global-allowlist".
+	Source string `json:"source"`
+	// Owner is responsible for the entry, i.e. the creator of the shoot for
+	// the entries of the rule.
+	Owner string `json:"owner"`
+	// Since is the time the CIDR was first allowed.
+	Since metav1.Time `json:"since"`
+	// Age is the human readable time since the CIDR was first allowed.
+	Age string `json:"age"`
+}
+
+// Reporter writes the access review reports.
+type Reporter struct {
+	client   client.Client
+	scheme   *runtime.Scheme
+	log      logr.Logger
+	interval time.Duration
+	hits     HitTracker
+}
+
+// AddToManager adds a Reporter with the given options to the manager. Nothing
+// is added if the interval is zero.
+//
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
+func AddToManager(mgr manager.Manager, opts AddOptions) error {
+	if opts.Interval == 0 {
+		return nil
+	}
+
+	return mgr.Add(&Reporter{
+		client:   mgr.GetClient(),
+		scheme:   mgr.GetScheme(),
+		log:      mgr.GetLogger().WithName("accessreview"),
+		interval: opts.Interval,
+		hits:     opts.Hits,
+	})
+}
+
+// Start implements manager.Runnable. As a runnable of the manager, the
+// reporter is only running on the leader.
+func (r *Reporter) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, r.report, r.interval)
+	return nil
+}
+
+func (r *Reporter) report(ctx context.Context) {
+	extensions := &extensionsv1alpha1.ExtensionList{}
+	if err := controller.ListExtensions(ctx, r.client, extensions); err != nil {
+		r.log.Error(err, "Could not list extensions")
+		return
+	}
+
+	for i := range extensions.Items {
+		ex := &extensions.Items[i]
+		if ex.Spec.Type != controller.Type || !ex.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if err := r.writeReport(ctx, ex, time.Now()); err != nil {
+			r.log.Error(err, "Could not write access review", "namespace", ex.Namespace)
+		}
+	}
+}
+
+func (r *Reporter) writeReport(ctx context.Context, ex *extensionsv1alpha1.Extension, now time.Time) error {
+	cluster, err := helper.GetClusterForExtension(ctx, r.client, ex)
+	if err != nil {
+		return err
+	}
+	state, err := controller.GetExtensionState(ex)
+	if err != nil {
+		return err
+	}
+
+	report := buildReport(ex.Namespace, cluster.Shoot.Annotations[v1beta1constants.GardenCreatedBy], state, now)
+	if r.hits != nil {
+		if lastHit, ok := r.hits.LastHit(ex.Namespace); ok {
+			report.LastHit = &metav1.Time{Time: lastHit}
+		}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: ex.Namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.client, configMap, func() error {
+		configMap.Data = map[string]string{ReportDataKey: string(data)}
+		return controllerutil.SetControllerReference(ex, configMap, r.scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("could not update ConfigMap %s: %w", ConfigMapName, err)
+	}
+	return nil
+}
+
+// buildReport builds the report from the allowlist recorded in the state of
+// the extension. The entries of the rule are owned by the creator of the
+// shoot, if known.
+func buildReport(shoot, creator string, state *controller.ExtensionState, now time.Time) *Report {
+	report := &Report{
+		Shoot:       shoot,
+		GeneratedAt: metav1.NewTime(now),
+		Entries:     []Entry{},
+	}
+
+	for _, allowed := range state.Allowlist {
+		report.Entries = append(report.Entries, Entry{
+			CIDR:   allowed.CIDR,
+			Source: allowed.Source,
+			Owner:  owner(allowed.Source, creator),
+			Since:  allowed.Since,
+			Age:    duration.HumanDuration(now.Sub(allowed.Since.Time)),
+		})
+	}
+	return report
+}
+
+func owner(source, creator string) string {
+	switch source {
+	case controller.SourceRule:
+		return creator
+	case controller.SourceShoot, controller.SourceInfrastructure, controller.SourceSeedNodes, controller.SourceBastion:
+		return OwnerGardener
+	default:
+		return OwnerOperator
+	}
+}
@@ -0,0 +1,110 @@
+package accessreview
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+)
+
+type fakeHits map[string]time.Time
+
+func (f fakeHits) LastHit(shoot string) (time.Time, bool) {
+	lastHit, ok := f[shoot]
+	return lastHit, ok
+}
+
+var _ = Describe("accessreview", func() {
+	var (
+		now   = time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+		state = &controller.ExtensionState{
+			Allowlist: []controller.AllowlistEntry{
+				{CIDR: "1.2.3.4/32", Source: controller.SourceRule, Since: metav1.NewTime(now.Add(-48 * time.Hour))},
+				{CIDR: "10.250.0.0/16", Source: controller.SourceSeed, Since: metav1.NewTime(now.Add(-time.Hour))},
+				{CIDR: "10.0.0.0/8", Source: controller.SourceGlobalAllowlist, Since: metav1.NewTime(now.Add(-time.Hour))},
+				{CIDR: "10.180.0.0/16", Source: controller.SourceShoot, Since: metav1.NewTime(now.Add(-time.Hour))},
+			},
+		}
+	)
+
+	Describe("buildReport", func() {
+		It("should list all allowlist entries with their owner and age", func() {
+			report := buildReport("shoot--foo--bar", "jane.doe@example.com", state, now)
+
+			Expect(report.Shoot).To(Equal("shoot--foo--bar"))
+			Expect(report.GeneratedAt).To(Equal(metav1.NewTime(now)))
+			Expect(report.Entries).To(Equal([]Entry{
+				{CIDR: "1.2.3.4/32", Source: controller.SourceRule, Owner: "jane.doe@example.com", Since: metav1.NewTime(now.Add(-48 * time.Hour)), Age: "2d"},
+				{CIDR: "10.250.0.0/16", Source: controller.SourceSeed, Owner: OwnerOperator, Since: metav1.NewTime(now.Add(-time.Hour)), Age: "60m"},
+				{CIDR: "10.0.0.0/8", Source: controller.SourceGlobalAllowlist, Owner: OwnerOperator, Since: metav1.NewTime(now.Add(-time.Hour)), Age: "60m"},
+				{CIDR: "10.180.0.0/16", Source: controller.SourceShoot, Owner: OwnerGardener, Since: metav1.NewTime(now.Add(-time.Hour)), Age: "60m"},
+			}))
+		})
+	})
+
+	Describe("writeReport", func() {
+		It("should write the report to a ConfigMap owned by the extension", func() {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+			Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+			stateJSON, err := json.Marshal(state)
+			Expect(err).NotTo(HaveOccurred())
+			ex := &extensionsv1alpha1.Extension{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "shoot--foo--bar", Name: "acl"},
+				Spec:       extensionsv1alpha1.ExtensionSpec{DefaultSpec: extensionsv1alpha1.DefaultSpec{Type: controller.Type}},
+				Status: extensionsv1alpha1.ExtensionStatus{
+					DefaultStatus: extensionsv1alpha1.DefaultStatus{State: &runtime.RawExtension{Raw: stateJSON}},
+				},
+			}
+			cluster := &extensionsv1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "shoot--foo--bar"},
+				Spec: extensionsv1alpha1.ClusterSpec{
+					CloudProfile: runtime.RawExtension{Raw: []byte("{}")},
+					Seed:         runtime.RawExtension{Object: &gardencorev1beta1.Seed{}},
+					Shoot: runtime.RawExtension{Object: &gardencorev1beta1.Shoot{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:        "bar",
+							Annotations: map[string]string{v1beta1constants.GardenCreatedBy: "jane.doe@example.com"},
+						},
+					}},
+				},
+			}
+			c := fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(ex, cluster).Build()
+			r := &Reporter{
+				client: c,
+				scheme: scheme,
+				log:    logr.Discard(),
+				hits:   fakeHits{"shoot--foo--bar": now.Add(-time.Minute)},
+			}
+
+			Expect(r.writeReport(ctx, ex, now)).To(Succeed())
+
+			configMap := &corev1.ConfigMap{}
+			Expect(c.Get(ctx, types.NamespacedName{Namespace: "shoot--foo--bar", Name: ConfigMapName}, configMap)).To(Succeed())
+			Expect(metav1.IsControlledBy(configMap, ex)).To(BeTrue())
+
+			report := &Report{}
+			Expect(json.Unmarshal([]byte(configMap.Data[ReportDataKey]), report)).To(Succeed())
+			Expect(report.LastHit).NotTo(BeNil())
+			Expect(report.LastHit.Time.Equal(now.Add(-time.Minute))).To(BeTrue())
+			Expect(report.Entries).To(HaveLen(4))
+			Expect(report.Entries[0].Owner).To(Equal("jane.doe@example.com"))
+		})
+	})
+})
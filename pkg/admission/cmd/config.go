@@ -6,15 +6,37 @@ import (
 	controllerconfig "github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
 )
 
+// DefaultClientIPUserExtraKey is the default key of the extra information of
+// the requesting user carrying its client IP, i.e. the one of the
+// "X-Remote-Extra-Acl.stackit.cloud%2Fclient-ip" header of an authenticating
+// proxy.
+const DefaultClientIPUserExtraKey = "acl.stackit.cloud/client-ip"
+
 // AdmissionOptions are command line options that can be set for admission controller.
 type AdmissionOptions struct {
 	// MaxAllowedCIDRs is the maximum number of allowed CIDRs per cluster
 	MaxAllowedCIDRs int
+	// InfrastructureEgressCIDRsAutoAllowed is true if the extension allows the
+	// egress CIDRs of the shoots' infrastructure automatically
+	InfrastructureEgressCIDRsAutoAllowed bool
+	// ClientIPUserExtraKey is the key of the extra information of the
+	// requesting user carrying its client IP
+	ClientIPUserExtraKey string
+	// ForbidShadowModePurposes are the purposes of the shoots which can't use
+	// "LOG" rules
+	ForbidShadowModePurposes []string
 }
 
 // AddFlags implements Flagger.AddFlags.
 func (a *AdmissionOptions) AddFlags(fs *pflag.FlagSet) {
-	fs.IntVar(&a.MaxAllowedCIDRs, "maxAllowedCIDRs", 50, "maximum number of allowed CIDRs per cluster")
+	fs.IntVar(&a.MaxAllowedCIDRs, "maxAllowedCIDRs", 50, "maximum number of allowed CIDRs per cluster, including the except blocks of the rule")
+	fs.BoolVar(&a.InfrastructureEgressCIDRsAutoAllowed, "infrastructureEgressCIDRsAutoAllowed", true,
+		"whether the extension allows the egress CIDRs of the shoots' infrastructure automatically, users are warned otherwise")
+	fs.StringVar(&a.ClientIPUserExtraKey, "clientIPUserExtraKey", DefaultClientIPUserExtraKey,
+		"key of the extra information of the requesting user carrying its client IP, e.g. set by an authenticating proxy, "+
+			"users are warned if their rule denies it (empty disables the warning)")
+	fs.StringSliceVar(&a.ForbidShadowModePurposes, "forbidShadowModePurposes", nil,
+		"purposes of the shoots which can't use 'LOG' rules, has to match the purpose policies of the extension")
 }
 
 // Complete implements Completer.Complete.
@@ -0,0 +1,125 @@
+package mutator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	extensionswebhook "github.com/gardener/gardener/extensions/pkg/webhook"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/webhook"
+)
+
+// NewShootMutator returns a new instance of a shootMutator, which reads the
+// projects of the shoots with the given reader.
+func NewShootMutator(reader client.Reader) extensionswebhook.Mutator {
+	return &shootMutator{reader: reader}
+}
+
+type shootMutator struct {
+	reader client.Reader
+}
+
+// Mutate brings the providerConfig of the ACL extension of the given shoot
+// into its canonical form, see extensionspec.ExtensionSpec.Canonicalize, so
+// equal rules are written the same way and the diffs between the generations
+// of the shoot only show actual changes. Shoots without a rule inherit the
+// baseline allowlist of their project, see inheritProjectBaseline.
+func (m *shootMutator) Mutate(ctx context.Context, new, _ client.Object) error {
+	shoot, ok := new.(*gardencorev1beta1.Shoot)
+	if !ok {
+		return fmt.Errorf("wrong object type %T", new)
+	}
+
+	for i := range shoot.Spec.Extensions {
+		ext := &shoot.Spec.Extensions[i]
+		if ext.Type != webhook.ExtensionName {
+			continue
+		}
+		if err := m.inheritProjectBaseline(ctx, shoot, ext); err != nil {
+			return err
+		}
+		if ext.ProviderConfig == nil || ext.ProviderConfig.Raw == nil {
+			continue
+		}
+		raw, changed, err := canonicalProviderConfig(ext.ProviderConfig.Raw)
+		if err != nil {
+			// invalid providerConfigs are rejected by the validator
+			logger.V(1).Info("Not canonicalizing invalid providerConfig", "shoot", client.ObjectKeyFromObject(shoot), "error", err.Error())
+			continue
+		}
+		if changed {
+			ext.ProviderConfig = &runtime.RawExtension{Raw: raw}
+		}
+	}
+	return nil
+}
+
+// inheritProjectBaseline sets an "ALLOW" rule with the baseline allowlist of
+// the shoot's project if the providerConfig of its ACL extension doesn't
+// contain a rule. The baseline is copied, later changes of the project only
+// apply to shoots without a rule of their own when they are updated.
+func (m *shootMutator) inheritProjectBaseline(ctx context.Context, shoot *gardencorev1beta1.Shoot, ext *gardencorev1beta1.Extension) error {
+	spec := &extensionspec.ExtensionSpec{}
+	if ext.ProviderConfig != nil && ext.ProviderConfig.Raw != nil {
+		var err error
+		if spec, err = decodeProviderConfig(ext.ProviderConfig.Raw); err != nil {
+			// invalid providerConfigs are rejected by the validator
+			return nil
+		}
+	}
+	if spec.Rule != nil || spec.Disabled {
+		return nil
+	}
+
+	baseline, err := helper.GetProjectBaseline(ctx, m.reader, shoot.Namespace)
+	if err != nil || baseline == nil {
+		return err
+	}
+	spec.Rule = &envoyfilters.ACLRule{Action: envoyfilters.ActionAllow, Type: envoyfilters.TypeRemoteIP, Cidrs: baseline.CIDRs}
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	logger.Info("Inheriting the baseline allowlist of the project", "shoot", client.ObjectKeyFromObject(shoot), "project", baseline.Project)
+	ext.ProviderConfig = &runtime.RawExtension{Raw: raw}
+	return nil
+}
+
+// canonicalProviderConfig returns the canonical form of the given
+// providerConfig and whether it differs from it.
+func canonicalProviderConfig(raw []byte) ([]byte, bool, error) {
+	spec, err := decodeProviderConfig(raw)
+	if err != nil {
+		return nil, false, err
+	}
+	if spec.Rule == nil {
+		return raw, false, nil
+	}
+
+	spec.Canonicalize()
+	canonical, err := json.Marshal(spec)
+	if err != nil {
+		return nil, false, err
+	}
+	return canonical, !bytes.Equal(canonical, raw), nil
+}
+
+// decodeProviderConfig decodes the given providerConfig. Unknown fields are an
+// error, so they aren't dropped silently when it is encoded again.
+func decodeProviderConfig(raw []byte) (*extensionspec.ExtensionSpec, error) {
+	spec := &extensionspec.ExtensionSpec{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
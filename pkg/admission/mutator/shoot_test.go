@@ -0,0 +1,133 @@
+package mutator_test
+
+import (
+	"context"
+
+	extensionswebhook "github.com/gardener/gardener/extensions/pkg/webhook"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/admission/mutator"
+)
+
+var _ = Describe("Shoot mutator", func() {
+	var (
+		ctx = context.Background()
+
+		shootMutator extensionswebhook.Mutator
+		gardenClient client.Client
+		project      *gardencorev1beta1.Project
+		shoot        *gardencorev1beta1.Shoot
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		Expect(gardencorev1beta1.AddToScheme(scheme)).To(Succeed())
+		project = &gardencorev1beta1.Project{ObjectMeta: metav1.ObjectMeta{Name: "dev"}}
+		gardenClient = fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "garden-dev", Labels: map[string]string{v1beta1constants.ProjectName: "dev"}}},
+			project,
+		).Build()
+
+		shootMutator = mutator.NewShootMutator(gardenClient)
+		shoot = &gardencorev1beta1.Shoot{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "garden-dev"},
+			Spec: gardencorev1beta1.ShootSpec{
+				Extensions: []gardencorev1beta1.Extension{{Type: "acl"}},
+			},
+		}
+	})
+
+	mutate := func(providerConfig string) string {
+		shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(providerConfig)}
+		Expect(shootMutator.Mutate(ctx, shoot, nil)).To(Succeed())
+		return string(shoot.Spec.Extensions[0].ProviderConfig.Raw)
+	}
+
+	It("should canonicalize the providerConfig", func() {
+		Expect(mutate(`{"rule":{"action":"allow","type":"REMOTE_IP","cidrs":["2001:DB8:0::/32","10.250.0.0/16","1.2.3.0/24","10.250.0.0/16"]}}`)).To(MatchJSON(
+			`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.0/24","10.250.0.0/16","2001:db8::/32"]},"profile":"full"}`))
+	})
+
+	It("should keep the host bits unless normalizeCIDRs is set", func() {
+		Expect(mutate(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["10.1.2.3/24"]}}`)).To(MatchJSON(
+			`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["10.1.2.3/24"]},"profile":"full"}`))
+		Expect(mutate(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["10.1.2.3/24","10.1.2.0/24"]},"normalizeCIDRs":true}`)).To(MatchJSON(
+			`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["10.1.2.0/24"]},"profile":"full","normalizeCIDRs":true}`))
+	})
+
+	It("should canonicalize the internal rule and materialize the burst", func() {
+		Expect(mutate(`{"rule":{"action":"rate_limit","type":"remote_ip","cidrs":["10.0.0.0/8"],"except":["10.2.0.0/16","10.1.0.0/16"],"rateLimit":{"connectionsPerSecond":10}},` +
+			`"internalRule":{"action":"allow","type":"remote_ip","cidrs":["192.168.0.0/16","172.16.0.0/12"]},"profile":"apiserver-only"}`)).To(MatchJSON(
+			`{"rule":{"action":"RATE_LIMIT","type":"remote_ip","cidrs":["10.0.0.0/8"],"except":["10.1.0.0/16","10.2.0.0/16"],"rateLimit":{"connectionsPerSecond":10,"burst":10}},` +
+				`"internalRule":{"action":"ALLOW","type":"remote_ip","cidrs":["172.16.0.0/12","192.168.0.0/16"]},"profile":"apiserver-only"}`))
+	})
+
+	It("should not change a canonical providerConfig", func() {
+		providerConfig := `{"rule":{"cidrs":["1.2.3.0/24"],"action":"ALLOW","type":"remote_ip"},"profile":"full"}`
+		Expect(mutate(providerConfig)).To(Equal(providerConfig))
+	})
+
+	DescribeTable("should leave providerConfigs it can't canonicalize to the validator",
+		func(providerConfig string) {
+			Expect(mutate(providerConfig)).To(Equal(providerConfig))
+		},
+		Entry("invalid JSON", `{"rule":`),
+		Entry("unknown field", `{"rule":{"action":"allow","type":"remote_ip","cidrs":["1.2.3.0/24"]},"unknown":true}`),
+		Entry("no rule", `{"profile":"apiserver-only"}`),
+	)
+
+	It("should keep invalid CIDRs for the validator", func() {
+		Expect(mutate(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["foo","1.2.3.0/24"]}}`)).To(MatchJSON(
+			`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.0/24","foo"]},"profile":"full"}`))
+	})
+
+	It("should not touch the providerConfigs of other extensions", func() {
+		shoot.Spec.Extensions = append(shoot.Spec.Extensions, gardencorev1beta1.Extension{
+			Type:           "foo",
+			ProviderConfig: &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"allow"}}`)},
+		})
+		mutate(`{"rule":{"action":"allow","type":"remote_ip","cidrs":["1.2.3.0/24"]}}`)
+
+		Expect(string(shoot.Spec.Extensions[1].ProviderConfig.Raw)).To(Equal(`{"rule":{"action":"allow"}}`))
+	})
+
+	Context("project baseline", func() {
+		BeforeEach(func() {
+			project.Annotations = map[string]string{"acl.stackit.cloud/baseline-cidrs": "10.0.0.0/8,1.2.3.0/24"}
+			Expect(gardenClient.Update(ctx, project)).To(Succeed())
+		})
+
+		It("should inherit the baseline allowlist of the project without rule", func() {
+			Expect(mutate(`{"profile":"apiserver-only"}`)).To(MatchJSON(
+				`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.0/24","10.0.0.0/8"]},"profile":"apiserver-only"}`))
+		})
+
+		It("should inherit the baseline allowlist of the project without providerConfig", func() {
+			Expect(shootMutator.Mutate(ctx, shoot, nil)).To(Succeed())
+			Expect(string(shoot.Spec.Extensions[0].ProviderConfig.Raw)).To(MatchJSON(
+				`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.0/24","10.0.0.0/8"]},"profile":"full"}`))
+		})
+
+		It("should keep the rule of the shoot", func() {
+			Expect(mutate(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["10.1.0.0/16"]}}`)).To(MatchJSON(
+				`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["10.1.0.0/16"]},"profile":"full"}`))
+		})
+
+		It("should not inherit the baseline allowlist if the enforcement is disabled", func() {
+			Expect(mutate(`{"disabled":true}`)).To(Equal(`{"disabled":true}`))
+		})
+	})
+
+	It("should reject other objects", func() {
+		Expect(shootMutator.Mutate(ctx, &corev1.ConfigMap{}, nil)).To(MatchError(ContainSubstring("wrong object type")))
+	})
+})
@@ -0,0 +1,36 @@
+package mutator
+
+import (
+	extensionswebhook "github.com/gardener/gardener/extensions/pkg/webhook"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// Name is a name for a mutation webhook.
+	Name = "mutator"
+)
+
+var logger = log.Log.WithName("acl-mutator-webhook")
+
+// New creates a new webhook that mutates Shoot resources. The mutating
+// webhook must be a separate webhook, as the extensions library doesn't
+// allow mutators and validators in the same webhook. The versioned Shoot is
+// mutated, as the patch is computed from its JSON representation.
+func New(mgr manager.Manager) (*extensionswebhook.Webhook, error) {
+	logger.Info("Setting up webhook", "name", Name)
+	return extensionswebhook.New(mgr, extensionswebhook.Args{
+		Provider: "acl",
+		Name:     Name,
+		Path:     "/webhooks/mutate",
+		Mutators: map[extensionswebhook.Mutator][]extensionswebhook.Type{
+			NewShootMutator(mgr.GetClient()): {{Obj: &gardencorev1beta1.Shoot{}}},
+		},
+		Target: extensionswebhook.TargetSeed,
+		ObjectSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"extensions.extensions.gardener.cloud/acl": "true"},
+		},
+	})
+}
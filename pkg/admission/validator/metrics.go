@@ -0,0 +1,109 @@
+package validator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsNamespace = "acl"
+	metricsSubsystem = "admission"
+
+	// ReasonDecodeError is the reject reason for providerConfigs which can't be decoded.
+	ReasonDecodeError = "decode_error"
+	// ReasonTooManyCIDRs is the reject reason for rules exceeding the maximum number of CIDRs.
+	ReasonTooManyCIDRs = "too_many_cidrs"
+	// ReasonInvalidProfile is the reject reason for unsupported profiles.
+	ReasonInvalidProfile = "invalid_profile"
+	// ReasonInvalidType is the reject reason for unsupported rule types.
+	ReasonInvalidType = "invalid_type"
+	// ReasonInternalRuleRateLimit is the reject reason for "RATE_LIMIT"
+	// internal rules, which are not supported.
+	ReasonInternalRuleRateLimit = "internal_rule_rate_limit"
+	// ReasonInternalRuleLog is the reject reason for "LOG" internal rules,
+	// which are not supported.
+	ReasonInternalRuleLog = "internal_rule_log"
+	// ReasonDuplicateCIDR is the reject reason for rules containing the same
+	// network more than once.
+	ReasonDuplicateCIDR = "duplicate_cidr"
+	// ReasonOpenAccess is the reject reason for ALLOW rules matching every
+	// address without allowOpenAccess.
+	ReasonOpenAccess = "open_access"
+	// ReasonInvalidMetadata is the reject reason for rules with a too long
+	// description or invalid labels.
+	ReasonInvalidMetadata = "invalid_metadata"
+	// ReasonIPFamily is the reject reason for "ALLOW" and "RATE_LIMIT" rules
+	// of IPv6-only shoots without IPv6 CIDRs.
+	ReasonIPFamily = "ip_family"
+	// ReasonShadowModeForbidden is the reject reason for "LOG" rules of
+	// shoots whose purpose forbids the shadow mode.
+	ReasonShadowModeForbidden = "shadow_mode_forbidden"
+	// ReasonHostBits is the reject reason for CIDRs with host bits set
+	// without normalizeCIDRs.
+	ReasonHostBits = "host_bits"
+	// ReasonProjectBaseline is the reject reason for rules widening the
+	// strict baseline allowlist of the shoot's project.
+	ReasonProjectBaseline = "project_baseline"
+	// ReasonAllowRuleRemoval is the reject reason for unconfirmed updates
+	// removing the last "ALLOW" rule of the shoot.
+	ReasonAllowRuleRemoval = "allow_rule_removal"
+	// ReasonInvalidSpec is the reject reason for rules failing the remaining
+	// checks of the controller, e.g. invalid actions or CIDRs.
+	ReasonInvalidSpec = "invalid_spec"
+	// ReasonAllowAll is the warning reason for confirmed ALLOW rules matching
+	// every address.
+	ReasonAllowAll = "allow_all"
+	// ReasonBroadPrefix is the warning reason for rules allowing very broad
+	// CIDRs, e.g. a /4.
+	ReasonBroadPrefix = "broad_prefix"
+	// ReasonDenyOnly is the warning reason for DENY rules, which don't
+	// restrict any other sources.
+	ReasonDenyOnly = "deny_only"
+	// ReasonLogOnly is the warning reason for LOG rules, which neither
+	// allow nor deny any source.
+	ReasonLogOnly = "log_only"
+	// ReasonNoEgressAutoAllow is the warning reason for rules in landscapes
+	// not allowing the infrastructure egress CIDRs automatically.
+	ReasonNoEgressAutoAllow = "no_egress_auto_allow"
+	// ReasonDisabled is the warning reason for ACLs whose enforcement is
+	// disabled.
+	ReasonDisabled = "disabled"
+	// ReasonLockout is the warning reason for rules denying the client IP of
+	// the requesting user.
+	ReasonLockout = "lockout"
+)
+
+var (
+	validationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "validation_duration_seconds",
+			Help:      "Duration of the validation of Shoots with the ACL extension.",
+			Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+		},
+		[]string{"result"},
+	)
+	validationRejects = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "rejects_total",
+			Help:      "Number of Shoots rejected by the ACL validation, partitioned by reason.",
+		},
+		[]string{"reason"},
+	)
+	validationWarnings = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "warnings_total",
+			Help:      "Number of findings about admitted Shoots with the ACL extension, partitioned by reason.",
+		},
+		[]string{"reason"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(validationDuration, validationRejects, validationWarnings)
+}
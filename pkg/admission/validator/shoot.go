@@ -4,20 +4,37 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
 
 	extensionswebhook "github.com/gardener/gardener/extensions/pkg/webhook"
 	"github.com/gardener/gardener/pkg/apis/core"
+	gardencorehelper "github.com/gardener/gardener/pkg/apis/core/helper"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/webhook"
 )
 
-// NewShootValidator returns a new instance of a shootValidator.
-func NewShootValidator() extensionswebhook.Validator {
-	return &shootValidator{}
+// AnnotationConfirmAllowRuleRemoval is the annotation of a Shoot which
+// confirms an update removing the last "ALLOW" rule of its ACL if set to
+// "true".
+const AnnotationConfirmAllowRuleRemoval = "acl.stackit.cloud/confirm-allow-rule-removal"
+
+// NewShootValidator returns a new instance of a shootValidator, which reads
+// the projects of the shoots with the given reader.
+func NewShootValidator(reader client.Reader) extensionswebhook.Validator {
+	return &shootValidator{reader: reader}
 }
 
 // DefaultAddOptions are the default options to apply when adding the webhook to the manager.
@@ -26,20 +43,49 @@ var DefaultAddOptions = AddOptions{}
 // AddOptions are options to apply when adding the webhook to the manager.
 type AddOptions struct {
 	MaxAllowedCIDRs int
+	// InfrastructureEgressCIDRsAutoAllowed is true if the extension allows
+	// the egress CIDRs of the shoots' infrastructure automatically.
+	InfrastructureEgressCIDRsAutoAllowed bool
+	// ClientIPUserExtraKey is the key of the extra information of the
+	// requesting user carrying its client IP, e.g. set by an authenticating
+	// proxy via the "X-Remote-Extra-" headers. Users are warned if the rule
+	// would deny their own client IP. Empty disables the warning.
+	ClientIPUserExtraKey string
+	// ForbidShadowModePurposes are the purposes of the shoots which can't use
+	// "LOG" rules, like the purpose policies of the extension.
+	ForbidShadowModePurposes []string
 }
 
-type shootValidator struct{}
+type shootValidator struct {
+	reader client.Reader
+}
 
 // Validate validates the given shoot object.
-func (s *shootValidator) Validate(ctx context.Context, new, _ client.Object) error {
+func (s *shootValidator) Validate(ctx context.Context, new, old client.Object) error {
 	shoot, ok := new.(*core.Shoot)
 	if !ok {
 		return fmt.Errorf("wrong object type %T", new)
 	}
-	return s.validateShoot(ctx, shoot)
+
+	start := time.Now()
+	oldShoot, _ := old.(*core.Shoot)
+	err := s.validateShoot(ctx, shoot, oldShoot)
+
+	result := "allowed"
+	if err != nil {
+		result = "rejected"
+	}
+	validationDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+
+	return err
 }
 
-func (s *shootValidator) validateShoot(_ context.Context, shoot *core.Shoot) error {
+func (s *shootValidator) validateShoot(ctx context.Context, shoot, oldShoot *core.Shoot) error {
+	if err := s.validateAllowRuleRemoval(shoot, oldShoot); err != nil {
+		validationRejects.WithLabelValues(ReasonAllowRuleRemoval).Inc()
+		return err
+	}
+
 	aclExtension, extensionIndex := s.findExtension(shoot)
 	if aclExtension == nil {
 		return nil
@@ -48,20 +94,299 @@ func (s *shootValidator) validateShoot(_ context.Context, shoot *core.Shoot) err
 
 	extensionSpec, err := s.decodeExtensionSpec(aclExtension.ProviderConfig)
 	if err != nil {
+		validationRejects.WithLabelValues(ReasonDecodeError).Inc()
 		return fmt.Errorf("error decoding ACL extension spec: %w", err)
 	}
 
+	baseline, err := helper.GetProjectBaseline(ctx, s.reader, shoot.Namespace)
+	if err != nil {
+		return fmt.Errorf("error reading the baseline allowlist of the project: %w", err)
+	}
+	if baseline != nil && baseline.Strict {
+		if err := validateStrictBaseline(extensionSpec, baseline, fldPath); err != nil {
+			validationRejects.WithLabelValues(ReasonProjectBaseline).Inc()
+			return err
+		}
+	}
+
+	if extensionSpec == nil || extensionSpec.Rule == nil {
+		return nil
+	}
+	extensionSpec.Workerless = gardencorehelper.IsWorkerless(shoot)
+
+	if !extensionspec.IsValidProfile(extensionSpec.Profile) {
+		validationRejects.WithLabelValues(ReasonInvalidProfile).Inc()
+		return field.NotSupported(fldPath.Child("profile"), extensionSpec.Profile, extensionspec.Profiles())
+	}
+	if extensionSpec.ProtectIngress && !extensionSpec.HasTarget(extensionspec.TargetIngress) {
+		validationRejects.WithLabelValues(ReasonInvalidProfile).Inc()
+		return field.Invalid(fldPath.Child("protectIngress"), extensionSpec.ProtectIngress,
+			fmt.Sprintf("requires the %q profile", extensionspec.ProfileFull))
+	}
+
+	if !slices.Contains(envoyfilters.Types(), strings.ToLower(extensionSpec.Rule.Type)) {
+		validationRejects.WithLabelValues(ReasonInvalidType).Inc()
+		return field.NotSupported(fldPath.Child("rule", "type"), extensionSpec.Rule.Type, envoyfilters.Types())
+	}
+	if target, hint, unsupported := extensionSpec.UnsupportedType(); unsupported {
+		validationRejects.WithLabelValues(ReasonInvalidType).Inc()
+		return field.Invalid(fldPath.Child("rule", "type"), extensionSpec.Rule.Type,
+			fmt.Sprintf("can't be used for the %s target of the profile, %s", target, hint))
+	}
+
+	if extensionSpec.Rule.CIDRCount() > DefaultAddOptions.MaxAllowedCIDRs {
+		validationRejects.WithLabelValues(ReasonTooManyCIDRs).Inc()
+		return field.TooMany(fldPath.Child("rule", "cidrs"), extensionSpec.Rule.CIDRCount(), DefaultAddOptions.MaxAllowedCIDRs)
+	}
+
+	if cidr := envoyfilters.DuplicateCIDR(extensionSpec.Rule.Cidrs); cidr != "" {
+		validationRejects.WithLabelValues(ReasonDuplicateCIDR).Inc()
+		return field.Duplicate(fldPath.Child("rule", "cidrs"), cidr)
+	}
+
+	if strings.EqualFold(extensionSpec.Rule.Action, "ALLOW") && extensionSpec.Rule.MatchesEverything() {
+		if !extensionSpec.AllowOpenAccess {
+			validationRejects.WithLabelValues(ReasonOpenAccess).Inc()
+			return field.Forbidden(fldPath.Child("rule", "cidrs"),
+				"the rule allows access from everywhere (0.0.0.0/0 or ::/0), which makes the ACL ineffective, set allowOpenAccess to confirm")
+		}
+	}
+
+	if purpose := shootPurpose(shoot); extensionSpec.Rule.IsLog() && slices.Contains(DefaultAddOptions.ForbidShadowModePurposes, purpose) {
+		validationRejects.WithLabelValues(ReasonShadowModeForbidden).Inc()
+		return field.Forbidden(fldPath.Child("rule", "action"),
+			fmt.Sprintf("'LOG' rules only log the connections instead of enforcing the ACL, which isn't allowed for %s shoots", purpose))
+	}
+
+	if err := validateRuleMetadata(extensionSpec.Rule, fldPath.Child("rule")); err != nil {
+		return err
+	}
+
+	if err := validateInternalRule(extensionSpec, fldPath.Child("internalRule")); err != nil {
+		return err
+	}
+
+	// the remaining checks of the controller, e.g. of the action, the CIDRs
+	// and their except blocks, so that invalid rules are rejected here
+	// instead of failing the reconciliation
+	if err := controller.ValidateExtensionSpec(extensionSpec); err != nil {
+		validationRejects.WithLabelValues(ReasonInvalidSpec).Inc()
+		return field.Invalid(fldPath, string(aclExtension.ProviderConfig.Raw), err.Error())
+	}
+
+	if err := validateHostBits(extensionSpec, s.oldExtensionSpec(oldShoot), fldPath); err != nil {
+		validationRejects.WithLabelValues(ReasonHostBits).Inc()
+		return err
+	}
+
+	if err := validateIPv6Only(shoot, extensionSpec, fldPath); err != nil {
+		validationRejects.WithLabelValues(ReasonIPFamily).Inc()
+		return err
+	}
+
+	for _, warning := range riskWarnings(extensionSpec) {
+		validationWarnings.WithLabelValues(warning.reason).Inc()
+	}
+
+	return nil
+}
+
+// validateInternalRule checks the optional internal rule like the rule, it
+// must neither be a "RATE_LIMIT" nor a "LOG" rule.
+func validateInternalRule(extensionSpec *extensionspec.ExtensionSpec, fldPath *field.Path) error {
+	rule := extensionSpec.InternalRule
+	if rule == nil {
+		return nil
+	}
+
+	if rule.IsRateLimit() {
+		validationRejects.WithLabelValues(ReasonInternalRuleRateLimit).Inc()
+		return field.NotSupported(fldPath.Child("action"), rule.Action, []string{envoyfilters.ActionAllow, envoyfilters.ActionDeny})
+	}
+	if rule.IsLog() {
+		validationRejects.WithLabelValues(ReasonInternalRuleLog).Inc()
+		return field.NotSupported(fldPath.Child("action"), rule.Action, []string{envoyfilters.ActionAllow, envoyfilters.ActionDeny})
+	}
+
+	if !slices.Contains(envoyfilters.Types(), strings.ToLower(rule.Type)) {
+		validationRejects.WithLabelValues(ReasonInvalidType).Inc()
+		return field.NotSupported(fldPath.Child("type"), rule.Type, envoyfilters.Types())
+	}
+	if hint, unsupported := extensionSpec.UnsupportedInternalRuleType(); unsupported {
+		validationRejects.WithLabelValues(ReasonInvalidType).Inc()
+		return field.Invalid(fldPath.Child("type"), rule.Type,
+			fmt.Sprintf("can't be used for the %s target, %s", extensionspec.TargetAPIServer, hint))
+	}
+
+	if rule.CIDRCount() > DefaultAddOptions.MaxAllowedCIDRs {
+		validationRejects.WithLabelValues(ReasonTooManyCIDRs).Inc()
+		return field.TooMany(fldPath.Child("cidrs"), rule.CIDRCount(), DefaultAddOptions.MaxAllowedCIDRs)
+	}
+
+	if cidr := envoyfilters.DuplicateCIDR(rule.Cidrs); cidr != "" {
+		validationRejects.WithLabelValues(ReasonDuplicateCIDR).Inc()
+		return field.Duplicate(fldPath.Child("cidrs"), cidr)
+	}
+
+	if strings.EqualFold(rule.Action, envoyfilters.ActionAllow) && rule.MatchesEverything() && !extensionSpec.AllowOpenAccess {
+		validationRejects.WithLabelValues(ReasonOpenAccess).Inc()
+		return field.Forbidden(fldPath.Child("cidrs"),
+			"the rule allows access from everywhere (0.0.0.0/0 or ::/0), which makes the ACL ineffective, set allowOpenAccess to confirm")
+	}
+
+	return validateRuleMetadata(rule, fldPath)
+}
+
+// validateStrictBaseline checks that the rules of the shoot only narrow the
+// baseline allowlist of its project, i.e. that they are "ALLOW" rules whose
+// CIDRs are contained in the baseline. The enforcement of the ACL can't be
+// disabled via the providerConfig, and a rule is required, so the default
+// rules of the seed don't apply. Invalid CIDRs are left to the validation of
+// the rules.
+func validateStrictBaseline(extensionSpec *extensionspec.ExtensionSpec, baseline *helper.ProjectBaseline, fldPath *field.Path) error {
 	if extensionSpec == nil || extensionSpec.Rule == nil {
+		return field.Required(fldPath.Child("rule"),
+			fmt.Sprintf("project %s requires a rule within its baseline allowlist %v", baseline.Project, baseline.CIDRs))
+	}
+	if extensionSpec.Disabled {
+		return field.Forbidden(fldPath.Child("disabled"),
+			fmt.Sprintf("project %s doesn't allow to disable the enforcement of the ACL", baseline.Project))
+	}
+
+	allowed := envoyfilters.NewCIDRSet(baseline.CIDRs...)
+	for _, r := range []struct {
+		rule *envoyfilters.ACLRule
+		path *field.Path
+	}{
+		{extensionSpec.Rule, fldPath.Child("rule")},
+		{extensionSpec.InternalRule, fldPath.Child("internalRule")},
+	} {
+		if r.rule == nil {
+			continue
+		}
+		if !strings.EqualFold(r.rule.Action, envoyfilters.ActionAllow) {
+			return field.NotSupported(r.path.Child("action"), r.rule.Action, []string{envoyfilters.ActionAllow})
+		}
+		for i, cidr := range r.rule.Cidrs {
+			if controller.IsTemplateVariable(cidr) {
+				return field.Forbidden(r.path.Child("cidrs").Index(i), fmt.Sprintf(
+					"template variables can't be checked against the baseline allowlist of project %s", baseline.Project))
+			}
+			prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+			if err == nil && !allowed.ContainsPrefix(prefix.Masked()) {
+				return field.Forbidden(r.path.Child("cidrs").Index(i), fmt.Sprintf(
+					"%s is not contained in the baseline allowlist %v of project %s, which rules may only narrow", cidr, baseline.CIDRs, baseline.Project))
+			}
+		}
+	}
+	return nil
+}
+
+// validateHostBits rejects CIDRs and except blocks with host bits set, e.g.
+// "10.1.2.3/24", unless normalizeCIDRs is set. Envoy enforces them as their
+// network, which is easily mistaken for the single host. CIDRs contained in
+// the old spec already (in any notation) are accepted, so updates of existing
+// shoots aren't blocked.
+func validateHostBits(extensionSpec, oldExtensionSpec *extensionspec.ExtensionSpec, fldPath *field.Path) error {
+	if extensionSpec.NormalizeCIDRs {
 		return nil
 	}
+	existing := sets.New[string]()
+	if oldExtensionSpec != nil {
+		for _, rule := range []*envoyfilters.ACLRule{oldExtensionSpec.Rule, oldExtensionSpec.InternalRule} {
+			if rule != nil {
+				for _, cidr := range slices.Concat(rule.Cidrs, rule.Except) {
+					existing.Insert(envoyfilters.CanonicalCIDR(cidr))
+				}
+			}
+		}
+	}
 
-	if len(extensionSpec.Rule.Cidrs) > DefaultAddOptions.MaxAllowedCIDRs {
-		return field.TooMany(fldPath.Child("rule", "cidrs"), len(extensionSpec.Rule.Cidrs), DefaultAddOptions.MaxAllowedCIDRs)
+	if err := validateRuleHostBits(extensionSpec.Rule, existing, fldPath.Child("rule")); err != nil {
+		return err
 	}
+	if extensionSpec.InternalRule != nil {
+		return validateRuleHostBits(extensionSpec.InternalRule, existing, fldPath.Child("internalRule"))
+	}
+	return nil
+}
 
+func validateRuleHostBits(rule *envoyfilters.ACLRule, existing sets.Set[string], fldPath *field.Path) error {
+	for i, cidr := range slices.Concat(rule.Cidrs, rule.Except) {
+		masked, hostBits := envoyfilters.MaskedCIDR(cidr)
+		if !hostBits || existing.Has(envoyfilters.CanonicalCIDR(cidr)) {
+			continue
+		}
+		idxPath := fldPath.Child("cidrs").Index(i)
+		if i >= len(rule.Cidrs) {
+			idxPath = fldPath.Child("except").Index(i - len(rule.Cidrs))
+		}
+		return field.Invalid(idxPath, cidr, fmt.Sprintf(
+			"has host bits set and would be enforced as %s, use %s or set normalizeCIDRs to accept it", masked, masked))
+	}
 	return nil
 }
 
+// validateIPv6Only rejects "ALLOW" and "RATE_LIMIT" rules of IPv6-only shoots
+// which only contain IPv4 CIDRs. The nodes and clients of such shoots connect
+// via IPv6, so these rules would silently deny every connection but the ones
+// of the always allowed CIDRs.
+func validateIPv6Only(shoot *core.Shoot, extensionSpec *extensionspec.ExtensionSpec, fldPath *field.Path) error {
+	if shoot.Spec.Networking == nil || !slices.Equal(shoot.Spec.Networking.IPFamilies, []core.IPFamily{core.IPFamilyIPv6}) {
+		return nil
+	}
+
+	if lacksIPv6CIDRs(extensionSpec.Rule) {
+		return ipv6OnlyError(fldPath.Child("rule", "cidrs"), extensionSpec.Rule)
+	}
+	if extensionSpec.InternalRule != nil && lacksIPv6CIDRs(extensionSpec.InternalRule) {
+		return ipv6OnlyError(fldPath.Child("internalRule", "cidrs"), extensionSpec.InternalRule)
+	}
+	return nil
+}
+
+// lacksIPv6CIDRs returns true if the rule restricts the sources it doesn't
+// contain, but doesn't contain any IPv6 CIDR. Rules with template variables
+// are left to the controller, which knows their CIDRs.
+func lacksIPv6CIDRs(rule *envoyfilters.ACLRule) bool {
+	return rule.RestrictsOtherSources() && !slices.ContainsFunc(rule.Cidrs, controller.IsTemplateVariable) &&
+		len(helper.MissingIPFamilies(rule.Cidrs, []gardencorev1beta1.IPFamily{gardencorev1beta1.IPFamilyIPv6})) > 0
+}
+
+func ipv6OnlyError(fldPath *field.Path, rule *envoyfilters.ACLRule) *field.Error {
+	return field.Invalid(fldPath, rule.Cidrs,
+		"the shoot is IPv6-only, but the rule only contains IPv4 CIDRs, which would deny all IPv6 clients of the API server, "+
+			"add the IPv6 CIDRs of the clients (e.g. the IPv6 prefix of the corporate network) to the rule")
+}
+
+// validateRuleMetadata checks the length of the description of the rule and
+// that its labels are valid Kubernetes labels.
+func validateRuleMetadata(rule *envoyfilters.ACLRule, fldPath *field.Path) error {
+	if len(rule.Description) > envoyfilters.MaxDescriptionLength {
+		validationRejects.WithLabelValues(ReasonInvalidMetadata).Inc()
+		return field.TooLong(fldPath.Child("description"), rule.Description, envoyfilters.MaxDescriptionLength)
+	}
+
+	for _, key := range sets.List(sets.KeySet(rule.Labels)) {
+		msgs := append(validation.IsQualifiedName(key), validation.IsValidLabelValue(rule.Labels[key])...)
+		if len(msgs) > 0 {
+			validationRejects.WithLabelValues(ReasonInvalidMetadata).Inc()
+			return field.Invalid(fldPath.Child("labels").Key(key), rule.Labels[key], strings.Join(msgs, "; "))
+		}
+	}
+
+	return nil
+}
+
+// shootPurpose returns the purpose of the shoot, or "evaluation", the default
+// purpose of shoots, if none is set.
+func shootPurpose(shoot *core.Shoot) string {
+	if shoot.Spec.Purpose == nil {
+		return string(core.ShootPurposeEvaluation)
+	}
+	return string(*shoot.Spec.Purpose)
+}
+
 func (s *shootValidator) findExtension(shoot *core.Shoot) (*core.Extension, int) {
 	for i, ext := range shoot.Spec.Extensions {
 		if ext.Type == webhook.ExtensionName {
@@ -71,6 +396,79 @@ func (s *shootValidator) findExtension(shoot *core.Shoot) (*core.Extension, int)
 	return nil, 0
 }
 
+// validateAllowRuleRemoval rejects updates removing the last "ALLOW" rule of
+// the shoot, i.e. making its API server reachable from everywhere or denying
+// all but the given sources, unless the update is confirmed with the
+// AnnotationConfirmAllowRuleRemoval annotation, like the deletion of a shoot.
+// Removing or disabling the ACL extension removes the rules as well.
+func (s *shootValidator) validateAllowRuleRemoval(shoot, oldShoot *core.Shoot) error {
+	if oldShoot == nil || shoot.Annotations[AnnotationConfirmAllowRuleRemoval] == "true" {
+		return nil
+	}
+	if oldSpec := s.enforcedExtensionSpec(oldShoot); oldSpec == nil || !hasAllowRule(oldSpec) {
+		return nil
+	}
+
+	fldPath := field.NewPath("spec", "extensions")
+	aclExtension, extensionIndex := s.findExtension(shoot)
+	if aclExtension != nil {
+		if _, err := s.decodeExtensionSpec(aclExtension.ProviderConfig); err != nil {
+			// invalid providerConfigs are rejected by their validation
+			return nil
+		}
+		fldPath = fldPath.Index(extensionIndex)
+	}
+	if spec := s.enforcedExtensionSpec(shoot); spec != nil && hasAllowRule(spec) {
+		return nil
+	}
+	return field.Forbidden(fldPath, fmt.Sprintf(
+		"the update removes the last 'ALLOW' rule of the ACL, which either opens the API server to everywhere or locks out all other sources, "+
+			"annotate the shoot with %s=true to confirm", AnnotationConfirmAllowRuleRemoval))
+}
+
+// enforcedExtensionSpec returns the ExtensionSpec of the shoot, or nil if the
+// ACL extension is missing or disabled, the enforcement of the ACL is
+// disabled or the providerConfig can't be decoded.
+func (s *shootValidator) enforcedExtensionSpec(shoot *core.Shoot) *extensionspec.ExtensionSpec {
+	aclExtension, _ := s.findExtension(shoot)
+	if aclExtension == nil || (aclExtension.Disabled != nil && *aclExtension.Disabled) {
+		return nil
+	}
+	extensionSpec, err := s.decodeExtensionSpec(aclExtension.ProviderConfig)
+	if err != nil || extensionSpec.Disabled {
+		return nil
+	}
+	return extensionSpec
+}
+
+// hasAllowRule returns true if the rule or the internal rule of the spec is an
+// "ALLOW" rule which doesn't allow access from everywhere.
+func hasAllowRule(extensionSpec *extensionspec.ExtensionSpec) bool {
+	for _, rule := range []*envoyfilters.ACLRule{extensionSpec.Rule, extensionSpec.InternalRule} {
+		if rule != nil && strings.EqualFold(rule.Action, envoyfilters.ActionAllow) && !rule.MatchesEverything() {
+			return true
+		}
+	}
+	return false
+}
+
+// oldExtensionSpec returns the ExtensionSpec of the old shoot of an update, or
+// nil if there is none or it can't be decoded.
+func (s *shootValidator) oldExtensionSpec(oldShoot *core.Shoot) *extensionspec.ExtensionSpec {
+	if oldShoot == nil {
+		return nil
+	}
+	aclExtension, _ := s.findExtension(oldShoot)
+	if aclExtension == nil {
+		return nil
+	}
+	extensionSpec, err := s.decodeExtensionSpec(aclExtension.ProviderConfig)
+	if err != nil {
+		return nil
+	}
+	return extensionSpec
+}
+
 func (s *shootValidator) decodeExtensionSpec(aclExt *runtime.RawExtension) (*extensionspec.ExtensionSpec, error) {
 	extSpec := &extensionspec.ExtensionSpec{}
 
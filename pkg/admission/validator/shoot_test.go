@@ -2,15 +2,23 @@ package validator_test
 
 import (
 	"context"
+	"strings"
 
 	extensionswebhook "github.com/gardener/gardener/extensions/pkg/webhook"
 	"github.com/gardener/gardener/pkg/apis/core"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gstruct"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	"github.com/stackitcloud/gardener-extension-acl/pkg/admission/validator"
 )
@@ -22,14 +30,26 @@ var _ = Describe("Shoot validator", func() {
 		var (
 			shootValidator extensionswebhook.Validator
 
-			shoot *core.Shoot
+			gardenClient client.Client
+			project      *gardencorev1beta1.Project
+			shoot        *core.Shoot
 
 			ctx = context.Background()
 		)
 
 		BeforeEach(func() {
-			shootValidator = validator.NewShootValidator()
+			scheme := runtime.NewScheme()
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			Expect(gardencorev1beta1.AddToScheme(scheme)).To(Succeed())
+			project = &gardencorev1beta1.Project{ObjectMeta: metav1.ObjectMeta{Name: "dev"}}
+			gardenClient = fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: map[string]string{v1beta1constants.ProjectName: "dev"}}},
+				project,
+			).Build()
+
+			shootValidator = validator.NewShootValidator(gardenClient)
 			validator.DefaultAddOptions.MaxAllowedCIDRs = 5
+			validator.DefaultAddOptions.InfrastructureEgressCIDRsAutoAllowed = true
 
 			shoot = &core.Shoot{
 				ObjectMeta: metav1.ObjectMeta{
@@ -40,7 +60,7 @@ var _ = Describe("Shoot validator", func() {
 					Extensions: []core.Extension{
 						{
 							Type:           "acl",
-							ProviderConfig: &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.4/24","10.250.0.0/16"],"type":"remote_ip"}}`)},
+							ProviderConfig: &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24","10.250.0.0/16"],"type":"remote_ip"}}`)},
 						},
 					},
 				},
@@ -53,19 +73,62 @@ var _ = Describe("Shoot validator", func() {
 			})
 
 			It("should return err if to many cidrs are specified in acl extension", func() {
-				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.4/24","10.250.0.0/16","208.127.57.6/32","165.1.187.201/32","165.1.187.202/32","165.1.187.203/32","165.1.187.207/32","165.1.187.208/32"],"type":"remote_ip"}}`)}
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24","10.250.0.0/16","208.127.57.6/32","165.1.187.201/32","165.1.187.202/32","165.1.187.203/32","165.1.187.207/32","165.1.187.208/32"],"type":"remote_ip"}}`)}
 				err := shootValidator.Validate(ctx, shoot, nil)
 				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
 					"Type":  Equal(field.ErrorTypeTooMany),
 					"Field": Equal("spec.extensions[0].providerConfig.rule.cidrs"),
 				})))
 			})
+
+			It("should count the except blocks of the rule", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["10.0.0.0/8","192.168.0.0/16","172.16.0.0/12"],"except":["10.1.0.0/16","10.2.0.0/16","10.3.0.0/16"],"type":"remote_ip"}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":     Equal(field.ErrorTypeTooMany),
+					"Field":    Equal("spec.extensions[0].providerConfig.rule.cidrs"),
+					"BadValue": Equal(6),
+				})))
+			})
+
+			It("should return err if an unknown type is specified in acl extension", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"destination_ip"}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeNotSupported),
+					"Field": Equal("spec.extensions[0].providerConfig.rule.type"),
+				})))
+			})
+
+			It("should return err if the type can't be used for the targets of the profile", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"source_ip"}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":   Equal(field.ErrorTypeInvalid),
+					"Field":  Equal("spec.extensions[0].providerConfig.rule.type"),
+					"Detail": ContainSubstring("use 'remote_ip' for the same behavior"),
+				})))
+			})
+
+			It("should succeed if a supported profile is specified in acl extension", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"profile":"apiserver-only","rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip"}}`)}
+				Expect(shootValidator.Validate(ctx, shoot, nil)).To(Succeed())
+			})
+
+			It("should return err if an unsupported profile is specified in acl extension", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"profile":"vpn-only","rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip"}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeNotSupported),
+					"Field": Equal("spec.extensions[0].providerConfig.profile"),
+				})))
+			})
 		})
 
 		Context("Shoot update", func() {
 			It("should return err if to many cidrs are specified in acl extension", func() {
 				newShoot := shoot
-				newShoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.4/24","10.250.0.0/16","208.127.57.6/32","165.1.187.201/32","165.1.187.202/32","165.1.187.203/32","165.1.187.207/32","165.1.187.208/32"],"type":"remote_ip"}}`)}
+				newShoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24","10.250.0.0/16","208.127.57.6/32","165.1.187.201/32","165.1.187.202/32","165.1.187.203/32","165.1.187.207/32","165.1.187.208/32"],"type":"remote_ip"}}`)}
 				err := shootValidator.Validate(ctx, newShoot, shoot)
 				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
 					"Type":  Equal(field.ErrorTypeTooMany),
@@ -75,9 +138,426 @@ var _ = Describe("Shoot validator", func() {
 
 			It("should succeed if number of specified cidrs in acl extension is below maximum", func() {
 				newShoot := shoot
-				newShoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.4/24","10.250.0.0/16","208.127.57.6/32","165.1.187.201/32","165.1.187.202/32"],"type":"remote_ip"}}`)}
+				newShoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24","10.250.0.0/16","208.127.57.6/32","165.1.187.201/32","165.1.187.202/32"],"type":"remote_ip"}}`)}
 				Expect(shootValidator.Validate(ctx, newShoot, shoot)).To(Succeed())
 			})
 		})
+
+		Context("open access", func() {
+			It("should reject an ALLOW rule for the whole address space", func() {
+				newShoot := shoot
+				newShoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24","::/0"],"type":"remote_ip"}}`)}
+				Expect(shootValidator.Validate(ctx, newShoot, shoot)).To(MatchError(ContainSubstring("allowOpenAccess")))
+			})
+
+			It("should succeed if the open access is confirmed", func() {
+				newShoot := shoot
+				newShoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["0.0.0.0/0"],"type":"remote_ip"},"allowOpenAccess":true}`)}
+				Expect(shootValidator.Validate(ctx, newShoot, shoot)).To(Succeed())
+			})
+
+			It("should succeed for a DENY rule for the whole address space", func() {
+				newShoot := shoot
+				newShoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"DENY","cidrs":["0.0.0.0/0"],"type":"remote_ip"}}`)}
+				Expect(shootValidator.Validate(ctx, newShoot, shoot)).To(Succeed())
+			})
+		})
+
+		Context("duplicate cidrs", func() {
+			It("should reject the same network specified twice", func() {
+				newShoot := shoot
+				newShoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["10.0.0.0/8","1.2.3.4/32","10.1.2.3/8"],"type":"remote_ip"}}`)}
+				Expect(shootValidator.Validate(ctx, newShoot, shoot)).To(MatchError(ContainSubstring("Duplicate value: \"10.1.2.3/8\"")))
+			})
+
+			It("should succeed for overlapping networks", func() {
+				newShoot := shoot
+				newShoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["10.0.0.0/8","10.1.0.0/16"],"type":"remote_ip"}}`)}
+				Expect(shootValidator.Validate(ctx, newShoot, shoot)).To(Succeed())
+			})
+		})
+
+		Context("protect ingress", func() {
+			It("should succeed with the full profile", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"protectIngress":true,"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip"}}`)}
+				Expect(shootValidator.Validate(ctx, shoot, nil)).To(Succeed())
+			})
+
+			It("should reject it with the apiserver-only profile", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"profile":"apiserver-only","protectIngress":true,"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip"}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeInvalid),
+					"Field": Equal("spec.extensions[0].providerConfig.protectIngress"),
+				})))
+			})
+		})
+
+		Context("internal rule", func() {
+			It("should succeed for a valid internal rule", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip"},"internalRule":{"action":"ALLOW","cidrs":["10.0.0.0/8"],"type":"remote_ip"}}`)}
+				Expect(shootValidator.Validate(ctx, shoot, nil)).To(Succeed())
+			})
+
+			It("should reject a RATE_LIMIT internal rule", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip"},"internalRule":{"action":"RATE_LIMIT","cidrs":["10.0.0.0/8"],"type":"remote_ip","rateLimit":{"connectionsPerSecond":10}}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeNotSupported),
+					"Field": Equal("spec.extensions[0].providerConfig.internalRule.action"),
+				})))
+			})
+
+			It("should reject an internal rule for the whole address space", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip"},"internalRule":{"action":"ALLOW","cidrs":["0.0.0.0/0"],"type":"remote_ip"}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeForbidden),
+					"Field": Equal("spec.extensions[0].providerConfig.internalRule.cidrs"),
+				})))
+			})
+		})
+
+		Context("IPv6-only shoot", func() {
+			BeforeEach(func() {
+				shoot.Spec.Networking = &core.Networking{IPFamilies: []core.IPFamily{core.IPFamilyIPv6}}
+			})
+
+			It("should reject an ALLOW rule with only IPv4 CIDRs", func() {
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":   Equal(field.ErrorTypeInvalid),
+					"Field":  Equal("spec.extensions[0].providerConfig.rule.cidrs"),
+					"Detail": ContainSubstring("IPv6-only"),
+				})))
+			})
+
+			It("should reject an internal rule with only IPv4 CIDRs", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["2001:db8::/32"],"type":"remote_ip"},"internalRule":{"action":"ALLOW","cidrs":["10.0.0.0/8"],"type":"remote_ip"}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeInvalid),
+					"Field": Equal("spec.extensions[0].providerConfig.internalRule.cidrs"),
+				})))
+			})
+
+			It("should succeed for an ALLOW rule with IPv6 CIDRs", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24","2001:db8::/32"],"type":"remote_ip"}}`)}
+				Expect(shootValidator.Validate(ctx, shoot, nil)).To(Succeed())
+			})
+
+			It("should succeed for a DENY rule with only IPv4 CIDRs", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"DENY","cidrs":["1.2.3.0/24"],"type":"remote_ip"}}`)}
+				Expect(shootValidator.Validate(ctx, shoot, nil)).To(Succeed())
+			})
+		})
+
+		Context("host bits", func() {
+			It("should reject a CIDR with host bits set", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["10.250.0.0/16","10.1.2.3/24"],"type":"remote_ip"}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":     Equal(field.ErrorTypeInvalid),
+					"Field":    Equal("spec.extensions[0].providerConfig.rule.cidrs[1]"),
+					"BadValue": Equal("10.1.2.3/24"),
+					"Detail":   ContainSubstring("would be enforced as 10.1.2.0/24"),
+				})))
+			})
+
+			It("should reject an except block of the internal rule with host bits set", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip"},"internalRule":{"action":"ALLOW","cidrs":["10.0.0.0/8"],"except":["10.1.2.3/16"],"type":"remote_ip"}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeInvalid),
+					"Field": Equal("spec.extensions[0].providerConfig.internalRule.except[0]"),
+				})))
+			})
+
+			It("should succeed if normalizeCIDRs is set", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["10.1.2.3/24"],"type":"remote_ip"},"normalizeCIDRs":true}`)}
+				Expect(shootValidator.Validate(ctx, shoot, nil)).To(Succeed())
+			})
+
+			It("should succeed for CIDRs the old shoot already contained", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["10.1.2.3/24"],"type":"remote_ip"}}`)}
+				newShoot := shoot.DeepCopy()
+				newShoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["10.1.2.3/24","1.2.3.0/24"],"type":"remote_ip"}}`)}
+				Expect(shootValidator.Validate(ctx, newShoot, shoot)).To(Succeed())
+
+				newShoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["10.1.2.3/24","1.2.3.4/24"],"type":"remote_ip"}}`)}
+				Expect(shootValidator.Validate(ctx, newShoot, shoot)).To(MatchError(ContainSubstring("rule.cidrs[1]")))
+			})
+
+			It("should count the rejects", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["10.1.2.3/24"],"type":"remote_ip"}}`)}
+				before := counterValue("acl_admission_rejects_total", validator.ReasonHostBits)
+
+				Expect(shootValidator.Validate(ctx, shoot, nil)).NotTo(Succeed())
+				Expect(counterValue("acl_admission_rejects_total", validator.ReasonHostBits)).To(Equal(before + 1))
+			})
+		})
+
+		Context("purpose policies", func() {
+			BeforeEach(func() {
+				validator.DefaultAddOptions.ForbidShadowModePurposes = []string{"production"}
+				DeferCleanup(func() {
+					validator.DefaultAddOptions.ForbidShadowModePurposes = nil
+				})
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"LOG","cidrs":["1.2.3.0/24"],"type":"remote_ip"}}`)}
+			})
+
+			It("should reject a LOG rule if the shadow mode is forbidden for the shoot's purpose", func() {
+				purpose := core.ShootPurposeProduction
+				shoot.Spec.Purpose = &purpose
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeForbidden),
+					"Field": Equal("spec.extensions[0].providerConfig.rule.action"),
+				})))
+			})
+
+			It("should succeed for a LOG rule of a shoot without purpose", func() {
+				Expect(shootValidator.Validate(ctx, shoot, nil)).To(Succeed())
+			})
+		})
+
+		Context("template variables", func() {
+			It("should succeed for known variables", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24","${infrastructure.egressCIDRs}"],"type":"remote_ip"}}`)}
+				Expect(shootValidator.Validate(ctx, shoot, nil)).To(Succeed())
+			})
+
+			It("should reject unknown variables", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24","${seed.egressCIDRs}"],"type":"remote_ip"}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":   Equal(field.ErrorTypeInvalid),
+					"Detail": ContainSubstring("unknown template variable: ${seed.egressCIDRs}"),
+				})))
+			})
+		})
+
+		Context("project baseline", func() {
+			BeforeEach(func() {
+				project.Annotations = map[string]string{
+					"acl.stackit.cloud/baseline-cidrs":  "1.2.0.0/16,10.250.0.0/16",
+					"acl.stackit.cloud/baseline-strict": "true",
+				}
+				Expect(gardenClient.Update(ctx, project)).To(Succeed())
+			})
+
+			It("should succeed for a rule narrowing the baseline", func() {
+				Expect(shootValidator.Validate(ctx, shoot, nil)).To(Succeed())
+			})
+
+			It("should reject CIDRs widening the baseline", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24","1.0.0.0/8"],"type":"remote_ip"}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeForbidden),
+					"Field": Equal("spec.extensions[0].providerConfig.rule.cidrs[1]"),
+				})))
+			})
+
+			It("should reject internal rules widening the baseline", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip"},"internalRule":{"action":"ALLOW","cidrs":["192.168.0.0/16"],"type":"remote_ip"}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeForbidden),
+					"Field": Equal("spec.extensions[0].providerConfig.internalRule.cidrs[0]"),
+				})))
+			})
+
+			It("should reject rules other than ALLOW rules", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"DENY","cidrs":["1.2.3.0/24"],"type":"remote_ip"}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeNotSupported),
+					"Field": Equal("spec.extensions[0].providerConfig.rule.action"),
+				})))
+			})
+
+			It("should require a rule", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = nil
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeRequired),
+					"Field": Equal("spec.extensions[0].providerConfig.rule"),
+				})))
+			})
+
+			It("should reject disabling the enforcement", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip"},"disabled":true}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeForbidden),
+					"Field": Equal("spec.extensions[0].providerConfig.disabled"),
+				})))
+			})
+
+			It("should not restrict the rules of projects without strict baseline", func() {
+				project.Annotations["acl.stackit.cloud/baseline-strict"] = "false"
+				Expect(gardenClient.Update(ctx, project)).To(Succeed())
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"DENY","cidrs":["1.0.0.0/8"],"type":"remote_ip"}}`)}
+				Expect(shootValidator.Validate(ctx, shoot, nil)).To(Succeed())
+			})
+		})
+
+		Context("removal of the last ALLOW rule", func() {
+			var newShoot *core.Shoot
+
+			BeforeEach(func() {
+				newShoot = shoot.DeepCopy()
+			})
+
+			It("should reject replacing the ALLOW rule", func() {
+				newShoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"DENY","cidrs":["1.2.3.0/24"],"type":"remote_ip"}}`)}
+				err := shootValidator.Validate(ctx, newShoot, shoot)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":   Equal(field.ErrorTypeForbidden),
+					"Field":  Equal("spec.extensions[0]"),
+					"Detail": ContainSubstring(validator.AnnotationConfirmAllowRuleRemoval + "=true"),
+				})))
+			})
+
+			It("should reject disabling the enforcement", func() {
+				newShoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip"},"disabled":true}`)}
+				Expect(shootValidator.Validate(ctx, newShoot, shoot)).To(MatchError(ContainSubstring(validator.AnnotationConfirmAllowRuleRemoval)))
+			})
+
+			It("should reject removing the extension", func() {
+				newShoot.Spec.Extensions = nil
+				err := shootValidator.Validate(ctx, newShoot, shoot)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeForbidden),
+					"Field": Equal("spec.extensions"),
+				})))
+			})
+
+			It("should succeed with the confirmation annotation", func() {
+				newShoot.Annotations = map[string]string{validator.AnnotationConfirmAllowRuleRemoval: "true"}
+				newShoot.Spec.Extensions[0].Disabled = ptr.To(true)
+				Expect(shootValidator.Validate(ctx, newShoot, shoot)).To(Succeed())
+			})
+
+			It("should succeed if the internal rule remains", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip"},"internalRule":{"action":"ALLOW","cidrs":["10.0.0.0/8"],"type":"remote_ip"}}`)}
+				newShoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"DENY","cidrs":["1.2.3.0/24"],"type":"remote_ip"},"internalRule":{"action":"ALLOW","cidrs":["10.0.0.0/8"],"type":"remote_ip"}}`)}
+				Expect(shootValidator.Validate(ctx, newShoot, shoot)).To(Succeed())
+			})
+
+			It("should count the rejects", func() {
+				before := counterValue("acl_admission_rejects_total", validator.ReasonAllowRuleRemoval)
+				newShoot.Spec.Extensions = nil
+
+				Expect(shootValidator.Validate(ctx, newShoot, shoot)).NotTo(Succeed())
+				Expect(counterValue("acl_admission_rejects_total", validator.ReasonAllowRuleRemoval)).To(Equal(before + 1))
+			})
+		})
+
+		Context("rule metadata", func() {
+			It("should succeed with a description and labels", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip","description":"office network, see TICKET-123","labels":{"team":"platform"}}}`)}
+				Expect(shootValidator.Validate(ctx, shoot, nil)).To(Succeed())
+			})
+
+			It("should reject a too long description", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip","description":"` + strings.Repeat("a", 257) + `"}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeTooLong),
+					"Field": Equal("spec.extensions[0].providerConfig.rule.description"),
+				})))
+			})
+
+			It("should reject invalid labels of the internal rule", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip"},"internalRule":{"action":"ALLOW","cidrs":["10.0.0.0/8"],"type":"remote_ip","labels":{"team":"platform engineering"}}}`)}
+				err := shootValidator.Validate(ctx, shoot, nil)
+				Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeInvalid),
+					"Field": Equal("spec.extensions[0].providerConfig.internalRule.labels[team]"),
+				})))
+			})
+		})
+
+		Context("checks of the controller", func() {
+			DescribeTable("should reject rules the controller can't apply",
+				func(providerConfig, detail string) {
+					shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(providerConfig)}
+					err := shootValidator.Validate(ctx, shoot, nil)
+					Expect(err).To(PointTo(MatchFields(IgnoreExtras, Fields{
+						"Type":   Equal(field.ErrorTypeInvalid),
+						"Field":  Equal("spec.extensions[0].providerConfig"),
+						"Detail": ContainSubstring(detail),
+					})))
+				},
+				Entry("unknown action", `{"rule":{"action":"PERMIT","cidrs":["1.2.3.0/24"],"type":"remote_ip"}}`, "action must either be"),
+				Entry("invalid CIDR", `{"rule":{"action":"ALLOW","cidrs":["1.2.3.4/33"],"type":"remote_ip"}}`, "invalid CIDR address"),
+				Entry("except outside of the CIDRs", `{"rule":{"action":"ALLOW","cidrs":["10.0.0.0/8"],"except":["192.168.0.0/16"],"type":"remote_ip"}}`, "except CIDRs must be contained"),
+				Entry("missing rateLimit", `{"rule":{"action":"RATE_LIMIT","cidrs":["10.0.0.0/8"],"type":"remote_ip"}}`, "rateLimit must only be set"),
+				Entry("rateLimit of an ALLOW rule", `{"rule":{"action":"ALLOW","cidrs":["10.0.0.0/8"],"type":"remote_ip","rateLimit":{"connectionsPerSecond":10}}}`, "rateLimit must only be set"),
+			)
+
+			It("should count the rejects", func() {
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"PERMIT","cidrs":["1.2.3.0/24"],"type":"remote_ip"}}`)}
+				before := counterValue("acl_admission_rejects_total", validator.ReasonInvalidSpec)
+
+				Expect(shootValidator.Validate(ctx, shoot, nil)).NotTo(Succeed())
+				Expect(counterValue("acl_admission_rejects_total", validator.ReasonInvalidSpec)).To(Equal(before + 1))
+			})
+		})
+
+		Context("metrics", func() {
+			It("should count rejects per reason", func() {
+				before := counterValue("acl_admission_rejects_total", validator.ReasonTooManyCIDRs)
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24","10.250.0.0/16","208.127.57.6/32","165.1.187.201/32","165.1.187.202/32","165.1.187.203/32"],"type":"remote_ip"}}`)}
+
+				Expect(shootValidator.Validate(ctx, shoot, nil)).NotTo(Succeed())
+				Expect(counterValue("acl_admission_rejects_total", validator.ReasonTooManyCIDRs)).To(Equal(before + 1))
+			})
+
+			It("should count the findings about DENY rules", func() {
+				before := counterValue("acl_admission_warnings_total", validator.ReasonDenyOnly)
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"DENY","cidrs":["10.0.0.0/8"],"type":"remote_ip"}}`)}
+
+				Expect(shootValidator.Validate(ctx, shoot, nil)).To(Succeed())
+				Expect(counterValue("acl_admission_warnings_total", validator.ReasonDenyOnly)).To(Equal(before + 1))
+			})
+
+			It("should count the findings about disabled ACLs", func() {
+				before := counterValue("acl_admission_warnings_total", validator.ReasonDisabled)
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["1.2.3.0/24"],"type":"remote_ip"},"disabled":true}`)}
+
+				Expect(shootValidator.Validate(ctx, shoot, nil)).To(Succeed())
+				Expect(counterValue("acl_admission_warnings_total", validator.ReasonDisabled)).To(Equal(before + 1))
+			})
+
+			It("should count warnings per reason", func() {
+				before := counterValue("acl_admission_warnings_total", validator.ReasonAllowAll)
+				shoot.Spec.Extensions[0].ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"rule":{"action":"ALLOW","cidrs":["0.0.0.0/0"],"type":"remote_ip"},"allowOpenAccess":true}`)}
+
+				Expect(shootValidator.Validate(ctx, shoot, nil)).To(Succeed())
+				Expect(counterValue("acl_admission_warnings_total", validator.ReasonAllowAll)).To(Equal(before + 1))
+			})
+		})
 	})
 })
+
+func counterValue(name, reason string) float64 {
+	families, err := metrics.Registry.Gather()
+	Expect(err).NotTo(HaveOccurred())
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "reason" && label.GetValue() == reason {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
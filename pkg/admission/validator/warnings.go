@@ -0,0 +1,178 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1helper "github.com/gardener/gardener/pkg/apis/core/v1beta1/helper"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/webhook"
+)
+
+const (
+	// broadIPv4PrefixLength and broadIPv6PrefixLength are the prefix lengths
+	// below which an allowed CIDR is considered to be very broad.
+	broadIPv4PrefixLength = 8
+	broadIPv6PrefixLength = 32
+)
+
+// riskWarning is a finding about a risky, but legal ACL configuration.
+type riskWarning struct {
+	reason  string
+	message string
+}
+
+// riskWarnings returns the findings about the (already validated) ACL
+// configuration of a shoot.
+func riskWarnings(spec *extensionspec.ExtensionSpec) []riskWarning {
+	rule := spec.Rule
+	if rule == nil {
+		return nil
+	}
+
+	if spec.Disabled {
+		return []riskWarning{{ReasonDisabled, "the enforcement of the ACL is disabled, all sources can access the API server"}}
+	}
+
+	var warnings []riskWarning
+	if rule.IsLog() {
+		warnings = append(warnings, riskWarning{ReasonLogOnly, fmt.Sprintf(
+			"the ACL only logs the connections of the CIDRs of the %s rule, all sources can still access the API server", rule.Action,
+		)})
+		return warnings
+	}
+	if !rule.RestrictsOtherSources() {
+		warnings = append(warnings, riskWarning{ReasonDenyOnly, fmt.Sprintf(
+			"the ACL only denies the CIDRs of the %s rule, all other sources can still access the API server", rule.Action,
+		)})
+		return warnings
+	}
+
+	if strings.EqualFold(rule.Action, envoyfilters.ActionAllow) && rule.MatchesEverything() {
+		warnings = append(warnings, riskWarning{ReasonAllowAll, "the ACL allows access from everywhere (0.0.0.0/0 or ::/0) and is not effective"})
+	}
+
+	for _, cidr := range rule.Cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		ones, bits := network.Mask.Size()
+		threshold := broadIPv4PrefixLength
+		if bits == net.IPv6len*8 {
+			threshold = broadIPv6PrefixLength
+		}
+		if ones > 0 && ones < threshold {
+			warnings = append(warnings, riskWarning{ReasonBroadPrefix, fmt.Sprintf(
+				"the CIDR %s of the ACL is very broad, consider restricting it to the networks which need to access the API server", cidr,
+			)})
+		}
+	}
+
+	// workerless shoots have no nodes connecting from the egress CIDRs
+	if !DefaultAddOptions.InfrastructureEgressCIDRsAutoAllowed && !spec.Workerless {
+		warnings = append(warnings, riskWarning{ReasonNoEgressAutoAllow,
+			"the egress CIDRs of the shoot's infrastructure (e.g. NAT IPs) are not allowed automatically in this landscape, " +
+				"make sure the ACL allows them, otherwise the nodes of the shoot can't reach the API server via its public address",
+		})
+	}
+	return warnings
+}
+
+// lockoutWarnings returns the findings about the (already validated) rule of
+// the shoot denying the given client IPs of the requesting user, who is about
+// to lock themselves out of the shoot's API server. The always allowed CIDRs
+// of the seed are unknown here, they rarely contain the client IPs of users.
+func lockoutWarnings(spec *extensionspec.ExtensionSpec, clientIPs []net.IP) []riskWarning {
+	if spec.Rule == nil || spec.Disabled {
+		return nil
+	}
+
+	var warnings []riskWarning
+	for _, ip := range clientIPs {
+		if decision := spec.Rule.Evaluate(ip, nil); !decision.Allowed {
+			warnings = append(warnings, riskWarning{ReasonLockout, fmt.Sprintf(
+				"your client IP %s is denied by the ACL (%s), you might lock yourself out of the API server of the shoot", ip, decision.Reason,
+			)})
+		}
+	}
+	return warnings
+}
+
+// clientIPs returns the client IPs of the requesting user from the given key
+// of its extra information. The values might be lists of IPs, e.g. copied
+// from the X-Forwarded-For header, invalid entries are skipped.
+func clientIPs(userInfo authenticationv1.UserInfo, key string) []net.IP {
+	if key == "" {
+		return nil
+	}
+
+	var ips []net.IP
+	seen := map[string]bool{}
+	for _, value := range userInfo.Extra[key] {
+		for _, entry := range strings.Split(value, ",") {
+			ip := net.ParseIP(strings.TrimSpace(entry))
+			if ip == nil || seen[ip.String()] {
+				continue
+			}
+			seen[ip.String()] = true
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// NewWarningHandler returns an admission handler returning the findings about
+// the ACL configuration of admitted shoots as admission warnings, so they are
+// shown to the user, e.g. by kubectl.
+func NewWarningHandler(handler admission.Handler) admission.Handler {
+	return &warningHandler{handler: handler}
+}
+
+type warningHandler struct {
+	handler admission.Handler
+}
+
+// Handle implements admission.Handler.
+func (h *warningHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	resp := h.handler.Handle(ctx, req)
+	if !resp.Allowed || req.Operation == admissionv1.Delete || len(req.Object.Raw) == 0 {
+		return resp
+	}
+
+	shoot := &gardencorev1beta1.Shoot{}
+	if err := json.Unmarshal(req.Object.Raw, shoot); err != nil {
+		return resp
+	}
+	requesterIPs := clientIPs(req.UserInfo, DefaultAddOptions.ClientIPUserExtraKey)
+
+	for _, ext := range shoot.Spec.Extensions {
+		if ext.Type != webhook.ExtensionName || ext.ProviderConfig == nil || ext.ProviderConfig.Raw == nil {
+			continue
+		}
+		spec := &extensionspec.ExtensionSpec{}
+		if err := json.Unmarshal(ext.ProviderConfig.Raw, spec); err != nil {
+			return resp
+		}
+		spec.Workerless = v1beta1helper.IsWorkerless(shoot)
+		for _, warning := range riskWarnings(spec) {
+			resp.Warnings = append(resp.Warnings, warning.message)
+		}
+		// the client IP is only known here, so the metric is recorded here
+		// instead of during the validation
+		for _, warning := range lockoutWarnings(spec, requesterIPs) {
+			validationWarnings.WithLabelValues(warning.reason).Inc()
+			resp.Warnings = append(resp.Warnings, warning.message)
+		}
+	}
+	return resp
+}
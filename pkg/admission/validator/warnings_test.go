@@ -0,0 +1,142 @@
+package validator_test
+
+import (
+	"context"
+	"encoding/json"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/admission/validator"
+)
+
+var _ = Describe("Warning handler", func() {
+	var (
+		ctx     = context.Background()
+		handler admission.Handler
+		allowed bool
+		workers []gardencorev1beta1.Worker
+	)
+
+	BeforeEach(func() {
+		validator.DefaultAddOptions.InfrastructureEgressCIDRsAutoAllowed = true
+		validator.DefaultAddOptions.ClientIPUserExtraKey = "acl.stackit.cloud/client-ip"
+		allowed = true
+		workers = []gardencorev1beta1.Worker{{Name: "worker"}}
+		handler = validator.NewWarningHandler(admission.HandlerFunc(func(context.Context, admission.Request) admission.Response {
+			if allowed {
+				return admission.Allowed("")
+			}
+			return admission.Denied("invalid")
+		}))
+	})
+
+	request := func(providerConfig string) admission.Request {
+		shoot := &gardencorev1beta1.Shoot{
+			Spec: gardencorev1beta1.ShootSpec{
+				Provider: gardencorev1beta1.Provider{Workers: workers},
+				Extensions: []gardencorev1beta1.Extension{{
+					Type:           "acl",
+					ProviderConfig: &runtime.RawExtension{Raw: []byte(providerConfig)},
+				}},
+			},
+		}
+		raw, err := json.Marshal(shoot)
+		Expect(err).NotTo(HaveOccurred())
+
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+	}
+
+	It("should not return warnings for a regular rule", func() {
+		resp := handler.Handle(ctx, request(`{"rule":{"action":"ALLOW","cidrs":["10.250.0.0/16"],"type":"remote_ip"}}`))
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Warnings).To(BeEmpty())
+	})
+
+	It("should warn about very broad prefixes", func() {
+		resp := handler.Handle(ctx, request(`{"rule":{"action":"ALLOW","cidrs":["10.0.0.0/8","64.0.0.0/4","2000::/16"],"type":"remote_ip"}}`))
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Warnings).To(ConsistOf(ContainSubstring("64.0.0.0/4"), ContainSubstring("2000::/16")))
+	})
+
+	It("should warn about confirmed open access", func() {
+		resp := handler.Handle(ctx, request(`{"allowOpenAccess":true,"rule":{"action":"ALLOW","cidrs":["0.0.0.0/0"],"type":"remote_ip"}}`))
+		Expect(resp.Warnings).To(ConsistOf(ContainSubstring("from everywhere")))
+	})
+
+	It("should warn about DENY rules", func() {
+		resp := handler.Handle(ctx, request(`{"rule":{"action":"DENY","cidrs":["10.0.0.0/8"],"type":"remote_ip"}}`))
+		Expect(resp.Warnings).To(ConsistOf(ContainSubstring("all other sources can still access the API server")))
+	})
+
+	It("should warn if the infrastructure egress CIDRs are not allowed automatically", func() {
+		validator.DefaultAddOptions.InfrastructureEgressCIDRsAutoAllowed = false
+
+		resp := handler.Handle(ctx, request(`{"rule":{"action":"ALLOW","cidrs":["10.250.0.0/16"],"type":"remote_ip"}}`))
+		Expect(resp.Warnings).To(ConsistOf(ContainSubstring("egress CIDRs")))
+	})
+
+	It("should not warn about the infrastructure egress CIDRs of workerless shoots", func() {
+		validator.DefaultAddOptions.InfrastructureEgressCIDRsAutoAllowed = false
+		workers = nil
+
+		resp := handler.Handle(ctx, request(`{"rule":{"action":"ALLOW","cidrs":["10.250.0.0/16"],"type":"remote_ip"}}`))
+		Expect(resp.Warnings).To(BeEmpty())
+	})
+
+	Context("client IP of the requesting user", func() {
+		requestFrom := func(providerConfig string, clientIPs ...string) admission.Request {
+			req := request(providerConfig)
+			req.UserInfo.Extra = map[string]authenticationv1.ExtraValue{"acl.stackit.cloud/client-ip": clientIPs}
+			return req
+		}
+
+		It("should warn if the rule denies the client IP", func() {
+			resp := handler.Handle(ctx, requestFrom(`{"rule":{"action":"ALLOW","cidrs":["10.250.0.0/16"],"type":"remote_ip"}}`, "203.0.113.7"))
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(resp.Warnings).To(ConsistOf(And(
+				ContainSubstring("your client IP 203.0.113.7 is denied by the ACL"),
+				ContainSubstring("lock yourself out"),
+			)))
+		})
+
+		It("should warn if a DENY rule contains the client IP", func() {
+			resp := handler.Handle(ctx, requestFrom(`{"rule":{"action":"DENY","cidrs":["203.0.113.0/24"],"type":"remote_ip"}}`, "203.0.113.7"))
+			Expect(resp.Warnings).To(ContainElement(ContainSubstring("your client IP 203.0.113.7")))
+		})
+
+		It("should check every client IP of a forwarded list", func() {
+			resp := handler.Handle(ctx, requestFrom(`{"rule":{"action":"ALLOW","cidrs":["10.250.0.0/16"],"type":"remote_ip"}}`,
+				"10.250.0.1, 203.0.113.7", "invalid", "203.0.113.7"))
+			Expect(resp.Warnings).To(ConsistOf(ContainSubstring("203.0.113.7")))
+		})
+
+		It("should not warn if the rule allows the client IP", func() {
+			resp := handler.Handle(ctx, requestFrom(`{"rule":{"action":"ALLOW","cidrs":["203.0.113.0/24"],"type":"remote_ip"}}`, "203.0.113.7"))
+			Expect(resp.Warnings).To(BeEmpty())
+		})
+
+		It("should not warn without the configured key", func() {
+			validator.DefaultAddOptions.ClientIPUserExtraKey = ""
+
+			resp := handler.Handle(ctx, requestFrom(`{"rule":{"action":"ALLOW","cidrs":["10.250.0.0/16"],"type":"remote_ip"}}`, "203.0.113.7"))
+			Expect(resp.Warnings).To(BeEmpty())
+		})
+	})
+
+	It("should not add warnings to denied requests", func() {
+		allowed = false
+
+		resp := handler.Handle(ctx, request(`{"rule":{"action":"DENY","cidrs":["10.0.0.0/8"],"type":"remote_ip"}}`))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(resp.Warnings).To(BeEmpty())
+	})
+})
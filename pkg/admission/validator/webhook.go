@@ -18,16 +18,23 @@ var logger = log.Log.WithName("acl-validator-webhook")
 // New creates a new webhook that validates Shoot resources.
 func New(mgr manager.Manager) (*extensionswebhook.Webhook, error) {
 	logger.Info("Setting up webhook", "name", Name)
-	return extensionswebhook.New(mgr, extensionswebhook.Args{
+	wh, err := extensionswebhook.New(mgr, extensionswebhook.Args{
 		Provider: "acl",
 		Name:     Name,
 		Path:     "/webhooks/validate",
 		Validators: map[extensionswebhook.Validator][]extensionswebhook.Type{
-			NewShootValidator(): {{Obj: &core.Shoot{}}},
+			NewShootValidator(mgr.GetClient()): {{Obj: &core.Shoot{}}},
 		},
 		Target: extensionswebhook.TargetSeed,
 		ObjectSelector: &metav1.LabelSelector{
 			MatchLabels: map[string]string{"extensions.extensions.gardener.cloud/acl": "true"},
 		},
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the validators of the extensions library can't return warnings
+	wh.Webhook.Handler = NewWarningHandler(wh.Webhook.Handler)
+	return wh, nil
 }
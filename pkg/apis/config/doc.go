@@ -0,0 +1,6 @@
+// +k8s:deepcopy-gen=package
+// +groupName=acl.extensions.config.gardener.cloud
+
+// Package config contains the internal version of the component configuration
+// of the ACL extension.
+package config // import "github.com/stackitcloud/gardener-extension-acl/pkg/apis/config"
@@ -0,0 +1,43 @@
+// Package loader decodes the component configuration of the ACL extension.
+package loader
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/apis/config"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/apis/config/install"
+)
+
+var decoder runtime.Decoder
+
+func init() {
+	scheme := runtime.NewScheme()
+	install.Install(scheme)
+	// unknown fields are rejected, so typos in the configuration don't go
+	// unnoticed
+	decoder = serializer.NewCodecFactory(scheme, serializer.EnableStrict).UniversalDecoder()
+}
+
+// LoadFromFile reads the file and decodes its contents into a defaulted
+// ControllerConfiguration.
+func LoadFromFile(filename string) (*config.ControllerConfiguration, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Load(data)
+}
+
+// Load decodes the data into a defaulted ControllerConfiguration. The data has
+// to specify the apiVersion and kind.
+func Load(data []byte) (*config.ControllerConfiguration, error) {
+	cfg := &config.ControllerConfiguration{}
+	if err := runtime.DecodeInto(decoder, data, cfg); err != nil {
+		return nil, fmt.Errorf("could not decode controller configuration: %w", err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,171 @@
+package loader_test
+
+import (
+	"time"
+
+	extensionsconfig "github.com/gardener/gardener/extensions/pkg/apis/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	componentbaseconfig "k8s.io/component-base/config"
+	"k8s.io/utils/ptr"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/apis/config"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/apis/config/loader"
+)
+
+var _ = Describe("#Load", func() {
+	It("should default an empty configuration", func() {
+		cfg, err := loader.Load([]byte(`
+apiVersion: acl.extensions.config.gardener.cloud/v1alpha1
+kind: ControllerConfiguration
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cfg.AlwaysAllowed).To(Equal(config.AlwaysAllowedConfiguration{InfrastructureEgressCIDRs: ptr.To(true)}))
+		Expect(cfg.EnvoyFilterMode).To(Equal(config.EnvoyFilterModePerShoot))
+		Expect(cfg.EnvoyFilterDeployment).To(Equal(config.EnvoyFilterDeploymentManagedResource))
+		Expect(cfg.MaxGatewayEnvoyFilterBytes).To(Equal(2 << 20))
+		Expect(cfg.Webhook).To(Equal(config.WebhookConfiguration{
+			FailurePolicy:  ptr.To(admissionregistrationv1.Fail),
+			TimeoutSeconds: ptr.To[int32](5),
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "istio-operator-managed", Operator: metav1.LabelSelectorOpExists}},
+			},
+		}))
+		Expect(cfg.Istio).To(Equal(config.IstioConfiguration{
+			APIServerGatewayName:    "kube-apiserver",
+			IngressGatewayNamespace: "garden",
+			IngressGatewayName:      "nginx-ingress-controller",
+		}))
+		Expect(cfg.Requeue).To(Equal(config.RequeueConfiguration{
+			GatewayNotFound: &metav1.Duration{Duration: 15 * time.Second},
+			ClusterNotReady: &metav1.Duration{Duration: 15 * time.Second},
+			Conflict:        &metav1.Duration{Duration: time.Second},
+			RulesRejected:   &metav1.Duration{Duration: 10 * time.Minute},
+		}))
+		Expect(cfg.HealthCheckConfig).To(Equal(&extensionsconfig.HealthCheckConfig{SyncPeriod: metav1.Duration{Duration: 30 * time.Second}}))
+		Expect(cfg.SyncPeriod).To(BeNil())
+		Expect(cfg.LeaderElection).To(BeNil())
+	})
+
+	It("should default the leader election", func() {
+		cfg, err := loader.Load([]byte(`
+apiVersion: acl.extensions.config.gardener.cloud/v1alpha1
+kind: ControllerConfiguration
+leaderElection:
+  leaseDuration: 60s
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cfg.LeaderElection).To(Equal(&componentbaseconfig.LeaderElectionConfiguration{
+			LeaderElect:   true,
+			LeaseDuration: metav1.Duration{Duration: 60 * time.Second},
+			RenewDeadline: metav1.Duration{Duration: 10 * time.Second},
+			RetryPeriod:   metav1.Duration{Duration: 2 * time.Second},
+			ResourceLock:  "leases",
+		}))
+	})
+
+	It("should decode a complete configuration", func() {
+		cfg, err := loader.Load([]byte(`
+apiVersion: acl.extensions.config.gardener.cloud/v1alpha1
+kind: ControllerConfiguration
+alwaysAllowed:
+  cidrs:
+  - 10.250.0.0/16
+  gardenEgressCIDRs:
+  - 198.51.100.0/24
+  infrastructureEgressCIDRs: false
+  globalAllowlistConfigMap:
+    namespace: extension-acl
+    name: global-allowlist
+globalDenylistConfigMap:
+  namespace: extension-acl
+  name: global-denylist
+maxAllowedCIDRs: 50
+maxCIDRs: 500
+maxGatewayEnvoyFilterBytes: 1048576
+publishShootInfo: true
+ignoredNamespaces:
+- istio-canary-.*
+envoyFilterMode: Aggregated
+envoyFilterDeployment: Direct
+webhook:
+  failurePolicy: Ignore
+  timeoutSeconds: 10
+  namespaceSelector:
+    matchLabels:
+      gardener.cloud/role: istio-ingress
+  objectSelector:
+    matchLabels:
+      acl.stackit.cloud/webhook: "true"
+istio:
+  apiServerGatewayName: apiserver
+  ingressGatewayNamespace: istio-system
+  ingressGatewayName: ingress
+requeue:
+  gatewayNotFound: 5s
+  clusterNotReady: 30s
+  conflict: 2s
+  rulesRejected: 1h
+syncPeriod: 1h
+healthCheckConfig:
+  syncPeriod: 1m
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cfg).To(Equal(&config.ControllerConfiguration{
+			AlwaysAllowed: config.AlwaysAllowedConfiguration{
+				CIDRs:                     []string{"10.250.0.0/16"},
+				InfrastructureEgressCIDRs: ptr.To(false),
+				GlobalAllowlistConfigMap:  &config.ConfigMapReference{Namespace: "extension-acl", Name: "global-allowlist"},
+				GardenEgressCIDRs:         []string{"198.51.100.0/24"},
+			},
+			GlobalDenylistConfigMap:    &config.ConfigMapReference{Namespace: "extension-acl", Name: "global-denylist"},
+			MaxAllowedCIDRs:            50,
+			MaxCIDRs:                   500,
+			MaxGatewayEnvoyFilterBytes: 1048576,
+			PublishShootInfo:           true,
+			IgnoredNamespaces:          []string{"istio-canary-.*"},
+			EnvoyFilterMode:            config.EnvoyFilterModeAggregated,
+			EnvoyFilterDeployment:      config.EnvoyFilterDeploymentDirect,
+			Webhook: config.WebhookConfiguration{
+				FailurePolicy:     ptr.To(admissionregistrationv1.Ignore),
+				TimeoutSeconds:    ptr.To[int32](10),
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"gardener.cloud/role": "istio-ingress"}},
+				ObjectSelector:    &metav1.LabelSelector{MatchLabels: map[string]string{"acl.stackit.cloud/webhook": "true"}},
+			},
+			Istio: config.IstioConfiguration{
+				APIServerGatewayName:    "apiserver",
+				IngressGatewayNamespace: "istio-system",
+				IngressGatewayName:      "ingress",
+			},
+			Requeue: config.RequeueConfiguration{
+				GatewayNotFound: &metav1.Duration{Duration: 5 * time.Second},
+				ClusterNotReady: &metav1.Duration{Duration: 30 * time.Second},
+				Conflict:        &metav1.Duration{Duration: 2 * time.Second},
+				RulesRejected:   &metav1.Duration{Duration: time.Hour},
+			},
+			SyncPeriod:        &metav1.Duration{Duration: time.Hour},
+			HealthCheckConfig: &extensionsconfig.HealthCheckConfig{SyncPeriod: metav1.Duration{Duration: time.Minute}},
+		}))
+	})
+
+	It("should reject unknown fields", func() {
+		_, err := loader.Load([]byte(`
+apiVersion: acl.extensions.config.gardener.cloud/v1alpha1
+kind: ControllerConfiguration
+alwaysAllowed:
+  cidr:
+  - 10.250.0.0/16
+`))
+		Expect(err).To(MatchError(ContainSubstring(`unknown field "alwaysAllowed.cidr"`)))
+	})
+
+	It("should reject configurations without apiVersion and kind", func() {
+		_, err := loader.Load([]byte(`maxAllowedCIDRs: 50`))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,121 @@
+package v1alpha1
+
+import (
+	"time"
+
+	extensionsconfigv1alpha1 "github.com/gardener/gardener/extensions/pkg/apis/config/v1alpha1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	componentbaseconfigv1alpha1 "k8s.io/component-base/config/v1alpha1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// DefaultAPIServerGatewayName is the default name of the istio Gateway
+	// serving the API server in every shoot namespace.
+	DefaultAPIServerGatewayName = "kube-apiserver"
+	// DefaultIngressGatewayName is the default name of the istio Gateway
+	// serving the ingress domain of the seed.
+	DefaultIngressGatewayName = "nginx-ingress-controller"
+	// DefaultWebhookTimeoutSeconds is the default timeout of the webhook.
+	DefaultWebhookTimeoutSeconds int32 = 5
+	// DefaultMaxGatewayEnvoyFilterBytes is the default maximum size of the
+	// EnvoyFilters of an istio ingress gateway. It leaves room for the rest
+	// of the listener config below the 4 MiB gRPC message size of istiod.
+	DefaultMaxGatewayEnvoyFilterBytes = 2 << 20
+	// DefaultGatewayNotFoundRequeue is the default interval after which the
+	// reconciliation is retried while the istio Gateway of the shoot's API
+	// server doesn't exist.
+	DefaultGatewayNotFoundRequeue = 15 * time.Second
+	// DefaultClusterNotReadyRequeue is the default interval after which the
+	// reconciliation is retried while the shoot has no advertised addresses.
+	DefaultClusterNotReadyRequeue = 15 * time.Second
+	// DefaultConflictRequeue is the default interval after which the
+	// reconciliation is retried after a conflict.
+	DefaultConflictRequeue = time.Second
+	// DefaultRulesRejectedRequeue is the default interval after which the
+	// reconciliation is retried after the rules of the shoot were rejected.
+	DefaultRulesRejectedRequeue = 10 * time.Minute
+	// DefaultHealthCheckSyncPeriod is the default sync period of the health
+	// check controller.
+	DefaultHealthCheckSyncPeriod = 30 * time.Second
+	// IstioOperatorManagedLabel is set by Gardener on the namespaces of all
+	// istio ingress gateways, including the zonal and ExposureClass handler
+	// ones.
+	IstioOperatorManagedLabel = "istio-operator-managed"
+)
+
+func addDefaultingFuncs(scheme *runtime.Scheme) error {
+	return RegisterDefaults(scheme)
+}
+
+// SetDefaults_ControllerConfiguration sets defaults for the ControllerConfiguration.
+func SetDefaults_ControllerConfiguration(obj *ControllerConfiguration) {
+	if obj.AlwaysAllowed.InfrastructureEgressCIDRs == nil {
+		obj.AlwaysAllowed.InfrastructureEgressCIDRs = ptr.To(true)
+	}
+
+	if obj.MaxGatewayEnvoyFilterBytes == 0 {
+		obj.MaxGatewayEnvoyFilterBytes = DefaultMaxGatewayEnvoyFilterBytes
+	}
+
+	if obj.EnvoyFilterMode == "" {
+		obj.EnvoyFilterMode = EnvoyFilterModePerShoot
+	}
+	if obj.EnvoyFilterDeployment == "" {
+		obj.EnvoyFilterDeployment = EnvoyFilterDeploymentManagedResource
+	}
+
+	if obj.Webhook.FailurePolicy == nil {
+		obj.Webhook.FailurePolicy = ptr.To(admissionregistrationv1.Fail)
+	}
+	if obj.Webhook.TimeoutSeconds == nil {
+		obj.Webhook.TimeoutSeconds = ptr.To(DefaultWebhookTimeoutSeconds)
+	}
+	if obj.Webhook.NamespaceSelector == nil {
+		obj.Webhook.NamespaceSelector = &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: IstioOperatorManagedLabel, Operator: metav1.LabelSelectorOpExists}},
+		}
+	}
+
+	if obj.Istio.APIServerGatewayName == "" {
+		obj.Istio.APIServerGatewayName = DefaultAPIServerGatewayName
+	}
+	if obj.Istio.IngressGatewayNamespace == "" {
+		obj.Istio.IngressGatewayNamespace = v1beta1constants.GardenNamespace
+	}
+	if obj.Istio.IngressGatewayName == "" {
+		obj.Istio.IngressGatewayName = DefaultIngressGatewayName
+	}
+
+	if obj.Requeue.GatewayNotFound == nil {
+		obj.Requeue.GatewayNotFound = &metav1.Duration{Duration: DefaultGatewayNotFoundRequeue}
+	}
+	if obj.Requeue.ClusterNotReady == nil {
+		obj.Requeue.ClusterNotReady = &metav1.Duration{Duration: DefaultClusterNotReadyRequeue}
+	}
+	if obj.Requeue.Conflict == nil {
+		obj.Requeue.Conflict = &metav1.Duration{Duration: DefaultConflictRequeue}
+	}
+	if obj.Requeue.RulesRejected == nil {
+		obj.Requeue.RulesRejected = &metav1.Duration{Duration: DefaultRulesRejectedRequeue}
+	}
+
+	if obj.HealthCheckConfig == nil {
+		obj.HealthCheckConfig = &extensionsconfigv1alpha1.HealthCheckConfig{
+			SyncPeriod: metav1.Duration{Duration: DefaultHealthCheckSyncPeriod},
+		}
+	}
+
+	if obj.LeaderElection != nil {
+		// the recommended defaults still use the endpoints lock, which isn't
+		// supported anymore
+		if obj.LeaderElection.ResourceLock == "" {
+			obj.LeaderElection.ResourceLock = resourcelock.LeasesResourceLock
+		}
+		componentbaseconfigv1alpha1.RecommendedDefaultLeaderElectionConfiguration(obj.LeaderElection)
+	}
+}
@@ -0,0 +1,8 @@
+// +k8s:deepcopy-gen=package
+// +k8s:conversion-gen=github.com/stackitcloud/gardener-extension-acl/pkg/apis/config
+// +k8s:defaulter-gen=TypeMeta
+
+// Package v1alpha1 contains the v1alpha1 version of the component
+// configuration of the ACL extension.
+// +groupName=acl.extensions.config.gardener.cloud
+package v1alpha1 // import "github.com/stackitcloud/gardener-extension-acl/pkg/apis/config/v1alpha1"
@@ -0,0 +1,226 @@
+package v1alpha1
+
+import (
+	extensionsconfigv1alpha1 "github.com/gardener/gardener/extensions/pkg/apis/config/v1alpha1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	componentbaseconfigv1alpha1 "k8s.io/component-base/config/v1alpha1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ControllerConfiguration configures the ACL extension.
+type ControllerConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// AlwaysAllowed configures the sources which are always allowed for every
+	// shoot.
+	// +optional
+	AlwaysAllowed AlwaysAllowedConfiguration `json:"alwaysAllowed"`
+	// GlobalDenylistConfigMap references the ConfigMap containing CIDRs in its
+	// 'cidrs' key which are always denied for every shoot with the ACL
+	// extension, taking precedence over the shoot's rule.
+	// +optional
+	GlobalDenylistConfigMap *ConfigMapReference `json:"globalDenylistConfigMap,omitempty"`
+	// PurposePolicies are the default ACL policies of the shoots by their
+	// purpose, e.g. a baseline denylist for production shoots. Shoots of
+	// purposes without a policy get no defaults.
+	// +optional
+	PurposePolicies []PurposePolicy `json:"purposePolicies,omitempty"`
+	// MaxAllowedCIDRs is the number of CIDRs per shoot above which a warning
+	// about an oversized rule set is surfaced to the shoot (0 means no limit).
+	// +optional
+	MaxAllowedCIDRs int `json:"maxAllowedCIDRs,omitempty"`
+	// MaxCIDRs is the maximum number of CIDRs of the rule of a shoot,
+	// including its except blocks. Rules exceeding it are rejected to protect
+	// the istio ingress gateways (0 means no limit).
+	// +optional
+	MaxCIDRs int `json:"maxCIDRs,omitempty"`
+	// MaxGatewayEnvoyFilterBytes is the maximum size of the specs of all
+	// EnvoyFilters of an istio ingress gateway. Configurations of shoots
+	// exceeding it are refused, so a single shoot can't make the gateway
+	// reject the updates of all shoots. Defaults to 2 MiB.
+	// +optional
+	MaxGatewayEnvoyFilterBytes int `json:"maxGatewayEnvoyFilterBytes,omitempty"`
+	// PublishShootInfo specifies whether the rules enforced for every shoot
+	// are published in the acl-info ConfigMap in the kube-system namespace of
+	// the shoot, e.g. for cluster admins without access to the garden.
+	// Defaults to false.
+	// +optional
+	PublishShootInfo bool `json:"publishShootInfo,omitempty"`
+	// IgnoredNamespaces are regular expressions matching the whole names of
+	// namespaces which are never touched by the webhook and the controller,
+	// e.g. the ones of test gateways or canary istio installations.
+	// +optional
+	IgnoredNamespaces []string `json:"ignoredNamespaces,omitempty"`
+	// EnvoyFilterMode specifies whether the EnvoyFilters of the API server and
+	// VPN access are rendered per shoot or aggregated per istio ingress
+	// gateway. Defaults to "PerShoot".
+	// +optional
+	EnvoyFilterMode EnvoyFilterMode `json:"envoyFilterMode,omitempty"`
+	// EnvoyFilterDeployment specifies how the aggregated EnvoyFilters are
+	// deployed. The objects of the shoots are always deployed via their
+	// ManagedResources. Defaults to "ManagedResource".
+	// +optional
+	EnvoyFilterDeployment EnvoyFilterDeployment `json:"envoyFilterDeployment,omitempty"`
+	// Webhook configures the webhook adding the always allowed CIDRs to the
+	// EnvoyFilters of the shoots.
+	// +optional
+	Webhook WebhookConfiguration `json:"webhook"`
+	// Istio configures the discovery of the istio ingress gateways serving the
+	// shoots.
+	// +optional
+	Istio IstioConfiguration `json:"istio"`
+	// Requeue configures the intervals in which the reconciliation of an
+	// Extension is retried after errors of a known class, instead of the
+	// exponential backoff of the controller.
+	// +optional
+	Requeue RequeueConfiguration `json:"requeue"`
+	// SyncPeriod is the interval in which the ACL controller reconciles the
+	// extensions again, even if they didn't change (0 disables it).
+	// +optional
+	SyncPeriod *metav1.Duration `json:"syncPeriod,omitempty"`
+	// HealthCheckConfig is the config for the health check controller.
+	// +optional
+	HealthCheckConfig *extensionsconfigv1alpha1.HealthCheckConfig `json:"healthCheckConfig,omitempty"`
+	// LeaderElection configures the leader election of the extension. The
+	// resource name and namespace default to the values of the
+	// --leader-election-id and --leader-election-namespace flags.
+	// +optional
+	LeaderElection *componentbaseconfigv1alpha1.LeaderElectionConfiguration `json:"leaderElection,omitempty"`
+}
+
+// EnvoyFilterMode is the mode of rendering the EnvoyFilters of the shoots.
+type EnvoyFilterMode string
+
+const (
+	// EnvoyFilterModePerShoot renders dedicated EnvoyFilters for every shoot.
+	EnvoyFilterModePerShoot EnvoyFilterMode = "PerShoot"
+	// EnvoyFilterModeAggregated merges the EnvoyFilter patches of the API
+	// server and VPN access of all shoots into a few EnvoyFilters per istio
+	// ingress gateway, to reduce the config size pushed by istiod.
+	EnvoyFilterModeAggregated EnvoyFilterMode = "Aggregated"
+)
+
+// EnvoyFilterDeployment is the mechanism deploying the aggregated
+// EnvoyFilters.
+type EnvoyFilterDeployment string
+
+const (
+	// EnvoyFilterDeploymentManagedResource deploys the aggregated EnvoyFilters
+	// via a ManagedResource of the seed's gardener-resource-manager.
+	EnvoyFilterDeploymentManagedResource EnvoyFilterDeployment = "ManagedResource"
+	// EnvoyFilterDeploymentDirect creates the aggregated EnvoyFilters directly,
+	// as done by earlier versions of the extension.
+	EnvoyFilterDeploymentDirect EnvoyFilterDeployment = "Direct"
+)
+
+// AlwaysAllowedConfiguration configures the sources which are always allowed
+// for every shoot.
+type AlwaysAllowedConfiguration struct {
+	// CIDRs are always allowed for every shoot, e.g. the seed networks or VPN
+	// endpoints.
+	// +optional
+	CIDRs []string `json:"cidrs,omitempty"`
+	// InfrastructureEgressCIDRs specifies whether the egress CIDRs (e.g. NAT
+	// IPs) from the status of the shoot's Infrastructure are always allowed.
+	// Defaults to true.
+	// +optional
+	InfrastructureEgressCIDRs *bool `json:"infrastructureEgressCIDRs,omitempty"`
+	// GlobalAllowlistConfigMap references the ConfigMap containing CIDRs in
+	// its 'cidrs' key which are always allowed for every shoot, e.g. corporate
+	// monitoring ranges.
+	// +optional
+	GlobalAllowlistConfigMap *ConfigMapReference `json:"globalAllowlistConfigMap,omitempty"`
+	// GardenEgressCIDRs are the egress CIDRs of the garden cluster, e.g. of
+	// the dashboard, the terminal-controller and the managed bastions, which
+	// are always allowed so that operator tooling keeps working with tight
+	// allowlists of the shoots.
+	// +optional
+	GardenEgressCIDRs []string `json:"gardenEgressCIDRs,omitempty"`
+}
+
+// ConfigMapReference references a ConfigMap in the seed.
+type ConfigMapReference struct {
+	// Namespace is the namespace of the ConfigMap.
+	Namespace string `json:"namespace"`
+	// Name is the name of the ConfigMap.
+	Name string `json:"name"`
+}
+
+// PurposePolicy is the default ACL policy of the shoots of a purpose.
+type PurposePolicy struct {
+	// Purpose is the purpose of the shoots the policy applies to, e.g.
+	// "production".
+	Purpose string `json:"purpose"`
+	// DeniedCIDRs are always denied for the shoots of the purpose, like the
+	// CIDRs of the global denylist.
+	// +optional
+	DeniedCIDRs []string `json:"deniedCIDRs,omitempty"`
+	// ForbidShadowMode rejects "LOG" rules for the shoots of the purpose, so
+	// their ACL is always enforced.
+	// +optional
+	ForbidShadowMode bool `json:"forbidShadowMode,omitempty"`
+}
+
+// WebhookConfiguration configures the MutatingWebhookConfiguration registered
+// by the extension.
+type WebhookConfiguration struct {
+	// FailurePolicy is the failure policy of the webhook. Defaults to "Fail".
+	// +optional
+	FailurePolicy *admissionregistrationv1.FailurePolicyType `json:"failurePolicy,omitempty"`
+	// TimeoutSeconds is the timeout of the webhook in seconds. Defaults to 5.
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+	// NamespaceSelector selects the namespaces whose EnvoyFilters are sent to
+	// the webhook. Defaults to the namespaces of the istio ingress gateways
+	// managed by Gardener.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// ObjectSelector selects the EnvoyFilters which are sent to the webhook,
+	// e.g. the ones labelled with "acl.stackit.cloud/webhook=true" by the
+	// extension. All EnvoyFilters are sent to the webhook if unset.
+	// +optional
+	ObjectSelector *metav1.LabelSelector `json:"objectSelector,omitempty"`
+}
+
+// IstioConfiguration configures the discovery of the istio ingress gateways.
+type IstioConfiguration struct {
+	// APIServerGatewayName is the name of the istio Gateway in every shoot
+	// namespace whose selector selects the ingress gateways serving the shoot's
+	// API server. Defaults to "kube-apiserver".
+	// +optional
+	APIServerGatewayName string `json:"apiServerGatewayName,omitempty"`
+	// IngressGatewayNamespace is the namespace of the istio Gateway serving the
+	// ingress domain of the seed. Defaults to "garden".
+	// +optional
+	IngressGatewayNamespace string `json:"ingressGatewayNamespace,omitempty"`
+	// IngressGatewayName is the name of the istio Gateway serving the ingress
+	// domain of the seed. Defaults to "nginx-ingress-controller".
+	// +optional
+	IngressGatewayName string `json:"ingressGatewayName,omitempty"`
+}
+
+// RequeueConfiguration configures the intervals in which the reconciliation of
+// an Extension is retried after errors of a known class. Unset intervals use
+// the exponential backoff of the controller.
+type RequeueConfiguration struct {
+	// GatewayNotFound is the interval after which the reconciliation is
+	// retried if the istio Gateway of the shoot's API server doesn't exist
+	// yet, e.g. during the creation of the shoot. Defaults to 15s.
+	// +optional
+	GatewayNotFound *metav1.Duration `json:"gatewayNotFound,omitempty"`
+	// ClusterNotReady is the interval after which the reconciliation is
+	// retried if the shoot has no advertised addresses yet. Defaults to 15s.
+	// +optional
+	ClusterNotReady *metav1.Duration `json:"clusterNotReady,omitempty"`
+	// Conflict is the interval after which the reconciliation is retried if
+	// an object was modified concurrently. Defaults to 1s.
+	// +optional
+	Conflict *metav1.Duration `json:"conflict,omitempty"`
+	// RulesRejected is the interval after which the reconciliation is retried
+	// if the rules of the shoot are invalid. They are only fixed by updating
+	// the shoot, which triggers a reconciliation anyway. Defaults to 10m.
+	// +optional
+	RulesRejected *metav1.Duration `json:"rulesRejected,omitempty"`
+}
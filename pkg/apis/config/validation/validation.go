@@ -0,0 +1,196 @@
+// Package validation validates the component configuration of the ACL
+// extension.
+package validation
+
+import (
+	"net"
+	"slices"
+	"time"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	componentbaseconfig "k8s.io/component-base/config"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/apis/config"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+// maxWebhookTimeoutSeconds is the maximum timeout of admission webhooks
+// accepted by the API server.
+const maxWebhookTimeoutSeconds = 30
+
+// ValidateControllerConfiguration validates the ControllerConfiguration.
+func ValidateControllerConfiguration(cfg *config.ControllerConfiguration) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	alwaysAllowedPath := field.NewPath("alwaysAllowed")
+	for i, cidr := range cfg.AlwaysAllowed.CIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			allErrs = append(allErrs, field.Invalid(alwaysAllowedPath.Child("cidrs").Index(i), cidr, err.Error()))
+		}
+	}
+	for i, cidr := range cfg.AlwaysAllowed.GardenEgressCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			allErrs = append(allErrs, field.Invalid(alwaysAllowedPath.Child("gardenEgressCIDRs").Index(i), cidr, err.Error()))
+		}
+	}
+	allErrs = append(allErrs, validateConfigMapReference(cfg.AlwaysAllowed.GlobalAllowlistConfigMap, alwaysAllowedPath.Child("globalAllowlistConfigMap"))...)
+	allErrs = append(allErrs, validateConfigMapReference(cfg.GlobalDenylistConfigMap, field.NewPath("globalDenylistConfigMap"))...)
+	allErrs = append(allErrs, validatePurposePolicies(cfg.PurposePolicies, field.NewPath("purposePolicies"))...)
+
+	if cfg.MaxAllowedCIDRs < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("maxAllowedCIDRs"), cfg.MaxAllowedCIDRs, "must not be negative"))
+	}
+	if cfg.MaxCIDRs < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("maxCIDRs"), cfg.MaxCIDRs, "must not be negative"))
+	}
+	if cfg.MaxCIDRs > 0 && cfg.MaxAllowedCIDRs > cfg.MaxCIDRs {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("maxAllowedCIDRs"), cfg.MaxAllowedCIDRs, "must not exceed maxCIDRs"))
+	}
+	if cfg.MaxGatewayEnvoyFilterBytes < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("maxGatewayEnvoyFilterBytes"), cfg.MaxGatewayEnvoyFilterBytes, "must not be negative"))
+	}
+
+	for i, pattern := range cfg.IgnoredNamespaces {
+		if _, err := helper.CompileNamespacePatterns([]string{pattern}); err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("ignoredNamespaces").Index(i), pattern, err.Error()))
+		}
+	}
+
+	if cfg.EnvoyFilterMode != config.EnvoyFilterModePerShoot && cfg.EnvoyFilterMode != config.EnvoyFilterModeAggregated {
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("envoyFilterMode"), cfg.EnvoyFilterMode,
+			[]string{string(config.EnvoyFilterModePerShoot), string(config.EnvoyFilterModeAggregated)}))
+	}
+	if cfg.EnvoyFilterDeployment != config.EnvoyFilterDeploymentManagedResource && cfg.EnvoyFilterDeployment != config.EnvoyFilterDeploymentDirect {
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("envoyFilterDeployment"), cfg.EnvoyFilterDeployment,
+			[]string{string(config.EnvoyFilterDeploymentManagedResource), string(config.EnvoyFilterDeploymentDirect)}))
+	}
+
+	webhookPath := field.NewPath("webhook")
+	if policy := cfg.Webhook.FailurePolicy; policy != nil && *policy != admissionregistrationv1.Fail && *policy != admissionregistrationv1.Ignore {
+		allErrs = append(allErrs, field.NotSupported(webhookPath.Child("failurePolicy"), *policy, []string{string(admissionregistrationv1.Fail), string(admissionregistrationv1.Ignore)}))
+	}
+	if timeout := cfg.Webhook.TimeoutSeconds; timeout != nil && (*timeout < 1 || *timeout > maxWebhookTimeoutSeconds) {
+		allErrs = append(allErrs, field.Invalid(webhookPath.Child("timeoutSeconds"), *timeout, "must be between 1 and 30 seconds"))
+	}
+	allErrs = append(allErrs, metav1validation.ValidateLabelSelector(cfg.Webhook.NamespaceSelector, metav1validation.LabelSelectorValidationOptions{}, webhookPath.Child("namespaceSelector"))...)
+	allErrs = append(allErrs, metav1validation.ValidateLabelSelector(cfg.Webhook.ObjectSelector, metav1validation.LabelSelectorValidationOptions{}, webhookPath.Child("objectSelector"))...)
+
+	istioPath := field.NewPath("istio")
+	if cfg.Istio.APIServerGatewayName == "" {
+		allErrs = append(allErrs, field.Required(istioPath.Child("apiServerGatewayName"), "must be set"))
+	}
+	if cfg.Istio.IngressGatewayNamespace == "" {
+		allErrs = append(allErrs, field.Required(istioPath.Child("ingressGatewayNamespace"), "must be set"))
+	}
+	if cfg.Istio.IngressGatewayName == "" {
+		allErrs = append(allErrs, field.Required(istioPath.Child("ingressGatewayName"), "must be set"))
+	}
+
+	requeuePath := field.NewPath("requeue")
+	for name, period := range map[string]*metav1.Duration{
+		"gatewayNotFound": cfg.Requeue.GatewayNotFound,
+		"clusterNotReady": cfg.Requeue.ClusterNotReady,
+		"conflict":        cfg.Requeue.Conflict,
+		"rulesRejected":   cfg.Requeue.RulesRejected,
+	} {
+		if period != nil && period.Duration < 0 {
+			allErrs = append(allErrs, field.Invalid(requeuePath.Child(name), period.Duration.String(), "must not be negative"))
+		}
+	}
+
+	if cfg.SyncPeriod != nil && cfg.SyncPeriod.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("syncPeriod"), cfg.SyncPeriod.Duration.String(), "must not be negative"))
+	}
+	if cfg.HealthCheckConfig != nil && cfg.HealthCheckConfig.SyncPeriod.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("healthCheckConfig", "syncPeriod"), cfg.HealthCheckConfig.SyncPeriod.Duration.String(), "must be positive"))
+	}
+
+	if cfg.LeaderElection != nil {
+		allErrs = append(allErrs, validateLeaderElection(cfg.LeaderElection, field.NewPath("leaderElection"))...)
+	}
+
+	return allErrs
+}
+
+// validateLeaderElection validates the leader election like the
+// component-base validation, but the resource name and namespace are optional
+// as they default to the flags.
+func validateLeaderElection(leaderElection *componentbaseconfig.LeaderElectionConfiguration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if !leaderElection.LeaderElect {
+		return allErrs
+	}
+
+	for name, duration := range map[string]time.Duration{
+		"leaseDuration": leaderElection.LeaseDuration.Duration,
+		"renewDeadline": leaderElection.RenewDeadline.Duration,
+		"retryPeriod":   leaderElection.RetryPeriod.Duration,
+	} {
+		if duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child(name), duration.String(), "must be positive"))
+		}
+	}
+	if leaderElection.LeaseDuration.Duration <= leaderElection.RenewDeadline.Duration {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("leaseDuration"), leaderElection.LeaseDuration.Duration.String(), "must be greater than the renew deadline"))
+	}
+	if leaderElection.RenewDeadline.Duration <= leaderElection.RetryPeriod.Duration {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("renewDeadline"), leaderElection.RenewDeadline.Duration.String(), "must be greater than the retry period"))
+	}
+	if leaderElection.ResourceLock != resourcelock.LeasesResourceLock {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("resourceLock"), leaderElection.ResourceLock, []string{resourcelock.LeasesResourceLock}))
+	}
+	return allErrs
+}
+
+// shootPurposes are the purposes of shoots supported by Gardener.
+var shootPurposes = []string{
+	string(gardencorev1beta1.ShootPurposeEvaluation),
+	string(gardencorev1beta1.ShootPurposeTesting),
+	string(gardencorev1beta1.ShootPurposeDevelopment),
+	string(gardencorev1beta1.ShootPurposeInfrastructure),
+	string(gardencorev1beta1.ShootPurposeProduction),
+}
+
+func validatePurposePolicies(policies []config.PurposePolicy, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	purposes := sets.New[string]()
+
+	for i, policy := range policies {
+		idxPath := fldPath.Index(i)
+		switch {
+		case !slices.Contains(shootPurposes, policy.Purpose):
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("purpose"), policy.Purpose, shootPurposes))
+		case purposes.Has(policy.Purpose):
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("purpose"), policy.Purpose))
+		}
+		purposes.Insert(policy.Purpose)
+
+		for j, cidr := range policy.DeniedCIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("deniedCIDRs").Index(j), cidr, err.Error()))
+			}
+		}
+	}
+	return allErrs
+}
+
+func validateConfigMapReference(ref *config.ConfigMapReference, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if ref == nil {
+		return allErrs
+	}
+
+	if ref.Namespace == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("namespace"), "must be set"))
+	}
+	if ref.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), "must be set"))
+	}
+	return allErrs
+}
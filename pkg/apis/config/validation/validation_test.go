@@ -0,0 +1,298 @@
+package validation_test
+
+import (
+	"time"
+
+	extensionsconfig "github.com/gardener/gardener/extensions/pkg/apis/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	componentbaseconfig "k8s.io/component-base/config"
+	"k8s.io/utils/ptr"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/apis/config"
+	. "github.com/stackitcloud/gardener-extension-acl/pkg/apis/config/validation"
+)
+
+var _ = Describe("#ValidateControllerConfiguration", func() {
+	var cfg *config.ControllerConfiguration
+
+	BeforeEach(func() {
+		cfg = &config.ControllerConfiguration{
+			AlwaysAllowed: config.AlwaysAllowedConfiguration{
+				CIDRs:                     []string{"10.250.0.0/16"},
+				InfrastructureEgressCIDRs: ptr.To(true),
+				GlobalAllowlistConfigMap:  &config.ConfigMapReference{Namespace: "extension-acl", Name: "global-allowlist"},
+			},
+			GlobalDenylistConfigMap: &config.ConfigMapReference{Namespace: "extension-acl", Name: "global-denylist"},
+			EnvoyFilterMode:         config.EnvoyFilterModePerShoot,
+			EnvoyFilterDeployment:   config.EnvoyFilterDeploymentManagedResource,
+			Webhook: config.WebhookConfiguration{
+				FailurePolicy:  ptr.To(admissionregistrationv1.Fail),
+				TimeoutSeconds: ptr.To[int32](5),
+			},
+			Istio: config.IstioConfiguration{
+				APIServerGatewayName:    "kube-apiserver",
+				IngressGatewayNamespace: "garden",
+				IngressGatewayName:      "nginx-ingress-controller",
+			},
+			HealthCheckConfig: &extensionsconfig.HealthCheckConfig{SyncPeriod: metav1.Duration{Duration: 30 * time.Second}},
+		}
+	})
+
+	It("should accept a valid configuration", func() {
+		Expect(ValidateControllerConfiguration(cfg)).To(BeEmpty())
+	})
+
+	It("should reject invalid always allowed CIDRs", func() {
+		cfg.AlwaysAllowed.CIDRs = append(cfg.AlwaysAllowed.CIDRs, "10.250.0.0")
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Type":  Equal(field.ErrorTypeInvalid),
+			"Field": Equal("alwaysAllowed.cidrs[1]"),
+		}))))
+	})
+
+	It("should reject invalid garden egress CIDRs", func() {
+		cfg.AlwaysAllowed.GardenEgressCIDRs = []string{"198.51.100.0/24", "dashboard"}
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Type":  Equal(field.ErrorTypeInvalid),
+			"Field": Equal("alwaysAllowed.gardenEgressCIDRs[1]"),
+		}))))
+	})
+
+	It("should reject incomplete ConfigMap references", func() {
+		cfg.AlwaysAllowed.GlobalAllowlistConfigMap.Namespace = ""
+		cfg.GlobalDenylistConfigMap.Name = ""
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeRequired),
+				"Field": Equal("alwaysAllowed.globalAllowlistConfigMap.namespace"),
+			})),
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeRequired),
+				"Field": Equal("globalDenylistConfigMap.name"),
+			})),
+		))
+	})
+
+	It("should accept purpose policies", func() {
+		cfg.PurposePolicies = []config.PurposePolicy{
+			{Purpose: "production", DeniedCIDRs: []string{"198.51.100.0/24"}, ForbidShadowMode: true},
+			{Purpose: "evaluation"},
+		}
+
+		Expect(ValidateControllerConfiguration(cfg)).To(BeEmpty())
+	})
+
+	It("should reject invalid purpose policies", func() {
+		cfg.PurposePolicies = []config.PurposePolicy{
+			{Purpose: "production", DeniedCIDRs: []string{"198.51.100.0"}},
+			{Purpose: "production"},
+			{Purpose: "staging"},
+		}
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeInvalid),
+				"Field": Equal("purposePolicies[0].deniedCIDRs[0]"),
+			})),
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeDuplicate),
+				"Field": Equal("purposePolicies[1].purpose"),
+			})),
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeNotSupported),
+				"Field": Equal("purposePolicies[2].purpose"),
+			})),
+		))
+	})
+
+	It("should reject a negative number of max allowed CIDRs", func() {
+		cfg.MaxAllowedCIDRs = -1
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Type":  Equal(field.ErrorTypeInvalid),
+			"Field": Equal("maxAllowedCIDRs"),
+		}))))
+	})
+
+	It("should reject a negative number of max CIDRs", func() {
+		cfg.MaxCIDRs = -1
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Type":  Equal(field.ErrorTypeInvalid),
+			"Field": Equal("maxCIDRs"),
+		}))))
+	})
+
+	It("should reject a negative max size of the EnvoyFilters of a gateway", func() {
+		cfg.MaxGatewayEnvoyFilterBytes = -1
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Type":  Equal(field.ErrorTypeInvalid),
+			"Field": Equal("maxGatewayEnvoyFilterBytes"),
+		}))))
+	})
+
+	It("should reject invalid ignored namespaces", func() {
+		cfg.IgnoredNamespaces = []string{"istio-canary-.*", "istio-(test"}
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Type":  Equal(field.ErrorTypeInvalid),
+			"Field": Equal("ignoredNamespaces[1]"),
+		}))))
+	})
+
+	It("should reject a warning threshold above the max CIDRs", func() {
+		cfg.MaxAllowedCIDRs = 100
+		cfg.MaxCIDRs = 50
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Type":  Equal(field.ErrorTypeInvalid),
+			"Field": Equal("maxAllowedCIDRs"),
+		}))))
+	})
+
+	It("should reject an unknown EnvoyFilter mode", func() {
+		cfg.EnvoyFilterMode = "Merged"
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Type":  Equal(field.ErrorTypeNotSupported),
+			"Field": Equal("envoyFilterMode"),
+		}))))
+	})
+
+	It("should reject an unknown EnvoyFilter deployment", func() {
+		cfg.EnvoyFilterDeployment = "Helm"
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Type":  Equal(field.ErrorTypeNotSupported),
+			"Field": Equal("envoyFilterDeployment"),
+		}))))
+	})
+
+	It("should reject invalid webhook settings", func() {
+		cfg.Webhook.FailurePolicy = ptr.To[admissionregistrationv1.FailurePolicyType]("Retry")
+		cfg.Webhook.TimeoutSeconds = ptr.To[int32](31)
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeNotSupported),
+				"Field": Equal("webhook.failurePolicy"),
+			})),
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeInvalid),
+				"Field": Equal("webhook.timeoutSeconds"),
+			})),
+		))
+	})
+
+	It("should reject invalid webhook selectors", func() {
+		cfg.Webhook.NamespaceSelector = &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "istio-operator-managed", Operator: "Matches"}},
+		}
+		cfg.Webhook.ObjectSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"acl.stackit.cloud/webhook": "not a valid value"}}
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Field": HavePrefix("webhook.namespaceSelector"),
+			})),
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Field": HavePrefix("webhook.objectSelector"),
+			})),
+		))
+	})
+
+	It("should require the istio gateways", func() {
+		cfg.Istio = config.IstioConfiguration{}
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(
+			PointTo(MatchFields(IgnoreExtras, Fields{"Field": Equal("istio.apiServerGatewayName")})),
+			PointTo(MatchFields(IgnoreExtras, Fields{"Field": Equal("istio.ingressGatewayNamespace")})),
+			PointTo(MatchFields(IgnoreExtras, Fields{"Field": Equal("istio.ingressGatewayName")})),
+		))
+	})
+
+	Describe("leader election", func() {
+		BeforeEach(func() {
+			cfg.LeaderElection = &componentbaseconfig.LeaderElectionConfiguration{
+				LeaderElect:   true,
+				LeaseDuration: metav1.Duration{Duration: 60 * time.Second},
+				RenewDeadline: metav1.Duration{Duration: 40 * time.Second},
+				RetryPeriod:   metav1.Duration{Duration: 5 * time.Second},
+				ResourceLock:  "leases",
+			}
+		})
+
+		It("should accept a leader election without resource name and namespace", func() {
+			Expect(ValidateControllerConfiguration(cfg)).To(BeEmpty())
+		})
+
+		It("should reject inconsistent durations", func() {
+			cfg.LeaderElection.RenewDeadline.Duration = 60 * time.Second
+			cfg.LeaderElection.RetryPeriod.Duration = 0
+
+			Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(
+				PointTo(MatchFields(IgnoreExtras, Fields{"Field": Equal("leaderElection.retryPeriod")})),
+				PointTo(MatchFields(IgnoreExtras, Fields{"Field": Equal("leaderElection.leaseDuration")})),
+			))
+		})
+
+		It("should reject other resource locks than leases", func() {
+			cfg.LeaderElection.ResourceLock = "endpoints"
+
+			Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeNotSupported),
+				"Field": Equal("leaderElection.resourceLock"),
+			}))))
+		})
+
+		It("should not validate a disabled leader election", func() {
+			cfg.LeaderElection = &componentbaseconfig.LeaderElectionConfiguration{}
+
+			Expect(ValidateControllerConfiguration(cfg)).To(BeEmpty())
+		})
+	})
+
+	It("should reject negative requeue intervals", func() {
+		cfg.Requeue = config.RequeueConfiguration{
+			GatewayNotFound: &metav1.Duration{Duration: -time.Second},
+			Conflict:        &metav1.Duration{},
+		}
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Type":  Equal(field.ErrorTypeInvalid),
+			"Field": Equal("requeue.gatewayNotFound"),
+		}))))
+	})
+
+	It("should reject a negative sync period", func() {
+		cfg.SyncPeriod = &metav1.Duration{Duration: -time.Minute}
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Type":  Equal(field.ErrorTypeInvalid),
+			"Field": Equal("syncPeriod"),
+		}))))
+	})
+
+	It("should allow disabling the sync period", func() {
+		cfg.SyncPeriod = &metav1.Duration{}
+
+		Expect(ValidateControllerConfiguration(cfg)).To(BeEmpty())
+	})
+
+	It("should reject a non-positive healthcheck sync period", func() {
+		cfg.HealthCheckConfig.SyncPeriod.Duration = 0
+
+		Expect(ValidateControllerConfiguration(cfg)).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Type":  Equal(field.ErrorTypeInvalid),
+			"Field": Equal("healthCheckConfig.syncPeriod"),
+		}))))
+	})
+})
@@ -0,0 +1,163 @@
+// Package authorizationpolicies builds istio AuthorizationPolicy specs
+// enforcing ACL rules, as an alternative to the low-level EnvoyFilter patches
+// of the envoyfilters package.
+//
+// AuthorizationPolicies are selected per gateway and can't be inserted into a
+// specific filter chain, so every policy uses action DENY and is scoped to the
+// traffic of a single shoot via a condition (e.g. on the SNI). This makes sure
+// the policies never affect the traffic of other shoots on the same gateway.
+package authorizationpolicies
+
+import (
+	"strings"
+
+	"github.com/gardener/gardener/extensions/pkg/controller"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+const (
+	keyRemoteIPBlocks    = "remoteIpBlocks"
+	keyNotRemoteIPBlocks = "notRemoteIpBlocks"
+	keyIPBlocks          = "ipBlocks"
+	keyNotIPBlocks       = "notIpBlocks"
+)
+
+// BuildAPIAuthorizationPolicySpecForHelmChart assembles an AuthorizationPolicy
+// spec for API server networking, matching the traffic for the given hosts via
+// SNI.
+func BuildAPIAuthorizationPolicySpecForHelmChart(
+	rule *envoyfilters.ACLRule, hosts, alwaysAllowedCIDRs []string, istioLabels map[string]string,
+) (map[string]interface{}, error) {
+	if len(hosts) == 0 {
+		return nil, envoyfilters.ErrNoHostsGiven
+	}
+
+	return buildSpec(rule, alwaysAllowedCIDRs, istioLabels, "connection.sni", hosts), nil
+}
+
+// BuildIngressAuthorizationPolicySpecForHelmChart assembles an
+// AuthorizationPolicy spec for endpoints using the seed ingress domain and, if
+// shootIngress is true, the wildcard ingress domain of the shoot. It returns
+// nil if there is neither.
+func BuildIngressAuthorizationPolicySpecForHelmChart(
+	cluster *controller.Cluster, rule *envoyfilters.ACLRule, alwaysAllowedCIDRs []string, istioLabels map[string]string, shootIngress bool,
+) map[string]interface{} {
+	var serverNames []string
+	if seedIngressDomain := helper.GetSeedIngressDomain(cluster.Seed); seedIngressDomain != "" {
+		serverNames = append(serverNames, "*-"+helper.ComputeShortShootID(cluster.Shoot)+"."+seedIngressDomain)
+	}
+	if shootIngressDomain := helper.GetShootIngressDomain(cluster.Shoot); shootIngress && shootIngressDomain != "" {
+		serverNames = append(serverNames, "*."+shootIngressDomain)
+	}
+	if len(serverNames) == 0 {
+		return nil
+	}
+
+	return buildSpec(rule, alwaysAllowedCIDRs, istioLabels, "connection.sni", serverNames)
+}
+
+// BuildVPNAuthorizationPolicySpecForHelmChart assembles an AuthorizationPolicy
+// spec for VPN, matching the traffic of the shoot via the reversed-vpn header.
+func BuildVPNAuthorizationPolicySpecForHelmChart(
+	cluster *controller.Cluster, rule *envoyfilters.ACLRule, alwaysAllowedCIDRs []string, istioLabels map[string]string,
+) map[string]interface{} {
+	// The actual header value will look something like
+	// `outbound|1194||vpn-seed-server.<technical-ID>.svc.cluster.local`.
+	// AuthorizationPolicies only support prefix and suffix matches, so anchor
+	// the technical ID with the dot in front of it and the service domain.
+	headerValue := "*." + cluster.Shoot.Status.TechnicalID + ".svc.cluster.local"
+	return buildSpec(rule, alwaysAllowedCIDRs, istioLabels, "request.headers[reversed-vpn]", []string{headerValue})
+}
+
+// buildSpec translates the ACLRule into DENY rules which only apply to the
+// traffic matching the given condition.
+//
+// For "ALLOW" rules, everything not contained in the rule's CIDRs or the
+// alwaysAllowedCIDRs is denied, as well as the except CIDRs (if not always
+// allowed). For "DENY" rules, the rule's CIDRs without the except CIDRs are
+// denied. The globally denied CIDRs are always denied. None of the sources
+// match the protected CIDRs of the rule.
+func buildSpec(
+	rule *envoyfilters.ACLRule, alwaysAllowedCIDRs []string, istioLabels map[string]string, conditionKey string, conditionValues []string,
+) map[string]interface{} {
+	ipBlocks, notIPBlocks := keyIPBlocks, keyNotIPBlocks
+	if rule.PrincipalType() == envoyfilters.TypeRemoteIP {
+		ipBlocks, notIPBlocks = keyRemoteIPBlocks, keyNotRemoteIPBlocks
+	}
+
+	var sources []map[string]interface{}
+	if strings.EqualFold(rule.Action, "ALLOW") {
+		alwaysAllowedCIDRs = append(append([]string{}, alwaysAllowedCIDRs...), rule.ProtectedCIDRs...)
+		source := map[string]interface{}{}
+		if len(alwaysAllowedCIDRs) > 0 {
+			source[keyNotRemoteIPBlocks] = alwaysAllowedCIDRs
+		}
+		// merge the lists if they target the same field, as not matching any
+		// of both lists is the same as not matching the combined list
+		if notIPBlocks == keyNotRemoteIPBlocks {
+			source[keyNotRemoteIPBlocks] = append(append([]string{}, rule.Cidrs...), alwaysAllowedCIDRs...)
+		} else {
+			source[notIPBlocks] = rule.Cidrs
+		}
+		sources = append(sources, source)
+
+		if len(rule.Except) > 0 {
+			exceptSource := map[string]interface{}{
+				ipBlocks: rule.Except,
+			}
+			if len(alwaysAllowedCIDRs) > 0 {
+				exceptSource[keyNotRemoteIPBlocks] = alwaysAllowedCIDRs
+			}
+			sources = append(sources, exceptSource)
+		}
+	} else {
+		source := map[string]interface{}{
+			ipBlocks: rule.Cidrs,
+		}
+		if len(rule.Except) > 0 {
+			source[notIPBlocks] = rule.Except
+		}
+		sources = append(sources, withoutProtectedCIDRs(rule, source))
+	}
+
+	if len(rule.DeniedCIDRs) > 0 {
+		sources = append(sources, withoutProtectedCIDRs(rule, map[string]interface{}{
+			keyRemoteIPBlocks: rule.DeniedCIDRs,
+		}))
+	}
+
+	// the fields of a single source are ANDed, while the sources are ORed
+	from := make([]map[string]interface{}, 0, len(sources))
+	for _, source := range sources {
+		from = append(from, map[string]interface{}{
+			"source": source,
+		})
+	}
+
+	return map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": istioLabels,
+		},
+		"action": "DENY",
+		"rules": []map[string]interface{}{{
+			"from": from,
+			"when": []map[string]interface{}{{
+				"key":    conditionKey,
+				"values": conditionValues,
+			}},
+		}},
+	}
+}
+
+// withoutProtectedCIDRs excludes the protected CIDRs of the rule from the
+// denied source, in addition to the remote IP blocks it excludes already.
+func withoutProtectedCIDRs(rule *envoyfilters.ACLRule, source map[string]interface{}) map[string]interface{} {
+	if len(rule.ProtectedCIDRs) == 0 {
+		return source
+	}
+	notRemoteIPBlocks, _ := source[keyNotRemoteIPBlocks].([]string)
+	source[keyNotRemoteIPBlocks] = append(append([]string{}, notRemoteIPBlocks...), rule.ProtectedCIDRs...)
+	return source
+}
@@ -0,0 +1,189 @@
+package authorizationpolicies
+
+import (
+	"os"
+	"path"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	"github.com/gardener/gardener/pkg/extensions"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+)
+
+var _ = Describe("AuthorizationPolicy Unit Tests", func() {
+	var (
+		alwaysAllowedCIDRs = []string{
+			"10.250.0.0/16",
+			"10.96.0.0/11",
+		}
+		labels = map[string]string{
+			"app":   "istio-ingressgateway",
+			"istio": "ingressgateway",
+		}
+		cluster *extensions.Cluster
+	)
+
+	BeforeEach(func() {
+		cluster = &extensions.Cluster{
+			Shoot: &gardencorev1beta1.Shoot{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+				},
+				Status: gardencorev1beta1.ShootStatus{
+					TechnicalID: "shoot--bar--foo",
+				},
+			},
+			Seed: &gardencorev1beta1.Seed{
+				Spec: gardencorev1beta1.SeedSpec{
+					Ingress: &gardencorev1beta1.Ingress{
+						Domain: "ingress.testseed.dev.ske.eu01.stackit.cloud",
+					},
+				},
+			},
+		}
+	})
+
+	Describe("BuildAPIAuthorizationPolicySpecForHelmChart", func() {
+		hosts := []string{
+			"api.test.garden.s.testseed.dev.ske.eu01.stackit.cloud",
+			"api.test.garden.internal.testseed.dev.ske.eu01.stackit.cloud",
+		}
+
+		It("Should create a spec matching the expected one for an allow rule", func() {
+			rule := createRule("ALLOW", "remote_ip", "10.180.0.0/16")
+
+			result, err := BuildAPIAuthorizationPolicySpecForHelmChart(rule, hosts, alwaysAllowedCIDRs, labels)
+
+			Expect(err).ToNot(HaveOccurred())
+			checkIfMapEqualsYAML(result, "apiAuthorizationPolicySpecWithOneAllowRule.yaml")
+		})
+
+		It("Should create a spec matching the expected one for an allow rule with except and denied CIDRs", func() {
+			rule := createRule("ALLOW", "source_ip", "10.0.0.0/16")
+			rule.Except = []string{"10.0.5.0/24"}
+			rule.DeniedCIDRs = []string{"203.0.113.0/24"}
+
+			result, err := BuildAPIAuthorizationPolicySpecForHelmChart(rule, hosts, alwaysAllowedCIDRs, labels)
+
+			Expect(err).ToNot(HaveOccurred())
+			checkIfMapEqualsYAML(result, "apiAuthorizationPolicySpecWithExceptAndDeniedCIDRs.yaml")
+		})
+
+		It("Should create a spec matching the expected one for a deny rule", func() {
+			rule := createRule("DENY", "remote_ip", "1.2.3.4/32")
+
+			result, err := BuildAPIAuthorizationPolicySpecForHelmChart(rule, hosts, alwaysAllowedCIDRs, labels)
+
+			Expect(err).ToNot(HaveOccurred())
+			checkIfMapEqualsYAML(result, "apiAuthorizationPolicySpecWithOneDenyRule.yaml")
+		})
+
+		It("Should match the client IPs from the PROXY protocol header for direct_remote_ip rules with the PROXY protocol", func() {
+			rule := createRule("ALLOW", "direct_remote_ip", "10.180.0.0/16")
+			rule.ProxyProtocol = true
+
+			result, err := BuildAPIAuthorizationPolicySpecForHelmChart(rule, hosts, alwaysAllowedCIDRs, labels)
+
+			Expect(err).ToNot(HaveOccurred())
+			checkIfMapEqualsYAML(result, "apiAuthorizationPolicySpecWithOneAllowRule.yaml")
+		})
+
+		It("Should exclude the protected CIDRs from every denied source", func() {
+			for _, action := range []string{"ALLOW", "DENY"} {
+				rule := createRule(action, "remote_ip", "10.0.0.0/8")
+				rule.Except = []string{"10.180.0.0/16"}
+				rule.DeniedCIDRs = []string{"0.0.0.0/0"}
+				rule.ProtectedCIDRs = []string{"10.180.0.0/16", "203.0.113.7/32"}
+
+				result, err := BuildAPIAuthorizationPolicySpecForHelmChart(rule, hosts, nil, labels)
+
+				Expect(err).ToNot(HaveOccurred())
+				from := result["rules"].([]map[string]interface{})[0]["from"].([]map[string]interface{})
+				Expect(from).NotTo(BeEmpty())
+				for _, source := range from {
+					Expect(source["source"]).To(HaveKeyWithValue("notRemoteIpBlocks", ContainElements("10.180.0.0/16", "203.0.113.7/32")), action)
+				}
+			}
+		})
+
+		It("Should return the appropriate error if there are no hosts", func() {
+			rule := createRule("ALLOW", "remote_ip", "0.0.0.0/0")
+
+			result, err := BuildAPIAuthorizationPolicySpecForHelmChart(rule, nil, alwaysAllowedCIDRs, labels)
+
+			Expect(err).To(Equal(envoyfilters.ErrNoHostsGiven))
+			Expect(result).To(BeNil())
+		})
+	})
+
+	Describe("BuildIngressAuthorizationPolicySpecForHelmChart", func() {
+		It("Should create a spec matching the expected one", func() {
+			rule := createRule("ALLOW", "remote_ip", "10.180.0.0/16")
+
+			result := BuildIngressAuthorizationPolicySpecForHelmChart(cluster, rule, alwaysAllowedCIDRs, labels, false)
+
+			checkIfMapEqualsYAML(result, "ingressAuthorizationPolicySpecWithOneAllowRule.yaml")
+		})
+
+		It("Should not create a spec when the seed has no ingress", func() {
+			rule := createRule("ALLOW", "remote_ip", "10.180.0.0/16")
+			cluster.Seed.Spec.Ingress = nil
+
+			Expect(BuildIngressAuthorizationPolicySpecForHelmChart(cluster, rule, alwaysAllowedCIDRs, labels, false)).To(BeNil())
+		})
+
+		It("Should match the ingress domain of the shoot if requested", func() {
+			rule := createRule("ALLOW", "remote_ip", "10.180.0.0/16")
+			cluster.Shoot.Spec.DNS = &gardencorev1beta1.DNS{Domain: ptr.To("foo.bar.example.com")}
+
+			result := BuildIngressAuthorizationPolicySpecForHelmChart(cluster, rule, alwaysAllowedCIDRs, labels, true)
+
+			Expect(result["rules"]).To(ConsistOf(HaveKeyWithValue("when", ConsistOf(HaveKeyWithValue("values", ConsistOf(
+				"*-bar--foo.ingress.testseed.dev.ske.eu01.stackit.cloud",
+				"*.ingress.foo.bar.example.com",
+			))))))
+		})
+	})
+
+	Describe("BuildVPNAuthorizationPolicySpecForHelmChart", func() {
+		It("Should create a spec matching the expected one", func() {
+			rule := createRule("ALLOW", "remote_ip", "10.180.0.0/16")
+
+			result := BuildVPNAuthorizationPolicySpecForHelmChart(cluster, rule, alwaysAllowedCIDRs, labels)
+
+			checkIfMapEqualsYAML(result, "vpnAuthorizationPolicySpecWithOneAllowRule.yaml")
+		})
+	})
+})
+
+func createRule(action, ruleType, cidr string) *envoyfilters.ACLRule {
+	return &envoyfilters.ACLRule{
+		Cidrs: []string{
+			cidr,
+		},
+		Action: action,
+		Type:   ruleType,
+	}
+}
+
+// checkIfMapEqualsYAML takes a map as input, and tries to compare its
+// marshaled contents to the string coming from the specified testdata file.
+// Fails the test if strings differ. The file contents are unmarshaled and
+// marshaled again to guarantee the strings are comparable.
+func checkIfMapEqualsYAML(input map[string]interface{}, relTestingFilePath string) {
+	goldenYAMLByteArray, err := os.ReadFile(path.Join("./testdata", relTestingFilePath))
+	Expect(err).ToNot(HaveOccurred())
+	goldenMap := map[string]interface{}{}
+	Expect(yaml.Unmarshal(goldenYAMLByteArray, goldenMap)).To(Succeed())
+	goldenYAMLProcessedByteArray, err := yaml.Marshal(goldenMap)
+	Expect(err).ToNot(HaveOccurred())
+
+	inputByteArray, err := yaml.Marshal(input)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(string(inputByteArray)).To(Equal(string(goldenYAMLProcessedByteArray)))
+}
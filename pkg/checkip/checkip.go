@@ -0,0 +1,93 @@
+// Package checkip simulates the decision of the ACL for a single source IP,
+// e.g. to find out why a client can't reach the API server of a shoot.
+package checkip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+// Error variables for the checkip pkg
+var (
+	// ErrNoExtension is returned if there is no ACL extension in the namespace.
+	ErrNoExtension = errors.New("there is no ACL extension in the namespace")
+	// ErrTemplateVariables is returned for rules with template variables,
+	// which are only resolved by the controller.
+	ErrTemplateVariables = errors.New("template variables are only resolved by the controller, replace them with the CIDRs of the shoot")
+)
+
+// Options contains the providerConfig and the IP to check.
+type Options struct {
+	// ProviderConfig is the providerConfig of the ACL extension in YAML or
+	// JSON.
+	ProviderConfig []byte
+	// IP is the source IP of the connection.
+	IP string
+	// AlwaysAllowedCIDRs are the CIDRs which are always allowed for the
+	// shoot, e.g. the seed networks or the node networks of the shoot.
+	AlwaysAllowedCIDRs []string
+	// DeniedCIDRs are the CIDRs of the global denylist.
+	DeniedCIDRs []string
+}
+
+// Check returns whether a connection from the IP to the API server of the
+// shoot would be allowed by the ACL, and which CIDR matched.
+func Check(opts Options) (*envoyfilters.Decision, error) {
+	ip := net.ParseIP(opts.IP)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP %q", opts.IP)
+	}
+
+	spec := &extensionspec.ExtensionSpec{}
+	if err := yaml.UnmarshalStrict(opts.ProviderConfig, spec); err != nil {
+		return nil, fmt.Errorf("could not decode providerConfig: %w", err)
+	}
+	if err := controller.ValidateExtensionSpec(spec); err != nil {
+		return nil, fmt.Errorf("invalid providerConfig: %w", err)
+	}
+	if controller.HasTemplateVariables(spec) {
+		return nil, ErrTemplateVariables
+	}
+	spec.Normalize()
+
+	spec.Rule.DeniedCIDRs = opts.DeniedCIDRs
+	decision := spec.Rule.Evaluate(ip, opts.AlwaysAllowedCIDRs)
+	return &decision, nil
+}
+
+// FromExtension returns the providerConfig of the ACL extension in the given
+// shoot namespace, and the always allowed CIDRs recorded in its status by the
+// last reconciliation.
+func FromExtension(ctx context.Context, c client.Reader, namespace string) (providerConfig []byte, alwaysAllowedCIDRs []string, err error) {
+	extensions := &extensionsv1alpha1.ExtensionList{}
+	if err := c.List(ctx, extensions, client.InNamespace(namespace)); err != nil {
+		return nil, nil, err
+	}
+
+	for i := range extensions.Items {
+		ex := &extensions.Items[i]
+		if ex.Spec.Type != controller.Type {
+			continue
+		}
+
+		extState, err := controller.GetExtensionState(ex)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ex.Spec.ProviderConfig != nil {
+			providerConfig = ex.Spec.ProviderConfig.Raw
+		}
+		return providerConfig, extState.AlwaysAllowedCIDRs, nil
+	}
+	return nil, nil, ErrNoExtension
+}
@@ -0,0 +1,111 @@
+package checkip
+
+import (
+	"context"
+	"encoding/json"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+)
+
+var _ = Describe("checkip", func() {
+	providerConfig := []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.0/24"]}}`)
+
+	Describe("#Check", func() {
+		It("should allow IPs in the rule's CIDRs", func() {
+			decision, err := Check(Options{ProviderConfig: providerConfig, IP: "1.2.3.4"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decision.Allowed).To(BeTrue())
+			Expect(decision.MatchedCIDR).To(Equal("1.2.3.0/24"))
+		})
+
+		It("should allow IPs in the always allowed CIDRs", func() {
+			decision, err := Check(Options{ProviderConfig: providerConfig, IP: "10.250.0.1", AlwaysAllowedCIDRs: []string{"10.250.0.0/16"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decision.Allowed).To(BeTrue())
+			Expect(decision.MatchedCIDR).To(Equal("10.250.0.0/16"))
+		})
+
+		It("should deny IPs in the denied CIDRs", func() {
+			decision, err := Check(Options{ProviderConfig: providerConfig, IP: "1.2.3.4", DeniedCIDRs: []string{"1.2.3.4/32"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decision.Allowed).To(BeFalse())
+			Expect(decision.MatchedCIDR).To(Equal("1.2.3.4/32"))
+		})
+
+		It("should deny other IPs", func() {
+			decision, err := Check(Options{ProviderConfig: providerConfig, IP: "5.6.7.8"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decision.Allowed).To(BeFalse())
+			Expect(decision.MatchedCIDR).To(BeEmpty())
+		})
+
+		It("should return an error for invalid IPs", func() {
+			_, err := Check(Options{ProviderConfig: providerConfig, IP: "1.2.3"})
+			Expect(err).To(MatchError(ContainSubstring("invalid IP")))
+		})
+
+		It("should return an error for an invalid providerConfig", func() {
+			_, err := Check(Options{ProviderConfig: []byte(`{"rule":{"action":"MAYBE"}}`), IP: "1.2.3.4"})
+			Expect(err).To(MatchError(controller.ErrSpecAction))
+		})
+
+		It("should return an error for rules with template variables", func() {
+			_, err := Check(Options{ProviderConfig: []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["${seed.nodes}"]}}`), IP: "1.2.3.4"})
+			Expect(err).To(MatchError(ErrTemplateVariables))
+		})
+	})
+
+	Describe("#FromExtension", func() {
+		var ctx = context.TODO()
+
+		newExtension := func(namespace, extensionType string, state *controller.ExtensionState) *extensionsv1alpha1.Extension {
+			stateJSON, err := json.Marshal(state)
+			Expect(err).NotTo(HaveOccurred())
+
+			return &extensionsv1alpha1.Extension{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: extensionType},
+				Spec: extensionsv1alpha1.ExtensionSpec{
+					DefaultSpec: extensionsv1alpha1.DefaultSpec{
+						Type:           extensionType,
+						ProviderConfig: &runtime.RawExtension{Raw: providerConfig},
+					},
+				},
+				Status: extensionsv1alpha1.ExtensionStatus{
+					DefaultStatus: extensionsv1alpha1.DefaultStatus{
+						State: &runtime.RawExtension{Raw: stateJSON},
+					},
+				},
+			}
+		}
+
+		It("should return the providerConfig and the always allowed CIDRs of the ACL extension", func() {
+			scheme := runtime.NewScheme()
+			Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+			c := fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(
+				newExtension("shoot--foo--bar", "other", &controller.ExtensionState{}),
+				newExtension("shoot--foo--bar", controller.Type, &controller.ExtensionState{AlwaysAllowedCIDRs: []string{"10.250.0.0/16"}}),
+			).Build()
+
+			config, alwaysAllowedCIDRs, err := FromExtension(ctx, c, "shoot--foo--bar")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config).To(MatchJSON(providerConfig))
+			Expect(alwaysAllowedCIDRs).To(ConsistOf("10.250.0.0/16"))
+		})
+
+		It("should return an error if there is no ACL extension", func() {
+			scheme := runtime.NewScheme()
+			Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+			c := fakeclient.NewClientBuilder().WithScheme(scheme).Build()
+
+			_, _, err := FromExtension(ctx, c, "shoot--foo--bar")
+			Expect(err).To(MatchError(ErrNoExtension))
+		})
+	})
+})
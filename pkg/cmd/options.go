@@ -16,6 +16,10 @@
 package cmd
 
 import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
 	"time"
 
 	extensionsconfig "github.com/gardener/gardener/extensions/pkg/apis/config"
@@ -23,45 +27,285 @@ import (
 	extensionshealthcheckcontroller "github.com/gardener/gardener/extensions/pkg/controller/healthcheck"
 	extensionscmdwebhook "github.com/gardener/gardener/extensions/pkg/webhook/cmd"
 	"github.com/spf13/pflag"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrlcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/stackitcloud/gardener-extension-acl/pkg/accessreview"
+	apisconfig "github.com/stackitcloud/gardener-extension-acl/pkg/apis/config"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/apis/config/loader"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/apis/config/validation"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/aggregation"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/bastion"
 	controllerconfig "github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/drift"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/globallist"
 	healthcheckcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller/healthcheck"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/operatorconfig"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/seednodes"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/deniedconnections"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/migration"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/multiseed"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/tracing"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/webhook"
 )
 
 const (
 	// DefaultSyncPeriod is the default healthcheck-sync-period
 	DefaultSyncPeriod = 30 * time.Second
+	// DefaultMaxConcurrentReconciles is the default max-concurrent-reconciles
+	DefaultMaxConcurrentReconciles = 5
 	// ChartPath is the path to the chart folder
 	ChartPath = "charts"
 )
 
 // ExtensionOptions holds options related to the extension (not the extension controller)
 type ExtensionOptions struct {
-	HealthCheckSyncPeriod  time.Duration
-	ChartPath              string
-	AdditionalAllowedCIDRs []string
+	ConfigFile                         string
+	HealthCheckSyncPeriod              time.Duration
+	ChartPath                          string
+	AdditionalAllowedCIDRs             []string
+	AutoAllowInfrastructureEgressCIDRs bool
+	MaxAllowedCIDRs                    int
+	GlobalAllowlistConfigMap           string
+	GlobalDenylistConfigMap            string
+	VerifyAPIServerReachability        bool
+	SeedKubeconfigs                    []string
+	EnforcementBackend                 string
+	DeniedConnectionsScrapeInterval    time.Duration
+	LogDeniedConnections               bool
+	ProxyProtocol                      bool
+	ClientIPPreservation               string
+	AccessReviewInterval               time.Duration
+	MigrationBatchSize                 int
+	MigrationBatchTimeout              time.Duration
+	ShutdownDelay                      time.Duration
+	TracingEndpoint                    string
+	TracingInsecure                    bool
+	TracingSamplingRatio               float64
+
+	config                   *apisconfig.ControllerConfiguration
+	globalAllowlistConfigMap types.NamespacedName
+	globalDenylistConfigMap  types.NamespacedName
+	seeds                    []multiseed.Seed
+}
+
+// deprecatedFlags are the flags covered by the ControllerConfiguration, they
+// are ignored if --config is given.
+var deprecatedFlags = map[string]string{
+	"healthcheck-sync-period":                "healthCheckConfig.syncPeriod",
+	"additional-allowed-cidrs":               "alwaysAllowed.cidrs",
+	"auto-allow-infrastructure-egress-cidrs": "alwaysAllowed.infrastructureEgressCIDRs",
+	"max-allowed-cidrs":                      "maxAllowedCIDRs",
+	"global-allowlist-configmap":             "alwaysAllowed.globalAllowlistConfigMap",
+	"global-denylist-configmap":              "globalDenylistConfigMap",
 }
 
 // AddFlags implements Flagger.AddFlags.
 func (o *ExtensionOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(
+		&o.ConfigFile,
+		"config",
+		"",
+		"Path of the ControllerConfiguration (acl.extensions.config.gardener.cloud/v1alpha1) of the extension. "+
+			"The flags covered by it are deprecated and ignored if it is given.",
+	)
 	fs.DurationVar(&o.HealthCheckSyncPeriod, "healthcheck-sync-period", DefaultSyncPeriod, "Default healthcheck sync period.")
 	fs.StringVar(&o.ChartPath, "chart-path", ChartPath, "Location of the chart directories to deploy")
 	fs.StringSliceVar(
 		&o.AdditionalAllowedCIDRs,
 		"additional-allowed-cidrs",
 		nil,
-		"List of IPs that will be added to the list of allowed CIDRs, e.g. '192.168.1.40/32,10.250.0.0/16'",
+		"List of CIDRs (e.g. seed networks or VPN endpoints) that are always allowed for every shoot, e.g. '192.168.1.40/32,10.250.0.0/16'",
+	)
+	fs.BoolVar(
+		&o.AutoAllowInfrastructureEgressCIDRs,
+		"auto-allow-infrastructure-egress-cidrs",
+		true,
+		"Always allow the egress CIDRs (e.g. NAT IPs) from the status of the shoot's Infrastructure object.",
+	)
+	fs.IntVar(
+		&o.MaxAllowedCIDRs,
+		"max-allowed-cidrs",
+		0,
+		"Number of CIDRs per shoot above which a warning about an oversized rule set is surfaced to the shoot (0 means no limit).",
+	)
+	fs.StringVar(
+		&o.GlobalAllowlistConfigMap,
+		"global-allowlist-configmap",
+		"",
+		"ConfigMap ('<namespace>/<name>') containing CIDRs in its 'cidrs' key that are always allowed for every shoot, e.g. corporate monitoring ranges.",
+	)
+	fs.StringVar(
+		&o.GlobalDenylistConfigMap,
+		"global-denylist-configmap",
+		"",
+		"ConfigMap ('<namespace>/<name>') containing CIDRs in its 'cidrs' key that are always denied for every shoot with the ACL extension, taking precedence over the shoot's rule.",
+	)
+	fs.BoolVar(
+		&o.VerifyAPIServerReachability,
+		"verify-apiserver-reachability",
+		false,
+		"Probe the shoot's API server with a TLS handshake after applying the ACL and record the result in the Extension status.",
+	)
+	fs.StringSliceVar(
+		&o.SeedKubeconfigs,
+		"seed-kubeconfig",
+		nil,
+		"Additional seed clusters ('<name>=<kubeconfig path>') served by this instance, each with isolated clients, caches and work queues.",
+	)
+	fs.StringVar(
+		&o.EnforcementBackend,
+		"enforcement-backend",
+		controllerconfig.EnforcementBackendEnvoyFilter,
+		fmt.Sprintf(
+			"Resources used to enforce the ACL in the istio namespaces, either '%s' or '%s'.",
+			controllerconfig.EnforcementBackendEnvoyFilter, controllerconfig.EnforcementBackendAuthorizationPolicy,
+		),
+	)
+	fs.DurationVar(
+		&o.DeniedConnectionsScrapeInterval,
+		"denied-connections-scrape-interval",
+		0,
+		"Interval for scraping the denied connections per shoot from the istio ingress gateways (0 disables the scraping).",
+	)
+	fs.BoolVar(
+		&o.LogDeniedConnections,
+		"log-denied-connections",
+		false,
+		"Log the connections denied by the ACL on the istio ingress gateways, unless configured otherwise in the shoot's providerConfig.",
+	)
+	fs.BoolVar(
+		&o.ProxyProtocol,
+		"proxy-protocol",
+		false,
+		fmt.Sprintf("Shortcut for --client-ip-preservation=%s.", helper.ClientIPProxied),
+	)
+	fs.StringVar(
+		&o.ClientIPPreservation,
+		"client-ip-preservation",
+		string(helper.ClientIPAuto),
+		fmt.Sprintf(
+			"How the load balancers of the seed's istio ingress gateways pass the client IPs, one of %v. "+
+				"With '%s', it is detected from the gateway Services and 'direct_remote_ip' rules match the client IPs from the PROXY protocol header if it is used.",
+			helper.ClientIPPreservations(), helper.ClientIPAuto,
+		),
+	)
+	fs.DurationVar(
+		&o.AccessReviewInterval,
+		"access-review-interval",
+		0,
+		"Interval for writing the access review report of every shoot to a ConfigMap in the shoot namespace (0 disables the reports).",
+	)
+	fs.IntVar(
+		&o.MigrationBatchSize,
+		"migration-batch-size",
+		migration.DefaultBatchSize,
+		"Number of extensions with objects of an older extension version which are migrated at once on startup (0 disables the migration).",
 	)
+	fs.DurationVar(
+		&o.MigrationBatchTimeout,
+		"migration-batch-timeout",
+		migration.DefaultBatchTimeout,
+		"Time to wait for the migration of a batch of extensions, the migration is stopped if no extension of a batch was migrated in time.",
+	)
+	fs.DurationVar(
+		&o.ShutdownDelay,
+		"shutdown-delay",
+		helper.DefaultShutdownDelay,
+		"Time the extension keeps serving admission requests after it was asked to terminate, while it is already reported as not ready.",
+	)
+	fs.StringVar(
+		&o.TracingEndpoint,
+		"tracing-endpoint",
+		"",
+		"Address ('<host>:<port>') of the OTLP/gRPC collector the spans of the reconciliations and the webhook are exported to (empty disables the tracing).",
+	)
+	fs.BoolVar(&o.TracingInsecure, "tracing-insecure", false, "Connect to the OTLP/gRPC collector without TLS.")
+	fs.Float64Var(
+		&o.TracingSamplingRatio,
+		"tracing-sampling-ratio",
+		tracing.DefaultOptions.SamplingRatio,
+		"Ratio of the reconciliations and admission requests which are traced, between 0 and 1.",
+	)
+
+	for flag, field := range deprecatedFlags {
+		if err := fs.MarkDeprecated(flag, fmt.Sprintf("use the %s field of --config instead", field)); err != nil {
+			panic(err)
+		}
+	}
 }
 
 // Complete implements Completer.Complete.
 func (o *ExtensionOptions) Complete() error {
-	// TODO validate mandatory input options
+	for _, cidr := range o.AdditionalAllowedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid additional allowed CIDR %q: %w", cidr, err)
+		}
+	}
+
+	if o.TracingSamplingRatio < 0 || o.TracingSamplingRatio > 1 {
+		return fmt.Errorf("invalid tracing sampling ratio %v, must be between 0 and 1", o.TracingSamplingRatio)
+	}
+
+	if o.MigrationBatchSize < 0 {
+		return fmt.Errorf("invalid migration batch size %d, must not be negative", o.MigrationBatchSize)
+	}
+
+	if o.EnforcementBackend != controllerconfig.EnforcementBackendEnvoyFilter &&
+		o.EnforcementBackend != controllerconfig.EnforcementBackendAuthorizationPolicy {
+		return fmt.Errorf("invalid enforcement backend %q", o.EnforcementBackend)
+	}
+
+	if !slices.Contains(helper.ClientIPPreservations(), helper.ClientIPPreservation(o.ClientIPPreservation)) {
+		return fmt.Errorf("invalid client IP preservation %q", o.ClientIPPreservation)
+	}
+
+	if o.ConfigFile != "" {
+		cfg, err := loader.LoadFromFile(o.ConfigFile)
+		if err != nil {
+			return fmt.Errorf("could not load config file: %w", err)
+		}
+		if errs := validation.ValidateControllerConfiguration(cfg); len(errs) > 0 {
+			return fmt.Errorf("invalid config file: %w", errs.ToAggregate())
+		}
+		if cfg.EnvoyFilterMode == apisconfig.EnvoyFilterModeAggregated && o.EnforcementBackend != controllerconfig.EnforcementBackendEnvoyFilter {
+			return fmt.Errorf("envoyFilterMode %s is only supported by the %s enforcement backend",
+				apisconfig.EnvoyFilterModeAggregated, controllerconfig.EnforcementBackendEnvoyFilter)
+		}
+		o.config = cfg
+	}
+
+	var err error
+	if o.globalAllowlistConfigMap, err = parseConfigMapReference(o.GlobalAllowlistConfigMap); err != nil {
+		return fmt.Errorf("invalid global allowlist ConfigMap: %w", err)
+	}
+	if o.globalDenylistConfigMap, err = parseConfigMapReference(o.GlobalDenylistConfigMap); err != nil {
+		return fmt.Errorf("invalid global denylist ConfigMap: %w", err)
+	}
+	if o.seeds, err = multiseed.ParseSeeds(o.SeedKubeconfigs); err != nil {
+		return fmt.Errorf("invalid seed kubeconfig: %w", err)
+	}
 	return nil
 }
 
+func parseConfigMapReference(reference string) (types.NamespacedName, error) {
+	if reference == "" {
+		return types.NamespacedName{}, nil
+	}
+
+	namespace, name, found := strings.Cut(reference, "/")
+	if !found || namespace == "" || name == "" {
+		return types.NamespacedName{}, fmt.Errorf("%q, expected '<namespace>/<name>'", reference)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
 // Completed returns ExtensionOptions.
 func (o *ExtensionOptions) Completed() *ExtensionOptions {
 	return o
@@ -72,18 +316,220 @@ func (o *ExtensionOptions) Apply(config *controllerconfig.Config) {
 	// TODO pass controller options from extensionoptions to config param
 	config.ChartPath = o.ChartPath
 	config.AdditionalAllowedCIDRs = o.AdditionalAllowedCIDRs
+	config.AutoAllowInfrastructureEgressCIDRs = o.AutoAllowInfrastructureEgressCIDRs
+	config.MaxAllowedCIDRs = o.MaxAllowedCIDRs
+	config.GlobalAllowlistConfigMap = o.globalAllowlistConfigMap
+	config.GlobalDenylistConfigMap = o.globalDenylistConfigMap
+	config.VerifyAPIServerReachability = o.VerifyAPIServerReachability
+	config.EnforcementBackend = o.EnforcementBackend
+	config.LogDeniedConnections = o.LogDeniedConnections
+	config.ClientIPPreservation = helper.ClientIPPreservation(o.ClientIPPreservation)
+	if o.ProxyProtocol {
+		config.ClientIPPreservation = helper.ClientIPProxied
+	}
+
+	if o.config == nil {
+		return
+	}
+	config.AdditionalAllowedCIDRs = o.config.AlwaysAllowed.CIDRs
+	config.GardenEgressCIDRs = o.config.AlwaysAllowed.GardenEgressCIDRs
+	config.AutoAllowInfrastructureEgressCIDRs = ptr.Deref(o.config.AlwaysAllowed.InfrastructureEgressCIDRs, true)
+	config.MaxAllowedCIDRs = o.config.MaxAllowedCIDRs
+	config.MaxCIDRs = o.config.MaxCIDRs
+	config.MaxGatewayEnvoyFilterBytes = o.config.MaxGatewayEnvoyFilterBytes
+	config.PublishShootInfo = o.config.PublishShootInfo
+	// the patterns were validated when completing the options
+	config.IgnoredNamespaces, _ = helper.CompileNamespacePatterns(o.config.IgnoredNamespaces)
+	config.AggregateEnvoyFilters = o.config.EnvoyFilterMode == apisconfig.EnvoyFilterModeAggregated
+	config.CreateAggregatedEnvoyFiltersDirectly = o.config.EnvoyFilterDeployment == apisconfig.EnvoyFilterDeploymentDirect
+	config.GlobalAllowlistConfigMap = configMapReference(o.config.AlwaysAllowed.GlobalAllowlistConfigMap)
+	config.GlobalDenylistConfigMap = configMapReference(o.config.GlobalDenylistConfigMap)
+	config.PurposePolicies = purposePolicies(o.config.PurposePolicies)
+	config.APIServerGatewayName = o.config.Istio.APIServerGatewayName
+	config.IngressGatewayNamespace = o.config.Istio.IngressGatewayNamespace
+	config.IngressGatewayName = o.config.Istio.IngressGatewayName
+	config.RequeueGatewayNotFound = durationOrZero(o.config.Requeue.GatewayNotFound)
+	config.RequeueClusterNotReady = durationOrZero(o.config.Requeue.ClusterNotReady)
+	config.RequeueConflict = durationOrZero(o.config.Requeue.Conflict)
+	config.RequeueRulesRejected = durationOrZero(o.config.Requeue.RulesRejected)
+	if o.config.SyncPeriod != nil {
+		config.SyncPeriod = o.config.SyncPeriod.Duration
+	}
+}
+
+func configMapReference(ref *apisconfig.ConfigMapReference) types.NamespacedName {
+	if ref == nil {
+		return types.NamespacedName{}
+	}
+	return types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+}
+
+func purposePolicies(policies []apisconfig.PurposePolicy) map[string]controllerconfig.PurposePolicy {
+	if len(policies) == 0 {
+		return nil
+	}
+	out := make(map[string]controllerconfig.PurposePolicy, len(policies))
+	for _, policy := range policies {
+		out[policy.Purpose] = controllerconfig.PurposePolicy{DeniedCIDRs: policy.DeniedCIDRs, ForbidShadowMode: policy.ForbidShadowMode}
+	}
+	return out
+}
+
+func durationOrZero(duration *metav1.Duration) time.Duration {
+	if duration == nil {
+		return 0
+	}
+	return duration.Duration
 }
 
 // ApplyHealthCheckConfig applies the ExtensionOptions to the passed HealthCheckConfig.
 func (o *ExtensionOptions) ApplyHealthCheckConfig(config *extensionsconfig.HealthCheckConfig) {
+	if o.config != nil && o.config.HealthCheckConfig != nil {
+		o.config.HealthCheckConfig.DeepCopyInto(config)
+		return
+	}
 	config.SyncPeriod.Duration = o.HealthCheckSyncPeriod
 }
 
+// ApplyLeaderElectionConfig applies the ExtensionOptions to the passed manager Options.
+func (o *ExtensionOptions) ApplyLeaderElectionConfig(opts *manager.Options) {
+	if o.config == nil || o.config.LeaderElection == nil {
+		return
+	}
+
+	leaderElection := o.config.LeaderElection
+	opts.LeaderElection = leaderElection.LeaderElect
+	opts.LeaseDuration = ptr.To(leaderElection.LeaseDuration.Duration)
+	opts.RenewDeadline = ptr.To(leaderElection.RenewDeadline.Duration)
+	opts.RetryPeriod = ptr.To(leaderElection.RetryPeriod.Duration)
+	opts.LeaderElectionResourceLock = leaderElection.ResourceLock
+	if leaderElection.ResourceName != "" {
+		opts.LeaderElectionID = leaderElection.ResourceName
+	}
+	if leaderElection.ResourceNamespace != "" {
+		opts.LeaderElectionNamespace = leaderElection.ResourceNamespace
+	}
+}
+
+// ApplyWebhookConfig applies the ExtensionOptions to the passed webhook AddToManagerConfig.
+func (o *ExtensionOptions) ApplyWebhookConfig(config *AddToManagerConfig) {
+	if o.config == nil {
+		return
+	}
+	config.FailurePolicy = o.config.Webhook.FailurePolicy
+	config.TimeoutSeconds = o.config.Webhook.TimeoutSeconds
+	config.NamespaceSelector = o.config.Webhook.NamespaceSelector
+	config.ObjectSelector = o.config.Webhook.ObjectSelector
+}
+
+// ApplyWebhookHandlerConfig applies the ExtensionOptions to the passed webhook
+// AddOptions. The handler fails open if the webhook is configured with the
+// "Ignore" failure policy, so EnvoyFilters are never blocked by the webhook.
+func (o *ExtensionOptions) ApplyWebhookHandlerConfig(opts *webhook.AddOptions) {
+	if o.config == nil {
+		return
+	}
+	policy := o.config.Webhook.FailurePolicy
+	opts.FailOpen = policy != nil && *policy == admissionregistrationv1.Ignore
+}
+
+// ApplyMultiSeedConfig applies the ExtensionOptions to the passed multiseed AddOptions.
+func (o *ExtensionOptions) ApplyMultiSeedConfig(opts *multiseed.AddOptions) {
+	opts.Seeds = o.seeds
+}
+
+// ApplyDeniedConnectionsConfig applies the ExtensionOptions to the passed deniedconnections AddOptions.
+func (o *ExtensionOptions) ApplyDeniedConnectionsConfig(opts *deniedconnections.AddOptions) {
+	opts.Interval = o.DeniedConnectionsScrapeInterval
+}
+
+// ApplyAccessReviewConfig applies the ExtensionOptions to the passed accessreview AddOptions.
+func (o *ExtensionOptions) ApplyAccessReviewConfig(opts *accessreview.AddOptions) {
+	opts.Interval = o.AccessReviewInterval
+}
+
+// ApplyMigrationConfig applies the ExtensionOptions to the passed migration AddOptions.
+func (o *ExtensionOptions) ApplyMigrationConfig(opts *migration.AddOptions) {
+	opts.BatchSize = o.MigrationBatchSize
+	opts.BatchTimeout = o.MigrationBatchTimeout
+}
+
+// ApplyTracingConfig applies the ExtensionOptions to the passed tracing Options.
+func (o *ExtensionOptions) ApplyTracingConfig(opts *tracing.Options) {
+	opts.Endpoint = o.TracingEndpoint
+	opts.Insecure = o.TracingInsecure
+	opts.SamplingRatio = o.TracingSamplingRatio
+}
+
+// ControllerOptions holds the number of workers of the extension controller
+// and the health check controller.
+type ControllerOptions struct {
+	// MaxConcurrentReconciles is the number of Extensions reconciled in
+	// parallel.
+	MaxConcurrentReconciles int
+	// HealthCheckMaxConcurrentReconciles overrides the number of Extensions
+	// checked in parallel by the health check controller. It defaults to
+	// MaxConcurrentReconciles.
+	HealthCheckMaxConcurrentReconciles int
+}
+
+// NewControllerOptions returns ControllerOptions with the default number of
+// workers.
+func NewControllerOptions() *ControllerOptions {
+	return &ControllerOptions{MaxConcurrentReconciles: DefaultMaxConcurrentReconciles}
+}
+
+// AddFlags implements Flagger.AddFlags.
+func (o *ControllerOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.IntVar(&o.MaxConcurrentReconciles, extensionscmdcontroller.MaxConcurrentReconcilesFlag, o.MaxConcurrentReconciles,
+		"The maximum number of concurrent reconciliations of the ACL extensions.")
+	fs.IntVar(&o.HealthCheckMaxConcurrentReconciles, "healthcheck-"+extensionscmdcontroller.MaxConcurrentReconcilesFlag, o.HealthCheckMaxConcurrentReconciles,
+		"The maximum number of concurrent health checks of the ACL extensions, defaults to --"+extensionscmdcontroller.MaxConcurrentReconcilesFlag+".")
+}
+
+// Complete implements Completer.Complete.
+func (o *ControllerOptions) Complete() error {
+	if o.MaxConcurrentReconciles < 1 {
+		return fmt.Errorf("invalid max concurrent reconciles %d, must be positive", o.MaxConcurrentReconciles)
+	}
+	if o.HealthCheckMaxConcurrentReconciles < 0 {
+		return fmt.Errorf("invalid health check max concurrent reconciles %d, must not be negative", o.HealthCheckMaxConcurrentReconciles)
+	}
+	if o.HealthCheckMaxConcurrentReconciles == 0 {
+		o.HealthCheckMaxConcurrentReconciles = o.MaxConcurrentReconciles
+	}
+	return nil
+}
+
+// Completed returns the completed ControllerOptions. Only call this if a
+// previous call to `Complete` succeeded.
+func (o *ControllerOptions) Completed() *ControllerOptions {
+	return o
+}
+
+// Apply applies the number of workers of the extension controller to the
+// passed controller options.
+func (o *ControllerOptions) Apply(opts *ctrlcontroller.Options) {
+	opts.MaxConcurrentReconciles = o.MaxConcurrentReconciles
+}
+
+// ApplyHealthCheck applies the number of workers of the health check
+// controller to the passed controller options.
+func (o *ControllerOptions) ApplyHealthCheck(opts *ctrlcontroller.Options) {
+	opts.MaxConcurrentReconciles = o.HealthCheckMaxConcurrentReconciles
+}
+
 // ControllerSwitches are the cmd.SwitchOptions for the provider controllers.
 func ControllerSwitches() *extensionscmdcontroller.SwitchOptions {
 	return extensionscmdcontroller.NewSwitchOptions(
 		extensionscmdcontroller.Switch(controller.Type, controller.AddToManager),
 		extensionscmdcontroller.Switch(extensionshealthcheckcontroller.ControllerName, healthcheckcontroller.AddToManager),
+		extensionscmdcontroller.Switch(globallist.ControllerName, globallist.AddToManager),
+		extensionscmdcontroller.Switch(seednodes.ControllerName, seednodes.AddToManager),
+		extensionscmdcontroller.Switch(bastion.ControllerName, bastion.AddToManager),
+		extensionscmdcontroller.Switch(operatorconfig.ControllerName, operatorconfig.AddToManager),
+		extensionscmdcontroller.Switch(aggregation.ControllerName, aggregation.AddToManager),
+		extensionscmdcontroller.Switch(drift.ControllerName, drift.AddToManager),
 	)
 }
 
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	extensionsconfig "github.com/gardener/gardener/extensions/pkg/apis/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	controllerconfig "github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/tracing"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/webhook"
+)
+
+var _ = Describe("ExtensionOptions", func() {
+	var (
+		opts *ExtensionOptions
+		fs   *pflag.FlagSet
+	)
+
+	BeforeEach(func() {
+		opts = &ExtensionOptions{}
+		fs = pflag.NewFlagSet("test", pflag.ContinueOnError)
+		opts.AddFlags(fs)
+	})
+
+	writeConfig := func(data string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		Expect(os.WriteFile(path, []byte(data), 0o600)).To(Succeed())
+		return path
+	}
+
+	It("should apply the flags without a config file", func() {
+		Expect(fs.Parse([]string{
+			"--additional-allowed-cidrs=10.250.0.0/16",
+			"--global-allowlist-configmap=extension-acl/global-allowlist",
+			"--healthcheck-sync-period=1m",
+		})).To(Succeed())
+		Expect(opts.Complete()).To(Succeed())
+
+		config := controllerconfig.Config{}
+		opts.Apply(&config)
+		Expect(config.AdditionalAllowedCIDRs).To(ConsistOf("10.250.0.0/16"))
+		Expect(config.AutoAllowInfrastructureEgressCIDRs).To(BeTrue())
+		Expect(config.GlobalAllowlistConfigMap).To(Equal(types.NamespacedName{Namespace: "extension-acl", Name: "global-allowlist"}))
+		Expect(config.APIServerGatewayName).To(BeEmpty())
+		Expect(config.ClientIPPreservation).To(Equal(helper.ClientIPAuto))
+
+		healthCheckConfig := extensionsconfig.HealthCheckConfig{}
+		opts.ApplyHealthCheckConfig(&healthCheckConfig)
+		Expect(healthCheckConfig.SyncPeriod.Duration).To(Equal(time.Minute))
+
+		webhookConfig := &AddToManagerConfig{}
+		opts.ApplyWebhookConfig(webhookConfig)
+		Expect(webhookConfig.FailurePolicy).To(BeNil())
+		Expect(webhookConfig.TimeoutSeconds).To(BeNil())
+
+		webhookOpts := webhook.AddOptions{}
+		opts.ApplyWebhookHandlerConfig(&webhookOpts)
+		Expect(webhookOpts.FailOpen).To(BeFalse())
+
+		mgrOpts := manager.Options{LeaderElection: true, LeaderElectionID: "acl-leader-election"}
+		opts.ApplyLeaderElectionConfig(&mgrOpts)
+		Expect(mgrOpts).To(Equal(manager.Options{LeaderElection: true, LeaderElectionID: "acl-leader-election"}))
+	})
+
+	It("should apply the leader election of the config file", func() {
+		Expect(fs.Parse([]string{"--config=" + writeConfig(`
+apiVersion: acl.extensions.config.gardener.cloud/v1alpha1
+kind: ControllerConfiguration
+leaderElection:
+  leaseDuration: 60s
+  renewDeadline: 40s
+  retryPeriod: 5s
+  resourceNamespace: extension-acl
+`)})).To(Succeed())
+		Expect(opts.Complete()).To(Succeed())
+
+		mgrOpts := manager.Options{LeaderElection: false, LeaderElectionID: "acl-leader-election", LeaderElectionNamespace: "garden"}
+		opts.ApplyLeaderElectionConfig(&mgrOpts)
+		Expect(mgrOpts.LeaderElection).To(BeTrue())
+		Expect(mgrOpts.LeaseDuration).To(Equal(ptr.To(60 * time.Second)))
+		Expect(mgrOpts.RenewDeadline).To(Equal(ptr.To(40 * time.Second)))
+		Expect(mgrOpts.RetryPeriod).To(Equal(ptr.To(5 * time.Second)))
+		Expect(mgrOpts.LeaderElectionResourceLock).To(Equal("leases"))
+		Expect(mgrOpts.LeaderElectionID).To(Equal("acl-leader-election"))
+		Expect(mgrOpts.LeaderElectionNamespace).To(Equal("extension-acl"))
+	})
+
+	It("should prefer the config file over the deprecated flags", func() {
+		Expect(fs.Parse([]string{
+			"--additional-allowed-cidrs=10.250.0.0/16",
+			"--config=" + writeConfig(`
+apiVersion: acl.extensions.config.gardener.cloud/v1alpha1
+kind: ControllerConfiguration
+alwaysAllowed:
+  cidrs:
+  - 10.180.0.0/16
+  gardenEgressCIDRs:
+  - 198.51.100.0/24
+  infrastructureEgressCIDRs: false
+globalDenylistConfigMap:
+  namespace: extension-acl
+  name: global-denylist
+purposePolicies:
+- purpose: production
+  deniedCIDRs:
+  - 203.0.113.0/24
+  forbidShadowMode: true
+webhook:
+  failurePolicy: Ignore
+istio:
+  apiServerGatewayName: apiserver
+syncPeriod: 30m
+healthCheckConfig:
+  syncPeriod: 2m
+`),
+		})).To(Succeed())
+		Expect(opts.Complete()).To(Succeed())
+
+		config := controllerconfig.Config{}
+		opts.Apply(&config)
+		Expect(config.AdditionalAllowedCIDRs).To(ConsistOf("10.180.0.0/16"))
+		Expect(config.GardenEgressCIDRs).To(ConsistOf("198.51.100.0/24"))
+		Expect(config.AutoAllowInfrastructureEgressCIDRs).To(BeFalse())
+		Expect(config.GlobalAllowlistConfigMap).To(BeZero())
+		Expect(config.GlobalDenylistConfigMap).To(Equal(types.NamespacedName{Namespace: "extension-acl", Name: "global-denylist"}))
+		Expect(config.PurposePolicies).To(Equal(map[string]controllerconfig.PurposePolicy{
+			"production": {DeniedCIDRs: []string{"203.0.113.0/24"}, ForbidShadowMode: true},
+		}))
+		Expect(config.APIServerGatewayName).To(Equal("apiserver"))
+		Expect(config.IngressGatewayNamespace).To(Equal("garden"))
+		Expect(config.IngressGatewayName).To(Equal("nginx-ingress-controller"))
+		Expect(config.SyncPeriod).To(Equal(30 * time.Minute))
+		Expect(config.RequeueGatewayNotFound).To(Equal(15 * time.Second))
+		Expect(config.RequeueRulesRejected).To(Equal(10 * time.Minute))
+
+		healthCheckConfig := extensionsconfig.HealthCheckConfig{}
+		opts.ApplyHealthCheckConfig(&healthCheckConfig)
+		Expect(healthCheckConfig.SyncPeriod.Duration).To(Equal(2 * time.Minute))
+
+		webhookConfig := &AddToManagerConfig{}
+		opts.ApplyWebhookConfig(webhookConfig)
+		Expect(webhookConfig.FailurePolicy).To(Equal(ptr.To(admissionregistrationv1.Ignore)))
+		Expect(webhookConfig.TimeoutSeconds).To(Equal(ptr.To[int32](5)))
+		Expect(webhookConfig.NamespaceSelector).NotTo(BeNil())
+		Expect(webhookConfig.ObjectSelector).To(BeNil())
+
+		webhookOpts := webhook.AddOptions{}
+		opts.ApplyWebhookHandlerConfig(&webhookOpts)
+		Expect(webhookOpts.FailOpen).To(BeTrue())
+	})
+
+	It("should configure the client IP preservation", func() {
+		Expect(fs.Parse([]string{"--client-ip-preservation=nated"})).To(Succeed())
+		Expect(opts.Complete()).To(Succeed())
+
+		config := controllerconfig.Config{}
+		opts.Apply(&config)
+		Expect(config.ClientIPPreservation).To(Equal(helper.ClientIPNATed))
+	})
+
+	It("should configure the PROXY protocol via the shortcut", func() {
+		Expect(fs.Parse([]string{"--proxy-protocol"})).To(Succeed())
+		Expect(opts.Complete()).To(Succeed())
+
+		config := controllerconfig.Config{}
+		opts.Apply(&config)
+		Expect(config.ClientIPPreservation).To(Equal(helper.ClientIPProxied))
+	})
+
+	It("should reject an invalid client IP preservation", func() {
+		Expect(fs.Parse([]string{"--client-ip-preservation=masqueraded"})).To(Succeed())
+
+		Expect(opts.Complete()).To(MatchError(ContainSubstring(`invalid client IP preservation "masqueraded"`)))
+	})
+
+	It("should configure the tracing", func() {
+		Expect(fs.Parse([]string{"--tracing-endpoint=otel-collector.garden:4317", "--tracing-insecure", "--tracing-sampling-ratio=0.1"})).To(Succeed())
+		Expect(opts.Complete()).To(Succeed())
+
+		tracingOpts := tracing.Options{}
+		opts.ApplyTracingConfig(&tracingOpts)
+		Expect(tracingOpts).To(Equal(tracing.Options{Endpoint: "otel-collector.garden:4317", Insecure: true, SamplingRatio: 0.1}))
+	})
+
+	It("should reject an invalid tracing sampling ratio", func() {
+		Expect(fs.Parse([]string{"--tracing-sampling-ratio=2"})).To(Succeed())
+
+		Expect(opts.Complete()).To(MatchError(ContainSubstring("invalid tracing sampling ratio 2")))
+	})
+
+	It("should aggregate the EnvoyFilters with the envoyfilter enforcement backend", func() {
+		Expect(fs.Parse([]string{"--config=" + writeConfig(`
+apiVersion: acl.extensions.config.gardener.cloud/v1alpha1
+kind: ControllerConfiguration
+envoyFilterMode: Aggregated
+`)})).To(Succeed())
+		Expect(opts.Complete()).To(Succeed())
+
+		config := controllerconfig.Config{}
+		opts.Apply(&config)
+		Expect(config.AggregateEnvoyFilters).To(BeTrue())
+		Expect(config.CreateAggregatedEnvoyFiltersDirectly).To(BeFalse())
+	})
+
+	It("should create the aggregated EnvoyFilters directly if configured", func() {
+		Expect(fs.Parse([]string{"--config=" + writeConfig(`
+apiVersion: acl.extensions.config.gardener.cloud/v1alpha1
+kind: ControllerConfiguration
+envoyFilterMode: Aggregated
+envoyFilterDeployment: Direct
+`)})).To(Succeed())
+		Expect(opts.Complete()).To(Succeed())
+
+		config := controllerconfig.Config{}
+		opts.Apply(&config)
+		Expect(config.CreateAggregatedEnvoyFiltersDirectly).To(BeTrue())
+	})
+
+	It("should reject aggregated EnvoyFilters with the authorizationpolicy enforcement backend", func() {
+		Expect(fs.Parse([]string{
+			"--enforcement-backend=authorizationpolicy",
+			"--config=" + writeConfig(`
+apiVersion: acl.extensions.config.gardener.cloud/v1alpha1
+kind: ControllerConfiguration
+envoyFilterMode: Aggregated
+`),
+		})).To(Succeed())
+
+		Expect(opts.Complete()).To(MatchError(ContainSubstring("envoyFilterMode Aggregated is only supported")))
+	})
+
+	It("should reject an invalid config file", func() {
+		Expect(fs.Parse([]string{"--config=" + writeConfig(`
+apiVersion: acl.extensions.config.gardener.cloud/v1alpha1
+kind: ControllerConfiguration
+alwaysAllowed:
+  cidrs:
+  - 10.180.0.0
+`)})).To(Succeed())
+
+		Expect(opts.Complete()).To(MatchError(ContainSubstring("alwaysAllowed.cidrs[0]")))
+	})
+})
+
+var _ = Describe("ControllerOptions", func() {
+	var (
+		opts *ControllerOptions
+		fs   *pflag.FlagSet
+	)
+
+	BeforeEach(func() {
+		opts = NewControllerOptions()
+		fs = pflag.NewFlagSet("test", pflag.ContinueOnError)
+		opts.AddFlags(fs)
+	})
+
+	apply := func() (controllerOpts, healthCheckOpts controller.Options) {
+		opts.Completed().Apply(&controllerOpts)
+		opts.Completed().ApplyHealthCheck(&healthCheckOpts)
+		return controllerOpts, healthCheckOpts
+	}
+
+	It("should default to the same number of workers for both controllers", func() {
+		Expect(fs.Parse(nil)).To(Succeed())
+		Expect(opts.Complete()).To(Succeed())
+
+		controllerOpts, healthCheckOpts := apply()
+		Expect(controllerOpts.MaxConcurrentReconciles).To(Equal(DefaultMaxConcurrentReconciles))
+		Expect(healthCheckOpts.MaxConcurrentReconciles).To(Equal(DefaultMaxConcurrentReconciles))
+	})
+
+	It("should let the health checks follow the workers of the extension controller", func() {
+		Expect(fs.Parse([]string{"--max-concurrent-reconciles=50"})).To(Succeed())
+		Expect(opts.Complete()).To(Succeed())
+
+		controllerOpts, healthCheckOpts := apply()
+		Expect(controllerOpts.MaxConcurrentReconciles).To(Equal(50))
+		Expect(healthCheckOpts.MaxConcurrentReconciles).To(Equal(50))
+	})
+
+	It("should override the workers of the health checks", func() {
+		Expect(fs.Parse([]string{"--max-concurrent-reconciles=50", "--healthcheck-max-concurrent-reconciles=10"})).To(Succeed())
+		Expect(opts.Complete()).To(Succeed())
+
+		controllerOpts, healthCheckOpts := apply()
+		Expect(controllerOpts.MaxConcurrentReconciles).To(Equal(50))
+		Expect(healthCheckOpts.MaxConcurrentReconciles).To(Equal(10))
+	})
+
+	It("should reject an invalid number of workers", func() {
+		Expect(fs.Parse([]string{"--max-concurrent-reconciles=0"})).To(Succeed())
+		Expect(opts.Complete()).To(MatchError(ContainSubstring("must be positive")))
+	})
+})
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	_ "embed"
 	"fmt"
 
 	extensionswebhook "github.com/gardener/gardener/extensions/pkg/webhook"
@@ -12,10 +13,10 @@ import (
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/clock"
-	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	defaultwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/yaml"
 
 	"github.com/stackitcloud/gardener-extension-acl/pkg/webhook"
 )
@@ -70,8 +71,21 @@ type AddToManagerConfig struct {
 	Server extensionscmdwebhook.ServerConfig
 	Switch extensionscmdwebhook.SwitchConfig
 	Clock  clock.Clock
+
+	// FailurePolicy overrides the failure policy of the generated webhooks if
+	// set.
+	FailurePolicy *admissionregistrationv1.FailurePolicyType
+	// TimeoutSeconds overrides the timeout of the generated webhooks if set.
+	TimeoutSeconds *int32
+	// NamespaceSelector and ObjectSelector restrict the EnvoyFilters sent to
+	// the generated webhooks if set.
+	NamespaceSelector *metav1.LabelSelector
+	ObjectSelector    *metav1.LabelSelector
 }
 
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+
 // AddToManager instantiates all webhooks of this configuration. If there are any webhooks, it creates a
 // webhook server, registers the webhooks and adds the server to the manager. Otherwise, it is a no-op.
 // It generates and registers the seed targeted webhooks via a MutatingWebhookConfiguration.
@@ -97,7 +111,7 @@ func (c *AddToManagerConfig) AddToManager(ctx context.Context, mgr manager.Manag
 		servicePort = c.Server.ServicePort
 	}
 
-	webhookConfig := BuildWebhookConfig(
+	webhookConfig, err := BuildWebhookConfig(
 		extensionswebhook.BuildClientConfigFor(
 			webhook.WebhookPath,
 			c.Server.Namespace,
@@ -108,6 +122,23 @@ func (c *AddToManagerConfig) AddToManager(ctx context.Context, mgr manager.Manag
 			nil,
 		),
 	)
+	if err != nil {
+		return err
+	}
+	for i := range webhookConfig.Webhooks {
+		if c.FailurePolicy != nil {
+			webhookConfig.Webhooks[i].FailurePolicy = c.FailurePolicy
+		}
+		if c.TimeoutSeconds != nil {
+			webhookConfig.Webhooks[i].TimeoutSeconds = c.TimeoutSeconds
+		}
+		if c.NamespaceSelector != nil {
+			webhookConfig.Webhooks[i].NamespaceSelector = c.NamespaceSelector
+		}
+		if c.ObjectSelector != nil {
+			webhookConfig.Webhooks[i].ObjectSelector = c.ObjectSelector
+		}
+	}
 
 	if c.Server.Namespace == "" {
 		// If the namespace is not set (e.g. when running locally), then we can't use the secrets manager for managing
@@ -186,34 +217,29 @@ func (r runOnceWithLeaderElection) Start(ctx context.Context) error {
 	return r(ctx)
 }
 
+// webhookManifests contains the MutatingWebhookConfiguration generated by
+// controller-gen from the kubebuilder markers of the webhook package, see
+// `make generate`.
+//
+//go:embed generated/manifests.yaml
+var webhookManifests []byte
+
 // BuildWebhookConfig returns MutatingWebhookConfiguration for WebhookClientConfig
-func BuildWebhookConfig(clientConfig admissionregistrationv1.WebhookClientConfig) *admissionregistrationv1.MutatingWebhookConfiguration {
+func BuildWebhookConfig(clientConfig admissionregistrationv1.WebhookClientConfig) (*admissionregistrationv1.MutatingWebhookConfiguration, error) {
+	generated := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	if err := yaml.Unmarshal(webhookManifests, generated); err != nil {
+		return nil, fmt.Errorf("could not decode generated webhook manifests: %w", err)
+	}
+
+	// the generated webhooks contain a placeholder client config
+	for i := range generated.Webhooks {
+		generated.Webhooks[i].ClientConfig = clientConfig
+	}
+
 	return &admissionregistrationv1.MutatingWebhookConfiguration{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: webhook.ExtensionName,
 		},
-		Webhooks: []admissionregistrationv1.MutatingWebhook{
-			{
-				Name:         "acl.stackit.cloud",
-				ClientConfig: clientConfig,
-				Rules: []admissionregistrationv1.RuleWithOperations{
-					{
-						Operations: []admissionregistrationv1.OperationType{
-							admissionregistrationv1.Create,
-							admissionregistrationv1.Update,
-						},
-						Rule: admissionregistrationv1.Rule{
-							APIGroups:   []string{"networking.istio.io"},
-							APIVersions: []string{"v1alpha3"},
-							Resources:   []string{"envoyfilters"},
-						},
-					},
-				},
-				FailurePolicy:           ptr.To(admissionregistrationv1.Fail),
-				SideEffects:             ptr.To(admissionregistrationv1.SideEffectClassNone),
-				TimeoutSeconds:          ptr.To(int32(5)),
-				AdmissionReviewVersions: []string{"v1"},
-			},
-		},
-	}
+		Webhooks: generated.Webhooks,
+	}, nil
 }
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	extensionswebhook "github.com/gardener/gardener/extensions/pkg/webhook"
+	extensionscmdwebhook "github.com/gardener/gardener/extensions/pkg/webhook/cmd"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	defaultwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/webhook"
+)
+
+var _ = Describe("BuildWebhookConfig", func() {
+	It("should build the webhook configuration from the generated manifests", func() {
+		clientConfig := admissionregistrationv1.WebhookClientConfig{
+			URL: ptr.To("https://localhost:9443" + webhook.WebhookPath),
+		}
+
+		config, err := BuildWebhookConfig(clientConfig)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Name).To(Equal(webhook.ExtensionName))
+		Expect(config.Webhooks).To(HaveLen(1))
+		Expect(config.Webhooks[0].Name).To(Equal("acl.stackit.cloud"))
+		Expect(config.Webhooks[0].ClientConfig).To(Equal(clientConfig))
+		Expect(config.Webhooks[0].FailurePolicy).To(Equal(ptr.To(admissionregistrationv1.Fail)))
+		Expect(config.Webhooks[0].Rules).To(ConsistOf(admissionregistrationv1.RuleWithOperations{
+			Operations: []admissionregistrationv1.OperationType{
+				admissionregistrationv1.Create,
+				admissionregistrationv1.Update,
+			},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{"networking.istio.io"},
+				APIVersions: []string{"v1alpha3", "v1beta1", "v1"},
+				Resources:   []string{"envoyfilters"},
+			},
+		}))
+	})
+})
+
+var _ = Describe("AddToManager", func() {
+	var (
+		namespace string
+		certDir   string
+		mgr       manager.Manager
+		cancel    context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "extension-acl-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespace = ns.Name
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, ns)).To(Succeed())
+		})
+
+		certDir = GinkgoT().TempDir()
+
+		var err error
+		mgr, err = manager.New(cfg, manager.Options{
+			Scheme:                 clientScheme,
+			Metrics:                metricsserver.Options{BindAddress: "0"},
+			HealthProbeBindAddress: "0",
+			WebhookServer: defaultwebhook.NewServer(defaultwebhook.Options{
+				Host:    "127.0.0.1",
+				Port:    0,
+				CertDir: certDir,
+			}),
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if cancel != nil {
+			cancel()
+		}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: webhook.ExtensionName},
+		}))).To(Succeed())
+	})
+
+	It("should manage the certificates of the webhook server and the CA bundle of the webhook configuration", func() {
+		config := &AddToManagerConfig{
+			extensionName: webhook.ExtensionName,
+			Server: extensionscmdwebhook.ServerConfig{
+				Mode:      extensionswebhook.ModeURL,
+				URL:       "localhost:9443",
+				Namespace: namespace,
+			},
+			Switch: extensionscmdwebhook.SwitchConfig{
+				WebhooksFactory: func(manager.Manager) ([]*extensionswebhook.Webhook, error) { return nil, nil },
+			},
+		}
+		Expect(config.AddToManager(ctx, mgr)).To(Succeed())
+
+		// the certificates are generated and written before the webhook server starts
+		secrets := &corev1.SecretList{}
+		Expect(k8sClient.List(ctx, secrets, client.InNamespace(namespace), client.MatchingLabels{"managed-by": "secrets-manager"})).To(Succeed())
+		Expect(secrets.Items).To(ContainElements(
+			HaveField("ObjectMeta.Name", HavePrefix("ca-"+webhook.ExtensionName+"-webhook")),
+			HaveField("ObjectMeta.Name", HavePrefix(webhook.ExtensionName+"-webhook-server")),
+		))
+		Expect(filepath.Join(certDir, "tls.crt")).To(BeAnExistingFile())
+		Expect(filepath.Join(certDir, "tls.key")).To(BeAnExistingFile())
+
+		var mgrCtx context.Context
+		mgrCtx, cancel = context.WithCancel(ctx)
+		go func() {
+			defer GinkgoRecover()
+			Expect(mgr.Start(mgrCtx)).To(Succeed())
+		}()
+
+		webhookConfig := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKey{Name: webhook.ExtensionName}, webhookConfig)).To(Succeed())
+			g.Expect(webhookConfig.Webhooks).To(HaveLen(1))
+			g.Expect(webhookConfig.Webhooks[0].ClientConfig.CABundle).ToNot(BeEmpty())
+		}).Should(Succeed())
+
+		serverCert, err := os.ReadFile(filepath.Join(certDir, "tls.crt"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverCert).ToNot(BeEmpty())
+	})
+})
@@ -16,42 +16,54 @@
 package controller
 
 import (
+	"cmp"
 	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/netip"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/gardener/gardener/extensions/pkg/controller"
 	"github.com/gardener/gardener/extensions/pkg/controller/extension"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
 	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
 	v1beta1helper "github.com/gardener/gardener/pkg/apis/core/v1beta1/helper"
 	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	resourcesv1alpha1 "github.com/gardener/gardener/pkg/apis/resources/v1alpha1"
 	"github.com/gardener/gardener/pkg/chartrenderer"
 	"github.com/gardener/gardener/pkg/utils/chart"
 	"github.com/gardener/gardener/pkg/utils/managedresources"
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
-	istionetworkv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"go.opentelemetry.io/otel/attribute"
 	istionetworkv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/stackitcloud/gardener-extension-acl/charts"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/authorizationpolicies"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/imagevector"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/tracing"
 )
 
 const (
@@ -66,6 +78,18 @@ const (
 	// HashAnnotationName name of annotation for triggering the envoyfilter webhook
 	// DEPRECATED: Remove after annotation has been removed from all EnvoyFilters
 	HashAnnotationName = "acl-ext-rule-hash"
+	// SchemaVersion is the version of the objects rendered by this version of
+	// the extension, which is recorded in the ExtensionState. It has to be
+	// increased whenever the names or the schema of the rendered objects
+	// change, so the objects of older versions are migrated on startup.
+	// Version 2 stopped rendering the rules into the legacy acl-vpn
+	// EnvoyFilter shared by all shoots of an istio namespace. Version 3 labels
+	// the EnvoyFilters mutated by the webhook with WebhookLabel. Version 4
+	// matches the VPN listener by its port instead of its address.
+	SchemaVersion = 4
+	// WebhookLabel marks the EnvoyFilters of the shoots which are mutated by
+	// the webhook, so its objectSelector can exclude all other EnvoyFilters.
+	WebhookLabel = "acl.stackit.cloud/webhook"
 	// ImageName is used for the image vector override.
 	// This is currently not implemented correctly.
 	// TODO implement
@@ -77,17 +101,94 @@ const (
 
 // Error variables for controller pkg
 var (
-	ErrSpecAction            = errors.New("action must either be 'ALLOW' or 'DENY'")
-	ErrSpecRule              = errors.New("rule must be present")
-	ErrSpecType              = errors.New("type must either be 'direct_remote_ip', 'remote_ip' or 'source_ip'")
-	ErrSpecCIDR              = errors.New("CIDRs must not be empty")
-	ErrNoExtensionsFound     = errors.New("could not list any extensions")
-	ErrNoAdvertisedAddresses = errors.New("advertised addresses are not available, likely because cluster creation has not yet completed")
+	ErrSpecAction                = errors.New("action must either be 'ALLOW', 'DENY', 'RATE_LIMIT' or 'LOG'")
+	ErrSpecRateLimit             = errors.New("rateLimit must only be set for 'RATE_LIMIT' rules, with a positive connectionsPerSecond and a burst of at least connectionsPerSecond")
+	ErrRateLimitNotSupported     = errors.New("'RATE_LIMIT' rules are not supported by the authorizationpolicy enforcement backend")
+	ErrLogNotSupported           = errors.New("'LOG' rules are not supported by the authorizationpolicy enforcement backend")
+	ErrSpecRule                  = errors.New("rule must be present")
+	ErrSpecInternalRuleRateLimit = errors.New("internalRule must not be a 'RATE_LIMIT' rule")
+	ErrSpecInternalRuleLog       = errors.New("internalRule must not be a 'LOG' rule")
+	ErrSpecType                  = errors.New("type must either be 'direct_remote_ip', 'remote_ip' or 'source_ip'")
+	ErrSpecTypeTarget            = errors.New("type can't be used for the targets of the profile")
+	ErrSpecCIDR                  = errors.New("CIDRs must not be empty")
+	ErrSpecDuplicateCIDR         = errors.New("CIDRs must not contain duplicates")
+	ErrSpecTooManyCIDRs          = errors.New("rule contains too many CIDRs")
+	ErrSpecExcept                = errors.New("except CIDRs must be contained in one of the rule's CIDRs")
+	ErrSpecDescription           = fmt.Errorf("description must not be longer than %d characters", envoyfilters.MaxDescriptionLength)
+	ErrSpecLabels                = errors.New("labels must have qualified names as keys and valid label values")
+	ErrSpecProfile               = errors.New("profile must either be 'apiserver-only' or 'full'")
+	ErrSpecProtectIngress        = errors.New("protectIngress requires the 'full' profile")
+	ErrSpecOpenAccess            = errors.New("'ALLOW' rule allows access from everywhere (0.0.0.0/0 or ::/0), set allowOpenAccess to confirm")
+	ErrSpecIPFamily              = errors.New("rule must contain CIDRs of every IP family of the seed")
+	ErrSpecShadowModeForbidden   = errors.New("'LOG' rules are not allowed for shoots of this purpose")
+	ErrNoAdvertisedAddresses     = errors.New("advertised addresses are not available, likely because cluster creation has not yet completed")
 )
 
 // ExtensionState contains the State of the Extension
 type ExtensionState struct {
+	// SchemaVersion is the SchemaVersion of the extension which last
+	// reconciled the objects of the shoot. It is zero for objects rendered by
+	// versions before the schema version was introduced.
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+	// IstioNamespace is the first of the IstioNamespaces. It is kept for
+	// compatibility with states written by older versions of the extension.
 	IstioNamespace *string `json:"istioNamespace"`
+	// IstioNamespaces contains the namespaces of all istio ingress gateways
+	// serving the shoot, e.g. zonal gateways of HA shoots.
+	IstioNamespaces []string `json:"istioNamespaces,omitempty"`
+	// AlwaysAllowedCIDRs contains the CIDRs which were merged into the rule
+	// of the shoot, i.e. the seed networks, the CIDRs configured by the
+	// operator and the shoot specific CIDRs.
+	AlwaysAllowedCIDRs []string `json:"alwaysAllowedCIDRs,omitempty"`
+	// Allowlist contains the CIDRs of the rule and the always allowed CIDRs
+	// together with their source and the time they were first allowed, e.g.
+	// for access reviews.
+	Allowlist []AllowlistEntry `json:"allowlist,omitempty"`
+	// Warnings contains findings about the ACL configuration which are
+	// surfaced to the Shoot by the health check, e.g. an oversized rule set.
+	Warnings []string `json:"warnings,omitempty"`
+	// GlobalAllowlistChecksum is the checksum of the global allowlist CIDRs
+	// the extension was last reconciled with. It is empty for DENY rules, which
+	// aren't affected by the global allowlist.
+	GlobalAllowlistChecksum string `json:"globalAllowlistChecksum,omitempty"`
+	// GlobalDenylistChecksum is the checksum of the global denylist CIDRs the
+	// extension was last reconciled with.
+	GlobalDenylistChecksum string `json:"globalDenylistChecksum,omitempty"`
+	// Verification contains the result of the last API server reachability
+	// probe, if enabled.
+	Verification *VerificationResult `json:"verification,omitempty"`
+	// Aggregated specifies whether the API server and VPN patches of the shoot
+	// are part of the aggregated EnvoyFilters instead of its dedicated
+	// EnvoyFilters.
+	Aggregated bool `json:"aggregated,omitempty"`
+	// Rules contains the description and the labels of the rules of the
+	// providerConfig, for auditing the ACL of the shoot.
+	Rules []RuleMetadata `json:"rules,omitempty"`
+	// RuleSource is the source of the enforced rules, i.e. the providerConfig
+	// of the shoot or the default rules of its seed or CloudProfile, see
+	// AnnotationDefaultRules.
+	RuleSource string `json:"ruleSource,omitempty"`
+	// History contains the last changes of the applied rule set, see
+	// MaxHistoryEntries.
+	History []HistoryEntry `json:"history,omitempty"`
+	// Disabled specifies whether the enforcement of the ACL is intentionally
+	// disabled, see IsDisabled.
+	Disabled bool `json:"disabled,omitempty"`
+	// OperatorConfigChecksum is the checksum of the operator configuration
+	// the extension was last reconciled with, see OperatorConfigChecksum.
+	OperatorConfigChecksum string `json:"operatorConfigChecksum,omitempty"`
+	// IstioVersion is the istio version of the ingress gateways the filters
+	// were last rendered for.
+	IstioVersion *IstioVersionStatus `json:"istioVersion,omitempty"`
+	// TrafficPaths contains the paths of the traffic to the control plane of
+	// the shoot through the ingress gateways, and whether they are protected
+	// by the ACL.
+	TrafficPaths []TrafficPathStatus `json:"trafficPaths,omitempty"`
+	// GatewayEnvoyFilterBytes contains the size of the specs of all
+	// EnvoyFilters of the istio ingress gateways per istio namespace after
+	// the EnvoyFilters of the shoot were applied. It is only tracked if
+	// MaxGatewayEnvoyFilterBytes is configured.
+	GatewayEnvoyFilterBytes map[string]int `json:"gatewayEnvoyFilterBytes,omitempty"`
 }
 
 // NewActuator returns an actuator responsible for Extension resources.
@@ -97,6 +198,7 @@ func NewActuator(mgr manager.Manager, cfg config.Config) extension.Actuator {
 		client:          mgr.GetClient(),
 		config:          mgr.GetConfig(),
 		decoder:         serializer.NewCodecFactory(mgr.GetScheme(), serializer.EnableStrict).UniversalDecoder(),
+		recorder:        mgr.GetEventRecorderFor(Type + suffix),
 	}
 }
 
@@ -104,17 +206,58 @@ type actuator struct {
 	client          client.Client
 	config          *rest.Config
 	decoder         runtime.Decoder
+	recorder        record.EventRecorder
 	extensionConfig config.Config
 }
 
 // Reconcile the Extension resource.
 //
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=clusters,verbs=get;list;watch
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=infrastructures,verbs=get;list;watch
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=bastions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=extensions,verbs=get;list;watch;patch;update
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=extensions/status,verbs=get;list;watch;patch;update
+// +kubebuilder:rbac:groups=networking.istio.io,resources=envoyfilters,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.istio.io,resources=gateways,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch
+// +kubebuilder:rbac:groups=resources.gardener.cloud,resources=managedresources,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+//
 //nolint:gocyclo // this is the main reconcile loop
-func (a *actuator) Reconcile(ctx context.Context, log logr.Logger, ex *extensionsv1alpha1.Extension) error {
-	cluster, err := helper.GetClusterForExtension(ctx, a.client, ex)
+func (a *actuator) Reconcile(ctx context.Context, log logr.Logger, ex *extensionsv1alpha1.Extension) (err error) {
+	defer func(start time.Time) { observeReconcile(ex.GetNamespace(), start, err) }(time.Now())
+	ctx, span := tracing.Start(ctx, "Reconcile", attribute.String("technicalID", ex.GetNamespace()))
+	defer func() { tracing.End(span, err) }()
+	defer func() { err = a.requeueAfter(err) }()
+
+	if err := a.ensureIstioInstalled(ctx, ex); err != nil {
+		return err
+	}
+
+	clusterCtx, clusterSpan := tracing.Start(ctx, "GetCluster")
+	cluster, err := helper.GetClusterForExtension(clusterCtx, a.client, ex)
+	tracing.End(clusterSpan, err)
 	if err != nil {
 		return err
 	}
+	log = shootLogger(log, ex, cluster)
+	if cluster.Shoot != nil {
+		span.SetAttributes(attribute.String("shoot", client.ObjectKeyFromObject(cluster.Shoot).String()))
+	}
+
+	// the API server and the ingress gateway listeners of hibernated shoots are
+	// gone, so there is nothing to reconcile. The rendered filters are kept
+	// instead of being torn down, so the API server isn't exposed without ACL
+	// while it is woken up. The wake-up reconciles the extension again, which
+	// applies changes made in the meantime.
+	if controller.IsHibernated(cluster) {
+		log.Info("Skipping reconciliation of hibernated shoot")
+		return nil
+	}
 
 	extSpec := &extensionspec.ExtensionSpec{}
 	if ex.Spec.ProviderConfig != nil && ex.Spec.ProviderConfig.Raw != nil {
@@ -122,117 +265,376 @@ func (a *actuator) Reconcile(ctx context.Context, log logr.Logger, ex *extension
 			return err
 		}
 	}
+	// workerless shoots have neither an Infrastructure nor a VPN, see
+	// getShootSpecificCIDRs
+	extSpec.Workerless = v1beta1helper.IsWorkerless(cluster.Shoot)
+	if IsDisabled(ex, extSpec) {
+		return a.disable(ctx, log, ex, shootPurpose(cluster))
+	}
+	ruleSource, err := ApplyDefaultRules(extSpec, cluster)
+	if err != nil {
+		return a.rejectRule(ctx, ex, err)
+	}
+	if err := ResolveTemplateVariables(ctx, a.client, ex, cluster, extSpec); err != nil {
+		if errors.Is(err, ErrSpecVariable) {
+			return a.rejectRule(ctx, ex, err)
+		}
+		return err
+	}
 	// validate the ExtensionSpec
 	if err := ValidateExtensionSpec(extSpec); err != nil {
-		return err
+		return a.rejectRule(ctx, ex, err)
+	}
+	extSpec.Normalize()
+	purpose := shootPurpose(cluster)
+	purposePolicy := a.extensionConfig.PurposePolicies[purpose]
+	if purposePolicy.ForbidShadowMode && extSpec.Rule.IsLog() {
+		return a.rejectRule(ctx, ex, fmt.Errorf("%w: the shoot's purpose is %s", ErrSpecShadowModeForbidden, purpose))
+	}
+	if err := validateCIDRCount(extSpec.Rule, a.extensionConfig.MaxCIDRs); err != nil {
+		return a.rejectRule(ctx, ex, err)
+	}
+	if extSpec.InternalRule != nil {
+		if err := validateCIDRCount(extSpec.InternalRule, a.extensionConfig.MaxCIDRs); err != nil {
+			return a.rejectRule(ctx, ex, fmt.Errorf("internalRule: %w", err))
+		}
+	}
+	seedIPFamilies := helper.GetSeedIPFamilies(cluster.Seed)
+	if err := validateIPFamilies(extSpec.Rule, seedIPFamilies); err != nil {
+		return a.rejectRule(ctx, ex, err)
+	}
+	if extSpec.InternalRule != nil {
+		if err := validateIPFamilies(extSpec.InternalRule, seedIPFamilies); err != nil {
+			return a.rejectRule(ctx, ex, fmt.Errorf("internalRule: %w", err))
+		}
 	}
 
-	istioNamespace, istioLabels, err := a.findIstioNamespaceForExtension(ctx, ex)
+	istioNamespaces, istioLabels, err := a.findIstioNamespacesForExtension(ctx, ex)
 	if err != nil {
-		// we ignore errors for hibernated clusters if they don't have a Gateway
-		// resource for the extension to get the istio namespace from
-		if controller.IsHibernated(cluster) {
-			return client.IgnoreNotFound(err)
+		if apierrors.IsNotFound(err) {
+			a.recorder.Eventf(ex, corev1.EventTypeWarning, EventReasonGatewayNotFound, "Could not find the istio Gateway of the API server: %v", err)
+			if err := a.updateEffectiveCondition(ctx, ex, gardencorev1beta1.ConditionFalse, ReasonIstioGatewayNotFound, err.Error()); err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: %w", ErrGatewayNotFound, err)
 		}
 		return err
 	}
+	log = log.WithValues("gatewayNamespaces", istioNamespaces)
+	span.SetAttributes(attribute.StringSlice("gatewayNamespaces", istioNamespaces))
 
-	extState, err := getExtensionState(ex)
+	extState, err := GetExtensionState(ex)
 	if err != nil {
 		return err
 	}
 
-	if err := a.triggerWebhook(ctx, ex.GetNamespace(), istioNamespace); err != nil {
-		return err
+	for _, istioNamespace := range istioNamespaces {
+		if err := a.triggerWebhook(ctx, ex.GetNamespace(), istioNamespace); err != nil {
+			return err
+		}
 	}
 
-	hosts := make([]string, 0)
-	if cluster.Shoot.Status.AdvertisedAddresses == nil || len(cluster.Shoot.Status.AdvertisedAddresses) < 1 {
+	// the filter chains of the API server are matched by the SNI hostnames of
+	// the shoot instead of the addresses of the gateway's listeners, so the
+	// ACL survives changes of the load balancer IPs
+	hosts := helper.GetAPIServerHosts(cluster.Shoot)
+	if len(hosts) == 0 {
 		return ErrNoAdvertisedAddresses
 	}
-
-	for _, address := range cluster.Shoot.Status.AdvertisedAddresses {
-		hosts = append(hosts, strings.Split(address.URL, "//")[1])
-	}
 	var shootSpecificCIDRs []string
 	var alwaysAllowedCIDRs []string
 
-	alwaysAllowedCIDRs = append(alwaysAllowedCIDRs, helper.GetSeedSpecificAllowedCIDRs(cluster.Seed)...)
+	seedCIDRs := helper.GetSeedSpecificAllowedCIDRs(cluster.Seed)
+	alwaysAllowedCIDRs = append(alwaysAllowedCIDRs, seedCIDRs...)
+
+	// the seed components reach the API server via the istio ingress gateway,
+	// possibly from the addresses of the seed's nodes
+	seedNodeCIDRs, err := helper.GetSeedNodeCIDRs(ctx, a.client)
+	if err != nil {
+		return err
+	}
+	alwaysAllowedCIDRs = append(alwaysAllowedCIDRs, seedNodeCIDRs...)
 
 	if len(a.extensionConfig.AdditionalAllowedCIDRs) >= 1 {
 		alwaysAllowedCIDRs = append(alwaysAllowedCIDRs, a.extensionConfig.AdditionalAllowedCIDRs...)
 	}
+	alwaysAllowedCIDRs = append(alwaysAllowedCIDRs, a.extensionConfig.GardenEgressCIDRs...)
 
-	// Gardener supports workerless Shoots. These don't have an associated
-	// Infrastructure object and don't need Node- or Pod-specific CIDRs to be
-	// allowed. Therefore, skip these steps for workerless Shoots.
-	if !v1beta1helper.IsWorkerless(cluster.Shoot) {
-		shootSpecificCIDRs = append(shootSpecificCIDRs, helper.GetShootNodeSpecificAllowedCIDRs(cluster.Shoot)...)
+	globalAllowedCIDRs, err := helper.GetCIDRsFromConfigMap(ctx, a.client, a.extensionConfig.GlobalAllowlistConfigMap)
+	if err != nil {
+		return err
+	}
+	alwaysAllowedCIDRs = append(alwaysAllowedCIDRs, globalAllowedCIDRs...)
 
-		infra, err := helper.GetInfrastructureForExtension(ctx, a.client, ex, cluster.Shoot.Name)
-		if err != nil {
-			return err
-		}
+	globalDeniedCIDRs, err := helper.GetCIDRsFromConfigMap(ctx, a.client, a.extensionConfig.GlobalDenylistConfigMap)
+	if err != nil {
+		return err
+	}
+	// the baseline denylist of the shoot's purpose is denied like the global
+	// denylist, but it's only part of the operator configuration, so it isn't
+	// covered by the checksum of the global denylist
+	deniedCIDRs := slices.Concat(globalDeniedCIDRs, purposePolicy.DeniedCIDRs)
+	extSpec.Rule.DeniedCIDRs = deniedCIDRs
+	if extSpec.InternalRule != nil {
+		extSpec.InternalRule.DeniedCIDRs = deniedCIDRs
+	}
+	if clamped := clampedCIDRs(extSpec.Rule); len(clamped) > 0 {
+		a.recorder.Eventf(ex, corev1.EventTypeWarning, EventReasonDenylistClamped,
+			"The CIDRs %s of the ACL rule overlap with the global denylist, which takes precedence", strings.Join(clamped, ", "))
+	}
 
-		providerSpecificCIRDs, err := helper.GetProviderSpecificAllowedCIDRs(infra)
-		if err != nil {
-			return err
+	clientIPPreservation, err := a.clientIPPreservation(ctx, istioNamespaces, istioLabels)
+	if err != nil {
+		return err
+	}
+	extSpec.Rule.ProxyProtocol = clientIPPreservation == helper.ClientIPProxied
+	extSpec.Rule.DualStack = helper.IsDualStack(seedIPFamilies)
+	if extSpec.InternalRule != nil {
+		extSpec.InternalRule.ProxyProtocol = extSpec.Rule.ProxyProtocol
+		extSpec.InternalRule.DualStack = extSpec.Rule.DualStack
+	}
+	if clientIPPreservation == helper.ClientIPNATed {
+		a.recorder.Event(ex, corev1.EventTypeWarning, EventReasonClientIPNotPreserved, clientIPNotPreservedMessage)
+	}
+
+	// the egress CIDRs are only needed if they are allowed automatically or
+	// for the VPN listener
+	needsEgressCIDRs := a.extensionConfig.AutoAllowInfrastructureEgressCIDRs || extSpec.HasTarget(extensionspec.TargetVPN)
+	nodeCIDRs, egressCIDRs, err := a.getShootSpecificCIDRs(ctx, ex, cluster, needsEgressCIDRs)
+	if err != nil {
+		return err
+	}
+	shootSpecificCIDRs = append(shootSpecificCIDRs, nodeCIDRs...)
+	if a.extensionConfig.AutoAllowInfrastructureEgressCIDRs {
+		shootSpecificCIDRs = append(shootSpecificCIDRs, egressCIDRs...)
+		// providers might only report the egress CIDRs of one family, the
+		// connections of the shoot's nodes from the other one are subject to
+		// the rule then
+		if missing := missingEgressIPFamilies(cluster, egressCIDRs); len(missing) > 0 {
+			a.recorder.Eventf(ex, corev1.EventTypeWarning, EventReasonEgressCIDRsIncomplete,
+				"The Infrastructure doesn't report %s egress CIDRs of the dual-stack shoot, they aren't allowed automatically", joinIPFamilies(missing))
 		}
+	}
+	// the bastions of the shoot are allowed while they exist, e.g. for
+	// gardenctl ssh sessions of the operators
+	bastionCIDRs, err := helper.GetBastionCIDRs(ctx, a.client, ex.Namespace)
+	if err != nil {
+		return err
+	}
+	shootSpecificCIDRs = append(shootSpecificCIDRs, bastionCIDRs...)
+	// the VPN client of the shoot connects from the shoot's egress IPs, so they
+	// are always allowed for the VPN listener
+	vpnShootSpecificCIDRs := append(append([]string{}, nodeCIDRs...), egressCIDRs...)
+
+	// the networks of the seed and of the shoot's nodes and pods are
+	// protected, so neither the rules nor the global denylist can sever the
+	// connections of the gardenlet and the kubelets
+	var podCIDRs []string
+	if !v1beta1helper.IsWorkerless(cluster.Shoot) {
+		podCIDRs = helper.GetShootPodSpecificAllowedCIDRs(cluster.Shoot)
+	}
+	protectedCIDRs := slices.Concat(seedCIDRs, seedNodeCIDRs, nodeCIDRs, podCIDRs)
+	if a.extensionConfig.AutoAllowInfrastructureEgressCIDRs {
+		protectedCIDRs = append(protectedCIDRs, egressCIDRs...)
+	}
+	extSpec.Rule.ProtectedCIDRs = protectedCIDRs
+	if extSpec.InternalRule != nil {
+		extSpec.InternalRule.ProtectedCIDRs = protectedCIDRs
+	}
 
-		shootSpecificCIDRs = append(shootSpecificCIDRs, providerSpecificCIRDs...)
+	istioVersion, renderings, err := a.detectIstioVersion(ctx, istioNamespaces, istioLabels)
+	if err != nil {
+		return err
+	}
+	if istioVersion.Message != "" {
+		log.Info("Filters might be incompatible with the istio version of the ingress gateways", "message", istioVersion.Message)
 	}
 
-	if err := a.createSeedResources(
+	aggregated, gatewayEnvoyFilterBytes, err := a.createSeedResources(
 		ctx,
 		log,
-		ex.GetNamespace(),
+		ex,
+		extState.Aggregated,
 		extSpec,
 		cluster,
 		hosts,
 		shootSpecificCIDRs,
+		vpnShootSpecificCIDRs,
 		alwaysAllowedCIDRs,
-		istioNamespace,
+		istioNamespaces,
 		istioLabels,
-	); err != nil {
+		renderings,
+	)
+	if err != nil {
 		return err
 	}
+	if err := a.setManagedResourceOwner(ctx, ex); err != nil {
+		return err
+	}
+	if !a.extensionConfig.AggregateEnvoyFilters {
+		if err := a.leaveAggregation(ctx, ex, extState.Aggregated); err != nil {
+			return err
+		}
+	}
+	extState.Aggregated = aggregated
+	extState.Disabled = false
 
-	if err := a.reconcileVPNEnvoyFilter(ctx, alwaysAllowedCIDRs, istioNamespace, istioLabels); err != nil {
+	trafficPaths, trafficPathWarnings, err := a.discoverTrafficPaths(ctx, ex, extSpec, cluster, hosts, istioNamespaces, istioLabels)
+	if err != nil {
 		return err
 	}
 
-	if extState.IstioNamespace != nil && *extState.IstioNamespace != istioNamespace {
-		// we need to cleanup the old vpn object if the istioNamespace changed
-		if err := a.reconcileVPNEnvoyFilter(ctx, alwaysAllowedCIDRs, *extState.IstioNamespace, nil); err != nil {
-			return err
+	extState.IstioNamespace = &istioNamespaces[0]
+	extState.IstioNamespaces = istioNamespaces
+	extState.IstioVersion = istioVersion
+	extState.GatewayEnvoyFilterBytes = gatewayEnvoyFilterBytes
+	extState.AlwaysAllowedCIDRs = sets.List(sets.New(alwaysAllowedCIDRs...).Insert(shootSpecificCIDRs...).Insert(podCIDRs...))
+	extState.Warnings = collectWarnings(extSpec, a.extensionConfig)
+	extState.Rules = rulesMetadata(extSpec)
+	extState.RuleSource = ruleSource
+	extState.TrafficPaths = trafficPaths
+	extState.Warnings = append(extState.Warnings, trafficPathWarnings...)
+	if clientIPPreservation == helper.ClientIPNATed {
+		extState.Warnings = append(extState.Warnings, clientIPNotPreservedMessage)
+	}
+	if extSpec.InternalRule != nil && len(helper.GetInternalAPIServerHosts(cluster.Shoot)) == 0 {
+		extState.Warnings = append(extState.Warnings, internalRuleIgnoredMessage)
+	}
+	now := time.Now()
+	previousAllowlist := extState.Allowlist
+	allowlist := newAllowlistBuilder(extState.Allowlist, now).
+		add(SourceRule, ruleAllowlist(extSpec)...).
+		add(SourceSeed, seedCIDRs...).
+		add(SourceSeedNodes, seedNodeCIDRs...).
+		add(SourceOperator, a.extensionConfig.AdditionalAllowedCIDRs...).
+		add(SourceGarden, a.extensionConfig.GardenEgressCIDRs...).
+		add(SourceGlobalAllowlist, globalAllowedCIDRs...).
+		add(SourceShoot, nodeCIDRs...).
+		add(SourceShoot, podCIDRs...)
+	if a.extensionConfig.AutoAllowInfrastructureEgressCIDRs {
+		allowlist.add(SourceInfrastructure, egressCIDRs...)
+	}
+	allowlist.add(SourceBastion, bastionCIDRs...)
+	extState.Allowlist = allowlist.entries
+	checksum, err := ruleSetChecksum(extSpec, extState.Allowlist)
+	if err != nil {
+		return err
+	}
+	// periodic resyncs apply the same rules again, which isn't worth an event
+	rulesChanged := len(extState.History) == 0 || extState.History[len(extState.History)-1].Checksum != checksum
+	extState.History = recordHistory(extState.History, ex.Generation, checksum, previousAllowlist, extState.Allowlist, now)
+	extState.GlobalAllowlistChecksum, extState.GlobalDenylistChecksum = GlobalListChecksums(extSpec, globalAllowedCIDRs, globalDeniedCIDRs)
+	extState.OperatorConfigChecksum = OperatorConfigChecksum(a.extensionConfig)
+
+	previousVerification := extState.Verification
+	extState.Verification = nil
+	// the API server is scaled down or not yet running while the shoot is
+	// hibernating or waking up
+	if a.extensionConfig.VerifyAPIServerReachability && !controller.IsHibernatingOrWakingUp(cluster) {
+		extState.Verification = previousVerification
+		if needsVerification(previousVerification, hosts[0], rulesChanged) {
+			extState.Verification = verifyAPIServerReachability(ctx, hosts[0])
+			if !extState.Verification.Reachable {
+				log.Info("API server is not reachable after applying the ACL", "address", extState.Verification.Address, "error", extState.Verification.Message)
+			}
+		}
+		if !extState.Verification.Reachable {
+			extState.Warnings = append(extState.Warnings, verificationWarning(extState.Verification))
 		}
 	}
 
-	extState.IstioNamespace = &istioNamespace
+	extState.SchemaVersion = SchemaVersion
+	if err := a.updateStatus(ctx, ex, extState); err != nil {
+		return err
+	}
+	if err := a.updateWarningsCondition(ctx, ex, extState.Warnings); err != nil {
+		return err
+	}
+	status, reason, message := effectiveCondition(extSpec, trafficPaths)
+	if err := a.updateEffectiveCondition(ctx, ex, status, reason, message); err != nil {
+		return err
+	}
+	if err := a.deployShootInfo(ctx, ex.GetNamespace(), shootInfoData(extSpec, a.extensionConfig.EnforcementBackend)); err != nil {
+		return err
+	}
+	if rulesChanged {
+		a.recorder.Eventf(ex, corev1.EventTypeNormal, EventReasonRulesApplied, "Applied the %s rule with %d CIDRs to the istio namespaces %s",
+			extSpec.Rule.Action, len(extSpec.Rule.Cidrs), strings.Join(istioNamespaces, ", "))
+	}
 
-	return a.updateStatus(ctx, ex, extState)
+	recordShoot(ex.GetNamespace(), purpose, len(extSpec.Rule.Cidrs)+len(extSpec.Rule.Except)+len(deniedCIDRs)+
+		len(alwaysAllowedCIDRs)+len(shootSpecificCIDRs), isOpenPolicy(extSpec.Rule))
+	return nil
 }
 
 // ValidateExtensionSpec checks if the ExtensionSpec exists, and if its action,
-// type and CIDRs are valid.
+// rate limit, type, CIDRs, except CIDRs and profile are valid. The CIDRs must
+// not contain duplicates and "ALLOW" rules covering the whole address space
+// have to be confirmed with allowOpenAccess. The optional internal rule is
+// validated the same way, but must neither be a "RATE_LIMIT" nor a "LOG"
+// rule. protectIngress requires a profile with the ingress target. Known
+// template variables are accepted in the CIDRs, see ResolveTemplateVariables.
 func ValidateExtensionSpec(spec *extensionspec.ExtensionSpec) error {
 	rule := spec.Rule
 
 	if rule == nil {
 		return ErrSpecRule
 	}
+	if err := validateRule(rule, spec.AllowOpenAccess); err != nil {
+		return err
+	}
+	if target, hint, unsupported := spec.UnsupportedType(); unsupported {
+		return fmt.Errorf("%w: %q can't be used for the %s target, %s", ErrSpecTypeTarget, rule.Type, target, hint)
+	}
+
+	// internal rule
+	if internalRule := spec.InternalRule; internalRule != nil {
+		if internalRule.IsRateLimit() {
+			return ErrSpecInternalRuleRateLimit
+		}
+		if internalRule.IsLog() {
+			return ErrSpecInternalRuleLog
+		}
+		if err := validateRule(internalRule, spec.AllowOpenAccess); err != nil {
+			return fmt.Errorf("internalRule: %w", err)
+		}
+		if hint, unsupported := spec.UnsupportedInternalRuleType(); unsupported {
+			return fmt.Errorf("internalRule: %w: %q can't be used for the %s target, %s",
+				ErrSpecTypeTarget, internalRule.Type, extensionspec.TargetAPIServer, hint)
+		}
+	}
+
+	// profile
+	if !extensionspec.IsValidProfile(spec.Profile) {
+		return ErrSpecProfile
+	}
+	if spec.ProtectIngress && !spec.HasTarget(extensionspec.TargetIngress) {
+		return ErrSpecProtectIngress
+	}
+
+	return nil
+}
 
+// validateRule checks the action, rate limit, type, CIDRs and except CIDRs of
+// a rule.
+func validateRule(rule *envoyfilters.ACLRule, allowOpenAccess bool) error {
 	// action
 	a := strings.ToLower(rule.Action)
-	if a != "allow" && a != "deny" {
+	if a != "allow" && a != "deny" && a != "rate_limit" && a != "log" {
 		return ErrSpecAction
 	}
 
+	// rate limit
+	if rule.IsRateLimit() != (rule.RateLimit != nil) {
+		return ErrSpecRateLimit
+	}
+	if rule.RateLimit != nil && (rule.RateLimit.ConnectionsPerSecond == 0 ||
+		(rule.RateLimit.Burst != 0 && rule.RateLimit.Burst < rule.RateLimit.ConnectionsPerSecond)) {
+		return ErrSpecRateLimit
+	}
+
 	// type
-	t := strings.ToLower(rule.Type)
-	if t != "direct_remote_ip" &&
-		t != "remote_ip" &&
-		t != "source_ip" {
+	if !slices.Contains(envoyfilters.Types(), strings.ToLower(rule.Type)) {
 		return ErrSpecType
 	}
 
@@ -241,7 +643,17 @@ func ValidateExtensionSpec(spec *extensionspec.ExtensionSpec) error {
 		return ErrSpecCIDR
 	}
 
+	// template variables are resolved before the rules are rendered, see
+	// ResolveTemplateVariables
+	hasVariables := false
 	for ii := range rule.Cidrs {
+		if IsTemplateVariable(rule.Cidrs[ii]) {
+			if !slices.Contains(TemplateVariables(), rule.Cidrs[ii]) {
+				return fmt.Errorf("%w: %s", ErrSpecVariable, rule.Cidrs[ii])
+			}
+			hasVariables = true
+			continue
+		}
 		_, mask, err := net.ParseCIDR(rule.Cidrs[ii])
 		if err != nil {
 			return err
@@ -251,41 +663,150 @@ func ValidateExtensionSpec(spec *extensionspec.ExtensionSpec) error {
 		}
 	}
 
+	if cidr := envoyfilters.DuplicateCIDR(rule.Cidrs); cidr != "" {
+		return fmt.Errorf("%w: %s", ErrSpecDuplicateCIDR, cidr)
+	}
+	if cidr := duplicateTemplateVariable(rule.Cidrs); cidr != "" {
+		return fmt.Errorf("%w: %s", ErrSpecDuplicateCIDR, cidr)
+	}
+
+	// open access
+	if strings.EqualFold(rule.Action, envoyfilters.ActionAllow) && rule.MatchesEverything() && !allowOpenAccess {
+		return ErrSpecOpenAccess
+	}
+
+	// except
+	cidrs := envoyfilters.NewCIDRSet(rule.Cidrs...)
+	for ii := range rule.Except {
+		if _, _, err := net.ParseCIDR(rule.Except[ii]); err != nil {
+			return err
+		}
+		except, err := netip.ParsePrefix(rule.Except[ii])
+		if err != nil || (!hasVariables && !cidrs.ContainsPrefix(except)) {
+			return ErrSpecExcept
+		}
+	}
+
+	// metadata
+	if len(rule.Description) > envoyfilters.MaxDescriptionLength {
+		return ErrSpecDescription
+	}
+	for _, key := range sets.List(sets.KeySet(rule.Labels)) {
+		if len(validation.IsQualifiedName(key)) > 0 || len(validation.IsValidLabelValue(rule.Labels[key])) > 0 {
+			return fmt.Errorf("%w: %s", ErrSpecLabels, key)
+		}
+	}
+
+	return nil
+}
+
+// validateIPFamilies checks if the (already validated) rule contains CIDRs of
+// every IP family of a dual-stack or IPv6-only seed. "ALLOW" and "RATE_LIMIT"
+// rules restrict all sources they don't contain, so a rule with e.g. only IPv4
+// CIDRs would silently deny all IPv6 connections to the shoot.
+func validateIPFamilies(rule *envoyfilters.ACLRule, seedIPFamilies []gardencorev1beta1.IPFamily) error {
+	if !slices.Contains(seedIPFamilies, gardencorev1beta1.IPFamilyIPv6) || !rule.RestrictsOtherSources() {
+		return nil
+	}
+	if missing := helper.MissingIPFamilies(rule.Cidrs, seedIPFamilies); len(missing) > 0 {
+		return fmt.Errorf("%w: all %s connections would be denied, add their CIDRs to the rule", ErrSpecIPFamily, joinIPFamilies(missing))
+	}
+	return nil
+}
+
+// missingEgressIPFamilies returns the IP families of a dual-stack shoot in a
+// dual-stack seed without any of the shoot's egress CIDRs. Nothing is
+// returned if there are no egress CIDRs at all.
+func missingEgressIPFamilies(cluster *controller.Cluster, egressCIDRs []string) []gardencorev1beta1.IPFamily {
+	shootIPFamilies := helper.GetShootIPFamilies(cluster.Shoot)
+	if len(egressCIDRs) == 0 || !helper.IsDualStack(shootIPFamilies) || !helper.IsDualStack(helper.GetSeedIPFamilies(cluster.Seed)) {
+		return nil
+	}
+	return helper.MissingIPFamilies(egressCIDRs, shootIPFamilies)
+}
+
+func joinIPFamilies(families []gardencorev1beta1.IPFamily) string {
+	names := make([]string, 0, len(families))
+	for _, family := range families {
+		names = append(names, string(family))
+	}
+	return strings.Join(names, " and ")
+}
+
+// validateCIDRCount checks if the (already validated) rule contains at most
+// maxCIDRs CIDRs, including its except blocks. A maxCIDRs of 0 means no limit.
+func validateCIDRCount(rule *envoyfilters.ACLRule, maxCIDRs int) error {
+	if maxCIDRs > 0 && rule.CIDRCount() > maxCIDRs {
+		return fmt.Errorf("%w: %d CIDRs are configured, but at most %d are allowed", ErrSpecTooManyCIDRs, rule.CIDRCount(), maxCIDRs)
+	}
 	return nil
 }
 
 // Delete the Extension resource.
 func (a *actuator) Delete(ctx context.Context, log logr.Logger, ex *extensionsv1alpha1.Extension) error {
+	log = shootLogger(log, ex, nil)
+	log.Info("Component is being deleted", "component", "", "namespace", ex.GetNamespace())
+
+	if err := a.removeEnforcement(ctx, log, ex); err != nil {
+		return err
+	}
+	if err := managedresources.DeleteForShoot(ctx, a.client, ex.GetNamespace(), ResourceNameShoot); err != nil {
+		return err
+	}
+
+	forgetShoot(ex.GetNamespace())
+	return nil
+}
+
+// removeEnforcement deletes the seed resources and the aggregated patches of
+// the shoot and removes the patches of the webhook from its EnvoyFilters.
+func (a *actuator) removeEnforcement(ctx context.Context, log logr.Logger, ex *extensionsv1alpha1.Extension) error {
 	namespace := ex.GetNamespace()
-	log.Info("Component is being deleted", "component", "", "namespace", namespace)
 
 	if err := a.deleteSeedResources(ctx, log, namespace); err != nil {
 		return err
 	}
+	aggregatedPatches := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: AggregatedPatchesConfigMapName, Namespace: namespace}}
+	if err := a.client.Delete(ctx, aggregatedPatches); client.IgnoreNotFound(err) != nil {
+		return err
+	}
 
-	var istioNamespace string
+	istioInstalled, err := helper.IsIstioInstalled(a.client.RESTMapper())
+	if err != nil {
+		return err
+	}
+	if !istioInstalled {
+		// without istio, there are no EnvoyFilters to clean up
+		return nil
+	}
 
-	istioNamespace, _, err := a.findIstioNamespaceForExtension(ctx, ex)
+	istioNamespaces, _, err := a.findIstioNamespacesForExtension(ctx, ex)
 	if client.IgnoreNotFound(err) != nil {
 		return err
 	}
-	if apierrors.IsNotFound(err) {
-		exState, err := getExtensionState(ex)
-		if err != nil {
-			return err
-		}
-		if exState.IstioNamespace == nil {
-			// we have never reconciled this cluster completely, therefore no
-			// cleanup needs to be performed
-			return nil
-		}
 
-		// the cluster has no Gateway object, but we can get the information
-		// from the extension state
-		istioNamespace = *exState.IstioNamespace
+	exState, err := GetExtensionState(ex)
+	if err != nil {
+		return err
 	}
 
-	return a.triggerWebhook(ctx, namespace, istioNamespace)
+	// the cluster might have no Gateway object anymore, or the Gateway might
+	// have moved to another istio namespace since the last reconciliation, so
+	// we clean up all istio namespaces recorded in the extension state, too -
+	// if we have never reconciled this cluster completely, no cleanup needs to
+	// be performed
+	for _, istioNamespace := range exState.GetIstioNamespaces() {
+		if !slices.Contains(istioNamespaces, istioNamespace) {
+			istioNamespaces = append(istioNamespaces, istioNamespace)
+		}
+	}
+
+	for _, istioNamespace := range istioNamespaces {
+		if err := a.triggerWebhook(ctx, namespace, istioNamespace); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // ForceDelete implements Network.Actuator.
@@ -303,121 +824,216 @@ func (a *actuator) Migrate(ctx context.Context, log logr.Logger, ex *extensionsv
 	return a.Delete(ctx, log, ex)
 }
 
-func (a *actuator) reconcileVPNEnvoyFilter(
-	ctx context.Context,
-	alwaysAllowedCIDRs []string,
-	istioNamespace string,
-	istioLabels map[string]string,
-) error {
-	aclMappings, istioLabelsFromExt, err := a.getAllShootsWithACLExtension(ctx, istioNamespace)
-	if err != nil {
+// setManagedResourceOwner makes the Extension the owner of its seed
+// ManagedResource, so the objects of the shoot are garbage collected together
+// with the Extension, even if it is removed without being deleted by the
+// actuator.
+func (a *actuator) setManagedResourceOwner(ctx context.Context, ex *extensionsv1alpha1.Extension) error {
+	mr := &resourcesv1alpha1.ManagedResource{}
+	if err := a.client.Get(ctx, client.ObjectKey{Namespace: ex.GetNamespace(), Name: ResourceNameSeed}, mr); err != nil {
 		return err
 	}
 
-	// build EnvoyFilter object as map[string]interface{}
-	// because the actual EnvoyFilter struct is a pain to type
-	name := "acl-vpn"
+	patch := client.MergeFrom(mr.DeepCopy())
+	if err := controllerutil.SetOwnerReference(ex, mr, a.client.Scheme()); err != nil {
+		return err
+	}
+	return a.client.Patch(ctx, mr, patch)
+}
 
-	// build EnvoyFilter object as map[string]interface{}
-	// because the actual EnvoyFilter struct is a pain to type
-	envoyFilter := &unstructured.Unstructured{}
-	envoyFilter.SetGroupVersionKind(istionetworkv1alpha3.SchemeGroupVersion.WithKind("EnvoyFilter"))
-	envoyFilter.SetNamespace(istioNamespace)
-	envoyFilter.SetName(name)
+func (a *actuator) createSeedResources(
+	ctx context.Context,
+	log logr.Logger,
+	ex *extensionsv1alpha1.Extension,
+	wasAggregated bool,
+	spec *extensionspec.ExtensionSpec,
+	cluster *controller.Cluster,
+	hosts []string,
+	shootSpecificCIRDs []string,
+	vpnShootSpecificCIDRs []string,
+	alwaysAllowedCIDRs []string,
+	istioNamespaces []string,
+	istioLabels map[string]string,
+	renderings envoyfilters.Renderings,
+) (bool, map[string]int, error) {
+	// The `nginx-ingress-controller` Gateway object only exists in g/g@v1.89, (introduced with
+	// https://github.com/gardener/gardener/pull/9038).
+	// If it doesn't exist yet, we can't apply ACLs to shoot ingresses.
+	ingressIstioLabels, err := a.findDefaultIstioLabels(ctx)
+	if client.IgnoreNotFound(err) != nil {
+		return false, nil, err
+	}
 
-	if len(aclMappings) == 0 {
-		// no shoot in this namespace with the ACL extension, so we can delete the config
-		err = a.client.Delete(ctx, envoyFilter)
-		return client.IgnoreNotFound(err)
+	_, renderSpan := tracing.Start(ctx, "SeedChartValues")
+	cfg, err := SeedChartValues(
+		a.extensionConfig,
+		spec,
+		cluster,
+		hosts,
+		shootSpecificCIRDs,
+		vpnShootSpecificCIDRs,
+		alwaysAllowedCIDRs,
+		istioNamespaces,
+		istioLabels,
+		ingressIstioLabels,
+		renderings,
+	)
+	tracing.End(renderSpan, err)
+	if err != nil {
+		return false, nil, err
 	}
-	if istioLabels == nil {
-		istioLabels = istioLabelsFromExt
+	envoyFilterWriter, err := envoyfilters.DiscoverWriter(a.client.RESTMapper())
+	if err != nil {
+		return false, nil, err
 	}
+	cfg["envoyFilterAPIVersion"] = envoyFilterWriter.APIVersion()
 
-	vpnEnvoyFilterSpec, err := envoyfilters.BuildLegacyVPNEnvoyFilterSpecForHelmChart(
-		aclMappings, alwaysAllowedCIDRs, istioLabels,
-	)
+	gatewayEnvoyFilterBytes, err := a.checkConfigSize(ctx, ex, cfg, istioNamespaces)
 	if err != nil {
-		return err
+		return false, nil, err
 	}
 
-	err = a.client.Get(ctx, client.ObjectKeyFromObject(envoyFilter), envoyFilter)
-	if client.IgnoreNotFound(err) != nil {
-		return err
+	aggregated := false
+	if a.extensionConfig.AggregateEnvoyFilters {
+		if aggregated, err = a.aggregatePatches(ctx, ex, cfg, istioNamespaces, istioLabels, wasAggregated); err != nil {
+			return false, nil, err
+		}
+	}
+	if aggregated {
+		// the aggregated EnvoyFilters contain the patches of the shoot
+		delete(cfg, "apiEnvoyFilterSpec")
+		delete(cfg, "vpnEnvoyFilterSpec")
 	}
 
-	envoyFilter.Object["spec"] = vpnEnvoyFilterSpec
+	cfg, err = chart.InjectImages(cfg, imagevector.ImageVector(), []string{ImageName})
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to find image version for %s: %v", ImageName, err)
+	}
 
-	if apierrors.IsNotFound(err) {
-		return a.client.Create(ctx, envoyFilter)
+	renderer, err := chartrenderer.NewForConfig(a.config)
+	if err != nil {
+		return false, nil, errors.Wrap(err, "could not create chart renderer")
 	}
 
-	return a.client.Update(ctx, envoyFilter)
+	namespace := ex.GetNamespace()
+	log.Info("Component is being applied", "component", "component-name", "namespace", namespace, "aggregated", aggregated)
+
+	return aggregated, gatewayEnvoyFilterBytes, a.createManagedResource(ctx, namespace, ResourceNameSeed, "seed", renderer, ChartNameSeed, namespace, cfg, nil, charts.Seed)
 }
 
-func (a *actuator) createSeedResources(
-	ctx context.Context,
-	log logr.Logger,
-	namespace string,
+// SeedChartValues returns the values of the seed chart rendering the
+// EnvoyFilters or AuthorizationPolicies of the shoot. The ingress of the shoot
+// is only restricted if ingressIstioLabels of the seed's ingress gateway are
+// given. The typed configs of the EnvoyFilters are rendered as supported by
+// the istio version of the gateways, see envoyfilters.RenderingsFor.
+func SeedChartValues(
+	extensionConfig config.Config,
 	spec *extensionspec.ExtensionSpec,
 	cluster *controller.Cluster,
 	hosts []string,
 	shootSpecificCIRDs []string,
+	vpnShootSpecificCIDRs []string,
 	alwaysAllowedCIDRs []string,
-	istioNamespace string,
+	istioNamespaces []string,
 	istioLabels map[string]string,
-) error {
+	ingressIstioLabels map[string]string,
+	renderings envoyfilters.Renderings,
+) (map[string]interface{}, error) {
 	var err error
 
-	alwaysAllowedCIDRs = append(alwaysAllowedCIDRs, shootSpecificCIRDs...)
+	vpnAllowedCIDRs := append(append([]string{}, alwaysAllowedCIDRs...), vpnShootSpecificCIDRs...)
+	// the VPN connection of the shoot originates from its egress CIDRs, they
+	// are protected on the VPN listener even if they aren't allowed otherwise
+	vpnRule := spec.Rule.WithProtectedCIDRs(vpnShootSpecificCIDRs...)
+	alwaysAllowedCIDRs = append(append([]string{}, alwaysAllowedCIDRs...), shootSpecificCIRDs...)
 
-	apiEnvoyFilterSpec, err := envoyfilters.BuildAPIEnvoyFilterSpecForHelmChart(
-		spec.Rule, hosts, alwaysAllowedCIDRs, istioLabels,
-	)
-	if err != nil {
-		return err
+	cfg := map[string]interface{}{
+		"shootName":        cluster.Shoot.Status.TechnicalID,
+		"targetNamespaces": istioNamespaces,
 	}
 
-	vpnEnvoyFilterSpec, err := envoyfilters.BuildVPNEnvoyFilterSpecForHelmChart(
-		cluster, spec.Rule, alwaysAllowedCIDRs, istioLabels,
-	)
-	if err != nil {
-		return err
+	// the internal rule replaces the rule for the connections to the internal
+	// address of the API server, if the shoot has one
+	internalRule := spec.InternalRule
+	internalHosts := helper.GetInternalAPIServerHosts(cluster.Shoot)
+	if len(internalHosts) == 0 {
+		internalRule = nil
 	}
 
-	cfg := map[string]interface{}{
-		"shootName":          cluster.Shoot.Status.TechnicalID,
-		"targetNamespace":    istioNamespace,
-		"apiEnvoyFilterSpec": apiEnvoyFilterSpec,
-		"vpnEnvoyFilterSpec": vpnEnvoyFilterSpec,
+	if cfg["ruleAnnotations"], err = ruleAnnotations(spec.Rule, AnnotationRuleDescription, AnnotationRuleLabels); err != nil {
+		return nil, err
 	}
-
-	defaultLabels, err := a.findDefaultIstioLabels(ctx)
-	if client.IgnoreNotFound(err) != nil {
-		return err
-	} else if err == nil {
-		// The `nginx-ingress-controller` Gateway object only exists in g/g@v1.89, (introduced with
-		// https://github.com/gardener/gardener/pull/9038).
-		// If it doesn't exist yet, we can't apply ACLs to shoot ingresses.
-		ingressEnvoyFilterSpec := envoyfilters.BuildIngressEnvoyFilterSpecForHelmChart(
-			cluster, spec.Rule, alwaysAllowedCIDRs, defaultLabels)
-
-		cfg["ingressEnvoyFilterSpec"] = ingressEnvoyFilterSpec
+	if cfg["internalRuleAnnotations"], err = ruleAnnotations(internalRule, AnnotationInternalRuleDescription, AnnotationInternalRuleLabels); err != nil {
+		return nil, err
 	}
 
-	cfg, err = chart.InjectImages(cfg, imagevector.ImageVector(), []string{ImageName})
-	if err != nil {
-		return fmt.Errorf("failed to find image version for %s: %v", ImageName, err)
+	if extensionConfig.EnforcementBackend == config.EnforcementBackendAuthorizationPolicy {
+		if spec.Rule.IsRateLimit() {
+			return nil, ErrRateLimitNotSupported
+		}
+		if spec.Rule.IsLog() {
+			return nil, ErrLogNotSupported
+		}
+		apiHosts := hosts
+		if internalRule != nil {
+			apiHosts = slices.DeleteFunc(slices.Clone(hosts), func(host string) bool { return slices.Contains(internalHosts, host) })
+			cfg["apiInternalAuthorizationPolicySpec"], err = authorizationpolicies.BuildAPIAuthorizationPolicySpecForHelmChart(
+				internalRule, internalHosts, alwaysAllowedCIDRs, istioLabels,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+		cfg["apiAuthorizationPolicySpec"], err = authorizationpolicies.BuildAPIAuthorizationPolicySpecForHelmChart(
+			spec.Rule, apiHosts, alwaysAllowedCIDRs, istioLabels,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if spec.HasTarget(extensionspec.TargetVPN) {
+			cfg["vpnAuthorizationPolicySpec"] = authorizationpolicies.BuildVPNAuthorizationPolicySpecForHelmChart(
+				cluster, vpnRule, vpnAllowedCIDRs, istioLabels,
+			)
+		}
+	} else {
+		cfg["apiEnvoyFilterSpec"], err = envoyfilters.BuildAPIEnvoyFilterSpecForHelmChart(
+			spec.Rule, internalRule, cluster.Shoot.Status.TechnicalID, hosts, internalHosts, alwaysAllowedCIDRs, istioLabels,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if spec.HasTarget(extensionspec.TargetVPN) {
+			cfg["vpnEnvoyFilterSpec"], err = envoyfilters.BuildVPNEnvoyFilterSpecForHelmChart(
+				cluster, vpnRule, vpnAllowedCIDRs, istioLabels, renderings,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	renderer, err := chartrenderer.NewForConfig(a.config)
-	if err != nil {
-		return errors.Wrap(err, "could not create chart renderer")
+	// the access logs are filtered by CEL expressions, which older gateways
+	// don't support. "LOG" rules always log the connections they match.
+	if (spec.ShouldLogDeniedConnections(extensionConfig.LogDeniedConnections) || spec.Rule.IsLog()) && renderings.AccessLogFilter {
+		cfg["accessLogEnvoyFilterSpec"], err = envoyfilters.BuildAccessLogEnvoyFilterSpecForHelmChart(
+			cluster, hosts, spec.HasTarget(extensionspec.TargetVPN), spec.Rule.IsLog(), istioLabels,
+		)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	log.Info("Component is being applied", "component", "component-name", "namespace", namespace)
+	if ingressIstioLabels != nil && spec.HasTarget(extensionspec.TargetIngress) {
+		if extensionConfig.EnforcementBackend == config.EnforcementBackendAuthorizationPolicy {
+			cfg["ingressAuthorizationPolicySpec"] = authorizationpolicies.BuildIngressAuthorizationPolicySpecForHelmChart(
+				cluster, spec.Rule, alwaysAllowedCIDRs, ingressIstioLabels, spec.ProtectIngress)
+		} else {
+			cfg["ingressEnvoyFilterSpec"] = envoyfilters.BuildIngressEnvoyFilterSpecForHelmChart(
+				cluster, spec.Rule, alwaysAllowedCIDRs, ingressIstioLabels, spec.ProtectIngress)
+		}
+	}
 
-	return a.createManagedResource(ctx, namespace, ResourceNameSeed, "seed", renderer, ChartNameSeed, namespace, cfg, nil, charts.Seed)
+	return cfg, nil
 }
 
 func (a *actuator) deleteSeedResources(ctx context.Context, log logr.Logger, namespace string) error {
@@ -440,12 +1056,17 @@ func (a *actuator) createManagedResource(
 	chartValues map[string]interface{},
 	injectedLabels map[string]string,
 	embeddedChart embed.FS,
-) error {
+) (err error) {
+	_, renderSpan := tracing.Start(ctx, "RenderChart", attribute.String("chart", chartName))
 	renderedChart, err := renderer.RenderEmbeddedFS(embeddedChart, chartName, chartName, chartNamespace, chartValues)
+	tracing.End(renderSpan, err)
 	if err != nil {
 		return err
 	}
 
+	ctx, applySpan := tracing.Start(ctx, "ApplyManagedResource", attribute.String("managedResource", name))
+	defer func() { tracing.End(applySpan, err) }()
+
 	data := map[string][]byte{chartName: renderedChart.Manifest()}
 	keepObjects := false
 	forceOverwriteAnnotations := false
@@ -480,7 +1101,21 @@ func (a *actuator) updateStatus(
 	return a.client.Status().Patch(ctx, ex, patch)
 }
 
-func getExtensionState(ex *extensionsv1alpha1.Extension) (*ExtensionState, error) {
+// GetIstioNamespaces returns the istio namespaces recorded in the state,
+// falling back to the single IstioNamespace of older states.
+func (s *ExtensionState) GetIstioNamespaces() []string {
+	if len(s.IstioNamespaces) > 0 {
+		return s.IstioNamespaces
+	}
+	if s.IstioNamespace != nil {
+		return []string{*s.IstioNamespace}
+	}
+	return nil
+}
+
+// GetExtensionState decodes the ExtensionState from the status of the given
+// Extension.
+func GetExtensionState(ex *extensionsv1alpha1.Extension) (*ExtensionState, error) {
 	extState := &ExtensionState{}
 	if ex.Status.State != nil && ex.Status.State.Raw != nil {
 		if err := json.Unmarshal(ex.Status.State.Raw, &extState); err != nil {
@@ -491,21 +1126,35 @@ func getExtensionState(ex *extensionsv1alpha1.Extension) (*ExtensionState, error
 	return extState, nil
 }
 
+// GlobalListChecksums returns the checksums of the global allowlist and
+// denylist CIDRs which are effective for the given ExtensionSpec. The always
+// allowed CIDRs are only merged into ALLOW and RATE_LIMIT rules, so the
+// allowlist checksum of a DENY rule is empty and changes of the global allowlist don't need to be
+// rendered for it.
+func GlobalListChecksums(spec *extensionspec.ExtensionSpec, allowedCIDRs, deniedCIDRs []string) (allowlistChecksum, denylistChecksum string) {
+	denylistChecksum = helper.ComputeCIDRsChecksum(deniedCIDRs)
+	if spec.Rule != nil && !spec.Rule.RestrictsOtherSources() {
+		return "", denylistChecksum
+	}
+	return helper.ComputeCIDRsChecksum(allowedCIDRs), denylistChecksum
+}
+
 // triggerWebhook allows us to "reconcile" the existing EnvoyFilter which we
-// need to modify using a mutating webhook. This is achieved by sending an empty
-// patch for this EnvoyFilter, which invokes the ACL webhook component. This
-// makes sure this EnvoyFilter is kept in sync with the ACL extension config
-// (e.g. when the allowed CIDRS from the extension spec or the
-// alwaysAllowedCIDRs change).
+// need to modify using a mutating webhook. This is achieved by sending a patch
+// only adding the WebhookLabel to this EnvoyFilter, which invokes the ACL
+// webhook component. This makes sure this EnvoyFilter is kept in sync with the
+// ACL extension config (e.g. when the allowed CIDRS from the extension spec or
+// the alwaysAllowedCIDRs change), and that it is selected by the webhook's
+// objectSelector.
 func (a *actuator) triggerWebhook(ctx context.Context, shootName, istioNamespace string) error {
-	// get envoyfilter with the shoot's name
-	envoyFilter := &istionetworkv1alpha3.EnvoyFilter{}
-	namespacedName := types.NamespacedName{
-		Namespace: istioNamespace,
-		Name:      shootName,
+	envoyFilterWriter, err := envoyfilters.DiscoverWriter(a.client.RESTMapper())
+	if err != nil {
+		return err
 	}
 
-	if err := a.client.Get(ctx, namespacedName, envoyFilter); err != nil {
+	// get envoyfilter with the shoot's name
+	envoyFilter := envoyFilterWriter.New(istioNamespace, shootName)
+	if err := a.client.Get(ctx, client.ObjectKeyFromObject(envoyFilter), envoyFilter); err != nil {
 		return client.IgnoreNotFound(err)
 	}
 
@@ -516,136 +1165,86 @@ func (a *actuator) triggerWebhook(ctx context.Context, shootName, istioNamespace
 	// the EnvoyFilter when the alwaysAllowedCIDRs changed, which wasn't
 	// correctly handled before
 	// --> migration code start
-	if _, ok := envoyFilter.Annotations[HashAnnotationName]; ok {
-		delete(envoyFilter.Annotations, HashAnnotationName)
+	annotations := envoyFilter.GetAnnotations()
+	if _, ok := annotations[HashAnnotationName]; ok {
+		delete(annotations, HashAnnotationName)
+		envoyFilter.SetAnnotations(annotations)
+		labels := envoyFilter.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[WebhookLabel] = "true"
+		envoyFilter.SetLabels(labels)
 		return a.client.Update(ctx, envoyFilter)
 	}
 	// --> migration code end
 
-	return a.client.Patch(ctx, envoyFilter, client.RawPatch(types.MergePatchType, []byte("{}")))
+	patch := fmt.Sprintf(`{"metadata":{"labels":{%q:"true"}}}`, WebhookLabel)
+	return a.client.Patch(ctx, envoyFilter, client.RawPatch(types.MergePatchType, []byte(patch)))
 }
 
-// getAllShootsWithACLExtension returns a list of all shoots that have the ACL
-// extension enabled, together with their rule.
-func (a *actuator) getAllShootsWithACLExtension(
-	ctx context.Context, istioNamespace string,
-) ([]envoyfilters.ACLMapping, map[string]string, error) {
-	extensions := &extensionsv1alpha1.ExtensionList{}
-	err := a.client.List(ctx, extensions)
-	if err != client.IgnoreNotFound(err) {
-		return nil, nil, err
-	}
+// getShootSpecificCIDRs returns the node CIDRs of the shoot and, if
+// needsEgressCIDRs is true, the egress CIDRs (e.g. NAT IPs) from the status of
+// the shoot's Infrastructure object. A missing Infrastructure object, e.g.
+// while the shoot is being created, doesn't report any egress CIDRs.
+//
+// Gardener supports workerless Shoots. These don't have an associated
+// Infrastructure object and don't need Node- or Pod-specific CIDRs to be
+// allowed. Therefore, no CIDRs are returned for workerless Shoots.
+func (a *actuator) getShootSpecificCIDRs(
+	ctx context.Context, ex *extensionsv1alpha1.Extension, cluster *controller.Cluster, needsEgressCIDRs bool,
+) (
+	nodeCIDRs []string,
+	egressCIDRs []string,
+	err error,
+) {
+	ctx, span := tracing.Start(ctx, "GetShootSpecificCIDRs")
+	defer func() { tracing.End(span, err) }()
 
-	if len(extensions.Items) < 1 {
-		return nil, nil, ErrNoExtensionsFound
+	if v1beta1helper.IsWorkerless(cluster.Shoot) {
+		return nil, nil, nil
 	}
 
-	mappings := []envoyfilters.ACLMapping{}
-
-	var istioLabels map[string]string
-
-	for i := range extensions.Items {
-		ex := &extensions.Items[i]
-		if ex.Spec.Type != Type {
-			continue
-		}
-
-		var shootIstioNamespace string
-		var shootIstioLabels map[string]string
-
-		shootIstioNamespace, shootIstioLabels, err = a.findIstioNamespaceForExtension(ctx, &extensions.Items[i])
-		if client.IgnoreNotFound(err) != nil {
-			return nil, nil, err
-		}
-		// If we don't find a Gateway object to get the Istio Namespace from, we
-		// try the extension status as a fallback. If both aren't available, we
-		// ignore the Shoot's ACL rules entirely in this pass. This can only
-		// occur when the ACL extension for the Shoot in question itself has
-		// never been reconciled before.
-		if apierrors.IsNotFound(err) {
-			extState, err := getExtensionState(ex)
-			if err != nil {
-				return nil, nil, err
-			}
-			if extState.IstioNamespace == nil {
-				continue
-			}
-
-			shootIstioNamespace = *extState.IstioNamespace
-		}
-
-		if istioNamespace != shootIstioNamespace {
-			continue
-		}
-
-		if istioLabels == nil {
-			istioLabels = shootIstioLabels
-		}
-
-		extSpec := &extensionspec.ExtensionSpec{}
-		if ex.Spec.ProviderConfig != nil && ex.Spec.ProviderConfig.Raw != nil {
-			if err := json.Unmarshal(ex.Spec.ProviderConfig.Raw, &extSpec); err != nil {
-				return nil, nil, err
-			}
-		}
-
-		cluster, err := controller.GetCluster(ctx, a.client, ex.GetNamespace())
-		if err != nil {
-			return nil, nil, err
-		}
-
-		var shootSpecificCIDRs []string
-
-		// Gardener supports workerless Shoots. These don't have an associated
-		// Infrastructure object and don't need Node- or Pod-specific CIDRs to be
-		// allowed. Therefore, skip these steps for workerless Shoots.
-		if !v1beta1helper.IsWorkerless(cluster.Shoot) {
-			infra := &extensionsv1alpha1.Infrastructure{}
-			namespacedName := types.NamespacedName{
-				Namespace: ex.GetNamespace(),
-				Name:      cluster.Shoot.Name,
-			}
-
-			if err := a.client.Get(ctx, namespacedName, infra); err != nil {
-				return nil, nil, err
-			}
+	nodeCIDRs = helper.GetShootNodeSpecificAllowedCIDRs(cluster.Shoot)
+	if !needsEgressCIDRs {
+		return nodeCIDRs, nil, nil
+	}
 
-			shootSpecificCIDRs = append(shootSpecificCIDRs, helper.GetShootNodeSpecificAllowedCIDRs(cluster.Shoot)...)
-			providerSpecificCIRDs, err := helper.GetProviderSpecificAllowedCIDRs(infra)
-			if err != nil {
-				return nil, nil, err
-			}
-			shootSpecificCIDRs = append(shootSpecificCIDRs, providerSpecificCIRDs...)
-		}
+	infra, err := helper.GetInfrastructureForExtension(ctx, a.client, ex, cluster.Shoot.Name)
+	if apierrors.IsNotFound(err) {
+		return nodeCIDRs, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
 
-		envoyFilter := &istionetworkv1alpha3.EnvoyFilter{}
-		name := "acl-vpn-" + ex.Namespace
-		err = a.client.Get(ctx, types.NamespacedName{Name: name, Namespace: istioNamespace}, envoyFilter)
-		if apierrors.IsNotFound(err) {
-			mappings = append(mappings, envoyfilters.ACLMapping{
-				ShootName:          ex.Namespace,
-				Rule:               *extSpec.Rule,
-				ShootSpecificCIDRs: shootSpecificCIDRs,
-			})
-		} else if err != nil {
-			return nil, nil, err
-		}
+	egressCIDRs, err = helper.GetProviderSpecificAllowedCIDRs(infra)
+	if err != nil {
+		return nil, nil, err
 	}
-	return mappings, istioLabels, nil
+
+	return nodeCIDRs, egressCIDRs, nil
 }
 
-// findIstioNamespaceForExtension finds the Istio namespace by the Istio Gateway
-// object named "kube-apiserver", which is expected to be present in every
-// Shoot namespace (except when the Shoot is hibernated - in this case, the
-// function returns a NotFoundError which the caller should handle).
+// findIstioNamespacesForExtension finds the Istio namespaces by the Istio
+// Gateway object named "kube-apiserver" (unless configured otherwise), which is
+// expected to be present in every Shoot namespace (except when the Shoot is
+// hibernated - in this case, the function returns a NotFoundError which the
+// caller should handle).
+//
+// The Gateway object has a Selector field that selects the ingress gateway
+// Deployments in the namespaces we need. There can be more than one, e.g. the
+// zonal ingress gateways of HA shoots. We list Deployments filtered by the
+// labelSelector and return the sorted namespaces of the returned Deployments.
 //
-// The Gateway object has a Selector field that selects a Deployment in the
-// namespace we need. We list Deployments filtered by the labelSelector and
-// return the namespace of the returned Deployment.
-func (a *actuator) findIstioNamespaceForExtension(
+// Shoots using an ExposureClass are served by the dedicated ingress gateway of
+// the ExposureClass handler, whose Deployment might also match the selector
+// of the default gateway. Hence, Deployments of ExposureClass handlers are
+// only considered if the Gateway selects a handler.
+func (a *actuator) findIstioNamespacesForExtension(
 	ctx context.Context, ex *extensionsv1alpha1.Extension,
 ) (
-	istioNamespace string,
+	istioNamespaces []string,
 	istioLabels map[string]string,
 	err error,
 ) {
@@ -653,10 +1252,10 @@ func (a *actuator) findIstioNamespaceForExtension(
 
 	err = a.client.Get(ctx, client.ObjectKey{
 		Namespace: ex.Namespace,
-		Name:      istioGatewayName,
+		Name:      cmp.Or(a.extensionConfig.APIServerGatewayName, istioGatewayName),
 	}, &gw)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 
 	labelsSelector := client.MatchingLabels(gw.Spec.Selector)
@@ -664,13 +1263,29 @@ func (a *actuator) findIstioNamespaceForExtension(
 	deployments := appsv1.DeploymentList{}
 	err = a.client.List(ctx, &deployments, labelsSelector)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
+	}
+	if len(deployments.Items) == 0 {
+		return nil, nil, fmt.Errorf("no istio namespace could be selected, because no deployments were found")
 	}
-	if len(deployments.Items) != 1 {
-		return "", nil, fmt.Errorf("no istio namespace could be selected, because the number of deployments found is %d", len(deployments.Items))
+
+	_, selectsExposureClassHandler := gw.Spec.Selector[v1beta1constants.LabelExposureClassHandlerName]
+
+	namespaces := sets.New[string]()
+	for _, deployment := range deployments.Items {
+		if _, isExposureClassHandler := deployment.Labels[v1beta1constants.LabelExposureClassHandlerName]; isExposureClassHandler != selectsExposureClassHandler {
+			continue
+		}
+		if a.extensionConfig.IgnoredNamespaces.Matches(deployment.Namespace) {
+			continue
+		}
+		namespaces.Insert(deployment.Namespace)
+	}
+	if namespaces.Len() == 0 {
+		return nil, nil, fmt.Errorf("no istio namespace could be selected, because no deployments of the expected ingress gateway were found")
 	}
 
-	return deployments.Items[0].Namespace, gw.Spec.Selector, nil
+	return sets.List(namespaces), gw.Spec.Selector, nil
 }
 
 func (a *actuator) findDefaultIstioLabels(
@@ -682,8 +1297,8 @@ func (a *actuator) findDefaultIstioLabels(
 	gw := istionetworkv1beta1.Gateway{}
 
 	err = a.client.Get(ctx, client.ObjectKey{
-		Namespace: v1beta1constants.GardenNamespace,
-		Name:      ingressGatewayName,
+		Namespace: cmp.Or(a.extensionConfig.IngressGatewayNamespace, v1beta1constants.GardenNamespace),
+		Name:      cmp.Or(a.extensionConfig.IngressGatewayName, ingressGatewayName),
 	}, &gw)
 	if err != nil {
 		return nil, err
@@ -691,3 +1306,14 @@ func (a *actuator) findDefaultIstioLabels(
 
 	return gw.Spec.Selector, nil
 }
+
+// shootLogger adds the technical ID and, if the cluster is given, the name of
+// the shoot to the log context, so the log lines of the reconciliations of
+// different shoots can be told apart.
+func shootLogger(log logr.Logger, ex *extensionsv1alpha1.Extension, cluster *controller.Cluster) logr.Logger {
+	log = log.WithValues("technicalID", ex.GetNamespace())
+	if cluster != nil && cluster.Shoot != nil {
+		log = log.WithValues("shoot", client.ObjectKeyFromObject(cluster.Shoot))
+	}
+	return log
+}
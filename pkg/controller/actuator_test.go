@@ -2,22 +2,45 @@ package controller
 
 import (
 	"encoding/json"
-
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"time"
+
+	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
 	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
 	"github.com/gardener/gardener/pkg/apis/resources/v1alpha1"
+	reconcilerutils "github.com/gardener/gardener/pkg/controllerutils/reconciler"
 	. "github.com/gardener/gardener/pkg/utils/test/matchers"
+	"github.com/go-logr/logr/funcr"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	istioapinetworkingv1beta1 "istio.io/api/networking/v1beta1"
 	istionetworkingClientGo "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	istionetworkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
 )
 
 var _ = Describe("actuator test", func() {
@@ -51,7 +74,7 @@ var _ = Describe("actuator test", func() {
 	})
 
 	Describe("reconciliation of an ACL extension object", func() {
-		It("should create a legacy acl-vpn EnvoyFilter object with the correct contents", func() {
+		It("should not create the legacy acl-vpn EnvoyFilter object shared by all shoots", func() {
 			extSpec := extensionspec.ExtensionSpec{
 				Rule: &envoyfilters.ACLRule{
 					Cidrs:  []string{"1.2.3.4/24"},
@@ -66,9 +89,24 @@ var _ = Describe("actuator test", func() {
 
 			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
 
-			envoyFilter := &istionetworkingClientGo.EnvoyFilter{}
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "acl-vpn", Namespace: istioNamespace1}, envoyFilter)).To(Succeed())
-			Expect(envoyFilter.Spec.MarshalJSON()).To(ContainSubstring("1.2.3.4"))
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: "acl-vpn", Namespace: istioNamespace1}, &istionetworkingClientGo.EnvoyFilter{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should make the extension the owner of its ManagedResource", func() {
+			ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`))
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			Expect(mr.OwnerReferences).To(ConsistOf(HaveField("UID", ext.UID)))
+
+			By("keeping the owner reference on further reconciliations")
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			Expect(mr.OwnerReferences).To(HaveLen(1))
 		})
 		It("should create managed resource containing acl-api-shoot and acl-vpn-shoot EnvoyFilter object", func() {
 			extSpec := extensionspec.ExtensionSpec{
@@ -89,18 +127,19 @@ var _ = Describe("actuator test", func() {
 			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
 			secret := &corev1.Secret{}
 			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
-			Expect(secret.Data["seed"]).To(ContainSubstring("1.2.3.4"))
+			Expect(secret.Data["seed"]).To(ContainSubstring("1.2.3.0"))
 			Expect(secret.Data["seed"]).To(ContainSubstring("acl-api-" + shootNamespace1))
 			Expect(secret.Data["seed"]).To(ContainSubstring("acl-vpn-" + shootNamespace1))
 		})
 
-		It("should record the last seen istio namespace in the status of the extension object", func() {
-			// arrange
+		It("should record the metadata of the rule in the status and on the rendered objects", func() {
 			extSpec := extensionspec.ExtensionSpec{
 				Rule: &envoyfilters.ACLRule{
-					Cidrs:  []string{"1.2.3.4/24"},
-					Action: "ALLOW",
-					Type:   "remote_ip",
+					Cidrs:       []string{"1.2.3.4/24"},
+					Action:      "ALLOW",
+					Type:        "remote_ip",
+					Description: "office network, see TICKET-123",
+					Labels:      map[string]string{"team": "platform"},
 				},
 			}
 			extSpecJSON, err := json.Marshal(extSpec)
@@ -108,299 +147,1664 @@ var _ = Describe("actuator test", func() {
 			ext := createNewExtension(shootNamespace1, extSpecJSON)
 			Expect(ext).To(Not(BeNil()))
 
-			// act
 			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
 
-			// assert
-			ext = &extensionsv1alpha1.Extension{}
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: shootNamespace1, Name: "acl"}, ext)).To(Succeed())
-
-			extState := &ExtensionState{}
-			Expect(ext.Status.State).ToNot(BeNil())
-			Expect(ext.Status.State.Raw).ToNot(BeNil())
-			Expect(json.Unmarshal(ext.Status.State.Raw, &extState)).To(Succeed())
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.Rules).To(ConsistOf(RuleMetadata{
+				Name:        "rule",
+				Description: "office network, see TICKET-123",
+				Labels:      map[string]string{"team": "platform"},
+			}))
 
-			Expect(extState.IstioNamespace).ToNot(BeNil())
-			Expect(*extState.IstioNamespace).To(Equal(istioNamespace1))
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			Expect(string(secret.Data["seed"])).To(ContainSubstring(AnnotationRuleDescription + ": office network, see TICKET-123"))
+			Expect(string(secret.Data["seed"])).To(ContainSubstring(AnnotationRuleLabels + `: '{"team":"platform"}'`))
 		})
 
-		// gardener >= v1.89, including https://github.com/gardener/gardener/pull/9038
-		Context("ingress-nginx is exposed via istio", func() {
-			BeforeEach(func() {
-				gateway := createNewGateway("nginx-ingress-controller", "garden", map[string]string{
-					"app":   "istio-ingressgateway",
-					"istio": "ingressgateway",
-				})
-
-				DeferCleanup(func() {
-					Expect(k8sClient.Delete(ctx, gateway)).To(Or(Succeed(), BeNotFoundError()))
-				})
-			})
+		It("should create managed resource containing AuthorizationPolicies instead of EnvoyFilters if configured", func() {
+			a.extensionConfig.EnforcementBackend = config.EnforcementBackendAuthorizationPolicy
 
-			It("should create managed resource including acl-ingress-shoot EnvoyFilter object", func() {
-				extSpec := extensionspec.ExtensionSpec{
-					Rule: &envoyfilters.ACLRule{
-						Cidrs:  []string{"1.2.3.4/24"},
-						Action: "ALLOW",
-						Type:   "remote_ip",
-					},
-				}
-				extSpecJSON, err := json.Marshal(extSpec)
-				Expect(err).To(BeNil())
-				ext := createNewExtension(shootNamespace1, extSpecJSON)
-				Expect(ext).To(Not(BeNil()))
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/24"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
 
-				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
 
-				mr := &v1alpha1.ManagedResource{}
-				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
-				secret := &corev1.Secret{}
-				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
-				Expect(secret.Data["seed"]).To(ContainSubstring("acl-ingress-" + shootNamespace1))
-			})
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			Expect(secret.Data["seed"]).To(ContainSubstring("kind: AuthorizationPolicy"))
+			Expect(secret.Data["seed"]).To(ContainSubstring("acl-api-" + shootNamespace1))
+			Expect(secret.Data["seed"]).To(ContainSubstring("acl-vpn-" + shootNamespace1))
+			Expect(secret.Data["seed"]).To(ContainSubstring("1.2.3.4/24"))
+			Expect(secret.Data["seed"]).ToNot(ContainSubstring("kind: EnvoyFilter"))
 		})
 
-		// gardener < v1.89
-		Context("ingress-nginx is not exposed via istio", func() {
-			It("should create managed resource not including acl-ingress-shoot EnvoyFilter object", func() {
-				extSpec := extensionspec.ExtensionSpec{
-					Rule: &envoyfilters.ACLRule{
-						Cidrs:  []string{"1.2.3.4/24"},
-						Action: "ALLOW",
-						Type:   "remote_ip",
-					},
-				}
-				extSpecJSON, err := json.Marshal(extSpec)
-				Expect(err).To(BeNil())
-				ext := createNewExtension(shootNamespace1, extSpecJSON)
-				Expect(ext).To(Not(BeNil()))
+		It("should only create the acl-api-shoot EnvoyFilter object for the apiserver-only profile", func() {
+			extSpec := extensionspec.ExtensionSpec{
+				Profile: extensionspec.ProfileAPIServerOnly,
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/24"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
 
-				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
 
-				mr := &v1alpha1.ManagedResource{}
-				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
-				secret := &corev1.Secret{}
-				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
-				Expect(secret.Data["seed"]).NotTo(ContainSubstring("acl-ingress-" + shootNamespace1))
-			})
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			Expect(secret.Data["seed"]).To(ContainSubstring("acl-api-" + shootNamespace1))
+			Expect(secret.Data["seed"]).ToNot(ContainSubstring("acl-vpn-" + shootNamespace1))
+			Expect(secret.Data["seed"]).ToNot(ContainSubstring("acl-ingress-" + shootNamespace1))
 		})
-	})
 
-	Describe("reconciliation of an extension object with other ACL extensions being present", func() {
-		BeforeEach(func() {
-			shootNamespace2 = createNewShootNamespace()
-			createNewEnvoyFilter(shootNamespace2, istioNamespace1)
-			createNewGateway("kube-apiserver", shootNamespace2, istioNamespace1Selector)
-			createNewCluster(shootNamespace2)
-			createNewInfrastructure(shootNamespace2)
-		})
+		It("should create the acl-access-log-shoot EnvoyFilter object if the seed logs denied connections", func() {
+			a.extensionConfig.LogDeniedConnections = true
 
-		AfterEach(func() {
-			deleteNamespace(shootNamespace2)
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/24"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			Expect(secret.Data["seed"]).To(ContainSubstring("acl-access-log-" + shootNamespace1))
+			Expect(secret.Data["seed"]).To(ContainSubstring("acl-denied-api-" + shootNamespace1))
+			Expect(secret.Data["seed"]).To(ContainSubstring("acl-denied-vpn-" + shootNamespace1))
 		})
 
-		It("should create a legacy acl-vpn EnvoyFilter object with the correct contents (from both extensions)", func() {
-			// arrange
-			extSpec1 := extensionspec.ExtensionSpec{
+		It("should not create the acl-access-log-shoot EnvoyFilter object if the shoot opts out", func() {
+			a.extensionConfig.LogDeniedConnections = true
+
+			extSpec := extensionspec.ExtensionSpec{
+				LogDeniedConnections: ptr.To(false),
 				Rule: &envoyfilters.ACLRule{
 					Cidrs:  []string{"1.2.3.4/24"},
 					Action: "ALLOW",
 					Type:   "remote_ip",
 				},
 			}
-			extSpecJSON1, err := json.Marshal(extSpec1)
+			extSpecJSON, err := json.Marshal(extSpec)
 			Expect(err).To(BeNil())
-			ext1 := createNewExtension(shootNamespace1, extSpecJSON1)
-			Expect(ext1).To(Not(BeNil()))
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
 
-			extSpec2 := extensionspec.ExtensionSpec{
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			Expect(secret.Data["seed"]).To(ContainSubstring("acl-api-" + shootNamespace1))
+			Expect(secret.Data["seed"]).ToNot(ContainSubstring("acl-access-log-" + shootNamespace1))
+		})
+
+		It("should record the last seen istio namespace in the status of the extension object", func() {
+			// arrange
+			extSpec := extensionspec.ExtensionSpec{
 				Rule: &envoyfilters.ACLRule{
-					Cidrs:  []string{"5.6.7.8/24"},
+					Cidrs:  []string{"1.2.3.4/24"},
 					Action: "ALLOW",
 					Type:   "remote_ip",
 				},
 			}
-			extSpecJSON2, err := json.Marshal(extSpec2)
+			extSpecJSON, err := json.Marshal(extSpec)
 			Expect(err).To(BeNil())
-			ext2 := createNewExtension(shootNamespace2, extSpecJSON2)
-			Expect(ext2).To(Not(BeNil()))
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
 
-			// act (shouldn't matter which extension is reconciled)
-			Expect(a.Reconcile(ctx, logger, ext1)).To(Succeed())
+			// act
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
 
 			// assert
-			envoyFilter := &istionetworkingClientGo.EnvoyFilter{}
-			Expect(k8sClient.Get(
-				ctx, types.NamespacedName{
-					Name:      "acl-vpn",
-					Namespace: istioNamespace1,
-				},
-				envoyFilter,
-			)).To(Succeed())
-
-			// as there is only one acl-vpn EnvoyFilter, this EnvoyFilter must
-			// contain the allowed CIDRs from all ACL extensions
-			Expect(envoyFilter.Spec.MarshalJSON()).To(And(
-				ContainSubstring("1.2.3.4"),
-				ContainSubstring("5.6.7.8"),
-			))
+			ext = &extensionsv1alpha1.Extension{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: shootNamespace1, Name: "acl"}, ext)).To(Succeed())
+
+			extState := &ExtensionState{}
+			Expect(ext.Status.State).ToNot(BeNil())
+			Expect(ext.Status.State.Raw).ToNot(BeNil())
+			Expect(json.Unmarshal(ext.Status.State.Raw, &extState)).To(Succeed())
+
+			Expect(extState.IstioNamespace).ToNot(BeNil())
+			Expect(*extState.IstioNamespace).To(Equal(istioNamespace1))
+			Expect(extState.SchemaVersion).To(Equal(SchemaVersion))
 		})
 
-		It("should migrate from a legacy acl-vpn EnvoyFilter to shoot specific EnvoyFilters", func() {
-			By("should create a legacy acl-vpn EnvoyFilter and a ManagedResource containing shoot specific EnvoyFilters")
+		It("should record the merged always allowed CIDRs in the status of the extension object", func() {
+			a.extensionConfig.AdditionalAllowedCIDRs = []string{"192.168.1.40/32"}
 
-			extSpec1 := extensionspec.ExtensionSpec{
+			extSpec := extensionspec.ExtensionSpec{
 				Rule: &envoyfilters.ACLRule{
 					Cidrs:  []string{"1.2.3.4/24"},
 					Action: "ALLOW",
 					Type:   "remote_ip",
 				},
 			}
-			extSpecJSON1, err := json.Marshal(extSpec1)
+			extSpecJSON, err := json.Marshal(extSpec)
 			Expect(err).To(BeNil())
-			ext1 := createNewExtension(shootNamespace1, extSpecJSON1)
-			Expect(ext1).To(Not(BeNil()))
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			ext = &extensionsv1alpha1.Extension{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: shootNamespace1, Name: "acl"}, ext)).To(Succeed())
 
-			extSpec2 := extensionspec.ExtensionSpec{
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			// seed networks of the cluster and the operator configured CIDRs
+			Expect(extState.AlwaysAllowedCIDRs).To(ConsistOf("10.10.0.0/24", "10.250.0.0/24", "192.168.1.40/32"))
+		})
+
+		It("should always allow the internal addresses of the seed's nodes", func() {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "seed-node"}}
+			Expect(k8sClient.Create(ctx, node)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, node))).To(Succeed())
+			})
+			node.Status.Addresses = []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "10.250.0.10"},
+				{Type: corev1.NodeExternalIP, Address: "203.0.113.10"},
+			}
+			Expect(k8sClient.Status().Update(ctx, node)).To(Succeed())
+
+			extSpec := extensionspec.ExtensionSpec{
 				Rule: &envoyfilters.ACLRule{
-					Cidrs:  []string{"5.6.7.8/24"},
+					Cidrs:  []string{"1.2.3.4/32"},
 					Action: "ALLOW",
 					Type:   "remote_ip",
 				},
 			}
-			extSpecJSON2, err := json.Marshal(extSpec2)
+			extSpecJSON, err := json.Marshal(extSpec)
 			Expect(err).To(BeNil())
-			ext2 := createNewExtension(shootNamespace2, extSpecJSON2)
-			Expect(ext2).To(Not(BeNil()))
-
-			Expect(a.Reconcile(ctx, logger, ext1)).To(Succeed())
-			Expect(a.Reconcile(ctx, logger, ext2)).To(Succeed())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
 
-			envoyFilter := &istionetworkingClientGo.EnvoyFilter{}
-			Expect(k8sClient.Get(
-				ctx, types.NamespacedName{
-					Name:      "acl-vpn",
-					Namespace: istioNamespace1,
-				},
-				envoyFilter,
-			)).To(Succeed())
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
 
-			Expect(envoyFilter.Spec.MarshalJSON()).To(And(
-				ContainSubstring("1.2.3.4"),
-				ContainSubstring("5.6.7.8"),
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.AlwaysAllowedCIDRs).To(ContainElement("10.250.0.10/32"))
+			Expect(extState.AlwaysAllowedCIDRs).NotTo(ContainElement("203.0.113.10/32"))
+			Expect(extState.Allowlist).To(ContainElement(
+				MatchFields(IgnoreExtras, Fields{"CIDR": Equal("10.250.0.10/32"), "Source": Equal(SourceSeedNodes)}),
 			))
+		})
 
-			mr := &v1alpha1.ManagedResource{}
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
-			secret := &corev1.Secret{}
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
-			Expect(secret.Data["seed"]).To(ContainSubstring("acl-vpn-" + shootNamespace1))
-
-			mr = &v1alpha1.ManagedResource{}
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace2}, mr)).To(Succeed())
-			secret = &corev1.Secret{}
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace2}, secret)).To(Succeed())
-			Expect(secret.Data["seed"]).To(ContainSubstring("acl-vpn-" + shootNamespace2))
-
-			By("should delete shoot specific rules after reconcile")
+		It("should allow the public IPs of the shoot's bastions while they exist", func() {
+			bastion := &extensionsv1alpha1.Bastion{
+				ObjectMeta: metav1.ObjectMeta{Name: "cli-xyz", Namespace: shootNamespace1},
+				Spec: extensionsv1alpha1.BastionSpec{
+					DefaultSpec: extensionsv1alpha1.DefaultSpec{Type: "local"},
+					UserData:    []byte("#!/bin/bash"),
+					Ingress: []extensionsv1alpha1.BastionIngressPolicy{
+						{IPBlock: networkingv1.IPBlock{CIDR: "198.51.100.1/32"}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, bastion)).To(Succeed())
+			bastion.Status.Ingress = &corev1.LoadBalancerIngress{IP: "203.0.113.20"}
+			Expect(k8sClient.Status().Update(ctx, bastion)).To(Succeed())
 
-			shootEnvoyFilter1 := &istionetworkingClientGo.EnvoyFilter{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "acl-vpn-" + shootNamespace1,
-					Namespace: istioNamespace1,
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/32"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
 				},
 			}
-			Expect(k8sClient.Create(ctx, shootEnvoyFilter1)).To(Succeed())
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
 
-			Expect(a.Reconcile(ctx, logger, ext1)).To(Succeed())
-			envoyFilter = &istionetworkingClientGo.EnvoyFilter{}
-			Expect(k8sClient.Get(
-				ctx, types.NamespacedName{
-					Name:      "acl-vpn",
-					Namespace: istioNamespace1,
-				},
-				envoyFilter,
-			)).To(Succeed())
-
-			Expect(envoyFilter.Spec.MarshalJSON()).To(And(
-				Not(ContainSubstring("1.2.3.4")),
-				ContainSubstring("5.6.7.8"),
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.AlwaysAllowedCIDRs).To(ContainElement("203.0.113.20/32"))
+			Expect(extState.Allowlist).To(ContainElement(
+				MatchFields(IgnoreExtras, Fields{"CIDR": Equal("203.0.113.20/32"), "Source": Equal(SourceBastion)}),
 			))
 
-			By("should delete legacy envoy filter after all shoot specific filters are present")
+			Expect(k8sClient.Delete(ctx, bastion)).To(Succeed())
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			extState, err = GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.AlwaysAllowedCIDRs).NotTo(ContainElement("203.0.113.20/32"))
+		})
 
-			shootEnvoyFilter2 := &istionetworkingClientGo.EnvoyFilter{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "acl-vpn-" + shootNamespace2,
-					Namespace: istioNamespace1,
+		It("should always allow the egress CIDRs of the garden cluster", func() {
+			a.extensionConfig.GardenEgressCIDRs = []string{"198.51.100.0/24"}
+
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/32"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
 				},
 			}
-			Expect(k8sClient.Create(ctx, shootEnvoyFilter2)).To(Succeed())
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.AlwaysAllowedCIDRs).To(ContainElement("198.51.100.0/24"))
+			Expect(extState.Allowlist).To(ContainElement(
+				MatchFields(IgnoreExtras, Fields{"CIDR": Equal("198.51.100.0/24"), "Source": Equal(SourceGarden)}),
+			))
+		})
+
+		It("should record the changes of the applied rule set in the history", func() {
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/32"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.History).To(HaveLen(1))
+			Expect(extState.History[0].Added).To(ContainElement("1.2.3.4/32"))
+
+			extSpec.Rule.Cidrs = []string{"5.6.7.8/32"}
+			extSpecJSON, err = json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext.Spec.ProviderConfig.Raw = extSpecJSON
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			extState, err = GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.History).To(HaveLen(2))
+			Expect(extState.History[1].Added).To(Equal([]string{"5.6.7.8/32"}))
+			Expect(extState.History[1].Removed).To(Equal([]string{"1.2.3.4/32"}))
+		})
+
+		It("should record the normalized CIDRs if normalizeCIDRs is set", func() {
+			ext := createNewExtension(shootNamespace1,
+				[]byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]},"normalizeCIDRs":true}`))
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.History[0].Added).To(ContainElement("1.2.3.0/24"))
+			Expect(extState.History[0].Added).NotTo(ContainElement("1.2.3.4/24"))
+			Expect(extState.Warnings).NotTo(ContainElement(ContainSubstring("host bits")))
+		})
+
+		It("should record the source of the allowlist entries and keep the time they were first allowed", func() {
+			a.extensionConfig.AdditionalAllowedCIDRs = []string{"192.168.1.40/32"}
+
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/24"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.Allowlist).To(ConsistOf(
+				MatchFields(IgnoreExtras, Fields{"CIDR": Equal("1.2.3.4/24"), "Source": Equal(SourceRule)}),
+				MatchFields(IgnoreExtras, Fields{"CIDR": Equal("10.250.0.0/24"), "Source": Equal(SourceSeed)}),
+				MatchFields(IgnoreExtras, Fields{"CIDR": Equal("10.10.0.0/24"), "Source": Equal(SourceSeed)}),
+				MatchFields(IgnoreExtras, Fields{"CIDR": Equal("192.168.1.40/32"), "Source": Equal(SourceOperator)}),
+			))
+			firstAllowlist := extState.Allowlist
+
+			time.Sleep(time.Second)
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			extState, err = GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.Allowlist).To(HaveLen(len(firstAllowlist)))
+			for i := range firstAllowlist {
+				Expect(extState.Allowlist[i].Since.Equal(&firstAllowlist[i].Since)).To(BeTrue())
+			}
+		})
+
+		It("should record metrics about the shoot until the extension is deleted", func() {
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/24"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			// the rule and the seed networks of the cluster
+			Expect(shootMetric("acl_controller_rendered_cidrs", shootNamespace1)).To(ContainElement(BeNumerically(">=", 3)))
+			Expect(shootMetric("acl_controller_reconcile_duration_seconds", shootNamespace1)).To(HaveLen(1))
+			Expect(shootMetric("acl_controller_open_policy", shootNamespace1)).To(Equal([]float64{0}))
+			Expect(testutil.ToFloat64(shootsWithACL)).To(BeNumerically(">=", 1))
+
+			Expect(a.Delete(ctx, logger, ext)).To(Succeed())
+
+			Expect(shootMetric("acl_controller_rendered_cidrs", shootNamespace1)).To(BeEmpty())
+			Expect(shootMetric("acl_controller_open_policy", shootNamespace1)).To(BeEmpty())
+			Expect(shootMetric("acl_controller_reconcile_duration_seconds", shootNamespace1)).To(BeEmpty())
+		})
+
+		It("should record DENY rules as open policies", func() {
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/24"},
+					Action: "DENY",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			Expect(shootMetric("acl_controller_open_policy", shootNamespace1)).To(Equal([]float64{1}))
+		})
+
+		It("should always allow the infrastructure egress CIDRs for the VPN listener", func() {
+			a.extensionConfig.AutoAllowInfrastructureEgressCIDRs = false
+
+			infra := &extensionsv1alpha1.Infrastructure{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: shootNamespace1, Namespace: shootNamespace1}, infra)).To(Succeed())
+			infra.Status.EgressCIDRs = []string{"198.51.100.7/32"}
+			Expect(k8sClient.Status().Update(ctx, infra)).To(Succeed())
+
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/24"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			for _, doc := range strings.Split(string(secret.Data["seed"]), "\n---\n") {
+				switch {
+				case strings.Contains(doc, "name: acl-api-"+shootNamespace1):
+					Expect(doc).ToNot(ContainSubstring("198.51.100.7"))
+				case strings.Contains(doc, "name: acl-vpn-"+shootNamespace1):
+					Expect(doc).To(ContainSubstring("198.51.100.7"))
+				}
+			}
+		})
+
+		It("should never deny the networks of the seed, even for DENY rules covering them", func() {
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"0.0.0.0/0"},
+					Action: "DENY",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			for _, doc := range strings.Split(string(secret.Data["seed"]), "\n---\n") {
+				if strings.Contains(doc, "name: acl-api-"+shootNamespace1) {
+					Expect(doc).To(ContainSubstring("not_id"))
+					Expect(doc).To(ContainSubstring("10.250.0.0"))
+				}
+			}
+		})
+
+		It("should not require the Infrastructure of a shoot with workers", func() {
+			// e.g. while the shoot is being created
+			infra := &extensionsv1alpha1.Infrastructure{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: shootNamespace1, Namespace: shootNamespace1}, infra)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, infra)).To(Succeed())
+
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/24"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+		})
+
+		It("should allow the CIDRs of the global allowlist ConfigMap", func() {
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "global-allowlist",
+					Namespace: shootNamespace1,
+				},
+				Data: map[string]string{
+					"cidrs": "172.16.0.0/24\n",
+				},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+			a.extensionConfig.GlobalAllowlistConfigMap = client.ObjectKeyFromObject(configMap)
+
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/24"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			ext = &extensionsv1alpha1.Extension{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: shootNamespace1, Name: "acl"}, ext)).To(Succeed())
+
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.AlwaysAllowedCIDRs).To(ContainElement("172.16.0.0/24"))
+			Expect(extState.GlobalAllowlistChecksum).To(Equal(helper.ComputeCIDRsChecksum([]string{"172.16.0.0/24"})))
+
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			Expect(secret.Data["seed"]).To(ContainSubstring("172.16.0.0"))
+		})
+
+		It("should deny the CIDRs of the global denylist ConfigMap", func() {
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "global-denylist",
+					Namespace: shootNamespace1,
+				},
+				Data: map[string]string{
+					"cidrs": "203.0.113.0/24\n",
+				},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+			a.extensionConfig.GlobalDenylistConfigMap = client.ObjectKeyFromObject(configMap)
+
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"0.0.0.0/0"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+				AllowOpenAccess: true,
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			ext = &extensionsv1alpha1.Extension{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: shootNamespace1, Name: "acl"}, ext)).To(Succeed())
+
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.GlobalDenylistChecksum).To(Equal(helper.ComputeCIDRsChecksum([]string{"203.0.113.0/24"})))
+
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			Expect(secret.Data["seed"]).To(ContainSubstring("not_id"))
+			Expect(secret.Data["seed"]).To(ContainSubstring("203.0.113.0"))
+		})
+
+		It("should deny the CIDRs of the policy of the shoot's purpose", func() {
+			a.extensionConfig.PurposePolicies = map[string]config.PurposePolicy{
+				"evaluation": {DeniedCIDRs: []string{"198.51.100.0/24"}},
+			}
+
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"0.0.0.0/0"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+				AllowOpenAccess: true,
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			Expect(secret.Data["seed"]).To(ContainSubstring("not_id"))
+			Expect(secret.Data["seed"]).To(ContainSubstring("198.51.100.0"))
+		})
+
+		It("should reject LOG rules if the policy of the shoot's purpose forbids the shadow mode", func() {
+			a.extensionConfig.PurposePolicies = map[string]config.PurposePolicy{
+				"evaluation": {ForbidShadowMode: true},
+			}
+
+			ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"LOG","type":"remote_ip","cidrs":["1.2.3.0/24"]}}`))
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(MatchError(ErrSpecShadowModeForbidden))
+
+			mr := &v1alpha1.ManagedResource{}
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should record events about the applied rule and the CIDRs clamped by the global denylist", func() {
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "global-denylist",
+					Namespace: shootNamespace1,
+				},
+				Data: map[string]string{
+					"cidrs": "203.0.113.0/24\n",
+				},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+			a.extensionConfig.GlobalDenylistConfigMap = client.ObjectKeyFromObject(configMap)
+			recorder := record.NewFakeRecorder(10)
+			a.recorder = recorder
+
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"203.0.0.0/16", "198.51.100.0/24"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			Expect(recorder.Events).To(Receive(Equal(
+				"Warning DenylistClamped The CIDRs 203.0.0.0/16 of the ACL rule overlap with the global denylist, which takes precedence",
+			)))
+			Expect(recorder.Events).To(Receive(Equal(
+				"Normal RulesApplied Applied the ALLOW rule with 2 CIDRs to the istio namespaces " + istioNamespace1,
+			)))
+
+			// resyncs applying the same rules don't record the event again
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			Expect(events).NotTo(ContainElement(ContainSubstring("RulesApplied")))
+
+			extSpec.Rule.Cidrs = []string{"198.51.100.0/24"}
+			ext.Spec.ProviderConfig.Raw, err = json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+			Expect(recorder.Events).To(Receive(Equal(
+				"Normal RulesApplied Applied the ALLOW rule with 1 CIDRs to the istio namespaces " + istioNamespace1,
+			)))
+		})
+
+		It("should reject a rule exceeding the maximum number of CIDRs", func() {
+			a.extensionConfig.MaxCIDRs = 2
+			recorder := record.NewFakeRecorder(10)
+			a.recorder = recorder
+
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"10.0.0.0/8", "192.168.0.0/16"},
+					Except: []string{"10.1.0.0/16"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(MatchError(ErrSpecTooManyCIDRs))
+			Expect(recorder.Events).To(Receive(ContainSubstring("RulesRejected")))
+
+			mr := &v1alpha1.ManagedResource{}
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should record an event if the rule is rejected", func() {
+			recorder := record.NewFakeRecorder(10)
+			a.recorder = recorder
+
+			ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip"}}`))
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(MatchError(ErrSpecCIDR))
+			Expect(recorder.Events).To(Receive(Equal("Warning RulesRejected Rejected the ACL rule: " + ErrSpecCIDR.Error())))
+			Expect(ext.Status.Conditions).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+				"Type":    Equal(ConditionTypeACLEffective),
+				"Status":  Equal(gardencorev1beta1.ConditionFalse),
+				"Reason":  Equal(ReasonRulesRejected),
+				"Message": Equal(ErrSpecCIDR.Error()),
+			})))
+		})
+
+		When("the load balancer of the istio ingress gateway uses the PROXY protocol", func() {
+			BeforeEach(func() {
+				createNewIstioService(istioNamespace1, istioNamespace1Selector,
+					map[string]string{"loadbalancer.openstack.org/proxy-protocol": "true"}, corev1.ServiceExternalTrafficPolicyCluster)
+			})
+
+			It("should match the client IPs from the PROXY protocol header for direct_remote_ip rules", func() {
+				ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"direct_remote_ip","cidrs":["1.2.3.4/24"]}}`))
+				Expect(ext).To(Not(BeNil()))
+
+				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+				mr := &v1alpha1.ManagedResource{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+				secret := &corev1.Secret{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+				Expect(secret.Data["seed"]).To(ContainSubstring("1.2.3.0"))
+				Expect(secret.Data["seed"]).NotTo(ContainSubstring("direct_remote_ip"))
+			})
+
+			It("should use the configured client IP preservation instead", func() {
+				a.extensionConfig.ClientIPPreservation = helper.ClientIPPreserved
+				ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"direct_remote_ip","cidrs":["1.2.3.4/24"]}}`))
+				Expect(ext).To(Not(BeNil()))
+
+				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+				mr := &v1alpha1.ManagedResource{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+				secret := &corev1.Secret{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+				Expect(secret.Data["seed"]).To(ContainSubstring("direct_remote_ip"))
+			})
+		})
+
+		When("the seed and the shoot are dual-stack", func() {
+			BeforeEach(func() {
+				cluster := &extensionsv1alpha1.Cluster{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: shootNamespace1}, cluster)).To(Succeed())
+				seed := &gardencorev1beta1.Seed{}
+				Expect(json.Unmarshal(cluster.Spec.Seed.Raw, seed)).To(Succeed())
+				seed.Spec.Networks.IPFamilies = []gardencorev1beta1.IPFamily{gardencorev1beta1.IPFamilyIPv4, gardencorev1beta1.IPFamilyIPv6}
+				shoot := &gardencorev1beta1.Shoot{}
+				Expect(json.Unmarshal(cluster.Spec.Shoot.Raw, shoot)).To(Succeed())
+				shoot.Spec.Networking.IPFamilies = seed.Spec.Networks.IPFamilies
+				cluster.Spec.Seed = runtime.RawExtension{Object: seed}
+				cluster.Spec.Shoot = runtime.RawExtension{Object: shoot}
+				Expect(k8sClient.Update(ctx, cluster)).To(Succeed())
+
+				infra := &extensionsv1alpha1.Infrastructure{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: shootNamespace1, Namespace: shootNamespace1}, infra)).To(Succeed())
+				infra.Status.EgressCIDRs = []string{"198.51.100.7/32"}
+				Expect(k8sClient.Status().Update(ctx, infra)).To(Succeed())
+			})
+
+			It("should reject an ALLOW rule which would deny all connections of one family", func() {
+				ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`))
+				Expect(ext).To(Not(BeNil()))
+
+				Expect(a.Reconcile(ctx, logger, ext)).To(MatchError(ErrSpecIPFamily))
+				Expect(a.Reconcile(ctx, logger, ext)).To(MatchError(ContainSubstring("all IPv6 connections would be denied")))
+			})
+
+			It("should match the IPv4 CIDRs as IPv4-mapped IPv6 CIDRs as well", func() {
+				recorder := record.NewFakeRecorder(10)
+				a.recorder = recorder
+				ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24","2001:db8::/32"]}}`))
+				Expect(ext).To(Not(BeNil()))
+
+				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+				mr := &v1alpha1.ManagedResource{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+				secret := &corev1.Secret{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+				Expect(secret.Data["seed"]).To(ContainSubstring("::ffff:1.2.3.0"))
+				Expect(secret.Data["seed"]).To(ContainSubstring("2001:db8::"))
+				Eventually(recorder.Events).Should(Receive(Equal(
+					"Warning EgressCIDRsIncomplete The Infrastructure doesn't report IPv6 egress CIDRs of the dual-stack shoot, they aren't allowed automatically",
+				)))
+			})
+		})
+
+		When("the shoot is workerless", func() {
+			BeforeEach(func() {
+				cluster := &extensionsv1alpha1.Cluster{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: shootNamespace1}, cluster)).To(Succeed())
+				shoot := &gardencorev1beta1.Shoot{}
+				Expect(json.Unmarshal(cluster.Spec.Shoot.Raw, shoot)).To(Succeed())
+				shoot.Spec.Provider.Workers = nil
+				cluster.Spec.Shoot = runtime.RawExtension{Object: shoot}
+				Expect(k8sClient.Update(ctx, cluster)).To(Succeed())
+
+				// workerless shoots don't have an Infrastructure
+				infra := &extensionsv1alpha1.Infrastructure{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: shootNamespace1, Namespace: shootNamespace1}, infra)).To(Succeed())
+				Expect(k8sClient.Delete(ctx, infra)).To(Succeed())
+			})
+
+			It("should only protect the API server, as there is neither an Infrastructure nor a VPN", func() {
+				ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`))
+				Expect(ext).To(Not(BeNil()))
+
+				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+				mr := &v1alpha1.ManagedResource{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+				secret := &corev1.Secret{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+				Expect(secret.Data["seed"]).To(ContainSubstring("name: acl-api-" + shootNamespace1))
+				Expect(secret.Data["seed"]).NotTo(ContainSubstring("name: acl-vpn-" + shootNamespace1))
+
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ext), ext)).To(Succeed())
+				extState, err := GetExtensionState(ext)
+				Expect(err).NotTo(HaveOccurred())
+				for _, path := range extState.TrafficPaths {
+					Expect(path.Name).NotTo(Equal(TrafficPathVPN))
+				}
+			})
+		})
+
+		It("should warn if the load balancer of the istio ingress gateway doesn't preserve the client IPs", func() {
+			createNewIstioService(istioNamespace1, istioNamespace1Selector, nil, corev1.ServiceExternalTrafficPolicyCluster)
+			recorder := record.NewFakeRecorder(10)
+			a.recorder = recorder
+
+			ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`))
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+			Expect(recorder.Events).To(Receive(Equal("Warning ClientIPNotPreserved " + clientIPNotPreservedMessage)))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ext), ext)).To(Succeed())
+			extState, err := GetExtensionState(ext)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(extState.Warnings).To(ContainElement(clientIPNotPreservedMessage))
+		})
+
+		Context("istio version of the ingress gateways", func() {
+			reconcile := func() (*ExtensionState, string) {
+				ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`))
+				Expect(ext).To(Not(BeNil()))
+
+				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+				mr := &v1alpha1.ManagedResource{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+				secret := &corev1.Secret{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ext), ext)).To(Succeed())
+				extState, err := GetExtensionState(ext)
+				Expect(err).NotTo(HaveOccurred())
+				return extState, string(secret.Data["seed"])
+			}
+
+			It("should record the detected istio version", func() {
+				extState, seed := reconcile()
+
+				Expect(extState.IstioVersion).To(Equal(&IstioVersionStatus{Version: "1.21"}))
+				Expect(seed).To(ContainSubstring("rules_stat_prefix"))
+			})
+
+			It("should only render the features supported by older gateways", func() {
+				setIstioProxyImage(istioNamespace1, "istio/proxyv2:1.14.6")
+
+				extState, seed := reconcile()
+
+				Expect(extState.IstioVersion.Version).To(Equal("1.14"))
+				Expect(extState.IstioVersion.Message).To(BeEmpty())
+				Expect(seed).NotTo(ContainSubstring("rules_stat_prefix"))
+			})
+
+			It("should report an unsupported istio version", func() {
+				setIstioProxyImage(istioNamespace1, "istio/proxyv2:1.12.9")
+
+				extState, _ := reconcile()
+
+				Expect(extState.IstioVersion.Version).To(Equal("1.12"))
+				Expect(extState.IstioVersion.Message).To(ContainSubstring("istio 1.12 of the ingress gateways is not supported"))
+			})
+
+			It("should report an unknown istio version and render the latest filters", func() {
+				setIstioProxyImage(istioNamespace1, "istio/proxyv2@sha256:0123456789abcdef")
+
+				extState, seed := reconcile()
+
+				Expect(extState.IstioVersion.Version).To(BeEmpty())
+				Expect(extState.IstioVersion.Message).To(ContainSubstring("is unknown"))
+				Expect(seed).To(ContainSubstring("rules_stat_prefix"))
+			})
+		})
+
+		Context("size of the EnvoyFilters of the istio ingress gateways", func() {
+			It("should track the size of the EnvoyFilters per gateway", func() {
+				a.extensionConfig.MaxGatewayEnvoyFilterBytes = 1 << 20
+				ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`))
+				Expect(ext).To(Not(BeNil()))
+
+				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ext), ext)).To(Succeed())
+				extState, err := GetExtensionState(ext)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(extState.GatewayEnvoyFilterBytes).To(HaveKeyWithValue(istioNamespace1, BeNumerically(">", 0)))
+				Expect(ext.Status.Conditions).NotTo(ContainElement(HaveField("Type", ConditionTypeConfigTooLarge)))
+			})
+
+			It("should refuse EnvoyFilters exceeding the maximum size and accept them once they fit", func() {
+				a.extensionConfig.MaxGatewayEnvoyFilterBytes = 100
+				recorder := record.NewFakeRecorder(10)
+				a.recorder = recorder
+				ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`))
+				Expect(ext).To(Not(BeNil()))
+
+				Expect(a.Reconcile(ctx, logger, ext)).To(MatchError(ErrConfigTooLarge))
+				Expect(recorder.Events).To(Receive(HavePrefix("Warning ConfigTooLarge Refused to apply the ACL rule")))
+				Expect(ext.Status.Conditions).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+					"Type":   Equal(ConditionTypeACLEffective),
+					"Status": Equal(gardencorev1beta1.ConditionFalse),
+					"Reason": Equal(ReasonConfigTooLarge),
+				})))
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, &v1alpha1.ManagedResource{})
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				Expect(ext.Status.Conditions).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+					"Type":   Equal(ConditionTypeConfigTooLarge),
+					"Status": Equal(gardencorev1beta1.ConditionTrue),
+					"Reason": Equal(ReasonConfigTooLarge),
+				})))
+
+				a.extensionConfig.MaxGatewayEnvoyFilterBytes = 1 << 20
+				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, &v1alpha1.ManagedResource{})).To(Succeed())
+				Expect(ext.Status.Conditions).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+					"Type":   Equal(ConditionTypeConfigTooLarge),
+					"Status": Equal(gardencorev1beta1.ConditionFalse),
+					"Reason": Equal(ReasonConfigSizeWithinLimit),
+				})))
+			})
+		})
+
+		Context("ACLEffective condition", func() {
+			reconcile := func(providerConfig string) *extensionsv1alpha1.Extension {
+				ext := createNewExtension(shootNamespace1, []byte(providerConfig))
+				Expect(ext).To(Not(BeNil()))
+
+				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+				return ext
+			}
+
+			It("should report the ACL as effective once the webhook mutated the EnvoyFilter of the internal flow", func() {
+				ext := reconcile(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`)
+				Expect(ext.Status.Conditions).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+					"Type":   Equal(ConditionTypeACLEffective),
+					"Status": Equal(gardencorev1beta1.ConditionProgressing),
+					"Reason": Equal(ReasonWebhookPending),
+				})))
+
+				envoyFilter := &istionetworkingClientGo.EnvoyFilter{ObjectMeta: metav1.ObjectMeta{Name: shootNamespace1, Namespace: istioNamespace1}}
+				Expect(k8sClient.Patch(ctx, envoyFilter, client.RawPatch(types.MergePatchType, []byte(
+					`{"spec":{"configPatches":[{"applyTo":"NETWORK_FILTER","patch":{"operation":"MERGE","value":{"filters":[{"name":"acl-internal-remote_ip"}]}}}]}}`,
+				)))).To(Succeed())
+				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+				Expect(ext.Status.Conditions).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+					"Type":   Equal(ConditionTypeACLEffective),
+					"Status": Equal(gardencorev1beta1.ConditionTrue),
+					"Reason": Equal(ReasonEnforced),
+				})))
+			})
+
+			It("should report LOG rules as shadow mode", func() {
+				ext := reconcile(`{"rule":{"action":"LOG","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`)
+				Expect(ext.Status.Conditions).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+					"Type":   Equal(ConditionTypeACLEffective),
+					"Status": Equal(gardencorev1beta1.ConditionFalse),
+					"Reason": Equal(ReasonShadowMode),
+				})))
+			})
+		})
+
+		Context("ACL info in the shoot", func() {
+			It("should publish the rules in the shoot and stop publishing them once disabled by the operator", func() {
+				a.extensionConfig.PublishShootInfo = true
+				ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`))
+				Expect(ext).To(Not(BeNil()))
+
+				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+				mr := &v1alpha1.ManagedResource{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameShoot, Namespace: shootNamespace1}, mr)).To(Succeed())
+				Expect(mr.Labels).To(HaveKeyWithValue("origin", "gardener-extension-acl"))
+				secret := &corev1.Secret{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+				Expect(secret.Data).To(HaveKeyWithValue("configmap__kube-system__acl-info.yaml", And(
+					ContainSubstring("name: acl-info"),
+					ContainSubstring("enforced: \"true\""),
+					ContainSubstring("rule.cidrs: 1.2.3.4/24"),
+				)))
+
+				a.extensionConfig.PublishShootInfo = false
+				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameShoot, Namespace: shootNamespace1}, mr)
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			})
+		})
+
+		Context("traffic paths of the shoot", func() {
+			reconcile := func() *ExtensionState {
+				ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`))
+				Expect(ext).To(Not(BeNil()))
+
+				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ext), ext)).To(Succeed())
+				extState, err := GetExtensionState(ext)
+				Expect(err).NotTo(HaveOccurred())
+				return extState
+			}
+
+			It("should report the apiserver-proxy path as protected if the EnvoyFilter was mutated by the webhook", func() {
+				envoyFilter := &istionetworkingClientGo.EnvoyFilter{ObjectMeta: metav1.ObjectMeta{Name: shootNamespace1, Namespace: istioNamespace1}}
+				Expect(k8sClient.Patch(ctx, envoyFilter, client.RawPatch(types.MergePatchType, []byte(
+					`{"spec":{"configPatches":[{"applyTo":"NETWORK_FILTER","patch":{"operation":"MERGE","value":{"filters":[{"name":"acl-internal-remote_ip"}]}}}]}}`,
+				)))).To(Succeed())
+
+				extState := reconcile()
+
+				Expect(extState.TrafficPaths).To(ConsistOf(
+					TrafficPathStatus{Name: TrafficPathAPIServerSNI, Hosts: []string{"test"}, Protected: true},
+					TrafficPathStatus{Name: TrafficPathAPIServerProxy, Protected: true},
+				))
+				Expect(extState.Warnings).NotTo(ContainElement(ContainSubstring("traffic path")))
+			})
+
+			It("should report the apiserver-proxy path as unprotected if the EnvoyFilter wasn't mutated by the webhook", func() {
+				extState := reconcile()
+
+				Expect(extState.TrafficPaths).To(ContainElement(TrafficPathStatus{
+					Name:   TrafficPathAPIServerProxy,
+					Reason: "the EnvoyFilter " + shootNamespace1 + " of the istio namespaces " + istioNamespace1 + " wasn't mutated by the webhook",
+				}))
+				Expect(extState.Warnings).To(ContainElement(ContainSubstring("the apiserver-proxy traffic path is not protected by the ACL")))
+			})
+
+			It("should not report the apiserver-proxy path if the shoot doesn't use it", func() {
+				Expect(k8sClient.Delete(ctx, &istionetworkingClientGo.EnvoyFilter{
+					ObjectMeta: metav1.ObjectMeta{Name: shootNamespace1, Namespace: istioNamespace1},
+				})).To(Succeed())
+
+				extState := reconcile()
+
+				Expect(extState.TrafficPaths).To(ConsistOf(
+					TrafficPathStatus{Name: TrafficPathAPIServerSNI, Hosts: []string{"test"}, Protected: true},
+				))
+			})
+
+			It("should report the hosts of further Gateways of the shoot as unprotected", func() {
+				gateway := &istionetworkingv1beta1.Gateway{
+					ObjectMeta: metav1.ObjectMeta{Name: "konnectivity-server", Namespace: shootNamespace1},
+					Spec: istioapinetworkingv1beta1.Gateway{
+						Selector: istioNamespace1Selector,
+						Servers: []*istioapinetworkingv1beta1.Server{{
+							Hosts: []string{"konnectivity.test", "test"},
+							Port:  &istioapinetworkingv1beta1.Port{Number: 8443, Name: "tls-tunnel", Protocol: "TLS"},
+							Tls:   &istioapinetworkingv1beta1.ServerTLSSettings{Mode: istioapinetworkingv1beta1.ServerTLSSettings_PASSTHROUGH},
+						}},
+					},
+				}
+				Expect(k8sClient.Create(ctx, gateway)).To(Succeed())
+
+				extState := reconcile()
+
+				Expect(extState.TrafficPaths).To(ContainElement(TrafficPathStatus{
+					Name:   "gateway/konnectivity-server",
+					Hosts:  []string{"konnectivity.test"},
+					Reason: "the hosts aren't matched by the filter chain of the API server",
+				}))
+				Expect(extState.Warnings).To(ContainElement(ContainSubstring("the gateway/konnectivity-server traffic path is not protected by the ACL")))
+			})
+		})
+
+		// gardener >= v1.89, including https://github.com/gardener/gardener/pull/9038
+		Context("ingress-nginx is exposed via istio", func() {
+			BeforeEach(func() {
+				gateway := createNewGateway("nginx-ingress-controller", "garden", map[string]string{
+					"app":   "istio-ingressgateway",
+					"istio": "ingressgateway",
+				})
+
+				DeferCleanup(func() {
+					Expect(k8sClient.Delete(ctx, gateway)).To(Or(Succeed(), BeNotFoundError()))
+				})
+			})
+
+			It("should create managed resource including acl-ingress-shoot EnvoyFilter object", func() {
+				extSpec := extensionspec.ExtensionSpec{
+					Rule: &envoyfilters.ACLRule{
+						Cidrs:  []string{"1.2.3.4/24"},
+						Action: "ALLOW",
+						Type:   "remote_ip",
+					},
+				}
+				extSpecJSON, err := json.Marshal(extSpec)
+				Expect(err).To(BeNil())
+				ext := createNewExtension(shootNamespace1, extSpecJSON)
+				Expect(ext).To(Not(BeNil()))
+
+				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+				mr := &v1alpha1.ManagedResource{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+				secret := &corev1.Secret{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+				Expect(secret.Data["seed"]).To(ContainSubstring("acl-ingress-" + shootNamespace1))
+			})
+		})
+
+		// gardener < v1.89
+		Context("ingress-nginx is not exposed via istio", func() {
+			It("should create managed resource not including acl-ingress-shoot EnvoyFilter object", func() {
+				extSpec := extensionspec.ExtensionSpec{
+					Rule: &envoyfilters.ACLRule{
+						Cidrs:  []string{"1.2.3.4/24"},
+						Action: "ALLOW",
+						Type:   "remote_ip",
+					},
+				}
+				extSpecJSON, err := json.Marshal(extSpec)
+				Expect(err).To(BeNil())
+				ext := createNewExtension(shootNamespace1, extSpecJSON)
+				Expect(ext).To(Not(BeNil()))
+
+				Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+				mr := &v1alpha1.ManagedResource{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+				secret := &corev1.Secret{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+				Expect(secret.Data["seed"]).NotTo(ContainSubstring("acl-ingress-" + shootNamespace1))
+			})
+		})
+	})
+
+	Describe("reconciliation of an extension object with other ACL extensions being present", func() {
+		BeforeEach(func() {
+			shootNamespace2 = createNewShootNamespace()
+			createNewEnvoyFilter(shootNamespace2, istioNamespace1)
+			createNewGateway("kube-apiserver", shootNamespace2, istioNamespace1Selector)
+			createNewCluster(shootNamespace2)
+			createNewInfrastructure(shootNamespace2)
+		})
+
+		AfterEach(func() {
+			deleteNamespace(shootNamespace2)
+		})
+
+		seedManifest := func(shootNamespace string) string {
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace}, secret)).To(Succeed())
+			return string(secret.Data["seed"])
+		}
+
+		It("should only render the rules of the reconciled extension into its own objects", func() {
+			ext1 := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`))
+			Expect(ext1).To(Not(BeNil()))
+			ext2 := createNewExtension(shootNamespace2, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["5.6.7.8/24"]}}`))
+			Expect(ext2).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext1)).To(Succeed())
+
+			Expect(seedManifest(shootNamespace1)).To(And(
+				ContainSubstring("acl-vpn-"+shootNamespace1),
+				ContainSubstring("1.2.3.0"),
+				Not(ContainSubstring("5.6.7.0")),
+			))
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace2}, &v1alpha1.ManagedResource{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should not fail when the Gateway resource can't be found for an extension other than the one being reconciled (e.g. for hibernated clusters)", func() {
+			ext1 := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`))
+			Expect(ext1).To(Not(BeNil()))
+
+			// contents of the seconds extension don't matter, it just needs to exist
+			ext2 := createNewExtension(shootNamespace2, []byte("{}"))
+			Expect(ext2).To(Not(BeNil()))
+
+			// simulate a hibernated cluster by deleting the Gateway object
+			gw2 := &istionetworkingv1beta1.Gateway{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "kube-apiserver",
+					Namespace: shootNamespace2,
+				},
+			}
+			Expect(k8sClient.Delete(ctx, gw2)).To(Succeed())
+
+			Expect(a.Reconcile(ctx, logger, ext1)).To(Succeed())
+
+			Expect(seedManifest(shootNamespace1)).To(ContainSubstring("1.2.3.0"))
+		})
+
+		It("should leave the objects of other extensions untouched when an extension is deleted", func() {
+			ext1 := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`))
+			Expect(ext1).To(Not(BeNil()))
+			ext2 := createNewExtension(shootNamespace2, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["5.6.7.8/24"]}}`))
+			Expect(ext2).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext1)).To(Succeed())
+			Expect(a.Reconcile(ctx, logger, ext2)).To(Succeed())
+
+			mr2 := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace2}, mr2)).To(Succeed())
+			resourceVersion := mr2.ResourceVersion
+
+			Expect(a.Delete(ctx, logger, ext1)).To(Succeed())
+
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, &v1alpha1.ManagedResource{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace2}, mr2)).To(Succeed())
+			Expect(mr2.ResourceVersion).To(Equal(resourceVersion))
+			Expect(seedManifest(shootNamespace2)).To(ContainSubstring("5.6.7.0"))
+		})
+
+		It("should remove the enforcement of a disabled ACL and restore it when it is enabled again", func() {
+			ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`))
+			Expect(ext).To(Not(BeNil()))
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(10)
+			a.recorder = recorder
+			metav1.SetMetaDataAnnotation(&ext.ObjectMeta, AnnotationDisabled, "true")
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, &v1alpha1.ManagedResource{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			Expect(recorder.Events).To(Receive(Equal("Warning Disabled Disabled the enforcement of the ACL")))
+			Expect(shootMetric("acl_controller_open_policy", shootNamespace1)).To(Equal([]float64{1}))
+
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.Disabled).To(BeTrue())
+			Expect(extState.Warnings).To(ConsistOf(disabledWarning))
+			Expect(ext.Status.Conditions).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+				"Type":    Equal(ConditionTypeConfigurationWarnings),
+				"Status":  Equal(gardencorev1beta1.ConditionTrue),
+				"Reason":  Equal(ReasonConfigurationNeedsAttention),
+				"Message": ContainSubstring(disabledWarning),
+			})))
+			Expect(ext.Status.Conditions).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+				"Type":   Equal(ConditionTypeACLEffective),
+				"Status": Equal(gardencorev1beta1.ConditionFalse),
+				"Reason": Equal(ReasonDisabled),
+			})))
+			Expect(extState.Allowlist).To(BeEmpty())
+			Expect(extState.GetIstioNamespaces()).NotTo(BeEmpty())
+			Expect(extState.History).To(HaveLen(2))
+			Expect(extState.History[1].Checksum).To(BeEmpty())
+			Expect(extState.History[1].Removed).To(ContainElement("1.2.3.4/24"))
+
+			delete(ext.Annotations, AnnotationDisabled)
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			Expect(seedManifest(shootNamespace1)).To(ContainSubstring("1.2.3.0"))
+			extState, err = GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.Disabled).To(BeFalse())
+			Expect(extState.Warnings).NotTo(ContainElement(disabledWarning))
+			Expect(ext.Status.Conditions).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+				"Type":    Equal(ConditionTypeConfigurationWarnings),
+				"Message": Not(ContainSubstring(disabledWarning)),
+			})))
+		})
+
+		It("should report the configuration warnings in a dedicated condition", func() {
+			ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["10.0.0.0/8","10.1.0.0/16"]}}`))
+			Expect(ext).To(Not(BeNil()))
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ext), ext)).To(Succeed())
+			Expect(ext.Status.Conditions).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+				"Type":    Equal(ConditionTypeConfigurationWarnings),
+				"Status":  Equal(gardencorev1beta1.ConditionTrue),
+				"Reason":  Equal(ReasonConfigurationNeedsAttention),
+				"Message": ContainSubstring("CIDR 10.1.0.0/16 is contained in 10.0.0.0/8"),
+			})))
+			// the warnings don't affect the health of the shoot
+			Expect(ext.Status.Conditions).NotTo(ContainElement(HaveField("Type", gardencorev1beta1.ShootControlPlaneHealthy)))
+
+			ext.Spec.ProviderConfig.Raw = []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["10.0.0.0/8"]}}`)
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ext), ext)).To(Succeed())
+			Expect(ext.Status.Conditions).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+				"Type":    Equal(ConditionTypeConfigurationWarnings),
+				"Message": Not(ContainSubstring("10.1.0.0/16")),
+			})))
+		})
+
+		It("should not validate the rules of an ACL disabled in the providerConfig", func() {
+			ext := createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4"]},"disabled":true}`))
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.Disabled).To(BeTrue())
+		})
+	})
+
+	Describe("a shoot switching the istio namespace (e.g. when being migrated to HA)", func() {
+		It("should modify the EnvoyFilter objects accordingly", func() {
+			By("1) creating the EnvoyFilter object correctly in the ORIGINAL namespace")
+			// arrange
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/24"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			// act
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			// assert
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			Expect(secret.Data["seed"]).To(ContainSubstring("1.2.3.0"))
+			Expect(secret.Data["seed"]).To(ContainSubstring(istioNamespace1))
+			Expect(secret.Data["seed"]).To(ContainSubstring("acl-vpn-" + shootNamespace1))
+
+			By("2) allowing for the shoot to switch to a different Istio namespace")
+			istioNamespace2 = createNewIstioNamespace()
+			istioNamespace2Selector = map[string]string{
+				"app":   "istio-ingressgateway",
+				"istio": istioNamespace2,
+			}
+
+			// switching the istio namespace by recreating the gateway in the
+			// shoot namespace with a new selector and creating a new
+			// EnvoyFilter for the shoot and Istio Deployment in the second
+			// istio namespace
+			gw := &istionetworkingv1beta1.Gateway{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "kube-apiserver",
+					Namespace: shootNamespace1,
+				},
+			}
+			Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+			createNewGateway("kube-apiserver", shootNamespace1, istioNamespace2Selector)
+			createNewIstioDeployment(istioNamespace2, istioNamespace2Selector)
+			createNewEnvoyFilter(shootNamespace1, istioNamespace2)
+
+			By("3) creating the EnvoyFilter object correctly in the NEW namespace")
+			// arrange
+			ext = &extensionsv1alpha1.Extension{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: shootNamespace1, Name: "acl"}, ext)).To(Succeed())
+
+			// act
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			// assert
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			Expect(secret.Data["seed"]).To(ContainSubstring(istioNamespace2))
+			Expect(secret.Data["seed"]).To(ContainSubstring("acl-vpn-" + shootNamespace1))
+
+			By("4) should have removed the EnvoyFilter object in the ORIGINAL namespace")
+			Expect(secret.Data["seed"]).NotTo(ContainSubstring(istioNamespace1))
+		})
+	})
+
+	Describe("a shoot served by multiple istio ingress gateways (e.g. zonal gateways)", func() {
+		It("should create the EnvoyFilter objects in all istio namespaces", func() {
+			zonalIstioNamespace := createNewIstioNamespace()
+			defer deleteNamespace(zonalIstioNamespace)
+			createNewIstioDeployment(zonalIstioNamespace, istioNamespace1Selector)
+
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/24"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			Expect(secret.Data["seed"]).To(ContainSubstring("namespace: " + istioNamespace1 + "\n"))
+			Expect(secret.Data["seed"]).To(ContainSubstring("namespace: " + zonalIstioNamespace + "\n"))
+
+			ext = &extensionsv1alpha1.Extension{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: shootNamespace1, Name: "acl"}, ext)).To(Succeed())
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.IstioNamespaces).To(ConsistOf(istioNamespace1, zonalIstioNamespace))
+		})
+
+		It("should not touch the istio namespaces which are ignored", func() {
+			zonalIstioNamespace := createNewIstioNamespace()
+			defer deleteNamespace(zonalIstioNamespace)
+			createNewIstioDeployment(zonalIstioNamespace, istioNamespace1Selector)
+			a.extensionConfig.IgnoredNamespaces = helper.NamespacePatterns{regexp.MustCompile("^" + regexp.QuoteMeta(zonalIstioNamespace) + "$")}
+
+			extSpecJSON, err := json.Marshal(extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{Cidrs: []string{"1.2.3.4/24"}, Action: "ALLOW", Type: "remote_ip"},
+			})
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			ext = &extensionsv1alpha1.Extension{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: shootNamespace1, Name: "acl"}, ext)).To(Succeed())
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			Expect(extState.IstioNamespaces).To(ConsistOf(istioNamespace1))
+		})
+	})
+
+	Describe("a seed with an ExposureClass handler", func() {
+		var (
+			handlerIstioNamespace string
+			handlerSelector       map[string]string
+		)
+
+		BeforeEach(func() {
+			handlerIstioNamespace = createNewIstioNamespace()
+			DeferCleanup(deleteNamespace, handlerIstioNamespace)
+
+			// the handler's gateway deployment also matches the selector of the
+			// default gateway
+			handlerSelector = map[string]string{
+				"handler.exposureclass.gardener.cloud/name": "internet",
+			}
+			for k, v := range istioNamespace1Selector {
+				handlerSelector[k] = v
+			}
+			createNewIstioDeployment(handlerIstioNamespace, handlerSelector)
+		})
+
+		reconcileAndGetIstioNamespaces := func() []string {
+			extSpec := extensionspec.ExtensionSpec{
+				Rule: &envoyfilters.ACLRule{
+					Cidrs:  []string{"1.2.3.4/24"},
+					Action: "ALLOW",
+					Type:   "remote_ip",
+				},
+			}
+			extSpecJSON, err := json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(ext).To(Not(BeNil()))
+
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			ext = &extensionsv1alpha1.Extension{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: shootNamespace1, Name: "acl"}, ext)).To(Succeed())
+			extState, err := GetExtensionState(ext)
+			Expect(err).To(BeNil())
+			return extState.IstioNamespaces
+		}
+
+		It("should not target the handler's gateway for shoots without ExposureClass", func() {
+			Expect(reconcileAndGetIstioNamespaces()).To(ConsistOf(istioNamespace1))
+		})
+
+		It("should only target the handler's gateway for shoots with ExposureClass", func() {
+			gw := &istionetworkingv1beta1.Gateway{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: shootNamespace1, Name: "kube-apiserver"}, gw)).To(Succeed())
+			gw.Spec.Selector = handlerSelector
+			Expect(k8sClient.Update(ctx, gw)).To(Succeed())
+
+			Expect(reconcileAndGetIstioNamespaces()).To(ConsistOf(handlerIstioNamespace))
+		})
+	})
+
+	Describe("aggregated EnvoyFilters", func() {
+		var ext *extensionsv1alpha1.Extension
+
+		getSeedResources := func() string {
+			mr := &v1alpha1.ManagedResource{}
+			ExpectWithOffset(1, k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			ExpectWithOffset(1, k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			return string(secret.Data["seed"])
+		}
+
+		acknowledge := func() {
+			configMap := &corev1.ConfigMap{}
+			ExpectWithOffset(1, k8sClient.Get(ctx, types.NamespacedName{Name: AggregatedPatchesConfigMapName, Namespace: shootNamespace1}, configMap)).To(Succeed())
+			patch := client.MergeFrom(configMap.DeepCopy())
+			metav1.SetMetaDataAnnotation(&configMap.ObjectMeta, AggregatedChecksumAnnotation, configMap.Annotations[ChecksumAnnotation])
+			ExpectWithOffset(1, k8sClient.Patch(ctx, configMap, patch)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			a.extensionConfig.AggregateEnvoyFilters = true
+			ext = createNewExtension(shootNamespace1, []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/24"]}}`))
+			Expect(ext).To(Not(BeNil()))
+		})
+
+		It("should keep the dedicated EnvoyFilters until the patches are aggregated", func() {
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: AggregatedPatchesConfigMapName, Namespace: shootNamespace1}, configMap)).To(Succeed())
+			Expect(configMap.Labels).To(HaveKeyWithValue(AggregatedLabel, "true"))
+			Expect(configMap.OwnerReferences).To(ConsistOf(HaveField("UID", ext.UID)))
+			Expect(configMap.Data[AggregatedPatchesDataKey]).To(And(ContainSubstring("1.2.3.0"), ContainSubstring(istioNamespace1)))
+			Expect(getSeedResources()).To(ContainSubstring("acl-api-" + shootNamespace1))
+
+			By("removing the dedicated EnvoyFilters once the patches are acknowledged")
+			acknowledge()
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+			Expect(getSeedResources()).NotTo(ContainSubstring("acl-api-" + shootNamespace1))
+			Expect(getSeedResources()).NotTo(ContainSubstring("acl-vpn-" + shootNamespace1))
+
+			extState, err := GetExtensionState(ext)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(extState.Aggregated).To(BeTrue())
+
+			By("not rendering the dedicated EnvoyFilters again when the rule changes")
+			ext.Spec.ProviderConfig.Raw = []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["5.6.7.8/24"]}}`)
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+			Expect(getSeedResources()).NotTo(ContainSubstring("acl-api-" + shootNamespace1))
+		})
+
+		It("should only leave the aggregation once the dedicated EnvoyFilters are applied", func() {
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+			acknowledge()
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+
+			a.extensionConfig.AggregateEnvoyFilters = false
+			err := a.Reconcile(ctx, logger, ext)
+			requeueAfterErr := &reconcilerutils.RequeueAfterError{}
+			Expect(errors.As(err, &requeueAfterErr)).To(BeTrue())
+			Expect(requeueAfterErr.Cause).To(Equal(ErrLeavingAggregation))
+			Expect(getSeedResources()).To(ContainSubstring("acl-api-" + shootNamespace1))
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: AggregatedPatchesConfigMapName, Namespace: shootNamespace1}, &corev1.ConfigMap{})).To(Succeed())
+		})
 
-			Expect(a.Reconcile(ctx, logger, ext1)).To(Succeed())
+		It("should delete the ConfigMap of a shoot which wasn't aggregated yet", func() {
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
 
-			envoyFilter = &istionetworkingClientGo.EnvoyFilter{}
-			err = k8sClient.Get(
-				ctx, types.NamespacedName{
-					Name:      "acl-vpn",
-					Namespace: istioNamespace1,
-				},
-				envoyFilter,
-			)
+			a.extensionConfig.AggregateEnvoyFilters = false
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: AggregatedPatchesConfigMapName, Namespace: shootNamespace1}, &corev1.ConfigMap{})
 			Expect(apierrors.IsNotFound(err)).To(BeTrue())
 		})
+	})
 
-		It("should not fail when the Gateway resource can't be found for an extension other than the one being reconciled (e.g. for hibernated clusters)", func() {
-			// arrange
-			extSpec1 := extensionspec.ExtensionSpec{
+	Describe("reconciliation of a hibernated cluster", func() {
+		setHibernated := func(hibernated bool) {
+			cluster := &extensionsv1alpha1.Cluster{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: shootNamespace1}, cluster)).To(Succeed())
+			shoot := &gardencorev1beta1.Shoot{}
+			Expect(json.Unmarshal(cluster.Spec.Shoot.Raw, shoot)).To(Succeed())
+			shoot.Spec.Hibernation = &gardencorev1beta1.Hibernation{Enabled: ptr.To(hibernated)}
+			shoot.Status.IsHibernated = hibernated
+			cluster.Spec.Shoot = runtime.RawExtension{Object: shoot}
+			Expect(k8sClient.Update(ctx, cluster)).To(Succeed())
+		}
+
+		seedResources := func() string {
+			mr := &v1alpha1.ManagedResource{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
+			return string(secret.Data["seed"])
+		}
+
+		It("should keep the rendered filters while hibernated and apply changes on wake-up", func() {
+			extSpec := extensionspec.ExtensionSpec{
 				Rule: &envoyfilters.ACLRule{
 					Cidrs:  []string{"1.2.3.4/24"},
 					Action: "ALLOW",
 					Type:   "remote_ip",
 				},
 			}
-			extSpecJSON1, err := json.Marshal(extSpec1)
+			extSpecJSON, err := json.Marshal(extSpec)
 			Expect(err).To(BeNil())
-			ext1 := createNewExtension(shootNamespace1, extSpecJSON1)
-			Expect(ext1).To(Not(BeNil()))
-
-			// contents of the seconds extension don't matter, it just needs to exist
-			ext2 := createNewExtension(shootNamespace2, []byte("{}"))
-			Expect(ext2).To(Not(BeNil()))
+			ext := createNewExtension(shootNamespace1, extSpecJSON)
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
 
-			// simulate a hibernated cluster by deleting the Gateway object
-			gw2 := &istionetworkingv1beta1.Gateway{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "kube-apiserver",
-					Namespace: shootNamespace2,
-				},
-			}
-			Expect(k8sClient.Delete(ctx, gw2)).To(Succeed())
+			By("hibernating the shoot, which deletes its Gateway")
+			setHibernated(true)
+			Expect(k8sClient.Delete(ctx, &istionetworkingv1beta1.Gateway{
+				ObjectMeta: metav1.ObjectMeta{Name: "kube-apiserver", Namespace: shootNamespace1},
+			})).To(Succeed())
 
-			// act (reconcile the existing extension)
-			Expect(a.Reconcile(ctx, logger, ext1)).To(Succeed())
+			extSpec.Rule.Cidrs = []string{"5.6.7.8/24"}
+			extSpecJSON, err = json.Marshal(extSpec)
+			Expect(err).To(BeNil())
+			ext.Spec.ProviderConfig = &runtime.RawExtension{Raw: extSpecJSON}
 
-			// assert
-			envoyFilter := &istionetworkingClientGo.EnvoyFilter{}
-			Expect(k8sClient.Get(
-				ctx, types.NamespacedName{
-					Name:      "acl-vpn",
-					Namespace: istioNamespace1,
-				},
-				envoyFilter,
-			)).To(Succeed())
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+			Expect(seedResources()).To(And(ContainSubstring("1.2.3.0"), Not(ContainSubstring("5.6.7.0"))))
 
-			// we expect the filter to contain the settings from the first extension
-			Expect(envoyFilter.Spec.MarshalJSON()).To(And(
-				ContainSubstring("1.2.3.4"),
-			))
+			By("waking up the shoot")
+			setHibernated(false)
+			createNewGateway("kube-apiserver", shootNamespace1, istioNamespace1Selector)
 
+			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+			Expect(seedResources()).To(And(ContainSubstring("5.6.7.0"), Not(ContainSubstring("1.2.3.0"))))
 		})
 	})
 
-	Describe("a shoot switching the istio namespace (e.g. when being migrated to HA)", func() {
-		It("should modify the EnvoyFilter objects accordingly", func() {
-			By("1) creating the EnvoyFilter object correctly in the ORIGINAL namespace")
+	Describe("deletion of a hibernated cluster (no Gateway resource exists)", func() {
+		It("should properly clean up according ManagedResource", func() {
 			// arrange
 			extSpec := extensionspec.ExtensionSpec{
 				Rule: &envoyfilters.ACLRule{
@@ -414,75 +1818,35 @@ var _ = Describe("actuator test", func() {
 			ext := createNewExtension(shootNamespace1, extSpecJSON)
 			Expect(ext).To(Not(BeNil()))
 
-			// act
 			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
 
-			// assert
 			mr := &v1alpha1.ManagedResource{}
 			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
-			secret := &corev1.Secret{}
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
-			Expect(secret.Data["seed"]).To(ContainSubstring("1.2.3.4"))
-			Expect(secret.Data["seed"]).To(ContainSubstring(istioNamespace1))
-			Expect(secret.Data["seed"]).To(ContainSubstring("acl-vpn-" + shootNamespace1))
-
-			// check legacy envoyfilter
-			envoyFilter := &istionetworkingClientGo.EnvoyFilter{}
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "acl-vpn", Namespace: istioNamespace1}, envoyFilter)).To(Succeed())
-			Expect(envoyFilter.Spec.MarshalJSON()).To(ContainSubstring("1.2.3.4"))
-
-			By("2) allowing for the shoot to switch to a different Istio namespace")
-			istioNamespace2 = createNewIstioNamespace()
-			istioNamespace2Selector = map[string]string{
-				"app":   "istio-ingressgateway",
-				"istio": istioNamespace2,
-			}
 
-			// switching the istio namespace by recreating the gateway in the
-			// shoot namespace with a new selector and creating a new
-			// EnvoyFilter for the shoot and Istio Deployment in the second
-			// istio namespace
+			// simulate a hibernated cluster by deleting the Gateway object
 			gw := &istionetworkingv1beta1.Gateway{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "kube-apiserver",
 					Namespace: shootNamespace1,
 				},
 			}
-			Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
-			createNewGateway("kube-apiserver", shootNamespace1, istioNamespace2Selector)
-			createNewIstioDeployment(istioNamespace2, istioNamespace2Selector)
-			createNewEnvoyFilter(shootNamespace1, istioNamespace2)
 
-			By("3) creating the EnvoyFilter object correctly in the NEW namespace")
-			// arrange
-			ext = &extensionsv1alpha1.Extension{}
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: shootNamespace1, Name: "acl"}, ext)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
 
 			// act
-			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
+			Expect(a.Delete(ctx, logger, ext)).To(Succeed())
 
 			// assert
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: mr.Spec.SecretRefs[0].Name, Namespace: shootNamespace1}, secret)).To(Succeed())
-			Expect(secret.Data["seed"]).To(ContainSubstring(istioNamespace2))
-			Expect(secret.Data["seed"]).To(ContainSubstring("acl-vpn-" + shootNamespace1))
-
-			// check legacy envoyfilter
-			envoyFilter = &istionetworkingClientGo.EnvoyFilter{}
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "acl-vpn", Namespace: istioNamespace2}, envoyFilter)).To(Succeed())
-			Expect(envoyFilter.Spec.MarshalJSON()).To(ContainSubstring("1.2.3.4"))
-
-			By("4) should have removed the EnvoyFilter object in the ORIGINAL namespace")
-			Expect(secret.Data["seed"]).NotTo(ContainSubstring(istioNamespace1))
-			// check legacy envoyfilter
-			envoyFilter = &istionetworkingClientGo.EnvoyFilter{}
-			err = k8sClient.Get(ctx, types.NamespacedName{Name: "acl-vpn", Namespace: istioNamespace1}, envoyFilter)
+			mr = &v1alpha1.ManagedResource{}
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)
+			Expect(err).ToNot(BeNil())
 			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
 		})
 	})
 
-	Describe("deletion of a hibernated cluster (no Gateway resource exists)", func() {
-		It("should properly clean up according ManagedResource", func() {
+	Describe("deletion of a cluster whose istio namespace changed since the last reconciliation", func() {
+		It("should clean up the recorded istio namespace as well", func() {
 			// arrange
 			extSpec := extensionspec.ExtensionSpec{
 				Rule: &envoyfilters.ACLRule{
@@ -498,28 +1862,47 @@ var _ = Describe("actuator test", func() {
 
 			Expect(a.Reconcile(ctx, logger, ext)).To(Succeed())
 
-			mr := &v1alpha1.ManagedResource{}
-			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)).To(Succeed())
+			// the hash annotation is removed when the webhook is triggered for
+			// the EnvoyFilter of the shoot
+			envoyFilter := &istionetworkingClientGo.EnvoyFilter{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: shootNamespace1, Namespace: istioNamespace1}, envoyFilter)).To(Succeed())
+			envoyFilter.Annotations = map[string]string{HashAnnotationName: "stale"}
+			Expect(k8sClient.Update(ctx, envoyFilter)).To(Succeed())
 
-			// simulate a hibernated cluster by deleting the Gateway object
+			// the shoot switches to another istio namespace, but is deleted
+			// before the extension is reconciled again
+			istioNamespace2 = createNewIstioNamespace()
+			istioNamespace2Selector = map[string]string{
+				"app":   "istio-ingressgateway",
+				"istio": istioNamespace2,
+			}
 			gw := &istionetworkingv1beta1.Gateway{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "kube-apiserver",
 					Namespace: shootNamespace1,
 				},
 			}
-
 			Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+			createNewGateway("kube-apiserver", shootNamespace1, istioNamespace2Selector)
+			createNewIstioDeployment(istioNamespace2, istioNamespace2Selector)
+			createNewEnvoyFilter(shootNamespace1, istioNamespace2)
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ext), ext)).To(Succeed())
+			ext.Finalizers = append(ext.Finalizers, "extensions.gardener.cloud/acl")
+			Expect(k8sClient.Update(ctx, ext)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, ext)).To(Succeed())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ext), ext)).To(Succeed())
+			DeferCleanup(func() {
+				ext.Finalizers = nil
+				Expect(client.IgnoreNotFound(k8sClient.Update(ctx, ext))).To(Succeed())
+			})
 
 			// act
 			Expect(a.Delete(ctx, logger, ext)).To(Succeed())
 
 			// assert
-			mr = &v1alpha1.ManagedResource{}
-			err = k8sClient.Get(ctx, types.NamespacedName{Name: ResourceNameSeed, Namespace: shootNamespace1}, mr)
-			Expect(err).ToNot(BeNil())
-			Expect(apierrors.IsNotFound(err)).To(BeTrue())
-
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(envoyFilter), envoyFilter)).To(Succeed())
+			Expect(envoyFilter.Annotations).NotTo(HaveKey(HashAnnotationName))
 		})
 	})
 })
@@ -553,10 +1936,25 @@ var _ = Describe("actuator unit test", func() {
 		deleteNamespace(namespace)
 	})
 
-	// TODO: test case can be removed together with the migration code in the
-	// triggerWebhook() function, the test only checks that the deprecated hash
-	// annotation is properly removed
 	Describe("triggerWebhook", func() {
+		It("Should label the envoyfilter for the webhook's objectSelector", func() {
+			envoyFilter := &istionetworkingClientGo.EnvoyFilter{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      namespace,
+					Namespace: istioNamespace,
+				},
+			}
+			Expect(k8sClient.Create(ctx, envoyFilter)).To(Succeed())
+
+			Expect(a.triggerWebhook(ctx, namespace, istioNamespace)).To(Succeed())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(envoyFilter), envoyFilter)).To(Succeed())
+
+			Expect(envoyFilter.Labels).To(HaveKeyWithValue(WebhookLabel, "true"))
+		})
+
+		// TODO: test case can be removed together with the migration code in the
+		// triggerWebhook() function, the test only checks that the deprecated hash
+		// annotation is properly removed
 		When("the envoyfilter has a hash annotation", func() {
 			It("Should remove the hash annotation", func() {
 				envoyFilter := &istionetworkingClientGo.EnvoyFilter{
@@ -584,11 +1982,290 @@ var _ = Describe("actuator unit test", func() {
 		})
 	})
 
+	Describe("verifyAPIServerReachability", func() {
+		It("Should record a reachable API server", func() {
+			server := httptest.NewTLSServer(http.NotFoundHandler())
+			defer server.Close()
+
+			result := verifyAPIServerReachability(ctx, strings.TrimPrefix(server.URL, "https://"))
+			Expect(result.Reachable).To(BeTrue())
+			Expect(result.Message).To(BeEmpty())
+		})
+
+		It("Should record an unreachable API server", func() {
+			server := httptest.NewTLSServer(http.NotFoundHandler())
+			address := strings.TrimPrefix(server.URL, "https://")
+			server.Close()
+
+			result := verifyAPIServerReachability(ctx, address)
+			Expect(result.Reachable).To(BeFalse())
+			Expect(result.Address).To(Equal(address))
+			Expect(result.Message).NotTo(BeEmpty())
+			Expect(verificationWarning(result)).To(ContainSubstring(address))
+		})
+
+		It("Should only probe again after the rules changed or for another host", func() {
+			previous := &VerificationResult{Address: "api.example.com:443"}
+
+			Expect(needsVerification(nil, "api.example.com", false)).To(BeTrue())
+			Expect(needsVerification(previous, "api.example.com", false)).To(BeFalse())
+			Expect(needsVerification(previous, "api.example.com:443", false)).To(BeFalse())
+			Expect(needsVerification(previous, "api.example.com", true)).To(BeTrue())
+			Expect(needsVerification(previous, "api.example.org", false)).To(BeTrue())
+		})
+	})
+
+	Describe("ensureIstioInstalled", func() {
+		var (
+			mapper *meta.DefaultRESTMapper
+			ex     *extensionsv1alpha1.Extension
+		)
+
+		BeforeEach(func() {
+			mapper = meta.NewDefaultRESTMapper(nil)
+			ex = &extensionsv1alpha1.Extension{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "acl"}}
+
+			scheme := runtime.NewScheme()
+			Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+			a = &actuator{client: fakeclient.NewClientBuilder().
+				WithScheme(scheme).
+				WithRESTMapper(mapper).
+				WithObjects(ex).
+				WithStatusSubresource(ex).
+				Build(),
+			}
+		})
+
+		It("Should requeue the extension and mark the seed as incompatible if istio is not installed", func() {
+			err := a.ensureIstioInstalled(ctx, ex)
+
+			requeueAfterErr := &reconcilerutils.RequeueAfterError{}
+			Expect(errors.As(err, &requeueAfterErr)).To(BeTrue())
+			Expect(requeueAfterErr.Cause).To(Equal(ErrIstioNotInstalled))
+			Expect(ex.Status.Conditions).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+				"Type":   Equal(ConditionTypeIncompatibleSeed),
+				"Status": Equal(gardencorev1beta1.ConditionTrue),
+				"Reason": Equal(ReasonIstioNotInstalled),
+			})))
+		})
+
+		It("Should reset the condition once istio is installed", func() {
+			Expect(a.ensureIstioInstalled(ctx, ex)).NotTo(Succeed())
+
+			mapper.Add(istionetworkingClientGo.SchemeGroupVersion.WithKind("EnvoyFilter"), meta.RESTScopeNamespace)
+			mapper.Add(istionetworkingv1beta1.SchemeGroupVersion.WithKind("Gateway"), meta.RESTScopeNamespace)
+			Expect(a.ensureIstioInstalled(ctx, ex)).To(Succeed())
+
+			Expect(ex.Status.Conditions).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+				"Type":   Equal(ConditionTypeIncompatibleSeed),
+				"Status": Equal(gardencorev1beta1.ConditionFalse),
+				"Reason": Equal(ReasonIstioInstalled),
+			})))
+		})
+
+		It("Should not add the condition to extensions in seeds with istio", func() {
+			mapper.Add(istionetworkingClientGo.SchemeGroupVersion.WithKind("EnvoyFilter"), meta.RESTScopeNamespace)
+			mapper.Add(istionetworkingv1beta1.SchemeGroupVersion.WithKind("Gateway"), meta.RESTScopeNamespace)
+
+			Expect(a.ensureIstioInstalled(ctx, ex)).To(Succeed())
+			Expect(ex.Status.Conditions).To(BeEmpty())
+		})
+	})
+
+	Describe("validateIPFamilies", func() {
+		var (
+			ipv4      = []gardencorev1beta1.IPFamily{gardencorev1beta1.IPFamilyIPv4}
+			ipv6      = []gardencorev1beta1.IPFamily{gardencorev1beta1.IPFamilyIPv6}
+			dualStack = []gardencorev1beta1.IPFamily{gardencorev1beta1.IPFamilyIPv4, gardencorev1beta1.IPFamilyIPv6}
+		)
+
+		DescribeTable("should only reject rules which deny all connections of a family of the seed",
+			func(action string, cidrs []string, seedIPFamilies []gardencorev1beta1.IPFamily, valid bool) {
+				rule := &envoyfilters.ACLRule{Action: action, Type: "remote_ip", Cidrs: cidrs}
+				if valid {
+					Expect(validateIPFamilies(rule, seedIPFamilies)).To(Succeed())
+				} else {
+					Expect(validateIPFamilies(rule, seedIPFamilies)).To(MatchError(ErrSpecIPFamily))
+				}
+			},
+			Entry("IPv4 rule in an IPv4 seed", "ALLOW", []string{"1.2.3.0/24"}, ipv4, true),
+			Entry("IPv4 rule in an IPv6-only seed", "ALLOW", []string{"1.2.3.0/24"}, ipv6, false),
+			Entry("IPv6 rule in an IPv6-only seed", "ALLOW", []string{"2001:db8::/32"}, ipv6, true),
+			Entry("IPv4 DENY rule in an IPv6-only seed", "DENY", []string{"1.2.3.0/24"}, ipv6, true),
+			Entry("IPv4 rule in a dual-stack seed", "ALLOW", []string{"1.2.3.0/24"}, dualStack, false),
+			Entry("dual-stack rule in a dual-stack seed", "ALLOW", []string{"1.2.3.0/24", "2001:db8::/32"}, dualStack, true),
+		)
+	})
+
+	Describe("clampedCIDRs", func() {
+		It("should return the CIDRs overlapping with the denied CIDRs", func() {
+			rule := &envoyfilters.ACLRule{
+				Action:      "ALLOW",
+				Cidrs:       []string{"10.0.0.0/8", "192.168.1.0/24", "172.16.0.0/12"},
+				DeniedCIDRs: []string{"10.1.0.0/16", "192.168.0.0/16"},
+			}
+			Expect(clampedCIDRs(rule)).To(Equal([]string{"10.0.0.0/8", "192.168.1.0/24"}))
+		})
+
+		It("should not clamp DENY rules", func() {
+			rule := &envoyfilters.ACLRule{
+				Action:      "DENY",
+				Cidrs:       []string{"10.0.0.0/8"},
+				DeniedCIDRs: []string{"10.1.0.0/16"},
+			}
+			Expect(clampedCIDRs(rule)).To(BeEmpty())
+		})
+	})
+
+	Describe("shootInfoData", func() {
+		It("should describe the rules of the shoot", func() {
+			spec := &extensionspec.ExtensionSpec{
+				Profile: extensionspec.ProfileAPIServerOnly,
+				Rule: &envoyfilters.ACLRule{
+					Action: "allow",
+					Type:   "remote_ip",
+					Cidrs:  []string{"10.0.0.0/16", "192.0.2.0/24"},
+					Except: []string{"10.0.5.0/24"},
+				},
+				InternalRule: &envoyfilters.ACLRule{Action: "ALLOW", Type: "remote_ip", Cidrs: []string{"10.0.0.0/8"}},
+			}
+
+			Expect(shootInfoData(spec, config.EnforcementBackendEnvoyFilter)).To(Equal(map[string]string{
+				"enforced":            "true",
+				"enforcementBackend":  "envoyfilter",
+				"targets":             "apiserver",
+				"rule.action":         "ALLOW",
+				"rule.type":           "remote_ip",
+				"rule.cidrs":          "10.0.0.0/16\n192.0.2.0/24",
+				"rule.except":         "10.0.5.0/24",
+				"internalRule.action": "ALLOW",
+				"internalRule.type":   "remote_ip",
+				"internalRule.cidrs":  "10.0.0.0/8",
+			}))
+		})
+
+		It("should only report that the ACL of a disabled shoot isn't enforced", func() {
+			Expect(shootInfoData(nil, config.EnforcementBackendEnvoyFilter)).To(Equal(map[string]string{"enforced": "false"}))
+		})
+	})
+
+	Describe("requeueAfter", func() {
+		var a *actuator
+
+		BeforeEach(func() {
+			a = &actuator{extensionConfig: config.Config{
+				RequeueGatewayNotFound: 15 * time.Second,
+				RequeueConflict:        time.Second,
+				RequeueRulesRejected:   10 * time.Minute,
+			}}
+		})
+
+		DescribeTable("should requeue the error classes after their interval",
+			func(err error, requeueAfter time.Duration) {
+				requeueAfterErr := &reconcilerutils.RequeueAfterError{}
+				Expect(errors.As(a.requeueAfter(err), &requeueAfterErr)).To(BeTrue())
+				Expect(requeueAfterErr.Cause).To(Equal(err))
+				Expect(requeueAfterErr.RequeueAfter).To(Equal(requeueAfter))
+			},
+			Entry("missing istio Gateway", fmt.Errorf("%w: not found", ErrGatewayNotFound), 15*time.Second),
+			Entry("conflict", apierrors.NewConflict(v1alpha1.Resource("managedresources"), ResourceNameSeed, errors.New("modified")), time.Second),
+			Entry("rejected rule", &rejectedRuleError{ErrSpecCIDR}, 10*time.Minute),
+		)
+
+		It("should keep the exponential backoff for classes without an interval", func() {
+			Expect(a.requeueAfter(ErrNoAdvertisedAddresses)).To(Equal(ErrNoAdvertisedAddresses))
+		})
+
+		It("should keep the exponential backoff for unknown errors", func() {
+			err := errors.New("unknown")
+			Expect(a.requeueAfter(err)).To(Equal(err))
+		})
+
+		It("should not change errors which are requeued already", func() {
+			err := &reconcilerutils.RequeueAfterError{Cause: ErrIstioNotInstalled, RequeueAfter: time.Minute}
+			Expect(a.requeueAfter(err)).To(BeIdenticalTo(err))
+		})
+	})
+
+	Describe("shootLogger", func() {
+		It("should add the technical ID and the shoot to the log context", func() {
+			var lines []string
+			log := funcr.New(func(prefix, args string) { lines = append(lines, args) }, funcr.Options{})
+			ex := &extensionsv1alpha1.Extension{ObjectMeta: metav1.ObjectMeta{Name: "acl", Namespace: "shoot--bar--foo"}}
+			cluster := &extensionscontroller.Cluster{Shoot: &gardencorev1beta1.Shoot{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "garden-bar"}}}
+
+			shootLogger(log, ex, cluster).Info("reconciling")
+			shootLogger(log, ex, nil).Info("deleting")
+
+			Expect(lines).To(HaveExactElements(
+				And(ContainSubstring(`"technicalID"="shoot--bar--foo"`), ContainSubstring(`"shoot"="garden-bar/foo"`)),
+				And(ContainSubstring(`"technicalID"="shoot--bar--foo"`), Not(ContainSubstring(`"shoot"`))),
+			))
+		})
+	})
+
+	Describe("collectWarnings", func() {
+		It("should not return warnings for a regular rule", func() {
+			extSpec := &extensionspec.ExtensionSpec{}
+			addRuleToSpec(extSpec, "ALLOW", "remote_ip", "1.2.3.0/24")
+
+			Expect(collectWarnings(extSpec, config.Config{MaxAllowedCIDRs: 5})).To(BeEmpty())
+		})
+
+		It("should warn about an oversized rule set", func() {
+			extSpec := &extensionspec.ExtensionSpec{}
+			addRuleToSpec(extSpec, "ALLOW", "remote_ip", "1.2.3.0/24")
+			extSpec.Rule.Cidrs = append(extSpec.Rule.Cidrs, "5.6.7.0/24")
+
+			Expect(collectWarnings(extSpec, config.Config{MaxAllowedCIDRs: 1})).To(ConsistOf(ContainSubstring("oversized")))
+		})
+
+		It("should warn about CIDRs contained in another CIDR", func() {
+			extSpec := &extensionspec.ExtensionSpec{}
+			addRuleToSpec(extSpec, "ALLOW", "remote_ip", "10.0.0.0/8")
+			extSpec.Rule.Cidrs = append(extSpec.Rule.Cidrs, "10.1.0.0/16")
+
+			Expect(collectWarnings(extSpec, config.Config{})).To(ConsistOf("CIDR 10.1.0.0/16 is contained in 10.0.0.0/8 and has no effect"))
+		})
+
+		It("should warn about CIDRs with host bits set", func() {
+			extSpec := &extensionspec.ExtensionSpec{}
+			addRuleToSpec(extSpec, "ALLOW", "remote_ip", "1.2.3.4/24")
+
+			Expect(collectWarnings(extSpec, config.Config{})).To(ConsistOf("CIDR 1.2.3.4/24 has host bits set and is enforced as 1.2.3.0/24"))
+		})
+
+		It("should not warn about host bits once the CIDRs are normalized", func() {
+			extSpec := &extensionspec.ExtensionSpec{NormalizeCIDRs: true}
+			addRuleToSpec(extSpec, "ALLOW", "remote_ip", "1.2.3.4/24")
+			extSpec.Normalize()
+
+			Expect(extSpec.Rule.Cidrs).To(Equal([]string{"1.2.3.0/24"}))
+			Expect(collectWarnings(extSpec, config.Config{})).To(BeEmpty())
+		})
+
+		It("should not warn about CIDRs within an except block of the containing CIDR", func() {
+			extSpec := &extensionspec.ExtensionSpec{}
+			addRuleToSpec(extSpec, "ALLOW", "remote_ip", "10.0.0.0/8")
+			extSpec.Rule.Cidrs = append(extSpec.Rule.Cidrs, "10.1.2.0/24")
+			extSpec.Rule.Except = []string{"10.1.0.0/16"}
+
+			Expect(collectWarnings(extSpec, config.Config{})).To(BeEmpty())
+		})
+
+		It("should warn about an allow rule matching everything", func() {
+			extSpec := &extensionspec.ExtensionSpec{}
+			addRuleToSpec(extSpec, "ALLOW", "remote_ip", "0.0.0.0/0")
+
+			Expect(collectWarnings(extSpec, config.Config{})).To(ConsistOf(ContainSubstring("not effective")))
+		})
+	})
+
 	Describe("ValidateExtensionSpec", func() {
 		When("there is an extension resource with one valid rule", func() {
 			It("Should not return an error", func() {
 				extSpec := &extensionspec.ExtensionSpec{}
-				addRuleToSpec(extSpec, "DENY", "source_ip", "0.0.0.0/0")
+				addRuleToSpec(extSpec, "DENY", "direct_remote_ip", "0.0.0.0/0")
 
 				Expect(ValidateExtensionSpec(extSpec)).To(Succeed())
 			})
@@ -610,6 +2287,17 @@ var _ = Describe("actuator unit test", func() {
 			})
 		})
 
+		When("there is an extension resource with a rule type not supported by the targets of the profile", func() {
+			It("Should return an actionable error", func() {
+				extSpec := &extensionspec.ExtensionSpec{Profile: extensionspec.ProfileAPIServerOnly}
+				addRuleToSpec(extSpec, "ALLOW", "source_ip", "10.0.0.0/8")
+
+				err := ValidateExtensionSpec(extSpec)
+				Expect(err).To(MatchError(ErrSpecTypeTarget))
+				Expect(err).To(MatchError(ContainSubstring(`"source_ip" can't be used for the apiserver target, it is deprecated by envoy, use 'remote_ip' for the same behavior`)))
+			})
+		})
+
 		When("there is an extension resource with a rule with invalid rule action", func() {
 			It("Should return the correct error", func() {
 				extSpec := &extensionspec.ExtensionSpec{}
@@ -645,6 +2333,36 @@ var _ = Describe("actuator unit test", func() {
 			})
 		})
 
+		When("there is an extension resource with a rule with duplicate CIDRs", func() {
+			It("Should return the correct error", func() {
+				extSpec := &extensionspec.ExtensionSpec{}
+				addRuleToSpec(extSpec, "DENY", "remote_ip", "10.0.0.0/8")
+				extSpec.Rule.Cidrs = append(extSpec.Rule.Cidrs, "10.1.2.3/8")
+
+				Expect(ValidateExtensionSpec(extSpec)).To(MatchError(ErrSpecDuplicateCIDR))
+			})
+		})
+
+		When("there is an extension resource with a rule with except CIDRs inside of its CIDRs", func() {
+			It("Should not return an error", func() {
+				extSpec := &extensionspec.ExtensionSpec{}
+				addRuleToSpec(extSpec, "ALLOW", "remote_ip", "10.0.0.0/16")
+				extSpec.Rule.Except = []string{"10.0.5.0/24"}
+
+				Expect(ValidateExtensionSpec(extSpec)).To(Succeed())
+			})
+		})
+
+		When("there is an extension resource with a rule with except CIDRs outside of its CIDRs", func() {
+			It("Should return the correct error", func() {
+				extSpec := &extensionspec.ExtensionSpec{}
+				addRuleToSpec(extSpec, "ALLOW", "remote_ip", "10.0.0.0/16")
+				extSpec.Rule.Except = []string{"10.1.5.0/24"}
+
+				Expect(ValidateExtensionSpec(extSpec)).To(Equal(ErrSpecExcept))
+			})
+		})
+
 		When("there is an extension resource with a rule with invalid CIDR", func() {
 			It("Should return the correct error", func() {
 				extSpec := &extensionspec.ExtensionSpec{}
@@ -655,19 +2373,96 @@ var _ = Describe("actuator unit test", func() {
 				Expect(ValidateExtensionSpec(extSpec)).ToNot(Succeed())
 			})
 		})
+
+		When("there is an extension resource with a RATE_LIMIT internal rule", func() {
+			It("Should return the correct error", func() {
+				extSpec := &extensionspec.ExtensionSpec{}
+				addRuleToSpec(extSpec, "ALLOW", "remote_ip", "10.0.0.0/16")
+				extSpec.InternalRule = &envoyfilters.ACLRule{
+					Action:    envoyfilters.ActionRateLimit,
+					Type:      "remote_ip",
+					Cidrs:     []string{"10.1.0.0/16"},
+					RateLimit: &envoyfilters.RateLimit{ConnectionsPerSecond: 10},
+				}
+
+				Expect(ValidateExtensionSpec(extSpec)).To(Equal(ErrSpecInternalRuleRateLimit))
+			})
+		})
+
+		When("there is an extension resource with a LOG internal rule", func() {
+			It("Should return the correct error", func() {
+				extSpec := &extensionspec.ExtensionSpec{}
+				addRuleToSpec(extSpec, "ALLOW", "remote_ip", "10.0.0.0/16")
+				extSpec.InternalRule = &envoyfilters.ACLRule{Action: envoyfilters.ActionLog, Type: "remote_ip", Cidrs: []string{"10.1.0.0/16"}}
+
+				Expect(ValidateExtensionSpec(extSpec)).To(Equal(ErrSpecInternalRuleLog))
+			})
+		})
+
+		When("there is an extension resource with an invalid internal rule", func() {
+			It("Should return the error of the internal rule", func() {
+				extSpec := &extensionspec.ExtensionSpec{}
+				addRuleToSpec(extSpec, "ALLOW", "remote_ip", "10.0.0.0/16")
+				extSpec.InternalRule = &envoyfilters.ACLRule{Action: "ALLOW", Type: "remote_ip"}
+
+				err := ValidateExtensionSpec(extSpec)
+				Expect(err).To(MatchError(ErrSpecCIDR))
+				Expect(err).To(MatchError(ContainSubstring("internalRule: ")))
+			})
+		})
+
+		It("Should validate all generated providerConfig fixtures correctly", func() {
+			for _, f := range providerConfigFixtures() {
+				err := ValidateExtensionSpec(f.spec)
+				if f.valid {
+					Expect(err).NotTo(HaveOccurred(), f.name)
+				} else {
+					Expect(err).To(HaveOccurred(), f.name)
+				}
+			}
+		})
 	})
 })
 
 func getNewActuator() *actuator {
 	return &actuator{
-		client: k8sClient,
-		config: cfg,
+		client:   k8sClient,
+		config:   cfg,
+		recorder: record.NewFakeRecorder(100),
 		extensionConfig: config.Config{
-			ChartPath: "../../charts",
+			ChartPath:                          "../../charts",
+			AutoAllowInfrastructureEgressCIDRs: true,
 		},
 	}
 }
 
+// shootMetric returns the values of the series of the given metric for a
+// shoot, using the sample count for histograms.
+func shootMetric(name, shoot string) []float64 {
+	families, err := metrics.Registry.Gather()
+	Expect(err).NotTo(HaveOccurred())
+
+	var values []float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() != "shoot" || label.GetValue() != shoot {
+					continue
+				}
+				if metric.GetHistogram() != nil {
+					values = append(values, float64(metric.GetHistogram().GetSampleCount()))
+				} else {
+					values = append(values, metric.GetGauge().GetValue())
+				}
+			}
+		}
+	}
+	return values
+}
+
 func addRuleToSpec(extSpec *extensionspec.ExtensionSpec, action, ruleType, cidr string) {
 	extSpec.Rule = &envoyfilters.ACLRule{
 		Cidrs: []string{
@@ -59,7 +59,7 @@ func AddToManagerWithOptions(ctx context.Context, mgr manager.Manager, opts *Add
 		ControllerOptions: opts.ControllerOptions,
 		Name:              Type + suffix,
 		FinalizerSuffix:   Type + suffix,
-		Resync:            0,
+		Resync:            opts.ExtensionConfig.SyncPeriod,
 		Predicates:        extension.DefaultPredicates(ctx, mgr, DefaultAddOptions.IgnoreOperationAnnotation),
 		Type:              Type,
 	})
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"time"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1helper "github.com/gardener/gardener/pkg/apis/core/v1beta1/helper"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	resourcesv1alpha1 "github.com/gardener/gardener/pkg/apis/resources/v1alpha1"
+	reconcilerutils "github.com/gardener/gardener/pkg/controllerutils/reconciler"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// AggregatedPatchesConfigMapName is the name of the ConfigMap in the shoot
+	// namespace containing the AggregatedPatches of the shoot.
+	AggregatedPatchesConfigMapName = "acl-aggregated-patches"
+	// AggregatedPatchesDataKey is the key of the AggregatedPatches in the
+	// ConfigMap.
+	AggregatedPatchesDataKey = "patches"
+	// AggregatedLabel marks the ConfigMaps containing AggregatedPatches and
+	// the aggregated EnvoyFilters in the istio namespaces.
+	AggregatedLabel = "acl.stackit.cloud/aggregated"
+	// ChecksumAnnotation is the checksum of the AggregatedPatches in the
+	// ConfigMap.
+	ChecksumAnnotation = "acl.stackit.cloud/checksum"
+	// AggregatedChecksumAnnotation is set by the aggregation controller to the
+	// checksum of the AggregatedPatches of the ConfigMap which were merged
+	// into the aggregated EnvoyFilters.
+	AggregatedChecksumAnnotation = "acl.stackit.cloud/aggregated-checksum"
+
+	aggregationRetryPeriod = 10 * time.Second
+)
+
+// ErrLeavingAggregation is returned while the dedicated EnvoyFilters of a
+// shoot aren't applied yet after the aggregation was disabled.
+var ErrLeavingAggregation = errors.New("waiting for the dedicated EnvoyFilters of the shoot to be applied before removing it from the aggregated EnvoyFilters")
+
+// AggregatedPatches are the EnvoyFilter patches of the API server and VPN
+// access of a shoot, which the aggregation controller merges into the
+// aggregated EnvoyFilters of its istio namespaces.
+type AggregatedPatches struct {
+	// IstioNamespaces are the namespaces of the istio ingress gateways
+	// serving the shoot.
+	IstioNamespaces []string `json:"istioNamespaces"`
+	// IstioLabels are the labels of the istio ingress gateways serving the
+	// shoot.
+	IstioLabels map[string]string `json:"istioLabels"`
+	// ConfigPatches are the patches of the shoot, in the order of the
+	// dedicated EnvoyFilters.
+	ConfigPatches []interface{} `json:"configPatches"`
+}
+
+// servesSameGateways returns true if both patches target the same istio
+// ingress gateways.
+func (p *AggregatedPatches) servesSameGateways(other *AggregatedPatches) bool {
+	return slices.Equal(p.IstioNamespaces, other.IstioNamespaces) && maps.Equal(p.IstioLabels, other.IstioLabels)
+}
+
+// aggregatePatches writes the API server and VPN patches of the given seed
+// chart values to the AggregatedPatches ConfigMap of the shoot. It returns true
+// if the dedicated EnvoyFilters of the shoot can be omitted, because the
+// aggregated EnvoyFilters contain the current patches, or they contained the
+// patches of the shoot already before and its ingress gateways didn't change,
+// so the aggregation controller only has to apply the changed rule.
+func (a *actuator) aggregatePatches(
+	ctx context.Context,
+	ex *extensionsv1alpha1.Extension,
+	values map[string]interface{},
+	istioNamespaces []string,
+	istioLabels map[string]string,
+	wasAggregated bool,
+) (bool, error) {
+	patches := &AggregatedPatches{IstioNamespaces: istioNamespaces, IstioLabels: istioLabels, ConfigPatches: []interface{}{}}
+	for _, key := range []string{"apiEnvoyFilterSpec", "vpnEnvoyFilterSpec"} {
+		spec, _ := values[key].(map[string]interface{})
+		configPatches, _ := spec["configPatches"].([]map[string]interface{})
+		for _, patch := range configPatches {
+			patches.ConfigPatches = append(patches.ConfigPatches, patch)
+		}
+	}
+	data, err := json.Marshal(patches)
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	sameGateways := false
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: AggregatedPatchesConfigMapName, Namespace: ex.GetNamespace()}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, a.client, configMap, func() error {
+		previous := &AggregatedPatches{}
+		if err := json.Unmarshal([]byte(configMap.Data[AggregatedPatchesDataKey]), previous); err == nil {
+			sameGateways = previous.servesSameGateways(patches)
+		}
+
+		metav1.SetMetaDataLabel(&configMap.ObjectMeta, AggregatedLabel, "true")
+		metav1.SetMetaDataAnnotation(&configMap.ObjectMeta, ChecksumAnnotation, checksum)
+		configMap.Data = map[string]string{AggregatedPatchesDataKey: string(data)}
+		return controllerutil.SetControllerReference(ex, configMap, a.client.Scheme())
+	}); err != nil {
+		return false, err
+	}
+
+	return configMap.Annotations[AggregatedChecksumAnnotation] == checksum || (wasAggregated && sameGateways), nil
+}
+
+// leaveAggregation removes the shoot from the aggregated EnvoyFilters once its
+// dedicated EnvoyFilters are applied, so the shoot is never left without ACL
+// while the aggregation is disabled.
+func (a *actuator) leaveAggregation(ctx context.Context, ex *extensionsv1alpha1.Extension, wasAggregated bool) error {
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: AggregatedPatchesConfigMapName, Namespace: ex.GetNamespace()}}
+	if err := a.client.Get(ctx, client.ObjectKeyFromObject(configMap), configMap); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if wasAggregated {
+		mr := &resourcesv1alpha1.ManagedResource{}
+		if err := a.client.Get(ctx, client.ObjectKey{Namespace: ex.GetNamespace(), Name: ResourceNameSeed}, mr); err != nil {
+			return err
+		}
+		condition := v1beta1helper.GetCondition(mr.Status.Conditions, resourcesv1alpha1.ResourcesApplied)
+		if mr.Status.ObservedGeneration != mr.Generation || condition == nil || condition.Status != gardencorev1beta1.ConditionTrue {
+			return &reconcilerutils.RequeueAfterError{Cause: ErrLeavingAggregation, RequeueAfter: aggregationRetryPeriod}
+		}
+	}
+
+	return client.IgnoreNotFound(a.client.Delete(ctx, configMap))
+}
@@ -0,0 +1,81 @@
+package aggregation
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+// ControllerName is the name of the EnvoyFilter aggregation controller.
+const ControllerName = "acl-aggregation"
+
+var (
+	// DefaultAddOptions are the default AddOptions for AddToManager.
+	DefaultAddOptions = AddOptions{ManagedResource: true}
+)
+
+// AddOptions are options to apply when adding the EnvoyFilter aggregation
+// controller to the manager.
+type AddOptions struct {
+	// ControllerOptions contains options for the controller.
+	ControllerOptions controller.Options
+	// ManagedResource specifies whether the aggregated EnvoyFilters are
+	// deployed via the ManagedResourceName ManagedResource instead of being
+	// created directly.
+	ManagedResource bool
+}
+
+// AddToManager adds a controller with the default Options to the given Controller Manager.
+//
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=networking.istio.io,resources=envoyfilters,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=extensions,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=resources.gardener.cloud,resources=managedresources,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+func AddToManager(ctx context.Context, mgr manager.Manager) error {
+	return AddToManagerWithOptions(ctx, mgr, &DefaultAddOptions)
+}
+
+// AddToManagerWithOptions adds a controller with the given Options to the given
+// manager. The controller runs regardless of the EnvoyFilter mode, so the
+// aggregated EnvoyFilters are cleaned up after the aggregation was disabled.
+// Nothing is added if istio isn't installed, the shoots keep their dedicated
+// EnvoyFilters until the extension is restarted then.
+func AddToManagerWithOptions(_ context.Context, mgr manager.Manager, opts *AddOptions) error {
+	istioInstalled, err := helper.IsIstioInstalled(mgr.GetRESTMapper())
+	if err != nil || !istioInstalled {
+		return err
+	}
+	envoyFilterWriter, err := envoyfilters.DiscoverWriter(mgr.GetRESTMapper())
+	if err != nil {
+		return err
+	}
+
+	// every change leads to the same request, as the aggregated EnvoyFilters
+	// are always rendered from all shoots
+	enqueue := handler.EnqueueRequestsFromMapFunc(func(context.Context, client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: ControllerName}}}
+	})
+	isAggregated := builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetLabels()[aclcontroller.AggregatedLabel] == "true"
+	}))
+
+	return builder.ControllerManagedBy(mgr).
+		Named(ControllerName).
+		WithOptions(opts.ControllerOptions).
+		Watches(&corev1.ConfigMap{}, enqueue, isAggregated).
+		Watches(envoyFilterWriter.New("", ""), enqueue, isAggregated).
+		Complete(&reconciler{client: mgr.GetClient(), managedResource: opts.ManagedResource})
+}
@@ -0,0 +1,29 @@
+package aggregation
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	aggregatedShoots = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "acl",
+			Subsystem: "aggregation",
+			Name:      "shoots",
+			Help:      "Number of shoots whose EnvoyFilter patches are part of the aggregated EnvoyFilters.",
+		},
+	)
+	aggregatedEnvoyFilters = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "acl",
+			Subsystem: "aggregation",
+			Name:      "envoyfilters",
+			Help:      "Number of aggregated EnvoyFilters in all istio namespaces.",
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(aggregatedShoots, aggregatedEnvoyFilters)
+}
@@ -0,0 +1,313 @@
+// Package aggregation contains a controller that merges the EnvoyFilter
+// patches of the API server and VPN access of all shoots into a few aggregated
+// EnvoyFilters per istio ingress gateway, instead of dedicated EnvoyFilters per
+// shoot, to reduce the config size and push latency of istiod on seeds with
+// many shoots.
+package aggregation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"time"
+
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	resourcesv1alpha1 "github.com/gardener/gardener/pkg/apis/resources/v1alpha1"
+	"github.com/gardener/gardener/pkg/utils/kubernetes/health"
+	"github.com/gardener/gardener/pkg/utils/managedresources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+)
+
+const (
+	// EnvoyFilterNamePrefix is the prefix of the names of the aggregated
+	// EnvoyFilters.
+	EnvoyFilterNamePrefix = "acl-aggregated-"
+	// buckets is the number of aggregated EnvoyFilters the shoots of an istio
+	// ingress gateway are spread across, so a change of a shoot only updates
+	// a fraction of the patches, and a single object doesn't grow too large.
+	buckets = 8
+	// ManagedResourceName is the name of the ManagedResource in the garden
+	// namespace deploying the aggregated EnvoyFilters.
+	ManagedResourceName = "extension-acl-aggregated-envoyfilters"
+
+	managedResourceRetryPeriod = 10 * time.Second
+)
+
+type reconciler struct {
+	client client.Client
+	// managedResource specifies whether the aggregated EnvoyFilters are
+	// deployed via a ManagedResource instead of being created directly.
+	managedResource bool
+}
+
+// shootPatches are the patches of a shoot in an aggregated EnvoyFilter.
+type shootPatches struct {
+	namespace string
+	patches   []interface{}
+}
+
+// aggregatedEnvoyFilter is the desired state of an aggregated EnvoyFilter.
+type aggregatedEnvoyFilter struct {
+	istioLabels map[string]string
+	shoots      []shootPatches
+}
+
+// Reconcile renders the AggregatedPatches of all shoots into the aggregated
+// EnvoyFilters of their istio namespaces, deploys them via a ManagedResource
+// (or directly) and deletes the aggregated EnvoyFilters which aren't needed
+// anymore. Afterwards, the checksum of the merged patches is recorded in the
+// ConfigMap of every shoot, and shoots still having their dedicated
+// EnvoyFilters are reconciled, so the actuator can remove them.
+func (r *reconciler) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	log := logf.FromContext(ctx)
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := r.client.List(ctx, configMaps, client.MatchingLabels{aclcontroller.AggregatedLabel: "true"}); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	desired := map[client.ObjectKey]*aggregatedEnvoyFilter{}
+	var aggregated []*corev1.ConfigMap
+	for i := range configMaps.Items {
+		configMap := &configMaps.Items[i]
+		if !configMap.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		patches := &aclcontroller.AggregatedPatches{}
+		if err := json.Unmarshal([]byte(configMap.Data[aclcontroller.AggregatedPatchesDataKey]), patches); err != nil {
+			// the shoot keeps its dedicated EnvoyFilters, as its ConfigMap
+			// isn't acknowledged
+			log.Error(err, "Ignoring invalid aggregated patches", "namespace", configMap.Namespace)
+			continue
+		}
+
+		for _, istioNamespace := range patches.IstioNamespaces {
+			key := client.ObjectKey{Namespace: istioNamespace, Name: envoyFilterName(patches.IstioLabels, configMap.Namespace)}
+			if desired[key] == nil {
+				desired[key] = &aggregatedEnvoyFilter{istioLabels: patches.IstioLabels}
+			}
+			desired[key].shoots = append(desired[key].shoots, shootPatches{namespace: configMap.Namespace, patches: patches.ConfigPatches})
+		}
+		aggregated = append(aggregated, configMap)
+	}
+
+	envoyFilterWriter, err := envoyfilters.DiscoverWriter(r.client.RESTMapper())
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	var envoyFilterObjects []*unstructured.Unstructured
+	for key, envoyFilter := range desired {
+		obj, err := newEnvoyFilter(envoyFilterWriter, key, envoyFilter)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("could not render aggregated EnvoyFilter %s: %w", key, err)
+		}
+		envoyFilterObjects = append(envoyFilterObjects, obj)
+	}
+
+	if r.managedResource {
+		applied, err := r.deployManagedResource(ctx, envoyFilterObjects)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("could not deploy the ManagedResource of the aggregated EnvoyFilters: %w", err)
+		}
+		if !applied {
+			// the dedicated EnvoyFilters of the shoots are only removed once
+			// the aggregated ones are applied
+			log.Info("Waiting for the ManagedResource of the aggregated EnvoyFilters to be applied")
+			return reconcile.Result{RequeueAfter: managedResourceRetryPeriod}, nil
+		}
+	} else {
+		if err := r.releaseManagedResource(ctx); err != nil {
+			return reconcile.Result{}, err
+		}
+		for _, obj := range envoyFilterObjects {
+			if err := r.applyEnvoyFilter(ctx, envoyFilterWriter, obj); err != nil {
+				return reconcile.Result{}, fmt.Errorf("could not apply aggregated EnvoyFilter %s: %w", client.ObjectKeyFromObject(obj), err)
+			}
+		}
+	}
+
+	envoyFilters := envoyFilterWriter.NewList()
+	if err := r.client.List(ctx, envoyFilters, client.MatchingLabels{aclcontroller.AggregatedLabel: "true"}); err != nil {
+		return reconcile.Result{}, err
+	}
+	for i := range envoyFilters.Items {
+		envoyFilter := &envoyFilters.Items[i]
+		if _, ok := desired[client.ObjectKeyFromObject(envoyFilter)]; ok {
+			continue
+		}
+		log.Info("Deleting aggregated EnvoyFilter without shoots", "namespace", envoyFilter.GetNamespace(), "name", envoyFilter.GetName())
+		if err := r.client.Delete(ctx, envoyFilter); client.IgnoreNotFound(err) != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	for _, configMap := range aggregated {
+		if err := r.acknowledge(ctx, configMap); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	aggregatedShoots.Set(float64(len(aggregated)))
+	aggregatedEnvoyFilters.Set(float64(len(desired)))
+	log.Info("Aggregated EnvoyFilters", "shoots", len(aggregated), "envoyFilters", len(desired))
+
+	return reconcile.Result{}, nil
+}
+
+// newEnvoyFilter returns the aggregated EnvoyFilter with the patches of its
+// shoots, ordered by their namespace so the spec only changes if the patches
+// of a shoot change.
+func newEnvoyFilter(envoyFilterWriter envoyfilters.Writer, key client.ObjectKey, desired *aggregatedEnvoyFilter) (*unstructured.Unstructured, error) {
+	slices.SortFunc(desired.shoots, func(a, b shootPatches) int {
+		return strings.Compare(a.namespace, b.namespace)
+	})
+	configPatches := []interface{}{}
+	for _, shoot := range desired.shoots {
+		configPatches = append(configPatches, shoot.patches...)
+	}
+
+	// the spec is converted to the types of unstructured objects, so it can be
+	// compared with the existing spec
+	data, err := json.Marshal(map[string]interface{}{
+		"workloadSelector": map[string]interface{}{"labels": desired.istioLabels},
+		"configPatches":    configPatches,
+	})
+	if err != nil {
+		return nil, err
+	}
+	spec := map[string]interface{}{}
+	if err := utiljson.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+
+	envoyFilter := envoyFilterWriter.New(key.Namespace, key.Name)
+	envoyFilter.SetLabels(map[string]string{aclcontroller.AggregatedLabel: "true"})
+	envoyFilter.Object["spec"] = spec
+	return envoyFilter, nil
+}
+
+// applyEnvoyFilter creates or updates the given aggregated EnvoyFilter.
+func (r *reconciler) applyEnvoyFilter(ctx context.Context, envoyFilterWriter envoyfilters.Writer, desired *unstructured.Unstructured) error {
+	envoyFilter := envoyFilterWriter.New(desired.GetNamespace(), desired.GetName())
+	_, err := controllerutil.CreateOrUpdate(ctx, r.client, envoyFilter, func() error {
+		envoyFilterLabels := envoyFilter.GetLabels()
+		if envoyFilterLabels == nil {
+			envoyFilterLabels = map[string]string{}
+		}
+		envoyFilterLabels[aclcontroller.AggregatedLabel] = "true"
+		envoyFilter.SetLabels(envoyFilterLabels)
+		envoyFilter.Object["spec"] = desired.Object["spec"]
+		return nil
+	})
+	return err
+}
+
+// deployManagedResource deploys the given aggregated EnvoyFilters via the
+// ManagedResourceName ManagedResource, which is deleted together with its
+// objects if there are none. It returns true once the gardener-resource-manager
+// has applied the current objects. EnvoyFilters created directly before are
+// adopted by the gardener-resource-manager.
+func (r *reconciler) deployManagedResource(ctx context.Context, envoyFilters []*unstructured.Unstructured) (bool, error) {
+	if len(envoyFilters) == 0 {
+		return true, managedresources.DeleteForSeed(ctx, r.client, v1beta1constants.GardenNamespace, ManagedResourceName)
+	}
+
+	data := map[string][]byte{}
+	for _, envoyFilter := range envoyFilters {
+		manifest, err := yaml.Marshal(envoyFilter.Object)
+		if err != nil {
+			return false, err
+		}
+		data[fmt.Sprintf("envoyfilter__%s__%s.yaml", envoyFilter.GetNamespace(), envoyFilter.GetName())] = manifest
+	}
+	if err := managedresources.CreateForSeed(ctx, r.client, v1beta1constants.GardenNamespace, ManagedResourceName, false, data); err != nil {
+		return false, err
+	}
+
+	mr := &resourcesv1alpha1.ManagedResource{}
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: v1beta1constants.GardenNamespace, Name: ManagedResourceName}, mr); err != nil {
+		return false, err
+	}
+	return health.CheckManagedResourceApplied(mr) == nil, nil
+}
+
+// releaseManagedResource deletes the ManagedResourceName ManagedResource of
+// earlier runs without its objects, so the aggregated EnvoyFilters are taken
+// over by the reconciler instead of being deleted after switching to
+// creating them directly.
+func (r *reconciler) releaseManagedResource(ctx context.Context) error {
+	mr := &resourcesv1alpha1.ManagedResource{}
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: v1beta1constants.GardenNamespace, Name: ManagedResourceName}, mr); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if err := managedresources.SetKeepObjects(ctx, r.client, v1beta1constants.GardenNamespace, ManagedResourceName, true); err != nil {
+		return err
+	}
+	return managedresources.DeleteForSeed(ctx, r.client, v1beta1constants.GardenNamespace, ManagedResourceName)
+}
+
+// acknowledge records the checksum of the merged patches in the ConfigMap of
+// the shoot, and triggers the reconciliation of the shoot's extension if it
+// still has its dedicated EnvoyFilters.
+func (r *reconciler) acknowledge(ctx context.Context, configMap *corev1.ConfigMap) error {
+	checksum := configMap.Annotations[aclcontroller.ChecksumAnnotation]
+	if configMap.Annotations[aclcontroller.AggregatedChecksumAnnotation] != checksum {
+		patch := client.MergeFrom(configMap.DeepCopy())
+		metav1.SetMetaDataAnnotation(&configMap.ObjectMeta, aclcontroller.AggregatedChecksumAnnotation, checksum)
+		if err := r.client.Patch(ctx, configMap, patch); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+	}
+
+	owner := metav1.GetControllerOf(configMap)
+	if owner == nil || owner.Kind != extensionsv1alpha1.ExtensionResource {
+		return nil
+	}
+	ex := &extensionsv1alpha1.Extension{}
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: configMap.Namespace, Name: owner.Name}, ex); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !ex.DeletionTimestamp.IsZero() || ex.Annotations[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile {
+		return nil
+	}
+	extState, err := aclcontroller.GetExtensionState(ex)
+	if err != nil || extState.Aggregated {
+		return err
+	}
+
+	logf.FromContext(ctx).Info("Triggering reconciliation to remove the dedicated EnvoyFilters", "namespace", ex.Namespace)
+	patch := client.MergeFrom(ex.DeepCopy())
+	metav1.SetMetaDataAnnotation(&ex.ObjectMeta, v1beta1constants.GardenerOperation, v1beta1constants.GardenerOperationReconcile)
+	return client.IgnoreNotFound(r.client.Patch(ctx, ex, patch))
+}
+
+// envoyFilterName returns the name of the aggregated EnvoyFilter containing
+// the patches of the shoot. The shoots of an istio ingress gateway are spread
+// across the buckets by their namespace, the checksum of the istio labels
+// separates the shoots of different gateways in the same istio namespace.
+func envoyFilterName(istioLabels map[string]string, shootNamespace string) string {
+	labelsChecksum := sha256.Sum256([]byte(labels.Set(istioLabels).String()))
+	bucket := fnv.New32a()
+	_, _ = bucket.Write([]byte(shootNamespace))
+	return fmt.Sprintf("%s%s-%d", EnvoyFilterNamePrefix, hex.EncodeToString(labelsChecksum[:])[:8], bucket.Sum32()%buckets)
+}
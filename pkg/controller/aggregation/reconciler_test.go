@@ -0,0 +1,250 @@
+package aggregation
+
+import (
+	"context"
+	"encoding/json"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	resourcesv1alpha1 "github.com/gardener/gardener/pkg/apis/resources/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	istionetworkv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+)
+
+var _ = Describe("reconciler", func() {
+	var (
+		ctx         = context.TODO()
+		c           client.Client
+		r           *reconciler
+		istioLabels = map[string]string{"app": "istio-ingressgateway", "istio": "ingressgateway"}
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+		Expect(istionetworkv1alpha3.AddToScheme(scheme)).To(Succeed())
+		Expect(resourcesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		c = fakeclient.NewClientBuilder().WithScheme(scheme).Build()
+		r = &reconciler{client: c}
+	})
+
+	// createShoot creates an ACL extension with the given aggregation state and
+	// its ConfigMap with a single patch for the given CIDR
+	createShoot := func(namespace, cidr string, aggregated bool, istioNamespaces ...string) {
+		extState, err := json.Marshal(&aclcontroller.ExtensionState{Aggregated: aggregated})
+		Expect(err).NotTo(HaveOccurred())
+		ex := &extensionsv1alpha1.Extension{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "acl", UID: types.UID("uid-" + namespace)},
+			Spec: extensionsv1alpha1.ExtensionSpec{
+				DefaultSpec: extensionsv1alpha1.DefaultSpec{Type: aclcontroller.Type},
+			},
+			Status: extensionsv1alpha1.ExtensionStatus{
+				DefaultStatus: extensionsv1alpha1.DefaultStatus{State: &runtime.RawExtension{Raw: extState}},
+			},
+		}
+		Expect(c.Create(ctx, ex)).To(Succeed())
+
+		patches, err := json.Marshal(&aclcontroller.AggregatedPatches{
+			IstioNamespaces: istioNamespaces,
+			IstioLabels:     istioLabels,
+			ConfigPatches:   []interface{}{map[string]interface{}{"applyTo": "NETWORK_FILTER", "cidr": cidr}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        aclcontroller.AggregatedPatchesConfigMapName,
+				Labels:      map[string]string{aclcontroller.AggregatedLabel: "true"},
+				Annotations: map[string]string{aclcontroller.ChecksumAnnotation: "checksum-" + cidr},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: extensionsv1alpha1.SchemeGroupVersion.String(),
+					Kind:       extensionsv1alpha1.ExtensionResource,
+					Name:       "acl",
+					UID:        ex.UID,
+					Controller: ptr.To(true),
+				}},
+			},
+			Data: map[string]string{aclcontroller.AggregatedPatchesDataKey: string(patches)},
+		})).To(Succeed())
+	}
+
+	getConfigMap := func(namespace string) *corev1.ConfigMap {
+		configMap := &corev1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: aclcontroller.AggregatedPatchesConfigMapName}, configMap)).To(Succeed())
+		return configMap
+	}
+
+	isTriggered := func(namespace string) bool {
+		ex := &extensionsv1alpha1.Extension{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "acl"}, ex)).To(Succeed())
+		return ex.Annotations[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile
+	}
+
+	listEnvoyFilters := func() []*istionetworkv1alpha3.EnvoyFilter {
+		envoyFilters := &istionetworkv1alpha3.EnvoyFilterList{}
+		Expect(c.List(ctx, envoyFilters)).To(Succeed())
+		return envoyFilters.Items
+	}
+
+	It("should merge the patches of all shoots into the aggregated EnvoyFilters of their istio namespaces", func() {
+		createShoot("shoot--foo--a", "10.0.0.1/32", false, "istio-ingress")
+		createShoot("shoot--foo--b", "10.0.0.2/32", false, "istio-ingress", "istio-ingress--zone-a")
+
+		_, err := r.Reconcile(ctx, reconcile.Request{})
+		Expect(err).NotTo(HaveOccurred())
+
+		envoyFilters := listEnvoyFilters()
+		Expect(envoyFilters).NotTo(BeEmpty())
+		specs := map[string]string{}
+		for _, envoyFilter := range envoyFilters {
+			Expect(envoyFilter.Name).To(HavePrefix(EnvoyFilterNamePrefix))
+			Expect(envoyFilter.Labels).To(HaveKeyWithValue(aclcontroller.AggregatedLabel, "true"))
+			spec, err := envoyFilter.Spec.MarshalJSON()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec).To(ContainSubstring(`"istio":"ingressgateway"`))
+			specs[envoyFilter.Namespace] += string(spec)
+		}
+		Expect(specs).To(HaveLen(2))
+		Expect(specs["istio-ingress"]).To(And(ContainSubstring("10.0.0.1/32"), ContainSubstring("10.0.0.2/32")))
+		Expect(specs["istio-ingress--zone-a"]).To(And(Not(ContainSubstring("10.0.0.1/32")), ContainSubstring("10.0.0.2/32")))
+
+		Expect(getConfigMap("shoot--foo--a").Annotations).To(HaveKeyWithValue(aclcontroller.AggregatedChecksumAnnotation, "checksum-10.0.0.1/32"))
+		Expect(getConfigMap("shoot--foo--b").Annotations).To(HaveKeyWithValue(aclcontroller.AggregatedChecksumAnnotation, "checksum-10.0.0.2/32"))
+		Expect(isTriggered("shoot--foo--a")).To(BeTrue())
+		Expect(isTriggered("shoot--foo--b")).To(BeTrue())
+	})
+
+	It("should not trigger the reconciliation of already aggregated shoots", func() {
+		createShoot("shoot--foo--a", "10.0.0.1/32", true, "istio-ingress")
+
+		_, err := r.Reconcile(ctx, reconcile.Request{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(getConfigMap("shoot--foo--a").Annotations).To(HaveKey(aclcontroller.AggregatedChecksumAnnotation))
+		Expect(isTriggered("shoot--foo--a")).To(BeFalse())
+	})
+
+	It("should spread the shoots of a gateway across a fixed number of EnvoyFilters", func() {
+		for _, shoot := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r"} {
+			createShoot("shoot--foo--"+shoot, "10.0.0.1/32", true, "istio-ingress")
+		}
+
+		_, err := r.Reconcile(ctx, reconcile.Request{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(len(listEnvoyFilters())).To(And(BeNumerically(">", 1), BeNumerically("<=", buckets)))
+		Expect(envoyFilterName(istioLabels, "shoot--foo--a")).NotTo(Equal(envoyFilterName(map[string]string{"istio": "other"}, "shoot--foo--a")))
+	})
+
+	It("should delete the aggregated EnvoyFilters without shoots", func() {
+		createShoot("shoot--foo--a", "10.0.0.1/32", true, "istio-ingress")
+		_, err := r.Reconcile(ctx, reconcile.Request{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(listEnvoyFilters()).To(HaveLen(1))
+
+		Expect(c.Delete(ctx, getConfigMap("shoot--foo--a"))).To(Succeed())
+		_, err = r.Reconcile(ctx, reconcile.Request{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(listEnvoyFilters()).To(BeEmpty())
+	})
+
+	It("should not acknowledge invalid patches", func() {
+		createShoot("shoot--foo--a", "10.0.0.1/32", false, "istio-ingress")
+		configMap := getConfigMap("shoot--foo--a")
+		configMap.Data[aclcontroller.AggregatedPatchesDataKey] = "{"
+		Expect(c.Update(ctx, configMap)).To(Succeed())
+
+		_, err := r.Reconcile(ctx, reconcile.Request{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(listEnvoyFilters()).To(BeEmpty())
+		Expect(getConfigMap("shoot--foo--a").Annotations).NotTo(HaveKey(aclcontroller.AggregatedChecksumAnnotation))
+		Expect(isTriggered("shoot--foo--a")).To(BeFalse())
+	})
+
+	Context("deployment via a ManagedResource", func() {
+		BeforeEach(func() {
+			r.managedResource = true
+		})
+
+		getManagedResource := func() (*resourcesv1alpha1.ManagedResource, error) {
+			mr := &resourcesv1alpha1.ManagedResource{}
+			return mr, c.Get(ctx, client.ObjectKey{Namespace: v1beta1constants.GardenNamespace, Name: ManagedResourceName}, mr)
+		}
+
+		It("should only acknowledge the patches once the ManagedResource is applied", func() {
+			createShoot("shoot--foo--a", "10.0.0.1/32", false, "istio-ingress")
+
+			result, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(listEnvoyFilters()).To(BeEmpty())
+			Expect(getConfigMap("shoot--foo--a").Annotations).NotTo(HaveKey(aclcontroller.AggregatedChecksumAnnotation))
+
+			mr, err := getManagedResource()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mr.Spec.Class).To(Equal(ptr.To("seed")))
+			Expect(mr.Spec.SecretRefs).To(HaveLen(1))
+			secret := &corev1.Secret{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: v1beta1constants.GardenNamespace, Name: mr.Spec.SecretRefs[0].Name}, secret)).To(Succeed())
+			Expect(secret.Data).To(HaveLen(1))
+			for _, manifest := range secret.Data {
+				Expect(string(manifest)).To(And(ContainSubstring("kind: EnvoyFilter"), ContainSubstring("10.0.0.1/32")))
+			}
+
+			mr.Status.ObservedGeneration = mr.Generation
+			mr.Status.Conditions = []gardencorev1beta1.Condition{{Type: resourcesv1alpha1.ResourcesApplied, Status: gardencorev1beta1.ConditionTrue}}
+			Expect(c.Update(ctx, mr)).To(Succeed())
+
+			result, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+			Expect(getConfigMap("shoot--foo--a").Annotations).To(HaveKeyWithValue(aclcontroller.AggregatedChecksumAnnotation, "checksum-10.0.0.1/32"))
+			Expect(isTriggered("shoot--foo--a")).To(BeTrue())
+		})
+
+		It("should delete the ManagedResource without shoots", func() {
+			createShoot("shoot--foo--a", "10.0.0.1/32", true, "istio-ingress")
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = getManagedResource()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(c.Delete(ctx, getConfigMap("shoot--foo--a"))).To(Succeed())
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = getManagedResource()
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should release the ManagedResource when creating the EnvoyFilters directly again", func() {
+			createShoot("shoot--foo--a", "10.0.0.1/32", true, "istio-ingress")
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).NotTo(HaveOccurred())
+
+			r.managedResource = false
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = getManagedResource()
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			Expect(listEnvoyFilters()).To(HaveLen(1))
+		})
+	})
+})
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+// Sources of the entries of the allowlist of a shoot.
+const (
+	// SourceRule are the CIDRs of an ALLOW rule in the providerConfig.
+	SourceRule = "rule"
+	// SourceSeed are the networks of the seed.
+	SourceSeed = "seed"
+	// SourceSeedNodes are the addresses of the nodes of the seed.
+	SourceSeedNodes = "seed-nodes"
+	// SourceOperator are the additional allowed CIDRs configured by the
+	// operator.
+	SourceOperator = "operator"
+	// SourceGarden are the egress CIDRs of the garden cluster, e.g. of the
+	// dashboard and the managed bastions.
+	SourceGarden = "garden"
+	// SourceGlobalAllowlist are the CIDRs of the global allowlist ConfigMap.
+	SourceGlobalAllowlist = "global-allowlist"
+	// SourceShoot are the node and pod networks of the shoot.
+	SourceShoot = "shoot"
+	// SourceInfrastructure are the egress CIDRs of the shoot's Infrastructure.
+	SourceInfrastructure = "infrastructure"
+	// SourceBastion are the public IPs of the bastions of the shoot.
+	SourceBastion = "bastion"
+)
+
+// AllowlistEntry is a CIDR allowed for a shoot together with its provenance.
+type AllowlistEntry struct {
+	// CIDR is the allowed CIDR.
+	CIDR string `json:"cidr"`
+	// Source is where the CIDR comes from, see the Source constants.
+	Source string `json:"source"`
+	// Since is the time the CIDR was first allowed from this source.
+	Since metav1.Time `json:"since"`
+}
+
+type allowlistKey struct {
+	cidr   string
+	source string
+}
+
+// allowlistBuilder collects the allowlist entries of a shoot, keeping the
+// time the entries were first seen in previous reconciliations.
+type allowlistBuilder struct {
+	previous map[allowlistKey]metav1.Time
+	seen     map[allowlistKey]bool
+	now      metav1.Time
+	entries  []AllowlistEntry
+}
+
+func newAllowlistBuilder(previous []AllowlistEntry, now time.Time) *allowlistBuilder {
+	b := &allowlistBuilder{
+		previous: map[allowlistKey]metav1.Time{},
+		seen:     map[allowlistKey]bool{},
+		now:      metav1.NewTime(now.UTC().Truncate(time.Second)),
+	}
+	for _, entry := range previous {
+		b.previous[allowlistKey{cidr: entry.CIDR, source: entry.Source}] = entry.Since
+	}
+	return b
+}
+
+func (b *allowlistBuilder) add(source string, cidrs ...string) *allowlistBuilder {
+	for _, cidr := range cidrs {
+		key := allowlistKey{cidr: cidr, source: source}
+		if b.seen[key] {
+			continue
+		}
+		b.seen[key] = true
+
+		since, ok := b.previous[key]
+		if !ok {
+			since = b.now
+		}
+		b.entries = append(b.entries, AllowlistEntry{CIDR: cidr, Source: source, Since: since})
+	}
+	return b
+}
+
+// ruleAllowlist returns the CIDRs of the rule which are part of the allowlist,
+// i.e. the CIDRs of ALLOW and RATE_LIMIT rules.
+func ruleAllowlist(spec *extensionspec.ExtensionSpec) []string {
+	if spec.Rule == nil || !spec.Rule.RestrictsOtherSources() {
+		return nil
+	}
+	return spec.Rule.Cidrs
+}
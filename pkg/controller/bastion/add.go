@@ -0,0 +1,80 @@
+package bastion
+
+import (
+	"context"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+)
+
+// ControllerName is the name of the bastion controller.
+const ControllerName = "acl-bastion"
+
+var (
+	// DefaultAddOptions are the default AddOptions for AddToManager.
+	DefaultAddOptions = AddOptions{}
+)
+
+// AddOptions are options to apply when adding the bastion controller to the
+// manager.
+type AddOptions struct {
+	// ControllerOptions contains options for the controller.
+	ControllerOptions controller.Options
+}
+
+// AddToManager adds a controller with the default Options to the given Controller Manager.
+//
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=bastions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=extensions,verbs=get;list;watch;patch
+func AddToManager(ctx context.Context, mgr manager.Manager) error {
+	return AddToManagerWithOptions(ctx, mgr, &DefaultAddOptions)
+}
+
+// AddToManagerWithOptions adds a controller with the given Options to the
+// given manager.
+func AddToManagerWithOptions(_ context.Context, mgr manager.Manager, opts *AddOptions) error {
+	return builder.ControllerManagedBy(mgr).
+		Named(ControllerName).
+		WithOptions(opts.ControllerOptions).
+		Watches(
+			&extensionsv1alpha1.Bastion{},
+			handler.EnqueueRequestsFromMapFunc(MapBastionToExtension),
+			builder.WithPredicates(BastionIngressChanged()),
+		).
+		Complete(&reconciler{client: mgr.GetClient()})
+}
+
+// BastionIngressChanged filters out updates of Bastions which neither change
+// the public IP of the bastion host nor start its deletion.
+func BastionIngressChanged() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldBastion, ok := e.ObjectOld.(*extensionsv1alpha1.Bastion)
+			if !ok {
+				return false
+			}
+			newBastion, ok := e.ObjectNew.(*extensionsv1alpha1.Bastion)
+			if !ok {
+				return false
+			}
+			return !equality.Semantic.DeepEqual(oldBastion.Status.Ingress, newBastion.Status.Ingress) ||
+				oldBastion.DeletionTimestamp.IsZero() != newBastion.DeletionTimestamp.IsZero()
+		},
+	}
+}
+
+// MapBastionToExtension maps a Bastion to the ACL Extension in the namespace
+// of its shoot.
+func MapBastionToExtension(_ context.Context, obj client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: obj.GetNamespace(), Name: aclcontroller.Type}}}
+}
@@ -0,0 +1,19 @@
+package bastion
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var changedShoots = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: "acl",
+		Subsystem: "bastion",
+		Name:      "changed_shoots_total",
+		Help:      "Number of shoots re-rendered because their bastions were created, changed or deleted.",
+	},
+)
+
+func init() {
+	metrics.Registry.MustRegister(changedShoots)
+}
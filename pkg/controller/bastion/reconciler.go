@@ -0,0 +1,87 @@
+// Package bastion contains a controller that watches the Bastions of the
+// shoots and triggers a reconciliation of their ACL extensions, so that the
+// public IPs of the bastion hosts are allowed while the bastions exist.
+package bastion
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+type reconciler struct {
+	client client.Client
+}
+
+// Reconcile annotates the ACL extension with the reconcile operation
+// annotation if its allowlist doesn't match the public IPs of the current
+// bastions of the shoot. Extensions whose rule doesn't restrict other
+// sources, e.g. DENY rules, and disabled ACLs are left untouched.
+func (r *reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	ex := &extensionsv1alpha1.Extension{}
+	if err := r.client.Get(ctx, req.NamespacedName, ex); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if ex.Spec.Type != aclcontroller.Type || !ex.DeletionTimestamp.IsZero() ||
+		ex.Annotations[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile {
+		return reconcile.Result{}, nil
+	}
+
+	extSpec := &extensionspec.ExtensionSpec{}
+	if ex.Spec.ProviderConfig != nil && ex.Spec.ProviderConfig.Raw != nil {
+		if err := json.Unmarshal(ex.Spec.ProviderConfig.Raw, extSpec); err != nil {
+			extSpec = &extensionspec.ExtensionSpec{}
+		}
+	}
+	if aclcontroller.IsDisabled(ex, extSpec) || (extSpec.Rule != nil && !extSpec.Rule.RestrictsOtherSources()) {
+		return reconcile.Result{}, nil
+	}
+
+	bastionCIDRs, err := helper.GetBastionCIDRs(ctx, r.client, ex.Namespace)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	extState, err := aclcontroller.GetExtensionState(ex)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if slices.Equal(allowedBastionCIDRs(extState), bastionCIDRs) {
+		return reconcile.Result{}, nil
+	}
+
+	logf.FromContext(ctx).Info("Triggering reconciliation because of changed bastions of the shoot", "bastionCIDRs", bastionCIDRs)
+
+	patch := client.MergeFrom(ex.DeepCopy())
+	metav1.SetMetaDataAnnotation(&ex.ObjectMeta, v1beta1constants.GardenerOperation, v1beta1constants.GardenerOperationReconcile)
+	if err := r.client.Patch(ctx, ex, patch); client.IgnoreNotFound(err) != nil {
+		return reconcile.Result{}, err
+	}
+
+	changedShoots.Inc()
+	return reconcile.Result{}, nil
+}
+
+// allowedBastionCIDRs returns the sorted public IPs of the bastions the
+// extension was reconciled with.
+func allowedBastionCIDRs(extState *aclcontroller.ExtensionState) []string {
+	cidrs := sets.New[string]()
+	for _, entry := range extState.Allowlist {
+		if entry.Source == aclcontroller.SourceBastion {
+			cidrs.Insert(entry.CIDR)
+		}
+	}
+	return sets.List(cidrs)
+}
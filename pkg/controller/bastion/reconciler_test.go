@@ -0,0 +1,151 @@
+package bastion
+
+import (
+	"context"
+	"encoding/json"
+
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+var _ = Describe("reconciler", func() {
+	const namespace = "shoot--foo--bar"
+
+	var (
+		ctx = context.TODO()
+		c   client.Client
+		r   *reconciler
+		key = types.NamespacedName{Namespace: namespace, Name: aclcontroller.Type}
+	)
+
+	newBastion := func(name, ip string) *extensionsv1alpha1.Bastion {
+		return &extensionsv1alpha1.Bastion{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Status:     extensionsv1alpha1.BastionStatus{Ingress: &corev1.LoadBalancerIngress{IP: ip}},
+		}
+	}
+
+	// reconciledWith creates an ACL extension with the given rule action,
+	// which was reconciled with the given bastion CIDRs
+	reconciledWith := func(action string, bastionCIDRs ...string) {
+		extSpec := &extensionspec.ExtensionSpec{
+			Rule: &envoyfilters.ACLRule{Action: action, Type: "remote_ip", Cidrs: []string{"1.2.3.4/32"}},
+		}
+		extSpecJSON, err := json.Marshal(extSpec)
+		Expect(err).NotTo(HaveOccurred())
+
+		extState := &aclcontroller.ExtensionState{
+			Allowlist: []aclcontroller.AllowlistEntry{{CIDR: "1.2.3.4/32", Source: aclcontroller.SourceRule}},
+		}
+		for _, cidr := range bastionCIDRs {
+			extState.Allowlist = append(extState.Allowlist, aclcontroller.AllowlistEntry{CIDR: cidr, Source: aclcontroller.SourceBastion})
+		}
+		extStateJSON, err := json.Marshal(extState)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.Create(ctx, &extensionsv1alpha1.Extension{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: aclcontroller.Type},
+			Spec: extensionsv1alpha1.ExtensionSpec{
+				DefaultSpec: extensionsv1alpha1.DefaultSpec{
+					Type:           aclcontroller.Type,
+					ProviderConfig: &runtime.RawExtension{Raw: extSpecJSON},
+				},
+			},
+			Status: extensionsv1alpha1.ExtensionStatus{
+				DefaultStatus: extensionsv1alpha1.DefaultStatus{
+					State: &runtime.RawExtension{Raw: extStateJSON},
+				},
+			},
+		})).To(Succeed())
+	}
+
+	isTriggered := func() bool {
+		ex := &extensionsv1alpha1.Extension{}
+		Expect(c.Get(ctx, key, ex)).To(Succeed())
+		return ex.Annotations[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		c = fakeclient.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(newBastion("cli-a", "203.0.113.1")).
+			Build()
+		r = &reconciler{client: c}
+	})
+
+	It("should not trigger an extension reconciled with the current bastions", func() {
+		reconciledWith("ALLOW", "203.0.113.1/32")
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isTriggered()).To(BeFalse())
+	})
+
+	It("should trigger an extension when a bastion was created", func() {
+		reconciledWith("ALLOW")
+		before := testutil.ToFloat64(changedShoots)
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isTriggered()).To(BeTrue())
+		Expect(testutil.ToFloat64(changedShoots)).To(Equal(before + 1))
+	})
+
+	It("should trigger an extension when a bastion was deleted", func() {
+		reconciledWith("ALLOW", "203.0.113.1/32", "203.0.113.2/32")
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isTriggered()).To(BeTrue())
+	})
+
+	It("should not trigger DENY rules", func() {
+		reconciledWith("DENY")
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isTriggered()).To(BeFalse())
+	})
+
+	It("should ignore shoots without ACL extension", func() {
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("#BastionIngressChanged", func() {
+		It("should only pass updates changing the IP or starting the deletion", func() {
+			p := BastionIngressChanged()
+			deleting := newBastion("cli-a", "203.0.113.1")
+			deleting.DeletionTimestamp = &metav1.Time{}
+
+			Expect(p.Create(event.CreateEvent{Object: newBastion("cli-a", "203.0.113.1")})).To(BeTrue())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: newBastion("cli-a", "203.0.113.1"), ObjectNew: newBastion("cli-a", "203.0.113.1")})).To(BeFalse())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: newBastion("cli-a", ""), ObjectNew: newBastion("cli-a", "203.0.113.1")})).To(BeTrue())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: newBastion("cli-a", "203.0.113.1"), ObjectNew: deleting})).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+const clientIPNotPreservedMessage = "The load balancer of the istio ingress gateway replaces the client IPs, " +
+	"the ACL rule matches the IPs of the load balancer or the seed nodes instead of the clients"
+
+// clientIPPreservation returns the configured ClientIPPreservation or detects
+// it from the Services of the istio ingress gateways in the given namespaces.
+// If any of them uses the PROXY protocol, ClientIPProxied is returned, as
+// "remote_ip" also matches the clients of the other gateways.
+func (a *actuator) clientIPPreservation(
+	ctx context.Context, istioNamespaces []string, istioLabels map[string]string,
+) (helper.ClientIPPreservation, error) {
+	if preservation := a.extensionConfig.ClientIPPreservation; preservation != "" && preservation != helper.ClientIPAuto {
+		return preservation, nil
+	}
+
+	result := helper.ClientIPPreserved
+	for _, istioNamespace := range istioNamespaces {
+		preservation, err := helper.DetectClientIPPreservation(ctx, a.client, istioNamespace, istioLabels)
+		if err != nil {
+			return "", err
+		}
+		switch preservation {
+		case helper.ClientIPProxied:
+			return helper.ClientIPProxied, nil
+		case helper.ClientIPNATed:
+			result = helper.ClientIPNATed
+		}
+	}
+	return result, nil
+}
@@ -15,12 +15,111 @@
 
 package config
 
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+const (
+	// EnforcementBackendEnvoyFilter enforces the ACL via EnvoyFilter patches.
+	EnforcementBackendEnvoyFilter = "envoyfilter"
+	// EnforcementBackendAuthorizationPolicy enforces the ACL via istio
+	// AuthorizationPolicies.
+	EnforcementBackendAuthorizationPolicy = "authorizationpolicy"
+)
+
 // Config contains configuration for the extension service.
 type Config struct {
 	// TODO define options
 	ChartPath string
 	// AdditionalAllowedCIDRs additional allowed cidrs that will be added to the list of allowed cidrs.
 	AdditionalAllowedCIDRs []string
+	// GardenEgressCIDRs are the egress CIDRs of the garden cluster (dashboard,
+	// terminal-controller, managed bastions) which are always allowed.
+	GardenEgressCIDRs []string
 	// MaxAllowedCIDRs is the maximum number of allowed CIDRs per cluster
 	MaxAllowedCIDRs int
+	// MaxCIDRs is the maximum number of CIDRs of the rule of a cluster,
+	// including its except blocks, rules exceeding it are rejected
+	MaxCIDRs int
+	// MaxGatewayEnvoyFilterBytes is the maximum size of the specs of all
+	// EnvoyFilters of an istio ingress gateway, configurations exceeding it
+	// are refused (0 means no limit)
+	MaxGatewayEnvoyFilterBytes int
+	// AutoAllowInfrastructureEgressCIDRs specifies whether the egress CIDRs of
+	// the shoot's Infrastructure (e.g. NAT IPs) are always allowed.
+	AutoAllowInfrastructureEgressCIDRs bool
+	// GlobalAllowlistConfigMap references the ConfigMap containing CIDRs that
+	// are always allowed for every shoot, e.g. corporate monitoring ranges.
+	GlobalAllowlistConfigMap types.NamespacedName
+	// GlobalDenylistConfigMap references the ConfigMap containing CIDRs that
+	// are always denied for every shoot with the ACL extension, taking
+	// precedence over the shoot's rule.
+	GlobalDenylistConfigMap types.NamespacedName
+	// PurposePolicies are the default ACL policies of the shoots by their
+	// purpose, shoots of purposes without a policy get no defaults.
+	PurposePolicies map[string]PurposePolicy
+	// VerifyAPIServerReachability specifies whether the shoot's API server is
+	// probed after applying the ACL to verify it is still reachable from the
+	// seed.
+	VerifyAPIServerReachability bool
+	// PublishShootInfo specifies whether the ACL of every shoot is published
+	// in a ConfigMap in the kube-system namespace of the shoot.
+	PublishShootInfo bool
+	// IgnoredNamespaces match the namespaces of istio ingress gateways which
+	// are never touched, the ACL of the shoots isn't enforced by them.
+	IgnoredNamespaces helper.NamespacePatterns
+	// EnforcementBackend specifies which resources are created in the istio
+	// namespaces to enforce the ACL, see EnforcementBackendEnvoyFilter and
+	// EnforcementBackendAuthorizationPolicy.
+	EnforcementBackend string
+	// AggregateEnvoyFilters specifies whether the EnvoyFilter patches of the
+	// API server and VPN access of all shoots are merged into a few
+	// EnvoyFilters per istio namespace instead of dedicated EnvoyFilters per
+	// shoot. Only supported by EnforcementBackendEnvoyFilter.
+	AggregateEnvoyFilters bool
+	// CreateAggregatedEnvoyFiltersDirectly specifies whether the aggregated
+	// EnvoyFilters are created directly instead of via a ManagedResource.
+	CreateAggregatedEnvoyFiltersDirectly bool
+	// LogDeniedConnections specifies whether the connections denied by the
+	// ACL are logged by the istio ingress gateways for shoots which don't
+	// configure it themselves.
+	LogDeniedConnections bool
+	// ClientIPPreservation specifies how the load balancers of the seed's
+	// istio ingress gateways pass the client IPs, with the PROXY protocol the
+	// rules match the client IPs from its header instead of the load
+	// balancer's IP. Defaults to detecting it from the gateway Services.
+	ClientIPPreservation helper.ClientIPPreservation
+	// APIServerGatewayName is the name of the istio Gateway in every shoot
+	// namespace which selects the ingress gateways serving the shoot's API
+	// server, defaults to "kube-apiserver".
+	APIServerGatewayName string
+	// IngressGatewayNamespace and IngressGatewayName reference the istio
+	// Gateway serving the ingress domain of the seed, they default to
+	// "garden/nginx-ingress-controller".
+	IngressGatewayNamespace string
+	IngressGatewayName      string
+	// SyncPeriod is the interval in which the extensions are reconciled again
+	// without changes, 0 disables the periodic reconciliation.
+	SyncPeriod time.Duration
+	// RequeueGatewayNotFound, RequeueClusterNotReady, RequeueConflict and
+	// RequeueRulesRejected are the intervals after which the reconciliation
+	// is retried after errors of these classes, 0 uses the exponential
+	// backoff of the controller.
+	RequeueGatewayNotFound time.Duration
+	RequeueClusterNotReady time.Duration
+	RequeueConflict        time.Duration
+	RequeueRulesRejected   time.Duration
+}
+
+// PurposePolicy is the default ACL policy of the shoots of a purpose.
+type PurposePolicy struct {
+	// DeniedCIDRs are always denied for the shoots of the purpose, like the
+	// CIDRs of the global denylist.
+	DeniedCIDRs []string
+	// ForbidShadowMode rejects "LOG" rules for the shoots of the purpose.
+	ForbidShadowMode bool
 }
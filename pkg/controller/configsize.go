@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1helper "github.com/gardener/gardener/pkg/apis/core/v1beta1/helper"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+)
+
+const (
+	// ConditionTypeConfigTooLarge is the condition of the Extension which is
+	// true if the EnvoyFilters of the shoot were refused, because they would
+	// exceed the maximum size of the EnvoyFilters of an istio ingress gateway.
+	ConditionTypeConfigTooLarge gardencorev1beta1.ConditionType = "ConfigTooLarge"
+	// ReasonConfigTooLarge is the reason of the ConfigTooLarge condition if
+	// the EnvoyFilters of the shoot were refused.
+	ReasonConfigTooLarge = "ConfigTooLarge"
+	// ReasonConfigSizeWithinLimit is the reason of the ConfigTooLarge
+	// condition once the EnvoyFilters of the shoot fit again.
+	ReasonConfigSizeWithinLimit = "ConfigSizeWithinLimit"
+)
+
+// ErrConfigTooLarge is returned if the EnvoyFilters of a shoot would exceed
+// the maximum size of the EnvoyFilters of an istio ingress gateway.
+var ErrConfigTooLarge = errors.New("the EnvoyFilters would exceed the maximum size of the EnvoyFilters of the istio ingress gateway")
+
+// gatewayEnvoyFilterSpecs are the keys of the seed chart values containing
+// the specs of the EnvoyFilters rendered into the istio namespaces of the
+// shoot, together with the prefixes of their names.
+var gatewayEnvoyFilterSpecs = map[string]string{
+	"apiEnvoyFilterSpec":       "acl-api-",
+	"vpnEnvoyFilterSpec":       "acl-vpn-",
+	"accessLogEnvoyFilterSpec": "acl-access-log-",
+}
+
+// checkConfigSize returns the size of the specs of all EnvoyFilters of the
+// istio ingress gateways in the given namespaces once the EnvoyFilters of the
+// given seed chart values are applied, and records the result in the
+// ConfigTooLarge condition of the extension. If the size of a gateway would
+// exceed MaxGatewayEnvoyFilterBytes, an event is recorded and
+// ErrConfigTooLarge is returned, so the previous EnvoyFilters of the shoot
+// stay in place instead of making the gateway reject the updates of all
+// shoots. The aggregated EnvoyFilters are counted as they are, i.e. including
+// the previous patches of the shoot, so the size is rather overestimated.
+func (a *actuator) checkConfigSize(
+	ctx context.Context, ex *extensionsv1alpha1.Extension, values map[string]interface{}, istioNamespaces []string,
+) (map[string]int, error) {
+	const withinLimitMessage = "the EnvoyFilters of the shoot fit into the maximum size of the EnvoyFilters of the istio ingress gateways"
+	if a.extensionConfig.MaxGatewayEnvoyFilterBytes == 0 {
+		return nil, a.updateConfigTooLargeCondition(ctx, ex, false, withinLimitMessage)
+	}
+
+	shootBytes := 0
+	for key := range gatewayEnvoyFilterSpecs {
+		if spec, ok := values[key]; ok && spec != nil {
+			size, err := jsonSize(spec)
+			if err != nil {
+				return nil, err
+			}
+			shootBytes += size
+		}
+	}
+	if shootBytes == 0 {
+		return nil, a.updateConfigTooLargeCondition(ctx, ex, false, withinLimitMessage)
+	}
+
+	envoyFilterWriter, err := envoyfilters.DiscoverWriter(a.client.RESTMapper())
+	if err != nil {
+		return nil, err
+	}
+	technicalID, _ := values["shootName"].(string)
+
+	gatewayBytes := map[string]int{}
+	var exceeded []string
+	for _, istioNamespace := range istioNamespaces {
+		envoyFilters := envoyFilterWriter.NewList()
+		if err := a.client.List(ctx, envoyFilters, client.InNamespace(istioNamespace)); err != nil {
+			return nil, err
+		}
+
+		size := shootBytes
+		for _, envoyFilter := range envoyFilters.Items {
+			if isEnvoyFilterOfShoot(envoyFilter.GetName(), technicalID) {
+				continue
+			}
+			specSize, err := jsonSize(envoyFilter.Object["spec"])
+			if err != nil {
+				return nil, err
+			}
+			size += specSize
+		}
+		gatewayBytes[istioNamespace] = size
+		if size > a.extensionConfig.MaxGatewayEnvoyFilterBytes {
+			exceeded = append(exceeded, fmt.Sprintf("%s (%d bytes)", istioNamespace, size))
+		}
+	}
+
+	if len(exceeded) > 0 {
+		message := fmt.Sprintf("the EnvoyFilters of the shoot (%d bytes) would exceed the maximum size of %d bytes of the EnvoyFilters of the istio namespaces %s",
+			shootBytes, a.extensionConfig.MaxGatewayEnvoyFilterBytes, strings.Join(exceeded, ", "))
+		if err := a.updateConfigTooLargeCondition(ctx, ex, true, message); err != nil {
+			return nil, err
+		}
+		if err := a.updateEffectiveCondition(ctx, ex, gardencorev1beta1.ConditionFalse, ReasonConfigTooLarge, message); err != nil {
+			return nil, err
+		}
+		a.recorder.Event(ex, corev1.EventTypeWarning, EventReasonConfigTooLarge, "Refused to apply the ACL rule: "+message)
+		return nil, fmt.Errorf("%w: %s", ErrConfigTooLarge, message)
+	}
+	return gatewayBytes, a.updateConfigTooLargeCondition(ctx, ex, false, withinLimitMessage)
+}
+
+// isEnvoyFilterOfShoot returns true if the EnvoyFilter is one of the
+// EnvoyFilters of the seed chart of the shoot, which are replaced when the
+// chart is applied.
+func isEnvoyFilterOfShoot(name, technicalID string) bool {
+	for _, prefix := range gatewayEnvoyFilterSpecs {
+		if name == prefix+technicalID {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonSize(value interface{}) (int, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (a *actuator) updateConfigTooLargeCondition(ctx context.Context, ex *extensionsv1alpha1.Extension, tooLarge bool, message string) error {
+	condition := v1beta1helper.GetCondition(ex.Status.Conditions, ConditionTypeConfigTooLarge)
+	if condition == nil {
+		if !tooLarge {
+			// shoots whose EnvoyFilters always fit don't get the condition
+			return nil
+		}
+		initCondition := v1beta1helper.InitConditionWithClock(clock.RealClock{}, ConditionTypeConfigTooLarge)
+		condition = &initCondition
+	}
+
+	var updated gardencorev1beta1.Condition
+	if tooLarge {
+		updated = v1beta1helper.UpdatedConditionWithClock(clock.RealClock{}, *condition, gardencorev1beta1.ConditionTrue,
+			ReasonConfigTooLarge, message)
+	} else {
+		updated = v1beta1helper.UpdatedConditionWithClock(clock.RealClock{}, *condition, gardencorev1beta1.ConditionFalse,
+			ReasonConfigSizeWithinLimit, message)
+	}
+	if v1beta1helper.ConditionsNeedUpdate([]gardencorev1beta1.Condition{*condition}, []gardencorev1beta1.Condition{updated}) {
+		patch := client.MergeFrom(ex.DeepCopy())
+		ex.Status.Conditions = v1beta1helper.MergeConditions(ex.Status.Conditions, updated)
+		return a.client.Status().Patch(ctx, ex, patch)
+	}
+	return nil
+}
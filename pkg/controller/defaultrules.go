@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gardener/gardener/extensions/pkg/controller"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+const (
+	// AnnotationDefaultRules is the annotation of a Seed or a CloudProfile in
+	// the garden containing a providerConfig whose rules are enforced for the
+	// shoots of the seed or the CloudProfile that don't define a rule
+	// themselves. The default rules of the seed take precedence over the ones
+	// of the CloudProfile.
+	AnnotationDefaultRules = "acl.stackit.cloud/default-rules"
+
+	// RuleSourceShoot is the source of the rules defined in the providerConfig
+	// of the shoot.
+	RuleSourceShoot = "Shoot"
+	// RuleSourceSeed is the source of the default rules of the seed.
+	RuleSourceSeed = "Seed"
+	// RuleSourceCloudProfile is the source of the default rules of the
+	// CloudProfile.
+	RuleSourceCloudProfile = "CloudProfile"
+)
+
+// ErrDefaultRules is returned if the AnnotationDefaultRules annotation
+// of the seed or the CloudProfile can't be decoded.
+var ErrDefaultRules = errors.New("default rules are invalid")
+
+// ApplyDefaultRules sets the rules of the default providerConfig of the seed
+// or, if the seed has none, of the CloudProfile of the cluster if the
+// providerConfig of the shoot doesn't define a rule. The rule, the internal
+// rule, allowOpenAccess and normalizeCIDRs are taken from the default, all
+// other settings of the shoot are kept. It returns the source of the rules
+// of the spec, see RuleSourceShoot.
+func ApplyDefaultRules(spec *extensionspec.ExtensionSpec, cluster *controller.Cluster) (string, error) {
+	if spec.Rule != nil {
+		return RuleSourceShoot, nil
+	}
+
+	source, raw := RuleSourceSeed, ""
+	if cluster.Seed != nil {
+		raw = cluster.Seed.Annotations[AnnotationDefaultRules]
+	}
+	if raw == "" && cluster.CloudProfile != nil {
+		source, raw = RuleSourceCloudProfile, cluster.CloudProfile.Annotations[AnnotationDefaultRules]
+	}
+	if raw == "" {
+		return RuleSourceShoot, nil
+	}
+
+	defaults := &extensionspec.ExtensionSpec{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(defaults); err != nil {
+		return "", fmt.Errorf("%w: annotation %s of the %s: %v", ErrDefaultRules, AnnotationDefaultRules, source, err)
+	}
+
+	spec.Rule = defaults.Rule
+	spec.InternalRule = defaults.InternalRule
+	spec.AllowOpenAccess = defaults.AllowOpenAccess
+	spec.NormalizeCIDRs = defaults.NormalizeCIDRs
+	return source, nil
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+var _ = Describe("#ApplyDefaultRules", func() {
+	const (
+		seedDefaults         = `{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["10.0.0.0/8"]},"normalizeCIDRs":true}`
+		cloudProfileDefaults = `{"rule":{"action":"DENY","type":"remote_ip","cidrs":["192.0.2.0/24"]}}`
+	)
+
+	var (
+		cluster *extensionscontroller.Cluster
+		spec    *extensionspec.ExtensionSpec
+	)
+
+	BeforeEach(func() {
+		cluster = &extensionscontroller.Cluster{
+			Seed:         &gardencorev1beta1.Seed{},
+			CloudProfile: &gardencorev1beta1.CloudProfile{},
+		}
+		spec = &extensionspec.ExtensionSpec{Profile: extensionspec.ProfileAPIServerOnly}
+	})
+
+	It("should keep the rule of the shoot", func() {
+		cluster.Seed.Annotations = map[string]string{AnnotationDefaultRules: seedDefaults}
+		spec.Rule = &envoyfilters.ACLRule{Action: "ALLOW", Type: "remote_ip", Cidrs: []string{"1.2.3.0/24"}}
+
+		Expect(ApplyDefaultRules(spec, cluster)).To(Equal(RuleSourceShoot))
+		Expect(spec.Rule.Cidrs).To(Equal([]string{"1.2.3.0/24"}))
+		Expect(spec.NormalizeCIDRs).To(BeFalse())
+	})
+
+	It("should apply the default rules of the seed before the ones of the CloudProfile", func() {
+		cluster.Seed.Annotations = map[string]string{AnnotationDefaultRules: seedDefaults}
+		cluster.CloudProfile.Annotations = map[string]string{AnnotationDefaultRules: cloudProfileDefaults}
+
+		Expect(ApplyDefaultRules(spec, cluster)).To(Equal(RuleSourceSeed))
+		Expect(spec.Rule).To(Equal(&envoyfilters.ACLRule{Action: "ALLOW", Type: "remote_ip", Cidrs: []string{"10.0.0.0/8"}}))
+		Expect(spec.NormalizeCIDRs).To(BeTrue())
+		Expect(spec.Profile).To(Equal(extensionspec.ProfileAPIServerOnly))
+	})
+
+	It("should apply the default rules of the CloudProfile", func() {
+		cluster.CloudProfile = &gardencorev1beta1.CloudProfile{ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{AnnotationDefaultRules: cloudProfileDefaults},
+		}}
+
+		Expect(ApplyDefaultRules(spec, cluster)).To(Equal(RuleSourceCloudProfile))
+		Expect(spec.Rule.Action).To(Equal("DENY"))
+	})
+
+	It("should keep a spec without rule if there are no default rules", func() {
+		cluster.CloudProfile = nil
+
+		Expect(ApplyDefaultRules(spec, cluster)).To(Equal(RuleSourceShoot))
+		Expect(spec.Rule).To(BeNil())
+	})
+
+	It("should reject invalid default rules", func() {
+		cluster.Seed.Annotations = map[string]string{AnnotationDefaultRules: `{"rules":[]}`}
+
+		_, err := ApplyDefaultRules(spec, cluster)
+		Expect(err).To(MatchError(ErrDefaultRules))
+		Expect(err).To(MatchError(ContainSubstring("of the Seed")))
+	})
+})
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+const (
+	// AnnotationDisabled is the annotation of the Extension which disables the
+	// enforcement of the ACL for the shoot if set to "true", e.g. as
+	// break-glass during a lockout.
+	AnnotationDisabled = "acl.stackit.cloud/disabled"
+
+	disabledWarning = "the enforcement of the ACL is intentionally disabled, the API server is reachable from everywhere"
+)
+
+// IsDisabled returns true if the enforcement of the ACL is disabled for the
+// shoot of the Extension, either with the AnnotationDisabled annotation or
+// with the disabled field of its providerConfig.
+func IsDisabled(ex *extensionsv1alpha1.Extension, spec *extensionspec.ExtensionSpec) bool {
+	return ex.Annotations[AnnotationDisabled] == "true" || (spec != nil && spec.Disabled)
+}
+
+// disable removes all objects enforcing the ACL of the shoot, like the
+// deletion of the Extension does, but keeps the Extension and reports the
+// disabled enforcement in its status. The istio namespaces of the state are
+// kept, so a later deletion cleans up all of them, and the history records the
+// removal of the allowlist.
+func (a *actuator) disable(ctx context.Context, log logr.Logger, ex *extensionsv1alpha1.Extension, purpose string) error {
+	log.Info("Enforcement of the ACL is disabled, removing all enforcing objects")
+
+	if err := a.removeEnforcement(ctx, log, ex); err != nil {
+		return err
+	}
+
+	extState, err := GetExtensionState(ex)
+	if err != nil {
+		return err
+	}
+	wasDisabled := extState.Disabled
+
+	extState = &ExtensionState{
+		SchemaVersion:   SchemaVersion,
+		IstioNamespace:  extState.IstioNamespace,
+		IstioNamespaces: extState.IstioNamespaces,
+		History:         recordHistory(extState.History, ex.Generation, "", extState.Allowlist, nil, time.Now()),
+		Disabled:        true,
+		Warnings:        []string{disabledWarning},
+	}
+	if err := a.updateStatus(ctx, ex, extState); err != nil {
+		return err
+	}
+	if err := a.updateWarningsCondition(ctx, ex, extState.Warnings); err != nil {
+		return err
+	}
+	if err := a.updateEffectiveCondition(ctx, ex, gardencorev1beta1.ConditionFalse, ReasonDisabled, disabledWarning); err != nil {
+		return err
+	}
+	if err := a.deployShootInfo(ctx, ex.GetNamespace(), shootInfoData(nil, "")); err != nil {
+		return err
+	}
+	if !wasDisabled {
+		a.recorder.Event(ex, corev1.EventTypeWarning, EventReasonDisabled, "Disabled the enforcement of the ACL")
+	}
+
+	recordShoot(ex.GetNamespace(), purpose, 0, true)
+	return nil
+}
@@ -0,0 +1,162 @@
+// Package drift contains controllers that watch the EnvoyFilters the
+// extension is responsible for and trigger their reconciliation as soon as
+// they are modified or deleted out-of-band, e.g. by an istio upgrade job,
+// instead of waiting for the next periodic resync.
+package drift
+
+import (
+	"context"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	resourcesv1alpha1 "github.com/gardener/gardener/pkg/apis/resources/v1alpha1"
+	resourcesv1alpha1helper "github.com/gardener/gardener/pkg/apis/resources/v1alpha1/helper"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+// ControllerName is the name of the EnvoyFilter drift controllers.
+const ControllerName = "acl-envoyfilter-drift"
+
+var (
+	// DefaultAddOptions are the default AddOptions for AddToManager.
+	DefaultAddOptions = AddOptions{}
+)
+
+// AddOptions are options to apply when adding the EnvoyFilter drift
+// controllers to the manager.
+type AddOptions struct {
+	// ControllerOptions contains options for the controllers.
+	ControllerOptions controller.Options
+}
+
+// AddToManager adds the controllers with the default Options to the given Controller Manager.
+//
+// +kubebuilder:rbac:groups=networking.istio.io,resources=envoyfilters,verbs=get;list;watch
+// +kubebuilder:rbac:groups=resources.gardener.cloud,resources=managedresources,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=extensions,verbs=get;list;watch;patch
+func AddToManager(ctx context.Context, mgr manager.Manager) error {
+	return AddToManagerWithOptions(ctx, mgr, &DefaultAddOptions)
+}
+
+// AddToManagerWithOptions adds the controllers with the given Options to the
+// given manager. One controller heals the EnvoyFilters of the ManagedResources
+// of the shoots, the other one the EnvoyFilters of the shoots' API servers,
+// which are mutated by the webhook. Nothing is added if istio isn't installed.
+func AddToManagerWithOptions(_ context.Context, mgr manager.Manager, opts *AddOptions) error {
+	istioInstalled, err := helper.IsIstioInstalled(mgr.GetRESTMapper())
+	if err != nil || !istioInstalled {
+		return err
+	}
+	envoyFilterWriter, err := envoyfilters.DiscoverWriter(mgr.GetRESTMapper())
+	if err != nil {
+		return err
+	}
+
+	if err := builder.ControllerManagedBy(mgr).
+		Named(ControllerName+"-managedresource").
+		WithOptions(opts.ControllerOptions).
+		Watches(
+			envoyFilterWriter.New("", ""),
+			handler.EnqueueRequestsFromMapFunc(MapManagedEnvoyFilterToManagedResource),
+			builder.WithPredicates(ManagedEnvoyFilterChanged()),
+		).
+		Complete(&managedResourceReconciler{client: mgr.GetClient()}); err != nil {
+		return err
+	}
+
+	return builder.ControllerManagedBy(mgr).
+		Named(ControllerName+"-extension").
+		WithOptions(opts.ControllerOptions).
+		Watches(
+			envoyFilterWriter.New("", ""),
+			handler.EnqueueRequestsFromMapFunc(MapShootEnvoyFilterToExtension(mgr.GetClient())),
+			builder.WithPredicates(WebhookLabelMissing()),
+		).
+		Complete(&extensionReconciler{client: mgr.GetClient()})
+}
+
+// managedResourceKey returns the key of the seed ManagedResource of a shoot
+// the given object was applied for by the gardener-resource-manager.
+func managedResourceKey(obj client.Object) (client.ObjectKey, bool) {
+	origin, ok := obj.GetAnnotations()[resourcesv1alpha1.OriginAnnotation]
+	if !ok {
+		return client.ObjectKey{}, false
+	}
+	_, key, err := resourcesv1alpha1helper.SplitOrigin(origin)
+	if err != nil || key.Name != aclcontroller.ResourceNameSeed {
+		return client.ObjectKey{}, false
+	}
+	return key, true
+}
+
+// ManagedEnvoyFilterChanged is a predicate for the EnvoyFilters of the seed
+// ManagedResources of the shoots, which lets deletions and changes of their
+// spec pass. Creations are made by the gardener-resource-manager itself.
+func ManagedEnvoyFilterChanged() predicate.Predicate {
+	isManaged := func(obj client.Object) bool {
+		_, ok := managedResourceKey(obj)
+		return ok
+	}
+
+	return predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isManaged(e.ObjectNew) && e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration()
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return isManaged(e.Object) },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+}
+
+// MapManagedEnvoyFilterToManagedResource maps an EnvoyFilter to the seed
+// ManagedResource of the shoot it belongs to.
+func MapManagedEnvoyFilterToManagedResource(_ context.Context, obj client.Object) []reconcile.Request {
+	key, ok := managedResourceKey(obj)
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: key}}
+}
+
+// WebhookLabelMissing is a predicate for EnvoyFilters which were created or
+// updated without the aclcontroller.WebhookLabel. The webhook isn't invoked
+// for such EnvoyFilters if it selects the objects by the label.
+func WebhookLabelMissing() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetDeletionTimestamp().IsZero() && obj.GetLabels()[aclcontroller.WebhookLabel] != "true"
+	})
+}
+
+// MapShootEnvoyFilterToExtension returns a MapFunc mapping the EnvoyFilter of
+// a shoot's API server, which is named after the shoot namespace, to the ACL
+// Extension of the shoot. The Extension is looked up with the
+// aclcontroller.IstioNamespaceIndex, so other EnvoyFilters in the istio
+// namespaces are dropped without listing all Extensions.
+func MapShootEnvoyFilterToExtension(reader client.Reader) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		extensions := &extensionsv1alpha1.ExtensionList{}
+		if err := aclcontroller.ListExtensionsForIstioNamespace(ctx, reader, obj.GetNamespace(), extensions); err != nil {
+			logf.FromContext(ctx).Error(err, "Could not list the extensions of the istio namespace", "namespace", obj.GetNamespace())
+			return nil
+		}
+
+		for i := range extensions.Items {
+			ex := &extensions.Items[i]
+			if ex.Namespace == obj.GetName() {
+				return []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(ex)}}
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,29 @@
+package drift
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// ObjectManagedResource is the object label of ManagedResources whose
+	// reconciliation was triggered.
+	ObjectManagedResource = "managedresource"
+	// ObjectExtension is the object label of Extensions whose reconciliation
+	// was triggered.
+	ObjectExtension = "extension"
+)
+
+var healedObjects = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "acl",
+		Subsystem: "envoyfilter_drift",
+		Name:      "triggered_reconciliations_total",
+		Help:      "Number of reconciliations triggered because EnvoyFilters were modified or deleted out-of-band.",
+	},
+	[]string{"object"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(healedObjects)
+}
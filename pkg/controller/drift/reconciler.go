@@ -0,0 +1,119 @@
+package drift
+
+import (
+	"context"
+
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	resourcesv1alpha1 "github.com/gardener/gardener/pkg/apis/resources/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+)
+
+// managedResourceReconciler triggers the reconciliation of the seed
+// ManagedResource of a shoot, which makes the gardener-resource-manager
+// restore the modified or deleted EnvoyFilters right away.
+type managedResourceReconciler struct {
+	client client.Client
+}
+
+// Reconcile annotates the ManagedResource with the reconcile operation
+// annotation. The gardener-resource-manager doesn't update objects which are
+// up to date, so EnvoyFilters it changed or deleted itself, e.g. after the
+// istio namespaces of the shoot changed, only lead to one more reconciliation
+// without changes.
+func (r *managedResourceReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	mr := &resourcesv1alpha1.ManagedResource{}
+	if err := r.client.Get(ctx, req.NamespacedName, mr); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// the EnvoyFilters of deleted ManagedResources are deleted on purpose
+	if !mr.DeletionTimestamp.IsZero() || mr.Annotations[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile {
+		return reconcile.Result{}, nil
+	}
+
+	logf.FromContext(ctx).Info("Triggering reconciliation of the ManagedResource because its EnvoyFilters were changed")
+	if err := triggerReconciliation(ctx, r.client, mr); err != nil {
+		return reconcile.Result{}, err
+	}
+	healedObjects.WithLabelValues(ObjectManagedResource).Inc()
+	return reconcile.Result{}, nil
+}
+
+// extensionReconciler triggers the reconciliation of an ACL Extension whose
+// API server EnvoyFilters lost the aclcontroller.WebhookLabel, e.g. because
+// they were recreated. The actuator adds the label again, which invokes the
+// webhook, so the EnvoyFilters contain the ACL again.
+type extensionReconciler struct {
+	client client.Client
+}
+
+// Reconcile annotates the Extension with the reconcile operation annotation
+// if one of the EnvoyFilters in its istio namespaces misses the webhook label.
+func (r *extensionReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	ex := &extensionsv1alpha1.Extension{}
+	if err := r.client.Get(ctx, req.NamespacedName, ex); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if ex.Spec.Type != aclcontroller.Type || !ex.DeletionTimestamp.IsZero() ||
+		ex.Annotations[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile {
+		return reconcile.Result{}, nil
+	}
+
+	drifted, err := r.webhookLabelMissing(ctx, ex)
+	if err != nil || !drifted {
+		return reconcile.Result{}, err
+	}
+
+	logf.FromContext(ctx).Info("Triggering reconciliation because an EnvoyFilter of the shoot misses the webhook label")
+	if err := triggerReconciliation(ctx, r.client, ex); err != nil {
+		return reconcile.Result{}, err
+	}
+	healedObjects.WithLabelValues(ObjectExtension).Inc()
+	return reconcile.Result{}, nil
+}
+
+func (r *extensionReconciler) webhookLabelMissing(ctx context.Context, ex *extensionsv1alpha1.Extension) (bool, error) {
+	extState, err := aclcontroller.GetExtensionState(ex)
+	if err != nil {
+		return false, err
+	}
+
+	envoyFilterWriter, err := envoyfilters.DiscoverWriter(r.client.RESTMapper())
+	if err != nil {
+		return false, err
+	}
+
+	for _, istioNamespace := range extState.GetIstioNamespaces() {
+		envoyFilter := envoyFilterWriter.New(istioNamespace, ex.Namespace)
+		if err := r.client.Get(ctx, client.ObjectKeyFromObject(envoyFilter), envoyFilter); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return false, err
+			}
+			continue
+		}
+		if envoyFilter.GetDeletionTimestamp().IsZero() && envoyFilter.GetLabels()[aclcontroller.WebhookLabel] != "true" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// triggerReconciliation annotates the object with the reconcile operation
+// annotation.
+func triggerReconciliation(ctx context.Context, c client.Client, obj client.Object) error {
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[v1beta1constants.GardenerOperation] = v1beta1constants.GardenerOperationReconcile
+	obj.SetAnnotations(annotations)
+	return client.IgnoreNotFound(c.Patch(ctx, obj, patch))
+}
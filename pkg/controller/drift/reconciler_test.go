@@ -0,0 +1,172 @@
+package drift
+
+import (
+	"context"
+	"encoding/json"
+
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	resourcesv1alpha1 "github.com/gardener/gardener/pkg/apis/resources/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	istionetworkv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+)
+
+var _ = Describe("drift", func() {
+	const shootNamespace = "shoot--foo--bar"
+
+	var (
+		ctx = context.TODO()
+		c   client.Client
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+		Expect(resourcesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		Expect(istionetworkv1alpha3.AddToScheme(scheme)).To(Succeed())
+
+		c = fakeclient.NewClientBuilder().
+			WithScheme(scheme).
+			WithIndex(&extensionsv1alpha1.Extension{}, aclcontroller.IstioNamespaceIndex, aclcontroller.IstioNamespaceIndexerFunc).
+			Build()
+	})
+
+	managedEnvoyFilter := func(origin string) *istionetworkv1alpha3.EnvoyFilter {
+		return &istionetworkv1alpha3.EnvoyFilter{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "istio-ingress",
+				Name:        "acl-api-" + shootNamespace,
+				Annotations: map[string]string{resourcesv1alpha1.OriginAnnotation: origin},
+			},
+		}
+	}
+
+	shootEnvoyFilter := func(labels map[string]string) *istionetworkv1alpha3.EnvoyFilter {
+		return &istionetworkv1alpha3.EnvoyFilter{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "istio-ingress", Name: shootNamespace, Labels: labels},
+		}
+	}
+
+	createExtension := func(istioNamespaces ...string) *extensionsv1alpha1.Extension {
+		extState, err := json.Marshal(&aclcontroller.ExtensionState{IstioNamespaces: istioNamespaces})
+		Expect(err).NotTo(HaveOccurred())
+		ex := &extensionsv1alpha1.Extension{
+			ObjectMeta: metav1.ObjectMeta{Namespace: shootNamespace, Name: "acl"},
+			Spec: extensionsv1alpha1.ExtensionSpec{
+				DefaultSpec: extensionsv1alpha1.DefaultSpec{Type: aclcontroller.Type},
+			},
+			Status: extensionsv1alpha1.ExtensionStatus{
+				DefaultStatus: extensionsv1alpha1.DefaultStatus{State: &runtime.RawExtension{Raw: extState}},
+			},
+		}
+		Expect(c.Create(ctx, ex)).To(Succeed())
+		return ex
+	}
+
+	isTriggered := func(obj client.Object) bool {
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(obj), obj)).To(Succeed())
+		return obj.GetAnnotations()[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile
+	}
+
+	Describe("managed EnvoyFilters", func() {
+		It("should map the EnvoyFilters to the seed ManagedResource of the shoot", func() {
+			key := client.ObjectKey{Namespace: shootNamespace, Name: aclcontroller.ResourceNameSeed}
+			Expect(MapManagedEnvoyFilterToManagedResource(ctx, managedEnvoyFilter("cluster-id:"+key.String()))).To(ConsistOf(reconcile.Request{NamespacedName: key}))
+			Expect(MapManagedEnvoyFilterToManagedResource(ctx, managedEnvoyFilter(key.String()))).To(ConsistOf(reconcile.Request{NamespacedName: key}))
+			Expect(MapManagedEnvoyFilterToManagedResource(ctx, managedEnvoyFilter(shootNamespace+"/other"))).To(BeEmpty())
+			Expect(MapManagedEnvoyFilterToManagedResource(ctx, shootEnvoyFilter(nil))).To(BeEmpty())
+		})
+
+		It("should only let deletions and spec changes pass", func() {
+			p := ManagedEnvoyFilterChanged()
+			old := managedEnvoyFilter(shootNamespace + "/" + aclcontroller.ResourceNameSeed)
+			changed := old.DeepCopy()
+			changed.Generation++
+
+			Expect(p.Create(event.CreateEvent{Object: old})).To(BeFalse())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: old.DeepCopy()})).To(BeFalse())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: changed})).To(BeTrue())
+			Expect(p.Delete(event.DeleteEvent{Object: old})).To(BeTrue())
+			Expect(p.Delete(event.DeleteEvent{Object: shootEnvoyFilter(nil)})).To(BeFalse())
+		})
+
+		It("should trigger the reconciliation of the ManagedResource", func() {
+			mr := &resourcesv1alpha1.ManagedResource{
+				ObjectMeta: metav1.ObjectMeta{Namespace: shootNamespace, Name: aclcontroller.ResourceNameSeed},
+			}
+			Expect(c.Create(ctx, mr)).To(Succeed())
+			before := testutil.ToFloat64(healedObjects.WithLabelValues(ObjectManagedResource))
+
+			r := &managedResourceReconciler{client: c}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(mr)})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(isTriggered(mr)).To(BeTrue())
+			Expect(testutil.ToFloat64(healedObjects.WithLabelValues(ObjectManagedResource))).To(Equal(before + 1))
+		})
+
+		It("should ignore missing ManagedResources", func() {
+			r := &managedResourceReconciler{client: c}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKey{Namespace: shootNamespace, Name: aclcontroller.ResourceNameSeed}})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("shoot EnvoyFilters", func() {
+		It("should only let EnvoyFilters without the webhook label pass", func() {
+			p := WebhookLabelMissing()
+			Expect(p.Create(event.CreateEvent{Object: shootEnvoyFilter(nil)})).To(BeTrue())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: shootEnvoyFilter(nil), ObjectNew: shootEnvoyFilter(map[string]string{aclcontroller.WebhookLabel: "true"})})).To(BeFalse())
+		})
+
+		It("should map the EnvoyFilter to the ACL extension of its shoot", func() {
+			ex := createExtension("istio-ingress")
+			mapFunc := MapShootEnvoyFilterToExtension(c)
+
+			Expect(mapFunc(ctx, shootEnvoyFilter(nil))).To(ConsistOf(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(ex)}))
+
+			other := shootEnvoyFilter(nil)
+			other.Name = "shoot--foo--other"
+			Expect(mapFunc(ctx, other)).To(BeEmpty())
+			other = shootEnvoyFilter(nil)
+			other.Namespace = "istio-ingress--zone-a"
+			Expect(mapFunc(ctx, other)).To(BeEmpty())
+		})
+
+		It("should trigger the reconciliation if the EnvoyFilter misses the webhook label", func() {
+			ex := createExtension("istio-ingress")
+			Expect(c.Create(ctx, shootEnvoyFilter(nil))).To(Succeed())
+			before := testutil.ToFloat64(healedObjects.WithLabelValues(ObjectExtension))
+
+			r := &extensionReconciler{client: c}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(ex)})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(isTriggered(ex)).To(BeTrue())
+			Expect(testutil.ToFloat64(healedObjects.WithLabelValues(ObjectExtension))).To(Equal(before + 1))
+		})
+
+		It("should not trigger the reconciliation if the EnvoyFilter has the webhook label", func() {
+			ex := createExtension("istio-ingress")
+			Expect(c.Create(ctx, shootEnvoyFilter(map[string]string{aclcontroller.WebhookLabel: "true"}))).To(Succeed())
+
+			r := &extensionReconciler{client: c}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(ex)})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(isTriggered(ex)).To(BeFalse())
+		})
+	})
+})
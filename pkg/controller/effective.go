@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1helper "github.com/gardener/gardener/pkg/apis/core/v1beta1/helper"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+const (
+	// ConditionTypeACLEffective is the condition of the Extension which is
+	// true if the rules of the shoot are enforced on all of its traffic paths,
+	// progressing while the webhook didn't mutate the EnvoyFilters of the
+	// internal flow yet, and false if the rules aren't enforced.
+	ConditionTypeACLEffective gardencorev1beta1.ConditionType = "ACLEffective"
+	// ReasonEnforced is the reason of the ACLEffective condition if the rules
+	// are enforced.
+	ReasonEnforced = "Enforced"
+	// ReasonWebhookPending is the reason of the ACLEffective condition while
+	// the EnvoyFilters of the internal flow aren't mutated by the webhook.
+	ReasonWebhookPending = "WebhookPending"
+	// ReasonShadowMode is the reason of the ACLEffective condition for LOG
+	// rules, which only count and log the connections of their CIDRs.
+	ReasonShadowMode = "ShadowMode"
+	// ReasonDisabled is the reason of the ACLEffective condition if the
+	// enforcement of the ACL is disabled, see IsDisabled.
+	ReasonDisabled = "Disabled"
+	// ReasonRulesRejected is the reason of the ACLEffective condition if the
+	// rules of the providerConfig are invalid.
+	ReasonRulesRejected = "RulesRejected"
+	// ReasonIstioGatewayNotFound is the reason of the ACLEffective condition
+	// if the istio Gateway of the API server doesn't exist.
+	ReasonIstioGatewayNotFound = "IstioGatewayNotFound"
+)
+
+// effectiveCondition returns the status, reason and message of the
+// ACLEffective condition after the rules of the spec were applied.
+func effectiveCondition(spec *extensionspec.ExtensionSpec, trafficPaths []TrafficPathStatus) (gardencorev1beta1.ConditionStatus, string, string) {
+	if spec.Rule.IsLog() {
+		return gardencorev1beta1.ConditionFalse, ReasonShadowMode,
+			"the LOG rule only counts and logs the connections of its CIDRs, all sources can access the API server"
+	}
+	for _, path := range trafficPaths {
+		if path.Name == TrafficPathAPIServerProxy && !path.Protected {
+			return gardencorev1beta1.ConditionProgressing, ReasonWebhookPending, path.Reason
+		}
+	}
+	return gardencorev1beta1.ConditionTrue, ReasonEnforced,
+		fmt.Sprintf("the %s rule is enforced for the targets %v", spec.Rule.Action, spec.Targets())
+}
+
+// updateEffectiveCondition records whether the ACL of the shoot is enforced
+// in the ACLEffective condition of the Extension.
+func (a *actuator) updateEffectiveCondition(
+	ctx context.Context, ex *extensionsv1alpha1.Extension, status gardencorev1beta1.ConditionStatus, reason, message string,
+) error {
+	condition := v1beta1helper.GetCondition(ex.Status.Conditions, ConditionTypeACLEffective)
+	if condition == nil {
+		initCondition := v1beta1helper.InitConditionWithClock(clock.RealClock{}, ConditionTypeACLEffective)
+		condition = &initCondition
+	}
+
+	updated := v1beta1helper.UpdatedConditionWithClock(clock.RealClock{}, *condition, status, reason, message)
+	if v1beta1helper.ConditionsNeedUpdate([]gardencorev1beta1.Condition{*condition}, []gardencorev1beta1.Condition{updated}) {
+		patch := client.MergeFrom(ex.DeepCopy())
+		ex.Status.Conditions = v1beta1helper.MergeConditions(ex.Status.Conditions, updated)
+		return a.client.Status().Patch(ctx, ex, patch)
+	}
+	return nil
+}
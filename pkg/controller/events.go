@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+)
+
+const (
+	// EventReasonRulesApplied is the reason of the event recorded when the
+	// ACL of a shoot was applied.
+	EventReasonRulesApplied = "RulesApplied"
+	// EventReasonRulesRejected is the reason of the event recorded when the
+	// rule of a shoot is invalid.
+	EventReasonRulesRejected = "RulesRejected"
+	// EventReasonDenylistClamped is the reason of the event recorded when
+	// CIDRs allowed by the rule of a shoot are restricted by the global
+	// denylist of the operator.
+	EventReasonDenylistClamped = "DenylistClamped"
+	// EventReasonGatewayNotFound is the reason of the event recorded when the
+	// istio Gateway of the shoot's API server doesn't exist.
+	EventReasonGatewayNotFound = "GatewayNotFound"
+	// EventReasonClientIPNotPreserved is the reason of the event recorded
+	// when the load balancers of the istio ingress gateways of the shoot
+	// don't preserve the client IPs.
+	EventReasonClientIPNotPreserved = "ClientIPNotPreserved"
+	// EventReasonDisabled is the reason of the event recorded when the
+	// enforcement of the ACL of a shoot got disabled.
+	EventReasonDisabled = "Disabled"
+	// EventReasonConfigTooLarge is the reason of the event recorded when the
+	// EnvoyFilters of a shoot were refused, because they would exceed the
+	// maximum size of the EnvoyFilters of an istio ingress gateway.
+	EventReasonConfigTooLarge = "ConfigTooLarge"
+	// EventReasonEgressCIDRsIncomplete is the reason of the event recorded
+	// when the Infrastructure of a dual-stack shoot doesn't report egress
+	// CIDRs of all of its IP families.
+	EventReasonEgressCIDRsIncomplete = "EgressCIDRsIncomplete"
+)
+
+// rejectRule records an event and the ACLEffective condition about the
+// rejected rule of the extension and returns the given error, marked as a
+// rejected rule for requeueAfter.
+func (a *actuator) rejectRule(ctx context.Context, ex *extensionsv1alpha1.Extension, err error) error {
+	a.recorder.Eventf(ex, corev1.EventTypeWarning, EventReasonRulesRejected, "Rejected the ACL rule: %v", err)
+	if updateErr := a.updateEffectiveCondition(ctx, ex, gardencorev1beta1.ConditionFalse, ReasonRulesRejected, err.Error()); updateErr != nil {
+		return fmt.Errorf("%w (could not update the %s condition: %v)", err, ConditionTypeACLEffective, updateErr)
+	}
+	return &rejectedRuleError{err}
+}
+
+// clampedCIDRs returns the CIDRs of the rule that overlap with the globally
+// denied CIDRs. The denylist takes precedence, so these CIDRs are allowed only
+// partially or not at all. DENY rules are never clamped.
+func clampedCIDRs(rule *envoyfilters.ACLRule) []string {
+	if !rule.RestrictsOtherSources() {
+		return nil
+	}
+
+	denied := envoyfilters.NewCIDRSet(rule.DeniedCIDRs...)
+	var clamped []string
+	for _, cidr := range rule.Cidrs {
+		network, err := netip.ParsePrefix(cidr)
+		if err == nil && denied.Overlaps(network) {
+			clamped = append(clamped, cidr)
+		}
+	}
+	return clamped
+}
@@ -0,0 +1,273 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+// fixture is a single providerConfig permutation. Every field of the
+// ExtensionSpec gets a list of valid and invalid values, which are applied one
+// at a time to an otherwise valid base spec. Tests for validation and
+// rendering iterate over the generated matrix, so new fields automatically
+// gain negative-path coverage once their values are added to the
+// fixtureFields below.
+type fixture struct {
+	// name is a human readable description of the fixture, usable as test
+	// entry description.
+	name string
+	// field is the path of the permuted field relative to the providerConfig,
+	// e.g. "rule.cidrs".
+	field string
+	// valid is true if the providerConfig is expected to pass validation.
+	valid bool
+	// spec is the permuted ExtensionSpec.
+	spec *extensionspec.ExtensionSpec
+}
+
+// raw returns the JSON encoded providerConfig of the fixture.
+func (f fixture) raw() ([]byte, error) {
+	return json.Marshal(f.spec)
+}
+
+type fieldValues struct {
+	field   string
+	valid   []interface{}
+	invalid []interface{}
+	apply   func(spec *extensionspec.ExtensionSpec, value interface{})
+}
+
+var fixtureFields = []fieldValues{
+	{
+		field:   "rule.action",
+		valid:   []interface{}{"ALLOW", "DENY", "LOG", "allow"},
+		invalid: []interface{}{"", "REJECT", envoyfilters.ActionRateLimit},
+		apply:   func(spec *extensionspec.ExtensionSpec, value interface{}) { spec.Rule.Action = value.(string) },
+	},
+	{
+		field:   "rule.type",
+		valid:   []interface{}{"remote_ip", "direct_remote_ip", "REMOTE_IP"},
+		invalid: []interface{}{"", "ip", "destination_ip", "source_ip"},
+		apply:   func(spec *extensionspec.ExtensionSpec, value interface{}) { spec.Rule.Type = value.(string) },
+	},
+	{
+		field: "rule.cidrs",
+		valid: []interface{}{
+			[]string{"10.0.0.0/16"},
+			[]string{"10.0.0.0/16", "1.2.3.4/32"},
+			[]string{"10.0.0.0/16", "2001:db8::/32"},
+		},
+		invalid: []interface{}{
+			[]string{},
+			[]string{"10.0.0.0"},
+			[]string{"10.0.0.0/33"},
+			[]string{"foo"},
+			[]string{"0.0.0.0/0"},
+			[]string{"10.0.0.0/16", "::/0"},
+			[]string{"10.0.0.0/16", "10.0.1.0/16"},
+		},
+		apply: func(spec *extensionspec.ExtensionSpec, value interface{}) { spec.Rule.Cidrs = value.([]string) },
+	},
+	{
+		field: "rule.except",
+		valid: []interface{}{
+			[]string(nil),
+			[]string{"10.0.5.0/24"},
+			[]string{"10.0.0.0/16"},
+		},
+		invalid: []interface{}{
+			[]string{"10.1.0.0/24"},
+			[]string{"10.0.0.0/8"},
+			[]string{"foo"},
+		},
+		apply: func(spec *extensionspec.ExtensionSpec, value interface{}) { spec.Rule.Except = value.([]string) },
+	},
+	{
+		field: "rule.rateLimit",
+		valid: []interface{}{
+			&envoyfilters.RateLimit{ConnectionsPerSecond: 10},
+			&envoyfilters.RateLimit{ConnectionsPerSecond: 10, Burst: 50},
+		},
+		invalid: []interface{}{
+			(*envoyfilters.RateLimit)(nil),
+			&envoyfilters.RateLimit{},
+			&envoyfilters.RateLimit{ConnectionsPerSecond: 10, Burst: 5},
+		},
+		apply: func(spec *extensionspec.ExtensionSpec, value interface{}) {
+			spec.Rule.Action = envoyfilters.ActionRateLimit
+			spec.Rule.RateLimit = value.(*envoyfilters.RateLimit)
+		},
+	},
+	{
+		field:   "rule.description",
+		valid:   []interface{}{"", "corporate network, see TICKET-123", strings.Repeat("a", envoyfilters.MaxDescriptionLength)},
+		invalid: []interface{}{strings.Repeat("a", envoyfilters.MaxDescriptionLength+1)},
+		apply:   func(spec *extensionspec.ExtensionSpec, value interface{}) { spec.Rule.Description = value.(string) },
+	},
+	{
+		field: "rule.labels",
+		valid: []interface{}{
+			map[string]string(nil),
+			map[string]string{"team": "platform", "example.com/ticket": "TICKET-123"},
+		},
+		invalid: []interface{}{
+			map[string]string{"owner team": "platform"},
+			map[string]string{"team": "platform engineering"},
+		},
+		apply: func(spec *extensionspec.ExtensionSpec, value interface{}) {
+			spec.Rule.Labels = value.(map[string]string)
+		},
+	},
+	{
+		field: "internalRule",
+		valid: []interface{}{
+			(*envoyfilters.ACLRule)(nil),
+			&envoyfilters.ACLRule{Action: "ALLOW", Type: "remote_ip", Cidrs: []string{"192.168.0.0/16"}},
+			&envoyfilters.ACLRule{Action: "DENY", Type: "direct_remote_ip", Cidrs: []string{"1.2.3.4/32"}},
+		},
+		invalid: []interface{}{
+			&envoyfilters.ACLRule{Action: envoyfilters.ActionRateLimit, Type: "remote_ip", Cidrs: []string{"192.168.0.0/16"},
+				RateLimit: &envoyfilters.RateLimit{ConnectionsPerSecond: 10}},
+			&envoyfilters.ACLRule{Action: envoyfilters.ActionLog, Type: "remote_ip", Cidrs: []string{"192.168.0.0/16"}},
+			&envoyfilters.ACLRule{Action: "ALLOW", Type: "source_ip", Cidrs: []string{"192.168.0.0/16"}},
+			&envoyfilters.ACLRule{Action: "ALLOW", Type: "remote_ip"},
+			&envoyfilters.ACLRule{Action: "ALLOW", Type: "remote_ip", Cidrs: []string{"0.0.0.0/0"}},
+		},
+		apply: func(spec *extensionspec.ExtensionSpec, value interface{}) {
+			spec.InternalRule = value.(*envoyfilters.ACLRule)
+		},
+	},
+	{
+		field:   "profile",
+		valid:   []interface{}{"", extensionspec.ProfileAPIServerOnly, extensionspec.ProfileFull},
+		invalid: []interface{}{"vpn-only", "FULL"},
+		apply:   func(spec *extensionspec.ExtensionSpec, value interface{}) { spec.Profile = value.(string) },
+	},
+	{
+		field:   "allowOpenAccess",
+		valid:   []interface{}{true},
+		invalid: []interface{}{false},
+		// the base rule doesn't need the confirmation, so the fixtures open
+		// the access to check it
+		apply: func(spec *extensionspec.ExtensionSpec, value interface{}) {
+			spec.Rule.Cidrs = append(spec.Rule.Cidrs, "0.0.0.0/0")
+			spec.AllowOpenAccess = value.(bool)
+		},
+	},
+	{
+		field:   "protectIngress",
+		valid:   []interface{}{false, true},
+		invalid: []interface{}{extensionspec.ProfileAPIServerOnly},
+		// the invalid value selects a profile without the ingress target
+		apply: func(spec *extensionspec.ExtensionSpec, value interface{}) {
+			if profile, ok := value.(string); ok {
+				spec.Profile = profile
+				spec.ProtectIngress = true
+				return
+			}
+			spec.ProtectIngress = value.(bool)
+		},
+	},
+	{
+		field: "logDeniedConnections",
+		valid: []interface{}{(*bool)(nil), ptr.To(true), ptr.To(false)},
+		apply: func(spec *extensionspec.ExtensionSpec, value interface{}) {
+			spec.LogDeniedConnections = value.(*bool)
+		},
+	},
+}
+
+// baseFixtureSpec returns the valid ExtensionSpec all fixtures are derived
+// from.
+func baseFixtureSpec() *extensionspec.ExtensionSpec {
+	return &extensionspec.ExtensionSpec{
+		Rule: &envoyfilters.ACLRule{
+			Action: "ALLOW",
+			Type:   "remote_ip",
+			Cidrs:  []string{"10.0.0.0/16"},
+		},
+	}
+}
+
+// providerConfigFixtures returns the full matrix of valid and invalid
+// fixtures.
+func providerConfigFixtures() []fixture {
+	fixtures := []fixture{
+		{name: "base", field: "", valid: true, spec: baseFixtureSpec()},
+		{name: "rule is missing", field: "rule", valid: false, spec: &extensionspec.ExtensionSpec{}},
+	}
+
+	for _, f := range fixtureFields {
+		for _, value := range f.valid {
+			fixtures = append(fixtures, newFixture(f, value, true))
+		}
+		for _, value := range f.invalid {
+			fixtures = append(fixtures, newFixture(f, value, false))
+		}
+	}
+
+	return fixtures
+}
+
+func newFixture(f fieldValues, value interface{}, valid bool) fixture {
+	spec := baseFixtureSpec()
+	f.apply(spec, value)
+
+	validity := "invalid"
+	if valid {
+		validity = "valid"
+	}
+
+	return fixture{
+		name:  fmt.Sprintf("%s %s=%v", validity, f.field, value),
+		field: f.field,
+		valid: valid,
+		spec:  spec,
+	}
+}
+
+var _ = Describe("providerConfig fixtures", func() {
+	It("should contain valid and invalid fixtures for every field", func() {
+		valid, invalid := map[string]bool{}, map[string]bool{}
+		for _, f := range providerConfigFixtures() {
+			if f.valid {
+				valid[f.field] = true
+			} else {
+				invalid[f.field] = true
+			}
+		}
+
+		for _, field := range []string{"rule.action", "rule.type", "rule.cidrs", "rule.except", "rule.rateLimit", "rule.description", "rule.labels", "internalRule", "profile"} {
+			Expect(valid).To(HaveKey(field))
+			Expect(invalid).To(HaveKey(field))
+		}
+	})
+
+	It("should encode fixtures which decode to the same spec", func() {
+		for _, f := range providerConfigFixtures() {
+			raw, err := f.raw()
+			Expect(err).NotTo(HaveOccurred(), f.name)
+
+			spec := &extensionspec.ExtensionSpec{}
+			Expect(json.Unmarshal(raw, spec)).To(Succeed(), f.name)
+			Expect(spec.Rule == nil).To(Equal(f.spec.Rule == nil), f.name)
+		}
+	})
+
+	It("should render all valid fixtures", func() {
+		for _, f := range providerConfigFixtures() {
+			if !f.valid {
+				continue
+			}
+			_, err := envoyfilters.CreateAPIConfigPatchFromRule(f.spec.Rule, "shoot--foo--bar", []string{"api.foo.bar"}, []string{"10.250.0.0/16"})
+			Expect(err).NotTo(HaveOccurred(), f.name)
+		}
+	})
+})
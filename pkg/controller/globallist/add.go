@@ -0,0 +1,104 @@
+package globallist
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// ControllerName is the name of the global allowlist and denylist controller.
+	ControllerName = "acl-global-lists"
+	// DefaultDebouncePeriod is the default period in which the changes of the
+	// global allowlist and denylist are collected before they are applied.
+	DefaultDebouncePeriod = 10 * time.Second
+)
+
+var (
+	// DefaultAddOptions are the default AddOptions for AddToManager.
+	DefaultAddOptions = AddOptions{DebouncePeriod: DefaultDebouncePeriod}
+
+	// listsRequest is the only request of the controller, as both lists are
+	// applied together.
+	listsRequest = reconcile.Request{NamespacedName: types.NamespacedName{Name: ControllerName}}
+)
+
+// AddOptions are options to apply when adding the global allowlist and
+// denylist controller to the manager.
+type AddOptions struct {
+	// ControllerOptions contains options for the controller.
+	ControllerOptions controller.Options
+	// AllowlistConfigMap references the ConfigMap containing the global allowlist.
+	AllowlistConfigMap types.NamespacedName
+	// DenylistConfigMap references the ConfigMap containing the global denylist.
+	DenylistConfigMap types.NamespacedName
+	// DebouncePeriod is the period in which the changes of both lists are
+	// collected before they are applied together.
+	DebouncePeriod time.Duration
+}
+
+// AddToManager adds a controller with the default Options to the given Controller Manager.
+//
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=extensions,verbs=get;list;watch;patch
+func AddToManager(ctx context.Context, mgr manager.Manager) error {
+	return AddToManagerWithOptions(ctx, mgr, &DefaultAddOptions)
+}
+
+// AddToManagerWithOptions adds a controller with the given Options to the given
+// manager. Nothing is added if neither a global allowlist nor a global
+// denylist ConfigMap is configured.
+func AddToManagerWithOptions(_ context.Context, mgr manager.Manager, opts *AddOptions) error {
+	if opts.AllowlistConfigMap.Name == "" && opts.DenylistConfigMap.Name == "" {
+		return nil
+	}
+
+	return builder.ControllerManagedBy(mgr).
+		Named(ControllerName).
+		WithOptions(opts.ControllerOptions).
+		Watches(&corev1.ConfigMap{}, debounced(opts.DebouncePeriod), builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			key := client.ObjectKeyFromObject(obj)
+			return key == opts.AllowlistConfigMap || key == opts.DenylistConfigMap
+		}))).
+		Complete(&reconciler{
+			client:             mgr.GetClient(),
+			allowlistConfigMap: opts.AllowlistConfigMap,
+			denylistConfigMap:  opts.DenylistConfigMap,
+		})
+}
+
+// debounced enqueues the listsRequest for the events of both ConfigMaps after
+// the given period. Further events within the period don't delay it, so a
+// series of changes (e.g. of both lists by a GitOps sync) only re-renders the
+// affected shoots once, while a continuously changing list is still applied
+// after the period.
+func debounced(period time.Duration) handler.EventHandler {
+	enqueue := func(q workqueue.RateLimitingInterface) {
+		q.AddAfter(listsRequest, period)
+	}
+	return handler.Funcs{
+		CreateFunc: func(_ context.Context, _ event.CreateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q)
+		},
+		UpdateFunc: func(_ context.Context, _ event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q)
+		},
+		DeleteFunc: func(_ context.Context, _ event.DeleteEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q)
+		},
+		GenericFunc: func(_ context.Context, _ event.GenericEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q)
+		},
+	}
+}
@@ -0,0 +1,30 @@
+package globallist
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ = Describe("debounced", func() {
+	It("should enqueue a single request for all events after the period", func() {
+		q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		DeferCleanup(q.ShutDown)
+		h := debounced(100 * time.Millisecond)
+
+		h.Create(context.Background(), event.CreateEvent{Object: &corev1.ConfigMap{}}, q)
+		h.Update(context.Background(), event.UpdateEvent{ObjectOld: &corev1.ConfigMap{}, ObjectNew: &corev1.ConfigMap{}}, q)
+		Expect(q.Len()).To(BeZero())
+
+		Eventually(q.Len).Should(Equal(1))
+		item, _ := q.Get()
+		Expect(item).To(Equal(listsRequest))
+		q.Done(item)
+		Consistently(q.Len, 200*time.Millisecond).Should(BeZero())
+	})
+})
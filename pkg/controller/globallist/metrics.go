@@ -0,0 +1,30 @@
+package globallist
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	changedShoots = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "acl",
+			Subsystem: "global_lists",
+			Name:      "changed_shoots_total",
+			Help:      "Number of shoots re-rendered because their effective global allowlist or denylist changed.",
+		},
+	)
+	activeLists = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "acl",
+			Subsystem: "global_lists",
+			Name:      "active_info",
+			Help:      "Checksums of the CIDRs of the global allowlist and denylist which were applied last, always 1.",
+		},
+		[]string{"allowlist_checksum", "denylist_checksum"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(changedShoots, activeLists)
+}
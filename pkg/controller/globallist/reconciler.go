@@ -0,0 +1,106 @@
+// Package globallist contains a controller that watches the operator-managed
+// global allowlist and denylist ConfigMaps and triggers a reconciliation of
+// only those ACL extensions whose effective set of global CIDRs changed.
+package globallist
+
+import (
+	"context"
+	"encoding/json"
+
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+type reconciler struct {
+	client             client.Client
+	allowlistConfigMap types.NamespacedName
+	denylistConfigMap  types.NamespacedName
+}
+
+// Reconcile annotates every ACL extension whose status doesn't match the
+// global allowlist or denylist effective for its rule with the reconcile
+// operation annotation, which makes the extension controller re-render its
+// EnvoyFilters. Extensions which aren't affected by a change of the lists,
+// e.g. DENY rules on allowlist changes, are left untouched.
+func (r *reconciler) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	log := logf.FromContext(ctx)
+
+	allowedCIDRs, err := helper.GetCIDRsFromConfigMap(ctx, r.client, r.allowlistConfigMap)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	deniedCIDRs, err := helper.GetCIDRsFromConfigMap(ctx, r.client, r.denylistConfigMap)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	extensions := &extensionsv1alpha1.ExtensionList{}
+	if err := aclcontroller.ListExtensions(ctx, r.client, extensions); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	changed := 0
+	for i := range extensions.Items {
+		ex := &extensions.Items[i]
+		if ex.Spec.Type != aclcontroller.Type || !ex.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		// the extension is already waiting for its reconciliation, which picks
+		// up the current lists anyway
+		if ex.Annotations[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile {
+			continue
+		}
+
+		extState, err := aclcontroller.GetExtensionState(ex)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+
+		// if the providerConfig can't be decoded, the rule is treated like an
+		// ALLOW rule, and the actuator surfaces the error
+		extSpec := &extensionspec.ExtensionSpec{}
+		if ex.Spec.ProviderConfig != nil && ex.Spec.ProviderConfig.Raw != nil {
+			if err := json.Unmarshal(ex.Spec.ProviderConfig.Raw, extSpec); err != nil {
+				extSpec = &extensionspec.ExtensionSpec{}
+			}
+		}
+		// disabled ACLs don't render the global lists at all
+		if aclcontroller.IsDisabled(ex, extSpec) {
+			continue
+		}
+
+		allowlistChecksum, denylistChecksum := aclcontroller.GlobalListChecksums(extSpec, allowedCIDRs, deniedCIDRs)
+		if extState.GlobalAllowlistChecksum == allowlistChecksum && extState.GlobalDenylistChecksum == denylistChecksum {
+			continue
+		}
+
+		log.Info("Triggering reconciliation because of changed global allowlist or denylist", "namespace", ex.Namespace)
+
+		patch := client.MergeFrom(ex.DeepCopy())
+		metav1.SetMetaDataAnnotation(&ex.ObjectMeta, v1beta1constants.GardenerOperation, v1beta1constants.GardenerOperationReconcile)
+		if err := r.client.Patch(ctx, ex, patch); client.IgnoreNotFound(err) != nil {
+			return reconcile.Result{}, err
+		}
+
+		changedShoots.Inc()
+		changed++
+	}
+
+	allowlistChecksum, denylistChecksum := helper.ComputeCIDRsChecksum(allowedCIDRs), helper.ComputeCIDRsChecksum(deniedCIDRs)
+	activeLists.Reset()
+	activeLists.WithLabelValues(allowlistChecksum, denylistChecksum).Set(1)
+	log.Info("Processed global allowlist and denylist", "changedShoots", changed,
+		"allowlistChecksum", allowlistChecksum, "denylistChecksum", denylistChecksum)
+
+	return reconcile.Result{}, nil
+}
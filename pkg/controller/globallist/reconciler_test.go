@@ -0,0 +1,130 @@
+package globallist
+
+import (
+	"context"
+	"encoding/json"
+
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+var _ = Describe("reconciler", func() {
+	var (
+		ctx          = context.TODO()
+		c            client.Client
+		r            *reconciler
+		allowlistKey = types.NamespacedName{Namespace: "garden", Name: "global-allowlist"}
+		denylistKey  = types.NamespacedName{Namespace: "garden", Name: "global-denylist"}
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		c = fakeclient.NewClientBuilder().
+			WithScheme(scheme).
+			WithIndex(&extensionsv1alpha1.Extension{}, aclcontroller.TypeIndex, aclcontroller.TypeIndexerFunc).
+			WithObjects(
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: allowlistKey.Namespace, Name: allowlistKey.Name},
+					Data:       map[string]string{"cidrs": "10.0.0.0/8"},
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: denylistKey.Namespace, Name: denylistKey.Name},
+					Data:       map[string]string{"cidrs": "192.168.0.0/16"},
+				},
+			).
+			Build()
+		r = &reconciler{client: c, allowlistConfigMap: allowlistKey, denylistConfigMap: denylistKey}
+	})
+
+	// reconciledWith creates an ACL extension with the given rule action,
+	// which was reconciled with the given global CIDRs
+	reconciledWith := func(namespace, action string, allowedCIDRs, deniedCIDRs []string) {
+		extSpec := &extensionspec.ExtensionSpec{
+			Rule: &envoyfilters.ACLRule{Action: action, Type: "remote_ip", Cidrs: []string{"1.2.3.4/32"}},
+		}
+		extSpecJSON, err := json.Marshal(extSpec)
+		Expect(err).NotTo(HaveOccurred())
+
+		extState := &aclcontroller.ExtensionState{}
+		extState.GlobalAllowlistChecksum, extState.GlobalDenylistChecksum = aclcontroller.GlobalListChecksums(extSpec, allowedCIDRs, deniedCIDRs)
+		extStateJSON, err := json.Marshal(extState)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.Create(ctx, &extensionsv1alpha1.Extension{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "acl"},
+			Spec: extensionsv1alpha1.ExtensionSpec{
+				DefaultSpec: extensionsv1alpha1.DefaultSpec{
+					Type:           aclcontroller.Type,
+					ProviderConfig: &runtime.RawExtension{Raw: extSpecJSON},
+				},
+			},
+			Status: extensionsv1alpha1.ExtensionStatus{
+				DefaultStatus: extensionsv1alpha1.DefaultStatus{
+					State: &runtime.RawExtension{Raw: extStateJSON},
+				},
+			},
+		})).To(Succeed())
+	}
+
+	isTriggered := func(namespace string) bool {
+		ex := &extensionsv1alpha1.Extension{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "acl"}, ex)).To(Succeed())
+		return ex.Annotations[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile
+	}
+
+	It("should only trigger extensions reconciled with different global CIDRs", func() {
+		reconciledWith("shoot--foo--up-to-date", "ALLOW", []string{"10.0.0.0/8"}, []string{"192.168.0.0/16"})
+		reconciledWith("shoot--foo--outdated-allowlist", "ALLOW", []string{"10.1.0.0/16"}, []string{"192.168.0.0/16"})
+		reconciledWith("shoot--foo--outdated-denylist", "ALLOW", []string{"10.0.0.0/8"}, nil)
+		before := testutil.ToFloat64(changedShoots)
+
+		_, err := r.Reconcile(ctx, reconcile.Request{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isTriggered("shoot--foo--up-to-date")).To(BeFalse())
+		Expect(isTriggered("shoot--foo--outdated-allowlist")).To(BeTrue())
+		Expect(isTriggered("shoot--foo--outdated-denylist")).To(BeTrue())
+		Expect(testutil.ToFloat64(changedShoots)).To(Equal(before + 2))
+		Expect(testutil.ToFloat64(activeLists.WithLabelValues(
+			helper.ComputeCIDRsChecksum([]string{"10.0.0.0/8"}), helper.ComputeCIDRsChecksum([]string{"192.168.0.0/16"}),
+		))).To(Equal(1.0))
+		Expect(testutil.CollectAndCount(activeLists)).To(Equal(1))
+	})
+
+	It("should not trigger DENY rules when only the global allowlist changed", func() {
+		reconciledWith("shoot--foo--deny", "DENY", []string{"10.1.0.0/16"}, []string{"192.168.0.0/16"})
+
+		_, err := r.Reconcile(ctx, reconcile.Request{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isTriggered("shoot--foo--deny")).To(BeFalse())
+	})
+
+	It("should trigger DENY rules when the global denylist changed", func() {
+		reconciledWith("shoot--foo--deny", "DENY", []string{"10.0.0.0/8"}, []string{"192.168.1.0/24"})
+
+		_, err := r.Reconcile(ctx, reconcile.Request{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isTriggered("shoot--foo--deny")).To(BeTrue())
+	})
+})
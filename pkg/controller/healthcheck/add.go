@@ -55,12 +55,21 @@ func RegisterHealthChecks(ctx context.Context, mgr manager.Manager, opts *health
 				ConditionType: string(gardencorev1beta1.SeedExtensionsReady),
 				HealthCheck:   general.CheckManagedResource(controller.ResourceNameSeed),
 			},
+			{
+				// the istio version of the gateways is a property of the seed
+				ConditionType: string(gardencorev1beta1.SeedExtensionsReady),
+				HealthCheck:   CheckIstioVersion(),
+			},
 		},
 		sets.New[gardencorev1beta1.ConditionType](),
 	)
 }
 
 // AddToManager adds a controller with the default Options.
+//
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=extensions,verbs=get;list;watch;patch;update
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=extensions/status,verbs=get;list;watch;patch;update
+// +kubebuilder:rbac:groups=resources.gardener.cloud,resources=managedresources,verbs=get;list;watch
 func AddToManager(ctx context.Context, mgr manager.Manager) error {
 	return RegisterHealthChecks(ctx, mgr, &DefaultAddOptions)
 }
@@ -0,0 +1,73 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gardener/gardener/extensions/pkg/controller/healthcheck"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+)
+
+// IstioVersionHealthChecker fails if the filters of a Shoot were rendered for
+// an unknown or unsupported istio version of the ingress gateways, instead of
+// silently relying on filters the gateways might reject.
+type IstioVersionHealthChecker struct {
+	logger     logr.Logger
+	seedClient client.Client
+}
+
+// CheckIstioVersion is a health check function which checks whether the istio
+// version of the ingress gateways serving a Shoot is supported.
+func CheckIstioVersion() healthcheck.HealthCheck {
+	return &IstioVersionHealthChecker{}
+}
+
+// InjectSeedClient injects the seed client
+func (healthChecker *IstioVersionHealthChecker) InjectSeedClient(seedClient client.Client) {
+	healthChecker.seedClient = seedClient
+}
+
+// SetLoggerSuffix injects the logger
+func (healthChecker *IstioVersionHealthChecker) SetLoggerSuffix(provider, extension string) {
+	healthChecker.logger = log.Log.WithName(fmt.Sprintf("%s-%s-healthcheck-istio-version", provider, extension))
+}
+
+// DeepCopy clones the healthCheck struct by making a copy and returning the pointer to that new copy
+func (healthChecker *IstioVersionHealthChecker) DeepCopy() healthcheck.HealthCheck {
+	shallowCopy := *healthChecker
+	return &shallowCopy
+}
+
+// Check executes the health check
+func (healthChecker *IstioVersionHealthChecker) Check(ctx context.Context, request types.NamespacedName) (*healthcheck.SingleCheckResult, error) {
+	ex := &extensionsv1alpha1.Extension{}
+	if err := healthChecker.seedClient.Get(ctx, request, ex); err != nil {
+		return nil, fmt.Errorf("unable to retrieve extension %q: %w", request, err)
+	}
+
+	extState, err := controller.GetExtensionState(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	// the version isn't known before the first reconciliation of this
+	// version of the extension, or while the ACL is disabled
+	if extState.IstioVersion != nil && extState.IstioVersion.Message != "" {
+		return &healthcheck.SingleCheckResult{
+			Status: gardencorev1beta1.ConditionFalse,
+			Detail: "ACL filters might be incompatible with the istio ingress gateways: " + extState.IstioVersion.Message,
+			Codes:  []gardencorev1beta1.ErrorCode{gardencorev1beta1.ErrorConfigurationProblem},
+		}, nil
+	}
+
+	return &healthcheck.SingleCheckResult{
+		Status: gardencorev1beta1.ConditionTrue,
+	}, nil
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+// MaxHistoryEntries is the number of changes of the applied rule set which
+// are kept in the status of the Extension.
+const MaxHistoryEntries = 10
+
+// HistoryEntry is a change of the rule set applied for a shoot.
+type HistoryEntry struct {
+	// Generation is the generation of the Extension the rule set was first
+	// applied for. It doesn't change if the rule set changed because of other
+	// sources, e.g. the global allowlist.
+	Generation int64 `json:"generation"`
+	// Checksum is the checksum of the applied rules, the global denylist and
+	// the allowlist. It is empty if the enforcement of the ACL got disabled.
+	Checksum string `json:"checksum"`
+	// Time is the time the rule set was first applied.
+	Time metav1.Time `json:"time"`
+	// Added contains the CIDRs which were added to the allowlist.
+	Added []string `json:"added,omitempty"`
+	// Removed contains the CIDRs which were removed from the allowlist.
+	Removed []string `json:"removed,omitempty"`
+}
+
+// ruleSetChecksum returns the checksum of the rule set applied for a shoot,
+// i.e. its rules, the global denylist and the CIDRs of the allowlist.
+func ruleSetChecksum(spec *extensionspec.ExtensionSpec, allowlist []AllowlistEntry) (string, error) {
+	raw, err := json.Marshal(struct {
+		Rule         *envoyfilters.ACLRule `json:"rule"`
+		InternalRule *envoyfilters.ACLRule `json:"internalRule,omitempty"`
+		DeniedCIDRs  []string              `json:"deniedCIDRs,omitempty"`
+		Allowlist    []string              `json:"allowlist,omitempty"`
+	}{
+		Rule:         spec.Rule,
+		InternalRule: spec.InternalRule,
+		DeniedCIDRs:  spec.Rule.DeniedCIDRs,
+		Allowlist:    sets.List(allowlistCIDRs(allowlist)),
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// recordHistory appends a HistoryEntry for the rule set with the given
+// checksum if it differs from the last applied one, keeping at most
+// MaxHistoryEntries entries. The entry contains the CIDRs added to and removed
+// from the previous allowlist.
+func recordHistory(history []HistoryEntry, generation int64, checksum string, previous, current []AllowlistEntry, now time.Time) []HistoryEntry {
+	if len(history) > 0 && history[len(history)-1].Checksum == checksum {
+		return history
+	}
+
+	previousCIDRs, currentCIDRs := allowlistCIDRs(previous), allowlistCIDRs(current)
+	history = append(history, HistoryEntry{
+		Generation: generation,
+		Checksum:   checksum,
+		Time:       metav1.NewTime(now.UTC().Truncate(time.Second)),
+		Added:      sets.List(currentCIDRs.Difference(previousCIDRs)),
+		Removed:    sets.List(previousCIDRs.Difference(currentCIDRs)),
+	})
+	if len(history) > MaxHistoryEntries {
+		history = history[len(history)-MaxHistoryEntries:]
+	}
+	return history
+}
+
+func allowlistCIDRs(allowlist []AllowlistEntry) sets.Set[string] {
+	cidrs := sets.New[string]()
+	for _, entry := range allowlist {
+		cidrs.Insert(entry.CIDR)
+	}
+	return cidrs
+}
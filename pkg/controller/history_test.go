@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+var _ = Describe("history", func() {
+	var (
+		now       = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		previous  = []AllowlistEntry{{CIDR: "1.2.3.4/32", Source: SourceRule}, {CIDR: "10.250.0.0/16", Source: SourceSeed}}
+		current   = []AllowlistEntry{{CIDR: "5.6.7.8/32", Source: SourceRule}, {CIDR: "10.250.0.0/16", Source: SourceSeed}}
+		spec      = &extensionspec.ExtensionSpec{Rule: &envoyfilters.ACLRule{Action: "ALLOW", Type: "remote_ip", Cidrs: []string{"5.6.7.8/32"}}}
+		checksum1 string
+		checksum2 string
+	)
+
+	BeforeEach(func() {
+		var err error
+		checksum1, err = ruleSetChecksum(spec, previous)
+		Expect(err).NotTo(HaveOccurred())
+		checksum2, err = ruleSetChecksum(spec, current)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should compute different checksums for different allowlists", func() {
+		Expect(checksum1).NotTo(Equal(checksum2))
+
+		reordered, err := ruleSetChecksum(spec, []AllowlistEntry{current[1], current[0]})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reordered).To(Equal(checksum2))
+	})
+
+	It("should record the added and removed CIDRs of a change", func() {
+		history := recordHistory([]HistoryEntry{{Generation: 1, Checksum: checksum1}}, 2, checksum2, previous, current, now)
+
+		Expect(history).To(HaveLen(2))
+		Expect(history[1].Generation).To(Equal(int64(2)))
+		Expect(history[1].Checksum).To(Equal(checksum2))
+		Expect(history[1].Time.Time).To(Equal(now))
+		Expect(history[1].Added).To(Equal([]string{"5.6.7.8/32"}))
+		Expect(history[1].Removed).To(Equal([]string{"1.2.3.4/32"}))
+	})
+
+	It("should not record an unchanged rule set", func() {
+		history := []HistoryEntry{{Generation: 1, Checksum: checksum2}}
+		Expect(recordHistory(history, 2, checksum2, current, current, now)).To(Equal(history))
+	})
+
+	It("should keep at most MaxHistoryEntries entries", func() {
+		var history []HistoryEntry
+		for i := 0; i < MaxHistoryEntries+3; i++ {
+			history = recordHistory(history, int64(i), fmt.Sprintf("checksum-%d", i), previous, current, now)
+		}
+
+		Expect(history).To(HaveLen(MaxHistoryEntries))
+		Expect(history[0].Checksum).To(Equal("checksum-3"))
+		Expect(history[MaxHistoryEntries-1].Checksum).To(Equal(fmt.Sprintf("checksum-%d", MaxHistoryEntries+2)))
+	})
+})
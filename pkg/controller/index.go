@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// TypeIndex is the name of the field index of the Extensions by their
+	// type.
+	TypeIndex = "spec.type"
+	// IstioNamespaceIndex is the name of the field index of the ACL Extensions
+	// by the namespaces of the istio ingress gateways recorded in their state.
+	IstioNamespaceIndex = "status.state.istioNamespaces"
+)
+
+// AddIndexes adds the field indexes of the Extensions to the given indexer.
+// They have to be added before the cache of the manager is started.
+func AddIndexes(ctx context.Context, indexer client.FieldIndexer) error {
+	if err := indexer.IndexField(ctx, &extensionsv1alpha1.Extension{}, TypeIndex, TypeIndexerFunc); err != nil {
+		return err
+	}
+	return indexer.IndexField(ctx, &extensionsv1alpha1.Extension{}, IstioNamespaceIndex, IstioNamespaceIndexerFunc)
+}
+
+// TypeIndexerFunc extracts the type of an Extension.
+func TypeIndexerFunc(obj client.Object) []string {
+	ex, ok := obj.(*extensionsv1alpha1.Extension)
+	if !ok {
+		return nil
+	}
+	return []string{ex.Spec.Type}
+}
+
+// IstioNamespaceIndexerFunc extracts the istio namespaces of an ACL
+// Extension. Extensions with an invalid state aren't indexed, they are
+// reconciled with the current istio namespaces anyway.
+func IstioNamespaceIndexerFunc(obj client.Object) []string {
+	ex, ok := obj.(*extensionsv1alpha1.Extension)
+	if !ok || ex.Spec.Type != Type {
+		return nil
+	}
+	extState, err := GetExtensionState(ex)
+	if err != nil {
+		return nil
+	}
+	return extState.GetIstioNamespaces()
+}
+
+// ListExtensions lists the ACL Extensions using the TypeIndex.
+func ListExtensions(ctx context.Context, c client.Reader, extensions *extensionsv1alpha1.ExtensionList) error {
+	return c.List(ctx, extensions, client.MatchingFields{TypeIndex: Type})
+}
+
+// ListExtensionsForIstioNamespace lists the ACL Extensions whose EnvoyFilters
+// are rendered into the given istio namespace using the IstioNamespaceIndex,
+// e.g. to map events of an istio ingress gateway to the affected shoots.
+func ListExtensionsForIstioNamespace(
+	ctx context.Context, c client.Reader, istioNamespace string, extensions *extensionsv1alpha1.ExtensionList,
+) error {
+	return c.List(ctx, extensions, client.MatchingFields{IstioNamespaceIndex: istioNamespace})
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("field indexes", func() {
+	newExtension := func(namespace, extensionType string, extState *ExtensionState) *extensionsv1alpha1.Extension {
+		ex := &extensionsv1alpha1.Extension{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: extensionType},
+			Spec: extensionsv1alpha1.ExtensionSpec{
+				DefaultSpec: extensionsv1alpha1.DefaultSpec{Type: extensionType},
+			},
+		}
+		if extState != nil {
+			raw, err := json.Marshal(extState)
+			Expect(err).NotTo(HaveOccurred())
+			ex.Status.State = &runtime.RawExtension{Raw: raw}
+		}
+		return ex
+	}
+
+	Describe("#IstioNamespaceIndexerFunc", func() {
+		It("should index the istio namespaces of ACL extensions", func() {
+			ex := newExtension("shoot--foo--bar", Type, &ExtensionState{IstioNamespaces: []string{"istio-ingress", "istio-ingress--zone-a"}})
+			Expect(IstioNamespaceIndexerFunc(ex)).To(ConsistOf("istio-ingress", "istio-ingress--zone-a"))
+		})
+
+		It("should fall back to the single istio namespace of older states", func() {
+			ex := newExtension("shoot--foo--bar", Type, &ExtensionState{IstioNamespace: ptr.To("istio-ingress")})
+			Expect(IstioNamespaceIndexerFunc(ex)).To(ConsistOf("istio-ingress"))
+		})
+
+		It("should not index other extensions or invalid states", func() {
+			Expect(IstioNamespaceIndexerFunc(newExtension("shoot--foo--bar", "other", &ExtensionState{IstioNamespaces: []string{"istio-ingress"}}))).To(BeEmpty())
+
+			ex := newExtension("shoot--foo--bar", Type, nil)
+			ex.Status.State = &runtime.RawExtension{Raw: []byte("{")}
+			Expect(IstioNamespaceIndexerFunc(ex)).To(BeEmpty())
+		})
+	})
+
+	It("should list the ACL extensions by type and istio namespace", func() {
+		ctx := context.TODO()
+		c := fakeclient.NewClientBuilder().
+			WithScheme(clientScheme).
+			WithIndex(&extensionsv1alpha1.Extension{}, TypeIndex, TypeIndexerFunc).
+			WithIndex(&extensionsv1alpha1.Extension{}, IstioNamespaceIndex, IstioNamespaceIndexerFunc).
+			WithObjects(
+				newExtension("shoot--foo--a", Type, &ExtensionState{IstioNamespaces: []string{"istio-ingress"}}),
+				newExtension("shoot--foo--b", Type, &ExtensionState{IstioNamespaces: []string{"istio-ingress--zone-a"}}),
+				newExtension("shoot--foo--c", Type, nil),
+				newExtension("shoot--foo--a", "other", &ExtensionState{IstioNamespaces: []string{"istio-ingress"}}),
+			).
+			Build()
+
+		namespaces := func(extensions *extensionsv1alpha1.ExtensionList) []string {
+			var result []string
+			for _, ex := range extensions.Items {
+				result = append(result, ex.Namespace)
+			}
+			return result
+		}
+
+		extensions := &extensionsv1alpha1.ExtensionList{}
+		Expect(ListExtensions(ctx, c, extensions)).To(Succeed())
+		Expect(namespaces(extensions)).To(ConsistOf("shoot--foo--a", "shoot--foo--b", "shoot--foo--c"))
+
+		extensions = &extensionsv1alpha1.ExtensionList{}
+		Expect(ListExtensionsForIstioNamespace(ctx, c, "istio-ingress", extensions)).To(Succeed())
+		Expect(namespaces(extensions)).To(ConsistOf("shoot--foo--a"))
+		Expect(extensions.Items[0].Spec.Type).To(Equal(Type))
+	})
+
+	It("should add the indexes to the indexer", func() {
+		indexer := &fakeIndexer{}
+		Expect(AddIndexes(context.TODO(), indexer)).To(Succeed())
+		Expect(indexer.fields).To(ConsistOf(TypeIndex, IstioNamespaceIndex))
+	})
+})
+
+type fakeIndexer struct {
+	fields []string
+}
+
+func (f *fakeIndexer) IndexField(_ context.Context, _ client.Object, field string, _ client.IndexerFunc) error {
+	f.fields = append(f.fields, field)
+	return nil
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1helper "github.com/gardener/gardener/pkg/apis/core/v1beta1/helper"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	reconcilerutils "github.com/gardener/gardener/pkg/controllerutils/reconciler"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+const (
+	// ConditionTypeIncompatibleSeed is the condition of the Extension which is
+	// true if the seed lacks prerequisites of the extension.
+	ConditionTypeIncompatibleSeed gardencorev1beta1.ConditionType = "IncompatibleSeed"
+	// ReasonIstioNotInstalled is the reason of the IncompatibleSeed condition
+	// if the istio CRDs are missing.
+	ReasonIstioNotInstalled = "IstioNotInstalled"
+	// ReasonIstioInstalled is the reason of the IncompatibleSeed condition
+	// once istio is installed.
+	ReasonIstioInstalled = "IstioInstalled"
+
+	istioRetryPeriod = time.Minute
+)
+
+// ErrIstioNotInstalled is returned for seeds without istio.
+var ErrIstioNotInstalled = errors.New("istio is not installed in the seed (the networking.istio.io EnvoyFilter and Gateway CRDs are missing), " +
+	"the ACL is enforced by the istio ingress gateways, so istio has to be installed in the seed before the ACL can be applied, " +
+	"the extension retries periodically")
+
+// ensureIstioInstalled checks whether istio is installed in the seed and
+// records the result in the IncompatibleSeed condition of the extension. If
+// istio is missing, the returned error requeues the extension, so the ACL is
+// applied once istio is installed later on.
+func (a *actuator) ensureIstioInstalled(ctx context.Context, ex *extensionsv1alpha1.Extension) error {
+	installed, err := helper.IsIstioInstalled(a.client.RESTMapper())
+	if err != nil {
+		return err
+	}
+
+	if err := a.updateIncompatibleSeedCondition(ctx, ex, installed); err != nil {
+		return err
+	}
+
+	if !installed {
+		return &reconcilerutils.RequeueAfterError{Cause: ErrIstioNotInstalled, RequeueAfter: istioRetryPeriod}
+	}
+	return nil
+}
+
+func (a *actuator) updateIncompatibleSeedCondition(ctx context.Context, ex *extensionsv1alpha1.Extension, istioInstalled bool) error {
+	condition := v1beta1helper.GetCondition(ex.Status.Conditions, ConditionTypeIncompatibleSeed)
+	if condition == nil {
+		if istioInstalled {
+			// compatible seeds don't get the condition at all
+			return nil
+		}
+		initCondition := v1beta1helper.InitConditionWithClock(clock.RealClock{}, ConditionTypeIncompatibleSeed)
+		condition = &initCondition
+	}
+
+	var updated gardencorev1beta1.Condition
+	if istioInstalled {
+		updated = v1beta1helper.UpdatedConditionWithClock(clock.RealClock{}, *condition, gardencorev1beta1.ConditionFalse,
+			ReasonIstioInstalled, "istio is installed in the seed")
+	} else {
+		updated = v1beta1helper.UpdatedConditionWithClock(clock.RealClock{}, *condition, gardencorev1beta1.ConditionTrue,
+			ReasonIstioNotInstalled, ErrIstioNotInstalled.Error())
+	}
+	if v1beta1helper.ConditionsNeedUpdate([]gardencorev1beta1.Condition{*condition}, []gardencorev1beta1.Condition{updated}) {
+		patch := client.MergeFrom(ex.DeepCopy())
+		ex.Status.Conditions = v1beta1helper.MergeConditions(ex.Status.Conditions, updated)
+		return a.client.Status().Patch(ctx, ex, patch)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+// IstioVersionStatus contains the istio version of the ingress gateways
+// serving a shoot.
+type IstioVersionStatus struct {
+	// Version is the istio release of the ingress gateways, e.g. "1.21". It is
+	// empty if the version couldn't be detected. If the gateways run different
+	// releases, e.g. during an upgrade, it is the oldest one.
+	Version string `json:"version,omitempty"`
+	// Message explains why the version is unknown or unsupported. The health
+	// check fails while it is set, as the filters might be rejected by the
+	// gateways.
+	Message string `json:"message,omitempty"`
+}
+
+// detectIstioVersion detects the istio version of the ingress gateways in the
+// given namespaces and returns the renderings of the filters compatible with
+// it. The filters are rendered with the envoyfilters.DefaultRenderings if the
+// version is unknown, which is reported in the message of the status instead
+// of failing the reconciliation, so the ACL is still enforced as far as
+// possible.
+func (a *actuator) detectIstioVersion(
+	ctx context.Context, istioNamespaces []string, istioLabels map[string]string,
+) (*IstioVersionStatus, envoyfilters.Renderings, error) {
+	var oldest *envoyfilters.IstioVersion
+	for _, istioNamespace := range istioNamespaces {
+		tags, err := helper.DetectIstioVersions(ctx, a.client, istioNamespace, istioLabels)
+		if err != nil {
+			return nil, envoyfilters.Renderings{}, err
+		}
+		for _, tag := range tags {
+			version, err := envoyfilters.ParseIstioVersion(tag)
+			if err != nil {
+				return &IstioVersionStatus{
+					Message: fmt.Sprintf("the istio version of the ingress gateways in namespace %s is unknown: %v", istioNamespace, err),
+				}, envoyfilters.DefaultRenderings, nil
+			}
+			if oldest == nil || !version.AtLeast(*oldest) {
+				oldest = &version
+			}
+		}
+	}
+	if oldest == nil {
+		return &IstioVersionStatus{
+			Message: "the istio version of the ingress gateways is unknown, no istio-proxy containers were found",
+		}, envoyfilters.DefaultRenderings, nil
+	}
+
+	status := &IstioVersionStatus{Version: oldest.String()}
+	if !oldest.IsSupported() {
+		status.Message = fmt.Sprintf("istio %s of the ingress gateways is not supported, the filters are only known to be compatible with istio %s to %s",
+			oldest, envoyfilters.MinIstioVersion, envoyfilters.MaxIstioVersion)
+	}
+	return status, envoyfilters.RenderingsFor(*oldest), nil
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gardener/gardener/extensions/pkg/controller"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+const (
+	metricsNamespace = "acl"
+	metricsSubsystem = "controller"
+
+	// ResultSuccess is the result label of successful reconciliations.
+	ResultSuccess = "success"
+	// ResultError is the result label of failed reconciliations.
+	ResultError = "error"
+)
+
+var (
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "reconcile_duration_seconds",
+			Help:      "Duration of the reconciliation of ACL extensions, partitioned by shoot and result.",
+			Buckets:   []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"shoot", "result"},
+	)
+	shootsWithACL = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "shoots",
+			Help:      "Number of shoots with the ACL extension reconciled by this instance.",
+		},
+	)
+	renderedCIDRs = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "rendered_cidrs",
+			Help:      "Number of CIDRs rendered into the API server rule of a shoot, including the always allowed and denied CIDRs.",
+		},
+		[]string{"shoot"},
+	)
+	openPolicy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "open_policy",
+			Help:      "Whether the ACL of a shoot is effectively open (1) or restricts the sources (0), partitioned by shoot and shoot purpose.",
+		},
+		[]string{"shoot", "purpose"},
+	)
+
+	// reconciledShoots tracks the shoots counted by shootsWithACL, as a shoot
+	// is reconciled many times during its lifetime
+	reconciledShoots      = sets.New[string]()
+	reconciledShootsMutex sync.Mutex
+)
+
+func init() {
+	metrics.Registry.MustRegister(reconcileDuration, shootsWithACL, renderedCIDRs, openPolicy)
+}
+
+// observeReconcile records the duration and result of a reconciliation of the
+// ACL extension in the given shoot namespace.
+func observeReconcile(shoot string, start time.Time, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultError
+	}
+	reconcileDuration.WithLabelValues(shoot, result).Observe(time.Since(start).Seconds())
+}
+
+// recordShoot records a successfully reconciled shoot, the number of CIDRs
+// rendered for it and whether its ACL is effectively open.
+func recordShoot(shoot, purpose string, cidrs int, open bool) {
+	renderedCIDRs.WithLabelValues(shoot).Set(float64(cidrs))
+	// the purpose of a shoot can change, so drop the series of the old one
+	openPolicy.DeletePartialMatch(prometheus.Labels{"shoot": shoot})
+	openPolicyValue := 0.0
+	if open {
+		openPolicyValue = 1
+	}
+	openPolicy.WithLabelValues(shoot, purpose).Set(openPolicyValue)
+
+	reconciledShootsMutex.Lock()
+	defer reconciledShootsMutex.Unlock()
+	reconciledShoots.Insert(shoot)
+	shootsWithACL.Set(float64(reconciledShoots.Len()))
+}
+
+// forgetShoot removes all metrics of a shoot whose ACL extension got deleted.
+func forgetShoot(shoot string) {
+	renderedCIDRs.DeleteLabelValues(shoot)
+	openPolicy.DeletePartialMatch(prometheus.Labels{"shoot": shoot})
+	reconcileDuration.DeletePartialMatch(prometheus.Labels{"shoot": shoot})
+
+	reconciledShootsMutex.Lock()
+	defer reconciledShootsMutex.Unlock()
+	reconciledShoots.Delete(shoot)
+	shootsWithACL.Set(float64(reconciledShoots.Len()))
+}
+
+// isOpenPolicy returns true if the rule renders an effectively open policy,
+// i.e. if it doesn't restrict other sources at all ("DENY" rules) or if it
+// allows access from the whole address space.
+func isOpenPolicy(rule *envoyfilters.ACLRule) bool {
+	if !rule.RestrictsOtherSources() {
+		return true
+	}
+	return strings.EqualFold(rule.Action, envoyfilters.ActionAllow) && rule.MatchesEverything()
+}
+
+// shootPurpose returns the purpose of the cluster's shoot, or "evaluation",
+// the default purpose of shoots, if none is set.
+func shootPurpose(cluster *controller.Cluster) string {
+	return helper.GetShootPurpose(cluster.Shoot)
+}
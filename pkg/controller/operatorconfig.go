@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+// OperatorConfigChecksum returns the checksum of the settings of the operator
+// configuration which change the objects rendered for every shoot, i.e. the
+// always allowed CIDRs and the enforcement options. The global allowlist and
+// denylist are covered by GlobalListChecksums, as they can change at runtime.
+func OperatorConfigChecksum(cfg config.Config) string {
+	settings := fmt.Sprintf("%s;%s;%t;%s;%t;%t;%s;%s;%s/%s",
+		helper.ComputeCIDRsChecksum(cfg.AdditionalAllowedCIDRs),
+		helper.ComputeCIDRsChecksum(cfg.GardenEgressCIDRs),
+		cfg.AutoAllowInfrastructureEgressCIDRs,
+		cfg.EnforcementBackend,
+		cfg.AggregateEnvoyFilters,
+		cfg.LogDeniedConnections,
+		cfg.ClientIPPreservation,
+		cfg.APIServerGatewayName,
+		cfg.IngressGatewayNamespace, cfg.IngressGatewayName,
+	)
+	sum := sha256.Sum256([]byte(settings))
+	return hex.EncodeToString(sum[:])
+}
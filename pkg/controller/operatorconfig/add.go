@@ -0,0 +1,78 @@
+// Package operatorconfig contains a controller that triggers a reconciliation
+// of the ACL extensions which were reconciled with another operator
+// configuration, e.g. other always allowed CIDRs, instead of waiting for the
+// maintenance window of every shoot.
+package operatorconfig
+
+import (
+	"context"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	controllerconfig "github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
+)
+
+// ControllerName is the name of the operator configuration controller.
+const ControllerName = "acl-operator-config"
+
+var (
+	// DefaultAddOptions are the default AddOptions for AddToManager.
+	DefaultAddOptions = AddOptions{}
+)
+
+// AddOptions are options to apply when adding the operator configuration
+// controller to the manager.
+type AddOptions struct {
+	// ControllerOptions contains options for the controller.
+	ControllerOptions controller.Options
+	// ExtensionConfig is the configuration of the extension controller the
+	// extensions are compared with.
+	ExtensionConfig controllerconfig.Config
+}
+
+// AddToManager adds a controller with the default Options to the given Controller Manager.
+//
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=extensions,verbs=get;list;watch;patch
+func AddToManager(ctx context.Context, mgr manager.Manager) error {
+	return AddToManagerWithOptions(ctx, mgr, &DefaultAddOptions)
+}
+
+// AddToManagerWithOptions adds a controller with the given Options to the
+// given manager. The operator configuration is read from a file, whose
+// changes restart the extension (see the checksum annotation of its
+// Deployment), so the extensions only need to be compared when they are
+// added to the cache on startup.
+func AddToManagerWithOptions(_ context.Context, mgr manager.Manager, opts *AddOptions) error {
+	checksum := aclcontroller.OperatorConfigChecksum(opts.ExtensionConfig)
+	activeConfig.WithLabelValues(checksum).Set(1)
+
+	return builder.ControllerManagedBy(mgr).
+		Named(ControllerName).
+		WithOptions(opts.ControllerOptions).
+		For(&extensionsv1alpha1.Extension{}, builder.WithPredicates(ACLExtensionCreated())).
+		Complete(&reconciler{
+			client:   mgr.GetClient(),
+			checksum: checksum,
+		})
+}
+
+// ACLExtensionCreated only passes the create events of ACL extensions, which
+// are also emitted for all existing extensions when the cache is started.
+func ACLExtensionCreated() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			ex, ok := e.Object.(*extensionsv1alpha1.Extension)
+			return ok && ex.Spec.Type == aclcontroller.Type
+		},
+		UpdateFunc:  func(event.UpdateEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+}
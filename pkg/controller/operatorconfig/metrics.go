@@ -0,0 +1,30 @@
+package operatorconfig
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	changedShoots = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "acl",
+			Subsystem: "operator_config",
+			Name:      "changed_shoots_total",
+			Help:      "Number of shoots re-rendered because the operator configuration of the extension changed.",
+		},
+	)
+	activeConfig = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "acl",
+			Subsystem: "operator_config",
+			Name:      "active_info",
+			Help:      "Checksum of the operator configuration the extension is running with, always 1.",
+		},
+		[]string{"checksum"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(changedShoots, activeConfig)
+}
@@ -0,0 +1,66 @@
+package operatorconfig
+
+import (
+	"context"
+	"encoding/json"
+
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+type reconciler struct {
+	client   client.Client
+	checksum string
+}
+
+// Reconcile annotates the ACL extension with the reconcile operation
+// annotation if it was reconciled with another operator configuration.
+// Extensions which were never reconciled and disabled ACLs are left
+// untouched.
+func (r *reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	ex := &extensionsv1alpha1.Extension{}
+	if err := r.client.Get(ctx, req.NamespacedName, ex); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if ex.Spec.Type != aclcontroller.Type || !ex.DeletionTimestamp.IsZero() || ex.Status.State == nil ||
+		ex.Annotations[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile {
+		return reconcile.Result{}, nil
+	}
+
+	extSpec := &extensionspec.ExtensionSpec{}
+	if ex.Spec.ProviderConfig != nil && ex.Spec.ProviderConfig.Raw != nil {
+		if err := json.Unmarshal(ex.Spec.ProviderConfig.Raw, extSpec); err != nil {
+			extSpec = &extensionspec.ExtensionSpec{}
+		}
+	}
+	if aclcontroller.IsDisabled(ex, extSpec) {
+		return reconcile.Result{}, nil
+	}
+
+	extState, err := aclcontroller.GetExtensionState(ex)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if extState.OperatorConfigChecksum == r.checksum {
+		return reconcile.Result{}, nil
+	}
+
+	logf.FromContext(ctx).Info("Triggering reconciliation because of changed operator configuration")
+
+	patch := client.MergeFrom(ex.DeepCopy())
+	metav1.SetMetaDataAnnotation(&ex.ObjectMeta, v1beta1constants.GardenerOperation, v1beta1constants.GardenerOperationReconcile)
+	if err := r.client.Patch(ctx, ex, patch); client.IgnoreNotFound(err) != nil {
+		return reconcile.Result{}, err
+	}
+
+	changedShoots.Inc()
+	return reconcile.Result{}, nil
+}
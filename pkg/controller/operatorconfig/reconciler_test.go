@@ -0,0 +1,119 @@
+package operatorconfig
+
+import (
+	"context"
+	"encoding/json"
+
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	controllerconfig "github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
+)
+
+var _ = Describe("reconciler", func() {
+	const namespace = "shoot--foo--bar"
+
+	var (
+		ctx = context.TODO()
+		c   client.Client
+		r   *reconciler
+		key = types.NamespacedName{Namespace: namespace, Name: aclcontroller.Type}
+
+		currentConfig = controllerconfig.Config{AdditionalAllowedCIDRs: []string{"10.0.0.0/8"}}
+	)
+
+	// reconciledWith creates an ACL extension, which was reconciled with the
+	// given operator configuration
+	reconciledWith := func(providerConfig string, cfg controllerconfig.Config) {
+		extStateJSON, err := json.Marshal(&aclcontroller.ExtensionState{OperatorConfigChecksum: aclcontroller.OperatorConfigChecksum(cfg)})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.Create(ctx, &extensionsv1alpha1.Extension{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: aclcontroller.Type},
+			Spec: extensionsv1alpha1.ExtensionSpec{
+				DefaultSpec: extensionsv1alpha1.DefaultSpec{
+					Type:           aclcontroller.Type,
+					ProviderConfig: &runtime.RawExtension{Raw: []byte(providerConfig)},
+				},
+			},
+			Status: extensionsv1alpha1.ExtensionStatus{
+				DefaultStatus: extensionsv1alpha1.DefaultStatus{
+					State: &runtime.RawExtension{Raw: extStateJSON},
+				},
+			},
+		})).To(Succeed())
+	}
+
+	isTriggered := func() bool {
+		ex := &extensionsv1alpha1.Extension{}
+		Expect(c.Get(ctx, key, ex)).To(Succeed())
+		return ex.Annotations[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		c = fakeclient.NewClientBuilder().WithScheme(scheme).Build()
+		r = &reconciler{client: c, checksum: aclcontroller.OperatorConfigChecksum(currentConfig)}
+	})
+
+	It("should not trigger an extension reconciled with the current configuration", func() {
+		reconciledWith(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/32"]}}`, currentConfig)
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isTriggered()).To(BeFalse())
+	})
+
+	It("should trigger an extension reconciled with other always allowed CIDRs", func() {
+		reconciledWith(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/32"]}}`, controllerconfig.Config{})
+		before := testutil.ToFloat64(changedShoots)
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isTriggered()).To(BeTrue())
+		Expect(testutil.ToFloat64(changedShoots)).To(Equal(before + 1))
+	})
+
+	It("should not trigger disabled ACLs", func() {
+		reconciledWith(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.4/32"]},"disabled":true}`, controllerconfig.Config{})
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isTriggered()).To(BeFalse())
+	})
+
+	It("should ignore shoots without ACL extension", func() {
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("#ACLExtensionCreated", func() {
+		It("should only pass the creation of ACL extensions", func() {
+			p := ACLExtensionCreated()
+			acl := &extensionsv1alpha1.Extension{Spec: extensionsv1alpha1.ExtensionSpec{DefaultSpec: extensionsv1alpha1.DefaultSpec{Type: aclcontroller.Type}}}
+			other := &extensionsv1alpha1.Extension{Spec: extensionsv1alpha1.ExtensionSpec{DefaultSpec: extensionsv1alpha1.DefaultSpec{Type: "other"}}}
+
+			Expect(p.Create(event.CreateEvent{Object: acl})).To(BeTrue())
+			Expect(p.Create(event.CreateEvent{Object: other})).To(BeFalse())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: acl, ObjectNew: acl})).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"errors"
+	"time"
+
+	reconcilerutils "github.com/gardener/gardener/pkg/controllerutils/reconciler"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// ErrGatewayNotFound is returned while the istio Gateway of the shoot's API
+// server doesn't exist, e.g. during the creation of the shoot.
+var ErrGatewayNotFound = errors.New("the istio Gateway of the API server was not found")
+
+// rejectedRuleError is returned for rules of the providerConfig which are
+// invalid, they are only fixed by updating the shoot.
+type rejectedRuleError struct {
+	error
+}
+
+func (e *rejectedRuleError) Unwrap() error {
+	return e.error
+}
+
+// requeueAfter returns the given error of a reconciliation as a
+// RequeueAfterError with the interval configured for its error class, so
+// e.g. a missing istio Gateway during the creation of a shoot is retried
+// quickly while rejected rules don't hot-loop. Errors of unknown classes and
+// classes without an interval are returned as is and retried with the
+// exponential backoff of the controller.
+func (a *actuator) requeueAfter(err error) error {
+	if err == nil {
+		return nil
+	}
+	var requeueAfterErr *reconcilerutils.RequeueAfterError
+	if errors.As(err, &requeueAfterErr) {
+		return err
+	}
+
+	var (
+		period      time.Duration
+		rejectedErr *rejectedRuleError
+	)
+	switch {
+	case errors.As(err, &rejectedErr):
+		period = a.extensionConfig.RequeueRulesRejected
+	case errors.Is(err, ErrGatewayNotFound):
+		period = a.extensionConfig.RequeueGatewayNotFound
+	case errors.Is(err, ErrNoAdvertisedAddresses):
+		period = a.extensionConfig.RequeueClusterNotReady
+	case apierrors.IsConflict(err):
+		period = a.extensionConfig.RequeueConflict
+	}
+	if period <= 0 {
+		return err
+	}
+	return &reconcilerutils.RequeueAfterError{Cause: err, RequeueAfter: period}
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"encoding/json"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+const (
+	// AnnotationRuleDescription is the annotation of the rendered objects
+	// containing the description of the rule.
+	AnnotationRuleDescription = "acl.stackit.cloud/rule-description"
+	// AnnotationRuleLabels is the annotation of the rendered objects
+	// containing the labels of the rule as JSON object.
+	AnnotationRuleLabels = "acl.stackit.cloud/rule-labels"
+	// AnnotationInternalRuleDescription is the annotation of the rendered
+	// objects containing the description of the internal rule.
+	AnnotationInternalRuleDescription = "acl.stackit.cloud/internal-rule-description"
+	// AnnotationInternalRuleLabels is the annotation of the rendered objects
+	// containing the labels of the internal rule as JSON object.
+	AnnotationInternalRuleLabels = "acl.stackit.cloud/internal-rule-labels"
+)
+
+// RuleMetadata is the description and the labels of a rule of the
+// providerConfig.
+type RuleMetadata struct {
+	// Name is the field of the rule in the providerConfig, i.e. "rule" or
+	// "internalRule".
+	Name string `json:"name"`
+	// Description is the description of the rule.
+	Description string `json:"description,omitempty"`
+	// Labels are the labels of the rule.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// rulesMetadata returns the metadata of the rules of the spec which have a
+// description or labels.
+func rulesMetadata(spec *extensionspec.ExtensionSpec) []RuleMetadata {
+	var metadata []RuleMetadata
+	for _, rule := range []struct {
+		name string
+		rule *envoyfilters.ACLRule
+	}{
+		{"rule", spec.Rule},
+		{"internalRule", spec.InternalRule},
+	} {
+		if rule.rule == nil || (rule.rule.Description == "" && len(rule.rule.Labels) == 0) {
+			continue
+		}
+		metadata = append(metadata, RuleMetadata{Name: rule.name, Description: rule.rule.Description, Labels: rule.rule.Labels})
+	}
+	return metadata
+}
+
+// ruleAnnotations returns the annotations of the rendered objects containing
+// the description and the labels of the rule under the given keys, or nil if
+// the rule has neither.
+func ruleAnnotations(rule *envoyfilters.ACLRule, descriptionKey, labelsKey string) (map[string]interface{}, error) {
+	if rule == nil || (rule.Description == "" && len(rule.Labels) == 0) {
+		return nil, nil
+	}
+
+	annotations := map[string]interface{}{}
+	if rule.Description != "" {
+		annotations[descriptionKey] = rule.Description
+	}
+	if len(rule.Labels) > 0 {
+		labels, err := json.Marshal(rule.Labels)
+		if err != nil {
+			return nil, err
+		}
+		annotations[labelsKey] = string(labels)
+	}
+	return annotations, nil
+}
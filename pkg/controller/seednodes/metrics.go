@@ -0,0 +1,19 @@
+package seednodes
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var changedShoots = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: "acl",
+		Subsystem: "seed_nodes",
+		Name:      "changed_shoots_total",
+		Help:      "Number of shoots re-rendered because the addresses of the seed's nodes changed.",
+	},
+)
+
+func init() {
+	metrics.Registry.MustRegister(changedShoots)
+}
@@ -0,0 +1,105 @@
+// Package seednodes contains a controller that watches the nodes of the seed
+// and triggers a reconciliation of the ACL extensions whose allowlist doesn't
+// contain the current addresses of the nodes, so that the seed components can
+// always reach the API servers of the shoots.
+package seednodes
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+type reconciler struct {
+	client client.Client
+}
+
+// Reconcile annotates every ACL extension whose allowlist doesn't match the
+// current addresses of the seed's nodes with the reconcile operation
+// annotation, which makes the extension controller re-render its
+// EnvoyFilters. Extensions whose rule doesn't restrict other sources, e.g.
+// DENY rules, and disabled ACLs are left untouched.
+func (r *reconciler) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	log := logf.FromContext(ctx)
+
+	nodeCIDRs, err := helper.GetSeedNodeCIDRs(ctx, r.client)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	extensions := &extensionsv1alpha1.ExtensionList{}
+	if err := aclcontroller.ListExtensions(ctx, r.client, extensions); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	changed := 0
+	for i := range extensions.Items {
+		ex := &extensions.Items[i]
+		if ex.Spec.Type != aclcontroller.Type || !ex.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		// the extension is already waiting for its reconciliation, which picks
+		// up the current addresses anyway
+		if ex.Annotations[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile {
+			continue
+		}
+
+		extSpec := &extensionspec.ExtensionSpec{}
+		if ex.Spec.ProviderConfig != nil && ex.Spec.ProviderConfig.Raw != nil {
+			if err := json.Unmarshal(ex.Spec.ProviderConfig.Raw, extSpec); err != nil {
+				extSpec = &extensionspec.ExtensionSpec{}
+			}
+		}
+		if aclcontroller.IsDisabled(ex, extSpec) || (extSpec.Rule != nil && !extSpec.Rule.RestrictsOtherSources()) {
+			continue
+		}
+
+		extState, err := aclcontroller.GetExtensionState(ex)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if slices.Equal(allowedNodeCIDRs(extState), nodeCIDRs) {
+			continue
+		}
+
+		log.Info("Triggering reconciliation because of changed addresses of the seed's nodes", "namespace", ex.Namespace)
+
+		patch := client.MergeFrom(ex.DeepCopy())
+		metav1.SetMetaDataAnnotation(&ex.ObjectMeta, v1beta1constants.GardenerOperation, v1beta1constants.GardenerOperationReconcile)
+		if err := r.client.Patch(ctx, ex, patch); client.IgnoreNotFound(err) != nil {
+			return reconcile.Result{}, err
+		}
+
+		changedShoots.Inc()
+		changed++
+	}
+
+	log.Info("Processed addresses of the seed's nodes", "changedShoots", changed)
+
+	return reconcile.Result{}, nil
+}
+
+// allowedNodeCIDRs returns the sorted addresses of the seed's nodes the
+// extension was reconciled with.
+func allowedNodeCIDRs(extState *aclcontroller.ExtensionState) []string {
+	cidrs := sets.New[string]()
+	for _, entry := range extState.Allowlist {
+		if entry.Source == aclcontroller.SourceSeedNodes {
+			cidrs.Insert(entry.CIDR)
+		}
+	}
+	return sets.List(cidrs)
+}
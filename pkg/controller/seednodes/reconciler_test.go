@@ -0,0 +1,137 @@
+package seednodes
+
+import (
+	"context"
+	"encoding/json"
+
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aclcontroller "github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+var _ = Describe("reconciler", func() {
+	var (
+		ctx = context.TODO()
+		c   client.Client
+		r   *reconciler
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		c = fakeclient.NewClientBuilder().
+			WithScheme(scheme).
+			WithIndex(&extensionsv1alpha1.Extension{}, aclcontroller.TypeIndex, aclcontroller.TypeIndexerFunc).
+			WithObjects(
+				&corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+					Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+						{Type: corev1.NodeInternalIP, Address: "10.250.0.1"},
+						{Type: corev1.NodeInternalIP, Address: "10.250.0.3"},
+						{Type: corev1.NodeExternalIP, Address: "203.0.113.1"},
+					}},
+				},
+			).
+			Build()
+		r = &reconciler{client: c}
+	})
+
+	// reconciledWith creates an ACL extension with the given rule action,
+	// which was reconciled with the given addresses of the seed's nodes
+	reconciledWith := func(namespace, action string, nodeCIDRs ...string) {
+		extSpec := &extensionspec.ExtensionSpec{
+			Rule: &envoyfilters.ACLRule{Action: action, Type: "remote_ip", Cidrs: []string{"1.2.3.4/32"}},
+		}
+		extSpecJSON, err := json.Marshal(extSpec)
+		Expect(err).NotTo(HaveOccurred())
+
+		extState := &aclcontroller.ExtensionState{
+			Allowlist: []aclcontroller.AllowlistEntry{{CIDR: "1.2.3.4/32", Source: aclcontroller.SourceRule}},
+		}
+		for _, cidr := range nodeCIDRs {
+			extState.Allowlist = append(extState.Allowlist, aclcontroller.AllowlistEntry{CIDR: cidr, Source: aclcontroller.SourceSeedNodes})
+		}
+		extStateJSON, err := json.Marshal(extState)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.Create(ctx, &extensionsv1alpha1.Extension{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "acl"},
+			Spec: extensionsv1alpha1.ExtensionSpec{
+				DefaultSpec: extensionsv1alpha1.DefaultSpec{
+					Type:           aclcontroller.Type,
+					ProviderConfig: &runtime.RawExtension{Raw: extSpecJSON},
+				},
+			},
+			Status: extensionsv1alpha1.ExtensionStatus{
+				DefaultStatus: extensionsv1alpha1.DefaultStatus{
+					State: &runtime.RawExtension{Raw: extStateJSON},
+				},
+			},
+		})).To(Succeed())
+	}
+
+	isTriggered := func(namespace string) bool {
+		ex := &extensionsv1alpha1.Extension{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "acl"}, ex)).To(Succeed())
+		return ex.Annotations[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile
+	}
+
+	It("should only trigger extensions reconciled with different node addresses", func() {
+		reconciledWith("shoot--foo--up-to-date", "ALLOW", "10.250.0.3/32", "10.250.0.1/32")
+		reconciledWith("shoot--foo--removed-node", "ALLOW", "10.250.0.1/32", "10.250.0.2/32", "10.250.0.3/32")
+		reconciledWith("shoot--foo--added-node", "ALLOW", "10.250.0.1/32")
+		before := testutil.ToFloat64(changedShoots)
+
+		_, err := r.Reconcile(ctx, reconcile.Request{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isTriggered("shoot--foo--up-to-date")).To(BeFalse())
+		Expect(isTriggered("shoot--foo--removed-node")).To(BeTrue())
+		Expect(isTriggered("shoot--foo--added-node")).To(BeTrue())
+		Expect(testutil.ToFloat64(changedShoots)).To(Equal(before + 2))
+	})
+
+	It("should not trigger DENY rules", func() {
+		reconciledWith("shoot--foo--deny", "DENY")
+
+		_, err := r.Reconcile(ctx, reconcile.Request{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isTriggered("shoot--foo--deny")).To(BeFalse())
+	})
+
+	Describe("#NodeAddressesChanged", func() {
+		node := func(addresses ...string) *corev1.Node {
+			n := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+			for _, address := range addresses {
+				n.Status.Addresses = append(n.Status.Addresses, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: address})
+			}
+			return n
+		}
+
+		It("should only pass updates changing the addresses", func() {
+			p := NodeAddressesChanged()
+			Expect(p.Create(event.CreateEvent{Object: node("10.250.0.1")})).To(BeTrue())
+			Expect(p.Delete(event.DeleteEvent{Object: node("10.250.0.1")})).To(BeTrue())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: node("10.250.0.1"), ObjectNew: node("10.250.0.1")})).To(BeFalse())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: node("10.250.0.1"), ObjectNew: node("10.250.0.2")})).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gardener/gardener/pkg/utils/managedresources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+const (
+	// ResourceNameShoot is the name of the ManagedResource deploying the
+	// ShootInfoConfigMapName ConfigMap into the shoot.
+	ResourceNameShoot = "acl-shoot"
+	// ShootInfoConfigMapName is the name of the ConfigMap in the kube-system
+	// namespace of the shoot which describes the ACL enforced for the shoot.
+	ShootInfoConfigMapName = "acl-info"
+	// shootResourcesOrigin identifies the ManagedResources of the shoot
+	// created by the extension.
+	shootResourcesOrigin = "gardener-extension-acl"
+)
+
+// shootInfoData returns the data of the ShootInfoConfigMapName ConfigMap for
+// the given (already validated) ExtensionSpec, a nil spec describes a shoot
+// whose ACL is disabled. Only the rules of the providerConfig are published,
+// the always allowed and globally denied CIDRs are configured by the operators
+// and contain networks of the seed and the garden.
+func shootInfoData(spec *extensionspec.ExtensionSpec, enforcementBackend string) map[string]string {
+	if spec == nil || spec.Rule == nil {
+		return map[string]string{"enforced": "false"}
+	}
+
+	targets := make([]string, 0, len(spec.Targets()))
+	for _, target := range spec.Targets() {
+		targets = append(targets, string(target))
+	}
+	data := map[string]string{
+		"enforced":           "true",
+		"enforcementBackend": enforcementBackend,
+		"targets":            strings.Join(targets, ","),
+	}
+	addRuleInfo(data, "rule", spec.Rule)
+	if spec.InternalRule != nil {
+		addRuleInfo(data, "internalRule", spec.InternalRule)
+	}
+	return data
+}
+
+// addRuleInfo adds the action, type and CIDRs of the rule to the data with
+// the given key prefix, one CIDR per line.
+func addRuleInfo(data map[string]string, prefix string, rule *envoyfilters.ACLRule) {
+	data[prefix+".action"] = strings.ToUpper(rule.Action)
+	data[prefix+".type"] = rule.Type
+	data[prefix+".cidrs"] = strings.Join(rule.Cidrs, "\n")
+	if len(rule.Except) > 0 {
+		data[prefix+".except"] = strings.Join(rule.Except, "\n")
+	}
+}
+
+// deployShootInfo publishes the given data in the ShootInfoConfigMapName
+// ConfigMap of the shoot via the ResourceNameShoot ManagedResource, so the
+// ACL can be inspected from within the shoot. The ManagedResource is deleted
+// if publishing is disabled.
+func (a *actuator) deployShootInfo(ctx context.Context, namespace string, data map[string]string) error {
+	if !a.extensionConfig.PublishShootInfo {
+		return managedresources.DeleteForShoot(ctx, a.client, namespace, ResourceNameShoot)
+	}
+
+	configMap := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: ShootInfoConfigMapName, Namespace: metav1.NamespaceSystem},
+		Data:       data,
+	}
+	manifest, err := yaml.Marshal(configMap)
+	if err != nil {
+		return err
+	}
+	return managedresources.CreateForShoot(ctx, a.client, namespace, ResourceNameShoot, shootResourcesOrigin, false,
+		map[string][]byte{"configmap__kube-system__" + ShootInfoConfigMapName + ".yaml": manifest})
+}
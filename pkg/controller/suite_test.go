@@ -134,8 +134,8 @@ func createNewIstioDeployment(namespace string, labels map[string]string) {
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
 						{
-							Name:  "fake",
-							Image: "pause",
+							Name:  "istio-proxy",
+							Image: "istio/proxyv2:1.21.1-distroless",
 						},
 					},
 				},
@@ -145,6 +145,40 @@ func createNewIstioDeployment(namespace string, labels map[string]string) {
 	Expect(k8sClient.Create(ctx, deployment)).ShouldNot(HaveOccurred())
 }
 
+// setIstioProxyImage sets the image of the istio-proxy containers of the
+// istio ingress gateway Deployments in the namespace.
+func setIstioProxyImage(namespace, image string) {
+	deployments := &appsv1.DeploymentList{}
+	Expect(k8sClient.List(ctx, deployments, client.InNamespace(namespace))).To(Succeed())
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+		deployment.Spec.Template.Spec.Containers[0].Image = image
+		Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
+	}
+}
+
+func createNewIstioService(
+	namespace string, labels, annotations map[string]string, policy corev1.ServiceExternalTrafficPolicy,
+) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "istio-ingressgateway-",
+			Namespace:    namespace,
+			Annotations:  annotations,
+		},
+		Spec: corev1.ServiceSpec{
+			Type:                  corev1.ServiceTypeLoadBalancer,
+			Selector:              labels,
+			ExternalTrafficPolicy: policy,
+			Ports: []corev1.ServicePort{{
+				Name: "tls-tunnel",
+				Port: 8132,
+			}},
+		},
+	}
+	Expect(k8sClient.Create(ctx, service)).ShouldNot(HaveOccurred())
+}
+
 func createNewGateway(name, shootNamespace string, labels map[string]string) *istionetworkingv1beta1.Gateway {
 	gw := &istionetworkingv1beta1.Gateway{
 		ObjectMeta: metav1.ObjectMeta{
@@ -230,6 +264,9 @@ func createNewCluster(shootNamespace string) {
 							Nodes: nil,
 							Pods:  nil,
 						},
+						Provider: gardencorev1beta1.Provider{
+							Workers: []gardencorev1beta1.Worker{{Name: "worker"}},
+						},
 					},
 					Status: gardencorev1beta1.ShootStatus{
 						TechnicalID: shootNamespace,
@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gardener/gardener/extensions/pkg/controller"
+	v1beta1helper "github.com/gardener/gardener/pkg/apis/core/v1beta1/helper"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+// Template variables which can be used in the CIDRs of the rules instead of
+// the CIDRs of the shoot's infrastructure, seed and networks. They are resolved
+// by the controller and the webhook when the rules are rendered, so the same
+// rules can be used for shoots on different infrastructures.
+const (
+	// VariableInfrastructureEgressCIDRs are the egress CIDRs of the shoot's
+	// Infrastructure, nothing while they aren't known.
+	VariableInfrastructureEgressCIDRs = "${infrastructure.egressCIDRs}"
+	// VariableSeedNodes is the node network of the seed.
+	VariableSeedNodes = "${seed.nodes}"
+	// VariableSeedPods is the pod network of the seed.
+	VariableSeedPods = "${seed.pods}"
+	// VariableShootNodes is the node network of the shoot, nothing for
+	// workerless shoots.
+	VariableShootNodes = "${shoot.networks.nodes}"
+	// VariableShootPods is the pod network of the shoot, nothing for
+	// workerless shoots.
+	VariableShootPods = "${shoot.networks.pods}"
+)
+
+// ErrSpecVariable is returned for unknown template variables in the CIDRs of
+// a rule.
+var ErrSpecVariable = errors.New("unknown template variable")
+
+// TemplateVariables returns all supported template variables.
+func TemplateVariables() []string {
+	return []string{
+		VariableInfrastructureEgressCIDRs,
+		VariableSeedNodes,
+		VariableSeedPods,
+		VariableShootNodes,
+		VariableShootPods,
+	}
+}
+
+// IsTemplateVariable returns true if the CIDR of a rule is a template
+// variable, i.e. of the form "${...}". Unknown variables are rejected by the
+// validation of the rule.
+func IsTemplateVariable(cidr string) bool {
+	return strings.HasPrefix(cidr, "${") && strings.HasSuffix(cidr, "}")
+}
+
+// HasTemplateVariables returns true if the CIDRs of a rule of the spec contain
+// a template variable.
+func HasTemplateVariables(spec *extensionspec.ExtensionSpec) bool {
+	for _, rule := range []*envoyfilters.ACLRule{spec.Rule, spec.InternalRule} {
+		if rule != nil && slices.ContainsFunc(rule.Cidrs, IsTemplateVariable) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveTemplateVariables replaces the template variables in the CIDRs of
+// the rules of the spec with the CIDRs of the cluster. The egress CIDRs of
+// the Infrastructure are only read if a rule uses them. CIDRs which are
+// already contained in the rule are skipped, and variables without CIDRs are
+// dropped, so a rule only consisting of such variables is rejected by its
+// validation.
+func ResolveTemplateVariables(
+	ctx context.Context, c client.Reader, ex *extensionsv1alpha1.Extension, cluster *controller.Cluster, spec *extensionspec.ExtensionSpec,
+) error {
+	if !HasTemplateVariables(spec) {
+		return nil
+	}
+
+	values := make(map[string][]string, len(TemplateVariables()))
+	for _, variable := range TemplateVariables() {
+		values[variable] = nil
+	}
+	if nodes := cluster.Seed.Spec.Networks.Nodes; nodes != nil {
+		values[VariableSeedNodes] = []string{*nodes}
+	}
+	if pods := cluster.Seed.Spec.Networks.Pods; pods != "" {
+		values[VariableSeedPods] = []string{pods}
+	}
+	if !v1beta1helper.IsWorkerless(cluster.Shoot) {
+		values[VariableShootNodes] = helper.GetShootNodeSpecificAllowedCIDRs(cluster.Shoot)
+		values[VariableShootPods] = helper.GetShootPodSpecificAllowedCIDRs(cluster.Shoot)
+
+		if usesTemplateVariable(spec, VariableInfrastructureEgressCIDRs) {
+			infra, err := helper.GetInfrastructureForExtension(ctx, c, ex, cluster.Shoot.Name)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			if err == nil {
+				if values[VariableInfrastructureEgressCIDRs], err = helper.GetProviderSpecificAllowedCIDRs(infra); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if err := resolveRuleVariables(spec.Rule, values); err != nil {
+		return err
+	}
+	if spec.InternalRule != nil {
+		if err := resolveRuleVariables(spec.InternalRule, values); err != nil {
+			return fmt.Errorf("internalRule: %w", err)
+		}
+	}
+	return nil
+}
+
+// duplicateTemplateVariable returns the first template variable which is
+// contained in the CIDRs more than once, or an empty string.
+func duplicateTemplateVariable(cidrs []string) string {
+	variables := sets.New[string]()
+	for _, cidr := range cidrs {
+		if !IsTemplateVariable(cidr) {
+			continue
+		}
+		if variables.Has(cidr) {
+			return cidr
+		}
+		variables.Insert(cidr)
+	}
+	return ""
+}
+
+func usesTemplateVariable(spec *extensionspec.ExtensionSpec, variable string) bool {
+	return (spec.Rule != nil && slices.Contains(spec.Rule.Cidrs, variable)) ||
+		(spec.InternalRule != nil && slices.Contains(spec.InternalRule.Cidrs, variable))
+}
+
+func resolveRuleVariables(rule *envoyfilters.ACLRule, values map[string][]string) error {
+	if rule == nil || !slices.ContainsFunc(rule.Cidrs, IsTemplateVariable) {
+		return nil
+	}
+
+	networks := sets.New[string]()
+	for _, cidr := range rule.Cidrs {
+		if !IsTemplateVariable(cidr) {
+			networks.Insert(templateNetwork(cidr))
+		}
+	}
+	cidrs := make([]string, 0, len(rule.Cidrs))
+	for _, cidr := range rule.Cidrs {
+		if !IsTemplateVariable(cidr) {
+			cidrs = append(cidrs, cidr)
+			continue
+		}
+		resolved, ok := values[cidr]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrSpecVariable, cidr)
+		}
+		for _, value := range resolved {
+			if !networks.Has(templateNetwork(value)) {
+				networks.Insert(templateNetwork(value))
+				cidrs = append(cidrs, value)
+			}
+		}
+	}
+	rule.Cidrs = cidrs
+	return nil
+}
+
+// templateNetwork returns the canonical network of the CIDR, so the CIDRs of
+// a variable which are already contained in the rule aren't duplicated.
+func templateNetwork(cidr string) string {
+	masked, _ := envoyfilters.MaskedCIDR(envoyfilters.CanonicalCIDR(cidr))
+	return masked
+}
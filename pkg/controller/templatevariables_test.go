@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+
+	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+var _ = Describe("template variables", func() {
+	const namespace = "shoot--foo--bar"
+
+	var (
+		ctx     = context.Background()
+		c       client.Client
+		ex      *extensionsv1alpha1.Extension
+		cluster *extensionscontroller.Cluster
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+		c = fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(&extensionsv1alpha1.Infrastructure{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "bar"},
+			Status:     extensionsv1alpha1.InfrastructureStatus{EgressCIDRs: []string{"198.51.100.1/32"}},
+		}).Build()
+
+		ex = &extensionsv1alpha1.Extension{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "acl"}}
+		cluster = &extensionscontroller.Cluster{
+			Seed: &gardencorev1beta1.Seed{Spec: gardencorev1beta1.SeedSpec{Networks: gardencorev1beta1.SeedNetworks{
+				Nodes: ptr.To("10.250.0.0/16"),
+				Pods:  "100.96.0.0/11",
+			}}},
+			Shoot: &gardencorev1beta1.Shoot{
+				ObjectMeta: metav1.ObjectMeta{Name: "bar"},
+				Spec: gardencorev1beta1.ShootSpec{
+					Networking: &gardencorev1beta1.Networking{Nodes: ptr.To("10.180.0.0/16"), Pods: ptr.To("100.64.0.0/12")},
+					Provider:   gardencorev1beta1.Provider{Workers: []gardencorev1beta1.Worker{{Name: "worker"}}},
+				},
+			},
+		}
+	})
+
+	rule := func(cidrs ...string) *envoyfilters.ACLRule {
+		return &envoyfilters.ACLRule{Action: "ALLOW", Type: "remote_ip", Cidrs: cidrs}
+	}
+
+	Describe("#ResolveTemplateVariables", func() {
+		It("should replace the variables with the CIDRs of the cluster", func() {
+			spec := &extensionspec.ExtensionSpec{
+				Rule:         rule("1.2.3.0/24", VariableInfrastructureEgressCIDRs, VariableSeedNodes, VariableSeedPods),
+				InternalRule: rule(VariableShootNodes, VariableShootPods),
+			}
+
+			Expect(ResolveTemplateVariables(ctx, c, ex, cluster, spec)).To(Succeed())
+			Expect(spec.Rule.Cidrs).To(Equal([]string{"1.2.3.0/24", "198.51.100.1/32", "10.250.0.0/16", "100.96.0.0/11"}))
+			Expect(spec.InternalRule.Cidrs).To(Equal([]string{"10.180.0.0/16", "100.64.0.0/12"}))
+		})
+
+		It("should not duplicate CIDRs of the rule", func() {
+			spec := &extensionspec.ExtensionSpec{Rule: rule("10.250.0.1/16", VariableSeedNodes, VariableShootNodes)}
+			cluster.Shoot.Spec.Networking.Nodes = ptr.To("10.250.0.0/16")
+
+			Expect(ResolveTemplateVariables(ctx, c, ex, cluster, spec)).To(Succeed())
+			Expect(spec.Rule.Cidrs).To(Equal([]string{"10.250.0.1/16"}))
+		})
+
+		It("should drop the variables of the shoot's networks of workerless shoots", func() {
+			cluster.Shoot.Spec.Provider.Workers = nil
+			spec := &extensionspec.ExtensionSpec{Rule: rule("1.2.3.0/24", VariableShootNodes, VariableInfrastructureEgressCIDRs)}
+
+			Expect(ResolveTemplateVariables(ctx, c, ex, cluster, spec)).To(Succeed())
+			Expect(spec.Rule.Cidrs).To(Equal([]string{"1.2.3.0/24"}))
+		})
+
+		It("should drop the egress CIDRs while the Infrastructure doesn't exist", func() {
+			cluster.Shoot.Name = "baz"
+			spec := &extensionspec.ExtensionSpec{Rule: rule("1.2.3.0/24", VariableInfrastructureEgressCIDRs)}
+
+			Expect(ResolveTemplateVariables(ctx, c, ex, cluster, spec)).To(Succeed())
+			Expect(spec.Rule.Cidrs).To(Equal([]string{"1.2.3.0/24"}))
+		})
+
+		It("should reject unknown variables", func() {
+			spec := &extensionspec.ExtensionSpec{Rule: rule("1.2.3.0/24", "${seed.foo}")}
+
+			Expect(ResolveTemplateVariables(ctx, c, ex, cluster, spec)).To(MatchError(ErrSpecVariable))
+		})
+	})
+
+	Describe("#ValidateExtensionSpec", func() {
+		It("should accept known variables", func() {
+			spec := &extensionspec.ExtensionSpec{Rule: rule(VariableSeedNodes)}
+			spec.Rule.Except = []string{"10.250.1.0/24"}
+
+			Expect(ValidateExtensionSpec(spec)).To(Succeed())
+		})
+
+		It("should reject unknown variables", func() {
+			Expect(ValidateExtensionSpec(&extensionspec.ExtensionSpec{Rule: rule("${seed.foo}")})).To(MatchError(ErrSpecVariable))
+		})
+
+		It("should reject repeated variables", func() {
+			Expect(ValidateExtensionSpec(&extensionspec.ExtensionSpec{Rule: rule(VariableSeedNodes, VariableSeedNodes)})).To(MatchError(ErrSpecDuplicateCIDR))
+		})
+	})
+})
@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gardener/gardener/extensions/pkg/controller"
+	v1beta1helper "github.com/gardener/gardener/pkg/apis/core/v1beta1/helper"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	istionetworkv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+const (
+	// TrafficPathAPIServerSNI is the traffic to the API server via the SNI
+	// listener of the ingress gateways, matched by the hosts of the
+	// kube-apiserver Gateway.
+	TrafficPathAPIServerSNI = "apiserver-sni"
+	// TrafficPathAPIServerProxy is the traffic of the apiserver-proxy of the
+	// shoot to the API server, which passes the internal listener of the
+	// ingress gateways configured by Gardener's EnvoyFilter of the shoot.
+	TrafficPathAPIServerProxy = "apiserver-proxy"
+	// TrafficPathVPN is the traffic of the VPN client of the shoot to the
+	// reversed VPN listener of the ingress gateways.
+	TrafficPathVPN = "vpn"
+	// trafficPathGatewayPrefix prefixes the paths of further Gateways in the
+	// namespace of the shoot, e.g. the konnectivity-server of older Gardener
+	// versions.
+	trafficPathGatewayPrefix = "gateway/"
+)
+
+// TrafficPathStatus reports whether a path of the traffic to the control plane
+// of the shoot through the ingress gateways is protected by the ACL.
+type TrafficPathStatus struct {
+	// Name is the name of the path, e.g. TrafficPathAPIServerProxy.
+	Name string `json:"name"`
+	// Hosts are the SNI hostnames of the path, if it is matched by them.
+	Hosts []string `json:"hosts,omitempty"`
+	// Protected specifies whether the rules are enforced for the path.
+	Protected bool `json:"protected"`
+	// Reason explains why the path isn't protected.
+	Reason string `json:"reason,omitempty"`
+}
+
+// discoverTrafficPaths returns the paths of the traffic to the control plane
+// of the shoot which exist in the seed, and whether the ACL covers them. Paths
+// which aren't protected unintentionally, i.e. not because the profile of the
+// spec excludes them, are also returned as warnings. The apiserver-proxy path
+// only exists if Gardener created its EnvoyFilter for the shoot, which is
+// protected once it was mutated by the webhook, see triggerWebhook.
+func (a *actuator) discoverTrafficPaths(
+	ctx context.Context,
+	ex *extensionsv1alpha1.Extension,
+	spec *extensionspec.ExtensionSpec,
+	cluster *controller.Cluster,
+	hosts []string,
+	istioNamespaces []string,
+	istioLabels map[string]string,
+) (paths []TrafficPathStatus, warnings []string, err error) {
+	paths = append(paths, TrafficPathStatus{Name: TrafficPathAPIServerSNI, Hosts: hosts, Protected: true})
+
+	envoyFilterWriter, err := envoyfilters.DiscoverWriter(a.client.RESTMapper())
+	if err != nil {
+		return nil, nil, err
+	}
+	var found bool
+	var unprotected []string
+	for _, istioNamespace := range istioNamespaces {
+		envoyFilter := envoyFilterWriter.New(istioNamespace, ex.Namespace)
+		if err := a.client.Get(ctx, client.ObjectKeyFromObject(envoyFilter), envoyFilter); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		found = true
+		if !hasInternalACLFilter(envoyFilter) {
+			unprotected = append(unprotected, istioNamespace)
+		}
+	}
+	if found {
+		path := TrafficPathStatus{Name: TrafficPathAPIServerProxy, Protected: len(unprotected) == 0}
+		if !path.Protected {
+			path.Reason = fmt.Sprintf("the EnvoyFilter %s of the istio namespaces %s wasn't mutated by the webhook",
+				ex.Namespace, strings.Join(unprotected, ", "))
+			warnings = append(warnings, unprotectedWarning(path))
+		}
+		paths = append(paths, path)
+	}
+
+	if !v1beta1helper.IsWorkerless(cluster.Shoot) {
+		path := TrafficPathStatus{Name: TrafficPathVPN, Protected: spec.HasTarget(extensionspec.TargetVPN)}
+		if !path.Protected {
+			path.Reason = fmt.Sprintf("the %s profile doesn't include the %s target", spec.Profile, extensionspec.TargetVPN)
+		}
+		paths = append(paths, path)
+	}
+
+	gatewayPaths, err := a.discoverGatewayPaths(ctx, ex.Namespace, hosts, istioLabels)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, path := range gatewayPaths {
+		warnings = append(warnings, unprotectedWarning(path))
+	}
+	return append(paths, gatewayPaths...), warnings, nil
+}
+
+// discoverGatewayPaths returns the hosts of the Gateways in the namespace of
+// the shoot besides the kube-apiserver Gateway, which are served by the same
+// ingress gateways. Their filter chains aren't matched by the filters of the
+// API server, so they are reported as unprotected.
+func (a *actuator) discoverGatewayPaths(
+	ctx context.Context, namespace string, hosts []string, istioLabels map[string]string,
+) ([]TrafficPathStatus, error) {
+	gateways := &istionetworkv1beta1.GatewayList{}
+	if err := a.client.List(ctx, gateways, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	var paths []TrafficPathStatus
+	for _, gateway := range gateways.Items {
+		if gateway.Name == cmp.Or(a.extensionConfig.APIServerGatewayName, istioGatewayName) ||
+			!maps.Equal(gateway.Spec.Selector, istioLabels) {
+			continue
+		}
+		var gatewayHosts []string
+		for _, server := range gateway.Spec.Servers {
+			for _, host := range server.GetHosts() {
+				if !slices.Contains(hosts, host) && !slices.Contains(gatewayHosts, host) {
+					gatewayHosts = append(gatewayHosts, host)
+				}
+			}
+		}
+		if len(gatewayHosts) == 0 {
+			continue
+		}
+		paths = append(paths, TrafficPathStatus{
+			Name:   trafficPathGatewayPrefix + gateway.Name,
+			Hosts:  gatewayHosts,
+			Reason: "the hosts aren't matched by the filter chain of the API server",
+		})
+	}
+	return paths, nil
+}
+
+// hasInternalACLFilter returns whether the filters of Gardener's EnvoyFilter
+// of the internal flow contain the filter added by the webhook.
+func hasInternalACLFilter(envoyFilter *unstructured.Unstructured) bool {
+	configPatches, _, _ := unstructured.NestedSlice(envoyFilter.Object, "spec", "configPatches")
+	if len(configPatches) == 0 {
+		return false
+	}
+	configPatch, ok := configPatches[0].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	filters, _, _ := unstructured.NestedSlice(configPatch, "patch", "value", "filters")
+	for _, filter := range filters {
+		if filter, ok := filter.(map[string]interface{}); ok {
+			if name, _ := filter["name"].(string); strings.HasPrefix(name, "acl-internal-") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unprotectedWarning returns the warning about an unintentionally unprotected
+// traffic path.
+func unprotectedWarning(path TrafficPathStatus) string {
+	return fmt.Sprintf("the %s traffic path is not protected by the ACL: %s", path.Name, path.Reason)
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	verificationTimeout  = 5 * time.Second
+	defaultAPIServerPort = "443"
+)
+
+// VerificationResult contains the result of the probe verifying that the
+// shoot's API server is still reachable from the extension after the ACL has
+// been applied.
+type VerificationResult struct {
+	// Reachable is true if a TLS handshake with the API server succeeded.
+	Reachable bool `json:"reachable"`
+	// Address is the address that was probed.
+	Address string `json:"address"`
+	// Message contains the error if the API server was not reachable.
+	Message string `json:"message,omitempty"`
+	// LastProbeTime is the time of the last probe.
+	LastProbeTime metav1.Time `json:"lastProbeTime"`
+}
+
+// verifyAPIServerReachability performs a TCP/TLS handshake with the shoot's API
+// server. As the extension runs in the seed's pod network, which is always
+// allowed, a failing handshake indicates that a rendered filter blocks
+// legitimate infrastructure sources. No kubeconfig is needed, as the server
+// certificate is not verified.
+func verifyAPIServerReachability(ctx context.Context, host string) *VerificationResult {
+	result := &VerificationResult{
+		Address:       verificationAddress(host),
+		LastProbeTime: metav1.Now(),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, verificationTimeout)
+	defer cancel()
+
+	dialer := &tls.Dialer{
+		Config: &tls.Config{
+			//nolint:gosec // only the reachability is verified, no data is sent
+			InsecureSkipVerify: true,
+		},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", result.Address)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	_ = conn.Close()
+
+	result.Reachable = true
+	return result
+}
+
+// verificationAddress returns the address probed for the given host of the
+// API server, which defaults to the port of the API server.
+func verificationAddress(host string) string {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		return net.JoinHostPort(host, defaultAPIServerPort)
+	}
+	return host
+}
+
+// needsVerification returns whether the reachability of the API server has to
+// be probed again. The probe blocks the reconciliation up to its timeout, so
+// it only runs after the applied rules changed or if there is no result for
+// the host yet. Otherwise, the result of the last probe is kept.
+func needsVerification(previous *VerificationResult, host string, rulesChanged bool) bool {
+	return rulesChanged || previous == nil || previous.Address != verificationAddress(host)
+}
+
+// verificationWarning returns a warning for a failed verification.
+func verificationWarning(result *VerificationResult) string {
+	return fmt.Sprintf("API server %s is not reachable from the seed after applying the ACL, "+
+		"a rule might block legitimate infrastructure sources: %s", result.Address, result.Message)
+}
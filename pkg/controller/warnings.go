@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1helper "github.com/gardener/gardener/pkg/apis/core/v1beta1/helper"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+const (
+	// ConditionTypeConfigurationWarnings is the condition of the Extension
+	// which is true if there are findings about the ACL configuration of the
+	// shoot, they are listed in its message. Unlike the health checks, it
+	// doesn't affect the health of the shoot.
+	ConditionTypeConfigurationWarnings gardencorev1beta1.ConditionType = "ConfigurationWarnings"
+	// ReasonConfigurationNeedsAttention is the reason of the
+	// ConfigurationWarnings condition if there are findings.
+	ReasonConfigurationNeedsAttention = "ConfigurationNeedsAttention"
+	// ReasonNoConfigurationWarnings is the reason of the ConfigurationWarnings
+	// condition once there are no findings anymore.
+	ReasonNoConfigurationWarnings = "NoConfigurationWarnings"
+)
+
+const internalRuleIgnoredMessage = "The shoot has no internal address of its API server, " +
+	"the internal rule is ignored and the rule applies to all addresses"
+
+// collectWarnings returns findings about an (already validated) ExtensionSpec
+// which don't prevent the rules from being applied, but which the shoot owner
+// should be aware of.
+func collectWarnings(spec *extensionspec.ExtensionSpec, cfg config.Config) []string {
+	var warnings []string
+
+	rule := spec.Rule
+	if rule == nil {
+		return nil
+	}
+
+	if cfg.MaxAllowedCIDRs > 0 && len(rule.Cidrs) > cfg.MaxAllowedCIDRs {
+		warnings = append(warnings, fmt.Sprintf(
+			"rule set is oversized: %d CIDRs are configured, but at most %d are recommended", len(rule.Cidrs), cfg.MaxAllowedCIDRs,
+		))
+	}
+
+	for _, overlap := range envoyfilters.OverlappingCIDRs(rule.Cidrs, rule.Except) {
+		warnings = append(warnings, fmt.Sprintf("CIDR %s is contained in %s and has no effect", overlap.CIDR, overlap.ContainedIn))
+	}
+
+	// such CIDRs are rejected by the admission, but may predate it
+	for _, cidr := range slices.Concat(rule.Cidrs, rule.Except) {
+		if masked, hostBits := envoyfilters.MaskedCIDR(cidr); hostBits {
+			warnings = append(warnings, fmt.Sprintf("CIDR %s has host bits set and is enforced as %s", cidr, masked))
+		}
+	}
+
+	if strings.EqualFold(rule.Action, "allow") {
+		for _, cidr := range rule.Cidrs {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				continue
+			}
+			if ones, _ := network.Mask.Size(); ones == 0 {
+				warnings = append(warnings, fmt.Sprintf("rule allows access from everywhere (%s), the ACL is not effective", cidr))
+				break
+			}
+		}
+	}
+
+	return warnings
+}
+
+// updateWarningsCondition reports the warnings recorded in the state in the
+// ConfigurationWarnings condition of the Extension. Shoots which never had
+// warnings don't get the condition.
+func (a *actuator) updateWarningsCondition(ctx context.Context, ex *extensionsv1alpha1.Extension, warnings []string) error {
+	condition := v1beta1helper.GetCondition(ex.Status.Conditions, ConditionTypeConfigurationWarnings)
+	if condition == nil {
+		if len(warnings) == 0 {
+			return nil
+		}
+		initCondition := v1beta1helper.InitConditionWithClock(clock.RealClock{}, ConditionTypeConfigurationWarnings)
+		condition = &initCondition
+	}
+
+	var updated gardencorev1beta1.Condition
+	if len(warnings) > 0 {
+		updated = v1beta1helper.UpdatedConditionWithClock(clock.RealClock{}, *condition, gardencorev1beta1.ConditionTrue,
+			ReasonConfigurationNeedsAttention, "ACL configuration needs attention: "+strings.Join(warnings, "; "))
+	} else {
+		updated = v1beta1helper.UpdatedConditionWithClock(clock.RealClock{}, *condition, gardencorev1beta1.ConditionFalse,
+			ReasonNoConfigurationWarnings, "The ACL configuration has no findings")
+	}
+	if v1beta1helper.ConditionsNeedUpdate([]gardencorev1beta1.Condition{*condition}, []gardencorev1beta1.Condition{updated}) {
+		patch := client.MergeFrom(ex.DeepCopy())
+		ex.Status.Conditions = v1beta1helper.MergeConditions(ex.Status.Conditions, updated)
+		return a.client.Status().Patch(ctx, ex, patch)
+	}
+	return nil
+}
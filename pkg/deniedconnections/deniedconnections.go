@@ -0,0 +1,280 @@
+// Package deniedconnections periodically scrapes the Envoy statistics of the
+// istio ingress gateways and exposes the number of connections denied by the
+// RBAC filters of every shoot on every gateway pod as a metric. It also keeps track of the last
+// time a connection to a shoot was allowed.
+package deniedconnections
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+)
+
+const (
+	// DefaultPodSelector selects the istio ingress gateway pods.
+	DefaultPodSelector = "app=istio-ingressgateway"
+	// DefaultStatsPort is the port of the Envoy Prometheus statistics of the
+	// istio proxy.
+	DefaultStatsPort = 15090
+	// DefaultStatsPath is the path of the Envoy Prometheus statistics.
+	DefaultStatsPath = "/stats/prometheus"
+
+	resultAllowed = "allowed"
+	resultDenied  = "denied"
+)
+
+var (
+	// DefaultAddOptions are the default AddOptions for AddToManager.
+	DefaultAddOptions = AddOptions{
+		PodSelector: DefaultPodSelector,
+		StatsPort:   DefaultStatsPort,
+		StatsPath:   DefaultStatsPath,
+	}
+
+	deniedConnectionsDesc = prometheus.NewDesc(
+		"acl_denied_connections_total",
+		"Number of connections denied by the ACL of a shoot on an istio ingress gateway pod.",
+		[]string{"shoot", "listener", "namespace", "pod"}, nil,
+	)
+
+	// The names of the statistics are sanitized by Envoy's Prometheus output,
+	// i.e. the dashes of the technical ID are replaced by underscores. Network
+	// filters count in "<stat prefix>.rbac.<result>", HTTP filters in
+	// "http.<connection manager>.rbac.<rules stat prefix><result>".
+	networkFilterStat = regexp.MustCompile(`acl_(` + envoyfilters.ListenerAPI + `|` + envoyfilters.ListenerIngress + `|` +
+		envoyfilters.ListenerInternal + `)_(shoot_\w+)_rbac_(` + resultAllowed + `|` + resultDenied + `)$`)
+	httpFilterStat = regexp.MustCompile(`acl_(` + envoyfilters.ListenerVPN + `)_(shoot_\w+)_(` + resultAllowed + `|` + resultDenied + `)$`)
+)
+
+// AddOptions are options to apply when adding the scraper to the manager.
+type AddOptions struct {
+	// Interval is the scrape interval, the scraper is disabled if it is zero.
+	Interval time.Duration
+	// PodSelector is the label selector of the istio ingress gateway pods.
+	PodSelector string
+	// StatsPort is the port of the Envoy Prometheus statistics.
+	StatsPort int
+	// StatsPath is the path of the Envoy Prometheus statistics.
+	StatsPath string
+}
+
+// key identifies the RBAC filter of a shoot on a listener.
+type key struct {
+	shoot    string
+	listener string
+}
+
+// podKey identifies the RBAC filter of a shoot on a listener of a gateway pod.
+// The denied connections are exposed per pod, as the counters of Envoy start
+// from zero whenever a pod is restarted, and a sum over all pods would
+// decrease.
+type podKey struct {
+	key
+	namespace string
+	pod       string
+}
+
+// Scraper scrapes the Envoy statistics of the istio ingress gateway pods and
+// exposes the denied connections per shoot.
+type Scraper struct {
+	reader      client.Reader
+	httpClient  *http.Client
+	log         logr.Logger
+	interval    time.Duration
+	podSelector labels.Selector
+	statsPort   int
+	statsPath   string
+
+	lock    sync.RWMutex
+	denied  map[podKey]float64
+	allowed map[string]float64
+	lastHit map[string]time.Time
+}
+
+var _ prometheus.Collector = &Scraper{}
+
+// AddToManager adds a Scraper with the given options to the manager and
+// registers it as metrics collector. Nothing is added and nil is returned if
+// the interval is zero.
+//
+// +kubebuilder:rbac:groups="",resources=pods,verbs=list
+func AddToManager(mgr manager.Manager, opts AddOptions) (*Scraper, error) {
+	if opts.Interval == 0 {
+		return nil, nil
+	}
+
+	selector, err := labels.Parse(opts.PodSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod selector %q: %w", opts.PodSelector, err)
+	}
+
+	scraper := &Scraper{
+		// the gateway pods are listed uncached, as caching all pods of the
+		// seed isn't worth it for one request per interval
+		reader:      mgr.GetAPIReader(),
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		log:         mgr.GetLogger().WithName("deniedconnections"),
+		interval:    opts.Interval,
+		podSelector: selector,
+		statsPort:   opts.StatsPort,
+		statsPath:   opts.StatsPath,
+		denied:      map[podKey]float64{},
+		allowed:     map[string]float64{},
+		lastHit:     map[string]time.Time{},
+	}
+
+	if err := metrics.Registry.Register(scraper); err != nil {
+		return nil, fmt.Errorf("could not register denied connections collector: %w", err)
+	}
+	return scraper, mgr.Add(scraper)
+}
+
+// Start implements manager.Runnable. As a runnable of the manager, the
+// scraper is only running on the leader, so the metric is only exposed once.
+func (s *Scraper) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, s.scrape, s.interval)
+	return nil
+}
+
+// LastHit returns the last time a connection to the shoot with the given
+// technical ID was allowed, as far as observed by the scraper. Envoy only
+// counts per RBAC filter, so it is unknown which CIDR the connection matched.
+func (s *Scraper) LastHit(shoot string) (time.Time, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	lastHit, ok := s.lastHit[shoot]
+	return lastHit, ok
+}
+
+// Describe implements prometheus.Collector.
+func (s *Scraper) Describe(ch chan<- *prometheus.Desc) {
+	ch <- deniedConnectionsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (s *Scraper) Collect(ch chan<- prometheus.Metric) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for k, value := range s.denied {
+		ch <- prometheus.MustNewConstMetric(deniedConnectionsDesc, prometheus.CounterValue, value, k.shoot, k.listener, k.namespace, k.pod)
+	}
+}
+
+func (s *Scraper) scrape(ctx context.Context) {
+	pods := &corev1.PodList{}
+	if err := s.reader.List(ctx, pods, client.MatchingLabelsSelector{Selector: s.podSelector}); err != nil {
+		s.log.Error(err, "Could not list istio ingress gateway pods")
+		return
+	}
+
+	denied := map[podKey]float64{}
+	allowed := map[string]float64{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+
+		families, err := s.scrapePod(ctx, pod.Status.PodIP)
+		if err != nil {
+			s.log.Error(err, "Could not scrape istio ingress gateway pod", "pod", client.ObjectKeyFromObject(&pod))
+			continue
+		}
+		for k, value := range mapConnections(families, resultDenied) {
+			denied[podKey{key: k, namespace: pod.Namespace, pod: pod.Name}] = value
+		}
+		for k, value := range mapConnections(families, resultAllowed) {
+			allowed[k.shoot] += value
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.denied = denied
+	s.recordHits(allowed, time.Now())
+}
+
+// recordHits updates the last hit of every shoot whose allowed connections
+// changed since the last scrape. A lower value means that a gateway pod was
+// restarted in between, which is counted as hit if there are any connections.
+func (s *Scraper) recordHits(allowed map[string]float64, now time.Time) {
+	for shoot, value := range allowed {
+		previous, ok := s.allowed[shoot]
+		if ok && (value > previous || (value < previous && value > 0)) {
+			s.lastHit[shoot] = now
+		}
+	}
+	s.allowed = allowed
+}
+
+func (s *Scraper) scrapePod(ctx context.Context, podIP string) (map[string]*dto.MetricFamily, error) {
+	url := "http://" + net.JoinHostPort(podIP, strconv.Itoa(s.statsPort)) + s.statsPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return parseStats(resp.Body)
+}
+
+func parseStats(r io.Reader) (map[string]*dto.MetricFamily, error) {
+	var parser expfmt.TextParser
+	return parser.TextToMetricFamilies(r)
+}
+
+// mapConnections maps the RBAC statistics of the ACL filters with the given
+// result to the shoots and listeners they belong to.
+func mapConnections(families map[string]*dto.MetricFamily, result string) map[key]float64 {
+	connections := map[key]float64{}
+	for name, family := range families {
+		match := networkFilterStat.FindStringSubmatch(name)
+		if match == nil {
+			match = httpFilterStat.FindStringSubmatch(name)
+		}
+		if match == nil || match[3] != result {
+			continue
+		}
+
+		// technical IDs only contain lowercase alphanumeric characters and
+		// dashes, so the sanitization can be reverted
+		k := key{shoot: strings.ReplaceAll(match[2], "_", "-"), listener: match[1]}
+		for _, metric := range family.GetMetric() {
+			if metric.GetCounter() != nil {
+				connections[k] += metric.GetCounter().GetValue()
+			} else {
+				connections[k] += metric.GetUntyped().GetValue()
+			}
+		}
+	}
+	return connections
+}
@@ -0,0 +1,110 @@
+package deniedconnections
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("deniedconnections", func() {
+	Describe("mapConnections", func() {
+		It("should map the denied connections of the ACL filters to the shoots", func() {
+			stats, err := os.Open(path.Join("testdata", "stats.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			defer stats.Close()
+
+			families, err := parseStats(stats)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(mapConnections(families, resultDenied)).To(Equal(map[key]float64{
+				{shoot: "shoot--foo--bar", listener: "api"}:         7,
+				{shoot: "shoot--foo--bar", listener: "internal"}:    2,
+				{shoot: "shoot--foo--my-rbac", listener: "ingress"}: 3,
+				{shoot: "shoot--foo--bar", listener: "vpn"}:         5,
+			}))
+		})
+
+		It("should map the allowed connections of the ACL filters to the shoots", func() {
+			stats, err := os.Open(path.Join("testdata", "stats.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			defer stats.Close()
+
+			families, err := parseStats(stats)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(mapConnections(families, resultAllowed)).To(Equal(map[key]float64{
+				{shoot: "shoot--foo--bar", listener: "api"}: 120,
+			}))
+		})
+	})
+
+	Describe("Collect", func() {
+		It("should expose the denied connections per gateway pod", func() {
+			k := key{shoot: "shoot--foo--bar", listener: "api"}
+			s := &Scraper{denied: map[podKey]float64{
+				{key: k, namespace: "istio-ingress", pod: "istio-ingressgateway-1"}: 7,
+				{key: k, namespace: "istio-ingress", pod: "istio-ingressgateway-2"}: 2,
+			}}
+
+			Expect(testutil.CollectAndCompare(s, strings.NewReader(`
+# HELP acl_denied_connections_total Number of connections denied by the ACL of a shoot on an istio ingress gateway pod.
+# TYPE acl_denied_connections_total counter
+acl_denied_connections_total{listener="api",namespace="istio-ingress",pod="istio-ingressgateway-1",shoot="shoot--foo--bar"} 7
+acl_denied_connections_total{listener="api",namespace="istio-ingress",pod="istio-ingressgateway-2",shoot="shoot--foo--bar"} 2
+`))).To(Succeed())
+		})
+	})
+
+	Describe("recordHits", func() {
+		It("should only record a hit if the allowed connections changed", func() {
+			s := &Scraper{allowed: map[string]float64{}, lastHit: map[string]time.Time{}}
+			first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			s.recordHits(map[string]float64{"shoot--foo--bar": 10}, first)
+			_, ok := s.LastHit("shoot--foo--bar")
+			Expect(ok).To(BeFalse())
+
+			s.recordHits(map[string]float64{"shoot--foo--bar": 12}, first.Add(time.Minute))
+			s.recordHits(map[string]float64{"shoot--foo--bar": 12}, first.Add(2*time.Minute))
+			Expect(s.LastHit("shoot--foo--bar")).To(Equal(first.Add(time.Minute)))
+
+			// the gateway pod was restarted
+			s.recordHits(map[string]float64{"shoot--foo--bar": 3}, first.Add(3*time.Minute))
+			Expect(s.LastHit("shoot--foo--bar")).To(Equal(first.Add(3 * time.Minute)))
+		})
+	})
+
+	Describe("scrapePod", func() {
+		It("should scrape the statistics of a gateway pod", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.URL.Path).To(Equal(DefaultStatsPath))
+				http.ServeFile(w, r, path.Join("testdata", "stats.txt"))
+			}))
+			defer server.Close()
+
+			serverURL, err := url.Parse(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			host, port, err := net.SplitHostPort(serverURL.Host)
+			Expect(err).NotTo(HaveOccurred())
+			s := &Scraper{httpClient: server.Client(), statsPath: DefaultStatsPath}
+			s.statsPort, err = strconv.Atoi(port)
+			Expect(err).NotTo(HaveOccurred())
+
+			families, err := s.scrapePod(context.Background(), host)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(families).To(HaveKey("envoy_acl_api_shoot__foo__bar_rbac_denied"))
+		})
+	})
+})
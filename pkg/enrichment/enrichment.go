@@ -0,0 +1,181 @@
+// Package enrichment contains hooks to annotate source IPs of denied
+// connections with a human-meaningful identity, e.g. the reverse DNS name or
+// the owner from an internal CMDB.
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Error variables for enrichment pkg
+var (
+	ErrNoIPGiven = errors.New("no IP was given")
+)
+
+const (
+	// KeyReverseDNS is the identity key set by the ReverseDNSEnricher.
+	KeyReverseDNS = "reverseDNS"
+	// DefaultTimeout is the default timeout of a single lookup.
+	DefaultTimeout = 2 * time.Second
+)
+
+// Enricher looks up the identity of a source IP. Implementations return an
+// empty map (and no error) if nothing is known about the IP.
+type Enricher interface {
+	Enrich(ctx context.Context, ip net.IP) (map[string]string, error)
+}
+
+// AuditEntry describes a single denied connection.
+type AuditEntry struct {
+	// Timestamp is the time the connection was denied.
+	Timestamp time.Time `json:"timestamp"`
+	// Shoot is the technical ID of the shoot the connection was targeting.
+	Shoot string `json:"shoot"`
+	// SourceIP is the IP of the client.
+	SourceIP string `json:"sourceIP"`
+	// SNI is the requested server name, if any.
+	SNI string `json:"sni,omitempty"`
+	// Identity contains the information returned by the enrichers.
+	Identity map[string]string `json:"identity,omitempty"`
+}
+
+// EnrichEntry annotates the entry with the identity returned by the given
+// enricher. Lookup errors are returned, but never prevent the entry from
+// being annotated with the information which could be found.
+func EnrichEntry(ctx context.Context, enricher Enricher, entry *AuditEntry) error {
+	if enricher == nil {
+		return nil
+	}
+
+	ip := net.ParseIP(entry.SourceIP)
+	if ip == nil {
+		return ErrNoIPGiven
+	}
+
+	identity, err := enricher.Enrich(ctx, ip)
+	if len(identity) > 0 {
+		if entry.Identity == nil {
+			entry.Identity = map[string]string{}
+		}
+		for k, v := range identity {
+			entry.Identity[k] = v
+		}
+	}
+	return err
+}
+
+// Chain combines multiple enrichers. Results of later enrichers take
+// precedence over earlier ones.
+type Chain []Enricher
+
+// Enrich implements Enricher.
+func (c Chain) Enrich(ctx context.Context, ip net.IP) (map[string]string, error) {
+	identity := map[string]string{}
+	var errs []error
+
+	for _, enricher := range c {
+		result, err := enricher.Enrich(ctx, ip)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for k, v := range result {
+			identity[k] = v
+		}
+	}
+
+	return identity, errors.Join(errs...)
+}
+
+// ReverseDNSEnricher looks up the PTR records of an IP.
+type ReverseDNSEnricher struct {
+	Resolver *net.Resolver
+	Timeout  time.Duration
+}
+
+// NewReverseDNSEnricher returns an enricher using the default resolver.
+func NewReverseDNSEnricher() *ReverseDNSEnricher {
+	return &ReverseDNSEnricher{
+		Resolver: net.DefaultResolver,
+		Timeout:  DefaultTimeout,
+	}
+}
+
+// Enrich implements Enricher.
+func (r *ReverseDNSEnricher) Enrich(ctx context.Context, ip net.IP) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	names, err := r.Resolver.LookupAddr(ctx, ip.String())
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("reverse DNS lookup for %s failed: %w", ip, err)
+	}
+	if len(names) == 0 {
+		return map[string]string{}, nil
+	}
+
+	return map[string]string{KeyReverseDNS: strings.TrimSuffix(names[0], ".")}, nil
+}
+
+// HTTPEnricher looks up an IP at an HTTP endpoint, e.g. an internal CMDB. The
+// IP is passed as `ip` query parameter, and the endpoint is expected to
+// respond with a flat JSON object of strings. A 404 response means that
+// nothing is known about the IP.
+type HTTPEnricher struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPEnricher returns an enricher querying the given URL.
+func NewHTTPEnricher(endpoint string) *HTTPEnricher {
+	return &HTTPEnricher{
+		URL:    endpoint,
+		Client: &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+// Enrich implements Enricher.
+func (h *HTTPEnricher) Enrich(ctx context.Context, ip net.IP) (map[string]string, error) {
+	endpoint, err := url.Parse(h.URL)
+	if err != nil {
+		return nil, err
+	}
+	query := endpoint.Query()
+	query.Set("ip", ip.String())
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("lookup for %s failed: %w", ip, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string]string{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("lookup for %s failed: unexpected status code %d", ip, resp.StatusCode)
+	}
+
+	identity := map[string]string{}
+	if err := json.NewDecoder(resp.Body).Decode(&identity); err != nil {
+		return nil, fmt.Errorf("lookup for %s failed: %w", ip, err)
+	}
+	return identity, nil
+}
@@ -0,0 +1,76 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type fakeEnricher struct {
+	identity map[string]string
+	err      error
+}
+
+func (f *fakeEnricher) Enrich(_ context.Context, _ net.IP) (map[string]string, error) {
+	return f.identity, f.err
+}
+
+var _ = Describe("enrichment Unit Tests", func() {
+	ctx := context.Background()
+
+	Describe("EnrichEntry", func() {
+		It("should annotate the entry with the identity", func() {
+			entry := &AuditEntry{SourceIP: "1.2.3.4"}
+
+			Expect(EnrichEntry(ctx, &fakeEnricher{identity: map[string]string{"owner": "team-a"}}, entry)).To(Succeed())
+			Expect(entry.Identity).To(Equal(map[string]string{"owner": "team-a"}))
+		})
+
+		It("should keep partial results of a failing chain", func() {
+			entry := &AuditEntry{SourceIP: "1.2.3.4"}
+			chain := Chain{
+				&fakeEnricher{identity: map[string]string{"owner": "team-a"}},
+				&fakeEnricher{err: errors.New("fake")},
+			}
+
+			Expect(EnrichEntry(ctx, chain, entry)).NotTo(Succeed())
+			Expect(entry.Identity).To(Equal(map[string]string{"owner": "team-a"}))
+		})
+
+		It("should return an error for entries without valid source IP", func() {
+			Expect(EnrichEntry(ctx, Chain{}, &AuditEntry{SourceIP: "foo"})).To(Equal(ErrNoIPGiven))
+		})
+	})
+
+	Describe("HTTPEnricher", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("ip") != "1.2.3.4" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = w.Write([]byte(`{"owner":"team-a","ticket":"SEC-1"}`))
+			}))
+			DeferCleanup(server.Close)
+		})
+
+		It("should return the identity known by the endpoint", func() {
+			identity, err := NewHTTPEnricher(server.URL).Enrich(ctx, net.ParseIP("1.2.3.4"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(identity).To(Equal(map[string]string{"owner": "team-a", "ticket": "SEC-1"}))
+		})
+
+		It("should return an empty identity for unknown IPs", func() {
+			identity, err := NewHTTPEnricher(server.URL).Enrich(ctx, net.ParseIP("5.6.7.8"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(identity).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,162 @@
+package envoyfilters
+
+import (
+	"github.com/gardener/gardener/extensions/pkg/controller"
+)
+
+// deniedDetailsPrefix is the prefix of the response code and connection
+// termination details set by Envoy's RBAC filters when denying a request or
+// connection, for both the EnvoyFilter and the AuthorizationPolicy backend.
+const deniedDetailsPrefix = "rbac_access_denied"
+
+// networkRBACMetadataNamespace is the namespace of the dynamic metadata the
+// network RBAC filters set, e.g. the result of their shadow rules.
+const networkRBACMetadataNamespace = "envoy.filters.network.rbac"
+
+// BuildAccessLogEnvoyFilterSpecForHelmChart assembles EnvoyFilter patches
+// which log the connections to the API server of the shoot, and to its VPN if
+// vpn is true, that were denied by an RBAC filter to the stdout of the istio
+// ingress gateway. If logMatches is true, the connections to the API server
+// matched by the CIDRs of a "LOG" rule are logged as well.
+func BuildAccessLogEnvoyFilterSpecForHelmChart(
+	cluster *controller.Cluster, hosts []string, vpn, logMatches bool, istioLabels map[string]string,
+) (map[string]interface{}, error) {
+	if len(hosts) == 0 {
+		return nil, ErrNoHostsGiven
+	}
+
+	technicalShootID := cluster.Shoot.Status.TechnicalID
+	configPatches := []map[string]interface{}{
+		CreateAPIAccessLogConfigPatch(hosts[0], technicalShootID, logMatches),
+	}
+	if vpn {
+		configPatches = append(configPatches, CreateVPNAccessLogConfigPatch(technicalShootID))
+	}
+
+	return map[string]interface{}{
+		"workloadSelector": map[string]interface{}{
+			"labels": istioLabels,
+		},
+		"configPatches": configPatches,
+	}, nil
+}
+
+// CreateAPIAccessLogConfigPatch creates a patch adding an access log for
+// denied connections to the tcp_proxy of the `GATEWAY` network filter chain
+// matching the host. The tcp_proxy also logs connections closed by a filter in
+// front of it. If logMatches is true, a second access log logs the
+// connections matched by the shadow rules of a "LOG" rule.
+func CreateAPIAccessLogConfigPatch(host, technicalShootID string, logMatches bool) map[string]interface{} {
+	accessLogs := []map[string]interface{}{
+		accessLog(
+			logTypeDenied, "connection.termination_details.startsWith('"+deniedDetailsPrefix+"')",
+			technicalShootID, ListenerAPI, "%CONNECTION_TERMINATION_DETAILS%",
+		),
+	}
+	if logMatches {
+		accessLogs = append(accessLogs, accessLog(
+			logTypeLogged, "'"+networkRBACMetadataNamespace+"' in metadata.filter_metadata && "+
+				"metadata.filter_metadata['"+networkRBACMetadataNamespace+"']['shadow_engine_result'] == 'denied'",
+			technicalShootID, ListenerAPI, "%DYNAMIC_METADATA("+networkRBACMetadataNamespace+":shadow_effective_policy_id)%",
+		))
+	}
+
+	return map[string]interface{}{
+		"applyTo": "NETWORK_FILTER",
+		"match": map[string]interface{}{
+			"context": "GATEWAY",
+			"listener": map[string]interface{}{
+				"filterChain": map[string]interface{}{
+					"sni": host,
+					"filter": map[string]interface{}{
+						"name": "envoy.filters.network.tcp_proxy",
+					},
+				},
+			},
+		},
+		"patch": map[string]interface{}{
+			"operation": "MERGE",
+			"value": map[string]interface{}{
+				"typed_config": map[string]interface{}{
+					"@type":      "type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy",
+					"access_log": accessLogs,
+				},
+			},
+		},
+	}
+}
+
+// CreateVPNAccessLogConfigPatch creates a patch adding an access log for
+// denied requests of the shoot to the HTTP connection manager of the VPN
+// listener. As the listener is shared by all shoots, the requests of the shoot
+// are selected by the reversed-vpn header, like in the RBAC filter.
+func CreateVPNAccessLogConfigPatch(technicalShootID string) map[string]interface{} {
+	return map[string]interface{}{
+		"applyTo": "NETWORK_FILTER",
+		"match": map[string]interface{}{
+			"context": "GATEWAY",
+			"listener": map[string]interface{}{
+				"portNumber": VPNListenerPort,
+				"filterChain": map[string]interface{}{
+					"filter": map[string]interface{}{
+						"name": "envoy.filters.network.http_connection_manager",
+					},
+				},
+			},
+		},
+		"patch": map[string]interface{}{
+			"operation": "MERGE",
+			"value": map[string]interface{}{
+				"typed_config": map[string]interface{}{
+					"@type": "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+					"access_log": []map[string]interface{}{
+						accessLog(
+							logTypeDenied, "'reversed-vpn' in request.headers && request.headers['reversed-vpn'].contains('."+technicalShootID+".') && "+
+								"response.code_details.startsWith('"+deniedDetailsPrefix+"')",
+							technicalShootID, ListenerVPN, "%RESPONSE_CODE_DETAILS%",
+						),
+					},
+				},
+			},
+		},
+	}
+}
+
+// Types of the access log entries, which are also part of the names of the
+// access logs.
+const (
+	logTypeDenied = "denied"
+	logTypeLogged = "logged"
+)
+
+// accessLog returns a JSON access log of the given type to stdout, filtered
+// by the given CEL expression.
+func accessLog(logType, expression, technicalShootID, listener, details string) map[string]interface{} {
+	return map[string]interface{}{
+		"name": "acl-" + logType + "-" + listener + "-" + technicalShootID,
+		"filter": map[string]interface{}{
+			"extension_filter": map[string]interface{}{
+				"name": "envoy.access_loggers.extension_filters.cel",
+				"typed_config": map[string]interface{}{
+					"@type":      "type.googleapis.com/envoy.extensions.access_loggers.filters.cel.v3.ExpressionFilter",
+					"expression": expression,
+				},
+			},
+		},
+		"typed_config": map[string]interface{}{
+			"@type": "type.googleapis.com/envoy.extensions.access_loggers.stream.v3.StdoutAccessLog",
+			"log_format": map[string]interface{}{
+				"json_format": map[string]interface{}{
+					"type":             "acl_" + logType,
+					"timestamp":        "%START_TIME%",
+					"shoot":            technicalShootID,
+					"listener":         listener,
+					"source_ip":        "%DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT%",
+					"direct_source_ip": "%DOWNSTREAM_DIRECT_REMOTE_ADDRESS_WITHOUT_PORT%",
+					"sni":              "%REQUESTED_SERVER_NAME%",
+					"details":          details,
+				},
+			},
+		},
+	}
+}
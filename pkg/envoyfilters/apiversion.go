@@ -0,0 +1,93 @@
+package envoyfilters
+
+import (
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DefaultVersion is the API version of the EnvoyFilters written if none of
+// the supported versions is served, e.g. because istio isn't installed yet.
+const DefaultVersion = "v1alpha3"
+
+// GroupKind is the group and kind of istio's EnvoyFilters.
+var GroupKind = schema.GroupKind{Group: "networking.istio.io", Kind: "EnvoyFilter"}
+
+// supportedVersions are the API versions of EnvoyFilters the extension can
+// write, in the order of preference. The schema of the EnvoyFilters is the
+// same in all of them, only the apiVersion of the objects differs.
+var supportedVersions = []string{"v1", "v1beta1", DefaultVersion}
+
+// Writer writes the EnvoyFilters of the extension in one API version of
+// istio, so the rest of the extension doesn't depend on the versions served
+// by the istio installation of the seed.
+type Writer interface {
+	// GroupVersionKind returns the GroupVersionKind of the written
+	// EnvoyFilters.
+	GroupVersionKind() schema.GroupVersionKind
+	// APIVersion returns the apiVersion field of the written EnvoyFilters,
+	// e.g. for the templates of the seed chart.
+	APIVersion() string
+	// New returns an EnvoyFilter with the given namespace and name, e.g. to
+	// get, create or patch it.
+	New(namespace, name string) *unstructured.Unstructured
+	// NewList returns an empty list of EnvoyFilters.
+	NewList() *unstructured.UnstructuredList
+}
+
+// NewWriter returns a Writer for the given API version of EnvoyFilters.
+func NewWriter(version string) Writer {
+	return &versionedWriter{gvk: GroupKind.WithVersion(version)}
+}
+
+// DiscoverWriter returns a Writer for the most preferred supported API version
+// of EnvoyFilters served by the seed, falling back to DefaultVersion if none
+// is served. The mapper is expected to discover versions installed later on,
+// like the dynamic RESTMapper of controller-runtime, so an istio upgrade
+// serving other versions is picked up without restarting the extension.
+func DiscoverWriter(mapper meta.RESTMapper) (Writer, error) {
+	mappings, err := mapper.RESTMappings(GroupKind)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return NewWriter(DefaultVersion), nil
+		}
+		return nil, err
+	}
+
+	for _, version := range supportedVersions {
+		if slices.ContainsFunc(mappings, func(mapping *meta.RESTMapping) bool {
+			return mapping.GroupVersionKind.Version == version
+		}) {
+			return NewWriter(version), nil
+		}
+	}
+	return NewWriter(DefaultVersion), nil
+}
+
+type versionedWriter struct {
+	gvk schema.GroupVersionKind
+}
+
+func (w *versionedWriter) GroupVersionKind() schema.GroupVersionKind {
+	return w.gvk
+}
+
+func (w *versionedWriter) APIVersion() string {
+	return w.gvk.GroupVersion().String()
+}
+
+func (w *versionedWriter) New(namespace, name string) *unstructured.Unstructured {
+	envoyFilter := &unstructured.Unstructured{}
+	envoyFilter.SetGroupVersionKind(w.gvk)
+	envoyFilter.SetNamespace(namespace)
+	envoyFilter.SetName(name)
+	return envoyFilter
+}
+
+func (w *versionedWriter) NewList() *unstructured.UnstructuredList {
+	envoyFilters := &unstructured.UnstructuredList{}
+	envoyFilters.SetGroupVersionKind(w.gvk.GroupVersion().WithKind(w.gvk.Kind + "List"))
+	return envoyFilters
+}
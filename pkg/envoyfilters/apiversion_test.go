@@ -0,0 +1,65 @@
+package envoyfilters
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("API versions", func() {
+	Describe("#DiscoverWriter", func() {
+		newMapper := func(versions ...string) meta.RESTMapper {
+			var groupVersions []schema.GroupVersion
+			for _, version := range versions {
+				groupVersions = append(groupVersions, schema.GroupVersion{Group: GroupKind.Group, Version: version})
+			}
+			mapper := meta.NewDefaultRESTMapper(groupVersions)
+			for _, gv := range groupVersions {
+				mapper.Add(gv.WithKind(GroupKind.Kind), meta.RESTScopeNamespace)
+			}
+			return mapper
+		}
+
+		It("should write the most preferred served version", func() {
+			writer, err := DiscoverWriter(newMapper("v1alpha3", "v1"))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(writer.APIVersion()).To(Equal("networking.istio.io/v1"))
+		})
+
+		It("should write v1alpha3 if it is the only served version", func() {
+			writer, err := DiscoverWriter(newMapper("v1alpha3"))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(writer.APIVersion()).To(Equal("networking.istio.io/v1alpha3"))
+		})
+
+		It("should fall back to the default version if no EnvoyFilters are served", func() {
+			writer, err := DiscoverWriter(newMapper())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(writer.GroupVersionKind()).To(Equal(GroupKind.WithVersion(DefaultVersion)))
+		})
+
+		It("should ignore unsupported versions", func() {
+			writer, err := DiscoverWriter(newMapper("v2alpha1"))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(writer.GroupVersionKind().Version).To(Equal(DefaultVersion))
+		})
+	})
+
+	Describe("#NewWriter", func() {
+		It("should create EnvoyFilters and lists of the API version", func() {
+			writer := NewWriter("v1")
+
+			envoyFilter := writer.New("istio-ingress", "shoot--foo--bar")
+			Expect(envoyFilter.GetAPIVersion()).To(Equal("networking.istio.io/v1"))
+			Expect(envoyFilter.GetKind()).To(Equal("EnvoyFilter"))
+			Expect(envoyFilter.GetNamespace()).To(Equal("istio-ingress"))
+			Expect(envoyFilter.GetName()).To(Equal("shoot--foo--bar"))
+			Expect(writer.NewList().GetKind()).To(Equal("EnvoyFilterList"))
+		})
+	})
+})
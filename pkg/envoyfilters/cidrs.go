@@ -0,0 +1,223 @@
+package envoyfilters
+
+import (
+	"cmp"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// CIDROverlap is a CIDR which is fully contained in another CIDR of the same
+// list.
+type CIDROverlap struct {
+	// CIDR is the contained CIDR, it doesn't have any effect.
+	CIDR string
+	// ContainedIn is the largest CIDR containing CIDR.
+	ContainedIn string
+}
+
+// MaskedCIDR returns the network of the CIDR without its host bits and
+// whether host bits were set, e.g. "10.1.2.0/24" and true for "10.1.2.3/24".
+// Envoy matches such CIDRs as their whole network, which is easily mistaken
+// for the single host. Invalid CIDRs are returned as they are.
+func MaskedCIDR(cidr string) (string, bool) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil || prefix == prefix.Masked() {
+		return cidr, false
+	}
+	return prefix.Masked().String(), true
+}
+
+// MaskCIDRs removes the host bits of the CIDRs and the except blocks of the
+// rule, see MaskedCIDR.
+func (r *ACLRule) MaskCIDRs() {
+	for i, cidr := range r.Cidrs {
+		r.Cidrs[i], _ = MaskedCIDR(cidr)
+	}
+	for i, cidr := range r.Except {
+		r.Except[i], _ = MaskedCIDR(cidr)
+	}
+}
+
+// CanonicalCIDR returns the canonical form of the CIDR, e.g. "2001:db8::1/64"
+// for "2001:DB8:0::1/64". Host bits are kept, see MaskedCIDR. Invalid CIDRs
+// are returned as they are.
+func CanonicalCIDR(cidr string) string {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+	if err != nil {
+		return cidr
+	}
+	return prefix.String()
+}
+
+// CanonicalCIDRs returns the CIDRs in their canonical form, sorted by address
+// and prefix length, IPv4 before IPv6, without repeated entries. Invalid
+// CIDRs are sorted after the valid ones.
+func CanonicalCIDRs(cidrs []string) []string {
+	canonical := make([]string, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		canonical = append(canonical, CanonicalCIDR(cidr))
+	}
+	slices.SortFunc(canonical, func(a, b string) int {
+		prefixA, errA := netip.ParsePrefix(a)
+		prefixB, errB := netip.ParsePrefix(b)
+		switch {
+		case errA != nil && errB != nil:
+			return strings.Compare(a, b)
+		case errA != nil:
+			return 1
+		case errB != nil:
+			return -1
+		}
+		return cmp.Or(prefixA.Addr().Compare(prefixB.Addr()), cmp.Compare(prefixA.Bits(), prefixB.Bits()))
+	})
+	return slices.Compact(canonical)
+}
+
+// DuplicateCIDR returns the first CIDR describing the same network as a
+// previous CIDR of the list, e.g. "10.0.0.0/8" and "10.1.2.3/8". It returns an
+// empty string if all networks are unique. Invalid CIDRs are ignored.
+func DuplicateCIDR(cidrs []string) string {
+	networks := make(map[string]struct{}, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		if _, ok := networks[network.String()]; ok {
+			return cidr
+		}
+		networks[network.String()] = struct{}{}
+	}
+	return ""
+}
+
+// OverlappingCIDRs returns the CIDRs of the list which are fully contained in
+// a larger CIDR of the list, e.g. "10.1.0.0/16" in "10.0.0.0/8". A CIDR isn't
+// covered by a larger CIDR if one of the except blocks of the larger CIDR
+// contains it, e.g. "10.1.2.0/24" still allows its addresses next to
+// "10.0.0.0/8" except "10.1.0.0/16". Duplicates and invalid CIDRs are ignored.
+func OverlappingCIDRs(cidrs, excepts []string) []CIDROverlap {
+	set := NewCIDRSet(cidrs...)
+	exceptSet := NewCIDRSet(excepts...)
+	// the first CIDR of the list describing each network of the set
+	first := make(map[netip.Prefix]string, len(cidrs))
+	for _, cidr := range cidrs {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			if _, ok := first[prefix.Masked()]; !ok {
+				first[prefix.Masked()] = cidr
+			}
+		}
+	}
+
+	var overlaps []CIDROverlap
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		for _, supernet := range set.Supernets(prefix) {
+			if !exceptedBy(exceptSet.Subnets(supernet), prefix) {
+				overlaps = append(overlaps, CIDROverlap{CIDR: cidr, ContainedIn: first[supernet]})
+				break
+			}
+		}
+	}
+	return overlaps
+}
+
+// exceptedBy returns whether one of the except blocks is larger than the
+// network and contains it.
+func exceptedBy(excepts []netip.Prefix, prefix netip.Prefix) bool {
+	for _, except := range excepts {
+		if except.Bits() < prefix.Bits() && except.Contains(prefix.Addr()) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeCIDRs canonicalizes the CIDRs (e.g. "10.1.2.3/8" to "10.0.0.0/8"),
+// removes duplicates and CIDRs contained in other CIDRs of the list, and merges
+// adjacent CIDRs into their common supernet (e.g. "10.0.0.0/25" and
+// "10.0.0.128/25" into "10.0.0.0/24") as long as possible. The result matches
+// exactly the same addresses with the least number of CIDRs, which keeps the
+// principals pushed to Envoy small. The CIDRs keep the order of their first
+// occurrence, a merged CIDR takes the place of the first CIDR it contains.
+// Invalid CIDRs are kept as they are.
+func NormalizeCIDRs(cidrs []string) []string {
+	var normalized []aggregatedPrefix
+	for i, cidr := range cidrs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			normalized = append(normalized, aggregatedPrefix{index: i})
+		}
+	}
+	normalized = append(normalized, NewCIDRSet(cidrs...).aggregate()...)
+	slices.SortFunc(normalized, func(a, b aggregatedPrefix) int { return cmp.Compare(a.index, b.index) })
+
+	result := make([]string, 0, len(normalized))
+	for _, p := range normalized {
+		if p.prefix.IsValid() {
+			result = append(result, p.prefix.String())
+		} else {
+			result = append(result, cidrs[p.index])
+		}
+	}
+	return result
+}
+
+// exceptedCIDR is a normalized CIDR of a rule together with the except blocks
+// contained in it.
+type exceptedCIDR struct {
+	cidr    string
+	excepts []string
+}
+
+// normalizeRuleCIDRs normalizes the CIDRs of a rule like NormalizeCIDRs, but
+// only merges CIDRs containing the same except blocks. An except block only
+// applies to the CIDRs containing it, so a CIDR within a larger CIDR whose
+// except blocks overlap it still allows the excepted addresses on its own and
+// must not be absorbed by the larger CIDR (e.g. "10.1.2.0/24" next to
+// "10.0.0.0/8" except "10.1.0.0/16"). The groups of CIDRs sharing the same
+// except blocks keep the order of their first CIDR, every returned CIDR comes
+// with the normalized except blocks it contains.
+func normalizeRuleCIDRs(cidrs, excepts []string) []exceptedCIDR {
+	exceptSet := NewCIDRSet(excepts...)
+
+	var keys []string
+	groups := map[string][]string{}
+	groupExcepts := map[string]*CIDRSet{}
+	for _, cidr := range cidrs {
+		var contained []string
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			for _, except := range exceptSet.Subnets(prefix.Masked()) {
+				contained = append(contained, except.String())
+			}
+		}
+		key := strings.Join(contained, ",")
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+			groupExcepts[key] = NewCIDRSet(contained...)
+		}
+		groups[key] = append(groups[key], cidr)
+	}
+
+	var result []exceptedCIDR
+	for _, key := range keys {
+		for _, cidr := range NormalizeCIDRs(groups[key]) {
+			normalized := exceptedCIDR{cidr: cidr}
+			if prefix, err := netip.ParsePrefix(cidr); err == nil {
+				var contained []string
+				for _, except := range groupExcepts[key].Subnets(prefix) {
+					contained = append(contained, except.String())
+				}
+				if len(contained) > 0 {
+					normalized.excepts = NormalizeCIDRs(contained)
+				}
+			}
+			result = append(result, normalized)
+		}
+	}
+	return result
+}
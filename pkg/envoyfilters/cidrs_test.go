@@ -0,0 +1,229 @@
+package envoyfilters
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NormalizeCIDRs", func() {
+	DescribeTable("should normalize the CIDRs",
+		func(cidrs, expected []string) {
+			Expect(NormalizeCIDRs(cidrs)).To(Equal(expected))
+		},
+		Entry("empty list", nil, []string{}),
+		Entry("canonical form", []string{"10.1.2.3/8", "1.2.3.4/32"}, []string{"10.0.0.0/8", "1.2.3.4/32"}),
+		Entry("duplicates", []string{"10.0.0.0/8", "10.1.2.3/8", "1.2.3.4/32", "1.2.3.4/32"}, []string{"10.0.0.0/8", "1.2.3.4/32"}),
+		Entry("contained CIDRs", []string{"10.1.0.0/16", "192.168.0.0/16", "10.0.0.0/8"}, []string{"10.0.0.0/8", "192.168.0.0/16"}),
+		Entry("adjacent halves", []string{"10.0.0.128/25", "10.0.0.0/25"}, []string{"10.0.0.0/24"}),
+		Entry("merged repeatedly", []string{"10.0.0.0/26", "10.0.0.64/26", "10.0.0.128/25", "10.0.1.0/24"}, []string{"10.0.0.0/23"}),
+		Entry("adjacent CIDRs of different supernets", []string{"10.0.0.128/25", "10.0.1.0/25"}, []string{"10.0.0.128/25", "10.0.1.0/25"}),
+		Entry("IPv6", []string{"2001:db8::/33", "2001:db8:8000::/33", "2001:db8:1::/48"}, []string{"2001:db8::/32"}),
+		Entry("IPv4 and IPv6 kept apart", []string{"0.0.0.0/0", "::/0", "::/1"}, []string{"0.0.0.0/0", "::/0"}),
+		Entry("invalid CIDRs kept in place", []string{"foo", "10.0.0.0/25", "10.0.0.128/25"}, []string{"foo", "10.0.0.0/24"}),
+	)
+
+	Describe("properties", func() {
+		const iterations = 500
+		// the CIDRs are generated within a small network, so all of its
+		// addresses can be checked
+		network := netip.MustParsePrefix("10.0.0.0/22")
+		var random *rand.Rand
+
+		BeforeEach(func() {
+			random = rand.New(rand.NewPCG(42, 42)) //nolint:gosec // reproducible test data
+		})
+
+		randomCIDRs := func() []string {
+			cidrs := make([]string, random.IntN(12))
+			for i := range cidrs {
+				addr := network.Addr().As4()
+				offset := random.IntN(1 << (32 - network.Bits()))
+				addr[2] += byte(offset >> 8)
+				addr[3] = byte(offset)
+				bits := network.Bits() + random.IntN(32-network.Bits()+1)
+				cidrs[i] = netip.PrefixFrom(netip.AddrFrom4(addr), bits).String()
+			}
+			return cidrs
+		}
+
+		contains := func(cidrs []string, addr netip.Addr) bool {
+			for _, cidr := range cidrs {
+				if netip.MustParsePrefix(cidr).Contains(addr) {
+					return true
+				}
+			}
+			return false
+		}
+
+		It("should match exactly the same addresses", func() {
+			for range iterations {
+				cidrs := randomCIDRs()
+				normalized := NormalizeCIDRs(cidrs)
+				for addr := network.Addr(); network.Contains(addr); addr = addr.Next() {
+					Expect(contains(normalized, addr)).To(Equal(contains(cidrs, addr)),
+						fmt.Sprintf("address %s of %v normalized to %v", addr, cidrs, normalized))
+				}
+			}
+		})
+
+		It("should return disjoint CIDRs in canonical form which can't be merged", func() {
+			for range iterations {
+				cidrs := randomCIDRs()
+				normalized := NormalizeCIDRs(cidrs)
+				Expect(len(normalized)).To(BeNumerically("<=", len(cidrs)))
+				for i, cidr := range normalized {
+					prefix := netip.MustParsePrefix(cidr)
+					Expect(prefix).To(Equal(prefix.Masked()), fmt.Sprintf("%v normalized to %v", cidrs, normalized))
+					for _, other := range normalized[i+1:] {
+						otherPrefix := netip.MustParsePrefix(other)
+						Expect(prefix.Overlaps(otherPrefix)).To(BeFalse(), fmt.Sprintf("%v normalized to %v", cidrs, normalized))
+						if prefix.Bits() == otherPrefix.Bits() && prefix.Bits() > 0 {
+							supernet := netip.PrefixFrom(prefix.Addr(), prefix.Bits()-1).Masked()
+							Expect(supernet.Contains(otherPrefix.Addr())).To(BeFalse(), fmt.Sprintf("%v normalized to %v", cidrs, normalized))
+						}
+					}
+				}
+			}
+		})
+
+		It("should be idempotent", func() {
+			for range iterations {
+				normalized := NormalizeCIDRs(randomCIDRs())
+				Expect(NormalizeCIDRs(normalized)).To(Equal(normalized))
+			}
+		})
+	})
+})
+
+var _ = Describe("normalizeRuleCIDRs", func() {
+	DescribeTable("should only merge CIDRs containing the same except blocks",
+		func(cidrs, excepts []string, expected []exceptedCIDR) {
+			Expect(normalizeRuleCIDRs(cidrs, excepts)).To(Equal(expected))
+		},
+		Entry("no except blocks", []string{"10.1.0.0/16", "10.0.0.0/8"}, nil, []exceptedCIDR{{cidr: "10.0.0.0/8"}}),
+		Entry("CIDR within an except block of a larger CIDR",
+			[]string{"10.0.0.0/8", "10.1.2.0/24"}, []string{"10.1.0.0/16"},
+			[]exceptedCIDR{{cidr: "10.0.0.0/8", excepts: []string{"10.1.0.0/16"}}, {cidr: "10.1.2.0/24"}},
+		),
+		Entry("CIDR next to an except block of a larger CIDR",
+			[]string{"10.0.0.0/8", "10.2.0.0/16"}, []string{"10.1.0.0/16"},
+			[]exceptedCIDR{{cidr: "10.0.0.0/8", excepts: []string{"10.1.0.0/16"}}, {cidr: "10.2.0.0/16"}},
+		),
+		Entry("CIDRs containing the same except blocks",
+			[]string{"10.0.0.0/8", "10.1.0.0/16"}, []string{"10.1.2.0/25", "10.1.2.128/25"},
+			[]exceptedCIDR{{cidr: "10.0.0.0/8", excepts: []string{"10.1.2.0/24"}}},
+		),
+	)
+
+	Describe("properties", func() {
+		const iterations = 500
+		network := netip.MustParsePrefix("10.0.0.0/22")
+		var random *rand.Rand
+
+		BeforeEach(func() {
+			random = rand.New(rand.NewPCG(42, 42)) //nolint:gosec // reproducible test data
+		})
+
+		randomCIDRs := func(maxCount int) []string {
+			cidrs := make([]string, random.IntN(maxCount))
+			for i := range cidrs {
+				addr := network.Addr().As4()
+				offset := random.IntN(1 << (32 - network.Bits()))
+				addr[2] += byte(offset >> 8)
+				addr[3] = byte(offset)
+				bits := network.Bits() + random.IntN(32-network.Bits()+1)
+				cidrs[i] = netip.PrefixFrom(netip.AddrFrom4(addr), bits).String()
+			}
+			return cidrs
+		}
+
+		// matches evaluates an RBAC principal like envoy does
+		var matches func(principal map[string]interface{}, addr netip.Addr) bool
+		matches = func(principal map[string]interface{}, addr netip.Addr) bool {
+			for key, value := range principal {
+				switch key {
+				case "and_ids":
+					for _, id := range value.(map[string]interface{})["ids"].([]map[string]interface{}) {
+						if !matches(id, addr) {
+							return false
+						}
+					}
+					return true
+				case "or_ids":
+					for _, id := range value.(map[string]interface{})["ids"].([]map[string]interface{}) {
+						if matches(id, addr) {
+							return true
+						}
+					}
+					return false
+				case "not_id":
+					return !matches(value.(map[string]interface{}), addr)
+				default:
+					cidr := value.(map[string]interface{})
+					prefix := netip.PrefixFrom(netip.MustParseAddr(cidr["address_prefix"].(string)), cidr["prefix_len"].(int))
+					return prefix.Contains(addr)
+				}
+			}
+			return false
+		}
+
+		DescribeTable("should render principals deciding like Evaluate",
+			func(action string) {
+				for range iterations {
+					rule := &ACLRule{
+						Action:      action,
+						Type:        "remote_ip",
+						Cidrs:       randomCIDRs(8),
+						Except:      randomCIDRs(4),
+						DeniedCIDRs: randomCIDRs(2),
+					}
+					alwaysAllowedCIDRs := randomCIDRs(2)
+					principals := ruleCIDRsToPrincipal(rule, alwaysAllowedCIDRs)
+
+					for addr := network.Addr(); network.Contains(addr); addr = addr.Next() {
+						matched := false
+						for _, principal := range principals {
+							if matches(principal, addr) {
+								matched = true
+								break
+							}
+						}
+						// the principals of "DENY" rules match the denied sources
+						allowed := matched == rule.RestrictsOtherSources()
+						Expect(allowed).To(Equal(rule.Evaluate(net.IP(addr.AsSlice()), alwaysAllowedCIDRs).Allowed),
+							fmt.Sprintf("address %s of %+v with always allowed CIDRs %v", addr, rule, alwaysAllowedCIDRs))
+					}
+				}
+			},
+			Entry("ALLOW rules", "ALLOW"),
+			Entry("DENY rules", "DENY"),
+		)
+	})
+})
+
+var _ = Describe("MaskedCIDR", func() {
+	DescribeTable("should remove the host bits",
+		func(cidr, expected string, hostBits bool) {
+			masked, ok := MaskedCIDR(cidr)
+			Expect(masked).To(Equal(expected))
+			Expect(ok).To(Equal(hostBits))
+		},
+		Entry("network", "10.1.2.0/24", "10.1.2.0/24", false),
+		Entry("host bits", "10.1.2.3/24", "10.1.2.0/24", true),
+		Entry("single host", "10.1.2.3/32", "10.1.2.3/32", false),
+		Entry("IPv6 host bits", "2001:db8::1/64", "2001:db8::/64", true),
+		Entry("invalid CIDR", "foo", "foo", false),
+	)
+
+	It("should mask the CIDRs and except blocks of a rule", func() {
+		rule := &ACLRule{Cidrs: []string{"10.1.2.3/16", "1.2.3.4/32"}, Except: []string{"10.1.2.3/24"}}
+		rule.MaskCIDRs()
+		Expect(rule.Cidrs).To(Equal([]string{"10.1.0.0/16", "1.2.3.4/32"}))
+		Expect(rule.Except).To(Equal([]string{"10.1.2.0/24"}))
+	})
+})
@@ -0,0 +1,261 @@
+package envoyfilters
+
+import (
+	"net/netip"
+)
+
+// CIDRSet is a set of CIDRs stored in a binary radix trie per address family,
+// so looking up an address or a network only takes as many steps as the
+// address has bits, no matter how many CIDRs the set contains. It is used for
+// the set operations on large lists, e.g. the CIDRs of a rule imported from an
+// IPAM. The CIDRs are stored in their canonical form, invalid CIDRs are
+// ignored. The zero value is an empty set.
+type CIDRSet struct {
+	roots [2]*cidrNode
+	// inserted is the number of CIDRs inserted so far.
+	inserted int
+}
+
+type cidrNode struct {
+	prefix   netip.Prefix
+	children [2]*cidrNode
+	// index is the position of the first occurrence of the CIDR in the lists
+	// inserted into the set, it is -1 if the node isn't a CIDR of the set.
+	index int
+}
+
+func (n *cidrNode) isMember() bool {
+	return n.index >= 0
+}
+
+// NewCIDRSet returns a set containing the given CIDRs.
+func NewCIDRSet(cidrs ...string) *CIDRSet {
+	s := &CIDRSet{}
+	s.Insert(cidrs...)
+	return s
+}
+
+// Insert adds the given CIDRs to the set.
+func (s *CIDRSet) Insert(cidrs ...string) {
+	for _, cidr := range cidrs {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			s.insert(prefix, s.inserted)
+		}
+		s.inserted++
+	}
+}
+
+// Contains returns whether the address is contained in a CIDR of the set.
+func (s *CIDRSet) Contains(addr netip.Addr) bool {
+	return s.ContainsPrefix(netip.PrefixFrom(addr, addr.BitLen()))
+}
+
+// ContainsPrefix returns whether the network is fully contained in a CIDR of
+// the set.
+func (s *CIDRSet) ContainsPrefix(prefix netip.Prefix) bool {
+	found := false
+	s.walk(prefix, func(node *cidrNode) bool {
+		found = node.isMember()
+		return !found
+	})
+	return found
+}
+
+// Overlaps returns whether the network has at least one address in common with
+// a CIDR of the set.
+func (s *CIDRSet) Overlaps(prefix netip.Prefix) bool {
+	node, contained := s.lookup(prefix)
+	return contained || node != nil
+}
+
+// Subnets returns the CIDRs of the set which are contained in the given
+// network, ordered by their address.
+func (s *CIDRSet) Subnets(prefix netip.Prefix) []netip.Prefix {
+	node, _ := s.lookup(prefix)
+	if node == nil {
+		return nil
+	}
+	var subnets []netip.Prefix
+	node.each(func(member *cidrNode) {
+		subnets = append(subnets, member.prefix)
+	})
+	return subnets
+}
+
+// Supernets returns the CIDRs of the set which contain the given network but
+// are larger than it, starting with the largest one.
+func (s *CIDRSet) Supernets(prefix netip.Prefix) []netip.Prefix {
+	var supernets []netip.Prefix
+	s.walk(prefix, func(node *cidrNode) bool {
+		if node.isMember() && node.prefix.Bits() < prefix.Bits() {
+			supernets = append(supernets, node.prefix)
+		}
+		return true
+	})
+	return supernets
+}
+
+// Prefixes returns the smallest list of CIDRs matching exactly the addresses
+// of the set, i.e. CIDRs contained in other CIDRs are left out and adjacent
+// CIDRs are merged into their common supernet (e.g. "10.0.0.0/25" and
+// "10.0.0.128/25" into "10.0.0.0/24"). The IPv4 CIDRs are ordered by their
+// address, followed by the IPv6 CIDRs.
+func (s *CIDRSet) Prefixes() []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, aggregate := range s.aggregate() {
+		prefixes = append(prefixes, aggregate.prefix)
+	}
+	return prefixes
+}
+
+// Difference returns the smallest list of CIDRs matching the addresses of the
+// given network which are not contained in any CIDR of the set, ordered by
+// their address.
+func (s *CIDRSet) Difference(prefix netip.Prefix) []netip.Prefix {
+	if !prefix.IsValid() {
+		return nil
+	}
+	prefix = prefix.Masked()
+	if s.ContainsPrefix(prefix) {
+		return nil
+	}
+	if !s.Overlaps(prefix) {
+		return []netip.Prefix{prefix}
+	}
+	lower, upper := halves(prefix)
+	return append(s.Difference(lower), s.Difference(upper)...)
+}
+
+func (s *CIDRSet) insert(prefix netip.Prefix, index int) {
+	prefix = prefix.Masked()
+	family := familyOf(prefix.Addr())
+	if s.roots[family] == nil {
+		s.roots[family] = &cidrNode{prefix: netip.PrefixFrom(prefix.Addr(), 0).Masked(), index: -1}
+	}
+
+	node := s.roots[family]
+	for bits := 0; bits < prefix.Bits(); bits++ {
+		bit := addressBit(prefix.Addr(), bits)
+		if node.children[bit] == nil {
+			node.children[bit] = &cidrNode{prefix: netip.PrefixFrom(prefix.Addr(), bits+1).Masked(), index: -1}
+		}
+		node = node.children[bit]
+	}
+	if !node.isMember() {
+		node.index = index
+	}
+}
+
+// walk calls visit for the nodes on the path to the given network in the trie,
+// starting with the root, until visit returns false.
+func (s *CIDRSet) walk(prefix netip.Prefix, visit func(*cidrNode) bool) {
+	if !prefix.IsValid() {
+		return
+	}
+	prefix = prefix.Masked()
+	node := s.roots[familyOf(prefix.Addr())]
+	for bits := 0; node != nil; bits++ {
+		if !visit(node) || bits == prefix.Bits() {
+			return
+		}
+		node = node.children[addressBit(prefix.Addr(), bits)]
+	}
+}
+
+// lookup returns the node of the given network, if there are CIDRs of the set
+// contained in it, and whether a CIDR of the set contains it.
+func (s *CIDRSet) lookup(prefix netip.Prefix) (node *cidrNode, contained bool) {
+	s.walk(prefix, func(visited *cidrNode) bool {
+		if visited.prefix.Bits() == prefix.Bits() {
+			node = visited
+		}
+		contained = contained || visited.isMember()
+		return true
+	})
+	return node, contained
+}
+
+// each calls visit for the CIDRs of the set in the subtree of the node,
+// ordered by their address and size.
+func (n *cidrNode) each(visit func(*cidrNode)) {
+	if n.isMember() {
+		visit(n)
+	}
+	for _, child := range n.children {
+		if child != nil {
+			child.each(visit)
+		}
+	}
+}
+
+type aggregatedPrefix struct {
+	prefix netip.Prefix
+	// index is the first occurrence of a CIDR contained in the prefix.
+	index int
+}
+
+func (s *CIDRSet) aggregate() []aggregatedPrefix {
+	var aggregates []aggregatedPrefix
+	for _, root := range s.roots {
+		if root != nil {
+			aggregates = append(aggregates, root.aggregate()...)
+		}
+	}
+	return aggregates
+}
+
+// aggregate returns the smallest list of CIDRs matching the addresses of the
+// CIDRs in the subtree of the node.
+func (n *cidrNode) aggregate() []aggregatedPrefix {
+	if n.isMember() {
+		index := n.index
+		n.each(func(member *cidrNode) { index = min(index, member.index) })
+		return []aggregatedPrefix{{prefix: n.prefix, index: index}}
+	}
+
+	var halves [2][]aggregatedPrefix
+	for bit, child := range n.children {
+		if child != nil {
+			halves[bit] = child.aggregate()
+		}
+	}
+	// the node is covered completely if both halves are
+	if len(halves[0]) == 1 && len(halves[1]) == 1 &&
+		halves[0][0].prefix == n.children[0].prefix && halves[1][0].prefix == n.children[1].prefix {
+		return []aggregatedPrefix{{prefix: n.prefix, index: min(halves[0][0].index, halves[1][0].index)}}
+	}
+	return append(halves[0], halves[1]...)
+}
+
+// halves returns both halves of the (masked) network.
+func halves(prefix netip.Prefix) (lower, upper netip.Prefix) {
+	position := prefix.Bits()
+	if prefix.Addr().Is4() {
+		position += 96
+	}
+	bytes := prefix.Addr().As16()
+	bytes[position/8] |= 1 << (7 - position%8)
+	upperAddr := netip.AddrFrom16(bytes)
+	if prefix.Addr().Is4() {
+		upperAddr = upperAddr.Unmap()
+	}
+	return netip.PrefixFrom(prefix.Addr(), prefix.Bits()+1), netip.PrefixFrom(upperAddr, prefix.Bits()+1)
+}
+
+func familyOf(addr netip.Addr) int {
+	if addr.Is4() {
+		return 0
+	}
+	return 1
+}
+
+// addressBit returns the bit of the address at the given position, counted
+// from the most significant bit.
+func addressBit(addr netip.Addr, position int) int {
+	if addr.Is4() {
+		// IPv4 addresses are stored in the last 4 bytes
+		position += 96
+	}
+	bytes := addr.As16()
+	return int(bytes[position/8]>>(7-position%8)) & 1
+}
@@ -0,0 +1,130 @@
+package envoyfilters
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CIDRSet", func() {
+	var set *CIDRSet
+
+	BeforeEach(func() {
+		set = NewCIDRSet("10.0.0.0/8", "192.168.1.0/24", "192.168.2.7/32", "2001:db8::/32", "foo")
+	})
+
+	It("should contain the addresses of its CIDRs", func() {
+		Expect(set.Contains(netip.MustParseAddr("10.1.2.3"))).To(BeTrue())
+		Expect(set.Contains(netip.MustParseAddr("192.168.2.7"))).To(BeTrue())
+		Expect(set.Contains(netip.MustParseAddr("2001:db8::1"))).To(BeTrue())
+		Expect(set.Contains(netip.MustParseAddr("192.168.2.8"))).To(BeFalse())
+		Expect(set.Contains(netip.MustParseAddr("11.0.0.1"))).To(BeFalse())
+		Expect(set.Contains(netip.MustParseAddr("2001:db9::1"))).To(BeFalse())
+	})
+
+	It("should not mix up the address families", func() {
+		Expect(NewCIDRSet("0.0.0.0/0").Contains(netip.MustParseAddr("::1"))).To(BeFalse())
+		Expect(NewCIDRSet("::/0").Contains(netip.MustParseAddr("1.2.3.4"))).To(BeFalse())
+	})
+
+	It("should contain the networks contained in its CIDRs", func() {
+		Expect(set.ContainsPrefix(netip.MustParsePrefix("10.1.0.0/16"))).To(BeTrue())
+		Expect(set.ContainsPrefix(netip.MustParsePrefix("10.0.0.0/8"))).To(BeTrue())
+		Expect(set.ContainsPrefix(netip.MustParsePrefix("10.0.0.0/7"))).To(BeFalse())
+		Expect(set.ContainsPrefix(netip.MustParsePrefix("192.168.0.0/16"))).To(BeFalse())
+	})
+
+	It("should find overlapping networks", func() {
+		Expect(set.Overlaps(netip.MustParsePrefix("10.1.0.0/16"))).To(BeTrue())
+		Expect(set.Overlaps(netip.MustParsePrefix("192.168.0.0/16"))).To(BeTrue())
+		Expect(set.Overlaps(netip.MustParsePrefix("192.168.3.0/24"))).To(BeFalse())
+	})
+
+	It("should return the subnets and supernets of a network", func() {
+		Expect(set.Subnets(netip.MustParsePrefix("192.168.0.0/16"))).To(Equal([]netip.Prefix{
+			netip.MustParsePrefix("192.168.1.0/24"),
+			netip.MustParsePrefix("192.168.2.7/32"),
+		}))
+		Expect(set.Subnets(netip.MustParsePrefix("10.1.0.0/16"))).To(BeEmpty())
+		Expect(set.Supernets(netip.MustParsePrefix("10.1.0.0/16"))).To(Equal([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}))
+		Expect(set.Supernets(netip.MustParsePrefix("10.0.0.0/8"))).To(BeEmpty())
+	})
+
+	It("should return the aggregated CIDRs", func() {
+		set.Insert("10.1.0.0/16", "11.0.0.0/8", "192.168.0.0/24")
+		Expect(set.Prefixes()).To(Equal([]netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/7"),
+			netip.MustParsePrefix("192.168.0.0/23"),
+			netip.MustParsePrefix("192.168.2.7/32"),
+			netip.MustParsePrefix("2001:db8::/32"),
+		}))
+	})
+
+	It("should return the addresses of a network not contained in the set", func() {
+		Expect(set.Difference(netip.MustParsePrefix("10.0.0.0/7"))).To(Equal([]netip.Prefix{netip.MustParsePrefix("11.0.0.0/8")}))
+		Expect(set.Difference(netip.MustParsePrefix("192.168.0.0/22"))).To(Equal([]netip.Prefix{
+			netip.MustParsePrefix("192.168.0.0/24"),
+			netip.MustParsePrefix("192.168.2.0/30"),
+			netip.MustParsePrefix("192.168.2.4/31"),
+			netip.MustParsePrefix("192.168.2.6/32"),
+			netip.MustParsePrefix("192.168.2.8/29"),
+			netip.MustParsePrefix("192.168.2.16/28"),
+			netip.MustParsePrefix("192.168.2.32/27"),
+			netip.MustParsePrefix("192.168.2.64/26"),
+			netip.MustParsePrefix("192.168.2.128/25"),
+			netip.MustParsePrefix("192.168.3.0/24"),
+		}))
+		Expect(set.Difference(netip.MustParsePrefix("10.1.0.0/16"))).To(BeEmpty())
+		Expect(set.Difference(netip.MustParsePrefix("::ffff:10.0.0.0/104"))).To(Equal([]netip.Prefix{netip.MustParsePrefix("::ffff:10.0.0.0/104")}))
+	})
+
+	It("should answer like the CIDRs of the list", func() {
+		random := rand.New(rand.NewPCG(7, 7)) //nolint:gosec // reproducible test data
+		randomPrefix := func() netip.Prefix {
+			addr := netip.AddrFrom4([4]byte{10, 0, byte(random.IntN(4)), byte(random.IntN(256))})
+			return netip.PrefixFrom(addr, 22+random.IntN(11)).Masked()
+		}
+
+		for range 500 {
+			var cidrs []string
+			var prefixes []netip.Prefix
+			for range random.IntN(12) {
+				prefix := randomPrefix()
+				cidrs = append(cidrs, prefix.String())
+				prefixes = append(prefixes, prefix)
+			}
+			set := NewCIDRSet(cidrs...)
+
+			query := randomPrefix()
+			var containsAddr, contained, overlaps bool
+			for _, prefix := range prefixes {
+				containsAddr = containsAddr || prefix.Contains(query.Addr())
+				contained = contained || (prefix.Bits() <= query.Bits() && prefix.Contains(query.Addr()))
+				overlaps = overlaps || prefix.Overlaps(query)
+			}
+			Expect(set.Contains(query.Addr())).To(Equal(containsAddr), fmt.Sprintf("%s in %v", query.Addr(), cidrs))
+			Expect(set.ContainsPrefix(query)).To(Equal(contained), fmt.Sprintf("%s in %v", query, cidrs))
+			Expect(set.Overlaps(query)).To(Equal(overlaps), fmt.Sprintf("%s overlapping %v", query, cidrs))
+
+			difference := &CIDRSet{}
+			for _, prefix := range set.Difference(query) {
+				difference.Insert(prefix.String())
+			}
+			addr := randomPrefix().Addr()
+			inDifference := query.Contains(addr) && !set.Contains(addr)
+			Expect(difference.Contains(addr)).To(Equal(inDifference), fmt.Sprintf("%s in %s minus %v", addr, query, cidrs))
+		}
+	})
+
+	It("should handle large lists", func() {
+		var cidrs []string
+		for i := range 1 << 14 {
+			cidrs = append(cidrs, fmt.Sprintf("10.%d.%d.0/24", i>>8, i&0xff))
+		}
+		Expect(NewCIDRSet(cidrs...).Prefixes()).To(Equal([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/10")}))
+		Expect(NormalizeCIDRs(cidrs)).To(Equal([]string{"10.0.0.0/10"}))
+	})
+})
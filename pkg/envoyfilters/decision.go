@@ -0,0 +1,117 @@
+package envoyfilters
+
+import (
+	"fmt"
+	"net"
+)
+
+// Decision is the result of evaluating an ACLRule for a single source IP.
+type Decision struct {
+	// Allowed is true if a connection from the IP is allowed.
+	Allowed bool `json:"allowed"`
+	// RateLimited is true if an allowed connection counts against the budget
+	// of a "RATE_LIMIT" rule, which only throttles the sources neither
+	// contained in its CIDRs nor in the always allowed CIDRs.
+	RateLimited bool `json:"rateLimited,omitempty"`
+	// MatchedCIDR is the CIDR which decided, it is empty if no CIDR matched.
+	MatchedCIDR string `json:"matchedCIDR,omitempty"`
+	// Reason explains the decision.
+	Reason string `json:"reason"`
+}
+
+// Evaluate returns whether a connection from the given IP to the API server
+// is allowed by the rule, in the same order as the principals of the RBAC
+// filters: The protected CIDRs are always allowed, the denied CIDRs take
+// precedence over everything else, followed by the rule's CIDRs minus their
+// except blocks and, for "ALLOW" and "RATE_LIMIT" rules, the always allowed
+// CIDRs. "LOG" rules allow every IP which isn't globally denied. The IP is compared with the address selected
+// by the rule's type, e.g. the downstream remote address for "remote_ip".
+func (r *ACLRule) Evaluate(ip net.IP, alwaysAllowedCIDRs []string) Decision {
+	if cidr := firstContaining(r.ProtectedCIDRs, ip); cidr != "" {
+		return Decision{
+			Allowed:     true,
+			MatchedCIDR: cidr,
+			Reason:      fmt.Sprintf("%s is contained in the protected CIDR %s, which is never denied", ip, cidr),
+		}
+	}
+	if cidr := firstContaining(r.DeniedCIDRs, ip); cidr != "" {
+		return Decision{MatchedCIDR: cidr, Reason: fmt.Sprintf("%s is contained in the globally denied CIDR %s", ip, cidr)}
+	}
+
+	if r.IsLog() {
+		if cidr := r.firstRuleCIDRContaining(ip); cidr != "" {
+			return Decision{
+				Allowed:     true,
+				MatchedCIDR: cidr,
+				Reason:      fmt.Sprintf("%s is contained in the CIDR %s of the LOG rule, its connections are only logged", ip, cidr),
+			}
+		}
+		return Decision{Allowed: true, Reason: fmt.Sprintf("%s is not contained in any CIDR of the LOG rule", ip)}
+	}
+
+	if cidr := r.firstRuleCIDRContaining(ip); cidr != "" {
+		if !r.RestrictsOtherSources() {
+			return Decision{MatchedCIDR: cidr, Reason: fmt.Sprintf("%s is contained in the CIDR %s of the DENY rule", ip, cidr)}
+		}
+		return Decision{
+			Allowed:     true,
+			MatchedCIDR: cidr,
+			Reason:      fmt.Sprintf("%s is contained in the CIDR %s of the %s rule", ip, cidr, r.Action),
+		}
+	}
+
+	if !r.RestrictsOtherSources() {
+		return Decision{Allowed: true, Reason: fmt.Sprintf("%s is not contained in any CIDR of the DENY rule", ip)}
+	}
+
+	if cidr := firstContaining(alwaysAllowedCIDRs, ip); cidr != "" {
+		return Decision{
+			Allowed:     true,
+			MatchedCIDR: cidr,
+			Reason:      fmt.Sprintf("%s is contained in the always allowed CIDR %s", ip, cidr),
+		}
+	}
+
+	if r.IsRateLimit() {
+		return Decision{
+			Allowed:     true,
+			RateLimited: true,
+			Reason:      fmt.Sprintf("%s is not contained in any CIDR of the RATE_LIMIT rule or the always allowed CIDRs, it is only throttled", ip),
+		}
+	}
+	return Decision{Reason: fmt.Sprintf("%s is not contained in any CIDR of the ALLOW rule or the always allowed CIDRs", ip)}
+}
+
+// firstRuleCIDRContaining returns the first CIDR of the rule containing the
+// IP, unless the IP is contained in one of the except blocks of the CIDR.
+func (r *ACLRule) firstRuleCIDRContaining(ip net.IP) string {
+	for _, cidr := range r.Cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil || !network.Contains(ip) {
+			continue
+		}
+
+		excepted := false
+		for _, except := range r.Except {
+			_, exceptNetwork, err := net.ParseCIDR(except)
+			if err == nil && IsSubnetOf(exceptNetwork, network) && exceptNetwork.Contains(ip) {
+				excepted = true
+				break
+			}
+		}
+		if !excepted {
+			return cidr
+		}
+	}
+	return ""
+}
+
+func firstContaining(cidrs []string, ip net.IP) string {
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err == nil && network.Contains(ip) {
+			return cidr
+		}
+	}
+	return ""
+}
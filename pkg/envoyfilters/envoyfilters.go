@@ -3,6 +3,7 @@ package envoyfilters
 import (
 	"errors"
 	"net"
+	"net/netip"
 	"strings"
 
 	"github.com/gardener/gardener/extensions/pkg/controller"
@@ -10,19 +11,63 @@ import (
 	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
 )
 
+// Listeners of the shoot specific RBAC filters, used in their stat prefixes.
+const (
+	ListenerAPI      = "api"
+	ListenerIngress  = "ingress"
+	ListenerVPN      = "vpn"
+	ListenerInternal = "internal"
+)
+
+// VPNListenerPort is the port of the VPN listener of the istio ingress
+// gateways. The listener is matched by its port rather than by its name, as
+// the name contains the address the gateway binds to, which differs e.g. for
+// IPv6 or dual-stack gateways.
+const VPNListenerPort = 8132
+
+// APIServerListenerPort is the port of the listener of the istio ingress
+// gateways serving the API servers of the shoots, both via SNI and via the
+// internal flow.
+const APIServerListenerPort = 8443
+
+// Actions of an ACLRule.
+const (
+	// ActionAllow only allows the rule's CIDRs and the always allowed CIDRs.
+	ActionAllow = "ALLOW"
+	// ActionDeny denies the rule's CIDRs.
+	ActionDeny = "DENY"
+	// ActionRateLimit doesn't deny other sources than the rule's CIDRs and
+	// the always allowed CIDRs, but throttles their new connections to the
+	// shoot according to the rule's RateLimit.
+	ActionRateLimit = "RATE_LIMIT"
+	// ActionLog neither allows nor denies the rule's CIDRs, but counts and
+	// logs their connections to the API server, e.g. to measure the impact of
+	// a future "DENY" rule. Only the globally denied CIDRs are denied.
+	ActionLog = "LOG"
+)
+
+// Types of an ACLRule, i.e. the principals of the envoy RBAC filters.
+const (
+	// TypeRemoteIP matches the address of the client, honoring the PROXY
+	// protocol.
+	TypeRemoteIP = "remote_ip"
+	// TypeDirectRemoteIP matches the address of the direct connection to the
+	// gateway.
+	TypeDirectRemoteIP = "direct_remote_ip"
+	// TypeSourceIP is deprecated by envoy in favor of TypeRemoteIP.
+	TypeSourceIP = "source_ip"
+)
+
+// Types returns all rule types.
+func Types() []string {
+	return []string{TypeRemoteIP, TypeDirectRemoteIP, TypeSourceIP}
+}
+
 // Error variables for envoyfilters pkg
 var (
 	ErrNoHostsGiven = errors.New("no hosts were given, at least one host is needed")
 )
 
-// ACLMapping maps a an ACL rule to a specific shoot and also contains the
-// shoot-specific CIDRs.
-type ACLMapping struct {
-	ShootName          string   `json:"shootName"`
-	Rule               ACLRule  `json:"rule"`
-	ShootSpecificCIDRs []string `json:"ShootSpecificCIDRs"`
-}
-
 // ACLRule contains a single ACL rule, consisting of a list of CIDRs, an action
 // and a rule type.
 type ACLRule struct {
@@ -30,82 +75,195 @@ type ACLRule struct {
 	Cidrs []string `json:"cidrs"`
 	// Action defines if the rule is a DENY or an ALLOW rule
 	Action string `json:"action"`
-	// Type can either be "source_ip", "direct_remote_ip" or "remote_ip"
+	// Type can either be "source_ip", "direct_remote_ip" or "remote_ip", see Types.
+	// Not every type can be used for every target, see the extensionspec package.
 	Type string `json:"type"`
+	// Except contains a list of CIDR blocks which are excluded from the rule.
+	// Every entry has to be contained in one of the Cidrs, e.g. to exclude a
+	// guest network from an otherwise allowed corporate network.
+	Except []string `json:"except,omitempty"`
+	// DeniedCIDRs contains CIDR blocks from the operator-managed global
+	// denylist. They are not part of the providerConfig, but set by the
+	// controller and take precedence over the rule and the always allowed
+	// CIDRs.
+	DeniedCIDRs []string `json:"-"`
+	// ProtectedCIDRs contains the networks of the seed and the shoot which
+	// the gardenlet, the kubelets and the VPN connection of the shoot
+	// originate from. They are set by the controller and allowed regardless
+	// of the rule's action, its except blocks and the global denylist, so
+	// no rule can sever the connectivity of the shoot.
+	ProtectedCIDRs []string `json:"-"`
+	// ProxyProtocol is set by the controller if the load balancers of the
+	// seed's istio ingress gateways use the PROXY protocol, see PrincipalType.
+	ProxyProtocol bool `json:"-"`
+	// DualStack is set by the controller if the seed is dual-stack. Its
+	// istio ingress gateways listen on IPv6 sockets which accept IPv4
+	// connections as well, whose clients show up as IPv4-mapped IPv6
+	// addresses (e.g. "::ffff:10.0.0.1"). Every IPv4 CIDR is therefore
+	// matched by its IPv4-mapped IPv6 CIDR as well, see WithIPv4MappedCIDRs.
+	DualStack bool `json:"-"`
+	// RateLimit is the budget of new connections for "RATE_LIMIT" rules.
+	RateLimit *RateLimit `json:"rateLimit,omitempty"`
+	// Description documents why the rule exists, e.g. with a ticket
+	// reference. It has no effect on the ACL, but is recorded in the status
+	// and on the rendered objects.
+	Description string `json:"description,omitempty"`
+	// Labels are arbitrary metadata of the rule, e.g. the owning team. Like
+	// the description, they have no effect on the ACL.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// MaxDescriptionLength is the maximum length of the description of a rule.
+const MaxDescriptionLength = 256
+
+// RateLimit is a budget of new connections, enforced by a token bucket.
+type RateLimit struct {
+	// ConnectionsPerSecond is the number of new connections per second.
+	ConnectionsPerSecond uint32 `json:"connectionsPerSecond"`
+	// Burst is the number of new connections which can be opened at once.
+	// Defaults to ConnectionsPerSecond.
+	Burst uint32 `json:"burst,omitempty"`
+}
+
+// IsRateLimit returns true for "RATE_LIMIT" rules.
+func (r *ACLRule) IsRateLimit() bool {
+	return strings.EqualFold(r.Action, ActionRateLimit)
+}
+
+// IsLog returns true for "LOG" rules.
+func (r *ACLRule) IsLog() bool {
+	return strings.EqualFold(r.Action, ActionLog)
+}
+
+// countsOnly returns true if the RBAC filters of the rule only count the
+// connections in their shadow rules instead of enforcing them, i.e. for
+// "RATE_LIMIT" and "LOG" rules. The globally denied CIDRs of these rules are
+// denied by a separate filter, see CreateDeniedCIDRsFilter.
+func (r *ACLRule) countsOnly() bool {
+	return r.IsRateLimit() || r.IsLog()
+}
+
+// RestrictsOtherSources returns true if the rule restricts the sources which
+// are neither contained in its CIDRs nor in the always allowed CIDRs, i.e.
+// for "ALLOW" and "RATE_LIMIT" rules.
+func (r *ACLRule) RestrictsOtherSources() bool {
+	return strings.EqualFold(r.Action, ActionAllow) || r.IsRateLimit()
+}
+
+// PrincipalType returns the principal type of the envoy RBAC filters matching
+// the rule's CIDRs. With the PROXY protocol, the address of the direct
+// connection is the one of the load balancer, so "direct_remote_ip" is
+// replaced by "remote_ip", which is restored from the PROXY protocol header by
+// the listener filter before any network or HTTP filter runs.
+func (r *ACLRule) PrincipalType() string {
+	principalType := strings.ToLower(r.Type)
+	if r.ProxyProtocol && principalType == TypeDirectRemoteIP {
+		return TypeRemoteIP
+	}
+	return principalType
+}
+
+// CIDRCount returns the number of CIDRs of the rule, including its except
+// blocks, i.e. the number of principals the rule adds to the filters.
+func (r *ACLRule) CIDRCount() int {
+	return len(r.Cidrs) + len(r.Except)
+}
+
+// MatchesEverything returns true if one of the rule's CIDRs covers the whole
+// IPv4 or IPv6 address space, e.g. 0.0.0.0/0 or ::/0.
+func (r *ACLRule) MatchesEverything() bool {
+	for _, cidr := range r.Cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			if ones, _ := network.Mask.Size(); ones == 0 {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // BuildAPIEnvoyFilterSpecForHelmChart assembles EnvoyFilter patches for API server
-// networking for every rule in the extension spec.
+// networking for every rule in the extension spec. If an internalRule and the
+// internalHosts are given, the internalRule is enforced for the connections to
+// the internalHosts instead of the rule.
 func BuildAPIEnvoyFilterSpecForHelmChart(
-	rule *ACLRule, hosts, alwaysAllowedCIDRs []string, istioLabels map[string]string,
+	rule, internalRule *ACLRule, technicalShootID string, hosts, internalHosts, alwaysAllowedCIDRs []string, istioLabels map[string]string,
 ) (map[string]interface{}, error) {
-	apiConfigPatch, err := CreateAPIConfigPatchFromRule(rule, hosts, alwaysAllowedCIDRs)
-	if err != nil {
-		return nil, err
+	var apiConfigPatches []map[string]interface{}
+	if internalRule != nil && len(internalHosts) > 0 {
+		patches, err := CreateAPIConfigPatchesFromRules(rule, internalRule, technicalShootID, hosts, internalHosts, alwaysAllowedCIDRs)
+		if err != nil {
+			return nil, err
+		}
+		apiConfigPatches = patches
+	} else {
+		apiConfigPatch, err := CreateAPIConfigPatchFromRule(rule, technicalShootID, hosts, alwaysAllowedCIDRs)
+		if err != nil {
+			return nil, err
+		}
+		apiConfigPatches = []map[string]interface{}{apiConfigPatch}
+	}
+
+	configPatches := append([]map[string]interface{}{}, apiConfigPatches...)
+	// the RBAC filters of "RATE_LIMIT" and "LOG" rules only count the
+	// connections, so the globally denied CIDRs are denied by a separate
+	// filter, which is inserted last and runs first
+	if deniedFilter := CreateDeniedCIDRsFilter(rule, "acl-api-denied", StatPrefix(ListenerAPI, technicalShootID)); deniedFilter != nil {
+		configPatches = append(configPatches, apiConfigPatch(hosts[0], map[string]interface{}{
+			"operation": "INSERT_FIRST",
+			"value":     deniedFilter,
+		}))
+	}
+	if rule.IsRateLimit() {
+		if rateLimitPatch := CreateAPIRateLimitConfigPatch(rule, technicalShootID, hosts, alwaysAllowedCIDRs, insertedFilters(configPatches)); rateLimitPatch != nil {
+			configPatches = append(configPatches, rateLimitPatch)
+		}
 	}
 
 	return map[string]interface{}{
 		"workloadSelector": map[string]interface{}{
 			"labels": istioLabels,
 		},
-		"configPatches": []map[string]interface{}{
-			apiConfigPatch,
-		},
+		"configPatches": configPatches,
 	}, nil
 }
 
 // BuildIngressEnvoyFilterSpecForHelmChart assembles EnvoyFilter patches for
-// endpoints using the seed ingress domain.
+// endpoints using the seed ingress domain and, if shootIngress is true, for the
+// wildcard ingress domain of the shoot. It returns nil if there is neither.
 func BuildIngressEnvoyFilterSpecForHelmChart(
-	cluster *controller.Cluster, rule *ACLRule, alwaysAllowedCIDRs []string, istioLabels map[string]string,
+	cluster *controller.Cluster, rule *ACLRule, alwaysAllowedCIDRs []string, istioLabels map[string]string, shootIngress bool,
 ) map[string]interface{} {
-	seedIngressDomain := helper.GetSeedIngressDomain(cluster.Seed)
-	if seedIngressDomain != "" {
+	var configPatches []map[string]interface{}
+	if seedIngressDomain := helper.GetSeedIngressDomain(cluster.Seed); seedIngressDomain != "" {
 		shootID := helper.ComputeShortShootID(cluster.Shoot)
-
-		return map[string]interface{}{
-			"workloadSelector": map[string]interface{}{
-				"labels": istioLabels,
-			},
-			"configPatches": []map[string]interface{}{
-				CreateIngressConfigPatchFromRule(rule, seedIngressDomain, shootID, alwaysAllowedCIDRs),
-			},
-		}
+		configPatches = append(configPatches,
+			CreateIngressConfigPatchFromRule(rule, seedIngressDomain, shootID, cluster.Shoot.Status.TechnicalID, alwaysAllowedCIDRs))
 	}
-	return nil
-}
-
-// BuildVPNEnvoyFilterSpecForHelmChart assembles EnvoyFilter patches for VPN.
-func BuildVPNEnvoyFilterSpecForHelmChart(
-	cluster *controller.Cluster, rule *ACLRule, alwaysAllowedCIDRs []string, istioLabels map[string]string,
-) (map[string]interface{}, error) {
-	vpnConfigPatch, err := CreateVPNConfigPatchFromRule(rule, helper.ComputeShortShootID(cluster.Shoot), cluster.Shoot.Status.TechnicalID, alwaysAllowedCIDRs)
-	if err != nil {
-		return nil, err
+	if shootIngressDomain := helper.GetShootIngressDomain(cluster.Shoot); shootIngress && shootIngressDomain != "" {
+		configPatches = append(configPatches,
+			CreateShootIngressConfigPatchFromRule(rule, shootIngressDomain, cluster.Shoot.Status.TechnicalID, alwaysAllowedCIDRs))
+	}
+	if len(configPatches) == 0 {
+		return nil
 	}
 
 	return map[string]interface{}{
 		"workloadSelector": map[string]interface{}{
 			"labels": istioLabels,
 		},
-		"configPatches": []map[string]interface{}{
-			vpnConfigPatch,
-		},
-	}, nil
+		"configPatches": configPatches,
+	}
 }
 
-// BuildLegacyVPNEnvoyFilterSpecForHelmChart assembles a single EnvoyFilter for all
-// shoots on the seed, due to the fact that we can't create one EnvoyFilter per
-// shoot - this doesn't work because all the VPN traffic flows through the same
-// filter.
-//
-// We use the technical ID of the shoot for the VPN rule, which is de facto the
-// same as the seed namespace of the shoot. (Gardener uses the seedNamespace
-// value in the botanist vpnshoot task.)
-func BuildLegacyVPNEnvoyFilterSpecForHelmChart(
-	mappings []ACLMapping, alwaysAllowedCIDRs []string, istioLabels map[string]string,
+// BuildVPNEnvoyFilterSpecForHelmChart assembles EnvoyFilter patches for VPN
+// in the given renderings.
+func BuildVPNEnvoyFilterSpecForHelmChart(
+	cluster *controller.Cluster, rule *ACLRule, alwaysAllowedCIDRs []string, istioLabels map[string]string, renderings Renderings,
 ) (map[string]interface{}, error) {
-	vpnConfigPatch, err := CreateLegacyVPNConfigPatchFromRule(mappings, alwaysAllowedCIDRs)
+	vpnConfigPatch, err := CreateVPNConfigPatchFromRule(
+		rule, helper.ComputeShortShootID(cluster.Shoot), cluster.Shoot.Status.TechnicalID, alwaysAllowedCIDRs, renderings,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -124,7 +282,7 @@ func BuildLegacyVPNEnvoyFilterSpecForHelmChart(
 // hosts list and the alwaysAllowedCIDRs into a network filter patch that can be
 // applied to the `GATEWAY` network filter chain matching the host.
 func CreateAPIConfigPatchFromRule(
-	rule *ACLRule, hosts, alwaysAllowedCIDRs []string,
+	rule *ACLRule, technicalShootID string, hosts, alwaysAllowedCIDRs []string,
 ) (map[string]interface{}, error) {
 	if len(hosts) == 0 {
 		return nil, ErrNoHostsGiven
@@ -132,6 +290,35 @@ func CreateAPIConfigPatchFromRule(
 	rbacName := "acl-api"
 	principals := ruleCIDRsToPrincipal(rule, alwaysAllowedCIDRs)
 
+	return apiConfigPatch(hosts[0], principalsToPatch(rbacName, StatPrefix(ListenerAPI, technicalShootID), rule.Action, "network", principals)), nil
+}
+
+// CreateAPIConfigPatchesFromRules creates two network filter patches for the
+// `GATEWAY` network filter chain matching the hosts: one enforcing the
+// internalRule for the connections to the internalHosts, and one enforcing the
+// rule for the connections to all other hosts. Both filters share the stat
+// prefix of the API server listener, so their denied connections are counted
+// together.
+func CreateAPIConfigPatchesFromRules(
+	rule, internalRule *ACLRule, technicalShootID string, hosts, internalHosts, alwaysAllowedCIDRs []string,
+) ([]map[string]interface{}, error) {
+	if len(hosts) == 0 || len(internalHosts) == 0 {
+		return nil, ErrNoHostsGiven
+	}
+	statPrefix := StatPrefix(ListenerAPI, technicalShootID)
+	internalServerNames := serverNamesPermission(internalHosts)
+
+	return []map[string]interface{}{
+		apiConfigPatch(hosts[0], scopedPrincipalsToPatch("acl-api", statPrefix, rule.Action,
+			ruleCIDRsToPrincipal(rule, alwaysAllowedCIDRs), map[string]interface{}{"not_rule": internalServerNames})),
+		apiConfigPatch(hosts[0], scopedPrincipalsToPatch("acl-api-internal", statPrefix, internalRule.Action,
+			ruleCIDRsToPrincipal(internalRule, alwaysAllowedCIDRs), internalServerNames)),
+	}, nil
+}
+
+// apiConfigPatch creates a network filter patch for the `GATEWAY` network
+// filter chain matching the host.
+func apiConfigPatch(host string, patch map[string]interface{}) map[string]interface{} {
 	return map[string]interface{}{
 		"applyTo": "NETWORK_FILTER",
 		"match": map[string]interface{}{
@@ -141,20 +328,197 @@ func CreateAPIConfigPatchFromRule(
 					// There is one filter chain per shoot in the SNI listener that has two SNI matches: one for the internal and
 					// one for the external shoot domain.
 					// We can use either shoot domain to match the filter chain that we want to patch with this EnvoyFilter.
-					// The ACL config will apply to traffic going via both the internal and the external API server address.
+					// The ACL config will apply to traffic going via both the internal and the external API server address,
+					// unless the filter's policy is scoped to the requested server names.
 					// See: https://istio.io/latest/docs/reference/config/networking/envoy-filter/#EnvoyFilter-ListenerMatch-FilterChainMatch
-					"sni": hosts[0],
+					"sni": host,
 				},
 			},
 		},
-		"patch": principalsToPatch(rbacName, rule.Action, "network", principals),
-	}, nil
+		"patch": patch,
+	}
+}
+
+// serverNamesPermission returns an RBAC permission matching the connections
+// requesting one of the given server names via SNI.
+func serverNamesPermission(serverNames []string) map[string]interface{} {
+	rules := make([]map[string]interface{}, 0, len(serverNames))
+	for _, serverName := range serverNames {
+		rules = append(rules, map[string]interface{}{
+			"requested_server_name": map[string]interface{}{
+				"exact": serverName,
+			},
+		})
+	}
+	return map[string]interface{}{
+		"or_rules": map[string]interface{}{
+			"rules": rules,
+		},
+	}
+}
+
+// CreateAPIRateLimitConfigPatch creates a filter chain patch throttling the new
+// connections to the API server according to the budget of a "RATE_LIMIT"
+// rule. Envoy can't rate-limit only some sources within a filter chain, so the
+// patch adds a filter chain for the hosts which only matches the sources
+// neither contained in the rule nor in the alwaysAllowedCIDRs, see
+// RateLimitedFilterChain. The filter chain runs the given filters of the
+// filter chain of the hosts in front of the rate limit, as filter chains added
+// by an EnvoyFilter aren't patched by its other patches. It returns nil if the
+// rule doesn't throttle any source.
+func CreateAPIRateLimitConfigPatch(
+	rule *ACLRule, technicalShootID string, hosts, alwaysAllowedCIDRs []string, filters []map[string]interface{},
+) map[string]interface{} {
+	statPrefix := StatPrefix(ListenerAPI, technicalShootID)
+	apiServerCluster := "outbound|443||kube-apiserver." + technicalShootID + ".svc.cluster.local"
+	filterChain := map[string]interface{}{
+		"name": statPrefix,
+		"filter_chain_match": map[string]interface{}{
+			"server_names": hosts,
+		},
+	}
+	filters = append(append([]map[string]interface{}{}, filters...),
+		rateLimitFilter("acl-api-ratelimit", statPrefix, rule.RateLimit),
+		map[string]interface{}{
+			"name": "envoy.filters.network.tcp_proxy",
+			"typed_config": map[string]interface{}{
+				"@type":       "type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy",
+				"cluster":     apiServerCluster,
+				"stat_prefix": apiServerCluster,
+			},
+		},
+	)
+
+	rateLimited := RateLimitedFilterChain(rule, filterChain, alwaysAllowedCIDRs, filters)
+	if rateLimited == nil {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"applyTo": "FILTER_CHAIN",
+		"match": map[string]interface{}{
+			"context": "GATEWAY",
+			"listener": map[string]interface{}{
+				"portNumber": APIServerListenerPort,
+			},
+		},
+		"patch": map[string]interface{}{
+			"operation": "ADD",
+			"value":     rateLimited,
+		},
+	}
+}
+
+// RateLimitedFilterChain returns a copy of the filter chain running the given
+// filters, whose filter chain match is narrowed down to the sources throttled
+// by a "RATE_LIMIT" rule, i.e. the ones neither contained in the rule's CIDRs
+// (minus their except blocks) nor in the alwaysAllowedCIDRs. Envoy prefers
+// the filter chain with the matching source over the original one, which
+// keeps serving all other sources without rate limit. The name of the filter
+// chain, if any, is suffixed with "_ratelimit", as they have to be unique. It
+// returns nil if the rule doesn't throttle any source.
+func RateLimitedFilterChain(
+	rule *ACLRule, filterChain map[string]interface{}, alwaysAllowedCIDRs []string, filters []map[string]interface{},
+) map[string]interface{} {
+	// like the principals, the source ranges of the filter chain match the
+	// address restored from the PROXY protocol header, unless the rule
+	// matches the address of the direct connection
+	sourceKey := "source_prefix_ranges"
+	if rule.PrincipalType() == TypeDirectRemoteIP {
+		sourceKey = "direct_source_prefix_ranges"
+	}
+	var sourceRanges []map[string]interface{}
+	for _, prefix := range rule.RateLimitedSources(alwaysAllowedCIDRs) {
+		sourceRanges = append(sourceRanges, map[string]interface{}{
+			"address_prefix": prefix.Addr().String(),
+			"prefix_len":     prefix.Bits(),
+		})
+	}
+	if len(sourceRanges) == 0 {
+		// without source ranges, the filter chain would match all sources
+		return nil
+	}
+
+	filterChainMatch := map[string]interface{}{}
+	if original, ok := filterChain["filter_chain_match"].(map[string]interface{}); ok {
+		for key, value := range original {
+			filterChainMatch[key] = value
+		}
+	}
+	filterChainMatch[sourceKey] = sourceRanges
+
+	rateLimited := map[string]interface{}{}
+	for key, value := range filterChain {
+		rateLimited[key] = value
+	}
+	if name, ok := filterChain["name"].(string); ok && name != "" {
+		rateLimited["name"] = name + "_ratelimit"
+	}
+	rateLimited["filter_chain_match"] = filterChainMatch
+	rateLimited["filters"] = filters
+	return rateLimited
+}
+
+// RateLimitedSources returns the smallest list of CIDRs matching the sources
+// throttled by a "RATE_LIMIT" rule, i.e. all addresses of both IP families
+// which are neither contained in the rule's CIDRs (minus their except blocks)
+// nor in the alwaysAllowedCIDRs or the protected CIDRs.
+func (r *ACLRule) RateLimitedSources(alwaysAllowedCIDRs []string) []netip.Prefix {
+	known := NewCIDRSet(r.familyCIDRs(append(append([]string{}, alwaysAllowedCIDRs...), r.ProtectedCIDRs...))...)
+	excepts := NewCIDRSet(r.familyCIDRs(r.Except)...)
+	for _, cidr := range r.familyCIDRs(r.Cidrs) {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		// the except blocks only apply to the CIDRs containing them
+		exceptsOfCIDR := &CIDRSet{}
+		for _, except := range excepts.Subnets(prefix.Masked()) {
+			exceptsOfCIDR.Insert(except.String())
+		}
+		for _, allowed := range exceptsOfCIDR.Difference(prefix) {
+			known.Insert(allowed.String())
+		}
+	}
+
+	var sources []netip.Prefix
+	for _, all := range []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0"), netip.MustParsePrefix("::/0")} {
+		sources = append(sources, known.Difference(all)...)
+	}
+	return sources
+}
+
+// CreateDeniedCIDRsFilter creates a network RBAC filter denying the globally
+// denied CIDRs of a "RATE_LIMIT" or "LOG" rule, whose RBAC filters only count
+// the connections and can't deny them. It returns nil if there are no denied
+// CIDRs or if the rule's RBAC filters deny them already.
+func CreateDeniedCIDRsFilter(rule *ACLRule, rbacName, statPrefix string) map[string]interface{} {
+	deniedPrincipals := withoutProtectedCIDRs(rule, remoteIPPrincipals(rule.familyCIDRs(rule.DeniedCIDRs)))
+	if !rule.countsOnly() || len(deniedPrincipals) == 0 {
+		return nil
+	}
+	return map[string]interface{}{
+		"name":         rbacName,
+		"typed_config": typedConfigToPatch(rbacName, statPrefix, ActionDeny, "network", deniedPrincipals),
+	}
+}
+
+// insertedFilters returns the filters inserted by the given network filter
+// patches in the order they run, i.e. the filter inserted last first.
+func insertedFilters(configPatches []map[string]interface{}) []map[string]interface{} {
+	var filters []map[string]interface{}
+	for _, configPatch := range configPatches {
+		if value, ok := configPatch["patch"].(map[string]interface{})["value"].(map[string]interface{}); ok {
+			filters = append([]map[string]interface{}{value}, filters...)
+		}
+	}
+	return filters
 }
 
 // CreateIngressConfigPatchFromRule creates a network filter patch that can be
 // applied to the `GATEWAY` network filter chain matching the wildcard ingress domain.
 func CreateIngressConfigPatchFromRule(
-	rule *ACLRule, seedIngressDomain, shootID string, alwaysAllowedCIDRs []string,
+	rule *ACLRule, seedIngressDomain, shootID, technicalShootID string, alwaysAllowedCIDRs []string,
 ) map[string]interface{} {
 	rbacName := "acl-ingress"
 	ingressSuffix := "-" + shootID + "." + seedIngressDomain
@@ -186,11 +550,10 @@ func CreateIngressConfigPatchFromRule(
 										},
 									},
 								}},
+								// not only IPv4 clients, but also the IPv6 and
+								// IPv4-mapped clients of dual-stack gateways
 								"principals": []map[string]interface{}{{
-									"remote_ip": map[string]interface{}{
-										"address_prefix": "0.0.0.0",
-										"prefix_len":     0,
-									},
+									"any": true,
 								}},
 							},
 							shootID: map[string]interface{}{
@@ -199,21 +562,50 @@ func CreateIngressConfigPatchFromRule(
 										"suffix": ingressSuffix,
 									},
 								}},
-								"principals": ruleCIDRsToPrincipal(rule, alwaysAllowedCIDRs),
+								"principals": sharedListenerPrincipals(rule, alwaysAllowedCIDRs),
 							},
 						},
 					},
-					"stat_prefix": "envoyrbac",
+					"stat_prefix": StatPrefix(ListenerIngress, technicalShootID),
+				},
+			},
+		},
+	}
+}
+
+// CreateShootIngressConfigPatchFromRule creates a network filter patch that
+// can be applied to the `GATEWAY` network filter chain matching the wildcard
+// ingress domain of the shoot. Unlike the seed ingress domain, the filter
+// chain only serves the shoot, so the rule applies to all of its connections.
+func CreateShootIngressConfigPatchFromRule(
+	rule *ACLRule, shootIngressDomain, technicalShootID string, alwaysAllowedCIDRs []string,
+) map[string]interface{} {
+	rbacName := "acl-shoot-ingress"
+	action := rule.Action
+	principals := ruleCIDRsToPrincipal(rule, alwaysAllowedCIDRs)
+	if rule.IsLog() {
+		action = ActionAllow
+		principals = sharedListenerPrincipals(rule, alwaysAllowedCIDRs)
+	}
+
+	return map[string]interface{}{
+		"applyTo": "NETWORK_FILTER",
+		"match": map[string]interface{}{
+			"context": "GATEWAY",
+			"listener": map[string]interface{}{
+				"filterChain": map[string]interface{}{
+					"sni": "*." + shootIngressDomain,
 				},
 			},
 		},
+		"patch": principalsToPatch(rbacName, StatPrefix(ListenerIngress, technicalShootID), action, "network", principals),
 	}
 }
 
 // CreateVPNConfigPatchFromRule creates an HTTP filter patch that can be applied to the
 // `GATEWAY` HTTP filter chain for the VPN.
 func CreateVPNConfigPatchFromRule(rule *ACLRule,
-	shortShootID, technicalShootID string, alwaysAllowedCIDRs []string,
+	shortShootID, technicalShootID string, alwaysAllowedCIDRs []string, renderings Renderings,
 ) (map[string]interface{}, error) {
 	rbacName := "acl-vpn"
 	headerMatcher := map[string]interface{}{
@@ -229,70 +621,37 @@ func CreateVPNConfigPatchFromRule(rule *ACLRule,
 			"contains": "." + technicalShootID + ".",
 		},
 	}
-	return map[string]interface{}{
-		"applyTo": "HTTP_FILTER",
-		"match": map[string]interface{}{
-			"context": "GATEWAY",
-			"listener": map[string]interface{}{
-				"name": "0.0.0.0_8132",
-			},
-		},
-		"patch": map[string]interface{}{
-			"operation": "INSERT_FIRST",
-			"value": map[string]interface{}{
-				"name": rbacName,
-				"typed_config": map[string]interface{}{
-					"@type": "type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBAC",
-					"rules": map[string]interface{}{
-						"action": "ALLOW",
-						"policies": map[string]interface{}{
-							shortShootID + "-inverse": map[string]interface{}{
-								"permissions": []map[string]interface{}{{
-									"not_rule": map[string]interface{}{
-										"header": headerMatcher,
-									},
-								}},
-								"principals": []map[string]interface{}{{
-									"remote_ip": map[string]interface{}{
-										"address_prefix": "0.0.0.0",
-										"prefix_len":     0,
-									},
-								}},
-							},
-							shortShootID: map[string]interface{}{
-								"permissions": []map[string]interface{}{{
-									"header": headerMatcher,
-								}},
-								"principals": ruleCIDRsToPrincipal(rule, alwaysAllowedCIDRs),
-							},
+	typedConfig := map[string]interface{}{
+		"@type": "type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBAC",
+		"rules": map[string]interface{}{
+			"action": "ALLOW",
+			"policies": map[string]interface{}{
+				shortShootID + "-inverse": map[string]interface{}{
+					"permissions": []map[string]interface{}{{
+						"not_rule": map[string]interface{}{
+							"header": headerMatcher,
 						},
-					},
-					"stat_prefix": "envoyrbac",
+					}},
+					// not only IPv4 clients, but also the IPv6 and
+					// IPv4-mapped clients of dual-stack gateways
+					"principals": []map[string]interface{}{{
+						"any": true,
+					}},
+				},
+				shortShootID: map[string]interface{}{
+					"permissions": []map[string]interface{}{{
+						"header": headerMatcher,
+					}},
+					"principals": sharedListenerPrincipals(rule, alwaysAllowedCIDRs),
 				},
 			},
 		},
-	}, nil
-}
-
-// CreateLegacyVPNConfigPatchFromRule combines a list of ACLMappings and the
-// alwaysAllowedCIDRs into a HTTP filter patch that can be applied to the
-// `GATEWAY` HTTP filter chain for the VPN.
-func CreateLegacyVPNConfigPatchFromRule(
-	mappings []ACLMapping, alwaysAllowedCIDRs []string,
-) (map[string]interface{}, error) {
-	rbacName := "acl-vpn"
-
-	policies := map[string]interface{}{}
-
-	policies[rbacName+"-inverse"] = createInverseVPNPolicy(mappings)
-
-	for i := range mappings {
-		mapping := &mappings[i]
-		policies[mapping.ShootName] = createVPNPolicyForShoot(
-			&mapping.Rule,
-			append(alwaysAllowedCIDRs, mapping.ShootSpecificCIDRs...),
-			mapping.ShootName,
-		)
+		"stat_prefix": "envoyrbac",
+	}
+	if renderings.RulesStatPrefix {
+		// the stat prefix of HTTP filters is the one of the connection
+		// manager, so the shoot is part of the rule stats instead
+		typedConfig["rules_stat_prefix"] = StatPrefix(ListenerVPN, technicalShootID) + "_"
 	}
 
 	return map[string]interface{}{
@@ -300,21 +659,14 @@ func CreateLegacyVPNConfigPatchFromRule(
 		"match": map[string]interface{}{
 			"context": "GATEWAY",
 			"listener": map[string]interface{}{
-				"name": "0.0.0.0_8132",
+				"portNumber": VPNListenerPort,
 			},
 		},
 		"patch": map[string]interface{}{
 			"operation": "INSERT_FIRST",
 			"value": map[string]interface{}{
-				"name": rbacName,
-				"typed_config": map[string]interface{}{
-					"@type":       "type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBAC",
-					"stat_prefix": "envoyrbac",
-					"rules": map[string]interface{}{
-						"action":   "ALLOW",
-						"policies": policies,
-					},
-				},
+				"name":         rbacName,
+				"typed_config": typedConfig,
 			},
 		},
 	}, nil
@@ -324,6 +676,7 @@ func CreateLegacyVPNConfigPatchFromRule(
 // alwaysAllowedCIDRs, and the shootSpecificCIDRs into a filter patch.
 func CreateInternalFilterPatchFromRule(
 	rule *ACLRule,
+	technicalShootID string,
 	alwaysAllowedCIDRs []string,
 	shootSpecificCIDRs []string,
 ) (map[string]interface{}, error) {
@@ -332,165 +685,376 @@ func CreateInternalFilterPatchFromRule(
 
 	return map[string]interface{}{
 		"name":         rbacName + "-" + strings.ToLower(rule.Type),
-		"typed_config": typedConfigToPatch(rbacName, rule.Action, "network", principals),
+		"typed_config": typedConfigToPatch(rbacName, StatPrefix(ListenerInternal, technicalShootID), rule.Action, "network", principals),
 	}, nil
 }
 
+// CreateInternalRateLimitFilterFromRule creates a filter throttling the new
+// connections of the internal flow according to the budget of a "RATE_LIMIT"
+// rule. It returns nil for other rules.
+func CreateInternalRateLimitFilterFromRule(rule *ACLRule, technicalShootID string) map[string]interface{} {
+	if !rule.IsRateLimit() {
+		return nil
+	}
+	return rateLimitFilter("acl-internal-ratelimit", StatPrefix(ListenerInternal, technicalShootID), rule.RateLimit)
+}
+
+// rateLimitFilter returns a local rate limit network filter with a token
+// bucket refilled every second.
+func rateLimitFilter(name, statPrefix string, rateLimit *RateLimit) map[string]interface{} {
+	burst := rateLimit.Burst
+	if burst == 0 {
+		burst = rateLimit.ConnectionsPerSecond
+	}
+
+	return map[string]interface{}{
+		"name": name,
+		"typed_config": map[string]interface{}{
+			"@type":       "type.googleapis.com/envoy.extensions.filters.network.local_ratelimit.v3.LocalRateLimit",
+			"stat_prefix": statPrefix,
+			"token_bucket": map[string]interface{}{
+				"max_tokens":      burst,
+				"tokens_per_fill": rateLimit.ConnectionsPerSecond,
+				"fill_interval":   "1s",
+			},
+		},
+	}
+}
+
 // ruleCIDRsToPrincipal translates a list of strings in the form "0.0.0.0/0"
 // into a list of envoy principals. The function checks for the rule action: If
 // the action is "ALLOW", the alwaysAllowedCIDRs are appended to the principals
-// to guarantee the downstream flow for these CIDRs is not blocked.
+// to guarantee the downstream flow for these CIDRs is not blocked. The CIDRs
+// are normalized with normalizeRuleCIDRs first, which only merges CIDRs
+// containing the same except blocks. For dual-stack seeds, all CIDRs are
+// complemented by their IPv4-mapped IPv6 CIDRs.
 func ruleCIDRsToPrincipal(rule *ACLRule, alwaysAllowedCIDRs []string) []map[string]interface{} {
 	principals := []map[string]interface{}{}
 
-	for _, cidr := range rule.Cidrs {
-		prefix, length, err := getPrefixAndPrefixLength(cidr)
+	for _, normalized := range normalizeRuleCIDRs(rule.familyCIDRs(rule.Cidrs), rule.familyCIDRs(rule.Except)) {
+		prefix, length, err := getPrefixAndPrefixLength(normalized.cidr)
 		if err != nil {
 			continue
 		}
-		principals = append(principals, map[string]interface{}{
-			strings.ToLower(rule.Type): map[string]interface{}{
+		principal := map[string]interface{}{
+			rule.PrincipalType(): map[string]interface{}{
 				"address_prefix": prefix,
 				"prefix_len":     length,
 			},
-		})
+		}
+
+		// carve the except blocks out of the CIDR by ANDing the CIDR with a
+		// negated match of all except blocks it contains
+		if exceptPrincipals := exceptCIDRsToPrincipal(rule, normalized.excepts); len(exceptPrincipals) > 0 {
+			principal = map[string]interface{}{
+				"and_ids": map[string]interface{}{
+					"ids": []map[string]interface{}{
+						principal,
+						{
+							"not_id": map[string]interface{}{
+								"or_ids": map[string]interface{}{
+									"ids": exceptPrincipals,
+								},
+							},
+						},
+					},
+				},
+			}
+		}
+
+		principals = append(principals, principal)
 	}
 
 	// if the rule has action "ALLOW" (which means "limit the access to only the
 	// specified IPs", we need to insert the node CIDR range to not block
-	// cluster-internal communication)
-	if rule.Action == "ALLOW" {
-		for _, cidr := range alwaysAllowedCIDRs {
-			prefix, length, err := getPrefixAndPrefixLength(cidr)
-			if err != nil {
-				continue
-			}
-			principals = append(principals, map[string]interface{}{
-				"remote_ip": map[string]interface{}{
-					"address_prefix": prefix,
-					"prefix_len":     length,
+	// cluster-internal communication), the same applies to "RATE_LIMIT"
+	if rule.RestrictsOtherSources() {
+		allowedCIDRs := append(append([]string{}, alwaysAllowedCIDRs...), rule.ProtectedCIDRs...)
+		principals = append(principals, remoteIPPrincipals(rule.familyCIDRs(allowedCIDRs))...)
+	}
+
+	return applyDeniedCIDRs(rule, principals)
+}
+
+// sharedListenerPrincipals returns the principals of the policy of the shoot
+// in the "ALLOW" RBAC filters of the VPN and ingress listeners. "LOG" rules
+// only count the connections to the API server, so there they allow all
+// sources but the globally denied CIDRs.
+func sharedListenerPrincipals(rule *ACLRule, alwaysAllowedCIDRs []string) []map[string]interface{} {
+	if !rule.IsLog() {
+		return ruleCIDRsToPrincipal(rule, alwaysAllowedCIDRs)
+	}
+
+	deniedPrincipals := withoutProtectedCIDRs(rule, remoteIPPrincipals(rule.familyCIDRs(rule.DeniedCIDRs)))
+	if len(deniedPrincipals) == 0 {
+		return []map[string]interface{}{{"any": true}}
+	}
+	return []map[string]interface{}{{
+		"not_id": map[string]interface{}{
+			"or_ids": map[string]interface{}{
+				"ids": deniedPrincipals,
+			},
+		},
+	}}
+}
+
+// applyDeniedCIDRs makes sure the globally denied CIDRs of the rule take
+// precedence over all other principals but the protected CIDRs: For "DENY"
+// rules they are simply added to the principals, while the principals of
+// "ALLOW" rules are ANDed with a negated match of the denied CIDRs. The
+// principals of "LOG" rules only select the logged connections, their denied
+// CIDRs are denied by a separate filter.
+func applyDeniedCIDRs(rule *ACLRule, principals []map[string]interface{}) []map[string]interface{} {
+	if rule.IsLog() {
+		return principals
+	}
+	deniedPrincipals := remoteIPPrincipals(rule.familyCIDRs(rule.DeniedCIDRs))
+	if !rule.RestrictsOtherSources() {
+		return withoutProtectedCIDRs(rule, append(principals, deniedPrincipals...))
+	}
+
+	deniedPrincipals = withoutProtectedCIDRs(rule, deniedPrincipals)
+	if len(deniedPrincipals) == 0 {
+		return principals
+	}
+
+	return []map[string]interface{}{{
+		"and_ids": map[string]interface{}{
+			"ids": []map[string]interface{}{
+				{
+					"or_ids": map[string]interface{}{
+						"ids": principals,
+					},
+				},
+				{
+					"not_id": map[string]interface{}{
+						"or_ids": map[string]interface{}{
+							"ids": deniedPrincipals,
+						},
+					},
 				},
-			})
+			},
+		},
+	}}
+}
+
+// withoutProtectedCIDRs ANDs the given principals of denied sources with a
+// negated match of the protected CIDRs of the rule, so they never match the
+// connections of the shoot's nodes or its VPN.
+func withoutProtectedCIDRs(rule *ACLRule, principals []map[string]interface{}) []map[string]interface{} {
+	protectedPrincipals := remoteIPPrincipals(rule.familyCIDRs(rule.ProtectedCIDRs))
+	if len(principals) == 0 || len(protectedPrincipals) == 0 {
+		return principals
+	}
+
+	return []map[string]interface{}{{
+		"and_ids": map[string]interface{}{
+			"ids": []map[string]interface{}{
+				{
+					"or_ids": map[string]interface{}{
+						"ids": principals,
+					},
+				},
+				{
+					"not_id": map[string]interface{}{
+						"or_ids": map[string]interface{}{
+							"ids": protectedPrincipals,
+						},
+					},
+				},
+			},
+		},
+	}}
+}
+
+// WithProtectedCIDRs returns a copy of the rule whose protected CIDRs are
+// complemented by the given CIDRs, e.g. by the egress CIDRs of the shoot for
+// the VPN listener.
+func (r *ACLRule) WithProtectedCIDRs(cidrs ...string) *ACLRule {
+	rule := *r
+	rule.ProtectedCIDRs = append(append([]string{}, r.ProtectedCIDRs...), cidrs...)
+	return &rule
+}
+
+// familyCIDRs returns the CIDRs complemented by their IPv4-mapped IPv6 CIDRs
+// if the rule is enforced by dual-stack gateways, otherwise the CIDRs as they
+// are.
+func (r *ACLRule) familyCIDRs(cidrs []string) []string {
+	if !r.DualStack {
+		return cidrs
+	}
+	return WithIPv4MappedCIDRs(cidrs)
+}
+
+// WithIPv4MappedCIDRs returns the CIDRs followed by the IPv4-mapped IPv6 CIDRs
+// of their IPv4 CIDRs, e.g. "::ffff:10.0.0.0/104" for "10.0.0.0/8". Invalid
+// CIDRs are kept as they are.
+func WithIPv4MappedCIDRs(cidrs []string) []string {
+	result := append([]string{}, cidrs...)
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil || !prefix.Addr().Is4() {
+			continue
+		}
+		mapped := netip.PrefixFrom(netip.AddrFrom16(prefix.Addr().As16()), prefix.Bits()+96).Masked()
+		result = append(result, mapped.String())
+	}
+	return result
+}
+
+func remoteIPPrincipals(cidrs []string) []map[string]interface{} {
+	principals := []map[string]interface{}{}
+
+	for _, cidr := range NormalizeCIDRs(cidrs) {
+		prefix, length, err := getPrefixAndPrefixLength(cidr)
+		if err != nil {
+			continue
 		}
+		principals = append(principals, map[string]interface{}{
+			"remote_ip": map[string]interface{}{
+				"address_prefix": prefix,
+				"prefix_len":     length,
+			},
+		})
 	}
 
 	return principals
 }
 
+// exceptCIDRsToPrincipal returns the principals for the given except blocks of
+// the rule.
+func exceptCIDRsToPrincipal(rule *ACLRule, excepts []string) []map[string]interface{} {
+	principals := []map[string]interface{}{}
+
+	for _, except := range excepts {
+		prefix, length, err := getPrefixAndPrefixLength(except)
+		if err != nil {
+			continue
+		}
+		principals = append(principals, map[string]interface{}{
+			rule.PrincipalType(): map[string]interface{}{
+				"address_prefix": prefix,
+				"prefix_len":     length,
+			},
+		})
+	}
+
+	return principals
+}
+
+// IsSubnetOf returns true if the network inner is fully contained in the
+// network outer.
+func IsSubnetOf(inner, outer *net.IPNet) bool {
+	innerOnes, innerBits := inner.Mask.Size()
+	outerOnes, outerBits := outer.Mask.Size()
+
+	return innerBits == outerBits && innerOnes >= outerOnes && outer.Contains(inner.IP)
+}
+
 func getPrefixAndPrefixLength(cidr string) (prefix string, prefixLen int, err error) {
 	// rule gets validated early in the code
-	ip, mask, err := net.ParseCIDR(cidr)
+	if _, _, err := net.ParseCIDR(cidr); err != nil {
+		return "", 0, err
+	}
+	// unlike net.IP, netip.Addr keeps the IPv6 notation of IPv4-mapped
+	// addresses, which envoy needs to match them
+	network, err := netip.ParsePrefix(cidr)
 	if err != nil {
 		return "", 0, err
 	}
-	prefixLen, _ = mask.Mask.Size()
 
 	// TODO use ip here or the one from the mask?
-	return ip.String(), prefixLen, nil
+	return network.Addr().String(), network.Bits(), nil
 }
 
 func principalsToPatch(
-	rbacName, ruleAction, filterType string, principals []map[string]interface{},
+	rbacName, statPrefix, ruleAction, filterType string, principals []map[string]interface{},
 ) map[string]interface{} {
 	return map[string]interface{}{
 		"operation": "INSERT_FIRST",
 		"value": map[string]interface{}{
 			"name":         rbacName,
-			"typed_config": typedConfigToPatch(rbacName, ruleAction, filterType, principals),
+			"typed_config": typedConfigToPatch(rbacName, statPrefix, ruleAction, filterType, principals),
 		},
 	}
 }
 
-func typedConfigToPatch(rbacName, ruleAction, filterType string, principals []map[string]interface{}) map[string]interface{} {
+// scopedPrincipalsToPatch creates a network RBAC filter patch like
+// principalsToPatch, whose rule only applies to the connections matching the
+// given permission.
+func scopedPrincipalsToPatch(
+	rbacName, statPrefix, ruleAction string, principals []map[string]interface{}, permission map[string]interface{},
+) map[string]interface{} {
 	return map[string]interface{}{
-		"@type":       "type.googleapis.com/envoy.extensions.filters." + filterType + ".rbac.v3.RBAC",
-		"stat_prefix": "envoyrbac",
-		"rules": map[string]interface{}{
-			"action": strings.ToUpper(ruleAction),
-			"policies": map[string]interface{}{
-				rbacName: map[string]interface{}{
-					"permissions": []map[string]interface{}{
-						{"any": true},
-					},
-					"principals": principals,
-				},
-			},
+		"operation": "INSERT_FIRST",
+		"value": map[string]interface{}{
+			"name":         rbacName,
+			"typed_config": scopedTypedConfigToPatch(rbacName, statPrefix, ruleAction, "network", principals, permission),
 		},
 	}
 }
 
-func createVPNPolicyForShoot(rule *ACLRule, alwaysAllowedCIDRs []string, technicalShootID string) map[string]interface{} {
-	// In the case of VPN, we need to nest the principal rules in a EnvoyFilter
-	// "and_ids" structure, because we add an additional principal matching on
-	// the "reversed-vpn" header, which needs to be ANDed with the other rules.
-	// Principals are concatenated using an OR rule, so matching one of them sufficies...
-	oredPrincipals := ruleCIDRsToPrincipal(rule, alwaysAllowedCIDRs)
-
-	// ...but only if the VPN header is also set, therefore combine via AND rule
-	return map[string]interface{}{
-		"permissions": []map[string]interface{}{
-			{"any": true},
-		},
-		"principals": []map[string]interface{}{
-			{
-				"and_ids": map[string]interface{}{
-					"ids": []map[string]interface{}{
-						{
-							"or_ids": map[string]interface{}{
-								"ids": oredPrincipals,
-							},
-						},
-						{
-							"header": map[string]interface{}{
-								"name": "reversed-vpn",
-								"string_match": map[string]interface{}{
-									// The actual header value will look something like
-									// `outbound|1194||vpn-seed-server.<technical-ID>.svc.cluster.local`.
-									// Include dots in the contains matcher as anchors, to always match the entire technical shoot ID.
-									// Otherwise, if there was one cluster named `foo` and one named `foo-bar` (in the same project),
-									// `foo` would effectively inherit the ACL of `foo-bar`.
-									"contains": "." + technicalShootID + ".",
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
+func typedConfigToPatch(rbacName, statPrefix, ruleAction, filterType string, principals []map[string]interface{}) map[string]interface{} {
+	return scopedTypedConfigToPatch(rbacName, statPrefix, ruleAction, filterType, principals, nil)
 }
 
-func createInverseVPNPolicy(mappings []ACLMapping) map[string]interface{} {
-	notHeaderPrincipals := []map[string]interface{}{}
+// scopedTypedConfigToPatch creates the typed config of an RBAC filter whose
+// rule only applies to the connections matching the permission, or to all
+// connections if the permission is nil. An "ALLOW" filter denies everything
+// not matching one of its policies, so the connections not matching the
+// permission are allowed by an inverse policy.
+func scopedTypedConfigToPatch(
+	rbacName, statPrefix, ruleAction, filterType string, principals []map[string]interface{}, permission map[string]interface{},
+) map[string]interface{} {
+	rulesKey := "rules"
+	action := strings.ToUpper(ruleAction)
+	switch action {
+	case ActionRateLimit:
+		// other sources are throttled by a separate filter chain instead of
+		// being denied, the shadow rules only count them
+		rulesKey = "shadow_rules"
+		action = ActionAllow
+	case ActionLog:
+		// the connections of the rule's CIDRs are neither allowed nor
+		// denied, the shadow rules count them as "shadow_denied"
+		rulesKey = "shadow_rules"
+		action = ActionDeny
+	}
 
-	for i := range mappings {
-		notHeaderPrincipals = append(notHeaderPrincipals, map[string]interface{}{
-			"not_id": map[string]interface{}{
-				"header": map[string]interface{}{
-					"name": "reversed-vpn",
-					"string_match": map[string]interface{}{
-						// The actual header value will look something like
-						// `outbound|1194||vpn-seed-server.<technical-ID>.svc.cluster.local`.
-						// Include dots in the contains matcher as anchors, to always match the entire technical shoot ID.
-						// Otherwise, if there was one cluster named `foo` and one named `foo-bar` (in the same project),
-						// `foo` would effectively inherit the ACL of `foo-bar`.
-						"contains": "." + mappings[i].ShootName + ".",
-					},
-				},
+	policies := map[string]interface{}{}
+	if permission == nil {
+		permission = map[string]interface{}{"any": true}
+	} else if action == ActionAllow {
+		policies[rbacName+"-inverse"] = map[string]interface{}{
+			"permissions": []map[string]interface{}{
+				{"not_rule": permission},
 			},
-		})
+			"principals": []map[string]interface{}{
+				{"any": true},
+			},
+		}
 	}
-
-	return map[string]interface{}{
+	policies[rbacName] = map[string]interface{}{
 		"permissions": []map[string]interface{}{
-			{"any": true},
+			permission,
 		},
-		"principals": []map[string]interface{}{
-			{
-				"and_ids": map[string]interface{}{
-					"ids": notHeaderPrincipals,
-				},
-			},
+		"principals": principals,
+	}
+
+	return map[string]interface{}{
+		"@type":       "type.googleapis.com/envoy.extensions.filters." + filterType + ".rbac.v3.RBAC",
+		"stat_prefix": statPrefix,
+		rulesKey: map[string]interface{}{
+			"action":   action,
+			"policies": policies,
 		},
 	}
 }
+
+// StatPrefix returns the stat prefix of the RBAC filter of a shoot on the
+// given listener, e.g. "acl_api_shoot--foo--bar". Envoy counts the denied
+// connections of the filter in "<prefix>.rbac.denied", which the
+// deniedconnections package maps back to the shoot.
+func StatPrefix(listener, technicalShootID string) string {
+	return "acl_" + listener + "_" + technicalShootID
+}
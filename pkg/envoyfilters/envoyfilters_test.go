@@ -1,6 +1,9 @@
 package envoyfilters
 
 import (
+	"fmt"
+	"net"
+	"net/netip"
 	"os"
 	"path"
 
@@ -10,6 +13,7 @@ import (
 	. "github.com/onsi/gomega"
 	"gopkg.in/yaml.v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
 var _ = Describe("EnvoyFilter Unit Tests", func() {
@@ -49,7 +53,143 @@ var _ = Describe("EnvoyFilter Unit Tests", func() {
 					"app":   "istio-ingressgateway",
 					"istio": "ingressgateway",
 				}
-				result, err := BuildAPIEnvoyFilterSpecForHelmChart(rule, hosts, alwaysAllowedCIDRs, labels)
+				result, err := BuildAPIEnvoyFilterSpecForHelmChart(rule, nil, "shoot--bar--foo", hosts, nil, alwaysAllowedCIDRs, labels)
+
+				Expect(err).ToNot(HaveOccurred())
+				checkIfMapEqualsYAML(result, "apiEnvoyFilterSpecWithOneAllowRule.yaml")
+			})
+		})
+
+		When("there is an extension resource with a rate limit rule", func() {
+			It("Should only count other sources and throttle the connections", func() {
+				rule := createRule("RATE_LIMIT", "remote_ip", "10.0.0.0/8")
+				rule.RateLimit = &RateLimit{ConnectionsPerSecond: 10, Burst: 20}
+				hosts := []string{"api.test.garden.s.testseed.dev.ske.eu01.stackit.cloud"}
+				labels := map[string]string{
+					"app":   "istio-ingressgateway",
+					"istio": "ingressgateway",
+				}
+				result, err := BuildAPIEnvoyFilterSpecForHelmChart(rule, nil, "shoot--bar--foo", hosts, nil, alwaysAllowedCIDRs, labels)
+
+				Expect(err).ToNot(HaveOccurred())
+				checkIfMapEqualsYAML(result, "apiEnvoyFilterSpecWithRateLimitRule.yaml")
+			})
+
+			It("Should deny the globally denied CIDRs and not throttle the allowed ones", func() {
+				rule := &ACLRule{
+					Action:      "RATE_LIMIT",
+					Type:        "remote_ip",
+					Cidrs:       []string{"1.2.0.0/16"},
+					Except:      []string{"1.2.3.0/24"},
+					DeniedCIDRs: []string{"1.2.4.0/24"},
+					RateLimit:   &RateLimit{ConnectionsPerSecond: 10},
+				}
+				hosts := []string{"api.test.garden.s.testseed.dev.ske.eu01.stackit.cloud"}
+				result, err := BuildAPIEnvoyFilterSpecForHelmChart(rule, nil, "shoot--bar--foo", hosts, nil, alwaysAllowedCIDRs, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				configPatches := result["configPatches"].([]map[string]interface{})
+				Expect(configPatches).To(HaveLen(3))
+				deniedFilter := configPatches[1]["patch"].(map[string]interface{})["value"].(map[string]interface{})
+				Expect(deniedFilter["name"]).To(Equal("acl-api-denied"))
+				Expect(deniedFilter["typed_config"]).To(HaveKeyWithValue("rules", HaveKeyWithValue("action", "DENY")))
+
+				filterChain := configPatches[2]["patch"].(map[string]interface{})["value"].(map[string]interface{})
+				var filterNames []string
+				for _, filter := range filterChain["filters"].([]map[string]interface{}) {
+					filterNames = append(filterNames, filter["name"].(string))
+				}
+				Expect(filterNames).To(Equal([]string{"acl-api-denied", "acl-api", "acl-api-ratelimit", "envoy.filters.network.tcp_proxy"}))
+
+				rateLimited := &CIDRSet{}
+				for _, sourceRange := range filterChain["filter_chain_match"].(map[string]interface{})["source_prefix_ranges"].([]map[string]interface{}) {
+					rateLimited.Insert(fmt.Sprintf("%s/%d", sourceRange["address_prefix"], sourceRange["prefix_len"]))
+				}
+				for _, ip := range []string{"1.2.0.1", "1.2.255.1", "10.250.1.1", "10.96.0.1"} {
+					Expect(rateLimited.Contains(netip.MustParseAddr(ip))).To(BeFalse(), ip)
+					Expect(rule.Evaluate(net.ParseIP(ip), alwaysAllowedCIDRs).RateLimited).To(BeFalse(), ip)
+				}
+				for _, ip := range []string{"1.2.3.4", "5.6.7.8", "2001:db8::1"} {
+					Expect(rateLimited.Contains(netip.MustParseAddr(ip))).To(BeTrue(), ip)
+					Expect(rule.Evaluate(net.ParseIP(ip), alwaysAllowedCIDRs).RateLimited).To(BeTrue(), ip)
+				}
+			})
+		})
+
+		When("there is an extension resource with a log rule", func() {
+			It("Should only count the connections of the rule's CIDRs and deny the globally denied CIDRs", func() {
+				rule := createRule("LOG", "remote_ip", "1.2.3.0/24")
+				rule.DeniedCIDRs = []string{"5.6.7.0/24"}
+				hosts := []string{"api.test.garden.s.testseed.dev.ske.eu01.stackit.cloud"}
+				result, err := BuildAPIEnvoyFilterSpecForHelmChart(rule, nil, "shoot--bar--foo", hosts, nil, alwaysAllowedCIDRs, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				configPatches := result["configPatches"].([]map[string]interface{})
+				Expect(configPatches).To(HaveLen(2))
+				logFilter := configPatches[0]["patch"].(map[string]interface{})["value"].(map[string]interface{})
+				Expect(logFilter["name"]).To(Equal("acl-api"))
+				typedConfig := logFilter["typed_config"].(map[string]interface{})
+				Expect(typedConfig).NotTo(HaveKey("rules"))
+				shadowRules := typedConfig["shadow_rules"].(map[string]interface{})
+				Expect(shadowRules).To(HaveKeyWithValue("action", "DENY"))
+				Expect(shadowRules["policies"]).To(HaveKeyWithValue("acl-api", HaveKeyWithValue("principals",
+					[]map[string]interface{}{remoteIPPrincipal("1.2.3.0", 24)})))
+
+				deniedFilter := configPatches[1]["patch"].(map[string]interface{})["value"].(map[string]interface{})
+				Expect(deniedFilter["name"]).To(Equal("acl-api-denied"))
+				Expect(deniedFilter["typed_config"]).To(HaveKeyWithValue("rules", HaveKeyWithValue("action", "DENY")))
+			})
+
+			It("Should only deny the globally denied CIDRs on the VPN", func() {
+				rule := createRule("LOG", "remote_ip", "1.2.3.0/24")
+
+				patch, err := CreateVPNConfigPatchFromRule(rule, "bar--foo", "shoot--bar--foo", alwaysAllowedCIDRs, DefaultRenderings)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rbacPolicies(patch["patch"].(map[string]interface{}))["bar--foo"]["principals"]).To(Equal([]map[string]interface{}{{"any": true}}))
+
+				rule.DeniedCIDRs = []string{"5.6.7.0/24"}
+				patch, err = CreateVPNConfigPatchFromRule(rule, "bar--foo", "shoot--bar--foo", alwaysAllowedCIDRs, DefaultRenderings)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rbacPolicies(patch["patch"].(map[string]interface{}))["bar--foo"]["principals"]).To(Equal([]map[string]interface{}{{
+					"not_id": map[string]interface{}{
+						"or_ids": map[string]interface{}{
+							"ids": []map[string]interface{}{remoteIPPrincipal("5.6.7.0", 24)},
+						},
+					},
+				}}))
+			})
+		})
+
+		When("there is an extension resource with an internal rule", func() {
+			It("Should scope the rules to the requested server names", func() {
+				rule := createRule("DENY", "remote_ip", "1.2.3.4/32")
+				internalRule := createRule("ALLOW", "remote_ip", "10.0.0.0/8")
+				hosts := []string{
+					"api.test.garden.s.testseed.dev.ske.eu01.stackit.cloud",
+					"api.test.garden.internal.testseed.dev.ske.eu01.stackit.cloud",
+				}
+				labels := map[string]string{
+					"app":   "istio-ingressgateway",
+					"istio": "ingressgateway",
+				}
+				result, err := BuildAPIEnvoyFilterSpecForHelmChart(rule, internalRule, "shoot--bar--foo", hosts, hosts[1:], alwaysAllowedCIDRs, labels)
+
+				Expect(err).ToNot(HaveOccurred())
+				checkIfMapEqualsYAML(result, "apiEnvoyFilterSpecWithInternalRule.yaml")
+			})
+
+			It("Should enforce the rule for all hosts without internal hosts", func() {
+				rule := createRule("ALLOW", "source_ip", "0.0.0.0/0")
+				internalRule := createRule("ALLOW", "remote_ip", "10.0.0.0/8")
+				hosts := []string{
+					"api.test.garden.s.testseed.dev.ske.eu01.stackit.cloud",
+					"api.test.garden.internal.testseed.dev.ske.eu01.stackit.cloud",
+				}
+				labels := map[string]string{
+					"app":   "istio-ingressgateway",
+					"istio": "ingressgateway",
+				}
+				result, err := BuildAPIEnvoyFilterSpecForHelmChart(rule, internalRule, "shoot--bar--foo", hosts, nil, alwaysAllowedCIDRs, labels)
 
 				Expect(err).ToNot(HaveOccurred())
 				checkIfMapEqualsYAML(result, "apiEnvoyFilterSpecWithOneAllowRule.yaml")
@@ -65,7 +205,7 @@ var _ = Describe("EnvoyFilter Unit Tests", func() {
 					"app":   "istio-ingressgateway",
 					"istio": "ingressgateway",
 				}
-				ingressEnvoyFilterSpec := BuildIngressEnvoyFilterSpecForHelmChart(cluster, rule, alwaysAllowedCIDRs, labels)
+				ingressEnvoyFilterSpec := BuildIngressEnvoyFilterSpecForHelmChart(cluster, rule, alwaysAllowedCIDRs, labels, false)
 
 				checkIfMapEqualsYAML(ingressEnvoyFilterSpec, "ingressEnvoyFilterSpecWithOneAllowRule.yaml")
 			})
@@ -76,9 +216,38 @@ var _ = Describe("EnvoyFilter Unit Tests", func() {
 					"app":   "istio-ingressgateway",
 					"istio": "ingressgateway",
 				}
-				ingressEnvoyFilterSpec := BuildIngressEnvoyFilterSpecForHelmChart(cluster, rule, alwaysAllowedCIDRs, labels)
+				ingressEnvoyFilterSpec := BuildIngressEnvoyFilterSpecForHelmChart(cluster, rule, alwaysAllowedCIDRs, labels, false)
 				Expect(ingressEnvoyFilterSpec["ingressEnvoyFilterSpec"]).To(BeNil())
 			})
+			It("Should protect the ingress domain of the shoot if requested", func() {
+				rule := createRule("ALLOW", "remote_ip", "10.180.0.0/16")
+				shootCluster := &extensions.Cluster{
+					Shoot: cluster.Shoot.DeepCopy(),
+					Seed:  &gardencorev1beta1.Seed{},
+				}
+				shootCluster.Shoot.Spec.DNS = &gardencorev1beta1.DNS{Domain: ptr.To("foo.bar.example.com")}
+				labels := map[string]string{
+					"app":   "istio-ingressgateway",
+					"istio": "ingressgateway",
+				}
+
+				Expect(BuildIngressEnvoyFilterSpecForHelmChart(shootCluster, rule, alwaysAllowedCIDRs, labels, false)).To(BeNil())
+				ingressEnvoyFilterSpec := BuildIngressEnvoyFilterSpecForHelmChart(shootCluster, rule, alwaysAllowedCIDRs, labels, true)
+				checkIfMapEqualsYAML(ingressEnvoyFilterSpec, "ingressEnvoyFilterSpecWithShootIngress.yaml")
+			})
+		})
+
+		When("the seed is dual-stack", func() {
+			It("Should not block the connections of other shoots from any client", func() {
+				rule := createRule("ALLOW", "remote_ip", "10.180.0.0/16")
+				rule.DualStack = true
+
+				patch := CreateIngressConfigPatchFromRule(rule, "ingress.testseed.dev.ske.eu01.stackit.cloud", "bar--foo", "shoot--bar--foo", alwaysAllowedCIDRs)
+
+				policies := rbacPolicies(patch["patch"].(map[string]interface{}))
+				Expect(policies["bar--foo-inverse"]["principals"]).To(Equal([]map[string]interface{}{{"any": true}}))
+				Expect(policies["bar--foo"]["principals"]).To(ContainElement(remoteIPPrincipal("::ffff:10.180.0.0", 112)))
+			})
 		})
 	})
 
@@ -90,46 +259,187 @@ var _ = Describe("EnvoyFilter Unit Tests", func() {
 					"app":   "istio-ingressgateway",
 					"istio": "ingressgateway",
 				}
-				result, err := BuildVPNEnvoyFilterSpecForHelmChart(cluster, rule, alwaysAllowedCIDRs, labels)
+				result, err := BuildVPNEnvoyFilterSpecForHelmChart(cluster, rule, alwaysAllowedCIDRs, labels, DefaultRenderings)
 
 				Expect(err).ToNot(HaveOccurred())
 				checkIfMapEqualsYAML(result, "vpnEnvoyFilterSpecWithOneAllowRule.yaml")
 			})
 		})
+
+		It("Should omit the rules stat prefix if the gateways don't support it", func() {
+			rule := createRule("ALLOW", "remote_ip", "10.180.0.0/16")
+
+			result, err := BuildVPNEnvoyFilterSpecForHelmChart(cluster, rule, alwaysAllowedCIDRs, nil, Renderings{})
+
+			Expect(err).ToNot(HaveOccurred())
+			typedConfig := result["configPatches"].([]map[string]interface{})[0]["patch"].(map[string]interface{})["value"].(map[string]interface{})["typed_config"]
+			Expect(typedConfig).NotTo(HaveKey("rules_stat_prefix"))
+			Expect(typedConfig).To(HaveKeyWithValue("stat_prefix", "envoyrbac"))
+		})
 	})
 
-	Describe("BuildLegacyVPNEnvoyFilterSpecForHelmChart", func() {
-		When("there is one shoot with a rule", func() {
-			It("Should create a envoyFilter spec matching the expected one", func() {
-				mappings := []ACLMapping{
-					{
-						ShootName: "shoot--projectname--shootname",
-						Rule:      *createRule("ALLOW", "remote_ip", "0.0.0.0/0"),
-					},
-				}
-				labels := map[string]string{
-					"app":   "istio-ingressgateway",
-					"istio": "ingressgateway",
-				}
-				result, err := BuildLegacyVPNEnvoyFilterSpecForHelmChart(mappings, alwaysAllowedCIDRs, labels)
+	Describe("CreateVPNConfigPatchFromRule", func() {
+		When("the seed is dual-stack", func() {
+			It("Should not block the connections of other shoots from any client", func() {
+				rule := createRule("ALLOW", "remote_ip", "10.180.0.0/16")
+				rule.DualStack = true
+
+				patch, err := CreateVPNConfigPatchFromRule(rule, "bar--foo", "shoot--bar--foo", alwaysAllowedCIDRs, DefaultRenderings)
 
 				Expect(err).ToNot(HaveOccurred())
-				checkIfMapEqualsYAML(result, "legacyVPNEnvoyFilterSpecWithOneAllowRule.yaml")
+				policies := rbacPolicies(patch["patch"].(map[string]interface{}))
+				Expect(policies["bar--foo-inverse"]["principals"]).To(Equal([]map[string]interface{}{{"any": true}}))
+				Expect(policies["bar--foo"]["principals"]).To(ContainElement(remoteIPPrincipal("::ffff:10.180.0.0", 112)))
 			})
 		})
 	})
 
+	Describe("BuildAccessLogEnvoyFilterSpecForHelmChart", func() {
+		labels := map[string]string{
+			"app":   "istio-ingressgateway",
+			"istio": "ingressgateway",
+		}
+
+		It("Should create an envoyFilter spec matching the expected one", func() {
+			hosts := []string{"api.test.garden.s.testseed.dev.ske.eu01.stackit.cloud"}
+
+			result, err := BuildAccessLogEnvoyFilterSpecForHelmChart(cluster, hosts, true, false, labels)
+
+			Expect(err).ToNot(HaveOccurred())
+			checkIfMapEqualsYAML(result, "accessLogEnvoyFilterSpec.yaml")
+		})
+
+		It("Should only log the API server connections without VPN", func() {
+			hosts := []string{"api.test.garden.s.testseed.dev.ske.eu01.stackit.cloud"}
+
+			result, err := BuildAccessLogEnvoyFilterSpecForHelmChart(cluster, hosts, false, false, labels)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result["configPatches"]).To(HaveLen(1))
+		})
+
+		It("Should log the connections matched by a log rule", func() {
+			hosts := []string{"api.test.garden.s.testseed.dev.ske.eu01.stackit.cloud"}
+
+			result, err := BuildAccessLogEnvoyFilterSpecForHelmChart(cluster, hosts, false, true, labels)
+
+			Expect(err).ToNot(HaveOccurred())
+			configPatches := result["configPatches"].([]map[string]interface{})
+			Expect(configPatches).To(HaveLen(1))
+			typedConfig := configPatches[0]["patch"].(map[string]interface{})["value"].(map[string]interface{})["typed_config"].(map[string]interface{})
+			var names []string
+			for _, accessLog := range typedConfig["access_log"].([]map[string]interface{}) {
+				names = append(names, accessLog["name"].(string))
+			}
+			Expect(names).To(Equal([]string{"acl-denied-api-shoot--bar--foo", "acl-logged-api-shoot--bar--foo"}))
+		})
+
+		It("Should return the appropriate error if there are no hosts", func() {
+			result, err := BuildAccessLogEnvoyFilterSpecForHelmChart(cluster, nil, true, false, labels)
+
+			Expect(err).To(Equal(ErrNoHostsGiven))
+			Expect(result).To(BeNil())
+		})
+	})
+
 	Describe("CreateInternalFilterPatchFromRule", func() {
 		When("there is an allow rule", func() {
 			It("Should create a filter spec matching the expected one, including the always allowed CIDRs", func() {
 				rule := createRule("ALLOW", "remote_ip", "0.0.0.0/0")
 
-				result, err := CreateInternalFilterPatchFromRule(rule, alwaysAllowedCIDRs, []string{})
+				result, err := CreateInternalFilterPatchFromRule(rule, "shoot--bar--foo", alwaysAllowedCIDRs, []string{})
 
 				Expect(err).ToNot(HaveOccurred())
 				checkIfMapEqualsYAML(result, "singleFiltersAllowEntry.yaml")
 			})
 		})
+
+		When("there is an allow rule with except CIDRs", func() {
+			It("Should carve the except CIDRs out of the containing CIDR, but not out of the always allowed CIDRs", func() {
+				rule := createRule("ALLOW", "remote_ip", "10.0.0.0/16")
+				rule.Except = []string{"10.0.5.0/24"}
+
+				result, err := CreateInternalFilterPatchFromRule(rule, "shoot--bar--foo", alwaysAllowedCIDRs, []string{})
+
+				Expect(err).ToNot(HaveOccurred())
+				checkIfMapEqualsYAML(result, "singleFiltersAllowEntryWithExcept.yaml")
+			})
+		})
+
+		When("the seed is dual-stack", func() {
+			It("Should match the IPv4 CIDRs as IPv4-mapped IPv6 CIDRs as well", func() {
+				rule := createRule("ALLOW", "remote_ip", "10.0.0.0/16")
+				rule.Cidrs = append(rule.Cidrs, "2001:db8::/32")
+				rule.Except = []string{"10.0.5.0/24"}
+				rule.DualStack = true
+
+				result, err := CreateInternalFilterPatchFromRule(rule, "shoot--bar--foo", alwaysAllowedCIDRs, []string{})
+
+				Expect(err).ToNot(HaveOccurred())
+				checkIfMapEqualsYAML(result, "singleFiltersAllowEntryDualStack.yaml")
+			})
+		})
+
+		When("there is an allow rule and globally denied CIDRs", func() {
+			It("Should deny the CIDRs with precedence over the rule and the always allowed CIDRs", func() {
+				rule := createRule("ALLOW", "remote_ip", "0.0.0.0/0")
+				rule.DeniedCIDRs = []string{"203.0.113.0/24"}
+
+				result, err := CreateInternalFilterPatchFromRule(rule, "shoot--bar--foo", alwaysAllowedCIDRs, []string{})
+
+				Expect(err).ToNot(HaveOccurred())
+				checkIfMapEqualsYAML(result, "singleFiltersAllowEntryWithDeniedCIDRs.yaml")
+			})
+		})
+
+		When("there is a deny rule and globally denied CIDRs", func() {
+			It("Should add the denied CIDRs to the rule", func() {
+				rule := createRule("DENY", "source_ip", "1.2.3.4/32")
+				rule.DeniedCIDRs = []string{"203.0.113.0/24"}
+
+				result, err := CreateInternalFilterPatchFromRule(rule, "shoot--bar--foo", alwaysAllowedCIDRs, []string{})
+
+				Expect(err).ToNot(HaveOccurred())
+				checkIfMapEqualsYAML(result, "singleFiltersDenyEntryWithDeniedCIDRs.yaml")
+			})
+		})
+	})
+
+	Describe("WithIPv4MappedCIDRs", func() {
+		It("should append the IPv4-mapped IPv6 CIDRs of the IPv4 CIDRs", func() {
+			Expect(WithIPv4MappedCIDRs([]string{"10.1.2.0/24", "2001:db8::/32", "0.0.0.0/0", "invalid"})).To(Equal([]string{
+				"10.1.2.0/24", "2001:db8::/32", "0.0.0.0/0", "invalid", "::ffff:10.1.2.0/120", "::ffff:0.0.0.0/96",
+			}))
+		})
+	})
+
+	Describe("PrincipalType", func() {
+		It("should match the address of the direct connection without the PROXY protocol", func() {
+			Expect(createRule("ALLOW", "DIRECT_REMOTE_IP", "10.0.0.0/8").PrincipalType()).To(Equal(TypeDirectRemoteIP))
+		})
+
+		It("should match the client IPs from the PROXY protocol header instead of the load balancer's IP", func() {
+			rule := createRule("ALLOW", "direct_remote_ip", "10.0.0.0/8")
+			rule.ProxyProtocol = true
+			Expect(rule.PrincipalType()).To(Equal(TypeRemoteIP))
+
+			remoteIPRule := createRule("ALLOW", "remote_ip", "10.0.0.0/8")
+			remoteIPRule.ProxyProtocol = true
+			Expect(remoteIPRule.PrincipalType()).To(Equal(TypeRemoteIP))
+		})
+
+		It("should render the same principals as a remote_ip rule", func() {
+			rule := createRule("ALLOW", "direct_remote_ip", "10.0.0.0/16")
+			rule.Except = []string{"10.0.5.0/24"}
+			rule.ProxyProtocol = true
+
+			Expect(ruleCIDRsToPrincipal(rule, alwaysAllowedCIDRs)).To(Equal(ruleCIDRsToPrincipal(&ACLRule{
+				Cidrs:  rule.Cidrs,
+				Action: rule.Action,
+				Type:   TypeRemoteIP,
+				Except: rule.Except,
+			}, alwaysAllowedCIDRs)))
+		})
 	})
 
 	Describe("CreateAPIConfigPatchFromRule", func() {
@@ -137,7 +447,7 @@ var _ = Describe("EnvoyFilter Unit Tests", func() {
 			It("should return the appropriate error", func() {
 				rule := createRule("ALLOW", "remote_ip", "0.0.0.0/0")
 
-				result, err := CreateAPIConfigPatchFromRule(rule, nil, alwaysAllowedCIDRs)
+				result, err := CreateAPIConfigPatchFromRule(rule, "shoot--bar--foo", nil, alwaysAllowedCIDRs)
 
 				Expect(err).To(Equal(ErrNoHostsGiven))
 				Expect(result).To(BeNil())
@@ -145,8 +455,254 @@ var _ = Describe("EnvoyFilter Unit Tests", func() {
 		})
 	})
 
+	Describe("DuplicateCIDR", func() {
+		It("should return the first CIDR describing the same network as a previous one", func() {
+			Expect(DuplicateCIDR([]string{"10.0.0.0/8", "1.2.3.4/32", "10.1.2.3/8", "1.2.3.4/32"})).To(Equal("10.1.2.3/8"))
+		})
+
+		It("should return an empty string for unique networks", func() {
+			Expect(DuplicateCIDR([]string{"10.0.0.0/8", "10.0.0.0/16", "2001:db8::/32"})).To(BeEmpty())
+		})
+	})
+
+	Describe("OverlappingCIDRs", func() {
+		It("should return the CIDRs contained in a larger CIDR", func() {
+			Expect(OverlappingCIDRs([]string{"10.1.0.0/16", "10.0.0.0/8", "192.168.0.0/16", "2001:db8:1::/48", "2001:db8::/32"}, nil)).To(Equal([]CIDROverlap{
+				{CIDR: "10.1.0.0/16", ContainedIn: "10.0.0.0/8"},
+				{CIDR: "2001:db8:1::/48", ContainedIn: "2001:db8::/32"},
+			}))
+		})
+
+		It("should ignore duplicates and disjoint CIDRs", func() {
+			Expect(OverlappingCIDRs([]string{"10.0.0.0/8", "10.0.0.0/8", "192.168.0.0/16"}, nil)).To(BeEmpty())
+		})
+
+		It("should ignore CIDRs contained in an except block of the larger CIDR", func() {
+			Expect(OverlappingCIDRs(
+				[]string{"10.0.0.0/8", "10.1.2.0/24", "10.2.0.0/16", "10.3.0.0/16"},
+				[]string{"10.1.0.0/16", "10.2.0.0/16", "10.3.4.0/24"},
+			)).To(Equal([]CIDROverlap{
+				{CIDR: "10.2.0.0/16", ContainedIn: "10.0.0.0/8"},
+				{CIDR: "10.3.0.0/16", ContainedIn: "10.0.0.0/8"},
+			}))
+		})
+	})
+
+	Describe("Evaluate", func() {
+		DescribeTable("should decide like the RBAC filters",
+			func(rule *ACLRule, ip string, allowed, rateLimited bool, matchedCIDR string) {
+				decision := rule.Evaluate(net.ParseIP(ip), alwaysAllowedCIDRs)
+
+				Expect(decision.Allowed).To(Equal(allowed))
+				Expect(decision.RateLimited).To(Equal(rateLimited))
+				Expect(decision.MatchedCIDR).To(Equal(matchedCIDR))
+				Expect(decision.Reason).To(ContainSubstring(ip))
+			},
+			Entry("ALLOW rule, IP in the rule's CIDRs",
+				createRule("ALLOW", "remote_ip", "1.2.3.0/24"), "1.2.3.4", true, false, "1.2.3.0/24"),
+			Entry("ALLOW rule, IP in the always allowed CIDRs",
+				createRule("ALLOW", "remote_ip", "1.2.3.0/24"), "10.250.1.1", true, false, "10.250.0.0/16"),
+			Entry("ALLOW rule, IP in no CIDR",
+				createRule("ALLOW", "remote_ip", "1.2.3.0/24"), "5.6.7.8", false, false, ""),
+			Entry("ALLOW rule, IP in an except block",
+				&ACLRule{Action: "ALLOW", Type: "remote_ip", Cidrs: []string{"1.2.0.0/16"}, Except: []string{"1.2.3.0/24"}}, "1.2.3.4", false, false, ""),
+			Entry("ALLOW rule, IP in a globally denied CIDR",
+				&ACLRule{Action: "ALLOW", Type: "remote_ip", Cidrs: []string{"1.2.3.0/24"}, DeniedCIDRs: []string{"1.2.3.4/32"}}, "1.2.3.4", false, false, "1.2.3.4/32"),
+			Entry("DENY rule, IP in the rule's CIDRs",
+				createRule("DENY", "remote_ip", "1.2.3.0/24"), "1.2.3.4", false, false, "1.2.3.0/24"),
+			Entry("DENY rule, IP in no CIDR",
+				createRule("DENY", "remote_ip", "1.2.3.0/24"), "5.6.7.8", true, false, ""),
+			Entry("RATE_LIMIT rule, IP in the rule's CIDRs",
+				&ACLRule{Action: "RATE_LIMIT", Type: "remote_ip", Cidrs: []string{"1.2.3.0/24"}, RateLimit: &RateLimit{ConnectionsPerSecond: 10}}, "1.2.3.4", true, false, "1.2.3.0/24"),
+			Entry("RATE_LIMIT rule, IP in the always allowed CIDRs",
+				&ACLRule{Action: "RATE_LIMIT", Type: "remote_ip", Cidrs: []string{"1.2.3.0/24"}, RateLimit: &RateLimit{ConnectionsPerSecond: 10}}, "10.250.1.1", true, false, "10.250.0.0/16"),
+			Entry("RATE_LIMIT rule, IP in a globally denied CIDR",
+				&ACLRule{Action: "RATE_LIMIT", Type: "remote_ip", Cidrs: []string{"1.2.3.0/24"}, DeniedCIDRs: []string{"1.2.3.4/32"}, RateLimit: &RateLimit{ConnectionsPerSecond: 10}}, "1.2.3.4", false, false, "1.2.3.4/32"),
+			Entry("RATE_LIMIT rule, IP in no CIDR",
+				&ACLRule{Action: "RATE_LIMIT", Type: "remote_ip", Cidrs: []string{"1.2.3.0/24"}, RateLimit: &RateLimit{ConnectionsPerSecond: 10}}, "5.6.7.8", true, true, ""),
+			Entry("LOG rule, IP in the rule's CIDRs",
+				createRule("LOG", "remote_ip", "1.2.3.0/24"), "1.2.3.4", true, false, "1.2.3.0/24"),
+			Entry("LOG rule, IP in no CIDR",
+				createRule("LOG", "remote_ip", "1.2.3.0/24"), "5.6.7.8", true, false, ""),
+			Entry("LOG rule, IP in a globally denied CIDR",
+				&ACLRule{Action: "LOG", Type: "remote_ip", Cidrs: []string{"1.2.3.0/24"}, DeniedCIDRs: []string{"1.2.3.4/32"}}, "1.2.3.4", false, false, "1.2.3.4/32"),
+			Entry("DENY rule, IP in a protected CIDR",
+				&ACLRule{Action: "DENY", Type: "remote_ip", Cidrs: []string{"0.0.0.0/0"}, ProtectedCIDRs: []string{"10.250.0.0/16"}}, "10.250.1.1", true, false, "10.250.0.0/16"),
+			Entry("ALLOW rule, IP in a globally denied and protected CIDR",
+				&ACLRule{Action: "ALLOW", Type: "remote_ip", Cidrs: []string{"1.2.3.0/24"}, DeniedCIDRs: []string{"10.0.0.0/8"}, ProtectedCIDRs: []string{"10.250.0.0/16"}}, "10.250.1.1", true, false, "10.250.0.0/16"),
+		)
+	})
+
+	Describe("protected CIDRs", func() {
+		var (
+			// the node network of the shoot and the egress CIDR of its NAT
+			// gateway, whose addresses the kubelets and the VPN connect from
+			protectedCIDRs = []string{"10.180.0.0/16", "203.0.113.7/32"}
+			protectedIPs   = []string{"10.180.1.1", "203.0.113.7"}
+			ruleCIDRs      = [][]string{
+				{"0.0.0.0/0"},
+				{"10.180.0.0/16"},
+				{"10.0.0.0/8", "203.0.113.0/24"},
+				{"198.51.100.0/24"},
+			}
+			excepts = [][]string{
+				nil,
+				{"10.180.1.0/24"},
+				{"203.0.113.7/32"},
+			}
+			deniedCIDRs = [][]string{
+				nil,
+				{"0.0.0.0/0"},
+				{"10.180.1.1/32", "203.0.113.0/24"},
+			}
+		)
+
+		It("should never deny the connections of the protected CIDRs, whatever the rule is", func() {
+			for _, action := range []string{ActionAllow, ActionDeny, ActionRateLimit, ActionLog} {
+				for _, ruleType := range Types() {
+					for _, cidrs := range ruleCIDRs {
+						for _, except := range excepts {
+							if !exceptsContained(except, cidrs) {
+								continue
+							}
+							for _, denied := range deniedCIDRs {
+								for _, dualStack := range []bool{false, true} {
+									rule := &ACLRule{
+										Action:         action,
+										Type:           ruleType,
+										Cidrs:          cidrs,
+										Except:         except,
+										DeniedCIDRs:    denied,
+										ProtectedCIDRs: protectedCIDRs,
+										DualStack:      dualStack,
+									}
+									if rule.IsRateLimit() {
+										rule.RateLimit = &RateLimit{ConnectionsPerSecond: 10}
+									}
+									description := fmt.Sprintf("%+v", *rule)
+
+									spec, err := BuildAPIEnvoyFilterSpecForHelmChart(rule, nil, "shoot--foo--bar", []string{"api.foo.bar"}, nil, nil, nil)
+									Expect(err).NotTo(HaveOccurred())
+									filters := insertedFilters(spec["configPatches"].([]map[string]interface{}))
+									rateLimited := NewCIDRSet()
+									for _, prefix := range rule.RateLimitedSources(nil) {
+										rateLimited.Insert(prefix.String())
+									}
+
+									for _, protectedIP := range protectedIPs {
+										ips := []netip.Addr{netip.MustParseAddr(protectedIP)}
+										if dualStack {
+											ips = append(ips, netip.AddrFrom16(ips[0].As16()))
+										}
+										for _, ip := range ips {
+											Expect(rule.Evaluate(net.IP(ip.AsSlice()), nil).Allowed).To(BeTrue(), "%s: %s", ip, description)
+											Expect(rbacFiltersAllow(filters, ip)).To(BeTrue(), "%s: %s", ip, description)
+											Expect(principalsMatch(sharedListenerPrincipals(rule, nil), ip)).To(BeTrue(), "%s: %s", ip, description)
+											Expect(rateLimited.Contains(ip)).To(BeFalse(), "%s: %s", ip, description)
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		})
+
+		It("should still enforce the rule for the other sources", func() {
+			rule := &ACLRule{
+				Action:         ActionDeny,
+				Type:           TypeRemoteIP,
+				Cidrs:          []string{"0.0.0.0/0"},
+				ProtectedCIDRs: protectedCIDRs,
+			}
+			spec, err := BuildAPIEnvoyFilterSpecForHelmChart(rule, nil, "shoot--foo--bar", []string{"api.foo.bar"}, nil, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(rbacFiltersAllow(insertedFilters(spec["configPatches"].([]map[string]interface{})), netip.MustParseAddr("10.181.1.1"))).To(BeFalse())
+			Expect(rule.Evaluate(net.ParseIP("10.181.1.1"), nil).Allowed).To(BeFalse())
+		})
+
+		It("should protect additional CIDRs on a copy of the rule", func() {
+			rule := &ACLRule{ProtectedCIDRs: []string{"10.180.0.0/16"}}
+
+			Expect(rule.WithProtectedCIDRs("203.0.113.7/32").ProtectedCIDRs).To(Equal([]string{"10.180.0.0/16", "203.0.113.7/32"}))
+			Expect(rule.ProtectedCIDRs).To(Equal([]string{"10.180.0.0/16"}))
+		})
+	})
 })
 
+// exceptsContained returns true if every except block is contained in one of
+// the CIDRs, like the validation of the extension spec requires.
+func exceptsContained(excepts, cidrs []string) bool {
+	for _, except := range excepts {
+		if !NewCIDRSet(cidrs...).ContainsPrefix(netip.MustParsePrefix(except)) {
+			return false
+		}
+	}
+	return true
+}
+
+// rbacFiltersAllow returns true if none of the given enforcing RBAC filters
+// denies a connection from the IP. All principal types are assumed to see the
+// same address, the filters only counting connections in their shadow rules
+// are skipped.
+func rbacFiltersAllow(filters []map[string]interface{}, ip netip.Addr) bool {
+	for _, filter := range filters {
+		typedConfig, _ := filter["typed_config"].(map[string]interface{})
+		rules, ok := typedConfig["rules"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		matched := false
+		for _, policy := range rules["policies"].(map[string]interface{}) {
+			if principalsMatch(policy.(map[string]interface{})["principals"].([]map[string]interface{}), ip) {
+				matched = true
+			}
+		}
+		if matched != (rules["action"] == ActionAllow) {
+			return false
+		}
+	}
+	return true
+}
+
+// principalsMatch returns true if one of the envoy principals matches a
+// connection from the IP.
+func principalsMatch(principals []map[string]interface{}, ip netip.Addr) bool {
+	for _, principal := range principals {
+		if principalMatches(principal, ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func principalMatches(principal map[string]interface{}, ip netip.Addr) bool {
+	for key, value := range principal {
+		switch key {
+		case "any":
+			return true
+		case "or_ids":
+			return principalsMatch(value.(map[string]interface{})["ids"].([]map[string]interface{}), ip)
+		case "and_ids":
+			for _, id := range value.(map[string]interface{})["ids"].([]map[string]interface{}) {
+				if !principalMatches(id, ip) {
+					return false
+				}
+			}
+			return true
+		case "not_id":
+			return !principalMatches(value.(map[string]interface{}), ip)
+		default:
+			cidr := value.(map[string]interface{})
+			prefix := netip.PrefixFrom(netip.MustParseAddr(cidr["address_prefix"].(string)), cidr["prefix_len"].(int))
+			return prefix.Contains(ip)
+		}
+	}
+	return false
+}
+
 //nolint:unparam // action currently only accepts ALLOW but that might change, so we leave the parameterization
 func createRule(action, ruleType, cidr string) *ACLRule {
 	return &ACLRule{
@@ -158,6 +714,25 @@ func createRule(action, ruleType, cidr string) *ACLRule {
 	}
 }
 
+// rbacPolicies returns the policies of the RBAC filter inserted by the patch.
+func rbacPolicies(patch map[string]interface{}) map[string]map[string]interface{} {
+	typedConfig := patch["value"].(map[string]interface{})["typed_config"].(map[string]interface{})
+	policies := map[string]map[string]interface{}{}
+	for name, policy := range typedConfig["rules"].(map[string]interface{})["policies"].(map[string]interface{}) {
+		policies[name] = policy.(map[string]interface{})
+	}
+	return policies
+}
+
+func remoteIPPrincipal(prefix string, length int) map[string]interface{} {
+	return map[string]interface{}{
+		"remote_ip": map[string]interface{}{
+			"address_prefix": prefix,
+			"prefix_len":     length,
+		},
+	}
+}
+
 // checkIfMapEqualsYAML takes a map as input, and tries to compare its
 // marshaled contents to the string coming from the specified testdata file.
 // Fails the test if strings differ. The file contents are unmarshaled and
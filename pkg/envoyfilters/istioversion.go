@@ -0,0 +1,101 @@
+package envoyfilters
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IstioVersion is the minor release of istio, e.g. 1.21, whose Envoy runs the
+// ingress gateways. The typed configs of the Envoy filters drift between the
+// releases, see RenderingsFor.
+type IstioVersion struct {
+	Major int
+	Minor int
+}
+
+var (
+	// MinIstioVersion is the oldest istio release the filters are rendered
+	// for.
+	MinIstioVersion = IstioVersion{Major: 1, Minor: 13}
+	// MaxIstioVersion is the latest istio release the renderings of the
+	// filters are known to be compatible with.
+	MaxIstioVersion = IstioVersion{Major: 1, Minor: 22}
+
+	// rulesStatPrefixVersion is the first istio release whose Envoy (1.23)
+	// supports the rules_stat_prefix of HTTP RBAC filters.
+	rulesStatPrefixVersion = IstioVersion{Major: 1, Minor: 15}
+	// celAccessLogFilterVersion is the first istio release whose Envoy (1.26)
+	// supports CEL expressions as access log filters.
+	celAccessLogFilterVersion = IstioVersion{Major: 1, Minor: 18}
+)
+
+// ParseIstioVersion parses the istio release from a version or an image tag,
+// e.g. "1.21.1", "v1.21" or "1.21.1-distroless".
+func ParseIstioVersion(version string) (IstioVersion, error) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return IstioVersion{}, fmt.Errorf("invalid istio version %q", version)
+	}
+	// the patch version might be followed by a suffix, the minor version
+	// only if there is no patch version
+	minor, _, _ := strings.Cut(parts[1], "-")
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return IstioVersion{}, fmt.Errorf("invalid istio version %q: %w", version, err)
+	}
+	minorVersion, err := strconv.Atoi(minor)
+	if err != nil {
+		return IstioVersion{}, fmt.Errorf("invalid istio version %q: %w", version, err)
+	}
+	return IstioVersion{Major: major, Minor: minorVersion}, nil
+}
+
+// String returns the version in the form "1.21".
+func (v IstioVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// AtLeast returns whether the version is the same or a later release than the
+// other one.
+func (v IstioVersion) AtLeast(other IstioVersion) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	return v.Minor >= other.Minor
+}
+
+// IsSupported returns whether the renderings of the filters are known to be
+// compatible with the version, i.e. whether it is between MinIstioVersion and
+// MaxIstioVersion.
+func (v IstioVersion) IsSupported() bool {
+	return v.AtLeast(MinIstioVersion) && MaxIstioVersion.AtLeast(v)
+}
+
+// Renderings selects the renderings of the typed configs which are compatible
+// with the Envoy of the ingress gateways. Envoy rejects the whole listener if a
+// filter contains fields it doesn't know, so features are only rendered if
+// the gateways support them.
+type Renderings struct {
+	// RulesStatPrefix specifies whether the rules_stat_prefix of HTTP RBAC
+	// filters is rendered. Without it, the denied connections of the VPN
+	// aren't counted per shoot.
+	RulesStatPrefix bool
+	// AccessLogFilter specifies whether the access logs of denied connections
+	// are rendered, as they are filtered by CEL expressions.
+	AccessLogFilter bool
+}
+
+// DefaultRenderings are the renderings of MaxIstioVersion, which are used if
+// the istio version is unknown.
+var DefaultRenderings = RenderingsFor(MaxIstioVersion)
+
+// RenderingsFor returns the renderings compatible with the given istio
+// version. Versions newer than MaxIstioVersion get the latest renderings.
+func RenderingsFor(version IstioVersion) Renderings {
+	return Renderings{
+		RulesStatPrefix: version.AtLeast(rulesStatPrefixVersion),
+		AccessLogFilter: version.AtLeast(celAccessLogFilterVersion),
+	}
+}
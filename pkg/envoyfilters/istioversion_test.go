@@ -0,0 +1,57 @@
+package envoyfilters
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("istio versions", func() {
+	Describe("#ParseIstioVersion", func() {
+		DescribeTable("should parse the release",
+			func(version string, expected IstioVersion) {
+				Expect(ParseIstioVersion(version)).To(Equal(expected))
+			},
+			Entry("version", "1.21.1", IstioVersion{Major: 1, Minor: 21}),
+			Entry("version with prefix", "v1.21", IstioVersion{Major: 1, Minor: 21}),
+			Entry("image tag", "1.21.1-distroless", IstioVersion{Major: 1, Minor: 21}),
+			Entry("image tag without patch version", "1.22-dev", IstioVersion{Major: 1, Minor: 22}),
+		)
+
+		DescribeTable("should reject invalid versions",
+			func(version string) {
+				_, err := ParseIstioVersion(version)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("empty", ""),
+			Entry("latest", "latest"),
+			Entry("major only", "1"),
+			Entry("invalid minor", "1.x.0"),
+		)
+	})
+
+	Describe("#IsSupported", func() {
+		It("should support the releases between the minimum and maximum version", func() {
+			Expect(MinIstioVersion.IsSupported()).To(BeTrue())
+			Expect(IstioVersion{Major: 1, Minor: 19}.IsSupported()).To(BeTrue())
+			Expect(MaxIstioVersion.IsSupported()).To(BeTrue())
+		})
+
+		It("should not support older or newer releases", func() {
+			Expect(IstioVersion{Major: 1, Minor: 12}.IsSupported()).To(BeFalse())
+			Expect(IstioVersion{Major: MaxIstioVersion.Major, Minor: MaxIstioVersion.Minor + 1}.IsSupported()).To(BeFalse())
+			Expect(IstioVersion{Major: 2, Minor: 0}.IsSupported()).To(BeFalse())
+		})
+	})
+
+	Describe("#RenderingsFor", func() {
+		It("should only render the features supported by the release", func() {
+			Expect(RenderingsFor(IstioVersion{Major: 1, Minor: 14})).To(Equal(Renderings{}))
+			Expect(RenderingsFor(IstioVersion{Major: 1, Minor: 17})).To(Equal(Renderings{RulesStatPrefix: true}))
+			Expect(RenderingsFor(IstioVersion{Major: 1, Minor: 18})).To(Equal(Renderings{RulesStatPrefix: true, AccessLogFilter: true}))
+		})
+
+		It("should render all features by default", func() {
+			Expect(DefaultRenderings).To(Equal(Renderings{RulesStatPrefix: true, AccessLogFilter: true}))
+		})
+	})
+})
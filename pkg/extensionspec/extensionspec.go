@@ -1,9 +1,189 @@
 package extensionspec
 
-import "github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+import (
+	"slices"
+	"strings"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+)
+
+const (
+	// ProfileAPIServerOnly only enforces the rule for the shoot's API server.
+	ProfileAPIServerOnly = "apiserver-only"
+	// ProfileFull enforces the rule for the shoot's API server, the VPN and
+	// the endpoints exposed via the seed ingress (e.g. the observability
+	// components). This is the default.
+	ProfileFull = "full"
+)
+
+// Target is a kind of traffic the rule is enforced for.
+type Target string
+
+const (
+	// TargetAPIServer is the traffic to the shoot's API server, both via SNI
+	// and via the internal flow.
+	TargetAPIServer Target = "apiserver"
+	// TargetVPN is the traffic to the shoot's reversed VPN listener.
+	TargetVPN Target = "vpn"
+	// TargetIngress is the traffic to the endpoints of the shoot exposed via
+	// the seed ingress domain, e.g. plutono and prometheus.
+	TargetIngress Target = "ingress"
+)
+
+// unsupportedTypes lists the rule types which can't be used for a target,
+// together with a hint for the user. The API server and the ingress are
+// served by SNI passthrough listeners, which only support the non-deprecated
+// principals of envoy.
+var unsupportedTypes = map[Target]map[string]string{
+	TargetAPIServer: {
+		envoyfilters.TypeSourceIP: "it is deprecated by envoy, use 'remote_ip' for the same behavior",
+	},
+	TargetIngress: {
+		envoyfilters.TypeSourceIP: "it is deprecated by envoy, use 'remote_ip' for the same behavior",
+	},
+}
+
+var profileTargets = map[string][]Target{
+	ProfileAPIServerOnly: {TargetAPIServer},
+	ProfileFull:          {TargetAPIServer, TargetVPN, TargetIngress},
+}
 
 // ExtensionSpec is the content of the ProviderConfig of the acl extension object
 type ExtensionSpec struct {
 	// Rule contain the user-defined Access Control Rule
 	Rule *envoyfilters.ACLRule `json:"rule"`
+	// InternalRule is enforced instead of the Rule for the connections to
+	// the internal address of the shoot's API server, e.g. to only allow the
+	// corporate VPN there. The Rule still applies to all other addresses and
+	// targets. "RATE_LIMIT" rules are not supported.
+	InternalRule *envoyfilters.ACLRule `json:"internalRule,omitempty"`
+	// Profile selects the targets the rule is enforced for, either
+	// "apiserver-only" or "full". Defaults to "full".
+	Profile string `json:"profile,omitempty"`
+	// ProtectIngress enforces the rule for the wildcard ingress domain of the
+	// shoot (`*.ingress.<shoot domain>`) as well, if it is served by the seed
+	// ingress gateway. It requires the ingress target of the "full" profile.
+	ProtectIngress bool `json:"protectIngress,omitempty"`
+	// LogDeniedConnections enables the access logging of the connections
+	// denied by the rule on the istio ingress gateways. Defaults to the
+	// setting of the seed.
+	LogDeniedConnections *bool `json:"logDeniedConnections,omitempty"`
+	// AllowOpenAccess confirms that an "ALLOW" rule covers the whole address
+	// space (0.0.0.0/0 or ::/0), which makes the ACL ineffective. Such rules
+	// are rejected unless this is set.
+	AllowOpenAccess bool `json:"allowOpenAccess,omitempty"`
+	// NormalizeCIDRs accepts CIDRs and except blocks with host bits set, e.g.
+	// "10.1.2.3/24", and enforces them as their network, e.g. "10.1.2.0/24".
+	// Such CIDRs are rejected unless this is set.
+	NormalizeCIDRs bool `json:"normalizeCIDRs,omitempty"`
+	// Disabled removes all enforcement of the ACL for the shoot while keeping
+	// the extension enabled, e.g. as break-glass during a lockout. The rules
+	// are kept, but neither validated nor enforced.
+	Disabled bool `json:"disabled,omitempty"`
+	// Workerless is set by the controller and the admission for shoots
+	// without workers. They have neither nodes nor a VPN, so the VPN target
+	// is dropped from their profile, see Targets.
+	Workerless bool `json:"-"`
+}
+
+// Profiles returns the names of all supported profiles.
+func Profiles() []string {
+	return []string{ProfileAPIServerOnly, ProfileFull}
+}
+
+// IsValidProfile returns true if the profile is empty or one of the supported
+// profiles.
+func IsValidProfile(profile string) bool {
+	_, ok := profileTargets[profile]
+	return ok || profile == ""
+}
+
+// Targets returns the targets the rule is enforced for, expanded from the
+// profile. Workerless shoots don't have the VPN target.
+func (s *ExtensionSpec) Targets() []Target {
+	targets := profileTargets[s.Profile]
+	if s.Profile == "" {
+		targets = profileTargets[ProfileFull]
+	}
+	if s.Workerless {
+		return slices.DeleteFunc(slices.Clone(targets), func(target Target) bool { return target == TargetVPN })
+	}
+	return targets
+}
+
+// ShouldLogDeniedConnections returns true if the denied connections are
+// logged, falling back to the given default of the seed.
+func (s *ExtensionSpec) ShouldLogDeniedConnections(seedDefault bool) bool {
+	if s.LogDeniedConnections == nil {
+		return seedDefault
+	}
+	return *s.LogDeniedConnections
+}
+
+// HasTarget returns true if the rule is enforced for the given target.
+// UnsupportedType returns the first target of the profile the type of the
+// rule can't be used for, together with a hint how to fix the rule.
+func (s *ExtensionSpec) UnsupportedType() (target Target, hint string, unsupported bool) {
+	if s.Rule == nil {
+		return "", "", false
+	}
+	for _, target := range s.Targets() {
+		if hint, ok := unsupportedTypes[target][strings.ToLower(s.Rule.Type)]; ok {
+			return target, hint, true
+		}
+	}
+	return "", "", false
+}
+
+// UnsupportedInternalRuleType returns a hint how to fix the InternalRule if
+// its type can't be used for the API server.
+func (s *ExtensionSpec) UnsupportedInternalRuleType() (hint string, unsupported bool) {
+	if s.InternalRule == nil {
+		return "", false
+	}
+	hint, unsupported = unsupportedTypes[TargetAPIServer][strings.ToLower(s.InternalRule.Type)]
+	return hint, unsupported
+}
+
+func (s *ExtensionSpec) HasTarget(target Target) bool {
+	return slices.Contains(s.Targets(), target)
+}
+
+// Normalize removes the host bits of the CIDRs of both rules if
+// NormalizeCIDRs is set.
+func (s *ExtensionSpec) Normalize() {
+	if !s.NormalizeCIDRs {
+		return
+	}
+	for _, rule := range []*envoyfilters.ACLRule{s.Rule, s.InternalRule} {
+		if rule != nil {
+			rule.MaskCIDRs()
+		}
+	}
+}
+
+// Canonicalize brings the spec into its canonical form, so equal rules are
+// written the same way: the actions are upper case and the types lower case,
+// the CIDRs and except blocks are canonical, sorted and unique (and without
+// host bits if NormalizeCIDRs is set), and the defaults of the profile and
+// the burst of "RATE_LIMIT" rules are set explicitly.
+func (s *ExtensionSpec) Canonicalize() {
+	if s.Profile == "" {
+		s.Profile = ProfileFull
+	}
+	s.Normalize()
+	for _, rule := range []*envoyfilters.ACLRule{s.Rule, s.InternalRule} {
+		if rule == nil {
+			continue
+		}
+		rule.Action = strings.ToUpper(rule.Action)
+		rule.Type = strings.ToLower(rule.Type)
+		rule.Cidrs = envoyfilters.CanonicalCIDRs(rule.Cidrs)
+		if len(rule.Except) > 0 {
+			rule.Except = envoyfilters.CanonicalCIDRs(rule.Except)
+		}
+		if rule.RateLimit != nil && rule.RateLimit.Burst == 0 {
+			rule.RateLimit.Burst = rule.RateLimit.ConnectionsPerSecond
+		}
+	}
 }
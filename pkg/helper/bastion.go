@@ -0,0 +1,43 @@
+package helper
+
+import (
+	"context"
+	"net"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GetBastionCIDRs returns the public IPs of the bastion hosts of the shoot in
+// the given namespace as single host CIDRs, sorted and without duplicates.
+// Bastions being deleted are skipped, so their IPs are removed from the ACL
+// as soon as the deletion starts.
+func GetBastionCIDRs(ctx context.Context, c client.Reader, namespace string) ([]string, error) {
+	bastions := &extensionsv1alpha1.BastionList{}
+	if err := c.List(ctx, bastions, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	return BastionCIDRs(bastions.Items), nil
+}
+
+// BastionCIDRs returns the public IPs of the given bastions which aren't being
+// deleted as single host CIDRs, sorted and without duplicates.
+func BastionCIDRs(bastions []extensionsv1alpha1.Bastion) []string {
+	cidrs := sets.New[string]()
+	for _, bastion := range bastions {
+		if !bastion.DeletionTimestamp.IsZero() || bastion.Status.Ingress == nil {
+			continue
+		}
+		ip := net.ParseIP(bastion.Status.Ingress.IP)
+		if ip == nil {
+			continue
+		}
+		if ip.To4() != nil {
+			cidrs.Insert(ip.String() + "/32")
+		} else {
+			cidrs.Insert(ip.String() + "/128")
+		}
+	}
+	return sets.List(cidrs)
+}
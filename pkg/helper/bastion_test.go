@@ -0,0 +1,46 @@
+package helper
+
+import (
+	"context"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("bastion", func() {
+	Describe("#GetBastionCIDRs", func() {
+		newBastion := func(namespace, name, ip string) *extensionsv1alpha1.Bastion {
+			bastion := &extensionsv1alpha1.Bastion{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+			if ip != "" {
+				bastion.Status.Ingress = &corev1.LoadBalancerIngress{IP: ip}
+			}
+			return bastion
+		}
+
+		It("should return the IPs of the bastions of the shoot", func() {
+			scheme := runtime.NewScheme()
+			Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+			deleting := newBastion("shoot--foo--bar", "deleting", "203.0.113.3")
+			deleting.DeletionTimestamp = &metav1.Time{}
+			deleting.Finalizers = []string{"extensions.gardener.cloud/bastion"}
+
+			c := fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(
+				newBastion("shoot--foo--bar", "cli-a", "203.0.113.1"),
+				newBastion("shoot--foo--bar", "cli-b", "2001:db8::1"),
+				newBastion("shoot--foo--bar", "pending", ""),
+				deleting,
+				newBastion("shoot--foo--baz", "cli-c", "203.0.113.2"),
+			).Build()
+
+			cidrs, err := GetBastionCIDRs(context.Background(), c, "shoot--foo--bar")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cidrs).To(Equal([]string{"2001:db8::1/128", "203.0.113.1/32"}))
+		})
+	})
+})
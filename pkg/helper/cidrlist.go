@@ -0,0 +1,66 @@
+package helper
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CIDRsDataKey is the key in the operator-managed global allowlist and
+// denylist ConfigMaps that contains the CIDRs.
+const CIDRsDataKey = "cidrs"
+
+// GetCIDRsFromConfigMap returns the CIDRs from an operator-managed ConfigMap,
+// e.g. the global allowlist or denylist. The CIDRs are separated by newlines
+// or commas. If no ConfigMap is configured or it doesn't exist, no CIDRs are
+// returned.
+func GetCIDRsFromConfigMap(ctx context.Context, c client.Reader, key types.NamespacedName) ([]string, error) {
+	if key.Name == "" {
+		return nil, nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := c.Get(ctx, key, configMap); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	cidrs, err := ParseCIDRList(configMap.Data[CIDRsDataKey])
+	if err != nil {
+		return nil, fmt.Errorf("ConfigMap %s: %w", key, err)
+	}
+	return cidrs, nil
+}
+
+// ParseCIDRList parses a newline or comma separated list of CIDRs. Empty lines
+// and lines starting with "#" are ignored.
+func ParseCIDRList(data string) ([]string, error) {
+	cidrs := make([]string, 0)
+	for _, field := range strings.FieldsFunc(data, func(r rune) bool { return r == ',' || r == '\n' }) {
+		cidr := strings.TrimSpace(field)
+		if cidr == "" || strings.HasPrefix(cidr, "#") {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, nil
+}
+
+// ComputeCIDRsChecksum returns a checksum of the given CIDRs, independent of
+// their order.
+func ComputeCIDRsChecksum(cidrs []string) string {
+	sorted := append([]string{}, cidrs...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, ",")))
+	return hex.EncodeToString(sum[:])
+}
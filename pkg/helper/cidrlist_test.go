@@ -0,0 +1,30 @@
+package helper
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("cidrlist", func() {
+	Describe("#ParseCIDRList", func() {
+		It("should parse newline and comma separated CIDRs", func() {
+			cidrs, err := ParseCIDRList("# monitoring\n10.0.0.0/24, 10.1.0.0/24\n\n192.168.0.1/32\n")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cidrs).To(Equal([]string{"10.0.0.0/24", "10.1.0.0/24", "192.168.0.1/32"}))
+		})
+
+		It("should return an error for invalid CIDRs", func() {
+			_, err := ParseCIDRList("10.0.0.0/24\nfoo")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("#ComputeCIDRsChecksum", func() {
+		It("should not depend on the order of the CIDRs", func() {
+			Expect(ComputeCIDRsChecksum([]string{"10.0.0.0/24", "10.1.0.0/24"})).
+				To(Equal(ComputeCIDRsChecksum([]string{"10.1.0.0/24", "10.0.0.0/24"})))
+			Expect(ComputeCIDRsChecksum([]string{"10.0.0.0/24"})).
+				NotTo(Equal(ComputeCIDRsChecksum([]string{"10.1.0.0/24"})))
+		})
+	})
+})
@@ -0,0 +1,91 @@
+package helper
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ClientIPPreservation describes how the load balancer of an istio ingress
+// gateway passes the IPs of the clients to the gateway.
+type ClientIPPreservation string
+
+const (
+	// ClientIPAuto detects the ClientIPPreservation from the Service of the
+	// istio ingress gateway, see DetectClientIPPreservation.
+	ClientIPAuto ClientIPPreservation = "auto"
+	// ClientIPPreserved means the connections to the gateway originate from
+	// the clients, both "remote_ip" and "direct_remote_ip" match the clients.
+	ClientIPPreserved ClientIPPreservation = "preserved"
+	// ClientIPProxied means the load balancer passes the client IPs via the
+	// PROXY protocol, only "remote_ip" matches the clients.
+	ClientIPProxied ClientIPPreservation = "proxied"
+	// ClientIPNATed means the client IPs are replaced by the load balancer or
+	// the nodes, no rule type matches the clients.
+	ClientIPNATed ClientIPPreservation = "nated"
+)
+
+// ClientIPPreservations returns all values of ClientIPPreservation.
+func ClientIPPreservations() []ClientIPPreservation {
+	return []ClientIPPreservation{ClientIPAuto, ClientIPPreserved, ClientIPProxied, ClientIPNATed}
+}
+
+// proxyProtocolAnnotations are the annotations of LoadBalancer Services
+// enabling the PROXY protocol, with the value enabling it or "" for any value.
+var proxyProtocolAnnotations = map[string]string{
+	"service.beta.kubernetes.io/aws-load-balancer-proxy-protocol": "",
+	"loadbalancer.openstack.org/proxy-protocol":                   "true",
+	"lb.stackit.cloud/tcp-proxy-protocol":                         "true",
+}
+
+// DetectClientIPPreservation determines the ClientIPPreservation of the
+// LoadBalancer Services in the namespace selecting the pods with the given
+// istio labels (all LoadBalancer Services if istioLabels is nil). The PROXY
+// protocol takes precedence, as "remote_ip" also matches the clients of
+// Services preserving them. ClientIPPreserved is returned if there is no such
+// Service, so the rule type is used as is.
+func DetectClientIPPreservation(
+	ctx context.Context, c client.Client, namespace string, istioLabels map[string]string,
+) (ClientIPPreservation, error) {
+	services := &corev1.ServiceList{}
+	if err := c.List(ctx, services, client.InNamespace(namespace)); err != nil {
+		return "", err
+	}
+
+	result := ClientIPPreserved
+	for i := range services.Items {
+		service := &services.Items[i]
+		if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		if istioLabels != nil && (len(service.Spec.Selector) == 0 ||
+			!labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(istioLabels))) {
+			continue
+		}
+
+		switch serviceClientIPPreservation(service) {
+		case ClientIPProxied:
+			return ClientIPProxied, nil
+		case ClientIPNATed:
+			result = ClientIPNATed
+		}
+	}
+	return result, nil
+}
+
+func serviceClientIPPreservation(service *corev1.Service) ClientIPPreservation {
+	for annotation, enabled := range proxyProtocolAnnotations {
+		if value, ok := service.Annotations[annotation]; ok && (enabled == "" || strings.EqualFold(value, enabled)) {
+			return ClientIPProxied
+		}
+	}
+
+	// kube-proxy masquerades the connections forwarded to other nodes
+	if service.Spec.ExternalTrafficPolicy != corev1.ServiceExternalTrafficPolicyLocal {
+		return ClientIPNATed
+	}
+	return ClientIPPreserved
+}
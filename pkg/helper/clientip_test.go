@@ -0,0 +1,81 @@
+package helper
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("clientip", func() {
+	Describe("#DetectClientIPPreservation", func() {
+		var (
+			ctx         = context.Background()
+			istioLabels = map[string]string{"app": "istio-ingressgateway", "istio": "ingressgateway"}
+		)
+
+		newService := func(name string, annotations map[string]string, policy corev1.ServiceExternalTrafficPolicy) *corev1.Service {
+			return &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-ingress", Annotations: annotations},
+				Spec: corev1.ServiceSpec{
+					Type:                  corev1.ServiceTypeLoadBalancer,
+					Selector:              istioLabels,
+					ExternalTrafficPolicy: policy,
+				},
+			}
+		}
+
+		detect := func(labels map[string]string, objects ...client.Object) ClientIPPreservation {
+			c := fakeclient.NewClientBuilder().WithObjects(objects...).Build()
+			preservation, err := DetectClientIPPreservation(ctx, c, "istio-ingress", labels)
+			Expect(err).NotTo(HaveOccurred())
+			return preservation
+		}
+
+		It("should detect the PROXY protocol from the annotations", func() {
+			Expect(detect(istioLabels, newService("istio-ingressgateway",
+				map[string]string{"service.beta.kubernetes.io/aws-load-balancer-proxy-protocol": "*"},
+				corev1.ServiceExternalTrafficPolicyCluster,
+			))).To(Equal(ClientIPProxied))
+			Expect(detect(istioLabels, newService("istio-ingressgateway",
+				map[string]string{"loadbalancer.openstack.org/proxy-protocol": "true"},
+				corev1.ServiceExternalTrafficPolicyLocal,
+			))).To(Equal(ClientIPProxied))
+		})
+
+		It("should ignore disabled PROXY protocol annotations", func() {
+			Expect(detect(istioLabels, newService("istio-ingressgateway",
+				map[string]string{"loadbalancer.openstack.org/proxy-protocol": "false"},
+				corev1.ServiceExternalTrafficPolicyLocal,
+			))).To(Equal(ClientIPPreserved))
+		})
+
+		It("should detect NATed client IPs of the externalTrafficPolicy Cluster", func() {
+			Expect(detect(istioLabels, newService("istio-ingressgateway", nil, corev1.ServiceExternalTrafficPolicyCluster))).
+				To(Equal(ClientIPNATed))
+		})
+
+		It("should prefer the PROXY protocol over NATed client IPs", func() {
+			Expect(detect(nil,
+				newService("istio-ingressgateway", nil, corev1.ServiceExternalTrafficPolicyCluster),
+				newService("istio-ingressgateway-proxy",
+					map[string]string{"lb.stackit.cloud/tcp-proxy-protocol": "true"},
+					corev1.ServiceExternalTrafficPolicyCluster,
+				),
+			)).To(Equal(ClientIPProxied))
+		})
+
+		It("should ignore Services of other gateways and other types", func() {
+			otherGateway := newService("other-ingressgateway", nil, corev1.ServiceExternalTrafficPolicyCluster)
+			otherGateway.Spec.Selector = map[string]string{"app": "other-ingressgateway"}
+			clusterIP := newService("istiod", nil, corev1.ServiceExternalTrafficPolicyCluster)
+			clusterIP.Spec.Type = corev1.ServiceTypeClusterIP
+
+			Expect(detect(istioLabels, otherGateway, clusterIP)).To(Equal(ClientIPPreserved))
+		})
+	})
+})
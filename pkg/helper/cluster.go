@@ -29,3 +29,24 @@ func GetClusterForExtension(
 	}
 	return cluster, nil
 }
+
+// DecodeCluster decodes the Shoot, Seed and CloudProfile embedded in the given
+// Cluster object like GetClusterForExtension.
+func DecodeCluster(cluster *extensionsv1alpha1.Cluster) (*controller.Cluster, error) {
+	cloudProfile, err := controller.CloudProfileFromCluster(cluster)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := controller.SeedFromCluster(cluster)
+	if err != nil {
+		return nil, err
+	}
+	shoot, err := controller.ShootFromCluster(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if seed == nil || shoot == nil {
+		return nil, ErrClusterObjectNotComplete
+	}
+	return &controller.Cluster{ObjectMeta: cluster.ObjectMeta, CloudProfile: cloudProfile, Seed: seed, Shoot: shoot}, nil
+}
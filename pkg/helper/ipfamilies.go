@@ -0,0 +1,62 @@
+package helper
+
+import (
+	"net/netip"
+	"slices"
+
+	"github.com/gardener/gardener/pkg/apis/core/v1beta1"
+)
+
+// GetSeedIPFamilies returns the IP families of the seed's networks, IPv4 if
+// none are configured. The istio ingress gateways of the seed accept the
+// connections of the families, so the ACL has to cover all of them.
+func GetSeedIPFamilies(seed *v1beta1.Seed) []v1beta1.IPFamily {
+	return ipFamiliesOrDefault(seed.Spec.Networks.IPFamilies)
+}
+
+// GetShootIPFamilies returns the IP families of the shoot's networks, IPv4 if
+// none are configured.
+func GetShootIPFamilies(shoot *v1beta1.Shoot) []v1beta1.IPFamily {
+	if shoot.Spec.Networking == nil {
+		return ipFamiliesOrDefault(nil)
+	}
+	return ipFamiliesOrDefault(shoot.Spec.Networking.IPFamilies)
+}
+
+// IsDualStack returns true if the IP families contain both IPv4 and IPv6.
+func IsDualStack(families []v1beta1.IPFamily) bool {
+	return slices.Contains(families, v1beta1.IPFamilyIPv4) && slices.Contains(families, v1beta1.IPFamilyIPv6)
+}
+
+// MissingIPFamilies returns the IP families none of the CIDRs belongs to, in
+// the order of the given families. IPv4-mapped IPv6 CIDRs belong to IPv4,
+// invalid CIDRs are skipped.
+func MissingIPFamilies(cidrs []string, families []v1beta1.IPFamily) []v1beta1.IPFamily {
+	present := map[v1beta1.IPFamily]bool{}
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		if prefix.Addr().Unmap().Is4() {
+			present[v1beta1.IPFamilyIPv4] = true
+		} else {
+			present[v1beta1.IPFamilyIPv6] = true
+		}
+	}
+
+	var missing []v1beta1.IPFamily
+	for _, family := range families {
+		if !present[family] {
+			missing = append(missing, family)
+		}
+	}
+	return missing
+}
+
+func ipFamiliesOrDefault(families []v1beta1.IPFamily) []v1beta1.IPFamily {
+	if len(families) == 0 {
+		return []v1beta1.IPFamily{v1beta1.IPFamilyIPv4}
+	}
+	return families
+}
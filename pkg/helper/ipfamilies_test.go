@@ -0,0 +1,54 @@
+package helper
+
+import (
+	"github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ipfamilies", func() {
+	Describe("#GetSeedIPFamilies", func() {
+		It("should default to IPv4", func() {
+			Expect(GetSeedIPFamilies(&v1beta1.Seed{})).To(Equal([]v1beta1.IPFamily{v1beta1.IPFamilyIPv4}))
+		})
+
+		It("should return the families of the seed", func() {
+			seed := &v1beta1.Seed{}
+			seed.Spec.Networks.IPFamilies = []v1beta1.IPFamily{v1beta1.IPFamilyIPv6, v1beta1.IPFamilyIPv4}
+			Expect(GetSeedIPFamilies(seed)).To(Equal([]v1beta1.IPFamily{v1beta1.IPFamilyIPv6, v1beta1.IPFamilyIPv4}))
+			Expect(IsDualStack(GetSeedIPFamilies(seed))).To(BeTrue())
+		})
+	})
+
+	Describe("#GetShootIPFamilies", func() {
+		It("should default to IPv4 for shoots without networking", func() {
+			Expect(GetShootIPFamilies(&v1beta1.Shoot{})).To(Equal([]v1beta1.IPFamily{v1beta1.IPFamilyIPv4}))
+		})
+
+		It("should return the families of the shoot", func() {
+			shoot := &v1beta1.Shoot{Spec: v1beta1.ShootSpec{Networking: &v1beta1.Networking{
+				IPFamilies: []v1beta1.IPFamily{v1beta1.IPFamilyIPv6},
+			}}}
+			Expect(GetShootIPFamilies(shoot)).To(Equal([]v1beta1.IPFamily{v1beta1.IPFamilyIPv6}))
+			Expect(IsDualStack(GetShootIPFamilies(shoot))).To(BeFalse())
+		})
+	})
+
+	Describe("#MissingIPFamilies", func() {
+		dualStack := []v1beta1.IPFamily{v1beta1.IPFamilyIPv4, v1beta1.IPFamilyIPv6}
+
+		It("should return nothing if all families are covered", func() {
+			Expect(MissingIPFamilies([]string{"10.0.0.0/8", "2001:db8::/32"}, dualStack)).To(BeEmpty())
+		})
+
+		It("should return the families without CIDRs", func() {
+			Expect(MissingIPFamilies([]string{"10.0.0.0/8"}, dualStack)).To(Equal([]v1beta1.IPFamily{v1beta1.IPFamilyIPv6}))
+			Expect(MissingIPFamilies([]string{"2001:db8::/32", "invalid"}, dualStack)).To(Equal([]v1beta1.IPFamily{v1beta1.IPFamilyIPv4}))
+			Expect(MissingIPFamilies(nil, dualStack)).To(Equal(dualStack))
+		})
+
+		It("should count IPv4-mapped IPv6 CIDRs as IPv4", func() {
+			Expect(MissingIPFamilies([]string{"::ffff:10.0.0.0/104"}, dualStack)).To(Equal([]v1beta1.IPFamily{v1beta1.IPFamilyIPv6}))
+		})
+	})
+})
@@ -0,0 +1,43 @@
+package helper
+
+import (
+	"net/http"
+
+	istionetworkv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	istionetworkv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// istioKinds are the istio resources used by the extension. EnvoyFilters are
+// written in any API version served by istio, so their version is empty.
+var istioKinds = []schema.GroupVersionKind{
+	{Group: istionetworkv1alpha3.SchemeGroupVersion.Group, Kind: "EnvoyFilter"},
+	istionetworkv1beta1.SchemeGroupVersion.WithKind("Gateway"),
+}
+
+// IsIstioInstalled returns true if the CRDs of all istio resources used by
+// the extension are installed. The mapper is expected to discover resources
+// installed later on, like the dynamic RESTMapper of controller-runtime.
+func IsIstioInstalled(mapper meta.RESTMapper) (bool, error) {
+	for _, gvk := range istioKinds {
+		if _, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+			if meta.IsNoMatchError(err) {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// IstioDiscoveryChecker returns a readiness check failing while the mapper
+// fails to discover the istio resources. Seeds without istio are ready, as
+// the reconciliations of their shoots are retried until istio is installed.
+func IstioDiscoveryChecker(mapper meta.RESTMapper) healthz.Checker {
+	return func(_ *http.Request) error {
+		_, err := IsIstioInstalled(mapper)
+		return err
+	}
+}
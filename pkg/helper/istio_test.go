@@ -0,0 +1,67 @@
+package helper
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	istionetworkv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	istionetworkv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("istio", func() {
+	Describe("#IsIstioInstalled", func() {
+		var mapper *meta.DefaultRESTMapper
+
+		BeforeEach(func() {
+			mapper = meta.NewDefaultRESTMapper([]schema.GroupVersion{
+				istionetworkv1alpha3.SchemeGroupVersion, istionetworkv1beta1.SchemeGroupVersion,
+			})
+		})
+
+		It("should return true if all istio resources are known", func() {
+			mapper.Add(istionetworkv1alpha3.SchemeGroupVersion.WithKind("EnvoyFilter"), meta.RESTScopeNamespace)
+			mapper.Add(istionetworkv1beta1.SchemeGroupVersion.WithKind("Gateway"), meta.RESTScopeNamespace)
+
+			Expect(IsIstioInstalled(mapper)).To(BeTrue())
+		})
+
+		It("should return true if the EnvoyFilters are served in another API version", func() {
+			mapper = meta.NewDefaultRESTMapper([]schema.GroupVersion{
+				{Group: "networking.istio.io", Version: "v1"}, istionetworkv1beta1.SchemeGroupVersion,
+			})
+			mapper.Add(schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1", Kind: "EnvoyFilter"}, meta.RESTScopeNamespace)
+			mapper.Add(istionetworkv1beta1.SchemeGroupVersion.WithKind("Gateway"), meta.RESTScopeNamespace)
+
+			Expect(IsIstioInstalled(mapper)).To(BeTrue())
+		})
+
+		It("should return false if an istio resource is unknown", func() {
+			mapper.Add(istionetworkv1alpha3.SchemeGroupVersion.WithKind("EnvoyFilter"), meta.RESTScopeNamespace)
+
+			Expect(IsIstioInstalled(mapper)).To(BeFalse())
+		})
+	})
+
+	Describe("#IstioDiscoveryChecker", func() {
+		It("should succeed if istio isn't installed", func() {
+			mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{istionetworkv1beta1.SchemeGroupVersion})
+
+			Expect(IstioDiscoveryChecker(mapper)(nil)).To(Succeed())
+		})
+
+		It("should fail if the discovery fails", func() {
+			Expect(IstioDiscoveryChecker(failingRESTMapper{})(nil)).To(MatchError(ContainSubstring("discovery failed")))
+		})
+	})
+})
+
+type failingRESTMapper struct {
+	meta.RESTMapper
+}
+
+func (failingRESTMapper) RESTMapping(schema.GroupKind, ...string) (*meta.RESTMapping, error) {
+	return nil, errors.New("discovery failed")
+}
@@ -0,0 +1,50 @@
+package helper
+
+import (
+	"context"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// istioProxyContainerName is the name of the Envoy container of the istio
+// ingress gateways.
+const istioProxyContainerName = "istio-proxy"
+
+// DetectIstioVersions returns the image tags of the Envoy containers of the
+// istio ingress gateway Deployments in the namespace selected by the given
+// istio labels, e.g. "1.21.1-distroless". There can be more than one version
+// while the gateways are being upgraded. An empty tag is returned for
+// Deployments whose version can't be determined, e.g. because the image is
+// only referenced by its digest.
+func DetectIstioVersions(
+	ctx context.Context, c client.Client, namespace string, istioLabels map[string]string,
+) ([]string, error) {
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments, client.InNamespace(namespace), client.MatchingLabels(istioLabels)); err != nil {
+		return nil, err
+	}
+
+	versions := sets.New[string]()
+	for _, deployment := range deployments.Items {
+		tag := ""
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			if container.Name == istioProxyContainerName {
+				tag = imageTag(container.Image)
+				break
+			}
+		}
+		versions.Insert(tag)
+	}
+	return sets.List(versions), nil
+}
+
+// imageTag returns the tag of the image reference, without the digest.
+func imageTag(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	name := image[strings.LastIndex(image, "/")+1:]
+	_, tag, _ := strings.Cut(name, ":")
+	return tag
+}
@@ -0,0 +1,70 @@
+package helper
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("istioversion", func() {
+	Describe("#DetectIstioVersions", func() {
+		var (
+			ctx         = context.Background()
+			istioLabels = map[string]string{"app": "istio-ingressgateway", "istio": "ingressgateway"}
+		)
+
+		newDeployment := func(name string, labels map[string]string, image string) *appsv1.Deployment {
+			return &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-ingress", Labels: labels},
+				Spec: appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "istio-proxy", Image: image},
+							},
+						},
+					},
+				},
+			}
+		}
+
+		detect := func(objects ...client.Object) []string {
+			c := fakeclient.NewClientBuilder().WithObjects(objects...).Build()
+			versions, err := DetectIstioVersions(ctx, c, "istio-ingress", istioLabels)
+			Expect(err).NotTo(HaveOccurred())
+			return versions
+		}
+
+		It("should return the image tag of the istio-proxy container", func() {
+			Expect(detect(
+				newDeployment("istio-ingressgateway", istioLabels, "registry.example.com:5000/istio/proxyv2:1.21.1-distroless"),
+			)).To(ConsistOf("1.21.1-distroless"))
+		})
+
+		It("should ignore the digest of the image", func() {
+			Expect(detect(
+				newDeployment("istio-ingressgateway", istioLabels, "istio/proxyv2:1.21.1@sha256:0123456789abcdef"),
+			)).To(ConsistOf("1.21.1"))
+		})
+
+		It("should return an empty version if the image has no tag", func() {
+			Expect(detect(
+				newDeployment("istio-ingressgateway", istioLabels, "istio/proxyv2@sha256:0123456789abcdef"),
+			)).To(ConsistOf(""))
+		})
+
+		It("should return all versions of the selected Deployments", func() {
+			Expect(detect(
+				newDeployment("istio-ingressgateway", istioLabels, "istio/proxyv2:1.21.1"),
+				newDeployment("istio-ingressgateway-zone-a", istioLabels, "istio/proxyv2:1.20.3"),
+				newDeployment("other", map[string]string{"app": "other"}, "istio/proxyv2:1.19.0"),
+			)).To(ConsistOf("1.20.3", "1.21.1"))
+		})
+	})
+})
@@ -0,0 +1,34 @@
+package helper
+
+import (
+	"regexp"
+)
+
+// NamespacePatterns are regular expressions matching the names of namespaces
+// which are never touched by the extension, e.g. the ones of test gateways or
+// canary istio installations of the operators.
+type NamespacePatterns []*regexp.Regexp
+
+// CompileNamespacePatterns compiles the given regular expressions, which have
+// to match the whole name of a namespace.
+func CompileNamespacePatterns(patterns []string) (NamespacePatterns, error) {
+	compiled := make(NamespacePatterns, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Matches returns whether one of the patterns matches the given namespace.
+func (p NamespacePatterns) Matches(namespace string) bool {
+	for _, re := range p {
+		if re.MatchString(namespace) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package helper
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NamespacePatterns", func() {
+	It("should match the whole name of the namespaces", func() {
+		patterns, err := CompileNamespacePatterns([]string{"istio-canary-.*", "test"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(patterns.Matches("istio-canary-1-22")).To(BeTrue())
+		Expect(patterns.Matches("test")).To(BeTrue())
+		Expect(patterns.Matches("istio-ingress")).To(BeFalse())
+		Expect(patterns.Matches("istio-ingress-test")).To(BeFalse())
+	})
+
+	It("should not match any namespace without patterns", func() {
+		Expect(NamespacePatterns(nil).Matches("istio-ingress")).To(BeFalse())
+	})
+
+	It("should reject invalid patterns", func() {
+		_, err := CompileNamespacePatterns([]string{"istio-(canary"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,70 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// AnnotationBaselineCIDRs is the annotation of a Project containing the
+	// comma separated baseline allowlist of its shoots.
+	AnnotationBaselineCIDRs = "acl.stackit.cloud/baseline-cidrs"
+	// AnnotationBaselineStrict is the annotation of a Project which only
+	// allows rules of its shoots narrowing the baseline allowlist if set to
+	// "true".
+	AnnotationBaselineStrict = "acl.stackit.cloud/baseline-strict"
+)
+
+// ProjectBaseline is the baseline allowlist of the shoots of a project.
+type ProjectBaseline struct {
+	// Project is the name of the project.
+	Project string
+	// CIDRs are the CIDRs of the baseline allowlist.
+	CIDRs []string
+	// Strict only allows rules narrowing the baseline allowlist.
+	Strict bool
+}
+
+// GetProjectBaseline returns the baseline allowlist of the project of the
+// given namespace of the garden, or nil if the namespace doesn't belong to a
+// project or the project has no baseline allowlist.
+func GetProjectBaseline(ctx context.Context, c client.Reader, namespace string) (*ProjectBaseline, error) {
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	projectName := ns.Labels[v1beta1constants.ProjectName]
+	if projectName == "" {
+		return nil, nil
+	}
+	project := &gardencorev1beta1.Project{}
+	if err := c.Get(ctx, client.ObjectKey{Name: projectName}, project); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	baseline := &ProjectBaseline{
+		Project: projectName,
+		Strict:  project.Annotations[AnnotationBaselineStrict] == "true",
+	}
+	for _, cidr := range strings.Split(project.Annotations[AnnotationBaselineCIDRs], ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return nil, fmt.Errorf("annotation %s of project %s: %w", AnnotationBaselineCIDRs, projectName, err)
+		}
+		baseline.CIDRs = append(baseline.CIDRs, cidr)
+	}
+	if len(baseline.CIDRs) == 0 {
+		return nil, nil
+	}
+	return baseline, nil
+}
@@ -0,0 +1,68 @@
+package helper
+
+import (
+	"context"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("project", func() {
+	Describe("#GetProjectBaseline", func() {
+		var (
+			ctx     = context.Background()
+			scheme  *runtime.Scheme
+			project *gardencorev1beta1.Project
+		)
+
+		BeforeEach(func() {
+			scheme = runtime.NewScheme()
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			Expect(gardencorev1beta1.AddToScheme(scheme)).To(Succeed())
+			project = &gardencorev1beta1.Project{ObjectMeta: metav1.ObjectMeta{Name: "dev"}}
+		})
+
+		newClient := func() client.Client {
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "garden-dev",
+				Labels: map[string]string{v1beta1constants.ProjectName: "dev"},
+			}}
+			return fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(namespace, project).Build()
+		}
+
+		It("should return the baseline allowlist of the project", func() {
+			project.Annotations = map[string]string{
+				AnnotationBaselineCIDRs:  "10.0.0.0/8, 2001:db8::/32,",
+				AnnotationBaselineStrict: "true",
+			}
+
+			Expect(GetProjectBaseline(ctx, newClient(), "garden-dev")).To(Equal(&ProjectBaseline{
+				Project: "dev",
+				CIDRs:   []string{"10.0.0.0/8", "2001:db8::/32"},
+				Strict:  true,
+			}))
+		})
+
+		It("should return nil if the project has no baseline allowlist", func() {
+			Expect(GetProjectBaseline(ctx, newClient(), "garden-dev")).To(BeNil())
+		})
+
+		It("should return nil for namespaces without project", func() {
+			Expect(GetProjectBaseline(ctx, newClient(), "garden")).To(BeNil())
+		})
+
+		It("should reject invalid CIDRs", func() {
+			project.Annotations = map[string]string{AnnotationBaselineCIDRs: "10.0.0.0/8,foo"}
+
+			_, err := GetProjectBaseline(ctx, newClient(), "garden-dev")
+			Expect(err).To(MatchError(ContainSubstring("annotation acl.stackit.cloud/baseline-cidrs of project dev")))
+		})
+	})
+})
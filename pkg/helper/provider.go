@@ -3,6 +3,7 @@ package helper
 import (
 	"encoding/json"
 	"errors"
+	"net/netip"
 
 	openstackv1alpha1 "github.com/gardener/gardener-extension-provider-openstack/pkg/apis/openstack/v1alpha1"
 	"github.com/gardener/gardener-extension-provider-openstack/pkg/openstack"
@@ -65,9 +66,14 @@ func getOpenstackProviderSpecificAllowedCIDRs(
 		return nil, err
 	}
 
-	router32CIDR := infraStatus.Networks.Router.IP + "/32"
+	// the router IP of IPv6 networks is allowed as /128, an invalid (e.g.
+	// still empty) router IP is skipped
+	routerIP, err := netip.ParseAddr(infraStatus.Networks.Router.IP)
+	if err != nil {
+		return cidrs, nil
+	}
 
-	cidrs = append(cidrs, router32CIDR)
+	cidrs = append(cidrs, netip.PrefixFrom(routerIP, routerIP.BitLen()).String())
 
 	return cidrs, nil
 }
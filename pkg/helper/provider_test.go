@@ -94,6 +94,35 @@ var _ = Describe("provider Unit Tests", func() {
 				allowedCIDRs = append(allowedCIDRs, providerIPs...)
 				Expect(allowedCIDRs).To(Equal([]string{"a", "b", "10.9.8.7/32"}))
 			})
+
+			It("Should add the IPv6 router IP as /128", func() {
+				infraStatusJSON, err := json.Marshal(&openstackv1alpha1.InfrastructureStatus{
+					Networks: openstackv1alpha1.NetworkStatus{
+						Router: openstackv1alpha1.RouterStatus{
+							ID: "router-id",
+							IP: "2001:db8::7",
+						},
+					},
+				})
+				Expect(err).To(BeNil())
+
+				infra := &extensionsv1alpha1.Infrastructure{
+					Spec: extensionsv1alpha1.InfrastructureSpec{
+						DefaultSpec: extensionsv1alpha1.DefaultSpec{
+							Type: openstack.Type,
+						},
+					},
+					Status: extensionsv1alpha1.InfrastructureStatus{
+						DefaultStatus: extensionsv1alpha1.DefaultStatus{
+							ProviderStatus: &runtime.RawExtension{
+								Raw: infraStatusJSON,
+							},
+						},
+					},
+				}
+
+				Expect(GetProviderSpecificAllowedCIDRs(infra)).To(Equal([]string{"2001:db8::7/128"}))
+			})
 		})
 	})
 })
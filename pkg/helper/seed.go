@@ -1,7 +1,13 @@
 package helper
 
 import (
+	"context"
+	"net"
+
 	"github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // GetSeedSpecificAllowedCIDRs returns the node and pod CIDRs from the seed
@@ -16,6 +22,44 @@ func GetSeedSpecificAllowedCIDRs(seed *v1beta1.Seed) []string {
 	return cidrs
 }
 
+// GetSeedNodeCIDRs returns the internal addresses of the nodes of the seed as
+// single host CIDRs, sorted and without duplicates. Components of the seed,
+// e.g. gardenlet or the prometheus of the shoot control plane, reach the
+// shoot's API server via the istio ingress gateway, which sees these
+// addresses as the source if the seed's node network doesn't cover them.
+// External addresses are not included, as they would allow every source
+// behind a node's public IP.
+func GetSeedNodeCIDRs(ctx context.Context, c client.Reader) ([]string, error) {
+	nodes := &corev1.NodeList{}
+	if err := c.List(ctx, nodes); err != nil {
+		return nil, err
+	}
+	return NodeCIDRs(nodes.Items), nil
+}
+
+// NodeCIDRs returns the internal addresses of the given nodes as single host
+// CIDRs, sorted and without duplicates.
+func NodeCIDRs(nodes []corev1.Node) []string {
+	cidrs := sets.New[string]()
+	for _, node := range nodes {
+		for _, address := range node.Status.Addresses {
+			if address.Type != corev1.NodeInternalIP {
+				continue
+			}
+			ip := net.ParseIP(address.Address)
+			if ip == nil {
+				continue
+			}
+			if ip.To4() != nil {
+				cidrs.Insert(ip.String() + "/32")
+			} else {
+				cidrs.Insert(ip.String() + "/128")
+			}
+		}
+	}
+	return sets.List(cidrs)
+}
+
 // GetSeedIngressDomain returns the ingress domain of the seed
 func GetSeedIngressDomain(seed *v1beta1.Seed) string {
 	domain := ""
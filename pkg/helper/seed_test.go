@@ -0,0 +1,51 @@
+package helper
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("seed", func() {
+	Describe("#GetSeedNodeCIDRs", func() {
+		newNode := func(name string, addresses ...corev1.NodeAddress) *corev1.Node {
+			return &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Status:     corev1.NodeStatus{Addresses: addresses},
+			}
+		}
+
+		It("should return the internal addresses of all nodes", func() {
+			c := fakeclient.NewClientBuilder().WithObjects(
+				newNode("node-b",
+					corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.250.0.2"},
+					corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "2001:db8::2"},
+					corev1.NodeAddress{Type: corev1.NodeHostName, Address: "node-b"},
+				),
+				newNode("node-a",
+					corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.250.0.1"},
+					corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "203.0.113.1"},
+					corev1.NodeAddress{Type: corev1.NodeInternalDNS, Address: "node-a.internal"},
+				),
+				newNode("node-c",
+					corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.250.0.1"},
+					corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "203.0.113.3"},
+				),
+			).Build()
+
+			cidrs, err := GetSeedNodeCIDRs(context.Background(), c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cidrs).To(Equal([]string{"10.250.0.1/32", "10.250.0.2/32", "2001:db8::2/128"}))
+		})
+
+		It("should return nothing without nodes", func() {
+			cidrs, err := GetSeedNodeCIDRs(context.Background(), fakeclient.NewClientBuilder().Build())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cidrs).To(BeEmpty())
+		})
+	})
+})
@@ -2,10 +2,14 @@ package helper
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"regexp"
+	"slices"
 
 	"github.com/gardener/gardener/pkg/apis/core/v1beta1"
 	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	gardenerutils "github.com/gardener/gardener/pkg/utils/gardener"
 )
 
 // GetShootNodeSpecificAllowedCIDRs returns the node CIDRs of the shoot
@@ -40,8 +44,88 @@ func GetShootPodSpecificAllowedCIDRs(shoot *v1beta1.Shoot) []string {
 // technicalIDPattern addresses the ambiguity that one or two dashes could follow the prefix "shoot" in the technical ID of the shoot.
 var technicalIDPattern = regexp.MustCompile(fmt.Sprintf("^%s-?", v1beta1constants.TechnicalIDPrefix))
 
+// GetShootPurpose returns the purpose of the shoot, or "evaluation", the
+// default purpose of shoots, if none is set.
+func GetShootPurpose(shoot *v1beta1.Shoot) string {
+	if shoot == nil || shoot.Spec.Purpose == nil {
+		return string(v1beta1.ShootPurposeEvaluation)
+	}
+	return string(*shoot.Spec.Purpose)
+}
+
 // ComputeShortShootID computes the host for a given prefix.
 func ComputeShortShootID(shoot *v1beta1.Shoot) string {
 	shortID := technicalIDPattern.ReplaceAllString(shoot.Status.TechnicalID, "")
 	return shortID
 }
+
+// GetAPIServerHosts returns the SNI hostnames of the shoot's API server from
+// its advertised addresses, the external one first, followed by the internal
+// one and any other (e.g. unmanaged or wildcard) address. All of them are
+// served by the same filter chain of the istio ingress gateway. The service
+// account issuer isn't served by the API server's filter chain, and IP
+// addresses can't be matched by SNI, so both are skipped.
+func GetAPIServerHosts(shoot *v1beta1.Shoot) []string {
+	var external, internal, others []string
+	for _, address := range shoot.Status.AdvertisedAddresses {
+		if address.Name == v1beta1constants.AdvertisedAddressServiceAccountIssuer {
+			continue
+		}
+		u, err := url.Parse(address.URL)
+		if err != nil || u.Hostname() == "" || net.ParseIP(u.Hostname()) != nil {
+			continue
+		}
+
+		switch address.Name {
+		case v1beta1constants.AdvertisedAddressExternal:
+			external = append(external, u.Hostname())
+		case v1beta1constants.AdvertisedAddressInternal:
+			internal = append(internal, u.Hostname())
+		default:
+			others = append(others, u.Hostname())
+		}
+	}
+
+	var hosts []string
+	for _, host := range append(append(external, internal...), others...) {
+		if !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// GetInternalAPIServerHosts returns the SNI hostnames of the internal
+// advertised addresses of the shoot's API server. Hostnames which are also
+// advertised under another name aren't internal-only and are skipped.
+func GetInternalAPIServerHosts(shoot *v1beta1.Shoot) []string {
+	var internal, others []string
+	for _, address := range shoot.Status.AdvertisedAddresses {
+		u, err := url.Parse(address.URL)
+		if err != nil || u.Hostname() == "" || net.ParseIP(u.Hostname()) != nil {
+			continue
+		}
+		if address.Name == v1beta1constants.AdvertisedAddressInternal {
+			internal = append(internal, u.Hostname())
+		} else {
+			others = append(others, u.Hostname())
+		}
+	}
+
+	var hosts []string
+	for _, host := range internal {
+		if !slices.Contains(others, host) && !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// GetShootIngressDomain returns the wildcard ingress domain of the shoot, i.e.
+// "ingress.<shoot domain>", or an empty string if the shoot has no domain.
+func GetShootIngressDomain(shoot *v1beta1.Shoot) string {
+	if shoot.Spec.DNS == nil || shoot.Spec.DNS.Domain == nil || *shoot.Spec.DNS.Domain == "" {
+		return ""
+	}
+	return gardenerutils.IngressPrefix + "." + *shoot.Spec.DNS.Domain
+}
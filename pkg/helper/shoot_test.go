@@ -6,6 +6,7 @@ import (
 	. "github.com/onsi/gomega"
 	gomegatypes "github.com/onsi/gomega/types"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
 var _ = Describe("helper", func() {
@@ -34,4 +35,78 @@ var _ = Describe("helper", func() {
 			"shoot--barProject--fooShoot",
 			Equal("barProject--fooShoot")),
 	)
+
+	Describe("#GetShootPurpose", func() {
+		It("should return the purpose of the shoot", func() {
+			shoot := &gardencorev1beta1.Shoot{Spec: gardencorev1beta1.ShootSpec{Purpose: ptr.To(gardencorev1beta1.ShootPurposeProduction)}}
+			Expect(GetShootPurpose(shoot)).To(Equal("production"))
+		})
+
+		It("should default to evaluation", func() {
+			Expect(GetShootPurpose(&gardencorev1beta1.Shoot{})).To(Equal("evaluation"))
+			Expect(GetShootPurpose(nil)).To(Equal("evaluation"))
+		})
+	})
+
+	Describe("#GetAPIServerHosts", func() {
+		shootWithAddresses := func(addresses ...gardencorev1beta1.ShootAdvertisedAddress) *gardencorev1beta1.Shoot {
+			return &gardencorev1beta1.Shoot{Status: gardencorev1beta1.ShootStatus{AdvertisedAddresses: addresses}}
+		}
+
+		It("should return the external host first, regardless of the order of the addresses", func() {
+			shoot := shootWithAddresses(
+				gardencorev1beta1.ShootAdvertisedAddress{Name: "service-account-issuer", URL: "https://discovery.example.com/projects/foo/shoots/uid/issuer"},
+				gardencorev1beta1.ShootAdvertisedAddress{Name: "internal", URL: "https://api.bar.foo.internal.example.com"},
+				gardencorev1beta1.ShootAdvertisedAddress{Name: "external", URL: "https://api.bar.foo.example.com"},
+			)
+			Expect(GetAPIServerHosts(shoot)).To(Equal([]string{"api.bar.foo.example.com", "api.bar.foo.internal.example.com"}))
+		})
+
+		It("should include other addresses and drop ports, IPs and duplicates", func() {
+			shoot := shootWithAddresses(
+				gardencorev1beta1.ShootAdvertisedAddress{Name: "unmanaged", URL: "https://api.bar.foo.example.com:443"},
+				gardencorev1beta1.ShootAdvertisedAddress{Name: "wildcard-tls-seed-bound", URL: "https://api-bar--foo.ingress.seed.example.com"},
+				gardencorev1beta1.ShootAdvertisedAddress{Name: "external", URL: "https://api.bar.foo.example.com"},
+				gardencorev1beta1.ShootAdvertisedAddress{Name: "ip", URL: "https://10.0.0.1"},
+			)
+			Expect(GetAPIServerHosts(shoot)).To(Equal([]string{"api.bar.foo.example.com", "api-bar--foo.ingress.seed.example.com"}))
+		})
+
+		It("should return nothing without advertised addresses", func() {
+			Expect(GetAPIServerHosts(shootWithAddresses())).To(BeEmpty())
+		})
+	})
+
+	Describe("#GetShootIngressDomain", func() {
+		It("should return the ingress domain of the shoot", func() {
+			shoot := &gardencorev1beta1.Shoot{Spec: gardencorev1beta1.ShootSpec{DNS: &gardencorev1beta1.DNS{Domain: ptr.To("bar.foo.example.com")}}}
+			Expect(GetShootIngressDomain(shoot)).To(Equal("ingress.bar.foo.example.com"))
+		})
+
+		It("should return nothing for shoots without domain", func() {
+			Expect(GetShootIngressDomain(&gardencorev1beta1.Shoot{})).To(BeEmpty())
+		})
+	})
+
+	Describe("#GetInternalAPIServerHosts", func() {
+		shootWithAddresses := func(addresses ...gardencorev1beta1.ShootAdvertisedAddress) *gardencorev1beta1.Shoot {
+			return &gardencorev1beta1.Shoot{Status: gardencorev1beta1.ShootStatus{AdvertisedAddresses: addresses}}
+		}
+
+		It("should only return the internal hosts", func() {
+			shoot := shootWithAddresses(
+				gardencorev1beta1.ShootAdvertisedAddress{Name: "external", URL: "https://api.bar.foo.example.com"},
+				gardencorev1beta1.ShootAdvertisedAddress{Name: "internal", URL: "https://api.bar.foo.internal.example.com:443"},
+			)
+			Expect(GetInternalAPIServerHosts(shoot)).To(Equal([]string{"api.bar.foo.internal.example.com"}))
+		})
+
+		It("should skip internal hosts which are also advertised under another name", func() {
+			shoot := shootWithAddresses(
+				gardencorev1beta1.ShootAdvertisedAddress{Name: "external", URL: "https://api.bar.foo.example.com"},
+				gardencorev1beta1.ShootAdvertisedAddress{Name: "internal", URL: "https://api.bar.foo.example.com"},
+			)
+			Expect(GetInternalAPIServerHosts(shoot)).To(BeEmpty())
+		})
+	})
 })
@@ -0,0 +1,39 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// DefaultShutdownDelay is the default time the extension keeps serving after
+// it was asked to terminate.
+const DefaultShutdownDelay = 5 * time.Second
+
+// DelayedShutdown returns a context which is canceled the given delay after
+// ctx, and a readiness check failing as soon as ctx is canceled. During the
+// delay, the pod is taken out of the endpoints of the webhook Service, while
+// the webhook server still handles the admission requests the API server sent
+// before. Once the returned context is canceled, the manager stops and waits
+// for the in-flight requests, so rolling updates don't drop any of them.
+func DelayedShutdown(ctx context.Context, delay time.Duration, clock clock.Clock) (context.Context, healthz.Checker) {
+	shutdownCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	go func() {
+		<-ctx.Done()
+		if delay > 0 {
+			<-clock.After(delay)
+		}
+		cancel()
+	}()
+
+	return shutdownCtx, func(_ *http.Request) error {
+		if ctx.Err() != nil {
+			return errors.New("shutting down")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,37 @@
+package helper
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	testclock "k8s.io/utils/clock/testing"
+)
+
+var _ = Describe("#DelayedShutdown", func() {
+	It("should report the shutdown immediately and cancel the context after the delay", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		fakeClock := testclock.NewFakeClock(time.Now())
+
+		shutdownCtx, check := DelayedShutdown(ctx, 5*time.Second, fakeClock)
+		Expect(check(nil)).To(Succeed())
+
+		cancel()
+		Expect(check(nil)).To(MatchError("shutting down"))
+		Eventually(fakeClock.HasWaiters).Should(BeTrue())
+		Expect(shutdownCtx.Err()).NotTo(HaveOccurred())
+
+		fakeClock.Step(5 * time.Second)
+		Eventually(shutdownCtx.Done()).Should(BeClosed())
+	})
+
+	It("should cancel the context immediately without a delay", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		shutdownCtx, _ := DelayedShutdown(ctx, 0, testclock.NewFakeClock(time.Now()))
+		cancel()
+
+		Eventually(shutdownCtx.Done()).Should(BeClosed())
+	})
+})
@@ -0,0 +1,75 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/gardener/gardener/pkg/utils/kubernetes/health"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+
+	upstreamcrds "github.com/stackitcloud/gardener-extension-acl/upstream-crds"
+)
+
+// InstallCRDs creates or updates the CRDs of gardener the extension depends on
+// and waits until they are established. The client's scheme has to contain
+// the apiextensions.k8s.io/v1 API group.
+func InstallCRDs(ctx context.Context, c client.Client, timeout time.Duration) error {
+	crds, err := GardenerCRDs()
+	if err != nil {
+		return err
+	}
+
+	for _, desired := range crds {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		crd.Name = desired.Name
+		if _, err := controllerutil.CreateOrUpdate(ctx, c, crd, func() error {
+			crd.Labels = desired.Labels
+			crd.Annotations = desired.Annotations
+			crd.Spec = desired.Spec
+			return nil
+		}); err != nil {
+			return fmt.Errorf("could not apply CRD %s: %w", desired.Name, err)
+		}
+	}
+
+	for _, desired := range crds {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := wait.PollUntilContextTimeout(ctx, time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+			if err := c.Get(ctx, client.ObjectKey{Name: desired.Name}, crd); err != nil {
+				return false, err
+			}
+			return health.CheckCustomResourceDefinition(crd) == nil, nil
+		}); err != nil {
+			return fmt.Errorf("CRD %s is not established: %w", desired.Name, err)
+		}
+	}
+	return nil
+}
+
+// GardenerCRDs returns the CRDs of gardener the extension depends on.
+func GardenerCRDs() ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	files, err := fs.Glob(upstreamcrds.Gardener, "*.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var crds []*apiextensionsv1.CustomResourceDefinition
+	for _, file := range files {
+		data, err := fs.ReadFile(upstreamcrds.Gardener, file)
+		if err != nil {
+			return nil, err
+		}
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := yaml.Unmarshal(data, crd); err != nil {
+			return nil, fmt.Errorf("could not decode %s: %w", file, err)
+		}
+		crds = append(crds, crd)
+	}
+	return crds, nil
+}
@@ -0,0 +1,252 @@
+// Package local provides what is needed to run the extension against a kind
+// cluster with istio instead of a seed of a Gardener landscape: the CRDs of
+// gardener the extension watches, and the objects gardenlet would create for a
+// shoot, i.e. its namespace, the Cluster, the istio Gateway of its API server,
+// the EnvoyFilter of its internal flow and the Extension.
+package local
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/webhook"
+)
+
+// Error variables for the local pkg
+var (
+	ErrNoShootName   = errors.New("the name and project of the shoot are required")
+	ErrNoIstioLabels = errors.New("the labels of the istio ingress gateway are required")
+)
+
+// Options contains the information about the fake shoot and the kind cluster
+// serving as its seed.
+type Options struct {
+	// ShootName and ProjectName make up the technical ID of the shoot, i.e.
+	// "shoot--<project>--<name>".
+	ShootName   string
+	ProjectName string
+	// APIServerHost is the host (SNI) of the shoot's API server, as in its
+	// advertised addresses.
+	APIServerHost string
+	// ProviderConfig is the providerConfig of the ACL extension in YAML or
+	// JSON. An "ALLOW" rule for the AllowedCIDRs is used if it is empty.
+	ProviderConfig []byte
+	// AllowedCIDRs are the CIDRs of the default rule.
+	AllowedCIDRs []string
+	// IstioNamespace is the namespace of the istio ingress gateway.
+	IstioNamespace string
+	// IstioLabels are the labels of the istio ingress gateway.
+	IstioLabels map[string]string
+	// SeedNodesCIDR and SeedPodsCIDR are the networks of the kind cluster.
+	SeedNodesCIDR string
+	SeedPodsCIDR  string
+}
+
+// DefaultOptions returns the Options for the default installation of istio in
+// a kind cluster.
+func DefaultOptions() Options {
+	return Options{
+		ShootName:      "local",
+		ProjectName:    "dev",
+		APIServerHost:  "api.local.dev.internal.local.gardener.cloud",
+		AllowedCIDRs:   []string{"172.16.0.0/12"},
+		IstioNamespace: "istio-system",
+		IstioLabels:    map[string]string{"app": "istio-ingressgateway", "istio": "ingressgateway"},
+		SeedNodesCIDR:  "172.18.0.0/16",
+		SeedPodsCIDR:   "10.244.0.0/16",
+	}
+}
+
+// TechnicalID returns the technical ID of the shoot, i.e. the name of its
+// namespace in the seed.
+func (o Options) TechnicalID() string {
+	return fmt.Sprintf("%s-%s--%s", v1beta1constants.TechnicalIDPrefix, o.ProjectName, o.ShootName)
+}
+
+// FakeShoot returns the objects gardenlet would create in the seed for a
+// workerless shoot with the ACL extension. The Gateway and the EnvoyFilter
+// select the istio ingress gateway, which is expected to exist already.
+func FakeShoot(opts Options) ([]client.Object, error) {
+	if opts.ShootName == "" || opts.ProjectName == "" {
+		return nil, ErrNoShootName
+	}
+	if len(opts.IstioLabels) == 0 {
+		return nil, ErrNoIstioLabels
+	}
+
+	providerConfig, err := providerConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	technicalID := opts.TechnicalID()
+
+	namespace := &corev1.Namespace{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   technicalID,
+			Labels: map[string]string{v1beta1constants.GardenRole: v1beta1constants.GardenRoleShoot},
+		},
+	}
+
+	cluster := &extensionsv1alpha1.Cluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: extensionsv1alpha1.SchemeGroupVersion.String(), Kind: extensionsv1alpha1.ClusterResource},
+		ObjectMeta: metav1.ObjectMeta{Name: technicalID},
+		Spec: extensionsv1alpha1.ClusterSpec{
+			CloudProfile: runtime.RawExtension{Object: &gardencorev1beta1.CloudProfile{
+				TypeMeta:   metav1.TypeMeta{APIVersion: gardencorev1beta1.SchemeGroupVersion.String(), Kind: "CloudProfile"},
+				ObjectMeta: metav1.ObjectMeta{Name: "local"},
+			}},
+			Seed: runtime.RawExtension{Object: &gardencorev1beta1.Seed{
+				TypeMeta:   metav1.TypeMeta{APIVersion: gardencorev1beta1.SchemeGroupVersion.String(), Kind: "Seed"},
+				ObjectMeta: metav1.ObjectMeta{Name: "local"},
+				Spec: gardencorev1beta1.SeedSpec{
+					Networks: gardencorev1beta1.SeedNetworks{
+						Nodes: ptr.To(opts.SeedNodesCIDR),
+						Pods:  opts.SeedPodsCIDR,
+					},
+				},
+			}},
+			// a workerless shoot, so there is no Infrastructure
+			Shoot: runtime.RawExtension{Object: &gardencorev1beta1.Shoot{
+				TypeMeta:   metav1.TypeMeta{APIVersion: gardencorev1beta1.SchemeGroupVersion.String(), Kind: "Shoot"},
+				ObjectMeta: metav1.ObjectMeta{Name: opts.ShootName, Namespace: "garden-" + opts.ProjectName},
+				Spec: gardencorev1beta1.ShootSpec{
+					Extensions: []gardencorev1beta1.Extension{{Type: controller.Type}},
+				},
+				Status: gardencorev1beta1.ShootStatus{
+					TechnicalID: technicalID,
+					AdvertisedAddresses: []gardencorev1beta1.ShootAdvertisedAddress{{
+						Name: v1beta1constants.AdvertisedAddressExternal,
+						URL:  "https://" + opts.APIServerHost,
+					}},
+				},
+			}},
+		},
+	}
+
+	gateway := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.istio.io/v1beta1",
+		"kind":       "Gateway",
+		"metadata": map[string]interface{}{
+			"name":      "kube-apiserver",
+			"namespace": technicalID,
+		},
+		"spec": map[string]interface{}{
+			"selector": stringMap(opts.IstioLabels),
+			"servers": []interface{}{map[string]interface{}{
+				"hosts": []interface{}{opts.APIServerHost},
+				"port":  map[string]interface{}{"name": "tls", "number": int64(443), "protocol": "TLS"},
+				"tls":   map[string]interface{}{"mode": "PASSTHROUGH"},
+			}},
+		},
+	}}
+
+	// the EnvoyFilter of the internal flow of the API server, which is
+	// mutated by the webhook
+	apiServerCluster := fmt.Sprintf("outbound|443||kube-apiserver.%s.svc.cluster.local", technicalID)
+	envoyFilter := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.istio.io/v1alpha3",
+		"kind":       "EnvoyFilter",
+		"metadata": map[string]interface{}{
+			"name":      technicalID,
+			"namespace": opts.IstioNamespace,
+			"labels":    map[string]interface{}{controller.WebhookLabel: "true"},
+		},
+		"spec": map[string]interface{}{
+			"workloadSelector": map[string]interface{}{"labels": stringMap(opts.IstioLabels)},
+			"configPatches": []interface{}{map[string]interface{}{
+				"applyTo": "FILTER_CHAIN",
+				"match": map[string]interface{}{
+					"context":  "ANY",
+					"listener": map[string]interface{}{"name": "0.0.0.0_8443", "portNumber": int64(8443)},
+				},
+				"patch": map[string]interface{}{
+					"operation": "ADD",
+					"value": map[string]interface{}{
+						"filter_chain_match": map[string]interface{}{"destination_port": int64(443)},
+						"filters": []interface{}{map[string]interface{}{
+							"name": "envoy.filters.network.tcp_proxy",
+							"typed_config": map[string]interface{}{
+								"@type":       "type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy",
+								"cluster":     apiServerCluster,
+								"stat_prefix": apiServerCluster,
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}}
+
+	extension := &extensionsv1alpha1.Extension{
+		TypeMeta: metav1.TypeMeta{APIVersion: extensionsv1alpha1.SchemeGroupVersion.String(), Kind: extensionsv1alpha1.ExtensionResource},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      webhook.ExtensionName,
+			Namespace: technicalID,
+		},
+		Spec: extensionsv1alpha1.ExtensionSpec{
+			DefaultSpec: extensionsv1alpha1.DefaultSpec{
+				Type:           controller.Type,
+				ProviderConfig: &runtime.RawExtension{Raw: providerConfig},
+			},
+		},
+	}
+
+	return []client.Object{namespace, cluster, gateway, envoyFilter, extension}, nil
+}
+
+// Manifests returns the YAML manifests of the objects, e.g. for kubectl apply.
+func Manifests(objects []client.Object) ([]byte, error) {
+	var manifests []byte
+	for _, obj := range objects {
+		manifest, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal %T %s: %w", obj, client.ObjectKeyFromObject(obj), err)
+		}
+		manifests = append(manifests, "---\n"...)
+		manifests = append(manifests, manifest...)
+	}
+	return manifests, nil
+}
+
+func providerConfig(opts Options) ([]byte, error) {
+	if len(opts.ProviderConfig) > 0 {
+		raw, err := yaml.YAMLToJSON(opts.ProviderConfig)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode providerConfig: %w", err)
+		}
+		return raw, nil
+	}
+
+	return json.Marshal(extensionspec.ExtensionSpec{
+		Rule: &envoyfilters.ACLRule{
+			Action: "ALLOW",
+			Type:   envoyfilters.TypeRemoteIP,
+			Cidrs:  slices.Clone(opts.AllowedCIDRs),
+		},
+	})
+}
+
+func stringMap(labels map[string]string) map[string]interface{} {
+	m := make(map[string]interface{}, len(labels))
+	for key, value := range labels {
+		m[key] = value
+	}
+	return m
+}
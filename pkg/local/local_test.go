@@ -0,0 +1,125 @@
+package local
+
+import (
+	"encoding/json"
+	"strings"
+
+	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+var _ = Describe("FakeShoot", func() {
+	var opts Options
+
+	BeforeEach(func() {
+		opts = DefaultOptions()
+	})
+
+	It("should return the objects gardenlet would create for the shoot", func() {
+		objects, err := FakeShoot(opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		var keys []string
+		for _, obj := range objects {
+			keys = append(keys, obj.GetObjectKind().GroupVersionKind().Kind+" "+obj.GetNamespace()+"/"+obj.GetName())
+		}
+		Expect(keys).To(Equal([]string{
+			"Namespace /shoot--dev--local",
+			"Cluster /shoot--dev--local",
+			"Gateway shoot--dev--local/kube-apiserver",
+			"EnvoyFilter istio-system/shoot--dev--local",
+			"Extension shoot--dev--local/acl",
+		}))
+	})
+
+	It("should describe a workerless shoot the extension can reconcile", func() {
+		objects, err := FakeShoot(opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		// the Cluster is read from the API server, i.e. decoded from JSON
+		raw, err := json.Marshal(objects[1])
+		Expect(err).NotTo(HaveOccurred())
+		cluster := &extensionsv1alpha1.Cluster{}
+		Expect(json.Unmarshal(raw, cluster)).To(Succeed())
+		decoded, err := helper.DecodeCluster(cluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(extensionscontroller.IsHibernated(decoded)).To(BeFalse())
+		Expect(decoded.Shoot.Spec.Provider.Workers).To(BeEmpty())
+		Expect(helper.GetAPIServerHosts(decoded.Shoot)).To(Equal([]string{opts.APIServerHost}))
+		Expect(helper.GetSeedSpecificAllowedCIDRs(decoded.Seed)).To(Equal([]string{"172.18.0.0/16", "10.244.0.0/16"}))
+
+		extension := objects[4].(*extensionsv1alpha1.Extension)
+		spec := &extensionspec.ExtensionSpec{}
+		Expect(json.Unmarshal(extension.Spec.ProviderConfig.Raw, spec)).To(Succeed())
+		Expect(controller.ValidateExtensionSpec(spec)).To(Succeed())
+		Expect(spec.Rule.Cidrs).To(Equal([]string{"172.16.0.0/12"}))
+	})
+
+	It("should use the given providerConfig", func() {
+		opts.ProviderConfig = []byte(`
+rule:
+  action: DENY
+  type: remote_ip
+  cidrs:
+  - 1.2.3.4/32
+`)
+		objects, err := FakeShoot(opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		extension := objects[4].(*extensionsv1alpha1.Extension)
+		Expect(string(extension.Spec.ProviderConfig.Raw)).To(MatchJSON(`{"rule":{"action":"DENY","type":"remote_ip","cidrs":["1.2.3.4/32"]}}`))
+	})
+
+	It("should require the name of the shoot and the istio labels", func() {
+		opts.ShootName = ""
+		_, err := FakeShoot(opts)
+		Expect(err).To(MatchError(ErrNoShootName))
+
+		opts = DefaultOptions()
+		opts.IstioLabels = nil
+		_, err = FakeShoot(opts)
+		Expect(err).To(MatchError(ErrNoIstioLabels))
+	})
+
+	It("should return the manifests of the objects", func() {
+		objects, err := FakeShoot(opts)
+		Expect(err).NotTo(HaveOccurred())
+		manifests, err := Manifests(objects)
+		Expect(err).NotTo(HaveOccurred())
+
+		documents := strings.Split(strings.TrimPrefix(string(manifests), "---\n"), "---\n")
+		Expect(documents).To(HaveLen(len(objects)))
+		for i, document := range documents {
+			obj := map[string]interface{}{}
+			Expect(yaml.Unmarshal([]byte(document), &obj)).To(Succeed())
+			Expect(obj).To(HaveKeyWithValue("kind", objects[i].GetObjectKind().GroupVersionKind().Kind))
+		}
+		Expect(documents[3]).To(ContainSubstring("outbound|443||kube-apiserver.shoot--dev--local.svc.cluster.local"))
+	})
+})
+
+var _ = Describe("GardenerCRDs", func() {
+	It("should return the CRDs the extension depends on", func() {
+		crds, err := GardenerCRDs()
+		Expect(err).NotTo(HaveOccurred())
+
+		var names []string
+		for _, crd := range crds {
+			names = append(names, crd.Name)
+		}
+		Expect(names).To(ConsistOf(
+			"bastions.extensions.gardener.cloud",
+			"clusters.extensions.gardener.cloud",
+			"extensions.extensions.gardener.cloud",
+			"infrastructures.extensions.gardener.cloud",
+			"managedresources.resources.gardener.cloud",
+		))
+	})
+})
@@ -0,0 +1,38 @@
+package migration
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// ResultMigrated is the result label of migrated extensions.
+	ResultMigrated = "migrated"
+	// ResultFailed is the result label of extensions which weren't migrated
+	// within the batch timeout.
+	ResultFailed = "failed"
+)
+
+var (
+	pendingExtensions = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "acl",
+			Subsystem: "migration",
+			Name:      "pending_extensions",
+			Help:      "Number of extensions with objects of an older extension version which are waiting for their migration.",
+		},
+	)
+	migratedExtensions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "acl",
+			Subsystem: "migration",
+			Name:      "extensions_total",
+			Help:      "Number of extensions with objects of an older extension version processed by the migration, partitioned by result.",
+		},
+		[]string{"result"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(pendingExtensions, migratedExtensions)
+}
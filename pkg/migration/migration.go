@@ -0,0 +1,354 @@
+// Package migration upgrades the objects rendered by older versions of the
+// extension on startup. Extensions whose objects are outdated, e.g. because
+// they were rendered with an older SchemaVersion or still carry the legacy
+// hash annotation, are reconciled again in small batches, so a problem of the
+// new version doesn't hit all shoots of the seed at once.
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	v1beta1helper "github.com/gardener/gardener/pkg/apis/core/v1beta1/helper"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"github.com/go-logr/logr"
+	istionetworkv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+)
+
+const (
+	// ConditionTypeSchemaMigrated is the condition of the Extension which
+	// reports the migration of the objects rendered by an older version of
+	// the extension.
+	ConditionTypeSchemaMigrated gardencorev1beta1.ConditionType = "SchemaMigrated"
+	// ReasonMigrationPending is the reason of the SchemaMigrated condition
+	// while the extension waits for its batch.
+	ReasonMigrationPending = "MigrationPending"
+	// ReasonMigrationSucceeded is the reason of the SchemaMigrated condition
+	// once the objects of the extension are up to date.
+	ReasonMigrationSucceeded = "MigrationSucceeded"
+	// ReasonMigrationFailed is the reason of the SchemaMigrated condition if
+	// the objects weren't migrated within the batch timeout.
+	ReasonMigrationFailed = "MigrationFailed"
+
+	// DefaultBatchSize is the default number of extensions migrated at once.
+	DefaultBatchSize = 10
+	// DefaultBatchTimeout is the default time to wait for the migration of a
+	// batch.
+	DefaultBatchTimeout = 5 * time.Minute
+
+	defaultPollInterval = 5 * time.Second
+
+	// legacyVPNEnvoyFilterName is the name of the EnvoyFilter shared by all
+	// shoots of an istio namespace, which was rendered before schema version 2.
+	legacyVPNEnvoyFilterName = "acl-vpn"
+)
+
+// DefaultAddOptions are the default AddOptions for AddToManager.
+var DefaultAddOptions = AddOptions{
+	BatchSize:    DefaultBatchSize,
+	BatchTimeout: DefaultBatchTimeout,
+}
+
+// AddOptions are options to apply when adding the migrator to the manager.
+type AddOptions struct {
+	// BatchSize is the number of extensions migrated at once, the migration
+	// is disabled if it is zero.
+	BatchSize int
+	// BatchTimeout is the time to wait for the extensions of a batch to be
+	// migrated.
+	BatchTimeout time.Duration
+}
+
+// Migrator migrates the objects of outdated extensions once on startup.
+type Migrator struct {
+	client       client.Client
+	log          logr.Logger
+	clock        clock.Clock
+	batchSize    int
+	batchTimeout time.Duration
+	pollInterval time.Duration
+}
+
+// AddToManager adds a Migrator with the given options to the manager. Nothing
+// is added if the batch size is zero.
+//
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=extensions,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=extensions/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=networking.istio.io,resources=envoyfilters,verbs=get;list;watch;delete
+func AddToManager(mgr manager.Manager, opts AddOptions) error {
+	if opts.BatchSize == 0 {
+		return nil
+	}
+
+	return mgr.Add(&Migrator{
+		client:       mgr.GetClient(),
+		log:          mgr.GetLogger().WithName("migration"),
+		clock:        clock.RealClock{},
+		batchSize:    opts.BatchSize,
+		batchTimeout: opts.BatchTimeout,
+		pollInterval: defaultPollInterval,
+	})
+}
+
+// Start implements manager.Runnable. As a runnable of the manager, the
+// migration only runs on the leader, once it is elected.
+func (m *Migrator) Start(ctx context.Context) error {
+	// errors are only logged, as the regular reconciliations of the shoots
+	// migrate the objects as well
+	if err := m.migrate(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		m.log.Error(err, "Could not migrate outdated extensions")
+	}
+	return nil
+}
+
+func (m *Migrator) migrate(ctx context.Context) error {
+	pending, err := m.outdatedExtensions(ctx)
+	if err != nil {
+		return err
+	}
+	pendingExtensions.Set(float64(len(pending)))
+	if len(pending) == 0 {
+		return m.deleteLegacyEnvoyFilters(ctx)
+	}
+
+	m.log.Info("Migrating extensions with outdated objects", "extensions", len(pending), "batchSize", m.batchSize)
+	for key, reasons := range pending {
+		if err := m.updateCondition(ctx, key, gardencorev1beta1.ConditionFalse, ReasonMigrationPending,
+			"objects are outdated ("+strings.Join(reasons, ", ")+"), waiting for the migration"); err != nil {
+			return err
+		}
+	}
+
+	keys := sortedKeys(pending)
+	for start := 0; start < len(keys); start += m.batchSize {
+		batch := keys[start:min(start+m.batchSize, len(keys))]
+
+		failed, err := m.migrateBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+		pendingExtensions.Sub(float64(len(batch)))
+
+		// if no extension of a batch could be migrated, the new version has
+		// likely a general problem, so the rollout is stopped to not break
+		// the remaining shoots as well
+		if failed == len(batch) {
+			return fmt.Errorf("no extension of the batch %v was migrated within %s, stopping the migration of the remaining %d extensions",
+				batch, m.batchTimeout, len(keys)-start-len(batch))
+		}
+	}
+
+	m.log.Info("Finished the migration of extensions with outdated objects")
+
+	// the extensions which failed in a partially migrated batch might still
+	// rely on the legacy EnvoyFilter
+	pending, err = m.outdatedExtensions(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		m.log.Info("Keeping the legacy EnvoyFilters until all extensions are migrated", "extensions", len(pending))
+		return nil
+	}
+	return m.deleteLegacyEnvoyFilters(ctx)
+}
+
+// deleteLegacyEnvoyFilters deletes the acl-vpn EnvoyFilters shared by all
+// shoots of an istio namespace. They were replaced by the EnvoyFilters of
+// every shoot, and aren't updated anymore.
+func (m *Migrator) deleteLegacyEnvoyFilters(ctx context.Context) error {
+	envoyFilters := &istionetworkv1alpha3.EnvoyFilterList{}
+	if err := m.client.List(ctx, envoyFilters); err != nil {
+		return err
+	}
+
+	for _, envoyFilter := range envoyFilters.Items {
+		if envoyFilter.Name != legacyVPNEnvoyFilterName {
+			continue
+		}
+		m.log.Info("Deleting legacy EnvoyFilter", "namespace", envoyFilter.Namespace, "name", envoyFilter.Name)
+		if err := m.client.Delete(ctx, envoyFilter); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateBatch triggers the reconciliation of the given extensions and waits
+// until their objects are up to date. It returns the number of extensions
+// which weren't migrated within the batch timeout.
+func (m *Migrator) migrateBatch(ctx context.Context, batch []types.NamespacedName) (int, error) {
+	for _, key := range batch {
+		ex := &extensionsv1alpha1.Extension{}
+		if err := m.client.Get(ctx, key, ex); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		patch := client.MergeFrom(ex.DeepCopy())
+		metav1.SetMetaDataAnnotation(&ex.ObjectMeta, v1beta1constants.GardenerOperation, v1beta1constants.GardenerOperationReconcile)
+		if err := m.client.Patch(ctx, ex, patch); client.IgnoreNotFound(err) != nil {
+			return 0, err
+		}
+	}
+
+	remaining := map[types.NamespacedName]bool{}
+	for _, key := range batch {
+		remaining[key] = true
+	}
+
+	err := wait.PollUntilContextTimeout(ctx, m.pollInterval, m.batchTimeout, true, func(ctx context.Context) (bool, error) {
+		for key := range remaining {
+			ex := &extensionsv1alpha1.Extension{}
+			if err := m.client.Get(ctx, key, ex); err != nil {
+				if client.IgnoreNotFound(err) != nil {
+					return false, err
+				}
+				// deleted extensions don't need to be migrated anymore
+				delete(remaining, key)
+				continue
+			}
+			if ex.Annotations[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile {
+				continue
+			}
+
+			reasons, err := m.outdatedReasons(ctx, ex)
+			if err != nil {
+				return false, err
+			}
+			if len(reasons) > 0 {
+				continue
+			}
+
+			if err := m.updateCondition(ctx, key, gardencorev1beta1.ConditionTrue, ReasonMigrationSucceeded,
+				fmt.Sprintf("objects are up to date with schema version %d", controller.SchemaVersion)); err != nil {
+				return false, err
+			}
+			migratedExtensions.WithLabelValues(ResultMigrated).Inc()
+			delete(remaining, key)
+		}
+		return len(remaining) == 0, nil
+	})
+	if err != nil && !wait.Interrupted(err) {
+		return 0, err
+	}
+
+	for key := range remaining {
+		m.log.Info("Extension was not migrated within the batch timeout", "namespace", key.Namespace, "timeout", m.batchTimeout)
+		if err := m.updateCondition(ctx, key, gardencorev1beta1.ConditionFalse, ReasonMigrationFailed,
+			fmt.Sprintf("objects weren't migrated within %s, see the last error of the extension", m.batchTimeout)); err != nil {
+			return 0, err
+		}
+		migratedExtensions.WithLabelValues(ResultFailed).Inc()
+	}
+	return len(remaining), nil
+}
+
+// outdatedExtensions returns the ACL extensions with outdated objects,
+// together with the reasons why their objects are outdated.
+func (m *Migrator) outdatedExtensions(ctx context.Context) (map[types.NamespacedName][]string, error) {
+	extensions := &extensionsv1alpha1.ExtensionList{}
+	if err := controller.ListExtensions(ctx, m.client, extensions); err != nil {
+		return nil, err
+	}
+
+	outdated := map[types.NamespacedName][]string{}
+	for i := range extensions.Items {
+		ex := &extensions.Items[i]
+		if ex.Spec.Type != controller.Type || !ex.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		reasons, err := m.outdatedReasons(ctx, ex)
+		if err != nil {
+			return nil, err
+		}
+		if len(reasons) > 0 {
+			outdated[client.ObjectKeyFromObject(ex)] = reasons
+		}
+	}
+	return outdated, nil
+}
+
+// outdatedReasons returns why the objects of the given extension are
+// outdated, or nothing if they are up to date.
+func (m *Migrator) outdatedReasons(ctx context.Context, ex *extensionsv1alpha1.Extension) ([]string, error) {
+	extState, err := controller.GetExtensionState(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	var reasons []string
+	if extState.SchemaVersion < controller.SchemaVersion {
+		reasons = append(reasons, fmt.Sprintf("rendered with schema version %d instead of %d", extState.SchemaVersion, controller.SchemaVersion))
+	}
+
+	// older versions triggered the webhook by a hash annotation on the
+	// EnvoyFilter of the shoot, which is removed by the reconciliation
+	for _, istioNamespace := range extState.GetIstioNamespaces() {
+		envoyFilter := &istionetworkv1alpha3.EnvoyFilter{}
+		if err := m.client.Get(ctx, types.NamespacedName{Namespace: istioNamespace, Name: ex.Namespace}, envoyFilter); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return nil, err
+			}
+			continue
+		}
+		if _, ok := envoyFilter.Annotations[controller.HashAnnotationName]; ok {
+			reasons = append(reasons, fmt.Sprintf("EnvoyFilter %s/%s has the legacy %s annotation", istioNamespace, ex.Namespace, controller.HashAnnotationName))
+		}
+	}
+	return reasons, nil
+}
+
+func (m *Migrator) updateCondition(
+	ctx context.Context, key types.NamespacedName, status gardencorev1beta1.ConditionStatus, reason, message string,
+) error {
+	ex := &extensionsv1alpha1.Extension{}
+	if err := m.client.Get(ctx, key, ex); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	condition := v1beta1helper.GetCondition(ex.Status.Conditions, ConditionTypeSchemaMigrated)
+	if condition == nil {
+		initCondition := v1beta1helper.InitConditionWithClock(m.clock, ConditionTypeSchemaMigrated)
+		condition = &initCondition
+	}
+
+	updated := v1beta1helper.UpdatedConditionWithClock(m.clock, *condition, status, reason, message)
+	if !v1beta1helper.ConditionsNeedUpdate([]gardencorev1beta1.Condition{*condition}, []gardencorev1beta1.Condition{updated}) {
+		return nil
+	}
+
+	patch := client.MergeFrom(ex.DeepCopy())
+	ex.Status.Conditions = v1beta1helper.MergeConditions(ex.Status.Conditions, updated)
+	return client.IgnoreNotFound(m.client.Status().Patch(ctx, ex, patch))
+}
+
+// sortedKeys returns the keys of the given map in a stable order, so the
+// batches are reproducible.
+func sortedKeys(m map[types.NamespacedName][]string) []types.NamespacedName {
+	keys := make([]types.NamespacedName, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b types.NamespacedName) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return keys
+}
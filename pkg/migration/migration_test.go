@@ -0,0 +1,225 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	v1beta1helper "github.com/gardener/gardener/pkg/apis/core/v1beta1/helper"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	istionetworkv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+)
+
+var _ = Describe("Migrator", func() {
+	var (
+		ctx = context.TODO()
+		c   client.Client
+		m   *Migrator
+
+		// reconcilable contains the namespaces of the extensions whose
+		// reconciliation is simulated when they get the reconcile annotation
+		reconcilable map[string]bool
+	)
+
+	stateJSON := func(schemaVersion int) []byte {
+		state, err := json.Marshal(&controller.ExtensionState{
+			SchemaVersion:   schemaVersion,
+			IstioNamespace:  ptr.To("istio-ingress"),
+			IstioNamespaces: []string{"istio-ingress"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return state
+	}
+
+	createExtension := func(namespace, extensionType string, schemaVersion int) {
+		Expect(c.Create(ctx, &extensionsv1alpha1.Extension{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "acl"},
+			Spec: extensionsv1alpha1.ExtensionSpec{
+				DefaultSpec: extensionsv1alpha1.DefaultSpec{Type: extensionType},
+			},
+			Status: extensionsv1alpha1.ExtensionStatus{
+				DefaultStatus: extensionsv1alpha1.DefaultStatus{
+					State: &runtime.RawExtension{Raw: stateJSON(schemaVersion)},
+				},
+			},
+		})).To(Succeed())
+	}
+
+	createEnvoyFilter := func(namespace, name string) {
+		Expect(c.Create(ctx, &istionetworkv1alpha3.EnvoyFilter{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		})).To(Succeed())
+	}
+
+	getCondition := func(namespace string) *gardencorev1beta1.Condition {
+		ex := &extensionsv1alpha1.Extension{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "acl"}, ex)).To(Succeed())
+		return v1beta1helper.GetCondition(ex.Status.Conditions, ConditionTypeSchemaMigrated)
+	}
+
+	isTriggered := func(namespace string) bool {
+		ex := &extensionsv1alpha1.Extension{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "acl"}, ex)).To(Succeed())
+		return ex.Annotations[v1beta1constants.GardenerOperation] == v1beta1constants.GardenerOperationReconcile
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+		Expect(istionetworkv1alpha3.AddToScheme(scheme)).To(Succeed())
+
+		reconcilable = map[string]bool{}
+		c = fakeclient.NewClientBuilder().
+			WithScheme(scheme).
+			WithStatusSubresource(&extensionsv1alpha1.Extension{}).
+			WithIndex(&extensionsv1alpha1.Extension{}, controller.TypeIndex, controller.TypeIndexerFunc).
+			WithInterceptorFuncs(interceptor.Funcs{
+				// simulate the extension controller, which records the current
+				// schema version and removes the operation annotation
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					if err := c.Patch(ctx, obj, patch, opts...); err != nil {
+						return err
+					}
+					ex, ok := obj.(*extensionsv1alpha1.Extension)
+					if !ok || !reconcilable[ex.Namespace] ||
+						ex.Annotations[v1beta1constants.GardenerOperation] != v1beta1constants.GardenerOperationReconcile {
+						return nil
+					}
+
+					ex.Status.State = &runtime.RawExtension{Raw: stateJSON(controller.SchemaVersion)}
+					if err := c.Status().Update(ctx, ex); err != nil {
+						return err
+					}
+					delete(ex.Annotations, v1beta1constants.GardenerOperation)
+					return c.Update(ctx, ex)
+				},
+			}).
+			Build()
+
+		m = &Migrator{
+			client:       c,
+			log:          logf.Log,
+			clock:        clock.RealClock{},
+			batchSize:    1,
+			batchTimeout: 100 * time.Millisecond,
+			pollInterval: 10 * time.Millisecond,
+		}
+	})
+
+	Describe("#outdatedExtensions", func() {
+		It("should return the ACL extensions rendered with an older schema version", func() {
+			createExtension("shoot--foo--outdated", controller.Type, 0)
+			createExtension("shoot--foo--up-to-date", controller.Type, controller.SchemaVersion)
+			createExtension("shoot--foo--other", "other", 0)
+
+			outdated, err := m.outdatedExtensions(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(outdated).To(HaveLen(1))
+			Expect(outdated).To(HaveKeyWithValue(
+				types.NamespacedName{Namespace: "shoot--foo--outdated", Name: "acl"},
+				ConsistOf(ContainSubstring("schema version 0")),
+			))
+		})
+
+		It("should return the ACL extensions whose EnvoyFilter has the legacy hash annotation", func() {
+			createExtension("shoot--foo--bar", controller.Type, controller.SchemaVersion)
+			Expect(c.Create(ctx, &istionetworkv1alpha3.EnvoyFilter{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "istio-ingress",
+					Name:        "shoot--foo--bar",
+					Annotations: map[string]string{controller.HashAnnotationName: "abc"},
+				},
+			})).To(Succeed())
+
+			outdated, err := m.outdatedExtensions(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(outdated).To(HaveKeyWithValue(
+				types.NamespacedName{Namespace: "shoot--foo--bar", Name: "acl"},
+				ConsistOf(ContainSubstring(controller.HashAnnotationName)),
+			))
+		})
+	})
+
+	Describe("#migrate", func() {
+		It("should migrate the outdated extensions in batches", func() {
+			createExtension("shoot--foo--a", controller.Type, 0)
+			createExtension("shoot--foo--b", controller.Type, 0)
+			createExtension("shoot--foo--c", controller.Type, controller.SchemaVersion)
+			reconcilable["shoot--foo--a"] = true
+			reconcilable["shoot--foo--b"] = true
+			before := testutil.ToFloat64(migratedExtensions.WithLabelValues(ResultMigrated))
+
+			Expect(m.migrate(ctx)).To(Succeed())
+
+			for _, namespace := range []string{"shoot--foo--a", "shoot--foo--b"} {
+				condition := getCondition(namespace)
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(gardencorev1beta1.ConditionTrue))
+				Expect(condition.Reason).To(Equal(ReasonMigrationSucceeded))
+			}
+			Expect(getCondition("shoot--foo--c")).To(BeNil())
+			Expect(isTriggered("shoot--foo--c")).To(BeFalse())
+			Expect(testutil.ToFloat64(migratedExtensions.WithLabelValues(ResultMigrated))).To(Equal(before + 2))
+			Expect(testutil.ToFloat64(pendingExtensions)).To(BeZero())
+		})
+
+		It("should delete the legacy acl-vpn EnvoyFilters once all extensions are migrated", func() {
+			createExtension("shoot--foo--a", controller.Type, 0)
+			reconcilable["shoot--foo--a"] = true
+			createEnvoyFilter("istio-ingress", "acl-vpn")
+			createEnvoyFilter("istio-ingress--0", "acl-vpn")
+			createEnvoyFilter("istio-ingress", "shoot--foo--a")
+
+			Expect(m.migrate(ctx)).To(Succeed())
+
+			envoyFilters := &istionetworkv1alpha3.EnvoyFilterList{}
+			Expect(c.List(ctx, envoyFilters)).To(Succeed())
+			Expect(envoyFilters.Items).To(ConsistOf(
+				HaveField("ObjectMeta.Name", "shoot--foo--a"),
+			))
+		})
+
+		It("should stop the rollout if no extension of a batch was migrated", func() {
+			createExtension("shoot--foo--a", controller.Type, 0)
+			createExtension("shoot--foo--b", controller.Type, 0)
+			createEnvoyFilter("istio-ingress", "acl-vpn")
+			before := testutil.ToFloat64(migratedExtensions.WithLabelValues(ResultFailed))
+
+			Expect(m.migrate(ctx)).To(MatchError(ContainSubstring("stopping the migration of the remaining 1 extensions")))
+
+			condition := getCondition("shoot--foo--a")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(gardencorev1beta1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(ReasonMigrationFailed))
+
+			condition = getCondition("shoot--foo--b")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(ReasonMigrationPending))
+			Expect(isTriggered("shoot--foo--b")).To(BeFalse())
+
+			Expect(testutil.ToFloat64(migratedExtensions.WithLabelValues(ResultFailed))).To(Equal(before + 1))
+			Expect(testutil.ToFloat64(pendingExtensions)).To(Equal(1.0))
+
+			// the remaining extensions might still rely on the legacy EnvoyFilter
+			Expect(c.Get(ctx, types.NamespacedName{Namespace: "istio-ingress", Name: "acl-vpn"}, &istionetworkv1alpha3.EnvoyFilter{})).To(Succeed())
+		})
+	})
+})
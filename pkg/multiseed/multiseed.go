@@ -0,0 +1,170 @@
+// Package multiseed allows one extension instance to serve several seed
+// clusters in addition to the cluster it is running in.
+package multiseed
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// DefaultRetryPeriod is the period after which the manager of a seed is
+// restarted after it stopped with an error, e.g. because the seed was
+// unreachable.
+const DefaultRetryPeriod = 30 * time.Second
+
+var (
+	// DefaultAddOptions are the default AddOptions for AddToManager.
+	DefaultAddOptions = AddOptions{
+		RetryPeriod: DefaultRetryPeriod,
+	}
+)
+
+// Seed is a seed cluster served by the extension in addition to the cluster it
+// is running in.
+type Seed struct {
+	// Name identifies the seed in logs.
+	Name string
+	// Kubeconfig is the path to the kubeconfig of the seed.
+	Kubeconfig string
+}
+
+// AddOptions are options to apply when adding the seed managers to the manager.
+type AddOptions struct {
+	// Seeds are the additional seeds to serve.
+	Seeds []Seed
+	// RetryPeriod is the period after which a failed seed manager is restarted.
+	RetryPeriod time.Duration
+	// ConfigureRESTConfig is called with the REST config of every seed, e.g.
+	// to apply client connection settings.
+	ConfigureRESTConfig func(*rest.Config)
+}
+
+// AddToManagerFunc adds controllers to the manager of a seed.
+type AddToManagerFunc func(context.Context, manager.Manager) error
+
+// ParseSeeds parses seed references in the format '<name>=<kubeconfig path>'.
+func ParseSeeds(references []string) ([]Seed, error) {
+	seeds := make([]Seed, 0, len(references))
+	names := make(map[string]struct{}, len(references))
+
+	for _, reference := range references {
+		name, kubeconfig, found := strings.Cut(reference, "=")
+		if !found || name == "" || kubeconfig == "" {
+			return nil, fmt.Errorf("%q, expected '<name>=<kubeconfig path>'", reference)
+		}
+		if _, ok := names[name]; ok {
+			return nil, fmt.Errorf("duplicate seed name %q", name)
+		}
+		names[name] = struct{}{}
+
+		seeds = append(seeds, Seed{Name: name, Kubeconfig: kubeconfig})
+	}
+	return seeds, nil
+}
+
+// AddToManager adds one manager per seed to the given manager. Every seed
+// manager has its own clients, caches and work queues, so an unreachable seed
+// doesn't block the reconciliation for the other seeds. The given
+// addToManager func is called to add the controllers to every seed manager.
+func AddToManager(mgr manager.Manager, opts AddOptions, addToManager AddToManagerFunc) error {
+	for _, seed := range opts.Seeds {
+		runnable := &seedRunnable{
+			seed:         seed,
+			log:          mgr.GetLogger().WithName("multiseed").WithValues("seed", seed.Name),
+			retryPeriod:  opts.RetryPeriod,
+			addToManager: addToManager,
+			newManager: func(log logr.Logger) (manager.Manager, error) {
+				return newSeedManager(mgr, seed, log, opts.ConfigureRESTConfig)
+			},
+		}
+
+		if err := mgr.Add(runnable); err != nil {
+			return fmt.Errorf("could not add manager for seed %q: %w", seed.Name, err)
+		}
+	}
+	return nil
+}
+
+func newSeedManager(
+	mgr manager.Manager, seed Seed, log logr.Logger, configureRESTConfig func(*rest.Config),
+) (manager.Manager, error) {
+	restConfig, err := clientcmd.BuildConfigFromFlags("", seed.Kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not load kubeconfig: %w", err)
+	}
+	if configureRESTConfig != nil {
+		configureRESTConfig(restConfig)
+	}
+
+	return manager.New(restConfig, manager.Options{
+		Scheme: mgr.GetScheme(),
+		Logger: log,
+		// leader election is done by the parent manager
+		LeaderElection: false,
+		// metrics and health probes are served by the parent manager
+		Metrics:                metricsserver.Options{BindAddress: "0"},
+		HealthProbeBindAddress: "0",
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				DisableFor: []client.Object{
+					&corev1.Secret{},    // applied for ManagedResources
+					&corev1.ConfigMap{}, // applied for monitoring config
+				},
+			},
+		},
+	})
+}
+
+// seedRunnable runs the manager of a single seed and restarts it after
+// RetryPeriod if it stops with an error.
+type seedRunnable struct {
+	seed         Seed
+	log          logr.Logger
+	retryPeriod  time.Duration
+	addToManager AddToManagerFunc
+	newManager   func(logr.Logger) (manager.Manager, error)
+}
+
+// Start implements manager.Runnable. It never returns an error, as a failing
+// seed must not stop the parent manager and thereby the other seeds.
+func (r *seedRunnable) Start(ctx context.Context) error {
+	for {
+		err := r.run(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		r.log.Error(err, "Manager for seed stopped, restarting", "retryPeriod", r.retryPeriod)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.retryPeriod):
+		}
+	}
+}
+
+func (r *seedRunnable) run(ctx context.Context) error {
+	mgr, err := r.newManager(r.log)
+	if err != nil {
+		return err
+	}
+	if err := r.addToManager(ctx, mgr); err != nil {
+		return fmt.Errorf("could not add controllers to manager: %w", err)
+	}
+
+	r.log.Info("Starting manager for seed")
+	if err := mgr.Start(ctx); err != nil {
+		return err
+	}
+	return fmt.Errorf("manager stopped unexpectedly")
+}
@@ -0,0 +1,63 @@
+package multiseed
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var _ = Describe("multiseed", func() {
+	Describe("ParseSeeds", func() {
+		It("should parse valid references", func() {
+			seeds, err := ParseSeeds([]string{"eu01=/etc/seeds/eu01/kubeconfig", "eu02=/etc/seeds/eu02/kubeconfig"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(seeds).To(Equal([]Seed{
+				{Name: "eu01", Kubeconfig: "/etc/seeds/eu01/kubeconfig"},
+				{Name: "eu02", Kubeconfig: "/etc/seeds/eu02/kubeconfig"},
+			}))
+		})
+
+		It("should reject malformed references", func() {
+			for _, reference := range []string{"eu01", "=/kubeconfig", "eu01="} {
+				_, err := ParseSeeds([]string{reference})
+				Expect(err).To(HaveOccurred(), reference)
+			}
+		})
+
+		It("should reject duplicate names", func() {
+			_, err := ParseSeeds([]string{"eu01=/a", "eu01=/b"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("seedRunnable", func() {
+		It("should restart a failing seed manager without returning an error", func() {
+			var attempts atomic.Int32
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			r := &seedRunnable{
+				seed:        Seed{Name: "unreachable"},
+				log:         logr.Discard(),
+				retryPeriod: 10 * time.Millisecond,
+				newManager: func(logr.Logger) (manager.Manager, error) {
+					attempts.Add(1)
+					return nil, errors.New("seed unreachable")
+				},
+			}
+
+			done := make(chan error)
+			go func() { done <- r.Start(ctx) }()
+
+			Eventually(attempts.Load).Should(BeNumerically(">=", 3))
+			cancel()
+			Eventually(done).Should(Receive(BeNil()))
+		})
+	})
+})
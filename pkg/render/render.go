@@ -0,0 +1,173 @@
+// Package render renders the EnvoyFilters or AuthorizationPolicies the
+// extension would create for a providerConfig without a seed, e.g. to review
+// rule changes.
+package render
+
+import (
+	"errors"
+	"fmt"
+
+	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	"github.com/gardener/gardener/pkg/chartrenderer"
+	"k8s.io/apimachinery/pkg/version"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stackitcloud/gardener-extension-acl/charts"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+)
+
+// Error variables for the render pkg
+var (
+	ErrNoTechnicalID     = errors.New("the technical ID of the shoot is required")
+	ErrNoIstioNamespaces = errors.New("at least one istio namespace is required")
+	ErrTemplateVariables = errors.New("template variables are only resolved by the controller, replace them with the CIDRs of the shoot")
+)
+
+// Options contains the providerConfig and the information about the shoot
+// and the seed needed to render its objects.
+type Options struct {
+	// ProviderConfig is the providerConfig of the ACL extension in YAML or
+	// JSON.
+	ProviderConfig []byte
+	// TechnicalID is the technical ID of the shoot.
+	TechnicalID string
+	// Hosts are the hosts (SNI) of the shoot's API server, as in its
+	// advertised addresses.
+	Hosts []string
+	// InternalHosts are the hosts (SNI) of the internal address of the
+	// shoot's API server. The internalRule of the providerConfig is only
+	// rendered if they are set.
+	InternalHosts []string
+	// IstioNamespaces are the namespaces of the istio ingress gateways
+	// serving the shoot.
+	IstioNamespaces []string
+	// IstioLabels are the labels of the istio ingress gateways serving the
+	// shoot.
+	IstioLabels map[string]string
+	// AlwaysAllowedCIDRs are the CIDRs allowed for every shoot, i.e. the seed
+	// networks, the additional allowed CIDRs and the global allowlist.
+	AlwaysAllowedCIDRs []string
+	// ShootCIDRs are the CIDRs of the shoot which are always allowed and
+	// never denied, i.e. its node networks and egress CIDRs.
+	ShootCIDRs []string
+	// ShootDomain is the domain of the shoot. The ingress domain of the shoot
+	// is only rendered with protectIngress if it is set.
+	ShootDomain string
+	// SeedIngressDomain is the ingress domain of the seed. The ingress of the
+	// shoot is only rendered if it is set.
+	SeedIngressDomain string
+	// IngressIstioLabels are the labels of the istio ingress gateway of the
+	// seed ingress domain, defaulting to the IstioLabels.
+	IngressIstioLabels map[string]string
+	// EnforcementBackend is the enforcement backend of the extension.
+	EnforcementBackend string
+	// LogDeniedConnections is the default of the seed for the denied
+	// connection logs.
+	LogDeniedConnections bool
+	// IstioVersion is the istio release of the ingress gateways, e.g.
+	// "1.21". The filters are rendered for the latest supported release if
+	// it is empty.
+	IstioVersion string
+}
+
+// Render returns the manifests of the objects the extension would create in
+// the istio namespaces for the given options.
+func Render(opts Options) ([]byte, error) {
+	spec := &extensionspec.ExtensionSpec{}
+	if err := yaml.UnmarshalStrict(opts.ProviderConfig, spec); err != nil {
+		return nil, fmt.Errorf("could not decode providerConfig: %w", err)
+	}
+	if err := controller.ValidateExtensionSpec(spec); err != nil {
+		return nil, fmt.Errorf("invalid providerConfig: %w", err)
+	}
+	if controller.HasTemplateVariables(spec) {
+		return nil, ErrTemplateVariables
+	}
+	spec.Normalize()
+	if opts.TechnicalID == "" {
+		return nil, ErrNoTechnicalID
+	}
+	if len(opts.IstioNamespaces) == 0 {
+		return nil, ErrNoIstioNamespaces
+	}
+	if opts.EnforcementBackend != config.EnforcementBackendEnvoyFilter &&
+		opts.EnforcementBackend != config.EnforcementBackendAuthorizationPolicy {
+		return nil, fmt.Errorf("invalid enforcement backend %q", opts.EnforcementBackend)
+	}
+	renderings := envoyfilters.DefaultRenderings
+	if opts.IstioVersion != "" {
+		istioVersion, err := envoyfilters.ParseIstioVersion(opts.IstioVersion)
+		if err != nil {
+			return nil, err
+		}
+		renderings = envoyfilters.RenderingsFor(istioVersion)
+	}
+
+	// like the controller, the rules never deny the networks of the shoot
+	spec.Rule.ProtectedCIDRs = opts.ShootCIDRs
+	if spec.InternalRule != nil {
+		spec.InternalRule.ProtectedCIDRs = opts.ShootCIDRs
+	}
+
+	cluster := &extensionscontroller.Cluster{
+		Shoot: &gardencorev1beta1.Shoot{
+			Status: gardencorev1beta1.ShootStatus{TechnicalID: opts.TechnicalID},
+		},
+		Seed: &gardencorev1beta1.Seed{},
+	}
+	for _, host := range opts.InternalHosts {
+		cluster.Shoot.Status.AdvertisedAddresses = append(cluster.Shoot.Status.AdvertisedAddresses, gardencorev1beta1.ShootAdvertisedAddress{
+			Name: v1beta1constants.AdvertisedAddressInternal,
+			URL:  "https://" + host,
+		})
+	}
+
+	var ingressIstioLabels map[string]string
+	if opts.SeedIngressDomain != "" {
+		cluster.Seed.Spec.Ingress = &gardencorev1beta1.Ingress{Domain: opts.SeedIngressDomain}
+	}
+	if opts.ShootDomain != "" {
+		cluster.Shoot.Spec.DNS = &gardencorev1beta1.DNS{Domain: &opts.ShootDomain}
+	}
+	if opts.SeedIngressDomain != "" || (opts.ShootDomain != "" && spec.ProtectIngress) {
+		ingressIstioLabels = opts.IngressIstioLabels
+		if len(ingressIstioLabels) == 0 {
+			ingressIstioLabels = opts.IstioLabels
+		}
+	}
+
+	values, err := controller.SeedChartValues(
+		config.Config{
+			EnforcementBackend:   opts.EnforcementBackend,
+			LogDeniedConnections: opts.LogDeniedConnections,
+		},
+		spec,
+		cluster,
+		opts.Hosts,
+		opts.ShootCIDRs,
+		opts.ShootCIDRs,
+		opts.AlwaysAllowedCIDRs,
+		opts.IstioNamespaces,
+		opts.IstioLabels,
+		ingressIstioLabels,
+		renderings,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// the seed chart doesn't depend on the Kubernetes version
+	renderer := chartrenderer.NewWithServerVersion(&version.Info{})
+	renderedChart, err := renderer.RenderEmbeddedFS(
+		charts.Seed, controller.ChartNameSeed, controller.ChartNameSeed, opts.TechnicalID, values,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return renderedChart.Manifest(), nil
+}
@@ -0,0 +1,171 @@
+package render
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
+)
+
+var _ = Describe("Render", func() {
+	var opts Options
+
+	BeforeEach(func() {
+		opts = Options{
+			ProviderConfig: []byte(`
+rule:
+  action: ALLOW
+  type: remote_ip
+  cidrs:
+  - 1.2.3.4/32
+`),
+			TechnicalID:        "shoot--foo--bar",
+			Hosts:              []string{"api.bar.foo.example.com"},
+			IstioNamespaces:    []string{"istio-ingress", "istio-ingress--zone-a"},
+			IstioLabels:        map[string]string{"app": "istio-ingressgateway", "istio": "ingressgateway"},
+			AlwaysAllowedCIDRs: []string{"10.250.0.0/16"},
+			EnforcementBackend: config.EnforcementBackendEnvoyFilter,
+		}
+	})
+
+	// objects returns the "<kind> <namespace>/<name>" of the rendered objects
+	objects := func(manifests []byte) []string {
+		var result []string
+		for _, document := range strings.Split(string(manifests), "\n---") {
+			obj := map[string]interface{}{}
+			Expect(yaml.Unmarshal([]byte(document), &obj)).To(Succeed())
+			if len(obj) == 0 {
+				continue
+			}
+			metadata := obj["metadata"].(map[string]interface{})
+			result = append(result, obj["kind"].(string)+" "+metadata["namespace"].(string)+"/"+metadata["name"].(string))
+		}
+		return result
+	}
+
+	It("should render the EnvoyFilters of the shoot in all istio namespaces", func() {
+		manifests, err := Render(opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(objects(manifests)).To(ConsistOf(
+			"EnvoyFilter istio-ingress/acl-api-shoot--foo--bar",
+			"EnvoyFilter istio-ingress--zone-a/acl-api-shoot--foo--bar",
+			"EnvoyFilter istio-ingress/acl-vpn-shoot--foo--bar",
+			"EnvoyFilter istio-ingress--zone-a/acl-vpn-shoot--foo--bar",
+		))
+		Expect(string(manifests)).To(ContainSubstring("sni: api.bar.foo.example.com"))
+		Expect(string(manifests)).To(ContainSubstring("address_prefix: 1.2.3.4"))
+		Expect(string(manifests)).To(ContainSubstring("address_prefix: 10.250.0.0"))
+	})
+
+	It("should render the EnvoyFilter of the ingress if the seed ingress domain is given", func() {
+		opts.SeedIngressDomain = "ingress.seed.example.com"
+
+		manifests, err := Render(opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(objects(manifests)).To(ContainElement("EnvoyFilter istio-ingress/acl-ingress-shoot--foo--bar"))
+		Expect(string(manifests)).To(ContainSubstring("ingress.seed.example.com"))
+	})
+
+	It("should render the filters for the given istio version", func() {
+		manifests, err := Render(opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(manifests)).To(ContainSubstring("rules_stat_prefix"))
+
+		opts.IstioVersion = "1.14"
+		manifests, err = Render(opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(manifests)).NotTo(ContainSubstring("rules_stat_prefix"))
+	})
+
+	It("should return an error for an invalid istio version", func() {
+		opts.IstioVersion = "latest"
+
+		_, err := Render(opts)
+		Expect(err).To(MatchError(ContainSubstring("invalid istio version")))
+	})
+
+	It("should render a separate AuthorizationPolicy for the internal rule", func() {
+		opts.EnforcementBackend = config.EnforcementBackendAuthorizationPolicy
+		opts.ProviderConfig = append(opts.ProviderConfig, []byte(`internalRule:
+  action: ALLOW
+  type: remote_ip
+  cidrs:
+  - 10.0.0.0/8
+`)...)
+		opts.Hosts = append(opts.Hosts, "api.bar.foo.internal.example.com")
+		opts.InternalHosts = []string{"api.bar.foo.internal.example.com"}
+
+		manifests, err := Render(opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(objects(manifests)).To(ContainElements(
+			"AuthorizationPolicy istio-ingress/acl-api-shoot--foo--bar",
+			"AuthorizationPolicy istio-ingress/acl-api-internal-shoot--foo--bar",
+		))
+		Expect(string(manifests)).To(ContainSubstring("10.0.0.0/8"))
+	})
+
+	It("should render the EnvoyFilter of the shoot's ingress domain with protectIngress", func() {
+		opts.ProviderConfig = append([]byte("protectIngress: true\n"), opts.ProviderConfig...)
+		opts.ShootDomain = "bar.foo.example.com"
+
+		manifests, err := Render(opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(objects(manifests)).To(ContainElement("EnvoyFilter istio-ingress/acl-ingress-shoot--foo--bar"))
+		Expect(string(manifests)).To(ContainSubstring("*.ingress.bar.foo.example.com"))
+	})
+
+	It("should render AuthorizationPolicies with the authorizationpolicy backend", func() {
+		opts.EnforcementBackend = config.EnforcementBackendAuthorizationPolicy
+
+		manifests, err := Render(opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(objects(manifests)).To(ContainElement("AuthorizationPolicy istio-ingress/acl-api-shoot--foo--bar"))
+		Expect(objects(manifests)).NotTo(ContainElement(HavePrefix("EnvoyFilter")))
+	})
+
+	It("should return an error for an invalid providerConfig", func() {
+		opts.ProviderConfig = []byte(`
+rule:
+  action: MAYBE
+  type: remote_ip
+  cidrs:
+  - 1.2.3.4/32
+`)
+
+		_, err := Render(opts)
+		Expect(err).To(MatchError(controller.ErrSpecAction))
+	})
+
+	It("should return an error for unknown fields in the providerConfig", func() {
+		opts.ProviderConfig = []byte(`
+rules:
+- action: ALLOW
+`)
+
+		_, err := Render(opts)
+		Expect(err).To(MatchError(ContainSubstring("could not decode providerConfig")))
+	})
+
+	It("should return an error for rules with template variables", func() {
+		opts.ProviderConfig = []byte(`{"rule":{"action":"ALLOW","type":"remote_ip","cidrs":["1.2.3.0/24","${shoot.networks.nodes}"]}}`)
+
+		_, err := Render(opts)
+		Expect(err).To(MatchError(ErrTemplateVariables))
+	})
+
+	It("should return an error without technical ID", func() {
+		opts.TechnicalID = ""
+
+		_, err := Render(opts)
+		Expect(err).To(MatchError(ErrNoTechnicalID))
+	})
+})
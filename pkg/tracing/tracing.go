@@ -0,0 +1,88 @@
+// Package tracing sets up the optional OpenTelemetry tracing of the
+// reconciliations of the shoots and of the webhook. The spans are exported via
+// OTLP/gRPC if an endpoint is configured. Otherwise, the no-op tracer provider
+// of OpenTelemetry is kept and starting spans costs next to nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ServiceName is the service name of the exported spans.
+	ServiceName = "gardener-extension-acl"
+
+	tracerName = "github.com/stackitcloud/gardener-extension-acl"
+)
+
+// DefaultOptions are the default Options for Setup.
+var DefaultOptions = Options{
+	SamplingRatio: 1,
+}
+
+// Options are options to apply when setting up the tracing.
+type Options struct {
+	// Endpoint is the address ('<host>:<port>') of the OTLP/gRPC collector
+	// the spans are exported to, the tracing is disabled if it is empty.
+	Endpoint string
+	// Insecure disables TLS for the connection to the collector.
+	Insecure bool
+	// SamplingRatio is the ratio of the traces which are sampled, unless the
+	// parent span was sampled already.
+	SamplingRatio float64
+}
+
+// Setup installs the global tracer provider exporting the spans to the
+// configured endpoint. The returned function flushes the pending spans and
+// stops the exporter, it has to be called before the extension exits.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporterOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create OTLP exporter: %w", err)
+	}
+
+	provider := NewTracerProvider(opts.SamplingRatio, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewTracerProvider returns a tracer provider sampling the given ratio of the
+// traces, with the service name of the extension as resource.
+func NewTracerProvider(samplingRatio float64, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(samplingRatio))),
+	}, opts...)...)
+}
+
+// Start starts a span with the given name and attributes as child of the span
+// in ctx, if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends the span and marks it as failed if err is set.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ = Describe("tracing", func() {
+	var (
+		ctx      context.Context
+		exporter *tracetest.InMemoryExporter
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		exporter = tracetest.NewInMemoryExporter()
+
+		previous := otel.GetTracerProvider()
+		provider := NewTracerProvider(1, sdktrace.WithSyncer(exporter))
+		otel.SetTracerProvider(provider)
+		DeferCleanup(func() {
+			Expect(provider.Shutdown(ctx)).To(Succeed())
+			otel.SetTracerProvider(previous)
+		})
+	})
+
+	It("should record nested spans with their attributes", func() {
+		parentCtx, parent := Start(ctx, "Reconcile", attribute.String("technicalID", "shoot--foo--bar"))
+		_, child := Start(parentCtx, "GetCluster")
+		End(child, nil)
+		End(parent, nil)
+
+		spans := exporter.GetSpans()
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Name).To(Equal("GetCluster"))
+		Expect(spans[0].Parent.SpanID()).To(Equal(spans[1].SpanContext.SpanID()))
+		Expect(spans[1].Name).To(Equal("Reconcile"))
+		Expect(spans[1].Attributes).To(ContainElement(attribute.String("technicalID", "shoot--foo--bar")))
+		Expect(spans[1].Status.Code).To(Equal(codes.Unset))
+		Expect(spans[1].Resource.Attributes()).To(ContainElement(attribute.String("service.name", ServiceName)))
+	})
+
+	It("should mark spans ending with an error as failed", func() {
+		_, span := Start(ctx, "Apply")
+		End(span, errors.New("conflict"))
+
+		spans := exporter.GetSpans()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Status).To(Equal(sdktrace.Status{Code: codes.Error, Description: "conflict"}))
+		Expect(spans[0].Events).To(ContainElement(HaveField("Name", "exception")))
+	})
+
+	It("should not sample traces if the ratio is zero", func() {
+		provider := NewTracerProvider(0, sdktrace.WithSyncer(exporter))
+		DeferCleanup(provider.Shutdown, ctx)
+
+		_, span := provider.Tracer("test").Start(ctx, "Reconcile")
+		span.End()
+
+		Expect(span.SpanContext().IsSampled()).To(BeFalse())
+		Expect(exporter.GetSpans()).To(BeEmpty())
+	})
+
+	It("should keep the no-op tracer provider without endpoint", func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+		shutdown, err := Setup(ctx, Options{SamplingRatio: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(shutdown(ctx)).To(Succeed())
+
+		_, span := Start(ctx, "Reconcile")
+		Expect(span.IsRecording()).To(BeFalse())
+	})
+})
@@ -2,10 +2,14 @@ package webhook
 
 import (
 	extensionswebhook "github.com/gardener/gardener/extensions/pkg/webhook"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
-	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
 )
 
 const (
@@ -24,22 +28,44 @@ var (
 
 // AddOptions are options to apply when adding the webhook to the manager.
 type AddOptions struct {
-	AllowedCIDRs []string
+	AllowedCIDRs                       []string
+	AutoAllowInfrastructureEgressCIDRs bool
+	GlobalAllowlistConfigMap           types.NamespacedName
+	GlobalDenylistConfigMap            types.NamespacedName
+	ClientIPPreservation               helper.ClientIPPreservation
+	PurposePolicies                    map[string]config.PurposePolicy
+	IgnoredNamespaces                  helper.NamespacePatterns
+	FailOpen                           bool
 }
 
 // AddToManagerWithOptions creates a webhook with the given options and adds it to the manager.
+//
+// +kubebuilder:webhook:path=/mutate,mutating=true,failurePolicy=fail,sideEffects=None,groups=networking.istio.io,resources=envoyfilters,verbs=create;update,versions=v1alpha3;v1beta1;v1,name=acl.stackit.cloud,admissionReviewVersions=v1,timeoutSeconds=5
+// +kubebuilder:rbac:groups=extensions.gardener.cloud,resources=clusters;extensions;infrastructures;bastions,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
 func AddToManagerWithOptions(
 	mgr manager.Manager,
 	options AddOptions,
 ) error {
 	logger.Info("Adding webhook to manager")
 
-	decoder := admission.NewDecoder(mgr.GetScheme())
-
 	mgr.GetWebhookServer().Register(WebhookPath, &webhook.Admission{Handler: &EnvoyFilterWebhook{
-		Client:                 mgr.GetClient(),
-		AdditionalAllowedCIDRs: options.AllowedCIDRs,
-		Decoder:                decoder,
+		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
+		// the ConfigMaps are excluded from the cache of the client, but the
+		// global list controller watches them anyway
+		ConfigMapReader:                    mgr.GetCache(),
+		Clusters:                           NewClusterCache(DefaultClusterCacheMaxAge, clock.RealClock{}),
+		AdditionalAllowedCIDRs:             options.AllowedCIDRs,
+		AutoAllowInfrastructureEgressCIDRs: options.AutoAllowInfrastructureEgressCIDRs,
+		GlobalAllowlistConfigMap:           options.GlobalAllowlistConfigMap,
+		GlobalDenylistConfigMap:            options.GlobalDenylistConfigMap,
+		ClientIPPreservation:               options.ClientIPPreservation,
+		PurposePolicies:                    options.PurposePolicies,
+		IgnoredNamespaces:                  options.IgnoredNamespaces,
+		FailOpen:                           options.FailOpen,
 	}})
 
 	return nil
@@ -0,0 +1,118 @@
+package webhook
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gardener/gardener/extensions/pkg/controller"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+// DefaultClusterCacheMaxAge is the default time a decoded Cluster is reused
+// for, even if it didn't change.
+const DefaultClusterCacheMaxAge = 10 * time.Minute
+
+const (
+	// SourceCache is the source label of objects read from the informer
+	// cache.
+	SourceCache = "cache"
+	// SourceAPIServer is the source label of objects read from the API server,
+	// as they weren't in the informer cache yet.
+	SourceAPIServer = "apiserver"
+)
+
+// ClusterCache caches the decoded Clusters of the shoots, so the Shoot, Seed
+// and CloudProfile embedded in a Cluster are only decoded again once the
+// Cluster changed or the entry is older than its max age. The returned
+// Clusters are shared between admission requests and must not be modified.
+type ClusterCache struct {
+	maxAge time.Duration
+	clock  clock.PassiveClock
+
+	mu      sync.Mutex
+	entries map[types.UID]*clusterCacheEntry
+}
+
+type clusterCacheEntry struct {
+	resourceVersion string
+	cluster         *controller.Cluster
+	expiresAt       time.Time
+}
+
+// NewClusterCache creates a ClusterCache whose entries are reused for at most
+// maxAge.
+func NewClusterCache(maxAge time.Duration, clock clock.PassiveClock) *ClusterCache {
+	return &ClusterCache{
+		maxAge:  maxAge,
+		clock:   clock,
+		entries: map[types.UID]*clusterCacheEntry{},
+	}
+}
+
+// Decode returns the decoded Cluster, which is reused while the
+// resourceVersion of the Cluster doesn't change. Expired entries are removed,
+// so the entries of deleted shoots don't pile up.
+func (c *ClusterCache) Decode(cluster *extensionsv1alpha1.Cluster) (*controller.Cluster, error) {
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[cluster.UID]
+	c.mu.Unlock()
+	if ok && entry.resourceVersion == cluster.ResourceVersion && now.Before(entry.expiresAt) {
+		return entry.cluster, nil
+	}
+
+	decoded, err := helper.DecodeCluster(cluster)
+	if err != nil {
+		return nil, err
+	}
+	clusterDecodes.Inc()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for uid, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, uid)
+		}
+	}
+	c.entries[cluster.UID] = &clusterCacheEntry{
+		resourceVersion: cluster.ResourceVersion,
+		cluster:         decoded,
+		expiresAt:       now.Add(c.maxAge),
+	}
+	return decoded, nil
+}
+
+// get reads the object from the informer cache of the client. Objects of
+// newly created shoots might not be in the cache yet, they are read from the
+// API server instead, so their EnvoyFilters aren't admitted without the ACL.
+func (e *EnvoyFilterWebhook) get(ctx context.Context, key client.ObjectKey, obj client.Object, kind string) error {
+	err := e.Client.Get(ctx, key, obj)
+	if !apierrors.IsNotFound(err) || e.APIReader == nil {
+		lookups.WithLabelValues(kind, SourceCache).Inc()
+		return err
+	}
+
+	lookups.WithLabelValues(kind, SourceAPIServer).Inc()
+	return e.APIReader.Get(ctx, key, obj)
+}
+
+// getCluster returns the decoded Cluster of the shoot namespace.
+func (e *EnvoyFilterWebhook) getCluster(ctx context.Context, namespace string) (*controller.Cluster, error) {
+	cluster := &extensionsv1alpha1.Cluster{}
+	if err := e.get(ctx, client.ObjectKey{Name: namespace}, cluster, "cluster"); err != nil {
+		return nil, err
+	}
+
+	if e.Clusters == nil {
+		return helper.DecodeCluster(cluster)
+	}
+	return e.Clusters.Decode(cluster)
+}
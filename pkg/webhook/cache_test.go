@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclock "k8s.io/utils/clock/testing"
+
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+)
+
+var _ = Describe("ClusterCache", func() {
+	var (
+		fakeClock *testclock.FakePassiveClock
+		cache     *ClusterCache
+		cluster   *extensionsv1alpha1.Cluster
+	)
+
+	BeforeEach(func() {
+		fakeClock = testclock.NewFakePassiveClock(time.Now())
+		cache = NewClusterCache(time.Minute, fakeClock)
+		cluster = getNewCluster("shoot--foo--bar", &gardencorev1beta1.Shoot{ObjectMeta: metav1.ObjectMeta{Name: "bar"}}, &gardencorev1beta1.Seed{})
+		cluster.UID = "cluster-uid"
+		cluster.ResourceVersion = "1"
+	})
+
+	It("should reuse the decoded Cluster while it doesn't change", func() {
+		first, err := cache.Decode(cluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first.Shoot.Name).To(Equal("bar"))
+
+		second, err := cache.Decode(cluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+	})
+
+	It("should decode the Cluster again once it changed", func() {
+		first, err := cache.Decode(cluster)
+		Expect(err).NotTo(HaveOccurred())
+
+		shootJSON, err := json.Marshal(&gardencorev1beta1.Shoot{ObjectMeta: metav1.ObjectMeta{Name: "baz"}})
+		Expect(err).NotTo(HaveOccurred())
+		cluster.Spec.Shoot = runtime.RawExtension{Raw: shootJSON}
+		cluster.ResourceVersion = "2"
+
+		second, err := cache.Decode(cluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).NotTo(BeIdenticalTo(first))
+		Expect(second.Shoot.Name).To(Equal("baz"))
+	})
+
+	It("should decode the Cluster again after the max age", func() {
+		first, err := cache.Decode(cluster)
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeClock.SetTime(fakeClock.Now().Add(time.Minute))
+
+		second, err := cache.Decode(cluster)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).NotTo(BeIdenticalTo(first))
+	})
+
+	It("should not cache incomplete Clusters", func() {
+		cluster.Spec.Seed = runtime.RawExtension{}
+
+		_, err := cache.Decode(cluster)
+		Expect(err).To(MatchError(helper.ErrClusterObjectNotComplete))
+		Expect(cache.entries).To(BeEmpty())
+	})
+})
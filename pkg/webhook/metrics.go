@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	metricsNamespace = "acl"
+	metricsSubsystem = "webhook"
+
+	// ResultPatched is the result label of EnvoyFilters patched by the webhook.
+	ResultPatched = "patched"
+	// ResultSkipped is the result label of EnvoyFilters admitted without
+	// patches, e.g. of shoots without the ACL extension.
+	ResultSkipped = "skipped"
+)
+
+var (
+	mutations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "mutations_total",
+			Help:      "Number of EnvoyFilters admitted by the ACL webhook, partitioned by result.",
+		},
+		[]string{"result"},
+	)
+	mutationErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "errors_total",
+			Help:      "Number of EnvoyFilters the ACL webhook failed to handle, partitioned by HTTP status code.",
+		},
+		[]string{"code"},
+	)
+	failOpens = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "fail_open_total",
+			Help:      "Number of EnvoyFilters admitted without patches because the ACL webhook failed to handle them, partitioned by HTTP status code.",
+		},
+		[]string{"code"},
+	)
+	lookups = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "lookups_total",
+			Help:      "Number of objects read by the ACL webhook, partitioned by object and source (informer cache or API server).",
+		},
+		[]string{"object", "source"},
+	)
+	clusterDecodes = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "cluster_decodes_total",
+			Help:      "Number of Clusters decoded by the ACL webhook because they changed or weren't cached.",
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(mutations, mutationErrors, failOpens, lookups, clusterDecodes)
+}
+
+// recordResponse counts the given admission response either as a mutation
+// or as an error.
+//
+//nolint:gocritic // admission.Response is passed by value everywhere in controller-runtime
+func recordResponse(resp admission.Response) {
+	if !resp.Allowed {
+		var code int32
+		if resp.Result != nil {
+			code = resp.Result.Code
+		}
+		mutationErrors.WithLabelValues(strconv.Itoa(int(code))).Inc()
+		return
+	}
+
+	if len(resp.Patches) > 0 {
+		mutations.WithLabelValues(ResultPatched).Inc()
+		return
+	}
+	mutations.WithLabelValues(ResultSkipped).Inc()
+}
+
+// recordFailOpen counts an EnvoyFilter admitted without patches because the
+// webhook failed with the given HTTP status code.
+func recordFailOpen(code int32) {
+	failOpens.WithLabelValues(strconv.Itoa(int(code))).Inc()
+}
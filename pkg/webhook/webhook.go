@@ -3,6 +3,7 @@ package webhook
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -11,6 +12,8 @@ import (
 	v1beta1helper "github.com/gardener/gardener/pkg/apis/core/v1beta1/helper"
 	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
 	"github.com/tidwall/gjson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"gomodules.xyz/jsonpatch/v2"
 	istionetworkingClientGo "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	admissionv1 "k8s.io/api/admission/v1"
@@ -20,9 +23,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller/config"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/tracing"
 )
 
 const (
@@ -33,9 +38,34 @@ const (
 // EnvoyFilterWebhook is a service struct that defines functions to handle
 // admission requests for EnvoyFilters.
 type EnvoyFilterWebhook struct {
-	Client                 client.Client
-	Decoder                *admission.Decoder
-	AdditionalAllowedCIDRs []string
+	// Client reads the objects of the shoots from the informer cache.
+	Client client.Client
+	// APIReader reads the objects of the shoots which aren't in the informer
+	// cache of Client yet, they are treated as missing if unset.
+	APIReader client.Reader
+	// ConfigMapReader reads the global allowlist and denylist ConfigMaps,
+	// which are excluded from the informer cache of Client. Client is used if
+	// unset.
+	ConfigMapReader client.Reader
+	// Clusters caches the decoded Clusters, they are decoded on every
+	// admission request if unset.
+	Clusters                           *ClusterCache
+	AdditionalAllowedCIDRs             []string
+	AutoAllowInfrastructureEgressCIDRs bool
+	GlobalAllowlistConfigMap           types.NamespacedName
+	GlobalDenylistConfigMap            types.NamespacedName
+	ClientIPPreservation               helper.ClientIPPreservation
+	// PurposePolicies are the default ACL policies of the shoots by their
+	// purpose, like in the configuration of the controller.
+	PurposePolicies map[string]config.PurposePolicy
+	// IgnoredNamespaces match the namespaces whose EnvoyFilters are admitted
+	// without being touched, e.g. the ones of test gateways.
+	IgnoredNamespaces helper.NamespacePatterns
+	// FailOpen admits the EnvoyFilters without patches if the webhook fails to
+	// handle them, like the API server does with the "Ignore" failure policy
+	// if the webhook isn't reachable. Otherwise, they are rejected, which
+	// blocks the updates of the EnvoyFilters until the webhook recovers.
+	FailOpen bool
 }
 
 // Handle receives incoming admission requests for EnvoyFilters and returns a
@@ -44,12 +74,53 @@ type EnvoyFilterWebhook struct {
 //
 //nolint:gocritic // the signature is forced by kubebuilder
 func (e *EnvoyFilterWebhook) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var resp admission.Response
+	ctx, span := tracing.Start(ctx, "EnvoyFilterWebhook",
+		attribute.String("namespace", req.Namespace), attribute.String("name", req.Name))
+	defer span.End()
+
+	// the EnvoyFilter is decoded regardless of its API version, as the schema
+	// of EnvoyFilters is the same in all versions served by istio. The ones in
+	// ignored namespaces aren't touched at all.
 	filter := &istionetworkingClientGo.EnvoyFilter{}
-	if err := e.Decoder.Decode(req, filter); err != nil {
-		return admission.Errored(http.StatusInternalServerError, err)
+	if e.IgnoredNamespaces.Matches(req.Namespace) {
+		resp = admission.Allowed("namespace is ignored by this webhook")
+	} else if err := json.Unmarshal(req.Object.Raw, filter); err != nil {
+		resp = admission.Errored(http.StatusInternalServerError, err)
+	} else {
+		resp = e.createAdmissionResponse(ctx, filter, string(req.Object.Raw))
+	}
+
+	recordResponse(resp)
+	span.SetAttributes(attribute.Bool("allowed", resp.Allowed), attribute.Int("patches", len(resp.Patches)))
+	if !resp.Allowed && resp.Result != nil {
+		span.SetStatus(codes.Error, resp.Result.Message)
+	}
+	if !resp.Allowed && e.FailOpen {
+		resp = failOpen(resp)
+	}
+	return resp
+}
+
+// failOpen turns the given error response into a response admitting the
+// EnvoyFilter without patches and records it. The ACL of the shoot isn't
+// enforced by the EnvoyFilter until the webhook mutates it on the next
+// reconciliation of the shoot.
+//
+//nolint:gocritic // admission.Response is passed by value everywhere in controller-runtime
+func failOpen(resp admission.Response) admission.Response {
+	var (
+		code    int32
+		message string
+	)
+	if resp.Result != nil {
+		code, message = resp.Result.Code, resp.Result.Message
 	}
+	recordFailOpen(code)
 
-	return e.createAdmissionResponse(ctx, filter, string(req.Object.Raw))
+	allowed := admission.Allowed("failing open: " + message)
+	allowed.Warnings = []string{fmt.Sprintf("the ACL webhook failed to handle the EnvoyFilter, it is admitted without the ACL filters: %s", message)}
+	return allowed
 }
 
 func (e *EnvoyFilterWebhook) createAdmissionResponse(
@@ -63,16 +134,16 @@ func (e *EnvoyFilterWebhook) createAdmissionResponse(
 	}
 
 	aclExtension := &extensionsv1alpha1.Extension{}
-	err := e.Client.Get(ctx, types.NamespacedName{Name: ExtensionName, Namespace: filter.Name}, aclExtension)
+	err := e.get(ctx, types.NamespacedName{Name: ExtensionName, Namespace: filter.Name}, aclExtension, "extension")
 
 	if client.IgnoreNotFound(err) != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
 	// if an error occured or the extension is in deletion, just allow without
-	// introducing any patches
+	// introducing any patches, but remove the ones of previous admissions
 	if err != nil || !aclExtension.DeletionTimestamp.IsZero() {
-		return admission.Allowed(fmt.Sprintf("extension %s not enabled for shoot %s or is in deletion", ExtensionName, filter.Name))
+		return withoutACLPatches(originalObjectJSON, fmt.Sprintf("extension %s not enabled for shoot %s or is in deletion", ExtensionName, filter.Name))
 	}
 
 	extSpec := &extensionspec.ExtensionSpec{}
@@ -80,43 +151,115 @@ func (e *EnvoyFilterWebhook) createAdmissionResponse(
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	if err := controller.ValidateExtensionSpec(extSpec); err != nil {
-		return admission.Errored(http.StatusBadRequest, err)
+	if controller.IsDisabled(aclExtension, extSpec) {
+		return withoutACLPatches(originalObjectJSON, fmt.Sprintf("enforcement of the ACL is disabled for shoot %s", filter.Name))
 	}
 
-	cluster, err := helper.GetClusterForExtension(ctx, e.Client, aclExtension)
+	cluster, err := e.getCluster(ctx, aclExtension.Namespace)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
+	if _, err := controller.ApplyDefaultRules(extSpec, cluster); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if err := controller.ResolveTemplateVariables(ctx, e.Client, aclExtension, cluster, extSpec); err != nil {
+		if errors.Is(err, controller.ErrSpecVariable) {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if err := controller.ValidateExtensionSpec(extSpec); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	extSpec.Normalize()
+
+	purpose := helper.GetShootPurpose(cluster.Shoot)
+	purposePolicy := e.PurposePolicies[purpose]
+	if purposePolicy.ForbidShadowMode && extSpec.Rule.IsLog() {
+		return admission.Errored(http.StatusBadRequest, fmt.Errorf("%w: the shoot's purpose is %s", controller.ErrSpecShadowModeForbidden, purpose))
+	}
+
 	var alwaysAllowedCIDRs []string
 	var shootSpecificCIRDs []string
 
 	alwaysAllowedCIDRs = append(alwaysAllowedCIDRs, helper.GetSeedSpecificAllowedCIDRs(cluster.Seed)...)
 
+	seedNodeCIDRs, err := helper.GetSeedNodeCIDRs(ctx, e.Client)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	alwaysAllowedCIDRs = append(alwaysAllowedCIDRs, seedNodeCIDRs...)
+	// the networks of the seed and of the shoot's nodes and pods are never
+	// denied, like in the filters rendered by the controller
+	protectedCIDRs := append([]string{}, alwaysAllowedCIDRs...)
+
 	if len(e.AdditionalAllowedCIDRs) >= 1 {
 		alwaysAllowedCIDRs = append(alwaysAllowedCIDRs, e.AdditionalAllowedCIDRs...)
 	}
 
+	configMapReader := e.ConfigMapReader
+	if configMapReader == nil {
+		configMapReader = e.Client
+	}
+	globalAllowedCIDRs, err := helper.GetCIDRsFromConfigMap(ctx, configMapReader, e.GlobalAllowlistConfigMap)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	alwaysAllowedCIDRs = append(alwaysAllowedCIDRs, globalAllowedCIDRs...)
+
+	extSpec.Rule.DeniedCIDRs, err = helper.GetCIDRsFromConfigMap(ctx, configMapReader, e.GlobalDenylistConfigMap)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	extSpec.Rule.DeniedCIDRs = append(extSpec.Rule.DeniedCIDRs, purposePolicy.DeniedCIDRs...)
+
+	clientIPPreservation := e.ClientIPPreservation
+	if clientIPPreservation == "" || clientIPPreservation == helper.ClientIPAuto {
+		// the internal listener is served by the gateways of the EnvoyFilter's
+		// namespace
+		clientIPPreservation, err = helper.DetectClientIPPreservation(ctx, e.Client, filter.Namespace, nil)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+	}
+	extSpec.Rule.ProxyProtocol = clientIPPreservation == helper.ClientIPProxied
+	extSpec.Rule.DualStack = helper.IsDualStack(helper.GetSeedIPFamilies(cluster.Seed))
+
 	// Gardener supports workerless Shoots. These don't have an associated
 	// Infrastructure object and don't need Node- or Pod-specific CIDRs to be
 	// allowed. Therefore, skip these steps for workerless Shoots.
 	if !v1beta1helper.IsWorkerless(cluster.Shoot) {
 		shootSpecificCIRDs = append(shootSpecificCIRDs, helper.GetShootNodeSpecificAllowedCIDRs(cluster.Shoot)...)
 		shootSpecificCIRDs = append(shootSpecificCIRDs, helper.GetShootPodSpecificAllowedCIDRs(cluster.Shoot)...)
+		protectedCIDRs = append(protectedCIDRs, shootSpecificCIRDs...)
 
-		infra, err := helper.GetInfrastructureForExtension(ctx, e.Client, aclExtension, cluster.Shoot.Name)
-		if err != nil {
-			return admission.Errored(http.StatusInternalServerError, err)
+		if e.AutoAllowInfrastructureEgressCIDRs {
+			// a missing Infrastructure, e.g. while the shoot is being created,
+			// doesn't report any egress CIDRs
+			infra, err := helper.GetInfrastructureForExtension(ctx, e.Client, aclExtension, cluster.Shoot.Name)
+			if client.IgnoreNotFound(err) != nil {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+
+			if err == nil {
+				providerSpecificCIRDs, err := helper.GetProviderSpecificAllowedCIDRs(infra)
+				if err != nil {
+					return admission.Errored(http.StatusInternalServerError, err)
+				}
+
+				shootSpecificCIRDs = append(shootSpecificCIRDs, providerSpecificCIRDs...)
+				protectedCIDRs = append(protectedCIDRs, providerSpecificCIRDs...)
+			}
 		}
 
-		providerSpecificCIRDs, err := helper.GetProviderSpecificAllowedCIDRs(infra)
+		bastionCIDRs, err := helper.GetBastionCIDRs(ctx, e.Client, aclExtension.Namespace)
 		if err != nil {
 			return admission.Errored(http.StatusInternalServerError, err)
 		}
-
-		shootSpecificCIRDs = append(shootSpecificCIRDs, providerSpecificCIRDs...)
+		shootSpecificCIRDs = append(shootSpecificCIRDs, bastionCIDRs...)
 	}
+	extSpec.Rule.ProtectedCIDRs = protectedCIDRs
 
 	originalFilter := gjson.Get(originalObjectJSON, `spec.configPatches.0.patch.value.filters.#(name="envoy.filters.network.tcp_proxy")`)
 	originalFilterMap := map[string]interface{}{}
@@ -124,15 +267,111 @@ func (e *EnvoyFilterWebhook) createAdmissionResponse(
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	filterPatch, err := envoyfilters.CreateInternalFilterPatchFromRule(extSpec.Rule, alwaysAllowedCIDRs, shootSpecificCIRDs)
+	filterPatch, err := envoyfilters.CreateInternalFilterPatchFromRule(extSpec.Rule, filter.Name, alwaysAllowedCIDRs, shootSpecificCIRDs)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
+	// the RBAC filter of "RATE_LIMIT" rules only counts the other sources, so
+	// the globally denied CIDRs are denied by a separate filter in front of it
+	filterPatches := []map[string]interface{}{}
+	if deniedFilter := envoyfilters.CreateDeniedCIDRsFilter(
+		extSpec.Rule, "acl-internal-denied", envoyfilters.StatPrefix(envoyfilters.ListenerInternal, filter.Name),
+	); deniedFilter != nil {
+		filterPatches = append(filterPatches, deniedFilter)
+	}
+	filterPatches = append(filterPatches, filterPatch)
+
 	// make sure the original filter is the last
-	filterPatches := []map[string]interface{}{filterPatch, originalFilterMap}
+	response := buildAdmissionResponseWithFilterPatches(append(filterPatches, originalFilterMap))
+
+	rateLimitFilter := envoyfilters.CreateInternalRateLimitFilterFromRule(extSpec.Rule, filter.Name)
+	if rateLimitFilter == nil {
+		response.Patches = append(response.Patches, removeRateLimitedFilterChain(originalObjectJSON)...)
+		return response
+	}
+
+	// the connections of the sources throttled by the rule are served by a
+	// copy of the filter chain, which only matches these sources and runs the
+	// rate limit in front of the original filter
+	originalConfigPatch := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(gjson.Get(originalObjectJSON, "spec.configPatches.0").Raw), &originalConfigPatch); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	patch, _ := originalConfigPatch["patch"].(map[string]interface{})
+	filterChain, _ := patch["value"].(map[string]interface{})
+	if filterChain == nil {
+		return admission.Errored(http.StatusInternalServerError, fmt.Errorf("EnvoyFilter %s has no filter chain to rate-limit", filter.Name))
+	}
+	rateLimitedFilterChain := envoyfilters.RateLimitedFilterChain(
+		extSpec.Rule, filterChain, append(alwaysAllowedCIDRs, shootSpecificCIRDs...),
+		append(append([]map[string]interface{}{}, filterPatches...), rateLimitFilter, originalFilterMap),
+	)
+	if rateLimitedFilterChain == nil {
+		response.Patches = append(response.Patches, removeRateLimitedFilterChain(originalObjectJSON)...)
+		return response
+	}
+	patch["value"] = rateLimitedFilterChain
 
-	return buildAdmissionResponseWithFilterPatches(filterPatches)
+	operation := "add"
+	if hasRateLimitedFilterChain(originalObjectJSON) {
+		operation = "replace"
+	}
+	response.Patches = append(response.Patches, jsonpatch.Operation{
+		Operation: operation,
+		Path:      "/spec/configPatches/1",
+		Value:     originalConfigPatch,
+	})
+	return response
+}
+
+// withoutACLPatches returns a response admitting the EnvoyFilter without the
+// ACL filters and the rate-limited filter chain of previous admissions, e.g.
+// if the EnvoyFilter is updated in place after the extension was disabled or
+// deleted. Otherwise, the last rule would still be enforced for the internal
+// flow.
+func withoutACLPatches(originalObjectJSON, message string) admission.Response {
+	var patches []jsonpatch.Operation
+
+	filters := gjson.Get(originalObjectJSON, "spec.configPatches.0.patch.value.filters")
+	originalFilter := filters.Get(`#(name="envoy.filters.network.tcp_proxy")`)
+	if originalFilter.Exists() && len(filters.Array()) > 1 {
+		originalFilterMap := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(originalFilter.Raw), &originalFilterMap); err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		patches = buildAdmissionResponseWithFilterPatches([]map[string]interface{}{originalFilterMap}).Patches
+	}
+	patches = append(patches, removeRateLimitedFilterChain(originalObjectJSON)...)
+
+	response := admission.Allowed(message)
+	if len(patches) > 0 {
+		response.Patches = patches
+		response.PatchType = ptr.To(admissionv1.PatchTypeJSONPatch)
+	}
+	return response
+}
+
+// hasRateLimitedFilterChain returns whether the EnvoyFilter contains the
+// rate-limited filter chain of a previous admission. The EnvoyFilters of
+// gardener only have one config patch, an existing second one is the copy of
+// a previous admission.
+func hasRateLimitedFilterChain(originalObjectJSON string) bool {
+	return gjson.Get(originalObjectJSON, "spec.configPatches.#").Int() > 1
+}
+
+// removeRateLimitedFilterChain returns the patch removing the rate-limited
+// filter chain of a previous admission, if any. The filter chain carries the
+// RBAC filters of the previous rule, which would still admit the sources
+// denied by the current one.
+func removeRateLimitedFilterChain(originalObjectJSON string) []jsonpatch.Operation {
+	if !hasRateLimitedFilterChain(originalObjectJSON) {
+		return nil
+	}
+	return []jsonpatch.Operation{{
+		Operation: "remove",
+		Path:      "/spec/configPatches/1",
+	}}
 }
 
 func buildAdmissionResponseWithFilterPatches(filters []map[string]interface{}) admission.Response {
@@ -3,8 +3,11 @@ package webhook
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"net/netip"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 
 	openstackv1alpha1 "github.com/gardener/gardener-extension-provider-openstack/pkg/apis/openstack/v1alpha1"
@@ -13,15 +16,22 @@ import (
 	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/tidwall/gjson"
+	"gomodules.xyz/jsonpatch/v2"
 	istionetworkingClientGo "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"github.com/stackitcloud/gardener-extension-acl/pkg/controller"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/envoyfilters"
 	"github.com/stackitcloud/gardener-extension-acl/pkg/extensionspec"
+	"github.com/stackitcloud/gardener-extension-acl/pkg/helper"
 )
 
 var _ = Describe("webhook unit test", func() {
@@ -86,6 +96,54 @@ var _ = Describe("webhook unit test", func() {
 		deleteNamespace(namespace)
 	})
 
+	Describe("Handle", func() {
+		It("counts EnvoyFilters admitted without patches", func() {
+			_, dfJSON := getEnvoyFilterFromFile("non-shoot-envoyfilter")
+			before := counterValue("acl_webhook_mutations_total", "result", ResultSkipped)
+
+			ar := e.Handle(context.Background(), newAdmissionRequest(dfJSON))
+
+			Expect(ar.Allowed).To(BeTrue())
+			Expect(counterValue("acl_webhook_mutations_total", "result", ResultSkipped)).To(Equal(before + 1))
+		})
+
+		It("admits EnvoyFilters in ignored namespaces without touching them", func() {
+			patterns, err := helper.CompileNamespacePatterns([]string{"istio-canary-.*"})
+			Expect(err).NotTo(HaveOccurred())
+			e.IgnoredNamespaces = patterns
+			req := newAdmissionRequest(`{"spec":"invalid"}`)
+			req.Namespace = "istio-canary-1-22"
+
+			ar := e.Handle(context.Background(), req)
+
+			Expect(ar.Allowed).To(BeTrue())
+			Expect(ar.Patches).To(BeEmpty())
+		})
+
+		It("counts EnvoyFilters which can't be decoded as errors", func() {
+			before := counterValue("acl_webhook_errors_total", "code", "500")
+
+			ar := e.Handle(context.Background(), newAdmissionRequest(`{"spec":"invalid"}`))
+
+			Expect(ar.Allowed).To(BeFalse())
+			Expect(counterValue("acl_webhook_errors_total", "code", "500")).To(Equal(before + 1))
+		})
+
+		It("admits EnvoyFilters it fails to handle without patches if it fails open", func() {
+			e.FailOpen = true
+			beforeErrors := counterValue("acl_webhook_errors_total", "code", "500")
+			beforeFailOpens := counterValue("acl_webhook_fail_open_total", "code", "500")
+
+			ar := e.Handle(context.Background(), newAdmissionRequest(`{"spec":"invalid"}`))
+
+			Expect(ar.Allowed).To(BeTrue())
+			Expect(ar.Patches).To(BeEmpty())
+			Expect(ar.Warnings).To(ConsistOf(ContainSubstring("admitted without the ACL filters")))
+			Expect(counterValue("acl_webhook_errors_total", "code", "500")).To(Equal(beforeErrors + 1))
+			Expect(counterValue("acl_webhook_fail_open_total", "code", "500")).To(Equal(beforeFailOpens + 1))
+		})
+	})
+
 	Describe("createAdmissionResponse", func() {
 		When("the name of the EnvoyFilter doesn't start with 'shoot-'", func() {
 			It("issues no patch for the EnvoyFilter", func() {
@@ -125,11 +183,100 @@ var _ = Describe("webhook unit test", func() {
 			})
 		})
 
+		When("the extension is in deletion", func() {
+			BeforeEach(func() {
+				extSpec := getExtensionSpec()
+				addRuleToSpec(extSpec, "RATE_LIMIT", "remote_ip", "1.2.3.0/24")
+				extSpec.Rule.RateLimit = &envoyfilters.RateLimit{ConnectionsPerSecond: 10}
+				ext = getNewExtension(namespace, *extSpec)
+				ext.Finalizers = []string{"extensions.gardener.cloud/acl"}
+
+				Expect(k8sClient.Create(ctx, ext)).To(Succeed())
+			})
+
+			AfterEach(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(ext), ext))).To(Succeed())
+				ext.Finalizers = nil
+				Expect(client.IgnoreNotFound(k8sClient.Update(ctx, ext))).To(Succeed())
+			})
+
+			It("removes the patches of previous admissions from the EnvoyFilter", func() {
+				df, dfJSON := getEnvoyFilterFromFile(namespace)
+				ar := e.createAdmissionResponse(context.Background(), df, dfJSON)
+				Expect(ar.Allowed).To(BeTrue())
+				patchedJSON := applyPatches(dfJSON, ar.Patches)
+
+				Expect(k8sClient.Delete(ctx, ext)).To(Succeed())
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ext), ext)).To(Succeed())
+				Expect(ext.DeletionTimestamp).NotTo(BeNil())
+
+				ar = e.createAdmissionResponse(context.Background(), df, patchedJSON)
+
+				Expect(ar.Allowed).To(BeTrue())
+				Expect(ar.Result.Message).To(ContainSubstring("is in deletion"))
+				Expect(applyPatches(patchedJSON, ar.Patches)).To(MatchJSON(dfJSON))
+			})
+
+			It("issues no patch for an EnvoyFilter without patches of previous admissions", func() {
+				Expect(k8sClient.Delete(ctx, ext)).To(Succeed())
+				df, dfJSON := getEnvoyFilterFromFile(namespace)
+
+				ar := e.createAdmissionResponse(context.Background(), df, dfJSON)
+
+				Expect(ar.Allowed).To(BeTrue())
+				Expect(ar.Patches).To(BeEmpty())
+			})
+		})
+
+		When("the enforcement of the ACL is disabled", func() {
+			BeforeEach(func() {
+				extSpec := getExtensionSpec()
+				addRuleToSpec(extSpec, "ALLOW", "remote_ip", "1.2.3.4/24")
+				ext = getNewExtension(namespace, *extSpec)
+				ext.Annotations[controller.AnnotationDisabled] = "true"
+
+				Expect(k8sClient.Create(ctx, ext)).To(Succeed())
+			})
+
+			AfterEach(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, ext))).To(Succeed())
+			})
+
+			It("issues no patch for an EnvoyFilter without patches of previous admissions", func() {
+				df, dfJSON := getEnvoyFilterFromFile(namespace)
+
+				ar := e.createAdmissionResponse(context.Background(), df, dfJSON)
+
+				Expect(ar.Allowed).To(BeTrue())
+				Expect(ar.Result.Message).To(ContainSubstring("enforcement of the ACL is disabled"))
+				Expect(ar.Patches).To(BeEmpty())
+			})
+
+			It("removes the patches of previous admissions from the EnvoyFilter", func() {
+				df, dfJSON := getEnvoyFilterFromFile(namespace)
+				delete(ext.Annotations, controller.AnnotationDisabled)
+				Expect(k8sClient.Update(ctx, ext)).To(Succeed())
+				ar := e.createAdmissionResponse(context.Background(), df, dfJSON)
+				Expect(ar.Allowed).To(BeTrue())
+				patchedJSON := applyPatches(dfJSON, ar.Patches)
+				Expect(patchedJSON).To(ContainSubstring("acl-internal-remote_ip"))
+
+				ext.Annotations[controller.AnnotationDisabled] = "true"
+				Expect(k8sClient.Update(ctx, ext)).To(Succeed())
+
+				ar = e.createAdmissionResponse(context.Background(), df, patchedJSON)
+
+				Expect(ar.Allowed).To(BeTrue())
+				Expect(ar.Result.Message).To(ContainSubstring("enforcement of the ACL is disabled"))
+				Expect(applyPatches(patchedJSON, ar.Patches)).To(MatchJSON(dfJSON))
+			})
+		})
+
 		When("there is an extension resource with one DENY rule", func() {
 			extSpec := getExtensionSpec()
 
 			BeforeEach(func() {
-				addRuleToSpec(extSpec, "DENY", "source_ip", "0.0.0.0/0")
+				addRuleToSpec(extSpec, "DENY", "remote_ip", "0.0.0.0/0")
 				ext = getNewExtension(namespace, *extSpec)
 
 				Expect(k8sClient.Create(ctx, ext)).To(Succeed())
@@ -139,7 +286,21 @@ var _ = Describe("webhook unit test", func() {
 				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, ext))).To(Succeed())
 			})
 
-			It("patches this rule into the filters object", func() {
+			It("reads the objects of the shoot from the API server if they aren't cached yet", func() {
+				e.Client = fakeclient.NewClientBuilder().WithScheme(clientScheme).Build()
+				e.APIReader = k8sClient
+				e.AutoAllowInfrastructureEgressCIDRs = false
+				before := counterValue("acl_webhook_lookups_total", "source", SourceAPIServer)
+				df, dfJSON := getEnvoyFilterFromFile(namespace)
+
+				ar := e.createAdmissionResponse(context.Background(), df, dfJSON)
+
+				Expect(ar.Allowed).To(BeTrue())
+				Expect(ar.Patches).NotTo(BeEmpty())
+				Expect(counterValue("acl_webhook_lookups_total", "source", SourceAPIServer)).To(BeNumerically(">", before))
+			})
+
+			It("patches this rule into the filters object, excluding the protected CIDRs of Seed|Shoot nodes and pods", func() {
 				df, dfJSON := getEnvoyFilterFromFile(namespace)
 
 				ar := e.createAdmissionResponse(context.Background(), df, dfJSON)
@@ -148,9 +309,9 @@ var _ = Describe("webhook unit test", func() {
 
 				expectedFilters := []map[string]interface{}{
 					{
-						"name": "acl-internal-source_ip",
+						"name": "acl-internal-remote_ip",
 						"typed_config": map[string]interface{}{
-							"stat_prefix": "envoyrbac",
+							"stat_prefix": "acl_internal_" + namespace,
 							"rules": map[string]interface{}{
 								"policies": map[string]interface{}{
 									"acl-internal": map[string]interface{}{
@@ -159,14 +320,31 @@ var _ = Describe("webhook unit test", func() {
 												"any": true,
 											},
 										},
-										"principals": []map[string]interface{}{
-											{
-												"source_ip": map[string]interface{}{
-													"address_prefix": "0.0.0.0",
-													"prefix_len":     0,
+										"principals": []map[string]interface{}{{
+											"and_ids": map[string]interface{}{
+												"ids": []map[string]interface{}{
+													{
+														"or_ids": map[string]interface{}{
+															"ids": []map[string]interface{}{
+																remoteIPPrincipal("0.0.0.0", 0),
+															},
+														},
+													},
+													{
+														"not_id": map[string]interface{}{
+															"or_ids": map[string]interface{}{
+																"ids": []map[string]interface{}{
+																	remoteIPPrincipal("100.250.0.0", 16),
+																	remoteIPPrincipal("10.96.0.0", 11),
+																	remoteIPPrincipal("10.250.0.0", 16),
+																	remoteIPPrincipal("100.96.0.0", 11),
+																},
+															},
+														},
+													},
 												},
 											},
-										},
+										}},
 									},
 								},
 								"action": "DENY",
@@ -193,7 +371,8 @@ var _ = Describe("webhook unit test", func() {
 			extSpec := getExtensionSpec()
 
 			BeforeEach(func() {
-				addRuleToSpec(extSpec, "ALLOW", "source_ip", "0.0.0.0/0")
+				addRuleToSpec(extSpec, "ALLOW", "remote_ip", "0.0.0.0/0")
+				extSpec.AllowOpenAccess = true
 				ext = getNewExtension(namespace, *extSpec)
 
 				Expect(k8sClient.Create(ctx, ext)).To(Succeed())
@@ -212,9 +391,9 @@ var _ = Describe("webhook unit test", func() {
 
 				expectedFilters := []map[string]interface{}{
 					{
-						"name": "acl-internal-source_ip",
+						"name": "acl-internal-remote_ip",
 						"typed_config": map[string]interface{}{
-							"stat_prefix": "envoyrbac",
+							"stat_prefix": "acl_internal_" + namespace,
 							"rules": map[string]interface{}{
 								"policies": map[string]interface{}{
 									"acl-internal": map[string]interface{}{
@@ -225,7 +404,7 @@ var _ = Describe("webhook unit test", func() {
 										},
 										"principals": []map[string]interface{}{
 											{
-												"source_ip": map[string]interface{}{
+												"remote_ip": map[string]interface{}{
 													"address_prefix": "0.0.0.0",
 													"prefix_len":     0,
 												},
@@ -281,7 +460,8 @@ var _ = Describe("webhook unit test", func() {
 			extSpec := getExtensionSpec()
 
 			BeforeEach(func() {
-				addRuleToSpec(extSpec, "ALLOW", "source_ip", "0.0.0.0/0")
+				addRuleToSpec(extSpec, "ALLOW", "remote_ip", "0.0.0.0/0")
+				extSpec.AllowOpenAccess = true
 				ext = getNewExtension(namespace, *extSpec)
 
 				Expect(k8sClient.Create(ctx, ext)).To(Succeed())
@@ -326,9 +506,9 @@ var _ = Describe("webhook unit test", func() {
 
 				expectedFilters := []map[string]interface{}{
 					{
-						"name": "acl-internal-source_ip",
+						"name": "acl-internal-remote_ip",
 						"typed_config": map[string]interface{}{
-							"stat_prefix": "envoyrbac",
+							"stat_prefix": "acl_internal_" + namespace,
 							"rules": map[string]interface{}{
 								"policies": map[string]interface{}{
 									"acl-internal": map[string]interface{}{
@@ -339,7 +519,7 @@ var _ = Describe("webhook unit test", func() {
 										},
 										"principals": []map[string]interface{}{
 											{
-												"source_ip": map[string]interface{}{
+												"remote_ip": map[string]interface{}{
 													"address_prefix": "0.0.0.0",
 													"prefix_len":     0,
 												},
@@ -395,13 +575,107 @@ var _ = Describe("webhook unit test", func() {
 
 				Expect(ar.Patches[0].Value).To(Equal(expectedFilters))
 			})
+
+			It("doesn't include the OpenStack router IP if auto-allowing infrastructure egress CIDRs is disabled", func() {
+				e.AutoAllowInfrastructureEgressCIDRs = false
+				df, dfJSON := getEnvoyFilterFromFile(namespace)
+
+				ar := e.createAdmissionResponse(context.Background(), df, dfJSON)
+
+				Expect(ar.Allowed).To(BeTrue())
+				patchJSON, err := json.Marshal(ar.Patches[0].Value)
+				Expect(err).To(BeNil())
+				Expect(string(patchJSON)).To(ContainSubstring("100.96.0.0"))
+				Expect(string(patchJSON)).NotTo(ContainSubstring("10.9.8.7"))
+			})
+		})
+
+		When("there is an extension resource with one RATE_LIMIT rule", func() {
+			extSpec := getExtensionSpec()
+
+			BeforeEach(func() {
+				addRuleToSpec(extSpec, "RATE_LIMIT", "remote_ip", "1.2.3.0/24")
+				extSpec.Rule.RateLimit = &envoyfilters.RateLimit{ConnectionsPerSecond: 10}
+				ext = getNewExtension(namespace, *extSpec)
+
+				Expect(k8sClient.Create(ctx, ext)).To(Succeed())
+			})
+
+			AfterEach(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, ext))).To(Succeed())
+			})
+
+			It("only throttles the connections of the sources not allowed by the rule", func() {
+				df, dfJSON := getEnvoyFilterFromFile(namespace)
+
+				ar := e.createAdmissionResponse(context.Background(), df, dfJSON)
+
+				Expect(ar.Allowed).To(BeTrue())
+				Expect(ar.Patches).To(HaveLen(2))
+				Expect(filterNames(ar.Patches[0].Value)).To(Equal([]string{"acl-internal-remote_ip", "envoy.filters.network.tcp_proxy"}))
+
+				Expect(ar.Patches[1].Operation).To(Equal("add"))
+				Expect(ar.Patches[1].Path).To(Equal("/spec/configPatches/1"))
+				filterChain := ar.Patches[1].Value.(map[string]interface{})["patch"].(map[string]interface{})["value"].(map[string]interface{})
+				Expect(filterNames(filterChain["filters"])).To(Equal([]string{
+					"acl-internal-remote_ip", "acl-internal-ratelimit", "envoy.filters.network.tcp_proxy",
+				}))
+
+				filterChainMatch := filterChain["filter_chain_match"].(map[string]interface{})
+				Expect(filterChainMatch).To(HaveKeyWithValue("destination_port", BeNumerically("==", 443)))
+				var sourceRanges []netip.Prefix
+				for _, sourceRange := range filterChainMatch["source_prefix_ranges"].([]map[string]interface{}) {
+					sourceRanges = append(sourceRanges, netip.PrefixFrom(
+						netip.MustParseAddr(sourceRange["address_prefix"].(string)), sourceRange["prefix_len"].(int)))
+				}
+				contains := func(ip string) bool {
+					for _, sourceRange := range sourceRanges {
+						if sourceRange.Contains(netip.MustParseAddr(ip)) {
+							return true
+						}
+					}
+					return false
+				}
+				// the rule's CIDRs, the seed and the shoot CIDRs aren't
+				// throttled, all other sources are
+				Expect(contains("1.2.3.4")).To(BeFalse())
+				Expect(contains("100.250.1.1")).To(BeFalse())
+				Expect(contains("10.250.1.1")).To(BeFalse())
+				Expect(contains("5.6.7.8")).To(BeTrue())
+				Expect(contains("2001:db8::1")).To(BeTrue())
+			})
+
+			It("removes the rate-limited filter chain once the rule doesn't rate-limit anymore", func() {
+				df, dfJSON := getEnvoyFilterFromFile(namespace)
+				ar := e.createAdmissionResponse(context.Background(), df, dfJSON)
+				Expect(ar.Allowed).To(BeTrue())
+				dfJSON = applyPatches(dfJSON, ar.Patches)
+
+				// the sources allowed by the rate-limited filter chain are
+				// denied by the new rule
+				addRuleToSpec(extSpec, "ALLOW", "remote_ip", "5.6.7.8/32")
+				rawSpec, err := json.Marshal(extSpec)
+				Expect(err).ToNot(HaveOccurred())
+				ext.Spec.ProviderConfig.Raw = rawSpec
+				Expect(k8sClient.Update(ctx, ext)).To(Succeed())
+
+				ar = e.createAdmissionResponse(context.Background(), df, dfJSON)
+
+				Expect(ar.Allowed).To(BeTrue())
+				Expect(ar.Patches).To(HaveLen(2))
+				Expect(filterNames(ar.Patches[0].Value)).To(Equal([]string{"acl-internal-remote_ip", "envoy.filters.network.tcp_proxy"}))
+				Expect(ar.Patches[1].Operation).To(Equal("remove"))
+				Expect(ar.Patches[1].Path).To(Equal("/spec/configPatches/1"))
+				Expect(gjson.Get(applyPatches(dfJSON, ar.Patches), "spec.configPatches.#").Int()).To(BeEquivalentTo(1))
+			})
 		})
 
 		When("the Shoot is workerless, and there is one allow rule", func() {
 			extSpec := getExtensionSpec()
 
 			BeforeEach(func() {
-				addRuleToSpec(extSpec, "ALLOW", "source_ip", "0.0.0.0/0")
+				addRuleToSpec(extSpec, "ALLOW", "remote_ip", "0.0.0.0/0")
+				extSpec.AllowOpenAccess = true
 				ext = getNewExtension(namespace, *extSpec)
 				Expect(k8sClient.Create(ctx, ext)).To(Succeed())
 
@@ -433,9 +707,9 @@ var _ = Describe("webhook unit test", func() {
 
 				expectedFilters := []map[string]interface{}{
 					{
-						"name": "acl-internal-source_ip",
+						"name": "acl-internal-remote_ip",
 						"typed_config": map[string]interface{}{
-							"stat_prefix": "envoyrbac",
+							"stat_prefix": "acl_internal_" + namespace,
 							"rules": map[string]interface{}{
 								"policies": map[string]interface{}{
 									"acl-internal": map[string]interface{}{
@@ -446,7 +720,7 @@ var _ = Describe("webhook unit test", func() {
 										},
 										"principals": []map[string]interface{}{
 											{
-												"source_ip": map[string]interface{}{
+												"remote_ip": map[string]interface{}{
 													"address_prefix": "0.0.0.0",
 													"prefix_len":     0,
 												},
@@ -488,14 +762,111 @@ var _ = Describe("webhook unit test", func() {
 	})
 })
 
+// applyPatches applies the JSON patches of an admission response to the JSON
+// representation of an object, like the API server does.
+func applyPatches(objectJSON string, patches []jsonpatch.Operation) string {
+	var object interface{}
+	Expect(json.Unmarshal([]byte(objectJSON), &object)).To(Succeed())
+
+	for _, patch := range patches {
+		segments := strings.Split(strings.TrimPrefix(patch.Path, "/"), "/")
+		object = applyPatch(object, segments, patch.Operation, roundTrip(patch.Value))
+	}
+
+	patched, err := json.Marshal(object)
+	Expect(err).ToNot(HaveOccurred())
+	return string(patched)
+}
+
+// applyPatch applies the operation to the value at the path segments below
+// the node and returns the patched node.
+func applyPatch(node interface{}, segments []string, operation string, value interface{}) interface{} {
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		switch {
+		case len(segments) > 1:
+			typed[segments[0]] = applyPatch(typed[segments[0]], segments[1:], operation, value)
+		case operation == "remove":
+			delete(typed, segments[0])
+		default:
+			typed[segments[0]] = value
+		}
+		return typed
+	case []interface{}:
+		index, err := strconv.Atoi(segments[0])
+		Expect(err).ToNot(HaveOccurred())
+		switch {
+		case len(segments) > 1:
+			typed[index] = applyPatch(typed[index], segments[1:], operation, value)
+		case operation == "add":
+			typed = append(typed[:index], append([]interface{}{value}, typed[index:]...)...)
+		case operation == "remove":
+			typed = append(typed[:index], typed[index+1:]...)
+		default:
+			typed[index] = value
+		}
+		return typed
+	}
+	Fail(fmt.Sprintf("can't apply %s to %v", operation, segments))
+	return nil
+}
+
+// roundTrip returns the JSON representation of the value decoded again.
+func roundTrip(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	raw, err := json.Marshal(value)
+	Expect(err).ToNot(HaveOccurred())
+	var decoded interface{}
+	Expect(json.Unmarshal(raw, &decoded)).To(Succeed())
+	return decoded
+}
+
+// filterNames returns the names of the given filters.
+func filterNames(filters interface{}) []string {
+	var names []string
+	for _, filter := range filters.([]map[string]interface{}) {
+		names = append(names, filter["name"].(string))
+	}
+	return names
+}
+
 func getNewWebhook() *EnvoyFilterWebhook {
-	decoder := admission.NewDecoder(clientScheme)
 	return &EnvoyFilterWebhook{
-		Client:  k8sClient,
-		Decoder: decoder,
+		Client:                             k8sClient,
+		AutoAllowInfrastructureEgressCIDRs: true,
+	}
+}
+
+func newAdmissionRequest(objectJSON string) admission.Request {
+	return admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: []byte(objectJSON)},
+		},
 	}
 }
 
+func counterValue(name, labelName, labelValue string) float64 {
+	families, err := metrics.Registry.Gather()
+	Expect(err).NotTo(HaveOccurred())
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == labelName && label.GetValue() == labelValue {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
 func getNewCluster(namespace string, shoot *gardencorev1beta1.Shoot, seed *gardencorev1beta1.Seed) *extensionsv1alpha1.Cluster {
 	shootJSON, err := json.Marshal(shoot)
 	Expect(err).To(BeNil())
@@ -558,6 +929,15 @@ func addRuleToSpec(extSpec *extensionspec.ExtensionSpec, action, ruleType, cidr
 	}
 }
 
+func remoteIPPrincipal(prefix string, length int) map[string]interface{} {
+	return map[string]interface{}{
+		"remote_ip": map[string]interface{}{
+			"address_prefix": prefix,
+			"prefix_len":     length,
+		},
+	}
+}
+
 // getEnvoyFilterFromFile takes the technical shoot ID as a parameter to render
 // into the JSON tempate file. Returns both the JSON representation as string
 // and the struct type.
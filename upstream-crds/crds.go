@@ -0,0 +1,11 @@
+// Package upstreamcrds embeds the CRDs of gardener the extension depends on.
+// They are installed by the tests and by the local mode of the extension, the
+// CRDs of istio come with the istio installation.
+package upstreamcrds
+
+import "embed"
+
+var (
+	//go:embed 10-*.yaml
+	Gardener embed.FS
+)